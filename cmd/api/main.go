@@ -19,6 +19,7 @@ import (
 	"github.com/alpardfm/library-management-api/internal/repository"
 	"github.com/alpardfm/library-management-api/internal/service"
 	"github.com/alpardfm/library-management-api/pkg/database"
+	"github.com/alpardfm/library-management-api/pkg/storage"
 )
 
 func main() {
@@ -28,16 +29,19 @@ func main() {
 	}
 
 	// Load configuration
-	cfg := configs.Load()
+	cfg, err := configs.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
 
 	// Setup logger
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	if cfg.AppEnv == "production" {
+	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	// Connect to database
-	db, err := database.Connect()
+	db, err := database.Open(&cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -47,24 +51,81 @@ func main() {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	// Open object storage for cover images
+	store, err := storage.Open(&cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	bookRepo := repository.NewBookRepository(db)
 	borrowRepo := repository.NewBorrowRepository(db)
+	settingRepo := repository.NewSettingRepository(db)
+	importJobRepo := repository.NewImportJobRepository(db)
+	marcRepo := repository.NewMarcRecordRepository(db)
+	authorRepo := repository.NewAuthorRepository(db)
+	subjectRepo := repository.NewSubjectRepository(db)
+	revisionRepo := repository.NewBookRevisionRepository(db)
+	workRepo := repository.NewWorkRepository(db)
+	seriesRepo := repository.NewSeriesRepository(db)
+	holdRepo := repository.NewHoldRepository(db)
+	branchRepo := repository.NewBranchRepository(db)
+	copyRepo := repository.NewCopyRepository(db)
+	transferRepo := repository.NewTransferRepository(db)
+	stocktakeRepo := repository.NewStocktakeRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, cfg.JWTSecret, cfg.JWTExpiry)
-	bookService := service.NewBookService(bookRepo)
-	borrowService := service.NewBorrowService(db, borrowRepo, bookRepo, userRepo, service.BorrowServiceConfig{
-		MaxBooksPerUser: cfg.MaxBooksPerUser,
-		BorrowDays:      cfg.BorrowDays,
-		FinePerDay:      cfg.FinePerDay,
-	})
+	authService := service.NewAuthService(userRepo, cfg.JWT.Secret, cfg.JWT.Expiry)
+	userService := service.NewUserService(db, userRepo, borrowRepo)
+	bookService := service.NewBookService(db, bookRepo, authorRepo, subjectRepo, revisionRepo, workRepo, copyRepo)
+	settingsService := service.NewSettingsService(db, settingRepo, service.BorrowServiceConfig{
+		MaxBooksPerUser: cfg.Circulation.MaxBooksPerUser,
+		BorrowDays:      cfg.Circulation.BorrowDays,
+		FinePerDay:      cfg.Circulation.FinePerDay,
+		ProcessingFee:   cfg.Circulation.ProcessingFee,
+	}, cfg.Settings.RefreshInterval)
+	borrowService := service.NewBorrowService(db, borrowRepo, bookRepo, revisionRepo, userRepo, holdRepo, copyRepo, transferRepo, branchRepo, accountRepo, settingsService)
+	importService := service.NewBookImportService(db, bookRepo, importJobRepo, marcRepo, authorRepo, subjectRepo, revisionRepo, copyRepo)
+	exportService := service.NewBookExportService(db, bookRepo)
+	marcService := service.NewMarcService(db, bookRepo, marcRepo)
+	authorService := service.NewAuthorService(db, authorRepo)
+	subjectService := service.NewSubjectService(db, subjectRepo)
+	workService := service.NewWorkService(workRepo, seriesRepo)
+	holdService := service.NewHoldService(db, holdRepo, bookRepo, workRepo, userRepo, copyRepo)
+	branchService := service.NewBranchService(branchRepo)
+	copyService := service.NewCopyService(db, copyRepo, transferRepo, branchRepo, bookRepo, revisionRepo, holdRepo)
+	shelfService := service.NewShelfService(bookRepo, copyRepo, branchRepo)
+	stocktakeService := service.NewStocktakeService(db, stocktakeRepo, copyRepo, bookRepo, branchRepo, transferRepo, holdRepo)
+	accountService := service.NewAccountService(accountRepo, userRepo)
+	coverService := service.NewCoverService(bookRepo, store, int64(cfg.Covers.MaxSize))
+
+	// Jobs that were running when the previous process stopped cannot resume
+	if n, err := importService.FailInterruptedImports(context.Background()); err != nil {
+		log.Printf("Failed to mark interrupted imports: %v", err)
+	} else if n > 0 {
+		log.Printf("Marked %d interrupted import jobs as failed", n)
+	}
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authService)
 	bookHandler := handler.NewBookHandler(bookService)
 	borrowHandler := handler.NewBorrowHandler(borrowService)
+	settingsHandler := handler.NewSettingsHandler(settingsService)
+	importHandler := handler.NewImportHandler(importService)
+	exportHandler := handler.NewExportHandler(exportService, marcService)
+	authorHandler := handler.NewAuthorHandler(authorService)
+	subjectHandler := handler.NewSubjectHandler(subjectService)
+	coverHandler := handler.NewCoverHandler(coverService)
+	workHandler := handler.NewWorkHandler(workService)
+	holdHandler := handler.NewHoldHandler(holdService)
+	branchHandler := handler.NewBranchHandler(branchService)
+	copyHandler := handler.NewCopyHandler(copyService)
+	shelfHandler := handler.NewShelfHandler(shelfService)
+	stocktakeHandler := handler.NewStocktakeHandler(stocktakeService)
+	accountHandler := handler.NewAccountHandler(accountService)
+	userHandler := handler.NewUserHandler(userService)
 
 	// Setup router
 	router := gin.New()
@@ -82,22 +143,99 @@ func main() {
 	{
 		public.POST("/auth/register", authHandler.Register)
 		public.POST("/auth/login", authHandler.Login)
+
+		// Cover images are public so catalog pages can embed them
+		public.GET("/books/:id/cover", coverHandler.GetCover)
+		public.GET("/books/:id/cover/:size", coverHandler.GetCover)
 	}
 
 	// Protected routes
 	protected := router.Group("/api/v1")
-	protected.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	protected.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
 	{
 		// Books
 		books := protected.Group("/books")
 		{
 			books.GET("", bookHandler.ListBooks)
+			books.GET("/suggest", bookHandler.SuggestBooks)
+			books.GET("/removed", middleware.RoleMiddleware("admin"), bookHandler.ListRemovedBooks)
 			books.GET("/:id", bookHandler.GetBook)
+			books.GET("/:id/marc", exportHandler.GetBookRecord)
+			books.GET("/:id/copies", copyHandler.ListCopies)
+			books.GET("/:id/shelf", shelfHandler.BrowseBook)
 
 			// Admin/Librarian only
 			books.POST("", middleware.RoleMiddleware("admin", "librarian"), bookHandler.CreateBook)
 			books.PUT("/:id", middleware.RoleMiddleware("admin", "librarian"), bookHandler.UpdateBook)
+			books.PATCH("/:id", middleware.RoleMiddleware("admin", "librarian"), bookHandler.PatchBook)
 			books.DELETE("/:id", middleware.RoleMiddleware("admin", "librarian"), bookHandler.DeleteBook)
+			books.POST("/:id/restore", middleware.RoleMiddleware("admin"), bookHandler.RestoreBook)
+			books.GET("/:id/history", middleware.RoleMiddleware("admin", "librarian"), bookHandler.BookHistory)
+			books.POST("/:id/revert", middleware.RoleMiddleware("admin", "librarian"), bookHandler.RevertBook)
+			books.PUT("/:id/cover", middleware.RoleMiddleware("admin", "librarian"), coverHandler.UploadCover)
+			books.DELETE("/:id/cover", middleware.RoleMiddleware("admin", "librarian"), coverHandler.DeleteCover)
+			books.POST("/:id/copies", middleware.RoleMiddleware("admin", "librarian"), copyHandler.AddCopy)
+
+			// Bulk import and export
+			books.GET("/export", middleware.RoleMiddleware("admin", "librarian"), exportHandler.ExportBooks)
+			books.POST("/import", middleware.RoleMiddleware("admin", "librarian"), importHandler.StartImport)
+			books.GET("/import/:id", middleware.RoleMiddleware("admin", "librarian"), importHandler.GetImportJob)
+			books.GET("/import/:id/issues", middleware.RoleMiddleware("admin", "librarian"), importHandler.ListImportIssues)
+		}
+
+		// Authors
+		authors := protected.Group("/authors")
+		{
+			authors.GET("", authorHandler.ListAuthors)
+			authors.GET("/:id", authorHandler.GetAuthor)
+			authors.GET("/:id/books", authorHandler.ListAuthorBooks)
+
+			// Admin/Librarian only
+			authors.POST("", middleware.RoleMiddleware("admin", "librarian"), authorHandler.CreateAuthor)
+			authors.PUT("/:id", middleware.RoleMiddleware("admin", "librarian"), authorHandler.UpdateAuthor)
+			authors.DELETE("/:id", middleware.RoleMiddleware("admin", "librarian"), authorHandler.DeleteAuthor)
+			authors.POST("/:id/variants", middleware.RoleMiddleware("admin", "librarian"), authorHandler.AddVariant)
+			authors.DELETE("/:id/variants/:variantId", middleware.RoleMiddleware("admin", "librarian"), authorHandler.RemoveVariant)
+			authors.POST("/:id/merge", middleware.RoleMiddleware("admin", "librarian"), authorHandler.MergeAuthors)
+		}
+
+		// Subjects
+		subjects := protected.Group("/subjects")
+		{
+			subjects.GET("", subjectHandler.ListSubjects)
+			subjects.GET("/:id", subjectHandler.GetSubject)
+			subjects.GET("/:id/books", subjectHandler.ListSubjectBooks)
+
+			// Admin/Librarian only
+			subjects.POST("", middleware.RoleMiddleware("admin", "librarian"), subjectHandler.CreateSubject)
+			subjects.PUT("/:id", middleware.RoleMiddleware("admin", "librarian"), subjectHandler.UpdateSubject)
+			subjects.POST("/:id/move", middleware.RoleMiddleware("admin", "librarian"), subjectHandler.MoveSubject)
+			subjects.DELETE("/:id", middleware.RoleMiddleware("admin", "librarian"), subjectHandler.DeleteSubject)
+			subjects.POST("/:id/aliases", middleware.RoleMiddleware("admin", "librarian"), subjectHandler.AddAlias)
+			subjects.DELETE("/:id/aliases/:aliasId", middleware.RoleMiddleware("admin", "librarian"), subjectHandler.RemoveAlias)
+			subjects.POST("/map-genres", middleware.RoleMiddleware("admin"), subjectHandler.MapGenres)
+		}
+
+		// Works group the editions of a book; series number works
+		works := protected.Group("/works")
+		{
+			works.GET("", workHandler.ListWorks)
+			works.GET("/:id", workHandler.GetWork)
+
+			// Admin/Librarian only
+			works.POST("", middleware.RoleMiddleware("admin", "librarian"), workHandler.CreateWork)
+			works.PUT("/:id", middleware.RoleMiddleware("admin", "librarian"), workHandler.UpdateWork)
+			works.DELETE("/:id", middleware.RoleMiddleware("admin", "librarian"), workHandler.DeleteWork)
+		}
+		series := protected.Group("/series")
+		{
+			series.GET("", workHandler.ListSeries)
+			series.GET("/:id", workHandler.GetSeries)
+
+			// Admin/Librarian only
+			series.POST("", middleware.RoleMiddleware("admin", "librarian"), workHandler.CreateSeries)
+			series.PUT("/:id", middleware.RoleMiddleware("admin", "librarian"), workHandler.UpdateSeries)
+			series.DELETE("/:id", middleware.RoleMiddleware("admin", "librarian"), workHandler.DeleteSeries)
 		}
 
 		// Borrow
@@ -110,21 +248,108 @@ func main() {
 			// Admin/Librarian only
 			borrow.GET("/active", middleware.RoleMiddleware("admin", "librarian"), borrowHandler.GetActiveBorrows)
 			borrow.GET("/overdue", middleware.RoleMiddleware("admin", "librarian"), borrowHandler.GetOverdueBorrows)
+			borrow.POST("/lost", middleware.RoleMiddleware("admin", "librarian"), borrowHandler.DeclareLost)
+			borrow.POST("/damaged", middleware.RoleMiddleware("admin", "librarian"), borrowHandler.ReturnDamaged)
+			borrow.POST("/found", middleware.RoleMiddleware("admin", "librarian"), borrowHandler.ReturnLost)
+		}
+
+		// Replacement charges and refunds for lost and damaged items
+		accounts := protected.Group("/accounts")
+		{
+			accounts.GET("/my-account", accountHandler.GetMyAccount)
+
+			// Admin/Librarian only
+			accounts.GET("/:id", middleware.RoleMiddleware("admin", "librarian"), accountHandler.GetAccount)
+		}
+
+		// Soft delete and restore of users (Admin only)
+		users := protected.Group("/users")
+		{
+			users.DELETE("/:id", middleware.RoleMiddleware("admin"), userHandler.DeleteUser)
+			users.POST("/:id/restore", middleware.RoleMiddleware("admin"), userHandler.RestoreUser)
+		}
+
+		// Holds on books that are out, or on any edition of a work
+		holds := protected.Group("/holds")
+		{
+			holds.POST("", holdHandler.PlaceHold)
+			holds.GET("/my-holds", holdHandler.GetMyHolds)
+			holds.DELETE("/:id", holdHandler.CancelHold)
+
+			// Admin/Librarian only
+			holds.GET("", middleware.RoleMiddleware("admin", "librarian"), holdHandler.ListHolds)
+		}
+
+		// Branches hold the copies of books
+		branches := protected.Group("/branches")
+		{
+			branches.GET("", branchHandler.ListBranches)
+			branches.GET("/:id", branchHandler.GetBranch)
+
+			// Admin only
+			branches.POST("", middleware.RoleMiddleware("admin"), branchHandler.CreateBranch)
+			branches.PUT("/:id", middleware.RoleMiddleware("admin"), branchHandler.UpdateBranch)
+			branches.DELETE("/:id", middleware.RoleMiddleware("admin"), branchHandler.DeleteBranch)
+		}
+
+		// Books in call number order, as they stand on the shelves
+		shelf := protected.Group("/shelf")
+		{
+			shelf.GET("", shelfHandler.BrowseCallNumber)
+
+			// Admin/Librarian only
+			shelf.GET("/list", middleware.RoleMiddleware("admin", "librarian"), shelfHandler.ShelfList)
+		}
+
+		// Transfers move copies between branches
+		transfers := protected.Group("/transfers", middleware.RoleMiddleware("admin", "librarian"))
+		{
+			transfers.GET("", copyHandler.ListTransfers)
+			transfers.GET("/:id", copyHandler.GetTransfer)
+			transfers.POST("", copyHandler.RequestTransfer)
+			transfers.POST("/:id/ship", copyHandler.ShipTransfer)
+			transfers.POST("/:id/receive", copyHandler.ReceiveTransfer)
+			transfers.POST("/:id/cancel", copyHandler.CancelTransfer)
+		}
+
+		// Stocktakes reconcile the catalog with the shelves of a branch
+		stocktakes := protected.Group("/stocktakes", middleware.RoleMiddleware("admin", "librarian"))
+		{
+			stocktakes.GET("", stocktakeHandler.ListStocktakes)
+			stocktakes.GET("/:id", stocktakeHandler.GetStocktake)
+			stocktakes.POST("", stocktakeHandler.StartStocktake)
+			stocktakes.POST("/:id/scans", stocktakeHandler.Scan)
+			stocktakes.POST("/:id/complete", stocktakeHandler.CompleteStocktake)
+			stocktakes.POST("/:id/cancel", stocktakeHandler.CancelStocktake)
+			stocktakes.GET("/:id/report", stocktakeHandler.Report)
+			stocktakes.POST("/:id/mark-missing", stocktakeHandler.MarkMissing)
+			stocktakes.POST("/:id/fix-location", stocktakeHandler.FixLocation)
+		}
+
+		// Runtime settings: librarians can read, only admins can change
+		settings := protected.Group("/settings", middleware.RoleMiddleware("admin", "librarian"))
+		{
+			settings.GET("", settingsHandler.ListSettings)
+			settings.GET("/history", settingsHandler.GetHistory)
+			settings.GET("/:key", settingsHandler.GetSetting)
+			settings.GET("/:key/history", settingsHandler.GetHistory)
+			settings.PUT("/:key", middleware.RoleMiddleware("admin"), settingsHandler.UpdateSetting)
+			settings.DELETE("/:key", middleware.RoleMiddleware("admin"), settingsHandler.ResetSetting)
 		}
 	}
 
 	// Start server
 	srv := &http.Server{
-		Addr:         ":" + cfg.AppPort,
+		Addr:         ":" + cfg.Server.Port,
 		Handler:      router,
-		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: cfg.WriteTimeout,
-		IdleTimeout:  cfg.IdleTimeout,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
 	// Graceful shutdown
 	go func() {
-		log.Printf("Server starting on port %s", cfg.AppPort)
+		log.Printf("Server starting on port %s", cfg.Server.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start server: %v", err)
 		}
@@ -143,6 +368,9 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	if err := importService.Shutdown(ctx); err != nil {
+		log.Printf("Import jobs did not stop in time: %v", err)
+	}
 
 	log.Println("Server exited gracefully")
 }
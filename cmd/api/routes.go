@@ -12,9 +12,9 @@ func registerSystemRoutes(router *gin.Engine, db *gorm.DB, cfg *configs.Config)
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "healthy",
-			"app":     cfg.AppName,
-			"version": cfg.AppVersion,
-			"env":     cfg.AppEnv,
+			"app":     cfg.App.Name,
+			"version": cfg.App.Version,
+			"env":     cfg.App.Env,
 		})
 	})
 
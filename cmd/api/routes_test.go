@@ -31,9 +31,11 @@ func setupSystemRoutesTest(t *testing.T) (*gin.Engine, sqlmock.Sqlmock) {
 
 	router := gin.New()
 	registerSystemRoutes(router, gormDB, &configs.Config{
-		AppName:    "Library Management API",
-		AppVersion: "1.0.0",
-		AppEnv:     "test",
+		App: configs.AppConfig{
+			Name:    "Library Management API",
+			Version: "1.0.0",
+			Env:     "test",
+		},
 	})
 
 	return router, mock
@@ -0,0 +1,76 @@
+// cmd/libctl/authors.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func (a *app) runAuthors(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: authors requires a subcommand", errUsage)
+	}
+
+	switch args[0] {
+	case "link":
+		return a.authorsLink(ctx, args[1:])
+	case "merge":
+		return a.authorsMerge(ctx, args[1:])
+	default:
+		return fmt.Errorf("%w: unknown authors subcommand %q", errUsage, args[0])
+	}
+}
+
+// authorsLink credits authors on books that predate contributor links, using
+// their author statement.
+func (a *app) authorsLink(ctx context.Context, args []string) error {
+	flags := newFlagSet("authors link")
+	apply := flags.Bool("apply", false, "write the links (default: report only)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	result, err := a.authorService.LinkBookAuthors(ctx, *apply)
+	if err != nil {
+		return err
+	}
+
+	return a.out.record(result, [][2]string{
+		{"applied", strconv.FormatBool(result.Applied)},
+		{"books_linked", strconv.Itoa(result.BooksLinked)},
+		{"authors_created", strconv.Itoa(result.AuthorsCreated)},
+	})
+}
+
+func (a *app) authorsMerge(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("%w: authors merge requires <target-id> <duplicate-id>...", errUsage)
+	}
+
+	ids := make([]uint, len(args))
+	for i, arg := range args {
+		id, err := strconv.ParseUint(arg, 10, 32)
+		if err != nil || id == 0 {
+			return fmt.Errorf("%w: invalid author ID %q", errUsage, arg)
+		}
+		ids[i] = uint(id)
+	}
+
+	author, result, err := a.authorService.MergeAuthors(ctx, ids[0], 0, ids[1:])
+	if err != nil {
+		return err
+	}
+
+	merged := make([]string, len(result.MergedAuthorIDs))
+	for i, id := range result.MergedAuthorIDs {
+		merged[i] = formatID(id)
+	}
+	return a.out.record(result, [][2]string{
+		{"author", formatID(author.ID) + " " + author.Name},
+		{"merged_author_ids", strings.Join(merged, ",")},
+		{"contributions_moved", strconv.FormatInt(result.ContributionsMoved, 10)},
+		{"variants_added", strconv.Itoa(result.VariantsAdded)},
+	})
+}
@@ -0,0 +1,233 @@
+// cmd/libctl/books.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/service"
+)
+
+const exportBatchSize = 500
+
+func (a *app) runBooks(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: books requires a subcommand", errUsage)
+	}
+
+	switch args[0] {
+	case "import":
+		return a.booksImport(ctx, args[1:])
+	case "export":
+		return a.booksExport(ctx, args[1:])
+	case "set-stock":
+		return a.booksSetStock(ctx, args[1:])
+	case "normalize-isbn":
+		return a.booksNormalizeISBN(ctx, args[1:])
+	default:
+		return fmt.Errorf("%w: unknown books subcommand %q", errUsage, args[0])
+	}
+}
+
+func (a *app) booksImport(ctx context.Context, args []string) error {
+	flags := newFlagSet("books import")
+	format := flags.String("format", "", "input format: csv, jsonl, json, marc or marcxml (default: from file extension)")
+	mode := flags.String("mode", service.ImportModeCreate, "create skips existing ISBNs, upsert updates them")
+	dryRun := flags.Bool("dry-run", false, "validate and report without writing")
+	batchSize := flags.Int("batch-size", service.DefaultImportBatchSize, "rows per transaction")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("%w: books import requires exactly one file", errUsage)
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rows, err := service.DecodeBookImport(file, *format)
+	if err != nil {
+		return err
+	}
+
+	result, err := a.importService.ImportBooks(ctx, rows, service.BookImportOptions{
+		Format:    *format,
+		Mode:      *mode,
+		DryRun:    *dryRun,
+		BatchSize: *batchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	tableRows := make([][]string, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		tableRows = append(tableRows, []string{
+			strconv.Itoa(row.Line),
+			row.ISBN,
+			row.Status,
+			formatID(row.BookID),
+			row.Error,
+		})
+	}
+
+	if err := a.out.table(result, []string{"LINE", "ISBN", "STATUS", "BOOK ID", "ERROR"}, tableRows); err != nil {
+		return err
+	}
+	if result.Failed > 0 {
+		return fmt.Errorf("%d of %d books failed to import", result.Failed, result.Total)
+	}
+	return nil
+}
+
+func (a *app) booksExport(ctx context.Context, args []string) error {
+	flags := newFlagSet("books export")
+	format := flags.String("format", "", "output format: json, csv, jsonl, xlsx, marc or marcxml (default: from file extension, else json)")
+	columns := flags.String("columns", "", "comma-separated columns for csv, jsonl and xlsx (default: catalog and availability columns)")
+	search := flags.String("search", "", "only export books whose title, author or ISBN matches")
+	sort := flags.String("sort", "created_at_desc", "created_at_desc, created_at_asc, title_asc, title_desc, call_number_asc or call_number_desc")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("%w: books export accepts at most one file", errUsage)
+	}
+
+	var w io.Writer = a.out.w
+	if flags.NArg() == 1 {
+		path := flags.Arg(0)
+		if *format == "" {
+			*format = strings.TrimPrefix(filepath.Ext(path), ".")
+		}
+
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if *format == "" {
+		*format = "json"
+	}
+	switch *format {
+	case "json":
+		return a.exportBooksJSON(ctx, w)
+	case "mrc", service.FormatMARC:
+		_, err := a.marcService.ExportRecords(ctx, w, service.MarcExportOptions{Format: service.FormatMARC, Filter: dto.BookListFilter{Search: *search}, Sort: *sort})
+		return err
+	case "xml", service.FormatMARCXML:
+		_, err := a.marcService.ExportRecords(ctx, w, service.MarcExportOptions{Format: service.FormatMARCXML, Filter: dto.BookListFilter{Search: *search}, Sort: *sort})
+		return err
+	}
+
+	opts := service.BookExportOptions{Format: *format, Filter: dto.BookListFilter{Search: *search}, Sort: *sort}
+	if *columns != "" {
+		opts.Columns = strings.Split(*columns, ",")
+	}
+	if _, err := opts.Normalize(); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	_, err := a.exportService.ExportBooks(ctx, w, opts)
+	return err
+}
+
+func (a *app) exportBooksJSON(ctx context.Context, w io.Writer) error {
+	books := []models.Book{}
+	if err := a.bookRepo.Each(ctx, exportBatchSize, func(batch []models.Book) error {
+		books = append(books, batch...)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(books)
+}
+
+func (a *app) booksSetStock(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("%w: books set-stock requires <id> <total>", errUsage)
+	}
+
+	id, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		return fmt.Errorf("%w: invalid book ID %q", errUsage, args[0])
+	}
+	total, err := strconv.Atoi(args[1])
+	if err != nil || total < 1 {
+		return fmt.Errorf("%w: total must be a positive integer", errUsage)
+	}
+
+	patch := fmt.Appendf(nil, `{"total_copies":%d}`, total)
+	book, _, err := a.bookService.PatchBook(ctx, dto.Actor{}, uint(id), 0, patch)
+	if err != nil {
+		return err
+	}
+
+	return a.out.record(book, [][2]string{
+		{"id", formatID(book.ID)},
+		{"isbn", book.ISBN},
+		{"title", book.Title},
+		{"total_copies", strconv.Itoa(book.TotalCopies)},
+		{"available_copies", strconv.Itoa(book.AvailableCopies)},
+	})
+}
+
+func formatID(id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func (a *app) booksNormalizeISBN(ctx context.Context, args []string) error {
+	flags := newFlagSet("books normalize-isbn")
+	apply := flags.Bool("apply", false, "write the changes (default: report only)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	results, err := a.maintenanceService.NormalizeISBNs(ctx, *apply)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 && a.out.format == formatTable {
+		return a.out.message("all ISBNs are already normalized")
+	}
+
+	rows := make([][]string, 0, len(results))
+	unresolved := 0
+	for _, result := range results {
+		rows = append(rows, []string{formatID(result.BookID), result.From, result.To, result.Status, result.Message})
+		if result.Status == service.ISBNInvalid || result.Status == service.ISBNDuplicate {
+			unresolved++
+		}
+	}
+	if err := a.out.table(results, []string{"BOOK ID", "FROM", "TO", "STATUS", "DETAIL"}, rows); err != nil {
+		return err
+	}
+
+	if unresolved > 0 {
+		return fmt.Errorf("%d ISBNs need manual correction", unresolved)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPrinter_RejectsUnknownFormat(t *testing.T) {
+	_, err := newPrinter(&strings.Builder{}, "yaml")
+
+	assert.ErrorIs(t, err, errUsage)
+}
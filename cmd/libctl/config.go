@@ -0,0 +1,34 @@
+// cmd/libctl/config.go
+package main
+
+import (
+	"fmt"
+
+	"github.com/alpardfm/library-management-api/configs"
+)
+
+// runConfig handles `config` subcommands. It runs without a database so a
+// broken configuration can still be inspected; loadErr is reported after the
+// effective values are printed.
+func runConfig(out *printer, cfg *configs.Config, loadErr error, args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("%w: config requires the print subcommand", errUsage)
+	}
+	if cfg == nil {
+		return loadErr
+	}
+
+	settings := cfg.Describe()
+	rows := make([][]string, 0, len(settings))
+	for _, s := range settings {
+		rows = append(rows, []string{s.Key, s.Env, s.Value, string(s.Source)})
+	}
+	if err := out.table(settings, []string{"KEY", "ENV", "VALUE", "SOURCE"}, rows); err != nil {
+		return err
+	}
+
+	if loadErr != nil {
+		return fmt.Errorf("invalid configuration:\n%w", loadErr)
+	}
+	return nil
+}
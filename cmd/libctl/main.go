@@ -0,0 +1,240 @@
+// cmd/libctl/main.go
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+
+	"github.com/alpardfm/library-management-api/configs"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/database"
+	"github.com/alpardfm/library-management-api/pkg/storage"
+)
+
+const usage = `libctl - administrative tasks for the Library Management API
+
+Usage:
+  libctl [-o json|table] [-config file] <command> [arguments]
+
+Commands:
+  migrate                      Apply database migrations
+  user create                  Create a user with any role
+  user promote <user>          Change the role of a user
+  user activate <user>         Re-enable a deactivated account
+  user deactivate <user>       Disable an account
+  user list                    List users
+  user delete <user>           Deactivate and soft-delete a user without active borrows
+  user restore <user>          Restore a deleted user that was not purged yet
+  books import <file>          Import books from CSV, JSON(L) or MARC (-mode upsert, -dry-run)
+  books export [file]          Export books as JSON, CSV, JSONL, XLSX or MARC
+  books set-stock <id> <total> Change the total copies of a book
+  books normalize-isbn         Rewrite legacy ISBNs as ISBN-13 (-apply to write)
+  authors link                 Credit authors on books without contributors (-apply to write)
+  authors merge <id> <dup>...  Fold duplicate authors into the first one
+  subjects map-genres          Assign subjects from book genres (-create, -apply to write)
+  check                        Run stock and borrow integrity checks
+  sweep overdue                Mark open borrows past their due date as overdue
+  purge                        Delete withdrawn books and anonymize deleted users past
+                               the retention period (-apply to write)
+  report circulation           Print borrow/return statistics for a period
+  report shelf-list [file]     Print books, or the copies at a branch, in shelf order
+                               (-format csv|html to write a list to print)
+  config print                 Show the effective configuration, secrets redacted
+
+Users can be referenced by username or email. The config file defaults to
+$CONFIG_FILE; environment variables override its values.
+`
+
+// errUsage signals that the command line was malformed and usage should be shown.
+var errUsage = errors.New("invalid usage")
+
+type app struct {
+	cfg *configs.Config
+	db  *gorm.DB
+	out *printer
+
+	userRepo   repository.UserRepository
+	bookRepo   repository.BookRepository
+	borrowRepo repository.BorrowRepository
+	branchRepo repository.BranchRepository
+
+	authService        service.AuthService
+	userService        service.UserService
+	bookService        service.BookService
+	importService      service.BookImportService
+	exportService      service.BookExportService
+	marcService        service.MarcService
+	authorService      service.AuthorService
+	subjectService     service.SubjectService
+	borrowService      service.BorrowService
+	maintenanceService service.MaintenanceService
+	retentionService   service.RetentionService
+	shelfService       service.ShelfService
+}
+
+func main() {
+	log.SetFlags(0)
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("warning: failed to load .env: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "libctl: %v\n\n%s", err, usage)
+			os.Exit(2)
+		}
+		log.Fatalf("libctl: %v", err)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("libctl", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	output := flags.String("o", string(formatTable), "output format: json or table")
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	out, err := newPrinter(stdout, *output)
+	if err != nil {
+		return err
+	}
+
+	args = flags.Args()
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	command, rest := args[0], args[1:]
+	if command == "help" {
+		fmt.Fprint(stdout, usage)
+		return nil
+	}
+
+	cfg, err := configs.LoadFile(*configFile)
+	if command == "config" {
+		return runConfig(out, cfg, err, rest)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	a, err := newApp(cfg, out)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	switch command {
+	case "migrate":
+		return a.migrate(ctx)
+	case "user":
+		return a.runUser(ctx, rest)
+	case "books":
+		return a.runBooks(ctx, rest)
+	case "authors":
+		return a.runAuthors(ctx, rest)
+	case "subjects":
+		return a.runSubjects(ctx, rest)
+	case "check":
+		return a.runCheck(ctx, rest)
+	case "sweep":
+		return a.runSweep(ctx, rest)
+	case "report":
+		return a.runReport(ctx, rest)
+	case "purge":
+		return a.runPurge(ctx, rest)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, command)
+	}
+}
+
+func newApp(cfg *configs.Config, out *printer) (*app, error) {
+	db, err := database.Open(&cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &app{
+		cfg:        cfg,
+		db:         db,
+		out:        out,
+		userRepo:   repository.NewUserRepository(db),
+		bookRepo:   repository.NewBookRepository(db),
+		borrowRepo: repository.NewBorrowRepository(db),
+		branchRepo: repository.NewBranchRepository(db),
+	}
+
+	a.authService = service.NewAuthService(a.userRepo, cfg.JWT.Secret, cfg.JWT.Expiry)
+	a.userService = service.NewUserService(db, a.userRepo, a.borrowRepo)
+	authorRepo := repository.NewAuthorRepository(db)
+	subjectRepo := repository.NewSubjectRepository(db)
+	revisionRepo := repository.NewBookRevisionRepository(db)
+	a.bookService = service.NewBookService(db, a.bookRepo, authorRepo, subjectRepo, revisionRepo, repository.NewWorkRepository(db), repository.NewCopyRepository(db))
+	a.authorService = service.NewAuthorService(db, authorRepo)
+	a.subjectService = service.NewSubjectService(db, subjectRepo)
+	settingsService := service.NewSettingsService(db, repository.NewSettingRepository(db), service.BorrowServiceConfig{
+		MaxBooksPerUser: cfg.Circulation.MaxBooksPerUser,
+		BorrowDays:      cfg.Circulation.BorrowDays,
+		FinePerDay:      cfg.Circulation.FinePerDay,
+		ProcessingFee:   cfg.Circulation.ProcessingFee,
+	}, cfg.Settings.RefreshInterval)
+	a.borrowService = service.NewBorrowService(db, a.borrowRepo, a.bookRepo, revisionRepo, a.userRepo, repository.NewHoldRepository(db), repository.NewCopyRepository(db), repository.NewTransferRepository(db), a.branchRepo, repository.NewAccountRepository(db), settingsService)
+	a.maintenanceService = service.NewMaintenanceService(db, a.bookRepo, revisionRepo, a.borrowRepo)
+	store, err := storage.Open(&cfg.Storage)
+	if err != nil {
+		a.close()
+		return nil, err
+	}
+	a.retentionService = service.NewRetentionService(db, a.bookRepo, revisionRepo, a.borrowRepo, a.userRepo, store, cfg.Retention.Period)
+	marcRepo := repository.NewMarcRecordRepository(db)
+	a.importService = service.NewBookImportService(db, a.bookRepo, repository.NewImportJobRepository(db), marcRepo, authorRepo, subjectRepo, revisionRepo, repository.NewCopyRepository(db))
+	a.exportService = service.NewBookExportService(db, a.bookRepo)
+	a.marcService = service.NewMarcService(db, a.bookRepo, marcRepo)
+	a.shelfService = service.NewShelfService(a.bookRepo, repository.NewCopyRepository(db), a.branchRepo)
+
+	return a, nil
+}
+
+func (a *app) close() {
+	if sqlDB, err := a.db.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+}
+
+func (a *app) migrate(ctx context.Context) error {
+	if err := database.AutoMigrate(a.db.WithContext(ctx)); err != nil {
+		return err
+	}
+	return a.out.message("migrations applied")
+}
+
+// newFlagSet builds a subcommand flag set that reports parse errors as usage errors.
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %s: %v", errUsage, flags.Name(), err)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+// cmd/libctl/ops.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"gorm.io/gorm"
+)
+
+const reportDateLayout = "2006-01-02"
+
+func (a *app) runCheck(ctx context.Context, args []string) error {
+	if err := parseFlags(newFlagSet("check"), args); err != nil {
+		return err
+	}
+
+	issues, err := a.maintenanceService.CheckIntegrity(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(issues) == 0 {
+		if a.out.format == formatJSON {
+			return a.out.json(issues)
+		}
+		return a.out.message("no integrity issues found")
+	}
+
+	rows := make([][]string, 0, len(issues))
+	for _, issue := range issues {
+		rows = append(rows, []string{issue.Check, formatID(issue.BookID), issue.Message})
+	}
+
+	if err := a.out.table(issues, []string{"CHECK", "BOOK ID", "DETAIL"}, rows); err != nil {
+		return err
+	}
+	return fmt.Errorf("%d integrity issues found", len(issues))
+}
+
+func (a *app) runSweep(ctx context.Context, args []string) error {
+	if len(args) != 1 || args[0] != "overdue" {
+		return fmt.Errorf("%w: expected \"sweep overdue\"", errUsage)
+	}
+
+	updated, err := a.borrowService.SweepOverdue(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.out.record(map[string]int64{"marked_overdue": updated}, [][2]string{
+		{"marked_overdue", strconv.FormatInt(updated, 10)},
+	})
+}
+
+func (a *app) runReport(ctx context.Context, args []string) error {
+	if len(args) > 0 && args[0] == "shelf-list" {
+		return a.reportShelfList(ctx, args[1:])
+	}
+	if len(args) == 0 || args[0] != "circulation" {
+		return fmt.Errorf("%w: expected \"report circulation\" or \"report shelf-list\"", errUsage)
+	}
+
+	now := time.Now()
+	flags := newFlagSet("report circulation")
+	from := flags.String("from", now.AddDate(0, 0, -30).Format(reportDateLayout), "start date (inclusive), YYYY-MM-DD")
+	to := flags.String("to", now.Format(reportDateLayout), "end date (inclusive), YYYY-MM-DD")
+	if err := parseFlags(flags, args[1:]); err != nil {
+		return err
+	}
+
+	fromDate, err := time.ParseInLocation(reportDateLayout, *from, time.Local)
+	if err != nil {
+		return fmt.Errorf("%w: invalid -from date %q", errUsage, *from)
+	}
+	toDate, err := time.ParseInLocation(reportDateLayout, *to, time.Local)
+	if err != nil {
+		return fmt.Errorf("%w: invalid -to date %q", errUsage, *to)
+	}
+
+	report, err := a.borrowService.CirculationReport(ctx, fromDate, toDate.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+
+	if a.out.format == formatJSON {
+		return a.out.json(report)
+	}
+
+	if err := a.out.record(report, [][2]string{
+		{"period", fmt.Sprintf("%s to %s", *from, *to)},
+		{"borrowed", strconv.FormatInt(report.Borrowed, 10)},
+		{"returned", strconv.FormatInt(report.Returned, 10)},
+		{"currently active", strconv.FormatInt(report.Active, 10)},
+		{"currently overdue", strconv.FormatInt(report.Overdue, 10)},
+	}); err != nil {
+		return err
+	}
+	if len(report.TopBooks) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(a.out.w)
+	rows := make([][]string, 0, len(report.TopBooks))
+	for _, book := range report.TopBooks {
+		rows = append(rows, []string{formatID(book.BookID), book.Title, strconv.FormatInt(book.Borrows, 10)})
+	}
+	return a.out.table(report.TopBooks, []string{"BOOK ID", "TITLE", "BORROWS"}, rows)
+}
+
+func (a *app) reportShelfList(ctx context.Context, args []string) error {
+	flags := newFlagSet("report shelf-list")
+	branchCode := flags.String("branch", "", "list the copies at home at the branch with this code")
+	location := flags.String("location", "", "only list what is shelved at this location")
+	classification := flags.String("classification", "", "only list call numbers of ddc or lcc")
+	from := flags.String("from", "", "start at this call number")
+	to := flags.String("to", "", "end with this call number and those within it")
+	limit := flags.Int("limit", service.DefaultShelfListLimit, "most entries to list")
+	format := flags.String("format", "", "write the list as csv or html (default: -o output)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("%w: report shelf-list accepts at most one file", errUsage)
+	}
+
+	req := dto.ShelfListRequest{
+		Location:       *location,
+		Classification: strings.ToLower(*classification),
+		From:           *from,
+		To:             *to,
+		Limit:          *limit,
+	}
+	if *branchCode != "" {
+		branch, err := a.branchRepo.FindByCode(ctx, strings.ToUpper(*branchCode))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("branch %q not found", *branchCode)
+			}
+			return err
+		}
+		req.BranchID = branch.ID
+	}
+	list, err := a.shelfService.ShelfList(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if *format != "" || flags.NArg() == 1 {
+		var w io.Writer = a.out.w
+		if flags.NArg() == 1 {
+			path := flags.Arg(0)
+			if *format == "" {
+				*format = strings.TrimPrefix(filepath.Ext(path), ".")
+			}
+			file, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			w = file
+		}
+		return service.WriteShelfList(w, strings.ToLower(*format), list)
+	}
+
+	rows := make([][]string, 0, len(list.Entries))
+	for _, entry := range list.Entries {
+		row := []string{entry.CallNumber, entry.Title, entry.Author, entry.ShelfLocation}
+		if req.BranchID != 0 {
+			row = append(row, entry.Barcode, entry.Status)
+		} else {
+			row = append(row, strconv.Itoa(entry.TotalCopies))
+		}
+		rows = append(rows, row)
+	}
+	headers := []string{"CALL NUMBER", "TITLE", "AUTHOR", "LOCATION", "COPIES"}
+	if req.BranchID != 0 {
+		headers = []string{"CALL NUMBER", "TITLE", "AUTHOR", "LOCATION", "BARCODE", "STATUS"}
+	}
+	if err := a.out.table(list, headers, rows); err != nil {
+		return err
+	}
+	if list.Truncated && a.out.format != formatJSON {
+		return a.out.message(fmt.Sprintf("list truncated at %d entries; raise -limit or narrow it", req.Limit))
+	}
+	return nil
+}
+
+func (a *app) runPurge(ctx context.Context, args []string) error {
+	flags := newFlagSet("purge")
+	apply := flags.Bool("apply", false, "write the changes (default: report only)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	report, err := a.retentionService.Purge(ctx, *apply)
+	if err != nil {
+		return err
+	}
+
+	return a.out.record(report, [][2]string{
+		{"cutoff", report.Cutoff.Format(time.RFC3339)},
+		{"applied", strconv.FormatBool(report.Applied)},
+		{"purged books", formatIDs(report.PurgedBooks)},
+		{"detached borrow records", strconv.FormatInt(report.DetachedBorrows, 10)},
+		{"anonymized users", formatIDs(report.AnonymizedUsers)},
+	})
+}
+
+func formatIDs(ids []uint) string {
+	formatted := make([]string, len(ids))
+	for i, id := range ids {
+		formatted[i] = formatID(id)
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,74 @@
+// cmd/libctl/output.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type outputFormat string
+
+const (
+	formatJSON  outputFormat = "json"
+	formatTable outputFormat = "table"
+)
+
+// printer renders command results either as indented JSON or as an aligned table.
+type printer struct {
+	w      io.Writer
+	format outputFormat
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch outputFormat(format) {
+	case formatJSON, formatTable:
+		return &printer{w: w, format: outputFormat(format)}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown output format %q", errUsage, format)
+	}
+}
+
+// table prints v as JSON, or as rows under the given header in table mode.
+func (p *printer) table(v any, header []string, rows [][]string) error {
+	if p.format == formatJSON {
+		return p.json(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// record prints v as JSON, or as aligned key/value pairs in table mode.
+func (p *printer) record(v any, fields [][2]string) error {
+	if p.format == formatJSON {
+		return p.json(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	for _, field := range fields {
+		fmt.Fprintf(tw, "%s:\t%s\n", field[0], field[1])
+	}
+	return tw.Flush()
+}
+
+func (p *printer) message(msg string) error {
+	if p.format == formatJSON {
+		return p.json(map[string]string{"message": msg})
+	}
+
+	_, err := fmt.Fprintln(p.w, msg)
+	return err
+}
+
+func (p *printer) json(v any) error {
+	encoder := json.NewEncoder(p.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
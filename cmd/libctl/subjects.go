@@ -0,0 +1,57 @@
+// cmd/libctl/subjects.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+func (a *app) runSubjects(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: subjects requires a subcommand", errUsage)
+	}
+
+	switch args[0] {
+	case "map-genres":
+		return a.subjectsMapGenres(ctx, args[1:])
+	default:
+		return fmt.Errorf("%w: unknown subjects subcommand %q", errUsage, args[0])
+	}
+}
+
+// subjectsMapGenres assigns subjects to books from their free-text genre and
+// prints how each distinct genre was mapped.
+func (a *app) subjectsMapGenres(ctx context.Context, args []string) error {
+	flags := newFlagSet("subjects map-genres")
+	apply := flags.Bool("apply", false, "write the assignments (default: report only)")
+	create := flags.Bool("create", false, "create a root subject for each genre that matches none")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	result, err := a.subjectService.MapGenres(ctx, *apply, *create)
+	if err != nil {
+		return err
+	}
+	if len(result.Genres) == 0 && a.out.format == formatTable {
+		return a.out.message("no books have a genre")
+	}
+
+	rows := make([][]string, 0, len(result.Genres))
+	for _, genre := range result.Genres {
+		detail := genre.Reason
+		if genre.Created {
+			detail = "created"
+		}
+		rows = append(rows, []string{
+			genre.Genre,
+			strconv.FormatInt(genre.Books, 10),
+			formatID(genre.SubjectID),
+			genre.Subject,
+			strconv.FormatInt(genre.Linked, 10),
+			detail,
+		})
+	}
+	return a.out.table(result, []string{"GENRE", "BOOKS", "SUBJECT ID", "SUBJECT", "LINKED", "DETAIL"}, rows)
+}
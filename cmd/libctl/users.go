@@ -0,0 +1,167 @@
+// cmd/libctl/users.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+)
+
+func (a *app) runUser(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: user requires a subcommand", errUsage)
+	}
+
+	switch args[0] {
+	case "create":
+		return a.userCreate(ctx, args[1:])
+	case "promote":
+		return a.userPromote(ctx, args[1:])
+	case "activate":
+		return a.userSetActive(ctx, args[1:], true)
+	case "deactivate":
+		return a.userSetActive(ctx, args[1:], false)
+	case "list":
+		return a.userList(ctx, args[1:])
+	case "delete":
+		return a.userRemoval(ctx, args[1:], a.userService.FindUser, a.userService.DeleteUser)
+	case "restore":
+		return a.userRemoval(ctx, args[1:], a.userService.FindUserWithDeleted, a.userService.RestoreUser)
+	default:
+		return fmt.Errorf("%w: unknown user subcommand %q", errUsage, args[0])
+	}
+}
+
+func (a *app) userCreate(ctx context.Context, args []string) error {
+	flags := newFlagSet("user create")
+	username := flags.String("username", "", "username")
+	email := flags.String("email", "", "email address")
+	password := flags.String("password", "", "password (prefer -password-stdin)")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from the first line of stdin")
+	role := flags.String("role", string(models.RoleMember), "role: admin, librarian or member")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *passwordStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("read password from stdin: %w", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+
+	req := dto.RegisterRequest{
+		Username: *username,
+		Email:    *email,
+		Password: *password,
+		Role:     *role,
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+
+	user, err := a.authService.Register(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return a.printUser(user)
+}
+
+func (a *app) userPromote(ctx context.Context, args []string) error {
+	flags := newFlagSet("user promote")
+	role := flags.String("role", string(models.RoleAdmin), "new role: admin, librarian or member")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("%w: user promote requires exactly one user", errUsage)
+	}
+
+	user, err := a.userService.ChangeRole(ctx, flags.Arg(0), models.UserRole(*role))
+	if err != nil {
+		return err
+	}
+
+	return a.printUser(user)
+}
+
+func (a *app) userSetActive(ctx context.Context, args []string, active bool) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: expected exactly one user", errUsage)
+	}
+
+	user, err := a.userService.SetActive(ctx, args[0], active)
+	if err != nil {
+		return err
+	}
+
+	return a.printUser(user)
+}
+
+// userRemoval deletes or restores the one user named in args.
+func (a *app) userRemoval(ctx context.Context, args []string, find func(context.Context, string) (*models.User, error), apply func(context.Context, uint) (*models.User, error)) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: expected exactly one user", errUsage)
+	}
+
+	user, err := find(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	user, err = apply(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return a.printUser(user)
+}
+
+func (a *app) userList(ctx context.Context, args []string) error {
+	flags := newFlagSet("user list")
+	page := flags.Int("page", 1, "page number")
+	limit := flags.Int("limit", 50, "users per page")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *page < 1 || *limit < 1 {
+		return fmt.Errorf("%w: page and limit must be positive", errUsage)
+	}
+
+	users, _, err := a.userService.ListUsers(ctx, *page, *limit)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(users))
+	for _, user := range users {
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(user.ID), 10),
+			user.Username,
+			user.Email,
+			string(user.Role),
+			strconv.FormatBool(user.IsActive),
+		})
+	}
+
+	return a.out.table(users, []string{"ID", "USERNAME", "EMAIL", "ROLE", "ACTIVE"}, rows)
+}
+
+func (a *app) printUser(user *models.User) error {
+	return a.out.record(user, [][2]string{
+		{"id", strconv.FormatUint(uint64(user.ID), 10)},
+		{"username", user.Username},
+		{"email", user.Email},
+		{"role", string(user.Role)},
+		{"active", strconv.FormatBool(user.IsActive)},
+	})
+}
@@ -2,90 +2,115 @@
 package configs
 
 import (
+	"errors"
 	"os"
-	"strconv"
 	"time"
+
+	"github.com/alpardfm/library-management-api/pkg/database"
+	"github.com/alpardfm/library-management-api/pkg/storage"
+)
+
+// Environment names accepted in APP_ENV.
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvStaging     = "staging"
+	EnvProduction  = "production"
 )
 
+// Config is the effective application configuration.
+//
+// Every setting has a file key (the `config` tags joined with dots, e.g.
+// "circulation.borrow_days"), an environment variable and a default.
+// Precedence is default < config file < environment.
 type Config struct {
-	// Server
-	AppName    string
-	AppEnv     string
-	AppPort    string
-	AppVersion string
-
-	// Database
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	DBSSLMode  string
-
-	// JWT
-	JWTSecret string
-	JWTExpiry time.Duration
-
-	// Server Timeouts
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
-
-	// Application
-	MaxBooksPerUser int
-	BorrowDays      int
-	FinePerDay      int
+	App         AppConfig         `config:"app"`
+	Server      ServerConfig      `config:"server"`
+	Database    database.Config   `config:"database"`
+	JWT         JWTConfig         `config:"jwt"`
+	Circulation CirculationConfig `config:"circulation"`
+	Settings    SettingsConfig    `config:"settings"`
+	Storage     storage.Config    `config:"storage"`
+	Covers      CoversConfig      `config:"covers"`
+	Retention   RetentionConfig   `config:"retention"`
+
+	// File is the config file that was loaded, if any.
+	File string `config:"-"`
+
+	sources map[string]Source
 }
 
-func Load() *Config {
-	return &Config{
-		// Server
-		AppName:    getEnv("APP_NAME", "Library Management API"),
-		AppEnv:     getEnv("APP_ENV", "development"),
-		AppPort:    getEnv("APP_PORT", "8080"),
-		AppVersion: getEnv("APP_VERSION", "1.0.0"),
-
-		// Database
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "password"),
-		DBName:     getEnv("DB_NAME", "library_db"),
-		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
-
-		// JWT
-		JWTSecret: getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
-		JWTExpiry: parseDuration(getEnv("JWT_EXPIRY", "24h")),
-
-		// Server Timeouts
-		ReadTimeout:  parseDuration(getEnv("READ_TIMEOUT", "10s")),
-		WriteTimeout: parseDuration(getEnv("WRITE_TIMEOUT", "10s")),
-		IdleTimeout:  parseDuration(getEnv("IDLE_TIMEOUT", "60s")),
-
-		// Application
-		MaxBooksPerUser: parseInt(getEnv("MAX_BOOKS_PER_USER", "5")),
-		BorrowDays:      parseInt(getEnv("BORROW_DAYS", "14")),
-		FinePerDay:      parseInt(getEnv("FINE_PER_DAY", "1000")),
-	}
+type AppConfig struct {
+	Name    string `config:"name" env:"APP_NAME" default:"Library Management API"`
+	Env     string `config:"env" env:"APP_ENV" default:"development"`
+	Version string `config:"version" env:"APP_VERSION" default:"1.0.0"`
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
+type ServerConfig struct {
+	Port         string        `config:"port" env:"APP_PORT" default:"8080"`
+	ReadTimeout  time.Duration `config:"read_timeout" env:"READ_TIMEOUT" default:"10s"`
+	WriteTimeout time.Duration `config:"write_timeout" env:"WRITE_TIMEOUT" default:"10s"`
+	IdleTimeout  time.Duration `config:"idle_timeout" env:"IDLE_TIMEOUT" default:"60s"`
 }
 
-func parseInt(value string) int {
-	if i, err := strconv.Atoi(value); err == nil {
-		return i
-	}
-	return 0
+type JWTConfig struct {
+	Secret string        `config:"secret" env:"JWT_SECRET" default:"your-super-secret-jwt-key-change-in-production" secret:"true"`
+	Expiry time.Duration `config:"expiry" env:"JWT_EXPIRY" default:"24h"`
+}
+
+type CirculationConfig struct {
+	MaxBooksPerUser int `config:"max_books_per_user" env:"MAX_BOOKS_PER_USER" default:"5"`
+	BorrowDays      int `config:"borrow_days" env:"BORROW_DAYS" default:"14"`
+	FinePerDay      int `config:"fine_per_day" env:"FINE_PER_DAY" default:"1000"`
+	ProcessingFee   int `config:"processing_fee" env:"PROCESSING_FEE" default:"5000"`
+}
+
+// SettingsConfig controls runtime settings stored in the database.
+type SettingsConfig struct {
+	// RefreshInterval bounds how long a replica may serve a cached value after
+	// another replica changed it.
+	RefreshInterval time.Duration `config:"refresh_interval" env:"SETTINGS_REFRESH_INTERVAL" default:"10s"`
+}
+
+// CoversConfig limits book cover uploads.
+type CoversConfig struct {
+	// MaxSize is the largest accepted cover image, in bytes.
+	MaxSize int `config:"max_size" env:"COVER_MAX_SIZE" default:"5242880"`
 }
 
-func parseDuration(value string) time.Duration {
-	if d, err := time.ParseDuration(value); err == nil {
-		return d
+// RetentionConfig controls when `libctl purge` deletes withdrawn books and
+// anonymizes deleted users.
+type RetentionConfig struct {
+	Period time.Duration `config:"period" env:"RETENTION_PERIOD" default:"8760h"`
+}
+
+// IsProduction reports whether the app runs with APP_ENV=production.
+func (c *Config) IsProduction() bool {
+	return c.App.Env == EnvProduction
+}
+
+// Load reads the file named by CONFIG_FILE (if set) and the environment.
+// See LoadFile.
+func Load() (*Config, error) {
+	return LoadFile(os.Getenv("CONFIG_FILE"))
+}
+
+// LoadFile builds the configuration from defaults, the YAML or TOML file at
+// path (skipped when path is empty) and environment overrides, then validates
+// it. All problems are reported together in the returned error. The config is
+// returned alongside validation errors so callers can still display it.
+func LoadFile(path string) (*Config, error) {
+	cfg := &Config{File: path, sources: make(map[string]Source)}
+
+	var fileValues map[string]string
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fileValues = values
 	}
-	return 0
+
+	loadErr := cfg.load(fileValues, os.LookupEnv)
+	return cfg, errors.Join(loadErr, cfg.Validate())
 }
@@ -0,0 +1,242 @@
+// configs/loader.go
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Source records where the effective value of a setting came from.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+)
+
+const redacted = "[redacted]"
+
+// Setting is one effective configuration value, as shown by `libctl config print`.
+type Setting struct {
+	Key    string `json:"key"`
+	Env    string `json:"env"`
+	Value  string `json:"value"`
+	Source Source `json:"source"`
+}
+
+// field is a leaf of Config reached through its `config` tags.
+type field struct {
+	key    string
+	env    string
+	def    string
+	secret bool
+	value  reflect.Value
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fields lists the leaves of cfg in declaration order.
+func (c *Config) fields() []field {
+	var out []field
+	collectFields(reflect.ValueOf(c).Elem(), "", &out)
+	return out
+}
+
+func collectFields(v reflect.Value, prefix string, out *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := sf.Tag.Get("config")
+		if name == "" || name == "-" || !sf.IsExported() {
+			continue
+		}
+
+		key := prefix + name
+		if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+			collectFields(v.Field(i), key+".", out)
+			continue
+		}
+
+		*out = append(*out, field{
+			key:    key,
+			env:    sf.Tag.Get("env"),
+			def:    sf.Tag.Get("default"),
+			secret: sf.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+}
+
+// load applies defaults, then file values, then environment variables.
+// Unparseable values keep the previous layer's value and are reported.
+func (c *Config) load(fileValues map[string]string, lookupEnv func(string) (string, bool)) error {
+	var errs []error
+	known := make(map[string]bool)
+
+	for _, f := range c.fields() {
+		known[f.key] = true
+
+		if err := f.set(f.def); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid default %q: %w", f.key, f.def, err))
+		}
+		c.sources[f.key] = SourceDefault
+
+		if raw, ok := fileValues[f.key]; ok {
+			if err := f.set(raw); err != nil {
+				errs = append(errs, fmt.Errorf("%s (in %s): %w", f.key, c.File, err))
+			} else {
+				c.sources[f.key] = SourceFile
+			}
+		}
+
+		if f.env == "" {
+			continue
+		}
+		if raw, ok := lookupEnv(f.env); ok && raw != "" {
+			if err := f.set(raw); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+			} else {
+				c.sources[f.key] = SourceEnv
+			}
+		}
+	}
+
+	var unknown []string
+	for key := range fileValues {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, fmt.Errorf("%s (in %s): unknown setting", key, c.File))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f field) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	switch {
+	case f.value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q (use e.g. 30s, 15m, 24h)", raw)
+		}
+		f.value.SetInt(int64(d))
+	case f.value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		f.value.SetInt(int64(n))
+	case f.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		f.value.SetBool(b)
+	case f.value.Kind() == reflect.String:
+		f.value.SetString(raw)
+	default:
+		return fmt.Errorf("unsupported setting type %s", f.value.Type())
+	}
+	return nil
+}
+
+func (f field) String() string {
+	if f.value.Type() == durationType {
+		return time.Duration(f.value.Int()).String()
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+// label names a setting in validation messages, e.g. "jwt.secret (JWT_SECRET)".
+func (c *Config) label(key string) string {
+	for _, f := range c.fields() {
+		if f.key == key && f.env != "" {
+			return fmt.Sprintf("%s (%s)", key, f.env)
+		}
+	}
+	return key
+}
+
+// Describe returns every effective setting with secrets redacted.
+func (c *Config) Describe() []Setting {
+	fields := c.fields()
+	settings := make([]Setting, 0, len(fields))
+	for _, f := range fields {
+		value := f.String()
+		if f.secret && value != "" {
+			value = redacted
+		}
+
+		source := c.sources[f.key]
+		if source == "" {
+			source = SourceDefault
+		}
+
+		settings = append(settings, Setting{Key: f.key, Env: f.env, Value: value, Source: source})
+	}
+	return settings
+}
+
+// readFile parses a YAML or TOML config file into dotted keys.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format %q (use .yaml, .yml or .toml)", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten(doc, "", values); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func flatten(doc map[string]any, prefix string, out map[string]string) error {
+	for name, value := range doc {
+		key := prefix + name
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flatten(v, key+".", out); err != nil {
+				return err
+			}
+		case nil:
+			// An empty value leaves the default in place.
+		case []any:
+			return fmt.Errorf("%s: lists are not supported", key)
+		case time.Time:
+			return fmt.Errorf("%s: dates are not supported", key)
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// configs/validate.go
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/alpardfm/library-management-api/pkg/database"
+	"github.com/alpardfm/library-management-api/pkg/storage"
+)
+
+// Insecure values shipped as defaults; production refuses to start with them.
+const (
+	defaultJWTSecret  = "your-super-secret-jwt-key-change-in-production"
+	defaultDBPassword = "password"
+
+	minProductionJWTSecretLength = 32
+)
+
+var (
+	appEnvs        = []string{EnvDevelopment, EnvTest, EnvStaging, EnvProduction}
+	dbDrivers      = []string{database.DriverPostgres, database.DriverSQLite}
+	dbSSLModes     = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	storageDrivers = []string{storage.DriverLocal, storage.DriverS3}
+)
+
+// Validate checks every setting and returns all problems joined together.
+func (c *Config) Validate() error {
+	v := &validator{cfg: c}
+
+	v.oneOf("app.env", c.App.Env, appEnvs)
+	v.port("server.port", c.Server.Port)
+	v.positive("server.read_timeout", int64(c.Server.ReadTimeout))
+	v.positive("server.write_timeout", int64(c.Server.WriteTimeout))
+	v.positive("server.idle_timeout", int64(c.Server.IdleTimeout))
+
+	v.oneOf("database.driver", c.Database.Driver, dbDrivers)
+	switch c.Database.Driver {
+	case database.DriverPostgres:
+		v.required("database.host", c.Database.Host)
+		v.port("database.port", c.Database.Port)
+		v.required("database.user", c.Database.User)
+		v.required("database.name", c.Database.DBName)
+		v.oneOf("database.sslmode", c.Database.SSLMode, dbSSLModes)
+	case database.DriverSQLite:
+		v.required("database.path", c.Database.Path)
+	}
+	if c.Database.StatementTimeout < 0 {
+		v.fail("database.statement_timeout", "must not be negative")
+	}
+
+	v.required("jwt.secret", c.JWT.Secret)
+	v.positive("jwt.expiry", int64(c.JWT.Expiry))
+
+	v.atLeast("circulation.max_books_per_user", c.Circulation.MaxBooksPerUser, 1)
+	v.atLeast("circulation.borrow_days", c.Circulation.BorrowDays, 1)
+	v.atLeast("circulation.fine_per_day", c.Circulation.FinePerDay, 0)
+	v.atLeast("circulation.processing_fee", c.Circulation.ProcessingFee, 0)
+	v.positive("settings.refresh_interval", int64(c.Settings.RefreshInterval))
+
+	v.oneOf("storage.driver", c.Storage.Driver, storageDrivers)
+	switch c.Storage.Driver {
+	case storage.DriverLocal:
+		v.required("storage.dir", c.Storage.Dir)
+	case storage.DriverS3:
+		v.required("storage.endpoint", c.Storage.Endpoint)
+		v.required("storage.bucket", c.Storage.Bucket)
+		v.required("storage.access_key", c.Storage.AccessKey)
+		v.required("storage.secret_key", c.Storage.SecretKey)
+	}
+	v.atLeast("covers.max_size", c.Covers.MaxSize, 1)
+	v.positive("retention.period", int64(c.Retention.Period))
+
+	if c.IsProduction() {
+		if c.JWT.Secret == defaultJWTSecret {
+			v.fail("jwt.secret", "must be changed from the default in production")
+		} else if len(c.JWT.Secret) < minProductionJWTSecretLength {
+			v.fail("jwt.secret", fmt.Sprintf("must be at least %d characters in production", minProductionJWTSecretLength))
+		}
+		if c.Database.Driver == database.DriverPostgres && c.Database.Password == defaultDBPassword {
+			v.fail("database.password", "must be changed from the default in production")
+		}
+	}
+
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	cfg  *Config
+	errs []error
+}
+
+func (v *validator) fail(key, msg string) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", v.cfg.label(key), msg))
+}
+
+func (v *validator) required(key, value string) {
+	if value == "" {
+		v.fail(key, "is required")
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed []string) {
+	if !slices.Contains(allowed, value) {
+		v.fail(key, fmt.Sprintf("must be one of %v, got %q", allowed, value))
+	}
+}
+
+func (v *validator) port(key, value string) {
+	if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+		v.fail(key, fmt.Sprintf("must be a port number between 1 and 65535, got %q", value))
+	}
+}
+
+func (v *validator) positive(key string, value int64) {
+	if value <= 0 {
+		v.fail(key, "must be greater than zero")
+	}
+}
+
+func (v *validator) atLeast(key string, value, min int) {
+	if value < min {
+		v.fail(key, fmt.Sprintf("must be at least %d, got %d", min, value))
+	}
+}
@@ -0,0 +1,58 @@
+// internal/dto/author.go
+package dto
+
+// ContributorRequest credits an author on a book, either an existing author by
+// ID or by name, which is matched against author names and variants and
+// creates a new author when nothing matches. Role defaults to author.
+type ContributorRequest struct {
+	AuthorID uint   `json:"author_id,omitempty"`
+	Name     string `json:"name,omitempty" binding:"required_without=AuthorID,max=255"`
+	Role     string `json:"role,omitempty" binding:"omitempty,oneof=author editor translator illustrator"`
+}
+
+type CreateAuthorRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+	// SortName defaults to the inverted name, e.g. "Rowling, J. K.".
+	SortName string   `json:"sort_name,omitempty" binding:"max=255"`
+	Bio      string   `json:"bio,omitempty"`
+	Variants []string `json:"variants,omitempty" binding:"dive,required,max=255"`
+}
+
+type UpdateAuthorRequest struct {
+	Name     string `json:"name,omitempty" binding:"max=255"`
+	SortName string `json:"sort_name,omitempty" binding:"max=255"`
+	Bio      string `json:"bio,omitempty"`
+}
+
+type AuthorVariantRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+type MergeAuthorsRequest struct {
+	// AuthorIDs are the duplicates folded into the author in the URL.
+	AuthorIDs []uint `json:"author_ids" binding:"required,min=1,dive,gt=0"`
+}
+
+// AuthorWork is a book an author is credited on, with their roles.
+type AuthorWork struct {
+	BookID          uint     `json:"book_id"`
+	ISBN            string   `json:"isbn"`
+	Title           string   `json:"title"`
+	Author          string   `json:"author"`
+	PublicationYear int      `json:"publication_year,omitempty"`
+	Roles           []string `json:"roles"`
+}
+
+type AuthorMergeResult struct {
+	MergedAuthorIDs    []uint `json:"merged_author_ids"`
+	ContributionsMoved int64  `json:"contributions_moved"`
+	VariantsAdded      int    `json:"variants_added"`
+}
+
+// AuthorLinkResult reports a backfill of contributor links from the author
+// statement of existing books.
+type AuthorLinkResult struct {
+	Applied        bool `json:"applied"`
+	BooksLinked    int  `json:"books_linked"`
+	AuthorsCreated int  `json:"authors_created"`
+}
@@ -2,24 +2,83 @@
 package dto
 
 type CreateBookRequest struct {
-	ISBN            string `json:"isbn" binding:"required,min=10,max=13"`
-	Title           string `json:"title" binding:"required"`
-	Author          string `json:"author" binding:"required"`
+	// ISBN accepts ISBN-10 or ISBN-13, with or without hyphens; it is stored as ISBN-13.
+	ISBN  string `json:"isbn" binding:"required,max=32"`
+	Title string `json:"title" binding:"required"`
+	// Author is the author statement shown with the book. It may be omitted
+	// when Contributors are given; it is then built from their names.
+	Author          string `json:"author" binding:"required_without=Contributors"`
 	Publisher       string `json:"publisher,omitempty"`
-	PublicationYear int    `json:"publication_year,omitempty" binding:"gte=1000,lte=2024"`
+	PublicationYear int    `json:"publication_year,omitempty" binding:"omitempty,gte=1000,lte=2024"`
 	Genre           string `json:"genre,omitempty"`
-	Description     string `json:"description,omitempty"`
-	TotalCopies     int    `json:"total_copies" binding:"gte=1"`
+	// Language is an ISO 639-2 code such as "eng".
+	Language string `json:"language,omitempty" binding:"omitempty,len=3,alpha"`
+	// ItemType defaults to "book".
+	ItemType    string `json:"item_type,omitempty" binding:"omitempty,oneof=book ebook audiobook periodical video music map"`
+	Description string `json:"description,omitempty"`
+	// TotalCopies is at least 1 for a new book; a book whose last copy was
+	// written off has none.
+	TotalCopies int `json:"total_copies" binding:"gte=0"`
+	// Contributors default to one author per ";"-separated name in Author.
+	Contributors []ContributorRequest `json:"contributors,omitempty" binding:"dive"`
+	// SubjectIDs default to the subject whose name or alias matches Genre.
+	SubjectIDs []uint `json:"subject_ids,omitempty" binding:"dive,gt=0"`
+	// WorkID groups the book with the other editions of a work.
+	WorkID uint `json:"work_id,omitempty"`
+	// Classification is the scheme of CallNumber, "ddc" or "lcc"; it is
+	// told from the call number when omitted.
+	Classification string `json:"classification,omitempty" binding:"omitempty,oneof=ddc lcc"`
+	CallNumber     string `json:"call_number,omitempty" binding:"max=100"`
+	ShelfLocation  string `json:"shelf_location,omitempty" binding:"max=100"`
+	// Price is the replacement cost of a copy.
+	Price int `json:"price,omitempty" binding:"gte=0"`
 }
 
+// UpdateBookRequest holds the fields an import changes on an existing book;
+// empty fields keep their stored values.
 type UpdateBookRequest struct {
+	ISBN            string `json:"isbn,omitempty" binding:"max=32"`
 	Title           string `json:"title,omitempty"`
 	Author          string `json:"author,omitempty"`
 	Publisher       string `json:"publisher,omitempty"`
 	PublicationYear int    `json:"publication_year,omitempty" binding:"omitempty,gte=1000,lte=2024"`
 	Genre           string `json:"genre,omitempty"`
+	Language        string `json:"language,omitempty" binding:"omitempty,len=3,alpha"`
+	ItemType        string `json:"item_type,omitempty" binding:"omitempty,oneof=book ebook audiobook periodical video music map"`
 	Description     string `json:"description,omitempty"`
 	TotalCopies     int    `json:"total_copies,omitempty" binding:"omitempty,gte=1"`
+	Price           int    `json:"price,omitempty" binding:"gte=0"`
+	// Contributors, when present, replace the book's contributor list.
+	Contributors []ContributorRequest `json:"contributors,omitempty" binding:"dive"`
+	// SubjectIDs, when present, replace the book's subjects; [] clears them.
+	SubjectIDs []uint `json:"subject_ids,omitempty" binding:"dive,gt=0"`
+}
+
+// FieldChange is a field of a resource changed by an update, with its old and
+// new values.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// RemoveBookRequest takes a book out of the catalog. Status defaults to
+// "withdrawn".
+type RemoveBookRequest struct {
+	Status string `json:"status,omitempty" binding:"omitempty,oneof=withdrawn archived"`
+	Reason string `json:"reason,omitempty" binding:"max=255"`
+}
+
+// RevertBookRequest names the version of a book to bring back.
+type RevertBookRequest struct {
+	Version uint `json:"version" binding:"required,gt=0"`
+}
+
+// Actor is who makes a change and in which request, as recorded in the
+// history of what it changes. UserID is 0 for changes made with libctl.
+type Actor struct {
+	UserID    uint
+	RequestID string
 }
 
 type BookResponse struct {
@@ -30,7 +89,59 @@ type BookResponse struct {
 	Publisher       string `json:"publisher,omitempty"`
 	PublicationYear int    `json:"publication_year,omitempty"`
 	Genre           string `json:"genre,omitempty"`
+	Language        string `json:"language,omitempty"`
+	ItemType        string `json:"item_type"`
 	Description     string `json:"description,omitempty"`
 	TotalCopies     int    `json:"total_copies"`
 	AvailableCopies int    `json:"available_copies"`
+	// Cover is absent for books without a cover image.
+	Cover *BookCover `json:"cover,omitempty"`
+}
+
+// BookCover holds the URLs of a cover image and its thumbnails by size
+// ("small", "medium" and "large").
+type BookCover struct {
+	URL        string            `json:"url"`
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
+// BookListFilter narrows a book listing. Each list matches any of its values;
+// different fields must all match.
+type BookListFilter struct {
+	Search string
+	// SearchMode is how Search matches: "substring" (the default) or
+	// "fulltext".
+	SearchMode string
+	// SubjectIDs match books assigned to the subjects or their descendants.
+	SubjectIDs []uint
+	// AuthorIDs match books crediting the authors in any role.
+	AuthorIDs  []uint
+	Genres     []string
+	Publishers []string
+	YearFrom   int
+	YearTo     int
+	Languages  []string
+	ItemTypes  []string
+	// Available, when set, keeps books with (true) or without (false) a copy
+	// on the shelf.
+	Available *bool
+	// CollapseWorks lists one edition per work, with the number of matching
+	// editions and of those available.
+	CollapseWorks bool
+}
+
+// FacetValue is one value of a facet and the number of matching books.
+// Label is the display name when Value is an ID or code.
+type FacetValue struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+// BookSuggestion completes a search: a book title or an author name.
+type BookSuggestion struct {
+	Kind     string `json:"kind"`
+	Text     string `json:"text"`
+	BookID   uint   `json:"book_id,omitempty"`
+	AuthorID uint   `json:"author_id,omitempty"`
 }
@@ -7,10 +7,30 @@ type BorrowBookRequest struct {
 	BookID  uint      `json:"book_id" binding:"required"`
 	UserID  uint      `json:"user_id,omitempty"` // Admin bisa specify user lain
 	DueDate time.Time `json:"due_date,omitempty"`
+	// BranchID is the branch the copy is checked out at; without it any
+	// available copy is lent.
+	BranchID uint `json:"branch_id,omitempty"`
 }
 
 type ReturnBookRequest struct {
 	BorrowRecordID uint `json:"borrow_record_id" binding:"required"`
+	// BranchID is the branch the copy is returned at, its home branch when
+	// unset. Copies returned elsewhere are sent home.
+	BranchID uint `json:"branch_id,omitempty"`
+}
+
+// DeclareLostRequest closes a loan whose copy the borrower lost.
+type DeclareLostRequest struct {
+	BorrowRecordID uint   `json:"borrow_record_id" binding:"required"`
+	Note           string `json:"note,omitempty" binding:"max=255"`
+}
+
+// ReturnDamagedRequest checks in the copy of a loan that came back damaged
+// beyond use, at BranchID as in ReturnBookRequest.
+type ReturnDamagedRequest struct {
+	BorrowRecordID uint   `json:"borrow_record_id" binding:"required"`
+	BranchID       uint   `json:"branch_id,omitempty"`
+	Note           string `json:"note,omitempty" binding:"max=255"`
 }
 
 type BorrowRecordResponse struct {
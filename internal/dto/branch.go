@@ -0,0 +1,27 @@
+package dto
+
+type BranchRequest struct {
+	Code    string `json:"code" binding:"required,max=20"`
+	Name    string `json:"name" binding:"required,max=255"`
+	Address string `json:"address,omitempty"`
+}
+
+// AddCopyRequest registers a copy of a book at its home branch. An existing
+// unassigned copy on the shelf is registered, unless New adds one to the
+// book's stock. ShelfLocation is set when the copy is shelved elsewhere than
+// the book.
+type AddCopyRequest struct {
+	Barcode       string `json:"barcode" binding:"required,max=32"`
+	BranchID      uint   `json:"branch_id" binding:"required"`
+	New           bool   `json:"new,omitempty"`
+	ShelfLocation string `json:"shelf_location,omitempty" binding:"max=100"`
+}
+
+// TransferRequest asks for an available copy to be sent to another branch,
+// for good when Permanent is set.
+type TransferRequest struct {
+	CopyID     uint   `json:"copy_id" binding:"required"`
+	ToBranchID uint   `json:"to_branch_id" binding:"required"`
+	Permanent  bool   `json:"permanent,omitempty"`
+	Note       string `json:"note,omitempty" binding:"max=255"`
+}
@@ -0,0 +1,9 @@
+// internal/dto/hold.go
+package dto
+
+// PlaceHoldRequest queues for a copy of one book, or of any edition of a
+// work; exactly one of the two is set.
+type PlaceHoldRequest struct {
+	BookID uint `json:"book_id,omitempty" binding:"required_without=WorkID,excluded_with=WorkID"`
+	WorkID uint `json:"work_id,omitempty"`
+}
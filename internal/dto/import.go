@@ -0,0 +1,21 @@
+// internal/dto/import.go
+package dto
+
+type BookImportRowResult struct {
+	Line   int    `json:"line"`
+	ISBN   string `json:"isbn"`
+	Status string `json:"status"`
+	BookID uint   `json:"book_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BookImportResult struct {
+	DryRun    bool                  `json:"dry_run"`
+	Total     int                   `json:"total"`
+	Created   int                   `json:"created"`
+	Updated   int                   `json:"updated"`
+	Unchanged int                   `json:"unchanged"`
+	Skipped   int                   `json:"skipped"`
+	Failed    int                   `json:"failed"`
+	Rows      []BookImportRowResult `json:"rows"`
+}
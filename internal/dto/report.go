@@ -0,0 +1,47 @@
+// internal/dto/report.go
+package dto
+
+import "time"
+
+type CirculationReport struct {
+	From     time.Time             `json:"from"`
+	To       time.Time             `json:"to"`
+	Borrowed int64                 `json:"borrowed"`
+	Returned int64                 `json:"returned"`
+	Active   int64                 `json:"active"`
+	Overdue  int64                 `json:"overdue"`
+	TopBooks []BookCirculationStat `json:"top_books"`
+}
+
+type BookCirculationStat struct {
+	BookID  uint   `json:"book_id"`
+	Title   string `json:"title"`
+	Borrows int64  `json:"borrows"`
+}
+
+type IntegrityIssue struct {
+	Check   string `json:"check"`
+	BookID  uint   `json:"book_id,omitempty"`
+	Message string `json:"message"`
+}
+
+// ISBNNormalization reports what happened to one stored ISBN that was not in
+// canonical ISBN-13 form.
+type ISBNNormalization struct {
+	BookID  uint   `json:"book_id"`
+	From    string `json:"from"`
+	To      string `json:"to,omitempty"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// PurgeReport lists what a retention purge deleted or anonymized, or would
+// have when it ran as a dry run. DetachedBorrows counts the borrow records of
+// the purged books, which keep their title and ISBN.
+type PurgeReport struct {
+	Cutoff          time.Time `json:"cutoff"`
+	Applied         bool      `json:"applied"`
+	PurgedBooks     []uint    `json:"purged_books"`
+	DetachedBorrows int64     `json:"detached_borrows"`
+	AnonymizedUsers []uint    `json:"anonymized_users"`
+}
@@ -0,0 +1,32 @@
+// internal/dto/setting.go
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type UpdateSettingRequest struct {
+	Value  json.RawMessage `json:"value" binding:"required"`
+	Reason string          `json:"reason,omitempty" binding:"max=255"`
+}
+
+type ResetSettingRequest struct {
+	Reason string `json:"reason,omitempty" binding:"max=255"`
+}
+
+// SettingResponse describes a runtime setting and its effective value.
+// Overridden is false when the value comes from the application config.
+type SettingResponse struct {
+	Key         string     `json:"key"`
+	Type        string     `json:"type"`
+	Description string     `json:"description"`
+	Value       any        `json:"value"`
+	Default     any        `json:"default"`
+	Min         *int       `json:"min,omitempty"`
+	Max         *int       `json:"max,omitempty"`
+	Overridden  bool       `json:"overridden"`
+	UpdatedBy   *uint      `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	Version     uint       `json:"version"`
+}
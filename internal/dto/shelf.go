@@ -0,0 +1,70 @@
+// internal/dto/shelf.go
+package dto
+
+import "time"
+
+// ShelfBrowseRequest says how much of the shelf to show on either side of a
+// position. With BranchID only books with a copy at home there count.
+type ShelfBrowseRequest struct {
+	BranchID uint
+	Before   int
+	After    int
+}
+
+// ShelfView is a stretch of shelf in call number order: the books before a
+// position, the book at it when browsing from a book, and the books after it.
+type ShelfView struct {
+	Classification string       `json:"classification"`
+	CallNumber     string       `json:"call_number"`
+	BranchID       uint         `json:"branch_id,omitempty"`
+	Before         []ShelfEntry `json:"before"`
+	Current        *ShelfEntry  `json:"current,omitempty"`
+	After          []ShelfEntry `json:"after"`
+}
+
+// ShelfListRequest selects the part of the collection a shelf list covers.
+// From and To are call numbers bounding it; To includes the call numbers
+// within it, so 823 runs through 823.914. Location matches the shelf location
+// case-insensitively.
+type ShelfListRequest struct {
+	BranchID       uint
+	Location       string
+	Classification string
+	From           string
+	To             string
+	Limit          int
+}
+
+// ShelfList lists books in shelf order, or with a branch the copies shelved
+// there. Truncated is set when there were more than the limit.
+type ShelfList struct {
+	GeneratedAt    time.Time    `json:"generated_at"`
+	BranchID       uint         `json:"branch_id,omitempty"`
+	BranchCode     string       `json:"branch_code,omitempty"`
+	BranchName     string       `json:"branch_name,omitempty"`
+	Location       string       `json:"location,omitempty"`
+	Classification string       `json:"classification,omitempty"`
+	From           string       `json:"from,omitempty"`
+	To             string       `json:"to,omitempty"`
+	Entries        []ShelfEntry `json:"entries"`
+	Truncated      bool         `json:"truncated"`
+}
+
+// ShelfEntry is a book as found on the shelf, or one copy of it in shelf
+// lists of a branch. Available says whether the book has a copy on the shelf,
+// or whether the copy is.
+type ShelfEntry struct {
+	BookID          uint   `json:"book_id"`
+	Classification  string `json:"classification,omitempty"`
+	CallNumber      string `json:"call_number,omitempty"`
+	Title           string `json:"title"`
+	Author          string `json:"author"`
+	PublicationYear int    `json:"publication_year,omitempty"`
+	ISBN            string `json:"isbn"`
+	ShelfLocation   string `json:"shelf_location,omitempty"`
+	Available       bool   `json:"available"`
+	TotalCopies     int    `json:"total_copies,omitempty"`
+	CopyID          uint   `json:"copy_id,omitempty"`
+	Barcode         string `json:"barcode,omitempty"`
+	Status          string `json:"status,omitempty"`
+}
@@ -0,0 +1,71 @@
+// internal/dto/stocktake.go
+package dto
+
+import "time"
+
+// StocktakeRequest starts a stocktake of a branch, or of one shelf location
+// of it.
+type StocktakeRequest struct {
+	BranchID uint   `json:"branch_id" binding:"required"`
+	Location string `json:"location,omitempty" binding:"max=100"`
+	Note     string `json:"note,omitempty" binding:"max=255"`
+}
+
+// StocktakeScanRequest is a batch of item identifiers read off the shelf.
+type StocktakeScanRequest struct {
+	Barcodes []string `json:"barcodes" binding:"required,min=1,max=500,dive,max=32"`
+}
+
+// StocktakeScanResult tells how a batch of scans went: Recorded items are new
+// to the stocktake, Duplicates were scanned before and Unknown lists the
+// barcodes no copy has.
+type StocktakeScanResult struct {
+	Recorded   int      `json:"recorded"`
+	Duplicates int      `json:"duplicates"`
+	Unknown    []string `json:"unknown"`
+}
+
+// StocktakeCopiesRequest names the copies a stocktake action applies to.
+type StocktakeCopiesRequest struct {
+	CopyIDs []uint `json:"copy_ids" binding:"required,min=1,max=500"`
+}
+
+// StocktakeReport compares what a stocktake scanned with what the catalog
+// expects on its shelves: the available copies at the branch in its
+// location. Found counts the scanned copies that are where they belong.
+//
+//   - Missing copies are expected but were not scanned.
+//   - Misplaced copies were scanned but belong to another branch or location.
+//   - Unexpected items are barcodes of no copy, copies of removed books or
+//     damaged copies.
+//   - CheckedOut copies were scanned although on loan, in transit or lost.
+//   - Recovered copies were scanned after being marked missing.
+type StocktakeReport struct {
+	StocktakeID uint            `json:"stocktake_id"`
+	BranchID    uint            `json:"branch_id"`
+	Location    string          `json:"location,omitempty"`
+	Status      string          `json:"status"`
+	Expected    int             `json:"expected"`
+	Scanned     int             `json:"scanned"`
+	Found       int             `json:"found"`
+	Missing     []StocktakeItem `json:"missing"`
+	Misplaced   []StocktakeItem `json:"misplaced"`
+	Unexpected  []StocktakeItem `json:"unexpected"`
+	CheckedOut  []StocktakeItem `json:"checked_out"`
+	Recovered   []StocktakeItem `json:"recovered"`
+}
+
+// StocktakeItem is an item of a stocktake report, as the catalog has it.
+// Missing copies have no ScannedAt; unknown barcodes only have a Barcode and
+// ScannedAt.
+type StocktakeItem struct {
+	Barcode       string     `json:"barcode"`
+	CopyID        uint       `json:"copy_id,omitempty"`
+	BookID        uint       `json:"book_id,omitempty"`
+	Title         string     `json:"title,omitempty"`
+	CallNumber    string     `json:"call_number,omitempty"`
+	Status        string     `json:"status,omitempty"`
+	BranchID      uint       `json:"branch_id,omitempty"`
+	ShelfLocation string     `json:"shelf_location,omitempty"`
+	ScannedAt     *time.Time `json:"scanned_at,omitempty"`
+}
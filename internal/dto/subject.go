@@ -0,0 +1,45 @@
+// internal/dto/subject.go
+package dto
+
+type CreateSubjectRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+	// ParentID places the subject below another one; it is a root otherwise.
+	ParentID    uint     `json:"parent_id,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Aliases     []string `json:"aliases,omitempty" binding:"dive,required,max=100"`
+}
+
+type UpdateSubjectRequest struct {
+	Name        string `json:"name,omitempty" binding:"max=100"`
+	Description string `json:"description,omitempty"`
+}
+
+type MoveSubjectRequest struct {
+	// ParentID is the new parent, or 0 to make the subject a root.
+	ParentID uint `json:"parent_id"`
+}
+
+type SubjectAliasRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// GenreMapping reports how one distinct genre string was mapped.
+type GenreMapping struct {
+	Genre     string `json:"genre"`
+	Books     int64  `json:"books"`
+	SubjectID uint   `json:"subject_id,omitempty"`
+	Subject   string `json:"subject,omitempty"`
+	Created   bool   `json:"created,omitempty"`
+	Linked    int64  `json:"linked"`
+	// Reason explains why a genre was left unmapped.
+	Reason string `json:"reason,omitempty"`
+}
+
+// GenreMappingResult reports a migration of free-text genres onto subjects.
+type GenreMappingResult struct {
+	Applied         bool           `json:"applied"`
+	BooksLinked     int64          `json:"books_linked"`
+	SubjectsCreated int            `json:"subjects_created"`
+	Unmapped        int            `json:"unmapped"`
+	Genres          []GenreMapping `json:"genres"`
+}
@@ -0,0 +1,17 @@
+// internal/dto/work.go
+package dto
+
+// WorkRequest creates or replaces a work, the editions and translations of
+// which are books linked to it.
+type WorkRequest struct {
+	Title  string `json:"title" binding:"required,max=255"`
+	Author string `json:"author,omitempty" binding:"max=255"`
+	// SeriesID places the work in a series, as Volume of it.
+	SeriesID uint    `json:"series_id,omitempty"`
+	Volume   float64 `json:"volume,omitempty" binding:"gte=0"`
+}
+
+type SeriesRequest struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Description string `json:"description,omitempty"`
+}
@@ -0,0 +1,57 @@
+// internal/handler/account_handler.go
+package handler
+
+import (
+	"net/http"
+
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type AccountHandler struct {
+	accountService service.AccountService
+}
+
+func NewAccountHandler(accountService service.AccountService) *AccountHandler {
+	return &AccountHandler{accountService: accountService}
+}
+
+// GetMyAccount lists the charges and refunds on the current user's account.
+func (h *AccountHandler) GetMyAccount(c *gin.Context) {
+	h.getAccount(c, c.GetUint("user_id"))
+}
+
+// GetAccount lists the charges and refunds on a user's account.
+func (h *AccountHandler) GetAccount(c *gin.Context) {
+	id, ok := idParam(c, "user")
+	if !ok {
+		return
+	}
+	h.getAccount(c, id)
+}
+
+// getAccount responds with a page of the entries on a user's account, newest
+// first, and the account balance in the meta.
+func (h *AccountHandler) getAccount(c *gin.Context, userID uint) {
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	entries, balance, info, err := h.accountService.GetAccount(c.Request.Context(), userID, params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	meta := query.PageMeta(params.PageRequest(), info)
+	meta["balance"] = balance
+	httpresponse.Success(c, http.StatusOK, "", entries, meta)
+}
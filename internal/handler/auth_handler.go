@@ -27,7 +27,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	user, err := h.authService.Register(req)
+	user, err := h.authService.Register(c.Request.Context(), req)
 	if err != nil {
 		httpresponse.Error(c, err)
 		return
@@ -49,7 +49,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	loginResponse, err := h.authService.Login(req)
+	loginResponse, err := h.authService.Login(c.Request.Context(), req)
 	if err != nil {
 		httpresponse.Error(c, err)
 		return
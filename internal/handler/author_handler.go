@@ -0,0 +1,244 @@
+// internal/handler/author_handler.go
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+var authorSorts = map[string]string{
+	"name_asc":        "sort_name ASC",
+	"name_desc":       "sort_name DESC",
+	"created_at_desc": "created_at DESC",
+}
+
+type AuthorHandler struct {
+	authorService service.AuthorService
+}
+
+func NewAuthorHandler(authorService service.AuthorService) *AuthorHandler {
+	return &AuthorHandler{authorService: authorService}
+}
+
+// authorIDParam parses the :id route parameter, answering 400 when it is not
+// a valid ID.
+func authorIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid author ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *AuthorHandler) ListAuthors(c *gin.Context) {
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 20,
+		MaxLimit:     100,
+		DefaultSort:  "name_asc",
+		AllowedSorts: authorSorts,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	authors, info, err := h.authorService.ListAuthors(c.Request.Context(), params.Search, params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	meta := query.PageMeta(params.PageRequest(), info)
+	meta["search"] = params.Search
+	httpresponse.Success(c, http.StatusOK, "", authors, meta)
+}
+
+func (h *AuthorHandler) GetAuthor(c *gin.Context) {
+	id, ok := authorIDParam(c)
+	if !ok {
+		return
+	}
+
+	author, err := h.authorService.GetAuthor(c.Request.Context(), id)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	if notModified(c, author.Version) {
+		return
+	}
+	setETag(c, author.Version)
+
+	httpresponse.Success(c, http.StatusOK, "", author, nil)
+}
+
+// ListAuthorBooks lists the works of an author; role narrows it to one kind
+// of credit.
+func (h *AuthorHandler) ListAuthorBooks(c *gin.Context) {
+	id, ok := authorIDParam(c)
+	if !ok {
+		return
+	}
+
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	role := c.Query("role")
+	works, info, err := h.authorService.ListAuthorWorks(c.Request.Context(), id, role, params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	meta := query.PageMeta(params.PageRequest(), info)
+	meta["role"] = role
+	httpresponse.Success(c, http.StatusOK, "", works, meta)
+}
+
+func (h *AuthorHandler) CreateAuthor(c *gin.Context) {
+	var req dto.CreateAuthorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	author, err := h.authorService.CreateAuthor(c.Request.Context(), req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, author.Version)
+	httpresponse.Success(c, http.StatusCreated, "Author created successfully", author, nil)
+}
+
+func (h *AuthorHandler) UpdateAuthor(c *gin.Context) {
+	id, ok := authorIDParam(c)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	var req dto.UpdateAuthorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	author, err := h.authorService.UpdateAuthor(c.Request.Context(), id, version, req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, author.Version)
+	httpresponse.Success(c, http.StatusOK, "Author updated successfully", author, nil)
+}
+
+func (h *AuthorHandler) DeleteAuthor(c *gin.Context) {
+	id, ok := authorIDParam(c)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	if err := h.authorService.DeleteAuthor(c.Request.Context(), id, version); err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Author deleted successfully", nil, nil)
+}
+
+func (h *AuthorHandler) AddVariant(c *gin.Context) {
+	id, ok := authorIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.AuthorVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	variant, err := h.authorService.AddVariant(c.Request.Context(), id, req.Name)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusCreated, "Name variant added", variant, nil)
+}
+
+func (h *AuthorHandler) RemoveVariant(c *gin.Context) {
+	id, ok := authorIDParam(c)
+	if !ok {
+		return
+	}
+	variantID, err := strconv.ParseUint(c.Param("variantId"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid variant ID"))
+		return
+	}
+
+	if err := h.authorService.RemoveVariant(c.Request.Context(), id, uint(variantID)); err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Name variant removed", nil, nil)
+}
+
+// MergeAuthors folds the authors in the body into the author in the URL.
+func (h *AuthorHandler) MergeAuthors(c *gin.Context) {
+	id, ok := authorIDParam(c)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	var req dto.MergeAuthorsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	author, result, err := h.authorService.MergeAuthors(c.Request.Context(), id, version, req.AuthorIDs)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, author.Version)
+	httpresponse.Success(c, http.StatusOK, "Authors merged successfully", author, result)
+}
@@ -2,17 +2,49 @@
 package handler
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/middleware"
+	"github.com/alpardfm/library-management-api/internal/models"
 	"github.com/alpardfm/library-management-api/internal/service"
 	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/mergepatch"
 	"github.com/alpardfm/library-management-api/pkg/query"
 	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 )
 
+// bookSorts are the sort options of book listings and exports. Call numbers
+// sort in shelf order.
+var bookSorts = map[string]string{
+	"created_at_desc":  "created_at DESC",
+	"created_at_asc":   "created_at ASC",
+	"title_asc":        "title ASC",
+	"title_desc":       "title DESC",
+	"call_number_asc":  "shelf_key ASC",
+	"call_number_desc": "shelf_key DESC",
+}
+
+// bookListSorts are bookSorts plus relevance, which full-text searches rank
+// by and other listings sort by title.
+var bookListSorts = map[string]string{
+	"created_at_desc":  "created_at DESC",
+	"created_at_asc":   "created_at ASC",
+	"title_asc":        "title ASC",
+	"title_desc":       "title DESC",
+	"call_number_asc":  "shelf_key ASC",
+	"call_number_desc": "shelf_key DESC",
+	"relevance":        "search_rank DESC",
+}
+
 type BookHandler struct {
 	bookService service.BookService
 }
@@ -21,6 +53,12 @@ func NewBookHandler(bookService service.BookService) *BookHandler {
 	return &BookHandler{bookService: bookService}
 }
 
+// actorOf is the signed-in user making a request, as recorded in the history
+// of what it changes.
+func actorOf(c *gin.Context) dto.Actor {
+	return dto.Actor{UserID: c.GetUint("user_id"), RequestID: c.GetString(middleware.RequestIDKey)}
+}
+
 func (h *BookHandler) CreateBook(c *gin.Context) {
 	var req dto.CreateBookRequest
 
@@ -29,12 +67,13 @@ func (h *BookHandler) CreateBook(c *gin.Context) {
 		return
 	}
 
-	book, err := h.bookService.CreateBook(req)
+	book, err := h.bookService.CreateBook(c.Request.Context(), actorOf(c), req)
 	if err != nil {
 		httpresponse.Error(c, err)
 		return
 	}
 
+	setETag(c, book.Version)
 	httpresponse.Success(c, http.StatusCreated, "Book created successfully", book, nil)
 }
 
@@ -46,15 +85,21 @@ func (h *BookHandler) GetBook(c *gin.Context) {
 		return
 	}
 
-	book, err := h.bookService.GetBookByID(uint(id))
+	book, err := h.bookService.GetBookByID(c.Request.Context(), uint(id))
 	if err != nil {
 		httpresponse.Error(c, err)
 		return
 	}
+	if notModified(c, book.Version) {
+		return
+	}
+	setETag(c, book.Version)
 
 	httpresponse.Success(c, http.StatusOK, "", book, nil)
 }
 
+// UpdateBook replaces a book: optional fields left out of the body are
+// cleared. Validation is left to the service so that errors name the fields.
 func (h *BookHandler) UpdateBook(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -63,19 +108,74 @@ func (h *BookHandler) UpdateBook(c *gin.Context) {
 		return
 	}
 
-	var req dto.UpdateBookRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	var req dto.CreateBookRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(fmt.Sprintf("invalid request body: %v", err)))
+		return
+	}
+
+	book, changes, err := h.bookService.ReplaceBook(c.Request.Context(), actorOf(c), uint(id), version, req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	logBookChanges(c, book.ID, changes)
+	setETag(c, book.Version)
+	httpresponse.Success(c, http.StatusOK, "Book updated successfully", book, gin.H{"changes": changes})
+}
+
+// PatchBook applies a JSON merge patch to a book. Fields set to null are
+// cleared.
+func (h *BookHandler) PatchBook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid book ID"))
 		return
 	}
 
-	book, err := h.bookService.UpdateBook(uint(id), req)
+	if contentType := c.ContentType(); contentType != mergepatch.ContentType && contentType != "application/json" {
+		httpresponse.Error(c, apperror.Unsupported(fmt.Sprintf("PATCH requires Content-Type %s", mergepatch.ContentType)))
+		return
+	}
+	version, err := ifMatchVersion(c)
 	if err != nil {
 		httpresponse.Error(c, err)
 		return
 	}
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("failed to read request body"))
+		return
+	}
 
-	httpresponse.Success(c, http.StatusOK, "Book updated successfully", book, nil)
+	book, changes, err := h.bookService.PatchBook(c.Request.Context(), actorOf(c), uint(id), version, patch)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	logBookChanges(c, book.ID, changes)
+	setETag(c, book.Version)
+	httpresponse.Success(c, http.StatusOK, "Book updated successfully", book, gin.H{"changes": changes})
+}
+
+// logBookChanges records who changed which fields of a book.
+func logBookChanges(c *gin.Context, id uint, changes []dto.FieldChange) {
+	if len(changes) == 0 {
+		return
+	}
+	log.Info().
+		Str("request_id", c.GetString(middleware.RequestIDKey)).
+		Uint("user_id", c.GetUint("user_id")).
+		Uint("book_id", id).
+		Interface("changes", changes).
+		Msg("book updated")
 }
 
 func (h *BookHandler) DeleteBook(c *gin.Context) {
@@ -86,7 +186,22 @@ func (h *BookHandler) DeleteBook(c *gin.Context) {
 		return
 	}
 
-	if err := h.bookService.DeleteBook(uint(id)); err != nil {
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	// The body is optional: without one the book is withdrawn.
+	var req dto.RemoveBookRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httpresponse.Error(c, apperror.BadRequest(err.Error()))
+			return
+		}
+	}
+
+	if err := h.bookService.DeleteBook(c.Request.Context(), actorOf(c), uint(id), version, req); err != nil {
 		httpresponse.Error(c, err)
 		return
 	}
@@ -94,36 +209,305 @@ func (h *BookHandler) DeleteBook(c *gin.Context) {
 	httpresponse.Success(c, http.StatusOK, "Book deleted successfully", nil, nil)
 }
 
-func (h *BookHandler) ListBooks(c *gin.Context) {
+// ListRemovedBooks lists withdrawn and archived books; status narrows the
+// list to one of them.
+func (h *BookHandler) ListRemovedBooks(c *gin.Context) {
 	params, err := query.ParseListParams(c, query.ListOptions{
 		DefaultPage:  1,
 		DefaultLimit: 10,
 		MaxLimit:     100,
-		DefaultSort:  "created_at_desc",
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	books, info, err := h.bookService.ListRemovedBooks(c.Request.Context(), c.Query("status"), params.Search, params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	meta := query.PageMeta(params.PageRequest(), info)
+	meta["search"] = params.Search
+	httpresponse.Success(c, http.StatusOK, "Removed books retrieved successfully", books, meta)
+}
+
+func (h *BookHandler) RestoreBook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid book ID"))
+		return
+	}
+
+	book, err := h.bookService.RestoreBook(c.Request.Context(), actorOf(c), uint(id))
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, book.Version)
+	httpresponse.Success(c, http.StatusOK, "Book restored successfully", book, nil)
+}
+
+// BookHistory lists the revisions of a book, latest first.
+func (h *BookHandler) BookHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid book ID"))
+		return
+	}
+
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 20,
+		MaxLimit:     100,
 		AllowedSorts: map[string]string{
-			"created_at_desc": "created_at DESC",
-			"created_at_asc":  "created_at ASC",
-			"title_asc":       "title ASC",
-			"title_desc":      "title DESC",
+			"version_desc": "version DESC",
+			"version_asc":  "version ASC",
 		},
+		DefaultSort: "version_desc",
 	})
 	if err != nil {
 		httpresponse.Error(c, err)
 		return
 	}
 
-	books, total, err := h.bookService.ListBooks(params.Page, params.Limit, params.Search, params.Sort)
+	revisions, info, err := h.bookService.BookHistory(c.Request.Context(), uint(id), params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Book history retrieved successfully", revisions, query.PageMeta(params.PageRequest(), info))
+}
+
+// RevertBook brings a book back to the catalog fields of an earlier version.
+func (h *BookHandler) RevertBook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid book ID"))
+		return
+	}
+
+	version, err := ifMatchVersion(c)
 	if err != nil {
 		httpresponse.Error(c, err)
 		return
 	}
+	var req dto.RevertBookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	book, changes, err := h.bookService.RevertBook(c.Request.Context(), actorOf(c), uint(id), version, req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	logBookChanges(c, book.ID, changes)
+	setETag(c, book.Version)
+	httpresponse.Success(c, http.StatusOK, "Book reverted successfully", book, gin.H{"changes": changes})
+}
+
+// ListBooks lists books matching search and the filter parameters.
+// search_mode=fulltext ranks matches by relevance (the default sort) and marks
+// them in title_highlight and snippet; meta.search_mode tells when it fell
+// back to the substring search. A search without results gets a spelling
+// hint in meta.did_you_mean. facets names the facets to count into
+// meta.facets, or "all". collapse=work lists one edition per work with
+// edition_count and available_editions.
+func (h *BookHandler) ListBooks(c *gin.Context) {
+	searchMode := c.DefaultQuery("search_mode", service.SearchModeSubstring)
+	defaultSort := "created_at_desc"
+	switch searchMode {
+	case service.SearchModeSubstring:
+	case service.SearchModeFullText:
+		defaultSort = "relevance"
+	default:
+		httpresponse.Error(c, apperror.BadRequest("search_mode must be substring or fulltext"))
+		return
+	}
 
-	httpresponse.Success(c, http.StatusOK, "", books, gin.H{
-		"page":        params.Page,
-		"limit":       params.Limit,
-		"total":       total,
-		"total_pages": query.TotalPages(total, params.Limit),
-		"sort":        params.Sort,
-		"search":      params.Search,
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 10,
+		MaxLimit:     100,
+		DefaultSort:  defaultSort,
+		AllowedSorts: bookListSorts,
 	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	filter, err := parseBookListFilter(c, params.Search)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	switch c.Query("collapse") {
+	case "":
+	case "work":
+		filter.CollapseWorks = true
+	default:
+		httpresponse.Error(c, apperror.BadRequest("collapse must be work"))
+		return
+	}
+
+	var books []models.Book
+	var info query.PageInfo
+	if searchMode == service.SearchModeFullText {
+		books, info, searchMode, err = h.bookService.SearchBooks(c.Request.Context(), filter, params.PageRequest())
+		filter.SearchMode = searchMode
+	} else {
+		books, info, err = h.bookService.ListBooks(c.Request.Context(), filter, params.PageRequest())
+	}
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	meta := query.PageMeta(params.PageRequest(), info)
+	meta["search"] = params.Search
+	meta["search_mode"] = searchMode
+	// An empty page with nothing before it means the search found nothing.
+	if len(books) == 0 && info.PrevCursor == "" && params.Search != "" {
+		suggestion, err := h.bookService.SpellingSuggestion(c.Request.Context(), params.Search)
+		if err != nil {
+			httpresponse.Error(c, err)
+			return
+		}
+		if suggestion != "" {
+			meta["did_you_mean"] = suggestion
+		}
+	}
+	if facets := queryList(c, "facets"); len(facets) > 0 {
+		if slices.Contains(facets, "all") {
+			facets = nil
+		}
+		counts, err := h.bookService.BookFacets(c.Request.Context(), filter, facets)
+		if err != nil {
+			httpresponse.Error(c, err)
+			return
+		}
+		meta["facets"] = counts
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", books, meta)
+}
+
+// SuggestBooks completes the search box: q is what has been typed so far.
+func (h *BookHandler) SuggestBooks(c *gin.Context) {
+	limit := 10
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			httpresponse.Error(c, apperror.BadRequest("invalid limit query"))
+			return
+		}
+		limit = min(parsed, service.MaxSuggestions)
+	}
+
+	suggestions, err := h.bookService.SuggestBooks(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", suggestions, nil)
+}
+
+// parseBookListFilter reads the filter parameters of a book listing. A
+// parameter may be repeated to match any of several values; IDs, languages
+// and item types may also be comma-separated.
+func parseBookListFilter(c *gin.Context, search string) (dto.BookListFilter, error) {
+	filter := dto.BookListFilter{
+		Search:     search,
+		Genres:     queryValues(c, "genre"),
+		Publishers: queryValues(c, "publisher"),
+		Languages:  queryList(c, "language"),
+		ItemTypes:  queryList(c, "item_type"),
+	}
+
+	var err error
+	if filter.SubjectIDs, err = queryIDs(c, "subject"); err != nil {
+		return filter, err
+	}
+	if filter.AuthorIDs, err = queryIDs(c, "author"); err != nil {
+		return filter, err
+	}
+	for _, itemType := range filter.ItemTypes {
+		if !slices.Contains(models.ItemTypes, itemType) {
+			return filter, apperror.BadRequest(fmt.Sprintf("invalid item_type %q (use %s)", itemType, strings.Join(models.ItemTypes, ", ")))
+		}
+	}
+
+	if filter.YearFrom, err = queryYear(c, "year_from"); err != nil {
+		return filter, err
+	}
+	if filter.YearTo, err = queryYear(c, "year_to"); err != nil {
+		return filter, err
+	}
+	if filter.YearFrom > 0 && filter.YearTo > 0 && filter.YearFrom > filter.YearTo {
+		return filter, apperror.BadRequest("year_from must not be after year_to")
+	}
+
+	if value := c.Query("available"); value != "" {
+		available, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, apperror.BadRequest("available must be a boolean")
+		}
+		filter.Available = &available
+	}
+	return filter, nil
+}
+
+// queryValues returns the non-empty values of a repeatable query parameter.
+func queryValues(c *gin.Context, name string) []string {
+	var values []string
+	for _, value := range c.QueryArray(name) {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// queryList is queryValues with comma-separated values split up.
+func queryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, value := range c.QueryArray(name) {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
+
+func queryYear(c *gin.Context, name string) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+	year, err := strconv.Atoi(value)
+	if err != nil || year < 1 {
+		return 0, apperror.BadRequest(name + " must be a year")
+	}
+	return year, nil
+}
+
+func queryIDs(c *gin.Context, name string) ([]uint, error) {
+	var ids []uint
+	for _, value := range queryList(c, name) {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || id == 0 {
+			return nil, apperror.BadRequest(fmt.Sprintf("invalid %s ID %q", name, value))
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
 }
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
 	"github.com/alpardfm/library-management-api/internal/service"
 	"github.com/alpardfm/library-management-api/pkg/apperror"
 	"github.com/alpardfm/library-management-api/pkg/query"
@@ -29,7 +30,7 @@ func (h *BorrowHandler) BorrowBook(c *gin.Context) {
 		return
 	}
 
-	borrowRecord, err := h.borrowService.BorrowBook(userID, req)
+	borrowRecord, err := h.borrowService.BorrowBook(c.Request.Context(), userID, req)
 	if err != nil {
 		httpresponse.Error(c, err)
 		return
@@ -48,7 +49,7 @@ func (h *BorrowHandler) ReturnBook(c *gin.Context) {
 		return
 	}
 
-	borrowRecord, fine, err := h.borrowService.ReturnBook(userID, role, req)
+	borrowRecord, fine, err := h.borrowService.ReturnBook(c.Request.Context(), userID, role, req)
 	if err != nil {
 		httpresponse.Error(c, err)
 		return
@@ -64,6 +65,66 @@ func (h *BorrowHandler) ReturnBook(c *gin.Context) {
 	httpresponse.Success(c, http.StatusOK, "Book returned successfully", data, nil)
 }
 
+func (h *BorrowHandler) DeclareLost(c *gin.Context) {
+	var req dto.DeclareLostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	borrowRecord, charges, err := h.borrowService.DeclareLost(c.Request.Context(), actorOf(c), req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Book declared lost", writeOffData(borrowRecord, charges), nil)
+}
+
+func (h *BorrowHandler) ReturnDamaged(c *gin.Context) {
+	var req dto.ReturnDamagedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	borrowRecord, charges, err := h.borrowService.ReturnDamaged(c.Request.Context(), actorOf(c), req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Book returned damaged", writeOffData(borrowRecord, charges), nil)
+}
+
+func (h *BorrowHandler) ReturnLost(c *gin.Context) {
+	var req dto.ReturnBookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	borrowRecord, refunds, err := h.borrowService.ReturnLost(c.Request.Context(), actorOf(c), req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Lost book returned", writeOffData(borrowRecord, refunds), nil)
+}
+
+// writeOffData is the response to a lost or damaged item action: the loan and
+// the entries it put on the borrower's account.
+func writeOffData(borrowRecord *models.BorrowRecord, entries []models.AccountEntry) gin.H {
+	if entries == nil {
+		entries = []models.AccountEntry{}
+	}
+	return gin.H{
+		"borrow_record": borrowRecord,
+		"charges":       entries,
+	}
+}
+
 func (h *BorrowHandler) GetMyBorrows(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	params, err := query.ParseListParams(c, query.ListOptions{
@@ -83,19 +144,13 @@ func (h *BorrowHandler) GetMyBorrows(c *gin.Context) {
 		return
 	}
 
-	borrows, total, err := h.borrowService.GetUserBorrows(userID, params.Page, params.Limit, params.Sort)
+	borrows, info, err := h.borrowService.GetUserBorrows(c.Request.Context(), userID, params.PageRequest())
 	if err != nil {
 		httpresponse.Error(c, err)
 		return
 	}
 
-	httpresponse.Success(c, http.StatusOK, "", borrows, gin.H{
-		"page":        params.Page,
-		"limit":       params.Limit,
-		"total":       total,
-		"total_pages": query.TotalPages(total, params.Limit),
-		"sort":        params.Sort,
-	})
+	httpresponse.Success(c, http.StatusOK, "", borrows, query.PageMeta(params.PageRequest(), info))
 }
 
 func (h *BorrowHandler) GetActiveBorrows(c *gin.Context) {
@@ -116,19 +171,13 @@ func (h *BorrowHandler) GetActiveBorrows(c *gin.Context) {
 		return
 	}
 
-	borrows, total, err := h.borrowService.GetActiveBorrows(params.Page, params.Limit, params.Sort)
+	borrows, info, err := h.borrowService.GetActiveBorrows(c.Request.Context(), params.PageRequest())
 	if err != nil {
 		httpresponse.Error(c, err)
 		return
 	}
 
-	httpresponse.Success(c, http.StatusOK, "", borrows, gin.H{
-		"page":        params.Page,
-		"limit":       params.Limit,
-		"total":       total,
-		"total_pages": query.TotalPages(total, params.Limit),
-		"sort":        params.Sort,
-	})
+	httpresponse.Success(c, http.StatusOK, "", borrows, query.PageMeta(params.PageRequest(), info))
 }
 
 func (h *BorrowHandler) GetOverdueBorrows(c *gin.Context) {
@@ -149,17 +198,11 @@ func (h *BorrowHandler) GetOverdueBorrows(c *gin.Context) {
 		return
 	}
 
-	borrows, total, err := h.borrowService.GetOverdueBorrows(params.Page, params.Limit, params.Sort)
+	borrows, info, err := h.borrowService.GetOverdueBorrows(c.Request.Context(), params.PageRequest())
 	if err != nil {
 		httpresponse.Error(c, err)
 		return
 	}
 
-	httpresponse.Success(c, http.StatusOK, "", borrows, gin.H{
-		"page":        params.Page,
-		"limit":       params.Limit,
-		"total":       total,
-		"total_pages": query.TotalPages(total, params.Limit),
-		"sort":        params.Sort,
-	})
+	httpresponse.Success(c, http.StatusOK, "", borrows, query.PageMeta(params.PageRequest(), info))
 }
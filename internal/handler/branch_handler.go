@@ -0,0 +1,127 @@
+// internal/handler/branch_handler.go
+package handler
+
+import (
+	"net/http"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type BranchHandler struct {
+	branchService service.BranchService
+}
+
+func NewBranchHandler(branchService service.BranchService) *BranchHandler {
+	return &BranchHandler{branchService: branchService}
+}
+
+// ListBranches lists the branches by code.
+func (h *BranchHandler) ListBranches(c *gin.Context) {
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	branches, info, err := h.branchService.ListBranches(c.Request.Context(), params.Search, params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	meta := query.PageMeta(params.PageRequest(), info)
+	meta["search"] = params.Search
+	httpresponse.Success(c, http.StatusOK, "", branches, meta)
+}
+
+func (h *BranchHandler) GetBranch(c *gin.Context) {
+	id, ok := idParam(c, "branch")
+	if !ok {
+		return
+	}
+
+	branch, err := h.branchService.GetBranch(c.Request.Context(), id)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	if notModified(c, branch.Version) {
+		return
+	}
+	setETag(c, branch.Version)
+
+	httpresponse.Success(c, http.StatusOK, "", branch, nil)
+}
+
+func (h *BranchHandler) CreateBranch(c *gin.Context) {
+	var req dto.BranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	branch, err := h.branchService.CreateBranch(c.Request.Context(), req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, branch.Version)
+	httpresponse.Success(c, http.StatusCreated, "Branch created successfully", branch, nil)
+}
+
+func (h *BranchHandler) UpdateBranch(c *gin.Context) {
+	id, ok := idParam(c, "branch")
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	var req dto.BranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	branch, err := h.branchService.UpdateBranch(c.Request.Context(), id, version, req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, branch.Version)
+	httpresponse.Success(c, http.StatusOK, "Branch updated successfully", branch, nil)
+}
+
+func (h *BranchHandler) DeleteBranch(c *gin.Context) {
+	id, ok := idParam(c, "branch")
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	if err := h.branchService.DeleteBranch(c.Request.Context(), id, version); err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Branch deleted successfully", nil, nil)
+}
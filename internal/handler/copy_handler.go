@@ -0,0 +1,156 @@
+// internal/handler/copy_handler.go
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// CopyHandler serves the copies of books at the branches and the transfers
+// between branches.
+type CopyHandler struct {
+	copyService service.CopyService
+}
+
+func NewCopyHandler(copyService service.CopyService) *CopyHandler {
+	return &CopyHandler{copyService: copyService}
+}
+
+// ListCopies lists the registered copies of a book with their branches.
+func (h *CopyHandler) ListCopies(c *gin.Context) {
+	id, ok := idParam(c, "book")
+	if !ok {
+		return
+	}
+
+	copies, err := h.copyService.ListCopies(c.Request.Context(), id)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", copies, nil)
+}
+
+func (h *CopyHandler) AddCopy(c *gin.Context) {
+	id, ok := idParam(c, "book")
+	if !ok {
+		return
+	}
+	var req dto.AddCopyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	item, err := h.copyService.AddCopy(c.Request.Context(), actorOf(c), id, req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusCreated, "Copy added successfully", item, nil)
+}
+
+// ListTransfers lists the open transfers, oldest first; status picks the
+// transfers of one status and branch_id those from or to a branch.
+func (h *CopyHandler) ListTransfers(c *gin.Context) {
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", models.TransferRequested, models.TransferInTransit, models.TransferReceived, models.TransferCancelled:
+	default:
+		httpresponse.Error(c, apperror.BadRequest("status must be requested, in_transit, received or cancelled"))
+		return
+	}
+	branchID, err := uintQuery(c, "branch_id")
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	transfers, info, err := h.copyService.ListTransfers(c.Request.Context(), status, branchID, params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	meta := query.PageMeta(params.PageRequest(), info)
+	meta["status"] = status
+	httpresponse.Success(c, http.StatusOK, "", transfers, meta)
+}
+
+func (h *CopyHandler) GetTransfer(c *gin.Context) {
+	id, ok := idParam(c, "transfer")
+	if !ok {
+		return
+	}
+
+	transfer, err := h.copyService.GetTransfer(c.Request.Context(), id)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", transfer, nil)
+}
+
+func (h *CopyHandler) RequestTransfer(c *gin.Context) {
+	var req dto.TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	transfer, err := h.copyService.RequestTransfer(c.Request.Context(), c.GetUint("user_id"), req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusCreated, "Transfer requested successfully", transfer, nil)
+}
+
+func (h *CopyHandler) ShipTransfer(c *gin.Context) {
+	h.moveTransfer(c, h.copyService.ShipTransfer, "Transfer shipped successfully")
+}
+
+func (h *CopyHandler) ReceiveTransfer(c *gin.Context) {
+	h.moveTransfer(c, h.copyService.ReceiveTransfer, "Transfer received successfully")
+}
+
+func (h *CopyHandler) CancelTransfer(c *gin.Context) {
+	h.moveTransfer(c, h.copyService.CancelTransfer, "Transfer cancelled successfully")
+}
+
+// moveTransfer runs one step of the transfer in the :id parameter.
+func (h *CopyHandler) moveTransfer(c *gin.Context, step func(ctx context.Context, id uint) (*models.Transfer, error), message string) {
+	id, ok := idParam(c, "transfer")
+	if !ok {
+		return
+	}
+
+	transfer, err := step(c.Request.Context(), id)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, message, transfer, nil)
+}
@@ -0,0 +1,139 @@
+// internal/handler/cover_handler.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is allowed on top of the cover size limit for the
+// multipart boundaries and part headers around the image.
+const multipartOverhead = 64 << 10
+
+type CoverHandler struct {
+	coverService service.CoverService
+}
+
+func NewCoverHandler(coverService service.CoverService) *CoverHandler {
+	return &CoverHandler{coverService: coverService}
+}
+
+// UploadCover accepts the image as a multipart upload in the "file" field or
+// as the raw request body. Its type is sniffed from the content.
+func (h *CoverHandler) UploadCover(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid book ID"))
+		return
+	}
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	maxSize := h.coverService.MaxSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+
+	var body io.Reader = c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			httpresponse.Error(c, coverUploadError(err, maxSize, "multipart upload must include a file field"))
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			httpresponse.Error(c, apperror.Internal("failed to read upload", err))
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	book, err := h.coverService.SetCover(c.Request.Context(), uint(id), version, body)
+	if err != nil {
+		httpresponse.Error(c, coverUploadError(err, maxSize, ""))
+		return
+	}
+
+	setETag(c, book.Version)
+	httpresponse.Success(c, http.StatusOK, "Cover updated successfully", book, nil)
+}
+
+func (h *CoverHandler) DeleteCover(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid book ID"))
+		return
+	}
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	if err := h.coverService.DeleteCover(c.Request.Context(), uint(id), version); err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Cover deleted successfully", nil, nil)
+}
+
+// GetCover sends the cover image, or the thumbnail named by the size
+// parameter. URLs carrying the current version are cached for good.
+func (h *CoverHandler) GetCover(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid book ID"))
+		return
+	}
+
+	image, err := h.coverService.GetCover(c.Request.Context(), uint(id), c.Param("size"))
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	if c.Query("v") == image.Version {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "public, max-age=300")
+	}
+	if image.Redirect != "" {
+		c.Redirect(http.StatusFound, image.Redirect)
+		return
+	}
+	defer image.Body.Close()
+
+	etag := fmt.Sprintf(`"%s"`, image.Version)
+	c.Header("ETag", etag)
+	if strings.Contains(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, -1, image.ContentType, image.Body, nil)
+}
+
+// coverUploadError maps an oversized body to 413 and keeps other errors.
+func coverUploadError(err error, maxSize int64, fallback string) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || strings.Contains(err.Error(), "request body too large") {
+		return apperror.TooLarge(fmt.Sprintf("cover image exceeds %d bytes", maxSize))
+	}
+	if fallback != "" {
+		return apperror.BadRequest(fallback)
+	}
+	return err
+}
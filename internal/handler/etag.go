@@ -0,0 +1,67 @@
+// internal/handler/etag.go
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/gin-gonic/gin"
+)
+
+// etag is the entity tag of a resource at version.
+func etag(version uint) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+func setETag(c *gin.Context, version uint) {
+	c.Header("ETag", etag(version))
+}
+
+// notModified answers 304 when If-None-Match names the resource at version,
+// and reports whether it did.
+func notModified(c *gin.Context, version uint) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			setETag(c, version)
+			c.Status(http.StatusNotModified)
+			c.Writer.WriteHeaderNow()
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion returns the version named by the If-Match header, which
+// every change to a versioned resource must send. "*" matches any version
+// and yields 0.
+func ifMatchVersion(c *gin.Context) (uint, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	switch {
+	case header == "":
+		return 0, apperror.PreconditionRequired("If-Match header with the ETag of the resource is required")
+	case header == "*":
+		return 0, nil
+	case strings.Contains(header, ","):
+		return 0, apperror.BadRequest("If-Match must name a single ETag")
+	}
+
+	// Weak tags never match: changes need the exact version.
+	value, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		value, ok = strings.CutSuffix(value, `"`)
+	}
+	version, err := strconv.ParseUint(value, 10, 32)
+	if !ok || err != nil || version == 0 {
+		return 0, apperror.PreconditionFailed("If-Match does not match the ETag of the resource")
+	}
+	return uint(version), nil
+}
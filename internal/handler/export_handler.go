@@ -0,0 +1,161 @@
+// internal/handler/export_handler.go
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/middleware"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/marc"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/alpardfm/library-management-api/pkg/xlsx"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+var exportContentTypes = map[string]string{
+	service.ExportFormatCSV:   "text/csv; charset=utf-8",
+	service.ExportFormatJSONL: "application/x-ndjson",
+	service.ExportFormatXLSX:  xlsx.ContentType,
+	service.FormatMARC:        "application/marc",
+	service.FormatMARCXML:     "application/marcxml+xml",
+}
+
+var exportExtensions = map[string]string{
+	service.FormatMARC:    "mrc",
+	service.FormatMARCXML: "xml",
+}
+
+type ExportHandler struct {
+	exportService service.BookExportService
+	marcService   service.MarcService
+}
+
+func NewExportHandler(exportService service.BookExportService, marcService service.MarcService) *ExportHandler {
+	return &ExportHandler{exportService: exportService, marcService: marcService}
+}
+
+// ExportBooks streams the catalog as a file download. It takes the search,
+// filter and sort parameters of ListBooks plus format and, for tabular
+// formats, a comma-separated columns list.
+func (h *ExportHandler) ExportBooks(c *gin.Context) {
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 1,
+		DefaultSort:  "created_at_desc",
+		AllowedSorts: bookSorts,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	filter, err := parseBookListFilter(c, params.Search)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	filter.SearchMode = c.DefaultQuery("search_mode", service.SearchModeSubstring)
+	if filter.SearchMode != service.SearchModeSubstring && filter.SearchMode != service.SearchModeFullText {
+		httpresponse.Error(c, apperror.BadRequest("search_mode must be substring or fulltext"))
+		return
+	}
+
+	format := strings.ToLower(c.Query("format"))
+	if format == service.FormatMARC || format == service.FormatMARCXML {
+		opts := service.MarcExportOptions{Format: format, Filter: filter, Sort: params.Sort}
+		h.stream(c, format, func(ctx context.Context, w io.Writer) (int, error) {
+			return h.marcService.ExportRecords(ctx, w, opts)
+		})
+		return
+	}
+
+	opts := service.BookExportOptions{
+		Format: format,
+		Filter: filter,
+		Sort:   params.Sort,
+	}
+	if raw := c.Query("columns"); raw != "" {
+		opts.Columns = strings.Split(raw, ",")
+	}
+	opts, err = opts.Normalize()
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	h.stream(c, opts.Format, func(ctx context.Context, w io.Writer) (int, error) {
+		return h.exportService.ExportBooks(ctx, w, opts)
+	})
+}
+
+// stream sends the output of export as an attachment. Options must already be
+// validated: once the first byte is written errors can only be logged.
+func (h *ExportHandler) stream(c *gin.Context, format string, export func(ctx context.Context, w io.Writer) (int, error)) {
+	// Large exports outlive the server write timeout. Test recorders do not
+	// support deadlines, which is harmless.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	ext := exportExtensions[format]
+	if ext == "" {
+		ext = format
+	}
+	fileName := "books-" + time.Now().UTC().Format("20060102T150405Z") + "." + ext
+	c.Header("Content-Type", exportContentTypes[format])
+	c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	rows, err := export(c.Request.Context(), c.Writer)
+	if err != nil {
+		// The status line is already sent; the client sees a truncated file.
+		log.Error().
+			Err(err).
+			Str("request_id", c.GetString(middleware.RequestIDKey)).
+			Int("rows", rows).
+			Msg("book export aborted")
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// GetBookRecord returns the MARC record of one book as MARCXML (default) or
+// ISO 2709 with format=marc.
+func (h *ExportHandler) GetBookRecord(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid book ID"))
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", service.FormatMARCXML))
+	if format != service.FormatMARC && format != service.FormatMARCXML {
+		httpresponse.Error(c, apperror.BadRequest("format must be marc or marcxml"))
+		return
+	}
+
+	rec, err := h.marcService.GetBookRecord(c.Request.Context(), uint(id))
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	var data []byte
+	if format == service.FormatMARC {
+		data, err = marc.Marshal(rec)
+	} else {
+		data, err = marc.MarshalXML(rec)
+	}
+	if err != nil {
+		httpresponse.Error(c, apperror.Internal("failed to encode MARC record", err))
+		return
+	}
+
+	c.Data(http.StatusOK, exportContentTypes[format], data)
+}
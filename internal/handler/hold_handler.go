@@ -0,0 +1,109 @@
+// internal/handler/hold_handler.go
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type HoldHandler struct {
+	holdService service.HoldService
+}
+
+func NewHoldHandler(holdService service.HoldService) *HoldHandler {
+	return &HoldHandler{holdService: holdService}
+}
+
+// PlaceHold queues the current user for a book, or for any edition of a work.
+func (h *HoldHandler) PlaceHold(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req dto.PlaceHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	hold, err := h.holdService.PlaceHold(c.Request.Context(), userID, req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusCreated, "Hold placed successfully", hold, nil)
+}
+
+func (h *HoldHandler) CancelHold(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid hold ID"))
+		return
+	}
+
+	hold, err := h.holdService.CancelHold(c.Request.Context(), c.GetUint("user_id"), c.GetString("role"), uint(id))
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Hold cancelled successfully", hold, nil)
+}
+
+func (h *HoldHandler) GetMyHolds(c *gin.Context) {
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 10,
+		MaxLimit:     50,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	holds, info, err := h.holdService.ListUserHolds(c.Request.Context(), c.GetUint("user_id"), params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", holds, query.PageMeta(params.PageRequest(), info))
+}
+
+// ListHolds lists the hold queue, oldest first; status picks the holds of
+// one status instead of the waiting and ready ones.
+func (h *HoldHandler) ListHolds(c *gin.Context) {
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", models.HoldWaiting, models.HoldReady, models.HoldFulfilled, models.HoldCancelled:
+	default:
+		httpresponse.Error(c, apperror.BadRequest("status must be waiting, ready, fulfilled or cancelled"))
+		return
+	}
+
+	holds, info, err := h.holdService.ListHolds(c.Request.Context(), status, params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	meta := query.PageMeta(params.PageRequest(), info)
+	meta["status"] = status
+	httpresponse.Success(c, http.StatusOK, "", holds, meta)
+}
@@ -0,0 +1,174 @@
+// internal/handler/import_handler.go
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// MaxImportFileSize bounds the size of an uploaded import file.
+const MaxImportFileSize = 32 << 20
+
+type ImportHandler struct {
+	importService service.BookImportService
+}
+
+func NewImportHandler(importService service.BookImportService) *ImportHandler {
+	return &ImportHandler{importService: importService}
+}
+
+// StartImport accepts a multipart upload in the "file" field or the file as the
+// raw request body. The format comes from the format query parameter, else from
+// the file extension or content type.
+func (h *ImportHandler) StartImport(c *gin.Context) {
+	opts := service.BookImportOptions{
+		Format: c.Query("format"),
+		Mode:   c.Query("mode"),
+	}
+	if raw := c.Query("dry_run"); raw != "" {
+		dryRun, err := strconv.ParseBool(raw)
+		if err != nil {
+			httpresponse.Error(c, apperror.BadRequest("dry_run must be a boolean"))
+			return
+		}
+		opts.DryRun = dryRun
+	}
+	if raw := c.Query("batch_size"); raw != "" {
+		batchSize, err := strconv.Atoi(raw)
+		if err != nil {
+			httpresponse.Error(c, apperror.BadRequest("batch_size must be an integer"))
+			return
+		}
+		opts.BatchSize = batchSize
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportFileSize)
+
+	var body io.Reader = c.Request.Body
+	contentType := c.ContentType()
+	if contentType == "multipart/form-data" {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			httpresponse.Error(c, importUploadError(err, "multipart upload must include a file field"))
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			httpresponse.Error(c, apperror.Internal("failed to read upload", err))
+			return
+		}
+		defer file.Close()
+
+		body = file
+		opts.FileName = filepath.Base(fileHeader.Filename)
+		contentType = fileHeader.Header.Get("Content-Type")
+	}
+
+	if opts.Format == "" {
+		opts.Format = importFormatOf(opts.FileName, contentType)
+	}
+	if opts.Format == "" {
+		httpresponse.Error(c, apperror.BadRequest("cannot determine import format; pass format=csv, jsonl, json, marc or marcxml"))
+		return
+	}
+
+	job, err := h.importService.StartImport(c.Request.Context(), actorOf(c), body, opts)
+	if err != nil {
+		httpresponse.Error(c, importUploadError(err, ""))
+		return
+	}
+
+	c.Header("Location", "/api/v1/books/import/"+strconv.FormatUint(uint64(job.ID), 10))
+	httpresponse.Success(c, http.StatusAccepted, "Import started", job, nil)
+}
+
+func (h *ImportHandler) GetImportJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid import job ID"))
+		return
+	}
+
+	job, err := h.importService.GetImportJob(c.Request.Context(), uint(id))
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	progress := 0
+	if job.TotalRows > 0 {
+		progress = job.ProcessedRows * 100 / job.TotalRows
+	}
+	httpresponse.Success(c, http.StatusOK, "", job, gin.H{"progress_percent": progress})
+}
+
+func (h *ImportHandler) ListImportIssues(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid import job ID"))
+		return
+	}
+
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 50,
+		MaxLimit:     500,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	issues, info, err := h.importService.ListImportIssues(c.Request.Context(), uint(id), params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", issues, query.PageMeta(params.PageRequest(), info))
+}
+
+func importFormatOf(fileName, contentType string) string {
+	switch ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), ".")); ext {
+	case "csv", "jsonl", "ndjson", "json", "marc", "mrc", "marcxml", "xml":
+		return ext
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return service.ImportFormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return service.ImportFormatJSONL
+	case "application/json":
+		return service.ImportFormatJSON
+	case "application/marc":
+		return service.FormatMARC
+	case "application/marcxml+xml", "application/xml", "text/xml":
+		return service.FormatMARCXML
+	}
+	return ""
+}
+
+// importUploadError maps an oversized body to 413 and keeps other errors.
+func importUploadError(err error, fallback string) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || strings.Contains(err.Error(), "request body too large") {
+		return apperror.TooLarge("import file exceeds 32 MiB")
+	}
+	if fallback != "" {
+		return apperror.BadRequest(fallback)
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+// internal/handler/settings_handler.go
+package handler
+
+import (
+	"net/http"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type SettingsHandler struct {
+	settingsService service.SettingsService
+}
+
+func NewSettingsHandler(settingsService service.SettingsService) *SettingsHandler {
+	return &SettingsHandler{settingsService: settingsService}
+}
+
+func (h *SettingsHandler) ListSettings(c *gin.Context) {
+	settings, err := h.settingsService.ListSettings(c.Request.Context())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", settings, nil)
+}
+
+func (h *SettingsHandler) GetSetting(c *gin.Context) {
+	setting, err := h.settingsService.GetSetting(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	if notModified(c, setting.Version) {
+		return
+	}
+	setETag(c, setting.Version)
+
+	httpresponse.Success(c, http.StatusOK, "", setting, nil)
+}
+
+func (h *SettingsHandler) UpdateSetting(c *gin.Context) {
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	var req dto.UpdateSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	setting, err := h.settingsService.UpdateSetting(c.Request.Context(), c.GetUint("user_id"), c.Param("key"), version, req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, setting.Version)
+	httpresponse.Success(c, http.StatusOK, "Setting updated successfully", setting, nil)
+}
+
+func (h *SettingsHandler) ResetSetting(c *gin.Context) {
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	var req dto.ResetSettingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httpresponse.Error(c, apperror.BadRequest(err.Error()))
+			return
+		}
+	}
+
+	setting, err := h.settingsService.ResetSetting(c.Request.Context(), c.GetUint("user_id"), c.Param("key"), version, req.Reason)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, setting.Version)
+	httpresponse.Success(c, http.StatusOK, "Setting reset to default", setting, nil)
+}
+
+// GetHistory lists setting changes, newest first. It covers every key unless
+// the route has a :key parameter.
+func (h *SettingsHandler) GetHistory(c *gin.Context) {
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	changes, info, err := h.settingsService.ListChanges(c.Request.Context(), c.Param("key"), params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", changes, query.PageMeta(params.PageRequest(), info))
+}
@@ -0,0 +1,164 @@
+// internal/handler/shelf_handler.go
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+var shelfListContentTypes = map[string]string{
+	service.ShelfListFormatCSV:  "text/csv; charset=utf-8",
+	service.ShelfListFormatHTML: "text/html; charset=utf-8",
+}
+
+// ShelfHandler serves books in shelf order: browsing the shelf and shelf
+// lists.
+type ShelfHandler struct {
+	shelfService service.ShelfService
+}
+
+func NewShelfHandler(shelfService service.ShelfService) *ShelfHandler {
+	return &ShelfHandler{shelfService: shelfService}
+}
+
+// BrowseBook returns the books shelved on either side of a book; before and
+// after say how many, branch_id keeps those with a copy at a branch.
+func (h *ShelfHandler) BrowseBook(c *gin.Context) {
+	id, ok := idParam(c, "book")
+	if !ok {
+		return
+	}
+	req, err := parseShelfBrowse(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	view, err := h.shelfService.BrowseBook(c.Request.Context(), id, req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", view, nil)
+}
+
+// BrowseCallNumber returns the books shelved around where the call_number
+// query would stand, with the parameters of BrowseBook.
+func (h *ShelfHandler) BrowseCallNumber(c *gin.Context) {
+	callNumber := c.Query("call_number")
+	if strings.TrimSpace(callNumber) == "" {
+		httpresponse.Error(c, apperror.BadRequest("call_number query is required"))
+		return
+	}
+	req, err := parseShelfBrowse(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	view, err := h.shelfService.BrowseCallNumber(c.Request.Context(), strings.ToLower(c.Query("classification")), callNumber, req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", view, nil)
+}
+
+// ShelfList returns a shelf list as JSON, or with format=csv or format=html
+// as a file to print. It is narrowed by branch_id, location, classification
+// and a from/to call number range.
+func (h *ShelfHandler) ShelfList(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", "json"))
+	if format != "json" && shelfListContentTypes[format] == "" {
+		httpresponse.Error(c, apperror.BadRequest("format must be json, csv or html"))
+		return
+	}
+	branchID, err := uintQuery(c, "branch_id")
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	limit, err := intQuery(c, "limit", 0)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	list, err := h.shelfService.ShelfList(c.Request.Context(), dto.ShelfListRequest{
+		BranchID:       branchID,
+		Location:       c.Query("location"),
+		Classification: strings.ToLower(c.Query("classification")),
+		From:           c.Query("from"),
+		To:             c.Query("to"),
+		Limit:          limit,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	if format == "json" {
+		httpresponse.Success(c, http.StatusOK, "", list, nil)
+		return
+	}
+	if format == service.ShelfListFormatCSV {
+		fileName := "shelf-list-" + list.GeneratedAt.Format("20060102T150405Z") + ".csv"
+		c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	}
+	c.Header("Content-Type", shelfListContentTypes[format])
+	c.Status(http.StatusOK)
+	if err := service.WriteShelfList(c.Writer, format, list); err != nil {
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+func parseShelfBrowse(c *gin.Context) (dto.ShelfBrowseRequest, error) {
+	var req dto.ShelfBrowseRequest
+	var err error
+	if req.BranchID, err = uintQuery(c, "branch_id"); err != nil {
+		return req, err
+	}
+	if req.Before, err = intQuery(c, "before", service.DefaultShelfBrowse); err != nil {
+		return req, err
+	}
+	if req.After, err = intQuery(c, "after", service.DefaultShelfBrowse); err != nil {
+		return req, err
+	}
+	return req, nil
+}
+
+// uintQuery parses an ID query parameter; it is 0 when absent.
+func uintQuery(c *gin.Context, name string) (uint, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, apperror.BadRequest("invalid " + name + " query")
+	}
+	return uint(parsed), nil
+}
+
+// intQuery parses an integer query parameter, fallback when absent.
+func intQuery(c *gin.Context, name string, fallback int) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, apperror.BadRequest("invalid " + name + " query")
+	}
+	return parsed, nil
+}
@@ -0,0 +1,183 @@
+// internal/handler/stocktake_handler.go
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// StocktakeHandler serves stocktakes: scanning the shelves of a branch and
+// fixing what the scans show the catalog has wrong.
+type StocktakeHandler struct {
+	stocktakeService service.StocktakeService
+}
+
+func NewStocktakeHandler(stocktakeService service.StocktakeService) *StocktakeHandler {
+	return &StocktakeHandler{stocktakeService: stocktakeService}
+}
+
+// ListStocktakes lists the stocktakes, newest first; status picks those of
+// one status and branch_id those of a branch.
+func (h *StocktakeHandler) ListStocktakes(c *gin.Context) {
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", models.StocktakeOpen, models.StocktakeCompleted, models.StocktakeCancelled:
+	default:
+		httpresponse.Error(c, apperror.BadRequest("status must be open, completed or cancelled"))
+		return
+	}
+	branchID, err := uintQuery(c, "branch_id")
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	stocktakes, info, err := h.stocktakeService.ListStocktakes(c.Request.Context(), status, branchID, params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	meta := query.PageMeta(params.PageRequest(), info)
+	meta["status"] = status
+	httpresponse.Success(c, http.StatusOK, "", stocktakes, meta)
+}
+
+func (h *StocktakeHandler) GetStocktake(c *gin.Context) {
+	id, ok := idParam(c, "stocktake")
+	if !ok {
+		return
+	}
+
+	stocktake, err := h.stocktakeService.GetStocktake(c.Request.Context(), id)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", stocktake, nil)
+}
+
+func (h *StocktakeHandler) StartStocktake(c *gin.Context) {
+	var req dto.StocktakeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	stocktake, err := h.stocktakeService.StartStocktake(c.Request.Context(), c.GetUint("user_id"), req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusCreated, "Stocktake started successfully", stocktake, nil)
+}
+
+// Scan records a batch of barcodes read off the shelf.
+func (h *StocktakeHandler) Scan(c *gin.Context) {
+	id, ok := idParam(c, "stocktake")
+	if !ok {
+		return
+	}
+	var req dto.StocktakeScanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	result, err := h.stocktakeService.Scan(c.Request.Context(), id, c.GetUint("user_id"), req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Scans recorded successfully", result, nil)
+}
+
+func (h *StocktakeHandler) CompleteStocktake(c *gin.Context) {
+	h.closeStocktake(c, h.stocktakeService.CompleteStocktake, "Stocktake completed successfully")
+}
+
+func (h *StocktakeHandler) CancelStocktake(c *gin.Context) {
+	h.closeStocktake(c, h.stocktakeService.CancelStocktake, "Stocktake cancelled successfully")
+}
+
+// Report compares what the stocktake scanned with what the catalog expects.
+func (h *StocktakeHandler) Report(c *gin.Context) {
+	id, ok := idParam(c, "stocktake")
+	if !ok {
+		return
+	}
+
+	report, err := h.stocktakeService.Report(c.Request.Context(), id)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", report, nil)
+}
+
+func (h *StocktakeHandler) MarkMissing(c *gin.Context) {
+	h.fixCopies(c, h.stocktakeService.MarkMissing, "Copies marked missing successfully")
+}
+
+func (h *StocktakeHandler) FixLocation(c *gin.Context) {
+	h.fixCopies(c, h.stocktakeService.FixLocation, "Copy locations fixed successfully")
+}
+
+// closeStocktake ends the stocktake in the :id parameter.
+func (h *StocktakeHandler) closeStocktake(c *gin.Context, step func(ctx context.Context, id uint) (*models.Stocktake, error), message string) {
+	id, ok := idParam(c, "stocktake")
+	if !ok {
+		return
+	}
+
+	stocktake, err := step(c.Request.Context(), id)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, message, stocktake, nil)
+}
+
+// fixCopies runs an action on copies of the report of the stocktake in the
+// :id parameter and returns the report as it is after it.
+func (h *StocktakeHandler) fixCopies(c *gin.Context, action func(ctx context.Context, id uint, req dto.StocktakeCopiesRequest) (*dto.StocktakeReport, error), message string) {
+	id, ok := idParam(c, "stocktake")
+	if !ok {
+		return
+	}
+	var req dto.StocktakeCopiesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	report, err := action(c.Request.Context(), id, req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, message, report, nil)
+}
@@ -0,0 +1,254 @@
+// internal/handler/subject_handler.go
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type SubjectHandler struct {
+	subjectService service.SubjectService
+}
+
+func NewSubjectHandler(subjectService service.SubjectService) *SubjectHandler {
+	return &SubjectHandler{subjectService: subjectService}
+}
+
+// subjectIDParam parses the :id route parameter, answering 400 when it is not
+// a valid ID.
+func subjectIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid subject ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// ListSubjects returns the taxonomy as a tree with book counts per subtree.
+func (h *SubjectHandler) ListSubjects(c *gin.Context) {
+	subjects, err := h.subjectService.ListSubjects(c.Request.Context())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", subjects, nil)
+}
+
+func (h *SubjectHandler) GetSubject(c *gin.Context) {
+	id, ok := subjectIDParam(c)
+	if !ok {
+		return
+	}
+
+	subject, err := h.subjectService.GetSubject(c.Request.Context(), id)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	if notModified(c, subject.Version) {
+		return
+	}
+	setETag(c, subject.Version)
+
+	httpresponse.Success(c, http.StatusOK, "", subject, nil)
+}
+
+// ListSubjectBooks lists the books of a subject and its descendants;
+// descendants=false keeps only books assigned to the subject itself.
+func (h *SubjectHandler) ListSubjectBooks(c *gin.Context) {
+	id, ok := subjectIDParam(c)
+	if !ok {
+		return
+	}
+
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 10,
+		MaxLimit:     100,
+		DefaultSort:  "title_asc",
+		AllowedSorts: bookSorts,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	descendants, err := strconv.ParseBool(c.DefaultQuery("descendants", "true"))
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("descendants must be a boolean"))
+		return
+	}
+
+	books, info, err := h.subjectService.ListSubjectBooks(c.Request.Context(), id, descendants, params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	meta := query.PageMeta(params.PageRequest(), info)
+	meta["descendants"] = descendants
+	httpresponse.Success(c, http.StatusOK, "", books, meta)
+}
+
+func (h *SubjectHandler) CreateSubject(c *gin.Context) {
+	var req dto.CreateSubjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	subject, err := h.subjectService.CreateSubject(c.Request.Context(), req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, subject.Version)
+	httpresponse.Success(c, http.StatusCreated, "Subject created successfully", subject, nil)
+}
+
+func (h *SubjectHandler) UpdateSubject(c *gin.Context) {
+	id, ok := subjectIDParam(c)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	var req dto.UpdateSubjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	subject, err := h.subjectService.UpdateSubject(c.Request.Context(), id, version, req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, subject.Version)
+	httpresponse.Success(c, http.StatusOK, "Subject updated successfully", subject, nil)
+}
+
+func (h *SubjectHandler) MoveSubject(c *gin.Context) {
+	id, ok := subjectIDParam(c)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	var req dto.MoveSubjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	subject, err := h.subjectService.MoveSubject(c.Request.Context(), id, version, req.ParentID)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, subject.Version)
+	httpresponse.Success(c, http.StatusOK, "Subject moved successfully", subject, nil)
+}
+
+func (h *SubjectHandler) DeleteSubject(c *gin.Context) {
+	id, ok := subjectIDParam(c)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	if err := h.subjectService.DeleteSubject(c.Request.Context(), id, version); err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Subject deleted successfully", nil, nil)
+}
+
+func (h *SubjectHandler) AddAlias(c *gin.Context) {
+	id, ok := subjectIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SubjectAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	alias, err := h.subjectService.AddAlias(c.Request.Context(), id, req.Name)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusCreated, "Subject alias added", alias, nil)
+}
+
+func (h *SubjectHandler) RemoveAlias(c *gin.Context) {
+	id, ok := subjectIDParam(c)
+	if !ok {
+		return
+	}
+	aliasID, err := strconv.ParseUint(c.Param("aliasId"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid alias ID"))
+		return
+	}
+
+	if err := h.subjectService.RemoveAlias(c.Request.Context(), id, uint(aliasID)); err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Subject alias removed", nil, nil)
+}
+
+// MapGenres maps the free-text genres of books onto subjects. It only reports
+// the mapping unless apply=true; create=true adds a root subject for each
+// genre that matches none.
+func (h *SubjectHandler) MapGenres(c *gin.Context) {
+	apply, err := strconv.ParseBool(c.DefaultQuery("apply", "false"))
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("apply must be a boolean"))
+		return
+	}
+	create, err := strconv.ParseBool(c.DefaultQuery("create", "false"))
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("create must be a boolean"))
+		return
+	}
+
+	result, err := h.subjectService.MapGenres(c.Request.Context(), apply, create)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "", result, nil)
+}
@@ -0,0 +1,50 @@
+// internal/handler/user_handler.go
+package handler
+
+import (
+	"net/http"
+
+	"github.com/alpardfm/library-management-api/internal/service"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type UserHandler struct {
+	userService service.UserService
+}
+
+func NewUserHandler(userService service.UserService) *UserHandler {
+	return &UserHandler{userService: userService}
+}
+
+// DeleteUser deactivates and soft-deletes a user without active borrows.
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, ok := idParam(c, "user")
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.DeleteUser(c.Request.Context(), id)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "User deleted successfully", user, nil)
+}
+
+// RestoreUser brings back a deleted user that has not been purged yet.
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	id, ok := idParam(c, "user")
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.RestoreUser(c.Request.Context(), id)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "User restored successfully", user, nil)
+}
@@ -0,0 +1,257 @@
+// internal/handler/work_handler.go
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	httpresponse "github.com/alpardfm/library-management-api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// WorkHandler serves works, which group the editions of a book, and the
+// series works are volumes of.
+type WorkHandler struct {
+	workService service.WorkService
+}
+
+func NewWorkHandler(workService service.WorkService) *WorkHandler {
+	return &WorkHandler{workService: workService}
+}
+
+// idParam parses the :id route parameter, answering 400 when it is not a
+// valid ID; what names the resource in the message.
+func idParam(c *gin.Context, what string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httpresponse.Error(c, apperror.BadRequest("invalid "+what+" ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// ListWorks lists works by title; series_id narrows it to one series.
+func (h *WorkHandler) ListWorks(c *gin.Context) {
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	var seriesID uint
+	if value := c.Query("series_id"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			httpresponse.Error(c, apperror.BadRequest("invalid series_id query"))
+			return
+		}
+		seriesID = uint(parsed)
+	}
+
+	works, info, err := h.workService.ListWorks(c.Request.Context(), params.Search, seriesID, params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	meta := query.PageMeta(params.PageRequest(), info)
+	meta["search"] = params.Search
+	httpresponse.Success(c, http.StatusOK, "", works, meta)
+}
+
+// GetWork returns a work with its editions.
+func (h *WorkHandler) GetWork(c *gin.Context) {
+	id, ok := idParam(c, "work")
+	if !ok {
+		return
+	}
+
+	work, err := h.workService.GetWork(c.Request.Context(), id)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	if notModified(c, work.Version) {
+		return
+	}
+	setETag(c, work.Version)
+
+	httpresponse.Success(c, http.StatusOK, "", work, nil)
+}
+
+func (h *WorkHandler) CreateWork(c *gin.Context) {
+	var req dto.WorkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	work, err := h.workService.CreateWork(c.Request.Context(), req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, work.Version)
+	httpresponse.Success(c, http.StatusCreated, "Work created successfully", work, nil)
+}
+
+func (h *WorkHandler) UpdateWork(c *gin.Context) {
+	id, ok := idParam(c, "work")
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	var req dto.WorkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	work, err := h.workService.UpdateWork(c.Request.Context(), id, version, req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, work.Version)
+	httpresponse.Success(c, http.StatusOK, "Work updated successfully", work, nil)
+}
+
+func (h *WorkHandler) DeleteWork(c *gin.Context) {
+	id, ok := idParam(c, "work")
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	if err := h.workService.DeleteWork(c.Request.Context(), id, version); err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Work deleted successfully", nil, nil)
+}
+
+func (h *WorkHandler) ListSeries(c *gin.Context) {
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	})
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	series, info, err := h.workService.ListSeries(c.Request.Context(), params.Search, params.PageRequest())
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	meta := query.PageMeta(params.PageRequest(), info)
+	meta["search"] = params.Search
+	httpresponse.Success(c, http.StatusOK, "", series, meta)
+}
+
+// GetSeries returns a series with its works in volume order.
+func (h *WorkHandler) GetSeries(c *gin.Context) {
+	id, ok := idParam(c, "series")
+	if !ok {
+		return
+	}
+
+	series, err := h.workService.GetSeries(c.Request.Context(), id)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	if notModified(c, series.Version) {
+		return
+	}
+	setETag(c, series.Version)
+
+	httpresponse.Success(c, http.StatusOK, "", series, nil)
+}
+
+func (h *WorkHandler) CreateSeries(c *gin.Context) {
+	var req dto.SeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	series, err := h.workService.CreateSeries(c.Request.Context(), req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, series.Version)
+	httpresponse.Success(c, http.StatusCreated, "Series created successfully", series, nil)
+}
+
+func (h *WorkHandler) UpdateSeries(c *gin.Context) {
+	id, ok := idParam(c, "series")
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+	var req dto.SeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpresponse.Error(c, apperror.BadRequest(err.Error()))
+		return
+	}
+
+	series, err := h.workService.UpdateSeries(c.Request.Context(), id, version, req)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	setETag(c, series.Version)
+	httpresponse.Success(c, http.StatusOK, "Series updated successfully", series, nil)
+}
+
+func (h *WorkHandler) DeleteSeries(c *gin.Context) {
+	id, ok := idParam(c, "series")
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	if err := h.workService.DeleteSeries(c.Request.Context(), id, version); err != nil {
+		httpresponse.Error(c, err)
+		return
+	}
+
+	httpresponse.Success(c, http.StatusOK, "Series deleted successfully", nil, nil)
+}
@@ -0,0 +1,33 @@
+// internal/models/account.go
+package models
+
+import "time"
+
+// Account entry kinds.
+const (
+	// ChargeReplacement bills the price of a lost or damaged copy.
+	ChargeReplacement = "replacement"
+	// ChargeProcessing is the fee for handling a lost or damaged copy; it
+	// is kept when the copy turns up.
+	ChargeProcessing = "processing_fee"
+	// ChargeRefund gives back a replacement charge for a lost copy that was
+	// found and returned.
+	ChargeRefund = "refund"
+)
+
+// AccountEntry is a line on a patron's account. Amounts are owed by the
+// patron; refunds are negative. The balance is the sum of the entries.
+type AccountEntry struct {
+	ID     uint `gorm:"primaryKey" json:"id"`
+	UserID uint `gorm:"not null;index" json:"user_id"`
+	// BorrowRecordID is the loan the entry is for.
+	BorrowRecordID *uint     `gorm:"index" json:"borrow_record_id,omitempty"`
+	BookID         *uint     `json:"book_id,omitempty"`
+	Kind           string    `gorm:"size:20;not null" json:"kind"`
+	Amount         int       `gorm:"not null" json:"amount"`
+	Note           string    `gorm:"size:255" json:"note,omitempty"`
+	CreatedBy      uint      `json:"created_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:RESTRICT" json:"-"`
+}
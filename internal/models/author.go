@@ -0,0 +1,55 @@
+// internal/models/author.go
+package models
+
+import (
+	"time"
+)
+
+// Contributor roles a person can have on a book.
+const (
+	RoleAuthor      = "author"
+	RoleEditor      = "editor"
+	RoleTranslator  = "translator"
+	RoleIllustrator = "illustrator"
+)
+
+// ContributorRoles lists the valid roles in display order.
+var ContributorRoles = []string{RoleAuthor, RoleEditor, RoleTranslator, RoleIllustrator}
+
+// Author is a person or organisation credited on books. NameKey is the
+// normalized name used to match catalog input against existing authors.
+type Author struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:255;not null" json:"name"`
+	SortName  string    `gorm:"size:255;not null;index" json:"sort_name"`
+	NameKey   string    `gorm:"size:255;not null;index" json:"-"`
+	Bio       string    `gorm:"type:text" json:"bio,omitempty"`
+	BookCount int64     `gorm:"->;-:migration" json:"book_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Version counts the writes to the row, like Book.Version.
+	Version uint `gorm:"not null;default:1" json:"version"`
+
+	Variants []AuthorVariant `gorm:"foreignKey:AuthorID;constraint:OnDelete:CASCADE" json:"variants,omitempty"`
+}
+
+// AuthorVariant is another form of an author's name, such as a pseudonym or a
+// transliteration. A variant key belongs to exactly one author.
+type AuthorVariant struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	AuthorID  uint      `gorm:"not null;index" json:"author_id"`
+	Name      string    `gorm:"size:255;not null" json:"name"`
+	NameKey   string    `gorm:"size:255;not null;uniqueIndex" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BookContributor links a book to an author in one role. Position orders the
+// contributors of a book as they are credited.
+type BookContributor struct {
+	BookID   uint   `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	AuthorID uint   `gorm:"primaryKey;autoIncrement:false;index" json:"author_id"`
+	Role     string `gorm:"primaryKey;size:20" json:"role"`
+	Position int    `gorm:"not null;default:0" json:"position"`
+
+	Author *Author `gorm:"foreignKey:AuthorID;constraint:OnDelete:RESTRICT" json:"author,omitempty"`
+}
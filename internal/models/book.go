@@ -5,31 +5,138 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/alpardfm/library-management-api/pkg/callnumber"
+	"github.com/alpardfm/library-management-api/pkg/isbn"
 	"gorm.io/gorm"
 )
 
+// Item types a catalog entry can have.
+const (
+	ItemTypeBook       = "book"
+	ItemTypeEbook      = "ebook"
+	ItemTypeAudiobook  = "audiobook"
+	ItemTypePeriodical = "periodical"
+	ItemTypeVideo      = "video"
+	ItemTypeMusic      = "music"
+	ItemTypeMap        = "map"
+)
+
+// ItemTypes lists every item type.
+var ItemTypes = []string{
+	ItemTypeBook, ItemTypeEbook, ItemTypeAudiobook, ItemTypePeriodical,
+	ItemTypeVideo, ItemTypeMusic, ItemTypeMap,
+}
+
+// Catalog statuses. Withdrawn (weeded, lost, damaged) and archived books are
+// soft-deleted: hidden from listings and lookups until restored.
+const (
+	BookStatusActive    = "active"
+	BookStatusWithdrawn = "withdrawn"
+	BookStatusArchived  = "archived"
+)
+
+// Cover renditions: the uploaded original and the generated thumbnails.
+const (
+	CoverOriginal = "original"
+	CoverSmall    = "small"
+	CoverMedium   = "medium"
+	CoverLarge    = "large"
+)
+
+// CoverWidths maps each thumbnail size to its width in pixels.
+var CoverWidths = map[string]int{
+	CoverSmall:  120,
+	CoverMedium: 300,
+	CoverLarge:  600,
+}
+
+// BookCover holds the URLs of a book's cover image and its thumbnails. The
+// version query parameter changes with every upload, so they can be cached.
+type BookCover struct {
+	URL        string            `json:"url"`
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
 type Book struct {
 	ID              uint      `gorm:"primaryKey" json:"id"`
 	ISBN            string    `gorm:"uniqueIndex;size:13;not null" json:"isbn"`
+	ISBN10          string    `gorm:"-" json:"isbn_10,omitempty"`
+	ISBNFormatted   string    `gorm:"-" json:"isbn_formatted,omitempty"`
 	Title           string    `gorm:"size:255;not null" json:"title"`
 	Author          string    `gorm:"size:255;not null" json:"author"`
 	Publisher       string    `gorm:"size:100" json:"publisher,omitempty"`
 	PublicationYear int       `json:"publication_year,omitempty"`
 	Genre           string    `gorm:"size:50" json:"genre,omitempty"`
+	Language        string    `gorm:"size:3;index" json:"language,omitempty"`
+	ItemType        string    `gorm:"size:20;not null;default:book;index" json:"item_type"`
+	WorkID          *uint     `gorm:"index" json:"work_id,omitempty"`
 	Description     string    `gorm:"type:text" json:"description,omitempty"`
 	TotalCopies     int       `gorm:"default:1" json:"total_copies"`
 	AvailableCopies int       `gorm:"default:1;check:available_copies_non_negative,available_copies >= 0;check:available_copies_not_exceed_total,available_copies <= total_copies" json:"available_copies"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 
+	// Classification is the scheme of CallNumber: ddc (Dewey) or lcc
+	// (Library of Congress). ShelfLocation is where the book is shelved,
+	// such as "Stacks, 2nd floor"; a copy can be shelved elsewhere at its
+	// branch.
+	Classification string `gorm:"size:3" json:"classification,omitempty"`
+	CallNumber     string `gorm:"size:100" json:"call_number,omitempty"`
+	ShelfLocation  string `gorm:"size:100;index" json:"shelf_location,omitempty"`
+	// ShelfKey is the call number in a form that sorts in shelf order, or
+	// callnumber.NoKey, which sorts last, for books without one.
+	ShelfKey string `gorm:"size:255;not null;default:~;index" json:"-"`
+	// Price is what a copy costs to replace, billed to a patron who loses
+	// or damages one.
+	Price int `gorm:"not null;default:0" json:"price"`
+	// Version counts the writes to the row. Updates name the version they
+	// were made from, so concurrent edits cannot overwrite each other.
+	Version uint `gorm:"not null;default:1" json:"version"`
+
+	// Status is active unless the book was removed from the catalog, when
+	// StatusReason says why and DeletedAt when.
+	Status       string         `gorm:"size:20;not null;default:active;index" json:"status"`
+	StatusReason string         `gorm:"size:255" json:"status_reason,omitempty"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	// Cover image: CoverVersion names the stored renditions and changes with
+	// every upload, CoverType is the media type of the original. Cover holds
+	// the URLs to fetch them.
+	CoverVersion string     `gorm:"size:16" json:"-"`
+	CoverType    string     `gorm:"size:20" json:"-"`
+	Cover        *BookCover `gorm:"-" json:"cover,omitempty"`
+
+	// Full-text search results only: relevance, and the title and a
+	// description excerpt with matches wrapped in <mark>.
+	SearchRank     float64 `gorm:"->;-:migration" json:"search_rank,omitempty"`
+	TitleHighlight string  `gorm:"->;-:migration" json:"title_highlight,omitempty"`
+	Snippet        string  `gorm:"->;-:migration" json:"snippet,omitempty"`
+
+	// Listings collapsed by work only: the matching editions of the work
+	// this book stands for, and how many of them have a copy on the shelf.
+	EditionCount      int64 `gorm:"-" json:"edition_count,omitempty"`
+	AvailableEditions int64 `gorm:"-" json:"available_editions,omitempty"`
+
+	// Holdings is the availability per branch of the registered copies.
+	Holdings []BranchHolding `gorm:"-" json:"holdings,omitempty"`
+
 	// Relations
-	BorrowRecords []BorrowRecord `gorm:"foreignKey:BookID" json:"borrow_records,omitempty"`
+	BorrowRecords []BorrowRecord    `gorm:"foreignKey:BookID" json:"borrow_records,omitempty"`
+	Contributors  []BookContributor `gorm:"foreignKey:BookID;constraint:OnDelete:CASCADE" json:"contributors,omitempty"`
+	Subjects      []Subject         `gorm:"many2many:book_subjects;constraint:OnDelete:CASCADE" json:"subjects,omitempty"`
 }
 
 func (b *Book) BeforeCreate(tx *gorm.DB) error {
 	b.CreatedAt = time.Now()
 	b.UpdatedAt = time.Now()
 
+	if b.ItemType == "" {
+		b.ItemType = ItemTypeBook
+	}
+	if b.Status == "" {
+		b.Status = BookStatusActive
+	}
+
 	// Initialize available copies
 	if b.AvailableCopies == 0 && b.TotalCopies > 0 {
 		b.AvailableCopies = b.TotalCopies
@@ -37,11 +144,63 @@ func (b *Book) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// BeforeSave keeps the shelf key in step with the call number.
+func (b *Book) BeforeSave(tx *gorm.DB) error {
+	b.ShelfKey = callnumber.NoKey
+	if parsed, err := callnumber.Parse(b.Classification, b.CallNumber); err == nil {
+		b.ShelfKey = parsed.Key()
+	}
+	return nil
+}
+
 func (b *Book) BeforeUpdate(tx *gorm.DB) error {
 	b.UpdatedAt = time.Now()
 	return nil
 }
 
+func (b *Book) AfterFind(tx *gorm.DB) error {
+	b.setISBNForms()
+	b.setCover()
+	return nil
+}
+
+func (b *Book) AfterSave(tx *gorm.DB) error {
+	b.setISBNForms()
+	b.setCover()
+	return nil
+}
+
+// setISBNForms fills the display-only ISBN fields from the stored ISBN-13.
+func (b *Book) setISBNForms() {
+	b.ISBNFormatted, b.ISBN10 = "", ""
+	if !isbn.Valid(b.ISBN) {
+		return
+	}
+	b.ISBNFormatted = isbn.Format(b.ISBN)
+	b.ISBN10, _ = isbn.ToISBN10(b.ISBN)
+}
+
+// setCover fills the cover URLs from the stored cover version.
+func (b *Book) setCover() {
+	b.Cover = nil
+	if b.CoverVersion == "" {
+		return
+	}
+	base := fmt.Sprintf("/api/v1/books/%d/cover", b.ID)
+	b.Cover = &BookCover{
+		URL:        base + "?v=" + b.CoverVersion,
+		Thumbnails: make(map[string]string, len(CoverWidths)),
+	}
+	for size := range CoverWidths {
+		b.Cover.Thumbnails[size] = base + "/" + size + "?v=" + b.CoverVersion
+	}
+}
+
+// Removed reports whether the book was withdrawn or archived.
+func (b *Book) Removed() bool {
+	return b.DeletedAt.Valid
+}
+
 // CanBorrow checks if book is available for borrowing
 func (b *Book) CanBorrow() bool {
 	return b.AvailableCopies > 0
@@ -0,0 +1,37 @@
+// internal/models/book_revision.go
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actions recorded in book revisions.
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+	RevisionRevert  = "revert"
+)
+
+// BookRevision records a change to a book's catalog record: who made it, in
+// which request, the fields it changed and the record as it stood after it.
+// Version is the book's version after the change; circulation also moves the
+// version but is not recorded, so versions in the history can skip numbers.
+type BookRevision struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	BookID  uint   `gorm:"not null;uniqueIndex:idx_book_revisions_book_version,priority:1" json:"book_id"`
+	Version uint   `gorm:"not null;uniqueIndex:idx_book_revisions_book_version,priority:2" json:"version"`
+	Action  string `gorm:"size:20;not null" json:"action"`
+	// SourceVersion is the version a revert brought back.
+	SourceVersion uint `json:"source_version,omitempty"`
+	// ActorID is 0 for changes made with libctl.
+	ActorID   uint   `gorm:"index" json:"actor_id"`
+	RequestID string `gorm:"size:128" json:"request_id,omitempty"`
+	// Snapshot is the catalog record in the form POST /books accepts;
+	// Changes lists the changed fields with their old and new values.
+	Snapshot  json.RawMessage `gorm:"serializer:json;type:text;not null" json:"snapshot"`
+	Changes   json.RawMessage `gorm:"serializer:json;type:text;not null" json:"changes"`
+	CreatedAt time.Time       `json:"created_at"`
+}
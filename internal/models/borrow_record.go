@@ -12,18 +12,31 @@ const (
 	StatusBorrowed BorrowStatus = "borrowed"
 	StatusReturned BorrowStatus = "returned"
 	StatusOverdue  BorrowStatus = "overdue"
+	// StatusLost and StatusDamaged close loans whose copy was lost, or came
+	// back damaged; the borrower is billed its replacement.
+	StatusLost    BorrowStatus = "lost"
+	StatusDamaged BorrowStatus = "damaged"
 )
 
 type BorrowRecord struct {
-	ID         uint         `gorm:"primaryKey" json:"id"`
-	UserID     uint         `gorm:"not null" json:"user_id"`
-	BookID     uint         `gorm:"not null" json:"book_id"`
+	ID     uint `gorm:"primaryKey" json:"id"`
+	UserID uint `gorm:"not null" json:"user_id"`
+	// BookID is nil once the book was purged; BookTitle and BookISBN then
+	// keep what was borrowed.
+	BookID     *uint        `gorm:"index" json:"book_id,omitempty"`
+	BookTitle  string       `gorm:"size:255" json:"book_title,omitempty"`
+	BookISBN   string       `gorm:"size:20" json:"book_isbn,omitempty"`
 	BorrowDate time.Time    `gorm:"not null" json:"borrow_date"`
 	DueDate    time.Time    `gorm:"not null" json:"due_date"`
 	ReturnDate *time.Time   `gorm:"index" json:"return_date,omitempty"`
 	Status     BorrowStatus `gorm:"type:varchar(20);default:'borrowed'" json:"status"`
-	CreatedAt  time.Time    `json:"created_at"`
-	UpdatedAt  time.Time    `json:"updated_at"`
+	// CopyID is the copy lent, unless an unassigned copy was. BranchID is
+	// where it was checked out and ReturnBranchID where it came back.
+	CopyID         *uint     `gorm:"index" json:"copy_id,omitempty"`
+	BranchID       *uint     `json:"branch_id,omitempty"`
+	ReturnBranchID *uint     `json:"return_branch_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Book Book `gorm:"foreignKey:BookID" json:"book,omitempty"`
@@ -43,7 +56,7 @@ func (br *BorrowRecord) BeforeCreate(tx *gorm.DB) error {
 func (br *BorrowRecord) BeforeUpdate(tx *gorm.DB) error {
 	br.UpdatedAt = time.Now()
 
-	if br.ReturnDate != nil && br.Status != StatusReturned {
+	if br.ReturnDate != nil && br.Status != StatusLost && br.Status != StatusDamaged {
 		br.Status = StatusReturned
 	} else if br.ReturnDate == nil && time.Now().After(br.DueDate) {
 		br.Status = StatusOverdue
@@ -0,0 +1,123 @@
+// internal/models/branch.go
+package models
+
+import "time"
+
+// Branch is a library location. Copies of books belong to a home branch.
+type Branch struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// Code is a short unique name such as "MAIN", used by libctl and on
+	// barcode labels.
+	Code      string    `gorm:"size:20;uniqueIndex;not null" json:"code"`
+	Name      string    `gorm:"size:255;not null" json:"name"`
+	Address   string    `gorm:"type:text" json:"address,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Version counts the writes to the row, like Book.Version.
+	Version uint `gorm:"not null;default:1" json:"version"`
+	// CopyCount is the number of copies the branch is home to.
+	CopyCount int64 `gorm:"->;-:migration" json:"copy_count"`
+}
+
+// Copy statuses. Only available copies count in Book.AvailableCopies.
+const (
+	CopyAvailable = "available"
+	CopyOnLoan    = "on_loan"
+	CopyInTransit = "in_transit"
+	// CopyOnHold is a copy set aside for a ready hold.
+	CopyOnHold = "on_hold"
+	// CopyMissing is a copy a stocktake did not find on the shelf.
+	CopyMissing = "missing"
+	// CopyLost and CopyDamaged are copies lost or damaged by a borrower.
+	// They no longer count in Book.TotalCopies.
+	CopyLost    = "lost"
+	CopyDamaged = "damaged"
+)
+
+// Copy is a physical copy of a book, identified by its barcode. A book's
+// copies not registered as Copy rows are unassigned: they count in its
+// totals but belong to no branch.
+type Copy struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	BookID  uint   `gorm:"not null;index" json:"book_id"`
+	Barcode string `gorm:"size:32;uniqueIndex;not null" json:"barcode"`
+	// HomeBranchID is where the copy is shelved; BranchID is where it is
+	// now, or was sent from while in transit.
+	HomeBranchID uint      `gorm:"not null;index" json:"home_branch_id"`
+	BranchID     uint      `gorm:"not null;index" json:"branch_id"`
+	Status       string    `gorm:"size:20;not null;default:available;index" json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	// ShelfLocation overrides the book's shelf location at the copy's home
+	// branch.
+	ShelfLocation string `gorm:"size:100" json:"shelf_location,omitempty"`
+
+	Book       *Book   `gorm:"foreignKey:BookID;constraint:OnDelete:CASCADE" json:"book,omitempty"`
+	HomeBranch *Branch `gorm:"foreignKey:HomeBranchID;constraint:OnDelete:RESTRICT" json:"home_branch,omitempty"`
+	Branch     *Branch `gorm:"foreignKey:BranchID;constraint:OnDelete:RESTRICT" json:"branch,omitempty"`
+}
+
+// Location is where the copy is shelved: its own shelf location or else its
+// book's. Book must be loaded for the latter.
+func (c *Copy) Location() string {
+	if c.ShelfLocation != "" || c.Book == nil {
+		return c.ShelfLocation
+	}
+	return c.Book.ShelfLocation
+}
+
+// Transfer statuses. A requested transfer is shipped, which puts the copy in
+// transit, and received at its destination.
+const (
+	TransferRequested = "requested"
+	TransferInTransit = "in_transit"
+	TransferReceived  = "received"
+	TransferCancelled = "cancelled"
+)
+
+// Transfer reasons.
+const (
+	// TransferReturn takes a copy returned at another branch home.
+	TransferReturn = "return"
+	// TransferRequest moves a copy on request of a librarian.
+	TransferRequest = "request"
+)
+
+// Transfer moves a copy between branches.
+type Transfer struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	CopyID       uint   `gorm:"not null;index" json:"copy_id"`
+	FromBranchID uint   `gorm:"not null;index" json:"from_branch_id"`
+	ToBranchID   uint   `gorm:"not null;index" json:"to_branch_id"`
+	Status       string `gorm:"size:20;not null;index" json:"status"`
+	Reason       string `gorm:"size:20;not null" json:"reason"`
+	// Permanent transfers make the destination the copy's home branch.
+	Permanent bool   `gorm:"not null;default:false" json:"permanent"`
+	Note      string `gorm:"size:255" json:"note,omitempty"`
+	// RequestedBy is the user who asked for the transfer; 0 for transfers
+	// of returns.
+	RequestedBy uint       `json:"requested_by,omitempty"`
+	ShippedAt   *time.Time `json:"shipped_at,omitempty"`
+	ReceivedAt  *time.Time `json:"received_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	Copy       *Copy   `gorm:"foreignKey:CopyID;constraint:OnDelete:CASCADE" json:"copy,omitempty"`
+	FromBranch *Branch `gorm:"foreignKey:FromBranchID;constraint:OnDelete:RESTRICT" json:"from_branch,omitempty"`
+	ToBranch   *Branch `gorm:"foreignKey:ToBranchID;constraint:OnDelete:RESTRICT" json:"to_branch,omitempty"`
+}
+
+// Open reports whether the transfer is still to be shipped or received.
+func (t *Transfer) Open() bool {
+	return t.Status == TransferRequested || t.Status == TransferInTransit
+}
+
+// BranchHolding is what a branch holds of a book: the copies it is home to
+// and those on its shelves now.
+type BranchHolding struct {
+	BranchID  uint   `json:"branch_id"`
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	Copies    int64  `json:"copies"`
+	Available int64  `json:"available"`
+}
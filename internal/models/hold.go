@@ -0,0 +1,41 @@
+// internal/models/hold.go
+package models
+
+import "time"
+
+// Hold statuses. A waiting hold becomes ready when a returned copy is set
+// aside for it, and fulfilled when the member borrows that copy.
+const (
+	HoldWaiting   = "waiting"
+	HoldReady     = "ready"
+	HoldFulfilled = "fulfilled"
+	HoldCancelled = "cancelled"
+)
+
+// Hold queues a member for a copy of a book that is out. Exactly one of
+// BookID and WorkID is set: a hold on one edition, or on whichever edition of
+// a work comes back first.
+type Hold struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	BookID *uint  `gorm:"index" json:"book_id,omitempty"`
+	WorkID *uint  `gorm:"index" json:"work_id,omitempty"`
+	Status string `gorm:"size:20;not null;default:waiting;index" json:"status"`
+	// ReadyBookID is the edition whose copy was set aside for a ready hold.
+	ReadyBookID *uint `json:"ready_book_id,omitempty"`
+	// ReadyCopyID is the copy set aside, when it is a registered one.
+	ReadyCopyID *uint      `json:"ready_copy_id,omitempty"`
+	ReadyAt     *time.Time `json:"ready_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	User      *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Book      *Book `gorm:"foreignKey:BookID;constraint:OnDelete:CASCADE" json:"book,omitempty"`
+	Work      *Work `gorm:"foreignKey:WorkID;constraint:OnDelete:CASCADE" json:"work,omitempty"`
+	ReadyBook *Book `gorm:"foreignKey:ReadyBookID;constraint:OnDelete:SET NULL" json:"ready_book,omitempty"`
+}
+
+// Active reports whether the hold still waits for or holds a copy.
+func (h *Hold) Active() bool {
+	return h.Status == HoldWaiting || h.Status == HoldReady
+}
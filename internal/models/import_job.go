@@ -0,0 +1,49 @@
+// internal/models/import_job.go
+package models
+
+import "time"
+
+type ImportJobStatus string
+
+const (
+	ImportJobPending   ImportJobStatus = "pending"
+	ImportJobRunning   ImportJobStatus = "running"
+	ImportJobCompleted ImportJobStatus = "completed"
+	ImportJobFailed    ImportJobStatus = "failed"
+)
+
+// ImportJob tracks an asynchronous catalog import. Counters are updated after
+// every batch so clients can poll progress.
+type ImportJob struct {
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	Status    ImportJobStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	Format    string          `gorm:"size:10;not null" json:"format"`
+	Mode      string          `gorm:"size:10;not null" json:"mode"`
+	DryRun    bool            `gorm:"not null" json:"dry_run"`
+	FileName  string          `gorm:"size:255" json:"file_name,omitempty"`
+	CreatedBy uint            `gorm:"not null" json:"created_by"`
+
+	TotalRows     int `gorm:"not null;default:0" json:"total_rows"`
+	ProcessedRows int `gorm:"not null;default:0" json:"processed_rows"`
+	Created       int `gorm:"not null;default:0" json:"created"`
+	Updated       int `gorm:"not null;default:0" json:"updated"`
+	Unchanged     int `gorm:"not null;default:0" json:"unchanged"`
+	Skipped       int `gorm:"not null;default:0" json:"skipped"`
+	Failed        int `gorm:"not null;default:0" json:"failed"`
+
+	Error      string     `gorm:"type:text" json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// ImportJobIssue records a row of an import job that was skipped or failed.
+type ImportJobIssue struct {
+	ID      uint   `gorm:"primaryKey" json:"-"`
+	JobID   uint   `gorm:"not null;index" json:"job_id"`
+	Line    int    `gorm:"not null" json:"line"`
+	ISBN    string `gorm:"size:32" json:"isbn,omitempty"`
+	Status  string `gorm:"size:20;not null" json:"status"`
+	Message string `gorm:"type:text" json:"message"`
+}
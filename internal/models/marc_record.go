@@ -0,0 +1,15 @@
+// internal/models/marc_record.go
+package models
+
+import "time"
+
+// MarcRecord keeps the MARC record a book was imported from, as MARCXML, so
+// fields the catalog does not model survive export.
+type MarcRecord struct {
+	BookID    uint      `gorm:"primaryKey;autoIncrement:false" json:"book_id"`
+	Record    string    `gorm:"type:text;not null" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Book *Book `gorm:"foreignKey:BookID;constraint:OnDelete:CASCADE" json:"-"`
+}
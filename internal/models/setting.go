@@ -0,0 +1,31 @@
+// internal/models/setting.go
+package models
+
+import "time"
+
+// Setting is a runtime override of a library setting. Keys without a row use
+// the value from the application config.
+type Setting struct {
+	Key       string    `gorm:"primaryKey;size:64" json:"key"`
+	Value     string    `gorm:"size:255;not null" json:"value"`
+	UpdatedBy uint      `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Version counts the changes to the setting, resets included, so an
+	// override created after a reset does not reuse an earlier version.
+	Version uint `gorm:"not null;default:1" json:"version"`
+}
+
+// SettingChange is one entry of the settings audit trail. A nil NewValue means
+// the override was removed and the config default applies again.
+//
+// IDs are also the settings revision: replicas compare the latest ID to decide
+// whether their cached values are stale.
+type SettingChange struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Key       string    `gorm:"size:64;not null;index" json:"key"`
+	OldValue  *string   `gorm:"size:255" json:"old_value"`
+	NewValue  *string   `gorm:"size:255" json:"new_value"`
+	ChangedBy uint      `gorm:"not null" json:"changed_by"`
+	Reason    string    `gorm:"size:255" json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,61 @@
+// internal/models/stocktake.go
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Stocktake statuses. Items are scanned while a stocktake is open; once
+// completed its report can still be acted on.
+const (
+	StocktakeOpen      = "open"
+	StocktakeCompleted = "completed"
+	StocktakeCancelled = "cancelled"
+)
+
+// Stocktake is an inventory of the copies shelved at a branch, or at one
+// shelf location of it, reconciled against the catalog.
+type Stocktake struct {
+	ID       uint `gorm:"primaryKey" json:"id"`
+	BranchID uint `gorm:"not null;index" json:"branch_id"`
+	// Location limits the stocktake to a shelf location, matched
+	// case-insensitively; empty covers the whole branch.
+	Location    string     `gorm:"size:100" json:"location,omitempty"`
+	Status      string     `gorm:"size:20;not null;index" json:"status"`
+	Note        string     `gorm:"size:255" json:"note,omitempty"`
+	StartedBy   uint       `gorm:"not null" json:"started_by"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// ScanCount is the number of distinct items scanned.
+	ScanCount int64 `gorm:"->;-:migration" json:"scan_count"`
+
+	Branch *Branch `gorm:"foreignKey:BranchID;constraint:OnDelete:RESTRICT" json:"branch,omitempty"`
+}
+
+// StocktakeScan is an item identifier scanned during a stocktake. CopyID is
+// the copy it identified when scanned, nil for barcodes the catalog does not
+// know. Scanning an item again keeps the first scan.
+type StocktakeScan struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	StocktakeID uint      `gorm:"not null;uniqueIndex:idx_stocktake_scan" json:"stocktake_id"`
+	Barcode     string    `gorm:"size:32;not null;uniqueIndex:idx_stocktake_scan" json:"barcode"`
+	CopyID      *uint     `gorm:"index" json:"copy_id,omitempty"`
+	ScannedBy   uint      `gorm:"not null" json:"scanned_by"`
+	ScannedAt   time.Time `gorm:"not null" json:"scanned_at"`
+
+	Stocktake *Stocktake `gorm:"foreignKey:StocktakeID;constraint:OnDelete:CASCADE" json:"-"`
+	Copy      *Copy      `gorm:"foreignKey:CopyID;constraint:OnDelete:SET NULL" json:"copy,omitempty"`
+}
+
+// Open reports whether items can still be scanned.
+func (s *Stocktake) Open() bool {
+	return s.Status == StocktakeOpen
+}
+
+// Covers reports whether a copy at the stocktake's branch is shelved in its
+// location. The copy's book must be loaded.
+func (s *Stocktake) Covers(item *Copy) bool {
+	return s.Location == "" || strings.EqualFold(item.Location(), s.Location)
+}
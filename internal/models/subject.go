@@ -0,0 +1,73 @@
+// internal/models/subject.go
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Subject is a node of the subject taxonomy. Path lists the IDs from the root
+// down to the subject ("/1/4/9/"), so a subtree is every subject whose path
+// starts with the path of its root.
+type Subject struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:100;not null" json:"name"`
+	NameKey     string    `gorm:"size:100;not null;index" json:"-"`
+	ParentID    *uint     `gorm:"index" json:"parent_id"`
+	Path        string    `gorm:"size:255;not null;index" json:"-"`
+	Depth       int       `gorm:"not null;default:0" json:"depth"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	BookCount   int64     `gorm:"->;-:migration" json:"book_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// Version counts the writes to the row, like Book.Version.
+	Version uint `gorm:"not null;default:1" json:"version"`
+
+	Parent    *Subject       `gorm:"foreignKey:ParentID;constraint:OnDelete:RESTRICT" json:"-"`
+	Aliases   []SubjectAlias `gorm:"foreignKey:SubjectID;constraint:OnDelete:CASCADE" json:"aliases,omitempty"`
+	Ancestors []Subject      `gorm:"-" json:"ancestors,omitempty"`
+	Children  []Subject      `gorm:"-" json:"children,omitempty"`
+}
+
+// SetPath places the subject below parent, or at the root when parent is nil.
+// The subject must already have its ID.
+func (s *Subject) SetPath(parent *Subject) {
+	if parent == nil {
+		s.ParentID, s.Path, s.Depth = nil, fmt.Sprintf("/%d/", s.ID), 0
+		return
+	}
+	parentID := parent.ID
+	s.ParentID, s.Path, s.Depth = &parentID, fmt.Sprintf("%s%d/", parent.Path, s.ID), parent.Depth+1
+}
+
+// AncestorIDs returns the IDs above the subject, root first.
+func (s *Subject) AncestorIDs() []uint {
+	var ids []uint
+	for _, part := range strings.Split(strings.Trim(s.Path, "/"), "/") {
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint(id) == s.ID {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
+
+// SubjectAlias is another term for a subject, such as "SF" for "Science
+// fiction". Alias keys are unique, so an alias always names one subject.
+type SubjectAlias struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SubjectID uint      `gorm:"not null;index" json:"subject_id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	NameKey   string    `gorm:"size:100;not null;uniqueIndex" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BookSubject assigns a subject to a book; it is the join table of
+// Book.Subjects.
+type BookSubject struct {
+	BookID    uint `gorm:"primaryKey;autoIncrement:false"`
+	SubjectID uint `gorm:"primaryKey;autoIncrement:false;index"`
+}
@@ -27,6 +27,11 @@ type User struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 
+	// DeletedAt soft-deletes the account; PurgedAt is set once its personal
+	// data was anonymized after the retention period.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	PurgedAt  *time.Time     `json:"purged_at,omitempty"`
+
 	// Relations
 	BorrowRecords []BorrowRecord `gorm:"foreignKey:UserID" json:"borrow_records,omitempty"`
 }
@@ -63,6 +68,16 @@ func (u *User) Validate() error {
 	return nil
 }
 
+// Anonymize replaces the personal data of the account. The row stays, so the
+// borrow history referencing it remains intact.
+func (u *User) Anonymize(now time.Time) {
+	u.Username = fmt.Sprintf("deleted-user-%d", u.ID)
+	u.Email = fmt.Sprintf("deleted-user-%d@invalid", u.ID)
+	u.PasswordHash = "!"
+	u.IsActive = false
+	u.PurgedAt = &now
+}
+
 // TableName specifies the table name
 func (User) TableName() string {
 	return "users"
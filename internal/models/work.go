@@ -0,0 +1,45 @@
+// internal/models/work.go
+package models
+
+import "time"
+
+// Work is the creation the editions of a book are published from: the
+// translations, reprints and formats of one novel share a work. Editions are
+// the books linking to it.
+type Work struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	Title  string `gorm:"size:255;not null;index" json:"title"`
+	Author string `gorm:"size:255" json:"author,omitempty"`
+	// SeriesID and Volume place the work in a series; volumes order the
+	// series and may be fractional for novellas between two volumes.
+	SeriesID  *uint     `gorm:"index" json:"series_id,omitempty"`
+	Volume    float64   `json:"volume,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Version counts the writes to the row, like Book.Version.
+	Version uint `gorm:"not null;default:1" json:"version"`
+	// EditionCount is the number of editions in the catalog.
+	EditionCount int64 `gorm:"->;-:migration" json:"edition_count"`
+
+	Series   *Series `gorm:"foreignKey:SeriesID;constraint:OnDelete:RESTRICT" json:"series,omitempty"`
+	Editions []Book  `gorm:"foreignKey:WorkID;constraint:OnDelete:SET NULL" json:"editions,omitempty"`
+}
+
+// Series is a numbered sequence of works, such as the novels of a saga.
+type Series struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:255;not null;index" json:"name"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// Version counts the writes to the row, like Book.Version.
+	Version   uint  `gorm:"not null;default:1" json:"version"`
+	WorkCount int64 `gorm:"->;-:migration" json:"work_count"`
+
+	Works []Work `gorm:"foreignKey:SeriesID" json:"works,omitempty"`
+}
+
+// TableName keeps the table name of Series plural.
+func (Series) TableName() string {
+	return "series"
+}
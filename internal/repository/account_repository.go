@@ -0,0 +1,69 @@
+// internal/repository/account_repository.go
+package repository
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/query"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AccountRepository interface {
+	WithTx(tx *gorm.DB) AccountRepository
+	Create(ctx context.Context, entries []models.AccountEntry) error
+	// ListByUser lists the entries on a user's account, newest first.
+	ListByUser(ctx context.Context, userID uint, req query.PageRequest) ([]models.AccountEntry, query.PageInfo, error)
+	// Balance sums the entries on a user's account.
+	Balance(ctx context.Context, userID uint) (int64, error)
+	// ListByBorrow lists the entries for a loan, oldest first.
+	ListByBorrow(ctx context.Context, borrowRecordID uint) ([]models.AccountEntry, error)
+}
+
+type accountRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountRepository(db *gorm.DB) AccountRepository {
+	return &accountRepository{db: db}
+}
+
+func (r *accountRepository) WithTx(tx *gorm.DB) AccountRepository {
+	return &accountRepository{db: tx}
+}
+
+func (r *accountRepository) Create(ctx context.Context, entries []models.AccountEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(&entries).Error
+}
+
+func (r *accountRepository) ListByUser(ctx context.Context, userID uint, req query.PageRequest) ([]models.AccountEntry, query.PageInfo, error) {
+	entries := r.db.WithContext(ctx).Model(&models.AccountEntry{}).Where("user_id = ?", userID)
+	return listPage(entries, req, sortKey[models.AccountEntry]{
+		columns: []string{"created_at", "id"},
+		desc:    true,
+		key:     func(e *models.AccountEntry) []any { return []any{e.CreatedAt, e.ID} },
+	}, nil)
+}
+
+func (r *accountRepository) Balance(ctx context.Context, userID uint) (int64, error) {
+	var balance int64
+	err := r.db.WithContext(ctx).Model(&models.AccountEntry{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("user_id = ?", userID).
+		Scan(&balance).Error
+	return balance, err
+}
+
+func (r *accountRepository) ListByBorrow(ctx context.Context, borrowRecordID uint) ([]models.AccountEntry, error) {
+	var entries []models.AccountEntry
+	err := r.db.WithContext(ctx).
+		Where("borrow_record_id = ?", borrowRecordID).
+		Order("created_at ASC, id ASC").
+		Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,242 @@
+// internal/repository/author_repository.go
+package repository
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/names"
+	"github.com/alpardfm/library-management-api/pkg/query"
+
+	"gorm.io/gorm"
+)
+
+type AuthorRepository interface {
+	WithTx(tx *gorm.DB) AuthorRepository
+	Create(ctx context.Context, author *models.Author) error
+	// Update saves author and bumps its version. It fails with ErrStaleVersion
+	// when the row was written since author was read.
+	Update(ctx context.Context, author *models.Author) error
+	Delete(ctx context.Context, id uint) error
+	// FindByID returns the author with its variants and book count.
+	FindByID(ctx context.Context, id uint) (*models.Author, error)
+	// FindByNameKey returns the oldest author whose name or one of whose
+	// variants has key.
+	FindByNameKey(ctx context.Context, key string) (*models.Author, error)
+	List(ctx context.Context, search string, req query.PageRequest) ([]models.Author, query.PageInfo, error)
+
+	FindVariantByNameKey(ctx context.Context, key string) (*models.AuthorVariant, error)
+	CreateVariant(ctx context.Context, variant *models.AuthorVariant) error
+	DeleteVariant(ctx context.Context, authorID, variantID uint) (int64, error)
+	// MoveVariants reassigns the variants of one author to another.
+	MoveVariants(ctx context.Context, fromID, toID uint) error
+
+	// ListBooks returns the books an author contributed to, in any role when
+	// role is empty, oldest publication first.
+	ListBooks(ctx context.Context, authorID uint, role string, req query.PageRequest) ([]models.Book, query.PageInfo, error)
+	ListContributions(ctx context.Context, authorID uint, bookIDs []uint) ([]models.BookContributor, error)
+	ListContributors(ctx context.Context, bookID uint) ([]models.BookContributor, error)
+	// ReplaceContributors sets the complete contributor list of a book.
+	ReplaceContributors(ctx context.Context, bookID uint, contributors []models.BookContributor) error
+	// MoveContributions reassigns the credits of one author to another. Credits
+	// the target already has on the same book and role are dropped. It returns
+	// the number of credits moved.
+	MoveContributions(ctx context.Context, fromID, toID uint) (int64, error)
+	// BooksWithoutContributors returns up to limit books with ID greater than
+	// afterID that have no contributor links, in ID order.
+	BooksWithoutContributors(ctx context.Context, afterID uint, limit int) ([]models.Book, error)
+}
+
+type authorRepository struct {
+	db *gorm.DB
+}
+
+func NewAuthorRepository(db *gorm.DB) AuthorRepository {
+	return &authorRepository{db: db}
+}
+
+func (r *authorRepository) WithTx(tx *gorm.DB) AuthorRepository {
+	return &authorRepository{db: tx}
+}
+
+// withBookCount selects authors together with the number of distinct books
+// they are credited on.
+func (r *authorRepository) withBookCount(ctx context.Context) *gorm.DB {
+	return selectBookCount(r.db.WithContext(ctx).Model(&models.Author{}))
+}
+
+func selectBookCount(query *gorm.DB) *gorm.DB {
+	return query.Select(
+		"authors.*, (SELECT COUNT(DISTINCT book_id) FROM book_contributors" +
+			" JOIN books ON books.id = book_contributors.book_id AND books.deleted_at IS NULL" +
+			" WHERE book_contributors.author_id = authors.id) AS book_count")
+}
+
+func (r *authorRepository) Create(ctx context.Context, author *models.Author) error {
+	return r.db.WithContext(ctx).Create(author).Error
+}
+
+func (r *authorRepository) Update(ctx context.Context, author *models.Author) error {
+	return updateVersioned(r.db.WithContext(ctx).Omit("Variants"), author, &author.Version)
+}
+
+func (r *authorRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Author{}, id).Error
+}
+
+func (r *authorRepository) FindByID(ctx context.Context, id uint) (*models.Author, error) {
+	var author models.Author
+	err := r.withBookCount(ctx).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("name ASC") }).
+		Where("authors.id = ?", id).
+		First(&author).Error
+	if err != nil {
+		return nil, err
+	}
+	return &author, nil
+}
+
+func (r *authorRepository) FindByNameKey(ctx context.Context, key string) (*models.Author, error) {
+	var author models.Author
+	err := r.db.WithContext(ctx).
+		Where("name_key = ? OR id IN (SELECT author_id FROM author_variants WHERE name_key = ?)", key, key).
+		Order("id ASC").
+		First(&author).Error
+	if err != nil {
+		return nil, err
+	}
+	return &author, nil
+}
+
+func (r *authorRepository) List(ctx context.Context, search string, req query.PageRequest) ([]models.Author, query.PageInfo, error) {
+	authors := applyAuthorSearch(r.db.WithContext(ctx).Model(&models.Author{}), search)
+	return listPage(authors, req, authorSort(req.Sort), selectBookCount)
+}
+
+// applyAuthorSearch matches search against author names and variants by key,
+// so "rowling j k" finds "J.K. Rowling".
+func applyAuthorSearch(query *gorm.DB, search string) *gorm.DB {
+	key := names.Key(search)
+	if key == "" {
+		return query
+	}
+	pattern := "%" + key + "%"
+	return query.Where("authors.name_key LIKE ? OR authors.id IN (SELECT author_id FROM author_variants WHERE name_key LIKE ?)", pattern, pattern)
+}
+
+func authorSort(sort string) sortKey[models.Author] {
+	switch sort {
+	case "name_desc":
+		return sortKey[models.Author]{columns: []string{"sort_name", "id"}, desc: true, key: authorNameKey}
+	case "created_at_desc":
+		return sortKey[models.Author]{columns: []string{"created_at", "id"}, desc: true, key: func(a *models.Author) []any {
+			return []any{a.CreatedAt, a.ID}
+		}}
+	default:
+		return sortKey[models.Author]{columns: []string{"sort_name", "id"}, key: authorNameKey}
+	}
+}
+
+func authorNameKey(a *models.Author) []any {
+	return []any{a.SortName, a.ID}
+}
+
+func (r *authorRepository) FindVariantByNameKey(ctx context.Context, key string) (*models.AuthorVariant, error) {
+	var variant models.AuthorVariant
+	err := r.db.WithContext(ctx).Where("name_key = ?", key).First(&variant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (r *authorRepository) CreateVariant(ctx context.Context, variant *models.AuthorVariant) error {
+	return r.db.WithContext(ctx).Create(variant).Error
+}
+
+func (r *authorRepository) DeleteVariant(ctx context.Context, authorID, variantID uint) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND author_id = ?", variantID, authorID).
+		Delete(&models.AuthorVariant{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *authorRepository) MoveVariants(ctx context.Context, fromID, toID uint) error {
+	return r.db.WithContext(ctx).Model(&models.AuthorVariant{}).
+		Where("author_id = ?", fromID).
+		Update("author_id", toID).Error
+}
+
+func (r *authorRepository) ListBooks(ctx context.Context, authorID uint, role string, req query.PageRequest) ([]models.Book, query.PageInfo, error) {
+	credits := r.db.Model(&models.BookContributor{}).Select("book_id").Where("author_id = ?", authorID)
+	if role != "" {
+		credits = credits.Where("role = ?", role)
+	}
+	books := r.db.WithContext(ctx).Model(&models.Book{}).Where("id IN (?)", credits)
+
+	return listPage(books, req, sortKey[models.Book]{
+		columns: []string{"publication_year", "title", "id"},
+		key:     func(b *models.Book) []any { return []any{b.PublicationYear, b.Title, b.ID} },
+	}, nil)
+}
+
+func (r *authorRepository) ListContributions(ctx context.Context, authorID uint, bookIDs []uint) ([]models.BookContributor, error) {
+	var contributors []models.BookContributor
+	if len(bookIDs) == 0 {
+		return contributors, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("author_id = ? AND book_id IN ?", authorID, bookIDs).
+		Order("position ASC").
+		Find(&contributors).Error
+	return contributors, err
+}
+
+func (r *authorRepository) ListContributors(ctx context.Context, bookID uint) ([]models.BookContributor, error) {
+	var contributors []models.BookContributor
+	err := r.db.WithContext(ctx).
+		Preload("Author").
+		Where("book_id = ?", bookID).
+		Order("position ASC, role ASC").
+		Find(&contributors).Error
+	return contributors, err
+}
+
+func (r *authorRepository) ReplaceContributors(ctx context.Context, bookID uint, contributors []models.BookContributor) error {
+	if err := r.db.WithContext(ctx).Where("book_id = ?", bookID).Delete(&models.BookContributor{}).Error; err != nil {
+		return err
+	}
+	if len(contributors) == 0 {
+		return nil
+	}
+	for i := range contributors {
+		contributors[i].BookID = bookID
+	}
+	return r.db.WithContext(ctx).Omit("Author").Create(&contributors).Error
+}
+
+func (r *authorRepository) MoveContributions(ctx context.Context, fromID, toID uint) (int64, error) {
+	moved := r.db.WithContext(ctx).Exec(`
+		UPDATE book_contributors SET author_id = ?
+		WHERE author_id = ? AND NOT EXISTS (
+			SELECT 1 FROM book_contributors existing
+			WHERE existing.book_id = book_contributors.book_id
+				AND existing.role = book_contributors.role
+				AND existing.author_id = ?
+		)`, toID, fromID, toID)
+	if moved.Error != nil {
+		return 0, moved.Error
+	}
+	err := r.db.WithContext(ctx).Where("author_id = ?", fromID).Delete(&models.BookContributor{}).Error
+	return moved.RowsAffected, err
+}
+
+func (r *authorRepository) BooksWithoutContributors(ctx context.Context, afterID uint, limit int) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.WithContext(ctx).
+		Where("id > ? AND NOT EXISTS (SELECT 1 FROM book_contributors WHERE book_contributors.book_id = books.id)", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&books).Error
+	return books, err
+}
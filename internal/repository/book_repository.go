@@ -2,7 +2,18 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/callnumber"
+	"github.com/alpardfm/library-management-api/pkg/database"
+	"github.com/alpardfm/library-management-api/pkg/names"
+	"github.com/alpardfm/library-management-api/pkg/query"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -10,14 +21,144 @@ import (
 
 type BookRepository interface {
 	WithTx(tx *gorm.DB) BookRepository
-	Create(book *models.Book) error
-	FindByID(id uint) (*models.Book, error)
-	FindByIDForUpdate(id uint) (*models.Book, error)
-	FindByISBN(isbn string) (*models.Book, error)
-	Update(book *models.Book) error
-	Delete(id uint) error
-	List(page, limit int, search, sort string) ([]models.Book, int64, error)
-	UpdateAvailableCopies(id uint, change int) error
+	Create(ctx context.Context, book *models.Book) error
+	FindByID(ctx context.Context, id uint) (*models.Book, error)
+	FindByIDForUpdate(ctx context.Context, id uint) (*models.Book, error)
+	// FindByISBN also finds removed books, which keep their ISBN.
+	FindByISBN(ctx context.Context, isbn string) (*models.Book, error)
+	// FindWithRemoved finds a book whether or not it was removed.
+	FindWithRemoved(ctx context.Context, id uint) (*models.Book, error)
+	// Update saves book and bumps its version. It fails with ErrStaleVersion
+	// when the row was written since book was read.
+	Update(ctx context.Context, book *models.Book) error
+	// Remove soft-deletes a book with a withdrawn or archived status.
+	Remove(ctx context.Context, id uint, status, reason string) error
+	// Restore returns a removed book to the catalog.
+	Restore(ctx context.Context, id uint) error
+	// Purge deletes a removed book for good.
+	Purge(ctx context.Context, id uint) error
+	// ListRemoved lists the removed books matching filter, most recently
+	// removed first.
+	ListRemoved(ctx context.Context, filter BookFilter, req query.PageRequest) ([]models.Book, query.PageInfo, error)
+	// RemovedBefore returns the books removed before cutoff.
+	RemovedBefore(ctx context.Context, cutoff time.Time) ([]models.Book, error)
+	List(ctx context.Context, filter BookFilter, req query.PageRequest) ([]models.Book, query.PageInfo, error)
+	// EditionCounts counts the editions of each of workIDs matching filter.
+	EditionCounts(ctx context.Context, filter BookFilter, workIDs []uint) (map[uint]EditionCount, error)
+	// Facets counts the books matching filter per value of each named facet,
+	// keeping the size most frequent values.
+	Facets(ctx context.Context, filter BookFilter, facets []string, size int) (map[string][]FacetCount, error)
+	// Suggest completes term with book titles and author names: those
+	// starting with term first, then those with a word starting with it and,
+	// when fuzzy is set (it needs pg_trgm), those with a similar word.
+	Suggest(ctx context.Context, term string, fuzzy bool, limit int) ([]Suggestion, error)
+	// Similar returns the titles and author names containing a word most
+	// similar to term by trigram word similarity. It needs pg_trgm.
+	Similar(ctx context.Context, term string, limit int) ([]Suggestion, error)
+	UpdateAvailableCopies(ctx context.Context, id uint, change int) error
+	// UpdateCover sets the cover version and media type of the book read at
+	// version; empty values remove the cover. It returns ErrStaleVersion when
+	// the book has changed since.
+	UpdateCover(ctx context.Context, id, version uint, coverVersion, contentType string) error
+	Each(ctx context.Context, batchSize int, fn func(books []models.Book) error) error
+	Scan(ctx context.Context, filter BookFilter, sort string, batchSize int, fn func(books []models.Book) error) error
+	// Shelf returns the books with a call number shelved around a position:
+	// up to before books ahead of it, nearest first, and up to after books
+	// behind it. A position is a shelf key and the ID of the book there; ID
+	// 0 stands ahead of every book with the key. With filter.BranchID only
+	// books with a copy at home there count.
+	Shelf(ctx context.Context, key string, id uint, filter ShelfFilter, before, after int) (ahead, behind []models.Book, err error)
+	// ShelfList returns up to limit books matching filter in shelf order.
+	ShelfList(ctx context.Context, filter ShelfFilter, limit int) ([]models.Book, error)
+}
+
+// BookFilter holds the conditions shared by book listings and exports. Each
+// list matches any of its values; set fields must all match.
+type BookFilter struct {
+	Search string
+	// TextQuery is a to_tsquery expression matched against the full-text
+	// search_vector; it needs a dialect with FullTextSearch.
+	TextQuery string
+	// SubjectIDs match books assigned anywhere in the subtrees of the subjects.
+	SubjectIDs []uint
+	AuthorIDs  []uint
+	// Genres and Publishers match case-insensitively.
+	Genres     []string
+	Publishers []string
+	YearFrom   int
+	YearTo     int
+	Languages  []string
+	ItemTypes  []string
+	Available  *bool
+	// Statuses match the catalog status; only removed books have one other
+	// than active.
+	Statuses []string
+	// CollapseWorks lists one matching edition per work, the first
+	// catalogued, along with the books that belong to no work. Only List
+	// collapses.
+	CollapseWorks bool
+}
+
+// ShelfFilter narrows shelf browsing and shelf lists. FromKey and ToKey are
+// shelf keys bounding the range, ToKey exclusive. Location matches the shelf
+// location case-insensitively.
+type ShelfFilter struct {
+	BranchID       uint
+	Location       string
+	Classification string
+	FromKey        string
+	ToKey          string
+}
+
+// EditionCount is the number of editions of a work matching a filter and how
+// many of them have a copy on the shelf.
+type EditionCount struct {
+	WorkID    uint
+	Editions  int64
+	Available int64
+}
+
+// Book facets, named after the list filter they drill down with.
+const (
+	BookFacetSubject   = "subject"
+	BookFacetAuthor    = "author"
+	BookFacetPublisher = "publisher"
+	BookFacetDecade    = "decade"
+	BookFacetLanguage  = "language"
+	BookFacetItemType  = "item_type"
+	BookFacetAvailable = "available"
+)
+
+// BookFacetNames lists every facet in display order.
+var BookFacetNames = []string{
+	BookFacetSubject, BookFacetAuthor, BookFacetPublisher, BookFacetDecade,
+	BookFacetLanguage, BookFacetItemType, BookFacetAvailable,
+}
+
+// FacetCount is a facet value and the number of books having it. Label is the
+// display name when Value is an ID.
+type FacetCount struct {
+	Value string
+	Label string
+	Count int64
+}
+
+// Suggestion kinds.
+const (
+	SuggestionTitle  = "title"
+	SuggestionAuthor = "author"
+)
+
+// Suggestion is a title or author name matching a search term. ID is a book
+// carrying the title or the author. Tier ranks how the term matched (2 at the
+// start, 1 at the start of a later word, 0 fuzzy) and Score is the trigram
+// similarity, 0 without pg_trgm.
+type Suggestion struct {
+	Kind  string
+	ID    uint
+	Text  string
+	Tier  int
+	Score float64
 }
 
 type bookRepository struct {
@@ -32,84 +173,606 @@ func (r *bookRepository) WithTx(tx *gorm.DB) BookRepository {
 	return &bookRepository{db: tx}
 }
 
-func (r *bookRepository) Create(book *models.Book) error {
-	return r.db.Create(book).Error
+// Contributor and subject links are written through their own repositories,
+// so book writes leave associations alone.
+func (r *bookRepository) Create(ctx context.Context, book *models.Book) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(book).Error
+}
+
+// withBookRelations preloads the credited authors in display order and the
+// subjects by name.
+func withBookRelations(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Contributors", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC, role ASC") }).
+		Preload("Contributors.Author").
+		Preload("Subjects", func(db *gorm.DB) *gorm.DB { return db.Order("name ASC") })
 }
 
-func (r *bookRepository) FindByID(id uint) (*models.Book, error) {
+func (r *bookRepository) FindByID(ctx context.Context, id uint) (*models.Book, error) {
 	var book models.Book
-	err := r.db.First(&book, id).Error
+	err := withBookRelations(r.db.WithContext(ctx)).First(&book, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &book, nil
 }
 
-func (r *bookRepository) FindByIDForUpdate(id uint) (*models.Book, error) {
+func (r *bookRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.Book, error) {
 	var book models.Book
-	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, id).Error
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &book, nil
 }
 
-func (r *bookRepository) FindByISBN(isbn string) (*models.Book, error) {
+func (r *bookRepository) FindByISBN(ctx context.Context, isbn string) (*models.Book, error) {
 	var book models.Book
-	err := r.db.Where("isbn = ?", isbn).First(&book).Error
+	err := r.db.WithContext(ctx).Unscoped().Where("isbn = ?", isbn).First(&book).Error
 	if err != nil {
 		return nil, err
 	}
 	return &book, nil
 }
 
-func (r *bookRepository) Update(book *models.Book) error {
-	return r.db.Save(book).Error
+func (r *bookRepository) FindWithRemoved(ctx context.Context, id uint) (*models.Book, error) {
+	var book models.Book
+	err := r.db.WithContext(ctx).Unscoped().First(&book, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+func (r *bookRepository) Update(ctx context.Context, book *models.Book) error {
+	return updateVersioned(r.db.WithContext(ctx).Omit(clause.Associations), book, &book.Version)
+}
+
+func (r *bookRepository) Remove(ctx context.Context, id uint, status, reason string) error {
+	result := r.db.WithContext(ctx).Model(&models.Book{ID: id}).Updates(map[string]any{
+		"status":        status,
+		"status_reason": reason,
+		"deleted_at":    time.Now(),
+		"version":       gorm.Expr("version + 1"),
+	})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+func (r *bookRepository) Restore(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.Book{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]any{
+			"status":        models.BookStatusActive,
+			"status_reason": "",
+			"deleted_at":    nil,
+			"updated_at":    time.Now(),
+			"version":       gorm.Expr("version + 1"),
+		})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
 }
 
-func (r *bookRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Book{}, id).Error
+func (r *bookRepository) Purge(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Unscoped().Delete(&models.Book{}, id).Error
 }
 
-func (r *bookRepository) List(page, limit int, search, sort string) ([]models.Book, int64, error) {
+func (r *bookRepository) ListRemoved(ctx context.Context, filter BookFilter, req query.PageRequest) ([]models.Book, query.PageInfo, error) {
+	books := r.applyFilter(r.db.WithContext(ctx).Unscoped().Model(&models.Book{}).Where("books.deleted_at IS NOT NULL"), filter)
+	return listPage(books, req, sortKey[models.Book]{
+		columns: []string{"deleted_at", "id"},
+		desc:    true,
+		key:     func(b *models.Book) []any { return []any{b.DeletedAt.Time, b.ID} },
+	}, withBookRelations)
+}
+
+func (r *bookRepository) RemovedBefore(ctx context.Context, cutoff time.Time) ([]models.Book, error) {
 	var books []models.Book
-	var total int64
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("id ASC").
+		Find(&books).Error
+	return books, err
+}
+
+func (r *bookRepository) List(ctx context.Context, filter BookFilter, req query.PageRequest) ([]models.Book, query.PageInfo, error) {
+	books := r.applyFilter(r.db.WithContext(ctx).Model(&models.Book{}), filter)
+	if filter.CollapseWorks {
+		firstEditions := r.applyFilter(r.db.WithContext(ctx).Model(&models.Book{}), filter).
+			Select("MIN(id)").
+			Where("work_id IS NOT NULL").
+			Group("work_id")
+		books = books.Where("work_id IS NULL OR id IN (?)", firstEditions)
+	}
+	return listPage(books, req, bookSort(req.Sort, filter.TextQuery != ""), func(db *gorm.DB) *gorm.DB {
+		if filter.TextQuery != "" {
+			db = withTextHighlights(db, filter.TextQuery)
+		}
+		return withBookRelations(db)
+	})
+}
+
+func (r *bookRepository) EditionCounts(ctx context.Context, filter BookFilter, workIDs []uint) (map[uint]EditionCount, error) {
+	counts := make(map[uint]EditionCount, len(workIDs))
+	if len(workIDs) == 0 {
+		return counts, nil
+	}
+	var rows []EditionCount
+	err := r.applyFilter(r.db.WithContext(ctx).Model(&models.Book{}), filter).
+		Select("work_id, COUNT(*) AS editions, SUM(CASE WHEN available_copies > 0 THEN 1 ELSE 0 END) AS available").
+		Where("work_id IN ?", workIDs).
+		Group("work_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.WorkID] = row
+	}
+	return counts, nil
+}
+
+// textSearchConfig is the text search configuration of search_vector.
+const textSearchConfig = "english"
+
+// withTextHighlights selects the relevance of each book for textQuery, its
+// title with the matches marked and the best fragments of its description.
+func withTextHighlights(query *gorm.DB, textQuery string) *gorm.DB {
+	tsquery := "to_tsquery('" + textSearchConfig + "', ?)"
+	return query.Select("books.*,"+
+		" ts_rank_cd(search_vector, "+tsquery+") AS search_rank,"+
+		" ts_headline('"+textSearchConfig+"', title, "+tsquery+", 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS title_highlight,"+
+		" ts_headline('"+textSearchConfig+"', coalesce(description, ''), "+tsquery+", 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=20') AS snippet",
+		textQuery, textQuery, textQuery)
+}
+
+// applyFilter matches search against title, author statement and ISBN, and
+// against the names and name variants of credited authors, then adds the
+// structured conditions.
+func (r *bookRepository) applyFilter(query *gorm.DB, filter BookFilter) *gorm.DB {
+	if filter.Search != "" {
+		searchTerm := "%" + filter.Search + "%"
+		dialect := database.DialectOf(r.db)
+		condition := dialect.ILike("title") + " OR " + dialect.ILike("author") + " OR " + dialect.ILike("isbn")
+		args := []any{searchTerm, searchTerm, searchTerm}
+		if key := names.Key(filter.Search); key != "" {
+			condition += " OR id IN (SELECT book_id FROM book_contributors WHERE author_id IN (" +
+				"SELECT id FROM authors WHERE name_key LIKE ? UNION SELECT author_id FROM author_variants WHERE name_key LIKE ?))"
+			args = append(args, "%"+key+"%", "%"+key+"%")
+		}
+		query = query.Where(condition, args...)
+	}
+	if filter.TextQuery != "" {
+		query = query.Where("search_vector @@ to_tsquery('"+textSearchConfig+"', ?)", filter.TextQuery)
+	}
+	if len(filter.SubjectIDs) > 0 {
+		query = query.Where("id IN (SELECT book_subjects.book_id FROM book_subjects"+
+			" JOIN subjects subtree ON subtree.id = book_subjects.subject_id"+
+			" JOIN subjects selected ON subtree.path LIKE selected.path || '%'"+
+			" WHERE selected.id IN ?)", filter.SubjectIDs)
+	}
+	if len(filter.AuthorIDs) > 0 {
+		query = query.Where("id IN (SELECT book_id FROM book_contributors WHERE author_id IN ?)", filter.AuthorIDs)
+	}
+	if len(filter.Genres) > 0 {
+		query = query.Where("LOWER(genre) IN ?", lowerAll(filter.Genres))
+	}
+	if len(filter.Publishers) > 0 {
+		query = query.Where("LOWER(publisher) IN ?", lowerAll(filter.Publishers))
+	}
+	if filter.YearFrom > 0 {
+		query = query.Where("publication_year >= ?", filter.YearFrom)
+	}
+	if filter.YearTo > 0 {
+		query = query.Where("publication_year <= ?", filter.YearTo)
+	}
+	if len(filter.Languages) > 0 {
+		query = query.Where("language IN ?", filter.Languages)
+	}
+	if len(filter.ItemTypes) > 0 {
+		query = query.Where("item_type IN ?", filter.ItemTypes)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.Available != nil {
+		if *filter.Available {
+			query = query.Where("available_copies > 0")
+		} else {
+			query = query.Where("available_copies = 0")
+		}
+	}
+	return query
+}
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(value)
+	}
+	return lowered
+}
 
-	offset := (page - 1) * limit
-	query := r.db.Model(&models.Book{})
+// Facets counts within the books matching filter, so each count is the size
+// of the listing after also filtering on that value. The subject facet drills
+// down the taxonomy: it counts the children of the only filtered subject, or
+// the root subjects, by subtree.
+func (r *bookRepository) Facets(ctx context.Context, filter BookFilter, facets []string, size int) (map[string][]FacetCount, error) {
+	matching := func() *gorm.DB {
+		return r.applyFilter(r.db.WithContext(ctx).Model(&models.Book{}), filter)
+	}
+	byColumn := func(column string) ([]FacetCount, error) {
+		var counts []FacetCount
+		err := matching().
+			Select(column + " AS value, COUNT(*) AS count").
+			Where(column + " <> ''").
+			Group(column).
+			Order("count DESC, value ASC").
+			Limit(size).
+			Scan(&counts).Error
+		return counts, err
+	}
 
-	// Add search if provided
-	if search != "" {
-		searchTerm := "%" + search + "%"
-		query = query.Where("title ILIKE ? OR author ILIKE ? OR isbn ILIKE ?",
-			searchTerm, searchTerm, searchTerm)
+	result := make(map[string][]FacetCount, len(facets))
+	for _, facet := range facets {
+		var counts []FacetCount
+		var err error
+		switch facet {
+		case BookFacetSubject:
+			counts, err = r.subjectFacet(ctx, matching(), filter, size)
+		case BookFacetAuthor:
+			counts, err = r.labelledFacet(r.db.WithContext(ctx).Table("book_contributors").
+				Select("authors.id AS id, authors.name AS label, COUNT(DISTINCT book_contributors.book_id) AS count").
+				Joins("JOIN authors ON authors.id = book_contributors.author_id").
+				Where("book_contributors.book_id IN (?)", matching().Select("id")).
+				Group("authors.id, authors.name"), size)
+		case BookFacetPublisher:
+			counts, err = byColumn("publisher")
+		case BookFacetLanguage:
+			counts, err = byColumn("language")
+		case BookFacetItemType:
+			counts, err = byColumn("item_type")
+		case BookFacetDecade:
+			counts, err = r.decadeFacet(matching(), size)
+		case BookFacetAvailable:
+			err = matching().
+				Select("CASE WHEN available_copies > 0 THEN 'true' ELSE 'false' END AS value, COUNT(*) AS count").
+				Group("value").
+				Order("value DESC").
+				Scan(&counts).Error
+		default:
+			return nil, fmt.Errorf("unknown book facet %q", facet)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result[facet] = counts
 	}
+	return result, nil
+}
 
-	// Count total
-	query.Count(&total)
+func (r *bookRepository) subjectFacet(ctx context.Context, matching *gorm.DB, filter BookFilter, size int) ([]FacetCount, error) {
+	query := r.db.WithContext(ctx).Table("subjects").
+		Select("subjects.id AS id, subjects.name AS label, COUNT(DISTINCT book_subjects.book_id) AS count").
+		Joins("JOIN subjects subtree ON subtree.path LIKE subjects.path || '%'").
+		Joins("JOIN book_subjects ON book_subjects.subject_id = subtree.id").
+		Where("book_subjects.book_id IN (?)", matching.Select("id")).
+		Group("subjects.id, subjects.name")
+	if len(filter.SubjectIDs) == 1 {
+		query = query.Where("subjects.parent_id = ?", filter.SubjectIDs[0])
+	} else {
+		query = query.Where("subjects.parent_id IS NULL")
+	}
+	return r.labelledFacet(query, size)
+}
+
+// labelledFacet runs a query selecting id, label and count, and returns the
+// most frequent rows with the ID as value.
+func (r *bookRepository) labelledFacet(query *gorm.DB, size int) ([]FacetCount, error) {
+	var rows []struct {
+		ID    uint
+		Label string
+		Count int64
+	}
+	if err := query.Order("count DESC, label ASC").Limit(size).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make([]FacetCount, len(rows))
+	for i, row := range rows {
+		counts[i] = FacetCount{Value: strconv.FormatUint(uint64(row.ID), 10), Label: row.Label, Count: row.Count}
+	}
+	return counts, nil
+}
 
-	// Get paginated results
-	err := query.Offset(offset).Limit(limit).Order(resolveBookSort(sort)).Find(&books).Error
+// decadeFacet groups by publication decade, latest first; the value is the
+// first year of the decade.
+func (r *bookRepository) decadeFacet(matching *gorm.DB, size int) ([]FacetCount, error) {
+	var rows []struct {
+		Decade int
+		Count  int64
+	}
+	err := matching.
+		Select("(publication_year / 10) * 10 AS decade, COUNT(*) AS count").
+		Where("publication_year > 0").
+		Group("decade").
+		Order("decade DESC").
+		Limit(size).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]FacetCount, len(rows))
+	for i, row := range rows {
+		counts[i] = FacetCount{Value: strconv.Itoa(row.Decade), Label: fmt.Sprintf("%ds", row.Decade), Count: row.Count}
+	}
+	return counts, nil
+}
 
-	return books, total, err
+// bookSort returns the list ordering of a sort. Relevance is only ranked when
+// ranked is set, for full-text searches, and pages by offset.
+func bookSort(sort string, ranked bool) sortKey[models.Book] {
+	if sort == "relevance" && ranked {
+		return sortKey[models.Book]{columns: []string{"search_rank", "id"}, desc: true}
+	}
+	column, desc := bookSortKey(sort)
+	key := func(b *models.Book) []any { return []any{bookSortValue(b, column), b.ID} }
+	return sortKey[models.Book]{columns: []string{column, "id"}, desc: desc, key: key}
 }
 
-func resolveBookSort(sort string) string {
+// bookSortKey maps a sort name to its column and direction. Relevance is
+// only ranked for full-text searches; other listings sort it by title. Call
+// number order is shelf order, with books without one last.
+func bookSortKey(sort string) (column string, desc bool) {
 	switch sort {
 	case "created_at_asc":
-		return "created_at ASC"
-	case "title_asc":
-		return "title ASC"
+		return "created_at", false
+	case "title_asc", "relevance":
+		return "title", false
 	case "title_desc":
-		return "title DESC"
+		return "title", true
+	case "call_number_asc":
+		return "shelf_key", false
+	case "call_number_desc":
+		return "shelf_key", true
 	default:
-		return "created_at DESC"
+		return "created_at", true
+	}
+}
+
+// bookSortValue is the value of a sort column of book.
+func bookSortValue(book *models.Book, column string) any {
+	switch column {
+	case "title":
+		return book.Title
+	case "shelf_key":
+		return book.ShelfKey
+	default:
+		return book.CreatedAt
+	}
+}
+
+func (r *bookRepository) Suggest(ctx context.Context, term string, fuzzy bool, limit int) ([]Suggestion, error) {
+	dialect := database.DialectOf(r.db)
+	complete := func(kind, table, column string) ([]Suggestion, error) {
+		atStart, atWord := dialect.ILike(column), dialect.ILike(column)
+		selectArgs := []any{term + "%", "% " + term + "%"}
+		where := atStart + " OR " + atWord
+		whereArgs := []any{term + "%", "% " + term + "%"}
+		score := "0"
+		if fuzzy {
+			score = "word_similarity(?, " + column + ")"
+			selectArgs = append(selectArgs, term)
+			where += " OR ? <% " + column
+			whereArgs = append(whereArgs, term)
+		}
+
+		var suggestions []Suggestion
+		err := r.suggestionSource(ctx, table).
+			Select("MIN(id) AS id, "+column+" AS text,"+
+				" CASE WHEN "+atStart+" THEN 2 WHEN "+atWord+" THEN 1 ELSE 0 END AS tier,"+
+				" "+score+" AS score", selectArgs...).
+			Where(where, whereArgs...).
+			Group(column).
+			Order("tier DESC, score DESC, LENGTH(" + column + ") ASC, " + column + " ASC").
+			Limit(limit).
+			Scan(&suggestions).Error
+		for i := range suggestions {
+			suggestions[i].Kind = kind
+		}
+		return suggestions, err
+	}
+
+	titles, err := complete(SuggestionTitle, "books", "title")
+	if err != nil {
+		return nil, err
+	}
+	authors, err := complete(SuggestionAuthor, "authors", "name")
+	if err != nil {
+		return nil, err
+	}
+	return rankSuggestions(append(titles, authors...), limit), nil
+}
+
+// suggestionSource selects from table, leaving out removed books.
+func (r *bookRepository) suggestionSource(ctx context.Context, table string) *gorm.DB {
+	source := r.db.WithContext(ctx).Table(table)
+	if table == "books" {
+		source = source.Where("deleted_at IS NULL")
+	}
+	return source
+}
+
+func (r *bookRepository) Similar(ctx context.Context, term string, limit int) ([]Suggestion, error) {
+	similar := func(kind, table, column string) ([]Suggestion, error) {
+		var suggestions []Suggestion
+		err := r.suggestionSource(ctx, table).
+			Select("MIN(id) AS id, "+column+" AS text, word_similarity(?, "+column+") AS score", term).
+			Where("? <% "+column, term).
+			Group(column).
+			Order("score DESC, LENGTH(" + column + ") ASC").
+			Limit(limit).
+			Scan(&suggestions).Error
+		for i := range suggestions {
+			suggestions[i].Kind = kind
+		}
+		return suggestions, err
+	}
+
+	titles, err := similar(SuggestionTitle, "books", "title")
+	if err != nil {
+		return nil, err
 	}
+	authors, err := similar(SuggestionAuthor, "authors", "name")
+	if err != nil {
+		return nil, err
+	}
+	return rankSuggestions(append(titles, authors...), limit), nil
 }
 
-func (r *bookRepository) UpdateAvailableCopies(id uint, change int) error {
-	return r.db.Model(&models.Book{}).
+// rankSuggestions merges suggestions by tier, then score, then the shortest
+// text, and keeps the first limit.
+func rankSuggestions(suggestions []Suggestion, limit int) []Suggestion {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Tier != b.Tier {
+			return a.Tier > b.Tier
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return len(a.Text) < len(b.Text)
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+func (r *bookRepository) UpdateAvailableCopies(ctx context.Context, id uint, change int) error {
+	return r.db.WithContext(ctx).Model(&models.Book{}).
 		Where("id = ?", id).
-		Update("available_copies", gorm.Expr("available_copies + ?", change)).
+		Updates(map[string]any{
+			"available_copies": gorm.Expr("available_copies + ?", change),
+			"version":          gorm.Expr("version + 1"),
+		}).
 		Error
 }
+
+func (r *bookRepository) UpdateCover(ctx context.Context, id, version uint, coverVersion, contentType string) error {
+	result := r.db.WithContext(ctx).Model(&models.Book{ID: id}).Where("version = ?", version).
+		Updates(map[string]any{"cover_version": coverVersion, "cover_type": contentType, "version": gorm.Expr("version + 1")})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrStaleVersion
+	}
+	return result.Error
+}
+
+// Each walks every book in ID order, handing batches of at most batchSize rows to fn.
+func (r *bookRepository) Each(ctx context.Context, batchSize int, fn func(books []models.Book) error) error {
+	var books []models.Book
+	return r.db.WithContext(ctx).Order("id ASC").FindInBatches(&books, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(books)
+	}).Error
+}
+
+// Scan walks the books matching filter in sort order, handing batches of at
+// most batchSize rows to fn. Each batch is a keyset query on the sort column
+// and ID, so no connection is held between batches and deep pages stay cheap.
+func (r *bookRepository) Scan(ctx context.Context, filter BookFilter, sort string, batchSize int, fn func(books []models.Book) error) error {
+	column, desc := bookSortKey(sort)
+	direction, op := "ASC", ">"
+	if desc {
+		direction, op = "DESC", "<"
+	}
+	order := fmt.Sprintf("%s %s, id %s", column, direction, direction)
+	after := fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", column, op, column, op)
+
+	var last *models.Book
+	for {
+		query := r.applyFilter(r.db.WithContext(ctx).Model(&models.Book{}), filter)
+		if last != nil {
+			value := bookSortValue(last, column)
+			query = query.Where(after, value, value, last.ID)
+		}
+
+		var books []models.Book
+		if err := query.Order(order).Limit(batchSize).Find(&books).Error; err != nil {
+			return err
+		}
+		if len(books) == 0 {
+			return nil
+		}
+		if err := fn(books); err != nil {
+			return err
+		}
+		if len(books) < batchSize {
+			return nil
+		}
+		last = &books[len(books)-1]
+	}
+}
+
+func (r *bookRepository) Shelf(ctx context.Context, key string, id uint, filter ShelfFilter, before, after int) ([]models.Book, []models.Book, error) {
+	shelf := func() *gorm.DB {
+		books := r.db.WithContext(ctx).Model(&models.Book{}).Where("shelf_key <> ?", callnumber.NoKey)
+		return withShelfRange(withBranchCopies(books, filter.BranchID), filter)
+	}
+
+	var ahead, behind []models.Book
+	if before > 0 {
+		err := shelf().
+			Where("(shelf_key < ? OR (shelf_key = ? AND id < ?))", key, key, id).
+			Order("shelf_key DESC, id DESC").Limit(before).Find(&ahead).Error
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if after > 0 {
+		err := shelf().
+			Where("(shelf_key > ? OR (shelf_key = ? AND id > ?))", key, key, id).
+			Order("shelf_key ASC, id ASC").Limit(after).Find(&behind).Error
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return ahead, behind, nil
+}
+
+func (r *bookRepository) ShelfList(ctx context.Context, filter ShelfFilter, limit int) ([]models.Book, error) {
+	books := withBranchCopies(r.db.WithContext(ctx).Model(&models.Book{}), filter.BranchID)
+	if filter.Location != "" {
+		books = books.Where("LOWER(books.shelf_location) = LOWER(?)", filter.Location)
+	}
+
+	var list []models.Book
+	err := withShelfRange(books, filter).Order("shelf_key ASC, id ASC").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+// withBranchCopies keeps the books with a copy at home at a branch, or every
+// book when branchID is 0.
+func withBranchCopies(books *gorm.DB, branchID uint) *gorm.DB {
+	if branchID == 0 {
+		return books
+	}
+	return books.Where("EXISTS (SELECT 1 FROM copies WHERE copies.book_id = books.id AND copies.home_branch_id = ?)", branchID)
+}
+
+// withShelfRange keeps the books, or the copies of books, of the
+// classification and between the shelf keys of filter.
+func withShelfRange(query *gorm.DB, filter ShelfFilter) *gorm.DB {
+	if filter.Classification != "" {
+		query = query.Where("books.classification = ?", filter.Classification)
+	}
+	if filter.FromKey != "" {
+		query = query.Where("books.shelf_key >= ?", filter.FromKey)
+	}
+	if filter.ToKey != "" {
+		query = query.Where("books.shelf_key < ?", filter.ToKey)
+	}
+	return query
+}
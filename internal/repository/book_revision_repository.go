@@ -0,0 +1,60 @@
+// internal/repository/book_revision_repository.go
+package repository
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/query"
+
+	"gorm.io/gorm"
+)
+
+type BookRevisionRepository interface {
+	WithTx(tx *gorm.DB) BookRevisionRepository
+	Create(ctx context.Context, revision *models.BookRevision) error
+	// List lists the revisions of a book, latest first unless req sorts by
+	// version_asc.
+	List(ctx context.Context, bookID uint, req query.PageRequest) ([]models.BookRevision, query.PageInfo, error)
+	FindByVersion(ctx context.Context, bookID, version uint) (*models.BookRevision, error)
+	// DeleteByBook deletes the history of a purged book.
+	DeleteByBook(ctx context.Context, bookID uint) error
+}
+
+type bookRevisionRepository struct {
+	db *gorm.DB
+}
+
+func NewBookRevisionRepository(db *gorm.DB) BookRevisionRepository {
+	return &bookRevisionRepository{db: db}
+}
+
+func (r *bookRevisionRepository) WithTx(tx *gorm.DB) BookRevisionRepository {
+	return &bookRevisionRepository{db: tx}
+}
+
+func (r *bookRevisionRepository) Create(ctx context.Context, revision *models.BookRevision) error {
+	return r.db.WithContext(ctx).Create(revision).Error
+}
+
+func (r *bookRevisionRepository) List(ctx context.Context, bookID uint, req query.PageRequest) ([]models.BookRevision, query.PageInfo, error) {
+	revisions := r.db.WithContext(ctx).Model(&models.BookRevision{}).Where("book_id = ?", bookID)
+	return listPage(revisions, req, sortKey[models.BookRevision]{
+		columns: []string{"version"},
+		desc:    req.Sort != "version_asc",
+		key:     func(rev *models.BookRevision) []any { return []any{rev.Version} },
+	}, nil)
+}
+
+func (r *bookRevisionRepository) FindByVersion(ctx context.Context, bookID, version uint) (*models.BookRevision, error) {
+	var revision models.BookRevision
+	err := r.db.WithContext(ctx).Where("book_id = ? AND version = ?", bookID, version).First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func (r *bookRevisionRepository) DeleteByBook(ctx context.Context, bookID uint) error {
+	return r.db.WithContext(ctx).Where("book_id = ?", bookID).Delete(&models.BookRevision{}).Error
+}
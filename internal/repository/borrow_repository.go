@@ -1,9 +1,11 @@
 package repository
 
 import (
+	"context"
 	"time"
 
 	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/query"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -11,15 +13,37 @@ import (
 
 type BorrowRepository interface {
 	WithTx(tx *gorm.DB) BorrowRepository
-	Create(record *models.BorrowRecord) error
-	FindByID(id uint) (*models.BorrowRecord, error)
-	FindByIDForUpdate(id uint) (*models.BorrowRecord, error)
-	FindActiveByUserAndBook(userID, bookID uint) (*models.BorrowRecord, error)
-	Update(record *models.BorrowRecord) error
-	ListByUser(userID uint, page, limit int, sort string) ([]models.BorrowRecord, int64, error)
-	ListActive(page, limit int, sort string) ([]models.BorrowRecord, int64, error)
-	ListOverdue(page, limit int, sort string) ([]models.BorrowRecord, int64, error)
-	CountActiveByUser(userID uint) (int64, error)
+	Create(ctx context.Context, record *models.BorrowRecord) error
+	FindByID(ctx context.Context, id uint) (*models.BorrowRecord, error)
+	FindByIDForUpdate(ctx context.Context, id uint) (*models.BorrowRecord, error)
+	FindActiveByUserAndBook(ctx context.Context, userID, bookID uint) (*models.BorrowRecord, error)
+	Update(ctx context.Context, record *models.BorrowRecord) error
+	ListByUser(ctx context.Context, userID uint, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error)
+	ListActive(ctx context.Context, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error)
+	ListOverdue(ctx context.Context, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error)
+	CountActiveByUser(ctx context.Context, userID uint) (int64, error)
+	CountActiveByBook(ctx context.Context) (map[uint]int64, error)
+	// CountByBook counts every borrow of a book, returned or not.
+	CountByBook(ctx context.Context, bookID uint) (int64, error)
+	// DetachBook unlinks the borrows of a book about to be purged, keeping
+	// its title and ISBN on them.
+	DetachBook(ctx context.Context, book *models.Book) (int64, error)
+	MarkOverdue(ctx context.Context, now time.Time) (int64, error)
+	CirculationStats(ctx context.Context, from, to time.Time, top int) (*CirculationStats, error)
+}
+
+type CirculationStats struct {
+	Borrowed int64
+	Returned int64
+	Active   int64
+	Overdue  int64
+	TopBooks []BookCirculation
+}
+
+type BookCirculation struct {
+	BookID  uint   `json:"book_id"`
+	Title   string `json:"title"`
+	Borrows int64  `json:"borrows"`
 }
 
 type borrowRepository struct {
@@ -34,24 +58,22 @@ func (r *borrowRepository) WithTx(tx *gorm.DB) BorrowRepository {
 	return &borrowRepository{db: tx}
 }
 
-func (r *borrowRepository) Create(record *models.BorrowRecord) error {
-	return r.db.Create(record).Error
+func (r *borrowRepository) Create(ctx context.Context, record *models.BorrowRecord) error {
+	return r.db.WithContext(ctx).Create(record).Error
 }
 
-func (r *borrowRepository) FindByID(id uint) (*models.BorrowRecord, error) {
+func (r *borrowRepository) FindByID(ctx context.Context, id uint) (*models.BorrowRecord, error) {
 	var record models.BorrowRecord
-	err := r.db.Preload("User").Preload("Book").First(&record, id).Error
+	err := withBorrowParties(r.db.WithContext(ctx)).First(&record, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &record, nil
 }
 
-func (r *borrowRepository) FindByIDForUpdate(id uint) (*models.BorrowRecord, error) {
+func (r *borrowRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.BorrowRecord, error) {
 	var record models.BorrowRecord
-	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
-		Preload("User").
-		Preload("Book").
+	err := withBorrowParties(r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"})).
 		First(&record, id).Error
 	if err != nil {
 		return nil, err
@@ -59,9 +81,9 @@ func (r *borrowRepository) FindByIDForUpdate(id uint) (*models.BorrowRecord, err
 	return &record, nil
 }
 
-func (r *borrowRepository) FindActiveByUserAndBook(userID, bookID uint) (*models.BorrowRecord, error) {
+func (r *borrowRepository) FindActiveByUserAndBook(ctx context.Context, userID, bookID uint) (*models.BorrowRecord, error) {
 	var record models.BorrowRecord
-	err := r.db.Where("user_id = ? AND book_id = ? AND return_date IS NULL",
+	err := r.db.WithContext(ctx).Where("user_id = ? AND book_id = ? AND return_date IS NULL",
 		userID, bookID).
 		First(&record).Error
 	if err != nil {
@@ -70,81 +92,154 @@ func (r *borrowRepository) FindActiveByUserAndBook(userID, bookID uint) (*models
 	return &record, nil
 }
 
-func (r *borrowRepository) Update(record *models.BorrowRecord) error {
-	return r.db.Save(record).Error
+func (r *borrowRepository) Update(ctx context.Context, record *models.BorrowRecord) error {
+	return r.db.WithContext(ctx).Save(record).Error
 }
 
-func (r *borrowRepository) ListByUser(userID uint, page, limit int, sort string) ([]models.BorrowRecord, int64, error) {
-	var records []models.BorrowRecord
-	var total int64
+func (r *borrowRepository) ListByUser(ctx context.Context, userID uint, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error) {
+	records := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).Where("user_id = ?", userID)
+	return listPage(records, req, borrowSort(req.Sort), func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Book", unscoped)
+	})
+}
 
-	offset := (page - 1) * limit
+func (r *borrowRepository) ListActive(ctx context.Context, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error) {
+	records := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).
+		Where("status = ?", models.StatusBorrowed)
+	return listPage(records, req, borrowSort(req.Sort), withBorrowParties)
+}
 
-	query := r.db.Preload("Book").Where("user_id = ?", userID)
-	query.Model(&models.BorrowRecord{}).Count(&total)
+func (r *borrowRepository) ListOverdue(ctx context.Context, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error) {
+	records := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).
+		Where("status = ? OR (return_date IS NULL AND due_date < ?)",
+			models.StatusOverdue, time.Now())
+	return listPage(records, req, borrowSort(req.Sort), withBorrowParties)
+}
 
-	err := query.Offset(offset).Limit(limit).
-		Order(resolveBorrowSort(sort)).
-		Find(&records).Error
+// withBorrowParties preloads the borrower and the book, also when they were
+// deleted since: the history keeps showing them.
+func withBorrowParties(query *gorm.DB) *gorm.DB {
+	return query.Preload("User", unscoped).Preload("Book", unscoped)
+}
 
-	return records, total, err
+func unscoped(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
 }
 
-func (r *borrowRepository) ListActive(page, limit int, sort string) ([]models.BorrowRecord, int64, error) {
-	var records []models.BorrowRecord
-	var total int64
+func (r *borrowRepository) CountByBook(ctx context.Context, bookID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).
+		Where("book_id = ?", bookID).
+		Count(&count).Error
+	return count, err
+}
 
-	offset := (page - 1) * limit
+func (r *borrowRepository) DetachBook(ctx context.Context, book *models.Book) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).
+		Where("book_id = ?", book.ID).
+		UpdateColumns(map[string]any{
+			"book_id":    nil,
+			"book_title": book.Title,
+			"book_isbn":  book.ISBN,
+		})
+	return result.RowsAffected, result.Error
+}
 
-	query := r.db.Preload("User").Preload("Book").
-		Where("status = ?", models.StatusBorrowed)
+func (r *borrowRepository) CountActiveByUser(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).
+		Where("user_id = ? AND return_date IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
 
-	query.Model(&models.BorrowRecord{}).Count(&total)
+func (r *borrowRepository) CountActiveByBook(ctx context.Context) (map[uint]int64, error) {
+	var rows []struct {
+		BookID uint
+		Count  int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).
+		Select("book_id, COUNT(*) AS count").
+		Where("return_date IS NULL").
+		Group("book_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
 
-	err := query.Offset(offset).Limit(limit).
-		Order(resolveBorrowSort(sort)).
-		Find(&records).Error
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.BookID] = row.Count
+	}
+	return counts, nil
+}
 
-	return records, total, err
+// MarkOverdue flags every unreturned record past its due date as overdue.
+func (r *borrowRepository) MarkOverdue(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).
+		Where("return_date IS NULL AND due_date < ? AND status = ?", now, models.StatusBorrowed).
+		Updates(map[string]interface{}{
+			"status":     models.StatusOverdue,
+			"updated_at": now,
+		})
+	return result.RowsAffected, result.Error
 }
 
-func (r *borrowRepository) ListOverdue(page, limit int, sort string) ([]models.BorrowRecord, int64, error) {
-	var records []models.BorrowRecord
-	var total int64
+func (r *borrowRepository) CirculationStats(ctx context.Context, from, to time.Time, top int) (*CirculationStats, error) {
+	stats := &CirculationStats{}
+	db := r.db.WithContext(ctx)
 
-	offset := (page - 1) * limit
-	now := time.Now()
+	if err := db.Model(&models.BorrowRecord{}).
+		Where("borrow_date >= ? AND borrow_date < ?", from, to).
+		Count(&stats.Borrowed).Error; err != nil {
+		return nil, err
+	}
 
-	query := r.db.Preload("User").Preload("Book").
-		Where("status = ? OR (return_date IS NULL AND due_date < ?)",
-			models.StatusOverdue, now)
+	// A loan closed because its copy was lost did not bring anything back.
+	if err := db.Model(&models.BorrowRecord{}).
+		Where("return_date >= ? AND return_date < ? AND status <> ?", from, to, models.StatusLost).
+		Count(&stats.Returned).Error; err != nil {
+		return nil, err
+	}
 
-	query.Model(&models.BorrowRecord{}).Count(&total)
+	if err := db.Model(&models.BorrowRecord{}).
+		Where("return_date IS NULL").
+		Count(&stats.Active).Error; err != nil {
+		return nil, err
+	}
 
-	err := query.Offset(offset).Limit(limit).
-		Order(resolveBorrowSort(sort)).
-		Find(&records).Error
+	if err := db.Model(&models.BorrowRecord{}).
+		Where("return_date IS NULL AND due_date < ?", time.Now()).
+		Count(&stats.Overdue).Error; err != nil {
+		return nil, err
+	}
 
-	return records, total, err
-}
+	err := db.Model(&models.BorrowRecord{}).
+		Select("borrow_records.book_id, books.title, COUNT(*) AS borrows").
+		Joins("JOIN books ON books.id = borrow_records.book_id").
+		Where("borrow_records.borrow_date >= ? AND borrow_records.borrow_date < ?", from, to).
+		Group("borrow_records.book_id, books.title").
+		Order("borrows DESC, borrow_records.book_id ASC").
+		Limit(top).
+		Scan(&stats.TopBooks).Error
+	if err != nil {
+		return nil, err
+	}
 
-func (r *borrowRepository) CountActiveByUser(userID uint) (int64, error) {
-	var count int64
-	err := r.db.Model(&models.BorrowRecord{}).
-		Where("user_id = ? AND return_date IS NULL", userID).
-		Count(&count).Error
-	return count, err
+	return stats, nil
 }
 
-func resolveBorrowSort(sort string) string {
+func borrowSort(sort string) sortKey[models.BorrowRecord] {
+	createdAt := func(r *models.BorrowRecord) []any { return []any{r.CreatedAt, r.ID} }
+	dueDate := func(r *models.BorrowRecord) []any { return []any{r.DueDate, r.ID} }
 	switch sort {
 	case "created_at_asc":
-		return "created_at ASC"
+		return sortKey[models.BorrowRecord]{columns: []string{"created_at", "id"}, key: createdAt}
 	case "due_date_desc":
-		return "due_date DESC"
+		return sortKey[models.BorrowRecord]{columns: []string{"due_date", "id"}, desc: true, key: dueDate}
 	case "created_at_desc":
-		return "created_at DESC"
+		return sortKey[models.BorrowRecord]{columns: []string{"created_at", "id"}, desc: true, key: createdAt}
 	default:
-		return "due_date ASC"
+		return sortKey[models.BorrowRecord]{columns: []string{"due_date", "id"}, key: dueDate}
 	}
 }
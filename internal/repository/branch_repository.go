@@ -0,0 +1,112 @@
+// internal/repository/branch_repository.go
+package repository
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/database"
+	"github.com/alpardfm/library-management-api/pkg/query"
+
+	"gorm.io/gorm"
+)
+
+type BranchRepository interface {
+	WithTx(tx *gorm.DB) BranchRepository
+	Create(ctx context.Context, branch *models.Branch) error
+	// Update saves branch and bumps its version. It fails with
+	// ErrStaleVersion when the row was written since branch was read.
+	Update(ctx context.Context, branch *models.Branch) error
+	Delete(ctx context.Context, id uint) error
+	// FindByID returns the branch with the number of copies it is home to.
+	FindByID(ctx context.Context, id uint) (*models.Branch, error)
+	FindByCode(ctx context.Context, code string) (*models.Branch, error)
+	// InUse reports whether copies are or were at the branch: a copy has it
+	// as home or current branch, a transfer went from or to it, or it was
+	// stocktaken.
+	InUse(ctx context.Context, id uint) (bool, error)
+	// List lists the branches whose code or name matches search, by code.
+	List(ctx context.Context, search string, req query.PageRequest) ([]models.Branch, query.PageInfo, error)
+}
+
+type branchRepository struct {
+	db *gorm.DB
+}
+
+func NewBranchRepository(db *gorm.DB) BranchRepository {
+	return &branchRepository{db: db}
+}
+
+func (r *branchRepository) WithTx(tx *gorm.DB) BranchRepository {
+	return &branchRepository{db: tx}
+}
+
+// selectCopyCount selects branches together with the number of copies they
+// are home to.
+func selectCopyCount(query *gorm.DB) *gorm.DB {
+	return query.Select("branches.*, (SELECT COUNT(*) FROM copies WHERE copies.home_branch_id = branches.id) AS copy_count")
+}
+
+func (r *branchRepository) Create(ctx context.Context, branch *models.Branch) error {
+	return r.db.WithContext(ctx).Create(branch).Error
+}
+
+func (r *branchRepository) Update(ctx context.Context, branch *models.Branch) error {
+	return updateVersioned(r.db.WithContext(ctx), branch, &branch.Version)
+}
+
+func (r *branchRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Branch{}, id).Error
+}
+
+func (r *branchRepository) FindByID(ctx context.Context, id uint) (*models.Branch, error) {
+	var branch models.Branch
+	err := selectCopyCount(r.db.WithContext(ctx).Model(&models.Branch{})).
+		Where("branches.id = ?", id).
+		First(&branch).Error
+	if err != nil {
+		return nil, err
+	}
+	return &branch, nil
+}
+
+func (r *branchRepository) FindByCode(ctx context.Context, code string) (*models.Branch, error) {
+	var branch models.Branch
+	if err := r.db.WithContext(ctx).Where("code = ?", code).First(&branch).Error; err != nil {
+		return nil, err
+	}
+	return &branch, nil
+}
+
+func (r *branchRepository) InUse(ctx context.Context, id uint) (bool, error) {
+	var copies, transfers, stocktakes int64
+	err := r.db.WithContext(ctx).Model(&models.Copy{}).
+		Where("home_branch_id = ? OR branch_id = ?", id, id).
+		Count(&copies).Error
+	if err != nil || copies > 0 {
+		return copies > 0, err
+	}
+	err = r.db.WithContext(ctx).Model(&models.Transfer{}).
+		Where("from_branch_id = ? OR to_branch_id = ?", id, id).
+		Count(&transfers).Error
+	if err != nil || transfers > 0 {
+		return transfers > 0, err
+	}
+	err = r.db.WithContext(ctx).Model(&models.Stocktake{}).
+		Where("branch_id = ?", id).
+		Count(&stocktakes).Error
+	return stocktakes > 0, err
+}
+
+func (r *branchRepository) List(ctx context.Context, search string, req query.PageRequest) ([]models.Branch, query.PageInfo, error) {
+	branches := r.db.WithContext(ctx).Model(&models.Branch{})
+	if search != "" {
+		dialect := database.DialectOf(r.db)
+		pattern := "%" + search + "%"
+		branches = branches.Where(dialect.ILike("branches.code")+" OR "+dialect.ILike("branches.name"), pattern, pattern)
+	}
+	return listPage(branches, req, sortKey[models.Branch]{
+		columns: []string{"code", "id"},
+		key:     func(b *models.Branch) []any { return []any{b.Code, b.ID} },
+	}, selectCopyCount)
+}
@@ -0,0 +1,176 @@
+// internal/repository/copy_repository.go
+package repository
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CopyRepository interface {
+	WithTx(tx *gorm.DB) CopyRepository
+	Create(ctx context.Context, item *models.Copy) error
+	Update(ctx context.Context, item *models.Copy) error
+	FindByIDForUpdate(ctx context.Context, id uint) (*models.Copy, error)
+	FindByBarcode(ctx context.Context, barcode string) (*models.Copy, error)
+	// FindByBarcodes returns the copies with any of the barcodes.
+	FindByBarcodes(ctx context.Context, barcodes []string) ([]models.Copy, error)
+	// FindAvailable locks an available copy of a book on the shelves of a
+	// branch, or of any branch when branchID is 0.
+	FindAvailable(ctx context.Context, bookID, branchID uint) (*models.Copy, error)
+	// ListByBook lists the registered copies of a book with their branches,
+	// by barcode.
+	ListByBook(ctx context.Context, bookID uint) ([]models.Copy, error)
+	// CountByBook counts the registered copies of a book still in stock,
+	// leaving out lost and damaged ones, and how many of them are available.
+	CountByBook(ctx context.Context, bookID uint) (total, available int64, err error)
+	// Holdings returns what each branch holds of the books, by branch code.
+	Holdings(ctx context.Context, bookIDs []uint) (map[uint][]models.BranchHolding, error)
+	// ShelfList returns up to limit copies at home at filter.BranchID with
+	// their books, in shelf order and by barcode.
+	ShelfList(ctx context.Context, filter ShelfFilter, limit int) ([]models.Copy, error)
+	// ListOnShelf lists the available copies at a branch with their books, in
+	// shelf order and by barcode. Copies of removed books are left out.
+	ListOnShelf(ctx context.Context, branchID uint) ([]models.Copy, error)
+}
+
+type copyRepository struct {
+	db *gorm.DB
+}
+
+func NewCopyRepository(db *gorm.DB) CopyRepository {
+	return &copyRepository{db: db}
+}
+
+func (r *copyRepository) WithTx(tx *gorm.DB) CopyRepository {
+	return &copyRepository{db: tx}
+}
+
+func (r *copyRepository) Create(ctx context.Context, item *models.Copy) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(item).Error
+}
+
+func (r *copyRepository) Update(ctx context.Context, item *models.Copy) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(item).Error
+}
+
+func (r *copyRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.Copy, error) {
+	var item models.Copy
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *copyRepository) FindByBarcode(ctx context.Context, barcode string) (*models.Copy, error) {
+	var item models.Copy
+	if err := r.db.WithContext(ctx).Where("barcode = ?", barcode).First(&item).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *copyRepository) FindByBarcodes(ctx context.Context, barcodes []string) ([]models.Copy, error) {
+	var copies []models.Copy
+	if len(barcodes) == 0 {
+		return copies, nil
+	}
+	err := r.db.WithContext(ctx).Where("barcode IN ?", barcodes).Find(&copies).Error
+	return copies, err
+}
+
+func (r *copyRepository) FindAvailable(ctx context.Context, bookID, branchID uint) (*models.Copy, error) {
+	copies := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("book_id = ? AND status = ?", bookID, models.CopyAvailable)
+	if branchID != 0 {
+		copies = copies.Where("branch_id = ?", branchID)
+	}
+	var item models.Copy
+	if err := copies.Order("id ASC").First(&item).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *copyRepository) ListByBook(ctx context.Context, bookID uint) ([]models.Copy, error) {
+	var copies []models.Copy
+	err := r.db.WithContext(ctx).
+		Preload("HomeBranch").
+		Preload("Branch").
+		Where("book_id = ?", bookID).
+		Order("barcode ASC").
+		Find(&copies).Error
+	return copies, err
+}
+
+func (r *copyRepository) CountByBook(ctx context.Context, bookID uint) (int64, int64, error) {
+	var counts struct {
+		Total     int64
+		Available int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.Copy{}).
+		Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS available", models.CopyAvailable).
+		Where("book_id = ? AND status NOT IN ?", bookID, []string{models.CopyLost, models.CopyDamaged}).
+		Scan(&counts).Error
+	return counts.Total, counts.Available, err
+}
+
+func (r *copyRepository) Holdings(ctx context.Context, bookIDs []uint) (map[uint][]models.BranchHolding, error) {
+	holdings := make(map[uint][]models.BranchHolding)
+	if len(bookIDs) == 0 {
+		return holdings, nil
+	}
+
+	var rows []struct {
+		BookID uint
+		models.BranchHolding
+	}
+	// A copy away from home counts at its home branch and is on the shelf
+	// of the branch it is at.
+	err := r.db.WithContext(ctx).Table("copies").
+		Select("copies.book_id, branches.id AS branch_id, branches.code, branches.name,"+
+			" SUM(CASE WHEN copies.home_branch_id = branches.id THEN 1 ELSE 0 END) AS copies,"+
+			" SUM(CASE WHEN copies.branch_id = branches.id AND copies.status = ? THEN 1 ELSE 0 END) AS available", models.CopyAvailable).
+		Joins("JOIN branches ON branches.id = copies.home_branch_id OR branches.id = copies.branch_id").
+		Where("copies.book_id IN ? AND copies.status NOT IN ?", bookIDs, []string{models.CopyLost, models.CopyDamaged}).
+		Group("copies.book_id, branches.id, branches.code, branches.name").
+		Order("branches.code ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		holdings[row.BookID] = append(holdings[row.BookID], row.BranchHolding)
+	}
+	return holdings, nil
+}
+
+func (r *copyRepository) ShelfList(ctx context.Context, filter ShelfFilter, limit int) ([]models.Copy, error) {
+	copies := r.db.WithContext(ctx).
+		Joins("JOIN books ON books.id = copies.book_id AND books.deleted_at IS NULL").
+		Preload("Book").
+		Where("copies.home_branch_id = ? AND copies.status NOT IN ?", filter.BranchID, []string{models.CopyLost, models.CopyDamaged})
+	if filter.Location != "" {
+		copies = copies.Where("LOWER(COALESCE(NULLIF(copies.shelf_location, ''), books.shelf_location)) = LOWER(?)", filter.Location)
+	}
+	copies = withShelfRange(copies, filter)
+
+	var items []models.Copy
+	err := copies.Order("books.shelf_key ASC, books.id ASC, copies.barcode ASC").Limit(limit).Find(&items).Error
+	return items, err
+}
+
+func (r *copyRepository) ListOnShelf(ctx context.Context, branchID uint) ([]models.Copy, error) {
+	var copies []models.Copy
+	err := r.db.WithContext(ctx).
+		Joins("JOIN books ON books.id = copies.book_id AND books.deleted_at IS NULL").
+		Preload("Book").
+		Where("copies.branch_id = ? AND copies.status = ?", branchID, models.CopyAvailable).
+		Order("books.shelf_key ASC, books.id ASC, copies.barcode ASC").
+		Find(&copies).Error
+	return copies, err
+}
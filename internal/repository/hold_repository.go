@@ -0,0 +1,135 @@
+// internal/repository/hold_repository.go
+package repository
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/query"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type HoldRepository interface {
+	WithTx(tx *gorm.DB) HoldRepository
+	Create(ctx context.Context, hold *models.Hold) error
+	Update(ctx context.Context, hold *models.Hold) error
+	FindByIDForUpdate(ctx context.Context, id uint) (*models.Hold, error)
+	// FindActive returns the waiting or ready hold of a user on a book, or on
+	// a work when workID is set.
+	FindActive(ctx context.Context, userID, bookID, workID uint) (*models.Hold, error)
+	// FindReady returns the ready hold of a user a copy of a book was set
+	// aside for.
+	FindReady(ctx context.Context, userID, bookID uint) (*models.Hold, error)
+	// NextWaiting locks the oldest waiting hold a copy of book can fill: one
+	// on the book itself or on its work.
+	NextWaiting(ctx context.Context, book *models.Book) (*models.Hold, error)
+	// ListByUser lists the holds of a user, newest first.
+	ListByUser(ctx context.Context, userID uint, req query.PageRequest) ([]models.Hold, query.PageInfo, error)
+	// List lists the holds with status, or the active ones when status is
+	// empty, in queue order.
+	List(ctx context.Context, status string, req query.PageRequest) ([]models.Hold, query.PageInfo, error)
+}
+
+type holdRepository struct {
+	db *gorm.DB
+}
+
+func NewHoldRepository(db *gorm.DB) HoldRepository {
+	return &holdRepository{db: db}
+}
+
+func (r *holdRepository) WithTx(tx *gorm.DB) HoldRepository {
+	return &holdRepository{db: tx}
+}
+
+func (r *holdRepository) Create(ctx context.Context, hold *models.Hold) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(hold).Error
+}
+
+func (r *holdRepository) Update(ctx context.Context, hold *models.Hold) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(hold).Error
+}
+
+func (r *holdRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.Hold, error) {
+	var hold models.Hold
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&hold, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func (r *holdRepository) FindActive(ctx context.Context, userID, bookID, workID uint) (*models.Hold, error) {
+	holds := r.db.WithContext(ctx).Where("user_id = ? AND status IN ?", userID, []string{models.HoldWaiting, models.HoldReady})
+	if workID != 0 {
+		holds = holds.Where("work_id = ?", workID)
+	} else {
+		holds = holds.Where("book_id = ?", bookID)
+	}
+	var hold models.Hold
+	if err := holds.First(&hold).Error; err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func (r *holdRepository) FindReady(ctx context.Context, userID, bookID uint) (*models.Hold, error) {
+	var hold models.Hold
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ? AND ready_book_id = ?", userID, models.HoldReady, bookID).
+		Order("ready_at ASC").
+		First(&hold).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func (r *holdRepository) NextWaiting(ctx context.Context, book *models.Book) (*models.Hold, error) {
+	holds := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("status = ?", models.HoldWaiting)
+	if book.WorkID != nil {
+		holds = holds.Where("book_id = ? OR work_id = ?", book.ID, *book.WorkID)
+	} else {
+		holds = holds.Where("book_id = ?", book.ID)
+	}
+	var hold models.Hold
+	if err := holds.Order("created_at ASC, id ASC").First(&hold).Error; err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// withHoldItems preloads what a hold is on, removed books included.
+func withHoldItems(query *gorm.DB) *gorm.DB {
+	return query.Preload("Book", unscoped).Preload("Work").Preload("ReadyBook", unscoped)
+}
+
+func (r *holdRepository) ListByUser(ctx context.Context, userID uint, req query.PageRequest) ([]models.Hold, query.PageInfo, error) {
+	holds := r.db.WithContext(ctx).Model(&models.Hold{}).Where("user_id = ?", userID)
+	return listPage(holds, req, sortKey[models.Hold]{
+		columns: []string{"created_at", "id"},
+		desc:    true,
+		key:     holdKey,
+	}, withHoldItems)
+}
+
+func (r *holdRepository) List(ctx context.Context, status string, req query.PageRequest) ([]models.Hold, query.PageInfo, error) {
+	holds := r.db.WithContext(ctx).Model(&models.Hold{})
+	if status != "" {
+		holds = holds.Where("status = ?", status)
+	} else {
+		holds = holds.Where("status IN ?", []string{models.HoldWaiting, models.HoldReady})
+	}
+	return listPage(holds, req, sortKey[models.Hold]{
+		columns: []string{"created_at", "id"},
+		key:     holdKey,
+	}, func(db *gorm.DB) *gorm.DB {
+		return withHoldItems(db).Preload("User", unscoped)
+	})
+}
+
+func holdKey(h *models.Hold) []any {
+	return []any{h.CreatedAt, h.ID}
+}
@@ -0,0 +1,74 @@
+// internal/repository/import_job_repository.go
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/query"
+
+	"gorm.io/gorm"
+)
+
+type ImportJobRepository interface {
+	Create(ctx context.Context, job *models.ImportJob) error
+	FindByID(ctx context.Context, id uint) (*models.ImportJob, error)
+	Update(ctx context.Context, job *models.ImportJob) error
+	CreateIssues(ctx context.Context, issues []models.ImportJobIssue) error
+	ListIssues(ctx context.Context, jobID uint, req query.PageRequest) ([]models.ImportJobIssue, query.PageInfo, error)
+	// FailUnfinished marks pending and running jobs as failed, returning how many were changed.
+	FailUnfinished(ctx context.Context, reason string, now time.Time) (int64, error)
+}
+
+type importJobRepository struct {
+	db *gorm.DB
+}
+
+func NewImportJobRepository(db *gorm.DB) ImportJobRepository {
+	return &importJobRepository{db: db}
+}
+
+func (r *importJobRepository) Create(ctx context.Context, job *models.ImportJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *importJobRepository) FindByID(ctx context.Context, id uint) (*models.ImportJob, error) {
+	var job models.ImportJob
+	err := r.db.WithContext(ctx).First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *importJobRepository) Update(ctx context.Context, job *models.ImportJob) error {
+	return r.db.WithContext(ctx).Save(job).Error
+}
+
+func (r *importJobRepository) CreateIssues(ctx context.Context, issues []models.ImportJobIssue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(issues, 500).Error
+}
+
+func (r *importJobRepository) ListIssues(ctx context.Context, jobID uint, req query.PageRequest) ([]models.ImportJobIssue, query.PageInfo, error) {
+	issues := r.db.WithContext(ctx).Model(&models.ImportJobIssue{}).Where("job_id = ?", jobID)
+	return listPage(issues, req, sortKey[models.ImportJobIssue]{
+		columns: []string{"line", "id"},
+		key:     func(i *models.ImportJobIssue) []any { return []any{i.Line, i.ID} },
+	}, nil)
+}
+
+func (r *importJobRepository) FailUnfinished(ctx context.Context, reason string, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.ImportJob{}).
+		Where("status IN ?", []models.ImportJobStatus{models.ImportJobPending, models.ImportJobRunning}).
+		Updates(map[string]any{
+			"status":      models.ImportJobFailed,
+			"error":       reason,
+			"finished_at": now,
+			"updated_at":  now,
+		})
+	return result.RowsAffected, result.Error
+}
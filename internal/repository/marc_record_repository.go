@@ -0,0 +1,55 @@
+// internal/repository/marc_record_repository.go
+package repository
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MarcRecordRepository interface {
+	WithTx(tx *gorm.DB) MarcRecordRepository
+	Upsert(ctx context.Context, record *models.MarcRecord) error
+	FindByBookID(ctx context.Context, bookID uint) (*models.MarcRecord, error)
+	FindByBookIDs(ctx context.Context, bookIDs []uint) ([]models.MarcRecord, error)
+}
+
+type marcRecordRepository struct {
+	db *gorm.DB
+}
+
+func NewMarcRecordRepository(db *gorm.DB) MarcRecordRepository {
+	return &marcRecordRepository{db: db}
+}
+
+func (r *marcRecordRepository) WithTx(tx *gorm.DB) MarcRecordRepository {
+	return &marcRecordRepository{db: tx}
+}
+
+func (r *marcRecordRepository) Upsert(ctx context.Context, record *models.MarcRecord) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "book_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"record", "updated_at"}),
+	}).Create(record).Error
+}
+
+func (r *marcRecordRepository) FindByBookID(ctx context.Context, bookID uint) (*models.MarcRecord, error) {
+	var record models.MarcRecord
+	err := r.db.WithContext(ctx).Where("book_id = ?", bookID).First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *marcRecordRepository) FindByBookIDs(ctx context.Context, bookIDs []uint) ([]models.MarcRecord, error) {
+	var records []models.MarcRecord
+	if len(bookIDs) == 0 {
+		return records, nil
+	}
+	err := r.db.WithContext(ctx).Where("book_id IN ?", bookIDs).Find(&records).Error
+	return records, err
+}
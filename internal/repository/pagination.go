@@ -0,0 +1,194 @@
+// internal/repository/pagination.go
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpardfm/library-management-api/pkg/database"
+	"github.com/alpardfm/library-management-api/pkg/query"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned when a cursor does not fit the list it is
+// used with.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// sortKey is the ordering of a list sort: columns ending with a unique one
+// (the ID), all in one direction. key reads a row's values of the columns and
+// enables keyset paging; sorts without it, such as relevance, page by offset.
+type sortKey[T any] struct {
+	columns []string
+	desc    bool
+	key     func(row *T) []any
+}
+
+func (k sortKey[T]) order(reverse bool) string {
+	direction := "ASC"
+	if k.desc != reverse {
+		direction = "DESC"
+	}
+	parts := make([]string, len(k.columns))
+	for i, column := range k.columns {
+		parts[i] = column + " " + direction
+	}
+	return strings.Join(parts, ", ")
+}
+
+// beyond matches the rows after the cursor key in list order, or before it.
+func (k sortKey[T]) beyond(values []any, before bool) (string, []any) {
+	op := ">"
+	if k.desc != before {
+		op = "<"
+	}
+	placeholders := strings.Repeat("?, ", len(values))
+	return fmt.Sprintf("(%s) %s (%s)", strings.Join(k.columns, ", "), op, placeholders[:len(placeholders)-2]), values
+}
+
+// encode turns row's key into cursor values.
+func (k sortKey[T]) encode(row *T) []string {
+	values := k.key(row)
+	encoded := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case time.Time:
+			encoded[i] = v.Format(time.RFC3339Nano)
+		case string:
+			encoded[i] = v
+		default:
+			encoded[i] = fmt.Sprint(v)
+		}
+	}
+	return encoded
+}
+
+// decode parses cursor values back to the types key returns.
+func (k sortKey[T]) decode(encoded []string) ([]any, error) {
+	zero := k.key(new(T))
+	if len(encoded) != len(zero) {
+		return nil, fmt.Errorf("cursor has %d values, want %d", len(encoded), len(zero))
+	}
+	values := make([]any, len(zero))
+	for i, kind := range zero {
+		var err error
+		switch kind.(type) {
+		case time.Time:
+			values[i], err = time.Parse(time.RFC3339Nano, encoded[i])
+		case string:
+			values[i] = encoded[i]
+		case uint:
+			var id uint64
+			id, err = strconv.ParseUint(encoded[i], 10, 64)
+			values[i] = uint(id)
+		default:
+			values[i], err = strconv.Atoi(encoded[i])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor value %q: %w", encoded[i], err)
+		}
+	}
+	return values, nil
+}
+
+// countRows counts the rows of db as mode asks; CountNone returns 0.
+func countRows(db *gorm.DB, mode string) (int64, error) {
+	var total int64
+	switch mode {
+	case query.CountNone:
+		return 0, nil
+	case query.CountEstimate:
+		return database.DialectOf(db).EstimateCount(db)
+	default:
+		err := db.Count(&total).Error
+		return total, err
+	}
+}
+
+// listPage counts db and loads the page req selects from it, one row more
+// than the limit to learn whether another page follows. prepare adds what
+// the rows need beyond the filter, e.g. preloads, after counting.
+func listPage[T any](db *gorm.DB, req query.PageRequest, sort sortKey[T], prepare func(*gorm.DB) *gorm.DB) ([]T, query.PageInfo, error) {
+	info := query.PageInfo{Count: req.Count}
+	total, err := countRows(db, req.Count)
+	if err != nil {
+		return nil, info, err
+	}
+	info.Total = total
+
+	find := db
+	if prepare != nil {
+		find = prepare(find)
+	}
+
+	cursor := req.Cursor
+	if sort.key == nil && cursor != nil && len(cursor.Key) > 0 {
+		return nil, info, fmt.Errorf("%w: sort %q pages by offset", ErrInvalidCursor, req.Sort)
+	}
+	if sort.key == nil || cursor == nil || len(cursor.Key) == 0 {
+		offset := (req.Page - 1) * req.Limit
+		if cursor != nil {
+			offset = cursor.Offset
+		}
+		rows := make([]T, 0, req.Limit+1)
+		if err := find.Order(sort.order(false)).Offset(offset).Limit(req.Limit + 1).Find(&rows).Error; err != nil {
+			return nil, info, err
+		}
+		more := len(rows) > req.Limit
+		if more {
+			rows = rows[:req.Limit]
+		}
+
+		if sort.key == nil {
+			if more {
+				info.NextCursor = query.Cursor{Sort: req.Sort, Offset: offset + req.Limit}.Encode()
+			}
+			if offset > 0 {
+				info.PrevCursor = query.Cursor{Sort: req.Sort, Offset: max(offset-req.Limit, 0)}.Encode()
+			}
+			return rows, info, nil
+		}
+		if more {
+			info.NextCursor = query.Cursor{Sort: req.Sort, Key: sort.encode(&rows[len(rows)-1])}.Encode()
+		}
+		if offset > 0 && len(rows) > 0 {
+			info.PrevCursor = query.Cursor{Sort: req.Sort, Key: sort.encode(&rows[0]), Before: true}.Encode()
+		}
+		return rows, info, nil
+	}
+
+	values, err := sort.decode(cursor.Key)
+	if err != nil {
+		return nil, info, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	condition, args := sort.beyond(values, cursor.Before)
+	rows := make([]T, 0, req.Limit+1)
+	err = find.Where(condition, args...).Order(sort.order(cursor.Before)).Limit(req.Limit + 1).Find(&rows).Error
+	if err != nil {
+		return nil, info, err
+	}
+	more := len(rows) > req.Limit
+	if more {
+		rows = rows[:req.Limit]
+	}
+	if cursor.Before {
+		slices.Reverse(rows)
+	}
+	if len(rows) == 0 {
+		return rows, info, nil
+	}
+
+	// Going forward there is a page behind the first row, and going back
+	// one after the last.
+	if more || cursor.Before {
+		info.NextCursor = query.Cursor{Sort: req.Sort, Key: sort.encode(&rows[len(rows)-1])}.Encode()
+	}
+	if more || !cursor.Before {
+		info.PrevCursor = query.Cursor{Sort: req.Sort, Key: sort.encode(&rows[0]), Before: true}.Encode()
+	}
+	return rows, info, nil
+}
@@ -0,0 +1,73 @@
+// internal/repository/series_repository.go
+package repository
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/database"
+	"github.com/alpardfm/library-management-api/pkg/query"
+
+	"gorm.io/gorm"
+)
+
+type SeriesRepository interface {
+	Create(ctx context.Context, series *models.Series) error
+	// Update saves series and bumps its version. It fails with
+	// ErrStaleVersion when the row was written since series was read.
+	Update(ctx context.Context, series *models.Series) error
+	Delete(ctx context.Context, id uint) error
+	// FindByID returns the series with its works in volume order.
+	FindByID(ctx context.Context, id uint) (*models.Series, error)
+	List(ctx context.Context, search string, req query.PageRequest) ([]models.Series, query.PageInfo, error)
+}
+
+type seriesRepository struct {
+	db *gorm.DB
+}
+
+func NewSeriesRepository(db *gorm.DB) SeriesRepository {
+	return &seriesRepository{db: db}
+}
+
+// selectWorkCount selects series together with the number of their works.
+func selectWorkCount(query *gorm.DB) *gorm.DB {
+	return query.Select("series.*, (SELECT COUNT(*) FROM works WHERE works.series_id = series.id) AS work_count")
+}
+
+func (r *seriesRepository) Create(ctx context.Context, series *models.Series) error {
+	return r.db.WithContext(ctx).Omit("Works").Create(series).Error
+}
+
+func (r *seriesRepository) Update(ctx context.Context, series *models.Series) error {
+	return updateVersioned(r.db.WithContext(ctx).Omit("Works"), series, &series.Version)
+}
+
+func (r *seriesRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Series{}, id).Error
+}
+
+func (r *seriesRepository) FindByID(ctx context.Context, id uint) (*models.Series, error) {
+	var series models.Series
+	err := selectWorkCount(r.db.WithContext(ctx).Model(&models.Series{})).
+		Preload("Works", func(db *gorm.DB) *gorm.DB {
+			return selectEditionCount(db).Order("volume ASC, title ASC, id ASC")
+		}).
+		Where("series.id = ?", id).
+		First(&series).Error
+	if err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (r *seriesRepository) List(ctx context.Context, search string, req query.PageRequest) ([]models.Series, query.PageInfo, error) {
+	series := r.db.WithContext(ctx).Model(&models.Series{})
+	if search != "" {
+		series = series.Where(database.DialectOf(r.db).ILike("series.name"), "%"+search+"%")
+	}
+	return listPage(series, req, sortKey[models.Series]{
+		columns: []string{"name", "id"},
+		key:     func(s *models.Series) []any { return []any{s.Name, s.ID} },
+	}, selectWorkCount)
+}
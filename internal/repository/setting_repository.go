@@ -0,0 +1,121 @@
+// internal/repository/setting_repository.go
+package repository
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/query"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SettingRepository interface {
+	WithTx(tx *gorm.DB) SettingRepository
+	List(ctx context.Context) ([]models.Setting, error)
+	FindByKey(ctx context.Context, key string) (*models.Setting, error)
+	// Create adds an override. It returns ErrStaleVersion when the setting
+	// has been overridden since it was read.
+	Create(ctx context.Context, setting *models.Setting) error
+	// Update and Delete only write the override at the version it was read
+	// and return ErrStaleVersion otherwise.
+	Update(ctx context.Context, setting *models.Setting) error
+	Delete(ctx context.Context, setting *models.Setting) error
+	CreateChange(ctx context.Context, change *models.SettingChange) error
+	// ChangeCounts returns the number of recorded changes of each key.
+	ChangeCounts(ctx context.Context) (map[string]int64, error)
+	ListChanges(ctx context.Context, key string, req query.PageRequest) ([]models.SettingChange, query.PageInfo, error)
+	// Revision returns the ID of the latest settings change, or 0 when there is none.
+	Revision(ctx context.Context) (int64, error)
+}
+
+type settingRepository struct {
+	db *gorm.DB
+}
+
+func NewSettingRepository(db *gorm.DB) SettingRepository {
+	return &settingRepository{db: db}
+}
+
+func (r *settingRepository) WithTx(tx *gorm.DB) SettingRepository {
+	return &settingRepository{db: tx}
+}
+
+func (r *settingRepository) List(ctx context.Context) ([]models.Setting, error) {
+	var settings []models.Setting
+	err := r.db.WithContext(ctx).Order("key ASC").Find(&settings).Error
+	return settings, err
+}
+
+func (r *settingRepository) FindByKey(ctx context.Context, key string) (*models.Setting, error) {
+	var setting models.Setting
+	err := r.db.WithContext(ctx).Where("key = ?", key).First(&setting).Error
+	if err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+func (r *settingRepository) Create(ctx context.Context, setting *models.Setting) error {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(setting)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrStaleVersion
+	}
+	return result.Error
+}
+
+func (r *settingRepository) Update(ctx context.Context, setting *models.Setting) error {
+	return updateVersioned(r.db.WithContext(ctx), setting, &setting.Version)
+}
+
+func (r *settingRepository) Delete(ctx context.Context, setting *models.Setting) error {
+	result := r.db.WithContext(ctx).Where("key = ? AND version = ?", setting.Key, setting.Version).Delete(&models.Setting{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrStaleVersion
+	}
+	return result.Error
+}
+
+func (r *settingRepository) CreateChange(ctx context.Context, change *models.SettingChange) error {
+	return r.db.WithContext(ctx).Create(change).Error
+}
+
+func (r *settingRepository) ListChanges(ctx context.Context, key string, req query.PageRequest) ([]models.SettingChange, query.PageInfo, error) {
+	changes := r.db.WithContext(ctx).Model(&models.SettingChange{})
+	if key != "" {
+		changes = changes.Where("key = ?", key)
+	}
+	return listPage(changes, req, sortKey[models.SettingChange]{
+		columns: []string{"id"},
+		desc:    true,
+		key:     func(c *models.SettingChange) []any { return []any{c.ID} },
+	}, nil)
+}
+
+func (r *settingRepository) ChangeCounts(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		Key   string
+		Count int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.SettingChange{}).
+		Select("key, COUNT(*) AS count").
+		Group("key").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Key] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *settingRepository) Revision(ctx context.Context) (int64, error) {
+	var revision int64
+	err := r.db.WithContext(ctx).Model(&models.SettingChange{}).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&revision).Error
+	return revision, err
+}
@@ -0,0 +1,131 @@
+// internal/repository/stocktake_repository.go
+package repository
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/query"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StocktakeFilter narrows a stocktake listing. Zero values match everything.
+type StocktakeFilter struct {
+	Status   string
+	BranchID uint
+}
+
+type StocktakeRepository interface {
+	WithTx(tx *gorm.DB) StocktakeRepository
+	Create(ctx context.Context, stocktake *models.Stocktake) error
+	Update(ctx context.Context, stocktake *models.Stocktake) error
+	// FindByID returns the stocktake with its branch and scan count.
+	FindByID(ctx context.Context, id uint) (*models.Stocktake, error)
+	FindByIDForUpdate(ctx context.Context, id uint) (*models.Stocktake, error)
+	// FindOpen returns the open stocktake of a branch at location, compared
+	// case-insensitively.
+	FindOpen(ctx context.Context, branchID uint, location string) (*models.Stocktake, error)
+	// List lists the stocktakes matching filter, newest first.
+	List(ctx context.Context, filter StocktakeFilter, req query.PageRequest) ([]models.Stocktake, query.PageInfo, error)
+	// AddScans records scans, skipping items already scanned in their
+	// stocktake, and returns how many were recorded.
+	AddScans(ctx context.Context, scans []models.StocktakeScan) (int64, error)
+	// Scans lists the scans of a stocktake with their copies and books, in
+	// scan order.
+	Scans(ctx context.Context, stocktakeID uint) ([]models.StocktakeScan, error)
+}
+
+type stocktakeRepository struct {
+	db *gorm.DB
+}
+
+func NewStocktakeRepository(db *gorm.DB) StocktakeRepository {
+	return &stocktakeRepository{db: db}
+}
+
+func (r *stocktakeRepository) WithTx(tx *gorm.DB) StocktakeRepository {
+	return &stocktakeRepository{db: tx}
+}
+
+// withStocktakeScans selects stocktakes with their branch and the number of
+// items scanned.
+func withStocktakeScans(query *gorm.DB) *gorm.DB {
+	return query.Select("stocktakes.*, (SELECT COUNT(*) FROM stocktake_scans WHERE stocktake_scans.stocktake_id = stocktakes.id) AS scan_count").
+		Preload("Branch")
+}
+
+func (r *stocktakeRepository) Create(ctx context.Context, stocktake *models.Stocktake) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(stocktake).Error
+}
+
+func (r *stocktakeRepository) Update(ctx context.Context, stocktake *models.Stocktake) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(stocktake).Error
+}
+
+func (r *stocktakeRepository) FindByID(ctx context.Context, id uint) (*models.Stocktake, error) {
+	var stocktake models.Stocktake
+	err := withStocktakeScans(r.db.WithContext(ctx).Model(&models.Stocktake{})).
+		Where("stocktakes.id = ?", id).
+		First(&stocktake).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stocktake, nil
+}
+
+func (r *stocktakeRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.Stocktake, error) {
+	var stocktake models.Stocktake
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&stocktake, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stocktake, nil
+}
+
+func (r *stocktakeRepository) FindOpen(ctx context.Context, branchID uint, location string) (*models.Stocktake, error) {
+	var stocktake models.Stocktake
+	err := r.db.WithContext(ctx).
+		Where("branch_id = ? AND status = ? AND LOWER(location) = LOWER(?)", branchID, models.StocktakeOpen, location).
+		First(&stocktake).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stocktake, nil
+}
+
+func (r *stocktakeRepository) List(ctx context.Context, filter StocktakeFilter, req query.PageRequest) ([]models.Stocktake, query.PageInfo, error) {
+	stocktakes := r.db.WithContext(ctx).Model(&models.Stocktake{})
+	if filter.Status != "" {
+		stocktakes = stocktakes.Where("status = ?", filter.Status)
+	}
+	if filter.BranchID != 0 {
+		stocktakes = stocktakes.Where("branch_id = ?", filter.BranchID)
+	}
+	return listPage(stocktakes, req, sortKey[models.Stocktake]{
+		columns: []string{"created_at", "id"},
+		desc:    true,
+		key:     func(s *models.Stocktake) []any { return []any{s.CreatedAt, s.ID} },
+	}, withStocktakeScans)
+}
+
+func (r *stocktakeRepository) AddScans(ctx context.Context, scans []models.StocktakeScan) (int64, error) {
+	if len(scans) == 0 {
+		return 0, nil
+	}
+	result := r.db.WithContext(ctx).Omit(clause.Associations).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "stocktake_id"}, {Name: "barcode"}}, DoNothing: true}).
+		Create(&scans)
+	return result.RowsAffected, result.Error
+}
+
+func (r *stocktakeRepository) Scans(ctx context.Context, stocktakeID uint) ([]models.StocktakeScan, error) {
+	var scans []models.StocktakeScan
+	err := r.db.WithContext(ctx).
+		Preload("Copy.Book", unscoped).
+		Where("stocktake_id = ?", stocktakeID).
+		Order("scanned_at ASC, id ASC").
+		Find(&scans).Error
+	return scans, err
+}
@@ -0,0 +1,228 @@
+// internal/repository/subject_repository.go
+package repository
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/query"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SubjectRepository interface {
+	WithTx(tx *gorm.DB) SubjectRepository
+	Create(ctx context.Context, subject *models.Subject) error
+	// Update saves subject and bumps its version. It fails with ErrStaleVersion
+	// when the row was written since subject was read.
+	Update(ctx context.Context, subject *models.Subject) error
+	Delete(ctx context.Context, id uint) error
+	// FindByID returns the subject with its aliases and the number of books in
+	// its subtree.
+	FindByID(ctx context.Context, id uint) (*models.Subject, error)
+	FindByIDs(ctx context.Context, ids []uint) ([]models.Subject, error)
+	// FindByNameKey returns every subject named key, wherever it is in the tree.
+	FindByNameKey(ctx context.Context, key string) ([]models.Subject, error)
+	// FindChildByNameKey returns the subject named key directly below
+	// parentID, or at the root when parentID is nil.
+	FindChildByNameKey(ctx context.Context, parentID *uint, key string) (*models.Subject, error)
+	// List returns all subjects with their subtree book counts, by name.
+	List(ctx context.Context) ([]models.Subject, error)
+	// ListChildren returns the subjects directly below parentID with their
+	// subtree book counts, by name.
+	ListChildren(ctx context.Context, parentID uint) ([]models.Subject, error)
+	CountChildren(ctx context.Context, id uint) (int64, error)
+	// MoveSubtree rewrites the paths below oldPath after the subject at
+	// oldPath moved to newPath, shifting their depth by depthChange.
+	MoveSubtree(ctx context.Context, oldPath, newPath string, depthChange int) error
+
+	FindAliasByNameKey(ctx context.Context, key string) (*models.SubjectAlias, error)
+	CreateAlias(ctx context.Context, alias *models.SubjectAlias) error
+	DeleteAlias(ctx context.Context, subjectID, aliasID uint) (int64, error)
+
+	// ListBooks returns the books assigned to the subject, or to any subject
+	// of its subtree when descendants is true.
+	ListBooks(ctx context.Context, subject *models.Subject, descendants bool, req query.PageRequest) ([]models.Book, query.PageInfo, error)
+	// ReplaceBookSubjects sets the complete subject list of a book.
+	ReplaceBookSubjects(ctx context.Context, bookID uint, subjectIDs []uint) error
+	// GenreCounts returns the distinct non-empty genre strings of books with
+	// the number of books using each.
+	GenreCounts(ctx context.Context) ([]GenreCount, error)
+	// LinkGenre assigns the subject to every book with the exact genre string
+	// that does not have it yet, and returns the number of books linked.
+	LinkGenre(ctx context.Context, genre string, subjectID uint) (int64, error)
+}
+
+// GenreCount is a distinct Book.Genre value and how many books use it.
+type GenreCount struct {
+	Genre string
+	Books int64
+}
+
+type subjectRepository struct {
+	db *gorm.DB
+}
+
+func NewSubjectRepository(db *gorm.DB) SubjectRepository {
+	return &subjectRepository{db: db}
+}
+
+func (r *subjectRepository) WithTx(tx *gorm.DB) SubjectRepository {
+	return &subjectRepository{db: tx}
+}
+
+// withBookCount selects subjects together with the number of distinct books
+// assigned anywhere in their subtree.
+func (r *subjectRepository) withBookCount(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&models.Subject{}).Select(
+		"subjects.*, (SELECT COUNT(DISTINCT book_subjects.book_id) FROM book_subjects" +
+			" JOIN books ON books.id = book_subjects.book_id AND books.deleted_at IS NULL" +
+			" JOIN subjects subtree ON subtree.id = book_subjects.subject_id" +
+			" WHERE subtree.path LIKE subjects.path || '%') AS book_count")
+}
+
+func (r *subjectRepository) Create(ctx context.Context, subject *models.Subject) error {
+	return r.db.WithContext(ctx).Omit("Aliases").Create(subject).Error
+}
+
+func (r *subjectRepository) Update(ctx context.Context, subject *models.Subject) error {
+	return updateVersioned(r.db.WithContext(ctx).Omit("Aliases", "Parent"), subject, &subject.Version)
+}
+
+func (r *subjectRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Subject{}, id).Error
+}
+
+func (r *subjectRepository) FindByID(ctx context.Context, id uint) (*models.Subject, error) {
+	var subject models.Subject
+	err := r.withBookCount(ctx).
+		Preload("Aliases", func(db *gorm.DB) *gorm.DB { return db.Order("name ASC") }).
+		Where("subjects.id = ?", id).
+		First(&subject).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subject, nil
+}
+
+func (r *subjectRepository) FindByIDs(ctx context.Context, ids []uint) ([]models.Subject, error) {
+	var subjects []models.Subject
+	if len(ids) == 0 {
+		return subjects, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&subjects).Error
+	return subjects, err
+}
+
+func (r *subjectRepository) FindByNameKey(ctx context.Context, key string) ([]models.Subject, error) {
+	var subjects []models.Subject
+	err := r.db.WithContext(ctx).Where("name_key = ?", key).Order("id ASC").Find(&subjects).Error
+	return subjects, err
+}
+
+func (r *subjectRepository) FindChildByNameKey(ctx context.Context, parentID *uint, key string) (*models.Subject, error) {
+	var subject models.Subject
+	query := r.db.WithContext(ctx).Where("name_key = ?", key)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	if err := query.First(&subject).Error; err != nil {
+		return nil, err
+	}
+	return &subject, nil
+}
+
+func (r *subjectRepository) List(ctx context.Context) ([]models.Subject, error) {
+	var subjects []models.Subject
+	err := r.withBookCount(ctx).Order("name ASC, id ASC").Find(&subjects).Error
+	return subjects, err
+}
+
+func (r *subjectRepository) ListChildren(ctx context.Context, parentID uint) ([]models.Subject, error) {
+	var subjects []models.Subject
+	err := r.withBookCount(ctx).Where("parent_id = ?", parentID).Order("name ASC, id ASC").Find(&subjects).Error
+	return subjects, err
+}
+
+func (r *subjectRepository) CountChildren(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Subject{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (r *subjectRepository) MoveSubtree(ctx context.Context, oldPath, newPath string, depthChange int) error {
+	return r.db.WithContext(ctx).Exec(
+		"UPDATE subjects SET path = ? || SUBSTR(path, ?), depth = depth + ?, version = version + 1 WHERE path LIKE ? AND path <> ?",
+		newPath, len(oldPath)+1, depthChange, oldPath+"%", oldPath).Error
+}
+
+func (r *subjectRepository) FindAliasByNameKey(ctx context.Context, key string) (*models.SubjectAlias, error) {
+	var alias models.SubjectAlias
+	err := r.db.WithContext(ctx).Where("name_key = ?", key).First(&alias).Error
+	if err != nil {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+func (r *subjectRepository) CreateAlias(ctx context.Context, alias *models.SubjectAlias) error {
+	return r.db.WithContext(ctx).Create(alias).Error
+}
+
+func (r *subjectRepository) DeleteAlias(ctx context.Context, subjectID, aliasID uint) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND subject_id = ?", aliasID, subjectID).
+		Delete(&models.SubjectAlias{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *subjectRepository) ListBooks(ctx context.Context, subject *models.Subject, descendants bool, req query.PageRequest) ([]models.Book, query.PageInfo, error) {
+	assigned := r.db.Model(&models.BookSubject{}).Select("book_id")
+	if descendants {
+		assigned = assigned.Where("subject_id IN (?)",
+			r.db.Model(&models.Subject{}).Select("id").Where("path LIKE ?", subject.Path+"%"))
+	} else {
+		assigned = assigned.Where("subject_id = ?", subject.ID)
+	}
+	books := r.db.WithContext(ctx).Model(&models.Book{}).Where("id IN (?)", assigned)
+	return listPage(books, req, bookSort(req.Sort, false), withBookRelations)
+}
+
+func (r *subjectRepository) ReplaceBookSubjects(ctx context.Context, bookID uint, subjectIDs []uint) error {
+	if err := r.db.WithContext(ctx).Where("book_id = ?", bookID).Delete(&models.BookSubject{}).Error; err != nil {
+		return err
+	}
+	if len(subjectIDs) == 0 {
+		return nil
+	}
+	links := make([]models.BookSubject, len(subjectIDs))
+	for i, id := range subjectIDs {
+		links[i] = models.BookSubject{BookID: bookID, SubjectID: id}
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error
+}
+
+func (r *subjectRepository) GenreCounts(ctx context.Context) ([]GenreCount, error) {
+	var counts []GenreCount
+	err := r.db.WithContext(ctx).Model(&models.Book{}).
+		Select("genre, COUNT(*) AS books").
+		Where("genre <> ''").
+		Group("genre").
+		Order("genre ASC").
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *subjectRepository) LinkGenre(ctx context.Context, genre string, subjectID uint) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		INSERT INTO book_subjects (book_id, subject_id)
+		SELECT id, ? FROM books
+		WHERE genre = ? AND NOT EXISTS (
+			SELECT 1 FROM book_subjects existing
+			WHERE existing.book_id = books.id AND existing.subject_id = ?
+		)`, subjectID, genre, subjectID)
+	return result.RowsAffected, result.Error
+}
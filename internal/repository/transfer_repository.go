@@ -0,0 +1,105 @@
+// internal/repository/transfer_repository.go
+package repository
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/query"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TransferFilter narrows a transfer listing. Zero values match everything,
+// except an empty Status, which matches the open transfers.
+type TransferFilter struct {
+	Status string
+	// BranchID matches transfers from or to the branch.
+	BranchID uint
+	CopyID   uint
+}
+
+type TransferRepository interface {
+	WithTx(tx *gorm.DB) TransferRepository
+	Create(ctx context.Context, transfer *models.Transfer) error
+	Update(ctx context.Context, transfer *models.Transfer) error
+	FindByID(ctx context.Context, id uint) (*models.Transfer, error)
+	FindByIDForUpdate(ctx context.Context, id uint) (*models.Transfer, error)
+	// FindOpen returns the requested or in-transit transfer of a copy.
+	FindOpen(ctx context.Context, copyID uint) (*models.Transfer, error)
+	// List lists the transfers matching filter, oldest first.
+	List(ctx context.Context, filter TransferFilter, req query.PageRequest) ([]models.Transfer, query.PageInfo, error)
+}
+
+type transferRepository struct {
+	db *gorm.DB
+}
+
+func NewTransferRepository(db *gorm.DB) TransferRepository {
+	return &transferRepository{db: db}
+}
+
+func (r *transferRepository) WithTx(tx *gorm.DB) TransferRepository {
+	return &transferRepository{db: tx}
+}
+
+func (r *transferRepository) Create(ctx context.Context, transfer *models.Transfer) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(transfer).Error
+}
+
+func (r *transferRepository) Update(ctx context.Context, transfer *models.Transfer) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(transfer).Error
+}
+
+// withTransferItems preloads the copy and branches of a transfer.
+func withTransferItems(query *gorm.DB) *gorm.DB {
+	return query.Preload("Copy.Book", unscoped).Preload("FromBranch").Preload("ToBranch")
+}
+
+func (r *transferRepository) FindByID(ctx context.Context, id uint) (*models.Transfer, error) {
+	var transfer models.Transfer
+	if err := withTransferItems(r.db.WithContext(ctx)).First(&transfer, id).Error; err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (r *transferRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.Transfer, error) {
+	var transfer models.Transfer
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&transfer, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (r *transferRepository) FindOpen(ctx context.Context, copyID uint) (*models.Transfer, error) {
+	var transfer models.Transfer
+	err := r.db.WithContext(ctx).
+		Where("copy_id = ? AND status IN ?", copyID, []string{models.TransferRequested, models.TransferInTransit}).
+		First(&transfer).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (r *transferRepository) List(ctx context.Context, filter TransferFilter, req query.PageRequest) ([]models.Transfer, query.PageInfo, error) {
+	transfers := r.db.WithContext(ctx).Model(&models.Transfer{})
+	if filter.Status != "" {
+		transfers = transfers.Where("status = ?", filter.Status)
+	} else {
+		transfers = transfers.Where("status IN ?", []string{models.TransferRequested, models.TransferInTransit})
+	}
+	if filter.BranchID != 0 {
+		transfers = transfers.Where("from_branch_id = ? OR to_branch_id = ?", filter.BranchID, filter.BranchID)
+	}
+	if filter.CopyID != 0 {
+		transfers = transfers.Where("copy_id = ?", filter.CopyID)
+	}
+	return listPage(transfers, req, sortKey[models.Transfer]{
+		columns: []string{"created_at", "id"},
+		key:     func(t *models.Transfer) []any { return []any{t.CreatedAt, t.ID} },
+	}, withTransferItems)
+}
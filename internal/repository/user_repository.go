@@ -2,6 +2,9 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/alpardfm/library-management-api/internal/models"
 
 	"gorm.io/gorm"
@@ -10,14 +13,19 @@ import (
 
 type UserRepository interface {
 	WithTx(tx *gorm.DB) UserRepository
-	Create(user *models.User) error
-	FindByID(id uint) (*models.User, error)
-	FindByIDForUpdate(id uint) (*models.User, error)
-	FindByUsername(username string) (*models.User, error)
-	FindByEmail(email string) (*models.User, error)
-	Update(user *models.User) error
-	Delete(id uint) error
-	List(page, limit int) ([]models.User, int64, error)
+	// WithDeleted returns a repository that also sees soft-deleted users.
+	WithDeleted() UserRepository
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	FindByIDForUpdate(ctx context.Context, id uint) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context, page, limit int) ([]models.User, int64, error)
+	// DeletedBefore returns the users deleted before cutoff whose personal
+	// data has not been purged yet.
+	DeletedBefore(ctx context.Context, cutoff time.Time) ([]models.User, error)
 }
 
 type userRepository struct {
@@ -32,65 +40,80 @@ func (r *userRepository) WithTx(tx *gorm.DB) UserRepository {
 	return &userRepository{db: tx}
 }
 
-func (r *userRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+func (r *userRepository) WithDeleted() UserRepository {
+	return &userRepository{db: r.db.Unscoped().Session(&gorm.Session{})}
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
-func (r *userRepository) FindByID(id uint) (*models.User, error) {
+func (r *userRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
 	var user models.User
-	err := r.db.First(&user, id).Error
+	err := r.db.WithContext(ctx).First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) FindByIDForUpdate(id uint) (*models.User, error) {
+func (r *userRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.User, error) {
 	var user models.User
-	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) FindByUsername(username string) (*models.User, error) {
+func (r *userRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("username = ?", username).First(&user).Error
+	err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) FindByEmail(email string) (*models.User, error) {
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
 }
 
-func (r *userRepository) Delete(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
+func (r *userRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.User{}, id).Error
 }
 
-func (r *userRepository) List(page, limit int) ([]models.User, int64, error) {
+func (r *userRepository) List(ctx context.Context, page, limit int) ([]models.User, int64, error) {
 	var users []models.User
 	var total int64
 
 	offset := (page - 1) * limit
 
 	// Count total
-	r.db.Model(&models.User{}).Count(&total)
+	if err := r.db.WithContext(ctx).Model(&models.User{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
 
 	// Get paginated results
-	err := r.db.Offset(offset).Limit(limit).Find(&users).Error
+	err := r.db.WithContext(ctx).Offset(offset).Limit(limit).Find(&users).Error
 
 	return users, total, err
 }
+
+func (r *userRepository) DeletedBefore(ctx context.Context, cutoff time.Time) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND purged_at IS NULL", cutoff).
+		Order("id ASC").
+		Find(&users).Error
+	return users, err
+}
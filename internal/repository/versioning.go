@@ -0,0 +1,27 @@
+// internal/repository/versioning.go
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrStaleVersion is returned when a row was written after it was read: the
+// version being saved over is no longer the stored one.
+var ErrStaleVersion = errors.New("row was changed since it was read")
+
+// updateVersioned saves every column of row, read at *version, and bumps the
+// version. Nothing is written when the stored version differs.
+func updateVersioned(db *gorm.DB, row any, version *uint) error {
+	read := *version
+	*version = read + 1
+	result := db.Model(row).Select("*").Where("version = ?", read).Updates(row)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrStaleVersion
+	}
+	if result.Error != nil {
+		*version = read
+	}
+	return result.Error
+}
@@ -0,0 +1,87 @@
+// internal/repository/work_repository.go
+package repository
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/database"
+	"github.com/alpardfm/library-management-api/pkg/query"
+
+	"gorm.io/gorm"
+)
+
+type WorkRepository interface {
+	WithTx(tx *gorm.DB) WorkRepository
+	Create(ctx context.Context, work *models.Work) error
+	// Update saves work and bumps its version. It fails with ErrStaleVersion
+	// when the row was written since work was read.
+	Update(ctx context.Context, work *models.Work) error
+	Delete(ctx context.Context, id uint) error
+	// FindByID returns the work with its series, edition count and the
+	// editions in the catalog, oldest publication first.
+	FindByID(ctx context.Context, id uint) (*models.Work, error)
+	// List lists the works whose title or author matches search, of one
+	// series when seriesID is set, by title.
+	List(ctx context.Context, search string, seriesID uint, req query.PageRequest) ([]models.Work, query.PageInfo, error)
+}
+
+type workRepository struct {
+	db *gorm.DB
+}
+
+func NewWorkRepository(db *gorm.DB) WorkRepository {
+	return &workRepository{db: db}
+}
+
+func (r *workRepository) WithTx(tx *gorm.DB) WorkRepository {
+	return &workRepository{db: tx}
+}
+
+// selectEditionCount selects works together with the number of their
+// editions in the catalog.
+func selectEditionCount(query *gorm.DB) *gorm.DB {
+	return query.Select("works.*, (SELECT COUNT(*) FROM books" +
+		" WHERE books.work_id = works.id AND books.deleted_at IS NULL) AS edition_count")
+}
+
+func (r *workRepository) Create(ctx context.Context, work *models.Work) error {
+	return r.db.WithContext(ctx).Omit("Series", "Editions").Create(work).Error
+}
+
+func (r *workRepository) Update(ctx context.Context, work *models.Work) error {
+	return updateVersioned(r.db.WithContext(ctx).Omit("Series", "Editions"), work, &work.Version)
+}
+
+func (r *workRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Work{}, id).Error
+}
+
+func (r *workRepository) FindByID(ctx context.Context, id uint) (*models.Work, error) {
+	var work models.Work
+	err := selectEditionCount(r.db.WithContext(ctx).Model(&models.Work{})).
+		Preload("Series").
+		Preload("Editions", func(db *gorm.DB) *gorm.DB { return db.Order("publication_year ASC, id ASC") }).
+		Where("works.id = ?", id).
+		First(&work).Error
+	if err != nil {
+		return nil, err
+	}
+	return &work, nil
+}
+
+func (r *workRepository) List(ctx context.Context, search string, seriesID uint, req query.PageRequest) ([]models.Work, query.PageInfo, error) {
+	works := r.db.WithContext(ctx).Model(&models.Work{})
+	if search != "" {
+		dialect := database.DialectOf(r.db)
+		pattern := "%" + search + "%"
+		works = works.Where(dialect.ILike("works.title")+" OR "+dialect.ILike("works.author"), pattern, pattern)
+	}
+	if seriesID != 0 {
+		works = works.Where("works.series_id = ?", seriesID)
+	}
+	return listPage(works, req, sortKey[models.Work]{
+		columns: []string{"title", "id"},
+		key:     func(w *models.Work) []any { return []any{w.Title, w.ID} },
+	}, selectEditionCount)
+}
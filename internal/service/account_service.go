@@ -0,0 +1,42 @@
+// internal/service/account_service.go
+package service
+
+import (
+	"context"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+)
+
+// AccountService reads what patrons owe the library.
+type AccountService interface {
+	// GetAccount lists the entries on a user's account, newest first, with
+	// its balance.
+	GetAccount(ctx context.Context, userID uint, req query.PageRequest) ([]models.AccountEntry, int64, query.PageInfo, error)
+}
+
+type accountService struct {
+	accountRepo repository.AccountRepository
+	userRepo    repository.UserRepository
+}
+
+func NewAccountService(accountRepo repository.AccountRepository, userRepo repository.UserRepository) AccountService {
+	return &accountService{accountRepo: accountRepo, userRepo: userRepo}
+}
+
+func (s *accountService) GetAccount(ctx context.Context, userID uint, req query.PageRequest) ([]models.AccountEntry, int64, query.PageInfo, error) {
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		return nil, 0, query.PageInfo{}, lookupError(err, "user")
+	}
+	entries, info, err := s.accountRepo.ListByUser(ctx, userID, req)
+	if err != nil {
+		return nil, 0, query.PageInfo{}, listError(err, "failed to list account entries")
+	}
+	balance, err := s.accountRepo.Balance(ctx, userID)
+	if err != nil {
+		return nil, 0, query.PageInfo{}, apperror.Internal("failed to sum account balance", err)
+	}
+	return entries, balance, info, nil
+}
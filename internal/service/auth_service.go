@@ -2,6 +2,7 @@
 package service
 
 import (
+	"context"
 	"github.com/alpardfm/library-management-api/internal/dto"
 	"github.com/alpardfm/library-management-api/internal/models"
 	"github.com/alpardfm/library-management-api/internal/repository"
@@ -12,8 +13,8 @@ import (
 )
 
 type AuthService interface {
-	Register(req dto.RegisterRequest) (*models.User, error)
-	Login(req dto.LoginRequest) (*dto.LoginResponse, error)
+	Register(ctx context.Context, req dto.RegisterRequest) (*models.User, error)
+	Login(ctx context.Context, req dto.LoginRequest) (*dto.LoginResponse, error)
 	GenerateToken(user *models.User) (string, error)
 	ValidateToken(tokenString string) (*auth.Claims, error)
 }
@@ -32,15 +33,18 @@ func NewAuthService(userRepo repository.UserRepository, jwtSecret string, jwtExp
 	}
 }
 
-func (s *authService) Register(req dto.RegisterRequest) (*models.User, error) {
+func (s *authService) Register(ctx context.Context, req dto.RegisterRequest) (*models.User, error) {
+	// Deleted users keep their username and email until they are purged
+	users := s.userRepo.WithDeleted()
+
 	// Check if username exists
-	existingUser, _ := s.userRepo.FindByUsername(req.Username)
+	existingUser, _ := users.FindByUsername(ctx, req.Username)
 	if existingUser != nil {
 		return nil, apperror.Conflict("username already exists")
 	}
 
 	// Check if email exists
-	existingUser, _ = s.userRepo.FindByEmail(req.Email)
+	existingUser, _ = users.FindByEmail(ctx, req.Email)
 	if existingUser != nil {
 		return nil, apperror.Conflict("email already exists")
 	}
@@ -72,19 +76,19 @@ func (s *authService) Register(req dto.RegisterRequest) (*models.User, error) {
 		user.Role = models.RoleMember
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, apperror.Internal("failed to create user", err)
 	}
 
 	return user, nil
 }
 
-func (s *authService) Login(req dto.LoginRequest) (*dto.LoginResponse, error) {
+func (s *authService) Login(ctx context.Context, req dto.LoginRequest) (*dto.LoginResponse, error) {
 	// Find user by username or email
-	user, err := s.userRepo.FindByUsername(req.Username)
+	user, err := s.userRepo.FindByUsername(ctx, req.Username)
 	if err != nil {
 		// Try email
-		user, err = s.userRepo.FindByEmail(req.Username)
+		user, err = s.userRepo.FindByEmail(ctx, req.Username)
 		if err != nil {
 			return nil, apperror.Unauthorized("invalid credentials")
 		}
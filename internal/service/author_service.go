@@ -0,0 +1,513 @@
+// internal/service/author_service.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/names"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"gorm.io/gorm"
+)
+
+// authorStatementSeparator separates names in a book's author statement.
+const authorStatementSeparator = "; "
+
+const authorLinkBatchSize = 500
+
+var errAuthorLinkDryRun = errors.New("author link dry run")
+
+type AuthorService interface {
+	CreateAuthor(ctx context.Context, req dto.CreateAuthorRequest) (*models.Author, error)
+	GetAuthor(ctx context.Context, id uint) (*models.Author, error)
+	// UpdateAuthor changes an author still at version; 0 skips the check,
+	// as it does for DeleteAuthor.
+	UpdateAuthor(ctx context.Context, id, version uint, req dto.UpdateAuthorRequest) (*models.Author, error)
+	// DeleteAuthor removes an author that is not credited on any book.
+	DeleteAuthor(ctx context.Context, id, version uint) error
+	ListAuthors(ctx context.Context, search string, req query.PageRequest) ([]models.Author, query.PageInfo, error)
+	// ListAuthorWorks lists the books an author is credited on, optionally
+	// only in one role.
+	ListAuthorWorks(ctx context.Context, id uint, role string, req query.PageRequest) ([]dto.AuthorWork, query.PageInfo, error)
+	AddVariant(ctx context.Context, id uint, name string) (*models.AuthorVariant, error)
+	RemoveVariant(ctx context.Context, id, variantID uint) error
+	// MergeAuthors folds duplicates into targetID, still at version: their
+	// credits and variants move to the target, their names become variants
+	// and they are deleted.
+	MergeAuthors(ctx context.Context, targetID, version uint, sourceIDs []uint) (*models.Author, *dto.AuthorMergeResult, error)
+	// LinkBookAuthors creates contributor links for books that have none from
+	// their author statement. Nothing is written unless apply is true.
+	LinkBookAuthors(ctx context.Context, apply bool) (*dto.AuthorLinkResult, error)
+}
+
+type authorService struct {
+	db         *gorm.DB
+	authorRepo repository.AuthorRepository
+}
+
+func NewAuthorService(db *gorm.DB, authorRepo repository.AuthorRepository) AuthorService {
+	return &authorService{db: db, authorRepo: authorRepo}
+}
+
+func (s *authorService) CreateAuthor(ctx context.Context, req dto.CreateAuthorRequest) (*models.Author, error) {
+	author, err := newAuthor(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if req.SortName != "" {
+		author.SortName = strings.TrimSpace(req.SortName)
+	}
+	author.Bio = req.Bio
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		authorRepo := s.authorRepo.WithTx(tx)
+		if err := authorRepo.Create(ctx, author); err != nil {
+			return apperror.Internal("failed to create author", err)
+		}
+		for _, name := range req.Variants {
+			if _, err := addVariant(ctx, authorRepo, author, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetAuthor(ctx, author.ID)
+}
+
+func (s *authorService) GetAuthor(ctx context.Context, id uint) (*models.Author, error) {
+	author, err := s.authorRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "author")
+	}
+	return author, nil
+}
+
+func (s *authorService) UpdateAuthor(ctx context.Context, id, version uint, req dto.UpdateAuthorRequest) (*models.Author, error) {
+	author, err := s.authorRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "author")
+	}
+	if err := checkVersion("author", author.Version, version); err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		renamed, err := newAuthor(req.Name)
+		if err != nil {
+			return nil, err
+		}
+		author.Name, author.NameKey = renamed.Name, renamed.NameKey
+		if req.SortName == "" {
+			author.SortName = renamed.SortName
+		}
+	}
+	if req.SortName != "" {
+		author.SortName = strings.TrimSpace(req.SortName)
+	}
+	if req.Bio != "" {
+		author.Bio = req.Bio
+	}
+
+	if err := s.authorRepo.Update(ctx, author); err != nil {
+		return nil, updateError(err, "author", "failed to update author")
+	}
+	return author, nil
+}
+
+func (s *authorService) DeleteAuthor(ctx context.Context, id, version uint) error {
+	author, err := s.authorRepo.FindByID(ctx, id)
+	if err != nil {
+		return lookupError(err, "author")
+	}
+	if err := checkVersion("author", author.Version, version); err != nil {
+		return err
+	}
+	if author.BookCount > 0 {
+		return apperror.Conflict("author is credited on books; merge it into another author instead")
+	}
+
+	if err := s.authorRepo.Delete(ctx, id); err != nil {
+		return apperror.Internal("failed to delete author", err)
+	}
+	return nil
+}
+
+func (s *authorService) ListAuthors(ctx context.Context, search string, req query.PageRequest) ([]models.Author, query.PageInfo, error) {
+	authors, info, err := s.authorRepo.List(ctx, search, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list authors")
+	}
+	return authors, info, nil
+}
+
+func (s *authorService) ListAuthorWorks(ctx context.Context, id uint, role string, req query.PageRequest) ([]dto.AuthorWork, query.PageInfo, error) {
+	if role != "" && !slices.Contains(models.ContributorRoles, role) {
+		return nil, query.PageInfo{}, apperror.BadRequest(fmt.Sprintf("role must be one of %s", strings.Join(models.ContributorRoles, ", ")))
+	}
+	if _, err := s.authorRepo.FindByID(ctx, id); err != nil {
+		return nil, query.PageInfo{}, lookupError(err, "author")
+	}
+
+	books, info, err := s.authorRepo.ListBooks(ctx, id, role, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list author works")
+	}
+
+	ids := make([]uint, len(books))
+	for i := range books {
+		ids[i] = books[i].ID
+	}
+	credits, err := s.authorRepo.ListContributions(ctx, id, ids)
+	if err != nil {
+		return nil, info, apperror.Internal("failed to list author works", err)
+	}
+	roles := make(map[uint][]string, len(books))
+	for _, credit := range credits {
+		roles[credit.BookID] = append(roles[credit.BookID], credit.Role)
+	}
+
+	works := make([]dto.AuthorWork, len(books))
+	for i, book := range books {
+		works[i] = dto.AuthorWork{
+			BookID:          book.ID,
+			ISBN:            book.ISBN,
+			Title:           book.Title,
+			Author:          book.Author,
+			PublicationYear: book.PublicationYear,
+			Roles:           roles[book.ID],
+		}
+	}
+	return works, info, nil
+}
+
+func (s *authorService) AddVariant(ctx context.Context, id uint, name string) (*models.AuthorVariant, error) {
+	var variant *models.AuthorVariant
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		authorRepo := s.authorRepo.WithTx(tx)
+		author, err := authorRepo.FindByID(ctx, id)
+		if err != nil {
+			return lookupError(err, "author")
+		}
+		variant, err = addVariant(ctx, authorRepo, author, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+func (s *authorService) RemoveVariant(ctx context.Context, id, variantID uint) error {
+	removed, err := s.authorRepo.DeleteVariant(ctx, id, variantID)
+	if err != nil {
+		return apperror.Internal("failed to remove author variant", err)
+	}
+	if removed == 0 {
+		return apperror.NotFound("author variant")
+	}
+	return nil
+}
+
+func (s *authorService) MergeAuthors(ctx context.Context, targetID, version uint, sourceIDs []uint) (*models.Author, *dto.AuthorMergeResult, error) {
+	result := &dto.AuthorMergeResult{MergedAuthorIDs: []uint{}}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		authorRepo := s.authorRepo.WithTx(tx)
+		target, err := authorRepo.FindByID(ctx, targetID)
+		if err != nil {
+			return lookupError(err, "author")
+		}
+		if err := checkVersion("author", target.Version, version); err != nil {
+			return err
+		}
+
+		for _, sourceID := range sourceIDs {
+			if sourceID == targetID {
+				return apperror.BadRequest("cannot merge an author into itself")
+			}
+			if slices.Contains(result.MergedAuthorIDs, sourceID) {
+				continue
+			}
+			source, err := authorRepo.FindByID(ctx, sourceID)
+			if err != nil {
+				return lookupError(err, fmt.Sprintf("author %d", sourceID))
+			}
+
+			moved, err := authorRepo.MoveContributions(ctx, source.ID, target.ID)
+			if err != nil {
+				return apperror.Internal("failed to move author credits", err)
+			}
+			if err := authorRepo.MoveVariants(ctx, source.ID, target.ID); err != nil {
+				return apperror.Internal("failed to move author variants", err)
+			}
+			if err := authorRepo.Delete(ctx, source.ID); err != nil {
+				return apperror.Internal("failed to delete merged author", err)
+			}
+
+			// The duplicate's name stays searchable as a variant of the target.
+			if source.NameKey != target.NameKey {
+				if _, err := authorRepo.FindVariantByNameKey(ctx, source.NameKey); errors.Is(err, gorm.ErrRecordNotFound) {
+					variant := &models.AuthorVariant{AuthorID: target.ID, Name: source.Name, NameKey: source.NameKey}
+					if err := authorRepo.CreateVariant(ctx, variant); err != nil {
+						return apperror.Internal("failed to add author variant", err)
+					}
+					result.VariantsAdded++
+				} else if err != nil {
+					return apperror.Internal("failed to check author variants", err)
+				}
+			}
+
+			result.MergedAuthorIDs = append(result.MergedAuthorIDs, source.ID)
+			result.ContributionsMoved += moved
+		}
+
+		// The target gained credits and variants: a new version.
+		if len(result.MergedAuthorIDs) > 0 {
+			if err := authorRepo.Update(ctx, target); err != nil {
+				return updateError(err, "author", "failed to update author")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	author, err := s.GetAuthor(ctx, targetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return author, result, nil
+}
+
+func (s *authorService) LinkBookAuthors(ctx context.Context, apply bool) (*dto.AuthorLinkResult, error) {
+	result := &dto.AuthorLinkResult{Applied: apply}
+
+	var afterID uint
+	for {
+		var done bool
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			authorRepo := s.authorRepo.WithTx(tx)
+			books, err := authorRepo.BooksWithoutContributors(ctx, afterID, authorLinkBatchSize)
+			if err != nil {
+				return apperror.Internal("failed to list books without contributors", err)
+			}
+			done = len(books) < authorLinkBatchSize
+
+			for i := range books {
+				afterID = books[i].ID
+				contributors, created, err := resolveContributors(ctx, authorRepo, nil, books[i].Author)
+				if err != nil {
+					return err
+				}
+				if len(contributors) == 0 {
+					continue
+				}
+				if err := authorRepo.ReplaceContributors(ctx, books[i].ID, contributors); err != nil {
+					return apperror.Internal("failed to link book authors", err)
+				}
+				result.BooksLinked++
+				result.AuthorsCreated += created
+			}
+
+			if !apply {
+				return errAuthorLinkDryRun
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errAuthorLinkDryRun) {
+			return result, err
+		}
+		if done {
+			return result, nil
+		}
+	}
+}
+
+// newAuthor builds an author from a display name, deriving its key and sort
+// name.
+func newAuthor(name string) (*models.Author, error) {
+	name = strings.TrimSpace(name)
+	key := names.Key(name)
+	if key == "" {
+		return nil, apperror.BadRequest(fmt.Sprintf("invalid author name %q", name))
+	}
+	return &models.Author{Name: name, SortName: names.Inverted(name), NameKey: key}, nil
+}
+
+func addVariant(ctx context.Context, authorRepo repository.AuthorRepository, author *models.Author, name string) (*models.AuthorVariant, error) {
+	name = strings.TrimSpace(name)
+	key := names.Key(name)
+	if key == "" {
+		return nil, apperror.BadRequest(fmt.Sprintf("invalid author name %q", name))
+	}
+	if key == author.NameKey {
+		return nil, apperror.BadRequest(fmt.Sprintf("%q is already a form of the author's name", name))
+	}
+
+	existing, err := authorRepo.FindVariantByNameKey(ctx, key)
+	if err == nil {
+		if existing.AuthorID == author.ID {
+			return nil, apperror.Conflict(fmt.Sprintf("%q is already a variant of this author", name))
+		}
+		return nil, apperror.Conflict(fmt.Sprintf("%q is already a variant of author %d", name, existing.AuthorID))
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.Internal("failed to check author variants", err)
+	}
+
+	variant := &models.AuthorVariant{AuthorID: author.ID, Name: name, NameKey: key}
+	if err := authorRepo.CreateVariant(ctx, variant); err != nil {
+		return nil, apperror.Internal("failed to add author variant", err)
+	}
+	return variant, nil
+}
+
+// resolveContributors turns contributor requests into links to existing
+// authors, creating authors for names that match nothing. Without requests,
+// every ";"-separated name in statement is credited as an author. It returns
+// the links in credit order and the number of authors created.
+func resolveContributors(ctx context.Context, authorRepo repository.AuthorRepository, reqs []dto.ContributorRequest, statement string) ([]models.BookContributor, int, error) {
+	if len(reqs) == 0 {
+		for _, name := range strings.Split(statement, ";") {
+			if name = strings.TrimSpace(name); name != "" {
+				reqs = append(reqs, dto.ContributorRequest{Name: name, Role: models.RoleAuthor})
+			}
+		}
+	}
+
+	var contributors []models.BookContributor
+	created := 0
+	for _, req := range reqs {
+		role := req.Role
+		if role == "" {
+			role = models.RoleAuthor
+		}
+		if !slices.Contains(models.ContributorRoles, role) {
+			return nil, 0, apperror.BadRequest(fmt.Sprintf("invalid contributor role %q", role))
+		}
+
+		var author *models.Author
+		var err error
+		if req.AuthorID != 0 {
+			author, err = authorRepo.FindByID(ctx, req.AuthorID)
+			if err != nil {
+				return nil, 0, lookupError(err, fmt.Sprintf("author %d", req.AuthorID))
+			}
+		} else {
+			var isNew bool
+			author, isNew, err = findOrCreateAuthor(ctx, authorRepo, req.Name)
+			if err != nil {
+				return nil, 0, err
+			}
+			if isNew {
+				created++
+			}
+		}
+
+		// The same person may be credited in several roles, but only once per role.
+		if slices.ContainsFunc(contributors, func(c models.BookContributor) bool {
+			return c.AuthorID == author.ID && c.Role == role
+		}) {
+			continue
+		}
+		contributors = append(contributors, models.BookContributor{
+			AuthorID: author.ID,
+			Role:     role,
+			Position: len(contributors),
+			Author:   author,
+		})
+	}
+	return contributors, created, nil
+}
+
+// relinkContributors applies a contributor change to a saved book. A new
+// contributor list replaces the old one; a new author statement alone
+// replaces only the author credits, keeping editors, translators and
+// illustrators.
+func relinkContributors(ctx context.Context, authorRepo repository.AuthorRepository, book *models.Book, reqs []dto.ContributorRequest, statement string) error {
+	var contributors []models.BookContributor
+	if len(reqs) > 0 {
+		resolved, _, err := resolveContributors(ctx, authorRepo, reqs, "")
+		if err != nil {
+			return err
+		}
+		contributors = resolved
+		if statement == "" {
+			book.Author = authorStatement(contributors)
+		}
+	} else {
+		authors, _, err := resolveContributors(ctx, authorRepo, nil, statement)
+		if err != nil {
+			return err
+		}
+		existing, err := authorRepo.ListContributors(ctx, book.ID)
+		if err != nil {
+			return apperror.Internal("failed to load book contributors", err)
+		}
+		contributors = authors
+		for _, c := range existing {
+			if c.Role != models.RoleAuthor {
+				c.Position = len(contributors)
+				contributors = append(contributors, c)
+			}
+		}
+	}
+
+	if err := authorRepo.ReplaceContributors(ctx, book.ID, contributors); err != nil {
+		return apperror.Internal("failed to link book contributors", err)
+	}
+	book.Contributors = contributors
+	return nil
+}
+
+func findOrCreateAuthor(ctx context.Context, authorRepo repository.AuthorRepository, name string) (*models.Author, bool, error) {
+	author, err := newAuthor(name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing, err := authorRepo.FindByNameKey(ctx, author.NameKey)
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, apperror.Internal("failed to look up author", err)
+	}
+
+	if err := authorRepo.Create(ctx, author); err != nil {
+		return nil, false, apperror.Internal("failed to create author", err)
+	}
+	return author, true, nil
+}
+
+// authorStatement joins the names of the contributors credited as authors,
+// or of all contributors when nobody is, e.g. for an edited volume.
+func authorStatement(contributors []models.BookContributor) string {
+	var authors, others []string
+	for _, c := range contributors {
+		if c.Author == nil {
+			continue
+		}
+		if c.Role == models.RoleAuthor {
+			authors = append(authors, c.Author.Name)
+		} else {
+			others = append(others, c.Author.Name)
+		}
+	}
+	if len(authors) == 0 {
+		authors = others
+	}
+	return strings.Join(authors, authorStatementSeparator)
+}
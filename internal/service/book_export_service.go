@@ -0,0 +1,369 @@
+// internal/service/book_export_service.go
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/database"
+	"github.com/alpardfm/library-management-api/pkg/textsearch"
+	"github.com/alpardfm/library-management-api/pkg/xlsx"
+	"gorm.io/gorm"
+)
+
+// Export formats accepted by BookExportService.
+const (
+	ExportFormatCSV   = "csv"
+	ExportFormatJSONL = "jsonl"
+	ExportFormatXLSX  = "xlsx"
+)
+
+const (
+	DefaultExportBatchSize = 500
+	MaxExportBatchSize     = 5000
+)
+
+// bookExportColumn is a column that can be selected for an export. snapshotAt
+// is the time the export started; availability columns reflect the stock as
+// read at that moment.
+type bookExportColumn struct {
+	name  string
+	value func(book *models.Book, snapshotAt time.Time) any
+}
+
+var bookExportColumns = []bookExportColumn{
+	{"id", func(b *models.Book, _ time.Time) any { return b.ID }},
+	{"isbn", func(b *models.Book, _ time.Time) any { return b.ISBN }},
+	{"isbn_10", func(b *models.Book, _ time.Time) any { return b.ISBN10 }},
+	{"isbn_formatted", func(b *models.Book, _ time.Time) any { return b.ISBNFormatted }},
+	{"title", func(b *models.Book, _ time.Time) any { return b.Title }},
+	{"author", func(b *models.Book, _ time.Time) any { return b.Author }},
+	{"publisher", func(b *models.Book, _ time.Time) any { return b.Publisher }},
+	{"publication_year", func(b *models.Book, _ time.Time) any { return nonZero(b.PublicationYear) }},
+	{"genre", func(b *models.Book, _ time.Time) any { return b.Genre }},
+	{"language", func(b *models.Book, _ time.Time) any { return b.Language }},
+	{"item_type", func(b *models.Book, _ time.Time) any { return b.ItemType }},
+	{"description", func(b *models.Book, _ time.Time) any { return b.Description }},
+	{"classification", func(b *models.Book, _ time.Time) any { return b.Classification }},
+	{"call_number", func(b *models.Book, _ time.Time) any { return b.CallNumber }},
+	{"shelf_location", func(b *models.Book, _ time.Time) any { return b.ShelfLocation }},
+	{"price", func(b *models.Book, _ time.Time) any { return b.Price }},
+	{"total_copies", func(b *models.Book, _ time.Time) any { return b.TotalCopies }},
+	{"available_copies", func(b *models.Book, _ time.Time) any { return b.AvailableCopies }},
+	{"borrowed_copies", func(b *models.Book, _ time.Time) any { return b.TotalCopies - b.AvailableCopies }},
+	{"is_available", func(b *models.Book, _ time.Time) any { return b.CanBorrow() }},
+	{"snapshot_at", func(_ *models.Book, at time.Time) any { return at }},
+	{"created_at", func(b *models.Book, _ time.Time) any { return b.CreatedAt.UTC() }},
+	{"updated_at", func(b *models.Book, _ time.Time) any { return b.UpdatedAt.UTC() }},
+}
+
+// DefaultBookExportColumns are exported when no columns are selected. The
+// catalog columns match the import format, so an export can be re-imported.
+var DefaultBookExportColumns = []string{
+	"id", "isbn", "title", "author", "publisher", "publication_year", "genre", "language", "item_type", "description",
+	"classification", "call_number", "shelf_location", "price", "total_copies", "available_copies", "borrowed_copies", "is_available", "snapshot_at",
+}
+
+// BookExportColumnNames lists every selectable column in display order.
+func BookExportColumnNames() []string {
+	names := make([]string, len(bookExportColumns))
+	for i, column := range bookExportColumns {
+		names[i] = column.name
+	}
+	return names
+}
+
+type BookExportOptions struct {
+	Format  string
+	Columns []string
+	// Filter selects the books as on ListBooks. Exports list every edition,
+	// so CollapseWorks is ignored.
+	Filter    dto.BookListFilter
+	Sort      string
+	BatchSize int
+}
+
+// Normalize validates opts and fills in defaults. ExportBooks calls it too;
+// callers use it to reject bad options before they start writing a response.
+func (o BookExportOptions) Normalize() (BookExportOptions, error) {
+	o.Format = strings.ToLower(strings.TrimSpace(o.Format))
+	switch o.Format {
+	case "":
+		o.Format = ExportFormatCSV
+	case "ndjson":
+		o.Format = ExportFormatJSONL
+	case ExportFormatCSV, ExportFormatJSONL, ExportFormatXLSX:
+	default:
+		return o, apperror.BadRequest(fmt.Sprintf("unsupported export format %q (use csv, jsonl or xlsx)", o.Format))
+	}
+
+	if len(o.Columns) == 0 {
+		o.Columns = DefaultBookExportColumns
+	}
+	seen := make(map[string]bool, len(o.Columns))
+	columns := make([]string, 0, len(o.Columns))
+	for _, name := range o.Columns {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := findBookExportColumn(name); !ok {
+			return o, apperror.BadRequest(fmt.Sprintf("unknown export column %q (available: %s)", name, strings.Join(BookExportColumnNames(), ", ")))
+		}
+		seen[name] = true
+		columns = append(columns, name)
+	}
+	if len(columns) == 0 {
+		return o, apperror.BadRequest("at least one export column is required")
+	}
+	o.Columns = columns
+
+	switch o.Filter.SearchMode {
+	case "", SearchModeSubstring, SearchModeFullText:
+	default:
+		return o, apperror.BadRequest("search_mode must be substring or fulltext")
+	}
+
+	switch o.Sort {
+	case "", "created_at_desc", "created_at_asc", "title_asc", "title_desc", "call_number_asc", "call_number_desc":
+	default:
+		return o, apperror.BadRequest(fmt.Sprintf("unsupported sort %q", o.Sort))
+	}
+
+	switch {
+	case o.BatchSize == 0:
+		o.BatchSize = DefaultExportBatchSize
+	case o.BatchSize < 0 || o.BatchSize > MaxExportBatchSize:
+		return o, apperror.BadRequest(fmt.Sprintf("batch size must be between 1 and %d", MaxExportBatchSize))
+	}
+	return o, nil
+}
+
+func findBookExportColumn(name string) (bookExportColumn, bool) {
+	for _, column := range bookExportColumns {
+		if column.name == name {
+			return column, true
+		}
+	}
+	return bookExportColumn{}, false
+}
+
+type BookExportService interface {
+	// ExportBooks streams the books matching opts to w and returns the number
+	// of rows written. Rows are read in batches, so memory use does not depend
+	// on the size of the catalog. When w has a Flush method it is called after
+	// every batch.
+	ExportBooks(ctx context.Context, w io.Writer, opts BookExportOptions) (int, error)
+}
+
+type bookExportService struct {
+	db       *gorm.DB
+	bookRepo repository.BookRepository
+}
+
+func NewBookExportService(db *gorm.DB, bookRepo repository.BookRepository) BookExportService {
+	return &bookExportService{db: db, bookRepo: bookRepo}
+}
+
+func (s *bookExportService) ExportBooks(ctx context.Context, w io.Writer, opts BookExportOptions) (int, error) {
+	opts, err := opts.Normalize()
+	if err != nil {
+		return 0, err
+	}
+
+	columns := make([]bookExportColumn, len(opts.Columns))
+	for i, name := range opts.Columns {
+		columns[i], _ = findBookExportColumn(name)
+	}
+
+	out, err := newExportWriter(w, opts.Format)
+	if err != nil {
+		return 0, apperror.Internal("failed to start export", err)
+	}
+	if err := out.header(opts.Columns); err != nil {
+		return 0, err
+	}
+
+	snapshotAt := time.Now().UTC().Truncate(time.Second)
+	written := 0
+	values := make([]any, len(columns))
+	err = s.bookRepo.Scan(ctx, exportFilter(s.db, opts.Filter), opts.Sort, opts.BatchSize, func(books []models.Book) error {
+		for i := range books {
+			for j, column := range columns {
+				values[j] = column.value(&books[i], snapshotAt)
+			}
+			if err := out.row(opts.Columns, values); err != nil {
+				return err
+			}
+			written++
+		}
+
+		if err := out.flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+
+	if err := out.close(); err != nil {
+		return written, err
+	}
+	return written, nil
+}
+
+// exportFilter is the repository filter of an export. A full-text search
+// falls back to the substring search where SearchBooks would: on a dialect
+// without full-text search or for a query without terms.
+func exportFilter(db *gorm.DB, filter dto.BookListFilter) repository.BookFilter {
+	filter.CollapseWorks = false
+	if filter.SearchMode == SearchModeFullText && (!database.DialectOf(db).FullTextSearch() || textsearch.Parse(filter.Search).Empty()) {
+		filter.SearchMode = SearchModeSubstring
+	}
+	return bookFilter(filter)
+}
+
+// exportWriter encodes rows in one export format.
+type exportWriter interface {
+	header(columns []string) error
+	row(columns []string, values []any) error
+	flush() error
+	close() error
+}
+
+func newExportWriter(w io.Writer, format string) (exportWriter, error) {
+	switch format {
+	case ExportFormatJSONL:
+		return &jsonlExportWriter{w: w}, nil
+	case ExportFormatXLSX:
+		sheet, err := xlsx.NewWriter(w, "Books")
+		if err != nil {
+			return nil, err
+		}
+		return &xlsxExportWriter{sheet: sheet}, nil
+	default:
+		return &csvExportWriter{csv: csv.NewWriter(w)}, nil
+	}
+}
+
+type csvExportWriter struct {
+	csv    *csv.Writer
+	record []string
+}
+
+func (e *csvExportWriter) header(columns []string) error {
+	return e.csv.Write(columns)
+}
+
+func (e *csvExportWriter) row(_ []string, values []any) error {
+	e.record = e.record[:0]
+	for _, value := range values {
+		e.record = append(e.record, formatExportValue(value))
+	}
+	return e.csv.Write(e.record)
+}
+
+func (e *csvExportWriter) flush() error {
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+func (e *csvExportWriter) close() error {
+	return e.flush()
+}
+
+type jsonlExportWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (e *jsonlExportWriter) header([]string) error {
+	return nil
+}
+
+// row writes the columns in the selected order, which a map would not keep.
+func (e *jsonlExportWriter) row(columns []string, values []any) error {
+	e.buf = append(e.buf[:0], '{')
+	for i, value := range values {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		e.buf = strconv.AppendQuote(e.buf, columns[i])
+		e.buf = append(e.buf, ':')
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		e.buf = append(e.buf, encoded...)
+	}
+	e.buf = append(e.buf, '}', '\n')
+
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+func (e *jsonlExportWriter) flush() error {
+	return nil
+}
+
+func (e *jsonlExportWriter) close() error {
+	return nil
+}
+
+type xlsxExportWriter struct {
+	sheet *xlsx.Writer
+}
+
+func (e *xlsxExportWriter) header(columns []string) error {
+	return e.sheet.WriteHeader(columns)
+}
+
+func (e *xlsxExportWriter) row(_ []string, values []any) error {
+	return e.sheet.WriteRow(values)
+}
+
+func (e *xlsxExportWriter) flush() error {
+	return e.sheet.Flush()
+}
+
+func (e *xlsxExportWriter) close() error {
+	return e.sheet.Close()
+}
+
+func formatExportValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// nonZero keeps unknown numeric values empty instead of exporting 0.
+func nonZero(n int) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
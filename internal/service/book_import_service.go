@@ -0,0 +1,772 @@
+// internal/service/book_import_service.go
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/marc"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Import modes. In create mode rows whose ISBN is already catalogued are
+// skipped; upsert updates them instead.
+const (
+	ImportModeCreate = "create"
+	ImportModeUpsert = "upsert"
+)
+
+// Tabular import formats accepted by DecodeBookImport, next to FormatMARC and
+// FormatMARCXML.
+const (
+	ImportFormatCSV   = "csv"
+	ImportFormatJSONL = "jsonl"
+	ImportFormatJSON  = "json"
+)
+
+// importFormats maps accepted format names and aliases to their canonical name.
+var importFormats = map[string]string{
+	"csv":     ImportFormatCSV,
+	"jsonl":   ImportFormatJSONL,
+	"ndjson":  ImportFormatJSONL,
+	"json":    ImportFormatJSON,
+	"marc":    FormatMARC,
+	"mrc":     FormatMARC,
+	"marcxml": FormatMARCXML,
+	"xml":     FormatMARCXML,
+}
+
+// Row statuses reported by an import. A dry run reports what would happen.
+const (
+	ImportRowCreated   = "created"
+	ImportRowUpdated   = "updated"
+	ImportRowUnchanged = "unchanged"
+	ImportRowSkipped   = "skipped"
+	ImportRowFailed    = "failed"
+)
+
+const (
+	DefaultImportBatchSize = 500
+	MaxImportBatchSize     = 5000
+)
+
+// errDryRun rolls back a dry-run batch after every row has been applied.
+var errDryRun = errors.New("dry run")
+
+// BookImportRow is one decoded record of an import file. Err is set when the
+// record could not be decoded; the row is then reported as failed. Marc holds
+// the source record of MARC imports, which is stored with the book.
+type BookImportRow struct {
+	Line int
+	Book dto.CreateBookRequest
+	Marc *marc.Record
+	Err  error
+}
+
+type BookImportOptions struct {
+	Format    string
+	Mode      string
+	DryRun    bool
+	BatchSize int
+	FileName  string
+	// Actor is who the revisions of imported books are recorded as made by.
+	Actor dto.Actor
+}
+
+func (o BookImportOptions) normalize() (BookImportOptions, error) {
+	format, ok := importFormats[strings.ToLower(strings.TrimSpace(o.Format))]
+	if !ok {
+		return o, apperror.BadRequest(fmt.Sprintf("unsupported import format %q (use csv, jsonl, json, marc or marcxml)", o.Format))
+	}
+	o.Format = format
+
+	switch o.Mode {
+	case "":
+		o.Mode = ImportModeCreate
+	case ImportModeCreate, ImportModeUpsert:
+	default:
+		return o, apperror.BadRequest(fmt.Sprintf("unsupported import mode %q (use create or upsert)", o.Mode))
+	}
+
+	switch {
+	case o.BatchSize == 0:
+		o.BatchSize = DefaultImportBatchSize
+	case o.BatchSize < 0 || o.BatchSize > MaxImportBatchSize:
+		return o, apperror.BadRequest(fmt.Sprintf("batch size must be between 1 and %d", MaxImportBatchSize))
+	}
+	return o, nil
+}
+
+type BookImportService interface {
+	// ImportBooks imports rows synchronously and reports every row.
+	ImportBooks(ctx context.Context, rows []BookImportRow, opts BookImportOptions) (*dto.BookImportResult, error)
+	// StartImport decodes r and imports it in the background. Progress is
+	// available through GetImportJob.
+	StartImport(ctx context.Context, actor dto.Actor, r io.Reader, opts BookImportOptions) (*models.ImportJob, error)
+	GetImportJob(ctx context.Context, id uint) (*models.ImportJob, error)
+	ListImportIssues(ctx context.Context, jobID uint, req query.PageRequest) ([]models.ImportJobIssue, query.PageInfo, error)
+	// FailInterruptedImports marks jobs left unfinished by a previous process as failed.
+	FailInterruptedImports(ctx context.Context) (int64, error)
+	// Shutdown cancels running jobs and waits for them to record their state.
+	Shutdown(ctx context.Context) error
+}
+
+type bookImportService struct {
+	db            *gorm.DB
+	bookRepo      repository.BookRepository
+	importJobRepo repository.ImportJobRepository
+	marcRepo      repository.MarcRecordRepository
+	authorRepo    repository.AuthorRepository
+	subjectRepo   repository.SubjectRepository
+	revisionRepo  repository.BookRevisionRepository
+	copyRepo      repository.CopyRepository
+
+	baseCtx context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewBookImportService(
+	db *gorm.DB,
+	bookRepo repository.BookRepository,
+	importJobRepo repository.ImportJobRepository,
+	marcRepo repository.MarcRecordRepository,
+	authorRepo repository.AuthorRepository,
+	subjectRepo repository.SubjectRepository,
+	revisionRepo repository.BookRevisionRepository,
+	copyRepo repository.CopyRepository,
+) BookImportService {
+	baseCtx, cancel := context.WithCancel(context.Background())
+	return &bookImportService{
+		db:            db,
+		bookRepo:      bookRepo,
+		importJobRepo: importJobRepo,
+		marcRepo:      marcRepo,
+		authorRepo:    authorRepo,
+		subjectRepo:   subjectRepo,
+		revisionRepo:  revisionRepo,
+		copyRepo:      copyRepo,
+		baseCtx:       baseCtx,
+		cancel:        cancel,
+	}
+}
+
+func (s *bookImportService) ImportBooks(ctx context.Context, rows []BookImportRow, opts BookImportOptions) (*dto.BookImportResult, error) {
+	opts, err := opts.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.BookImportResult{DryRun: opts.DryRun, Rows: make([]dto.BookImportRowResult, 0, len(rows))}
+	err = s.process(ctx, rows, opts, func(batch []dto.BookImportRowResult) error {
+		result.Rows = append(result.Rows, batch...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var counts importCounts
+	for _, row := range result.Rows {
+		counts.add(row.Status)
+	}
+	result.Total = counts.total
+	result.Created = counts.created
+	result.Updated = counts.updated
+	result.Unchanged = counts.unchanged
+	result.Skipped = counts.skipped
+	result.Failed = counts.failed
+	return result, nil
+}
+
+func (s *bookImportService) StartImport(ctx context.Context, actor dto.Actor, r io.Reader, opts BookImportOptions) (*models.ImportJob, error) {
+	opts, err := opts.normalize()
+	if err != nil {
+		return nil, err
+	}
+	opts.Actor = actor
+
+	rows, err := DecodeBookImport(r, opts.Format)
+	if err != nil {
+		return nil, apperror.BadRequest(err.Error())
+	}
+	if len(rows) == 0 {
+		return nil, apperror.BadRequest("import file contains no rows")
+	}
+
+	job := &models.ImportJob{
+		Status:    models.ImportJobPending,
+		Format:    opts.Format,
+		Mode:      opts.Mode,
+		DryRun:    opts.DryRun,
+		FileName:  opts.FileName,
+		CreatedBy: actor.UserID,
+		TotalRows: len(rows),
+	}
+	if err := s.importJobRepo.Create(ctx, job); err != nil {
+		return nil, apperror.Internal("failed to create import job", err)
+	}
+
+	// The goroutine works on its own copy so callers can read job safely.
+	running := *job
+	s.wg.Add(1)
+	go s.run(&running, rows, opts)
+
+	return job, nil
+}
+
+func (s *bookImportService) run(job *models.ImportJob, rows []BookImportRow, opts BookImportOptions) {
+	defer s.wg.Done()
+
+	ctx := s.baseCtx
+	logger := log.With().Uint("import_job_id", job.ID).Logger()
+
+	startedAt := time.Now()
+	job.Status = models.ImportJobRunning
+	job.StartedAt = &startedAt
+	if err := s.importJobRepo.Update(ctx, job); err != nil {
+		logger.Error().Err(err).Msg("failed to start import job")
+	}
+
+	var counts importCounts
+	err := s.process(ctx, rows, opts, func(batch []dto.BookImportRowResult) error {
+		var issues []models.ImportJobIssue
+		for _, row := range batch {
+			counts.add(row.Status)
+			if row.Status == ImportRowSkipped || row.Status == ImportRowFailed {
+				issues = append(issues, models.ImportJobIssue{
+					JobID:   job.ID,
+					Line:    row.Line,
+					ISBN:    row.ISBN,
+					Status:  row.Status,
+					Message: row.Error,
+				})
+			}
+		}
+		if err := s.importJobRepo.CreateIssues(ctx, issues); err != nil {
+			return fmt.Errorf("record import issues: %w", err)
+		}
+
+		job.ProcessedRows = counts.total
+		job.Created = counts.created
+		job.Updated = counts.updated
+		job.Unchanged = counts.unchanged
+		job.Skipped = counts.skipped
+		job.Failed = counts.failed
+		if err := s.importJobRepo.Update(ctx, job); err != nil {
+			return fmt.Errorf("record import progress: %w", err)
+		}
+		return nil
+	})
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.Status = models.ImportJobCompleted
+	if err != nil {
+		job.Status = models.ImportJobFailed
+		job.Error = err.Error()
+		if errors.Is(err, context.Canceled) {
+			job.Error = "import interrupted by shutdown"
+		}
+	}
+
+	// The base context may already be cancelled; the final state must still be saved.
+	saveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.importJobRepo.Update(saveCtx, job); err != nil {
+		logger.Error().Err(err).Msg("failed to record import job result")
+		return
+	}
+
+	logger.Info().
+		Str("status", string(job.Status)).
+		Int("created", job.Created).
+		Int("updated", job.Updated).
+		Int("failed", job.Failed).
+		Msg("import job finished")
+}
+
+func (s *bookImportService) GetImportJob(ctx context.Context, id uint) (*models.ImportJob, error) {
+	job, err := s.importJobRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "import job")
+	}
+	return job, nil
+}
+
+func (s *bookImportService) ListImportIssues(ctx context.Context, jobID uint, req query.PageRequest) ([]models.ImportJobIssue, query.PageInfo, error) {
+	if _, err := s.GetImportJob(ctx, jobID); err != nil {
+		return nil, query.PageInfo{}, err
+	}
+
+	issues, info, err := s.importJobRepo.ListIssues(ctx, jobID, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list import issues")
+	}
+	return issues, info, nil
+}
+
+func (s *bookImportService) FailInterruptedImports(ctx context.Context) (int64, error) {
+	return s.importJobRepo.FailUnfinished(ctx, "import interrupted by restart", time.Now())
+}
+
+func (s *bookImportService) Shutdown(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// process imports rows in batches of opts.BatchSize, calling report after each
+// batch has been committed (or rolled back for a dry run).
+func (s *bookImportService) process(ctx context.Context, rows []BookImportRow, opts BookImportOptions, report func([]dto.BookImportRowResult) error) error {
+	seen := make(map[string]int, len(rows))
+
+	for start := 0; start < len(rows); start += opts.BatchSize {
+		end := start + opts.BatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		results, err := s.importBatch(ctx, rows[start:end], opts, seen)
+		if err != nil {
+			return err
+		}
+		if err := report(results); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *bookImportService) importBatch(ctx context.Context, rows []BookImportRow, opts BookImportOptions, seen map[string]int) ([]dto.BookImportRowResult, error) {
+	var results []dto.BookImportRowResult
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		results = make([]dto.BookImportRowResult, 0, len(rows))
+		for _, row := range rows {
+			result, err := s.importRow(ctx, tx, row, opts, seen)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, apperror.Internal("failed to import books", err)
+	}
+	return results, nil
+}
+
+// importRow applies one row inside its own savepoint so a failing row does not
+// abort the rest of the batch. Only database errors other than the row's own
+// write are returned.
+func (s *bookImportService) importRow(ctx context.Context, tx *gorm.DB, row BookImportRow, opts BookImportOptions, seen map[string]int) (dto.BookImportRowResult, error) {
+	result := dto.BookImportRowResult{Line: row.Line, ISBN: row.Book.ISBN}
+	fail := func(err error) (dto.BookImportRowResult, error) {
+		result.Status = ImportRowFailed
+		result.Error = importErrorMessage(err)
+		return result, nil
+	}
+
+	if row.Err != nil {
+		return fail(row.Err)
+	}
+	if err := binding.Validator.ValidateStruct(&row.Book); err != nil {
+		return fail(err)
+	}
+	normalizedISBN, err := normalizeISBN(row.Book.ISBN)
+	if err != nil {
+		return fail(err)
+	}
+	result.ISBN = normalizedISBN
+
+	if line, ok := seen[normalizedISBN]; ok {
+		return fail(fmt.Errorf("duplicate of line %d", line))
+	}
+	seen[normalizedISBN] = row.Line
+
+	existing, err := s.bookRepo.WithTx(tx).FindByISBN(ctx, normalizedISBN)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return result, err
+	}
+
+	if existing == nil {
+		book := newBook(row.Book, normalizedISBN)
+		if err := validateNewBookStock(book); err != nil {
+			return fail(err)
+		}
+		if err := setCallNumber(book, row.Book); err != nil {
+			return fail(err)
+		}
+		if err := tx.Transaction(func(rowTx *gorm.DB) error {
+			authorRepo := s.authorRepo.WithTx(rowTx)
+			contributors, _, err := resolveContributors(ctx, authorRepo, row.Book.Contributors, row.Book.Author)
+			if err != nil {
+				return err
+			}
+			if book.Author == "" {
+				book.Author = authorStatement(contributors)
+			}
+			subjectRepo := s.subjectRepo.WithTx(rowTx)
+			subjects, err := resolveBookSubjects(ctx, subjectRepo, row.Book.SubjectIDs, row.Book.Genre)
+			if err != nil {
+				return err
+			}
+			if err := s.bookRepo.WithTx(rowTx).Create(ctx, book); err != nil {
+				return err
+			}
+			if err := authorRepo.ReplaceContributors(ctx, book.ID, contributors); err != nil {
+				return err
+			}
+			book.Contributors = contributors
+			if len(subjects) > 0 {
+				if err := setBookSubjects(ctx, subjectRepo, book, subjects); err != nil {
+					return err
+				}
+			}
+			changes := bookChanges(bookDocument(&models.Book{}), bookDocument(book))
+			if err := recordRevision(ctx, s.revisionRepo.WithTx(rowTx), book, models.BookRevision{Action: models.RevisionCreate}, changes, opts.Actor); err != nil {
+				return err
+			}
+			return s.saveMarcRecord(ctx, rowTx, book.ID, row.Marc)
+		}); err != nil {
+			return fail(err)
+		}
+		result.Status = ImportRowCreated
+		result.BookID = book.ID
+		return result, nil
+	}
+
+	result.BookID = existing.ID
+	if existing.Removed() {
+		result.Status = ImportRowSkipped
+		result.Error = fmt.Sprintf("book with this ISBN was %s", existing.Status)
+		return result, nil
+	}
+	if opts.Mode == ImportModeCreate {
+		result.Status = ImportRowSkipped
+		result.Error = "book with this ISBN already exists"
+		return result, nil
+	}
+
+	before := *existing
+	relink := len(row.Book.Contributors) > 0 || (row.Book.Author != "" && row.Book.Author != existing.Author)
+	if err := applyBookUpdate(existing, dto.UpdateBookRequest{
+		Title:           row.Book.Title,
+		Author:          row.Book.Author,
+		Publisher:       row.Book.Publisher,
+		PublicationYear: row.Book.PublicationYear,
+		Genre:           row.Book.Genre,
+		Language:        row.Book.Language,
+		ItemType:        row.Book.ItemType,
+		Description:     row.Book.Description,
+		TotalCopies:     row.Book.TotalCopies,
+		Price:           row.Book.Price,
+	}); err != nil {
+		return fail(err)
+	}
+	// Like the other fields, a call number or shelf location left empty
+	// keeps the stored one.
+	shelving := dto.CreateBookRequest{Classification: existing.Classification, CallNumber: existing.CallNumber, ShelfLocation: existing.ShelfLocation}
+	if row.Book.CallNumber != "" || row.Book.Classification != "" {
+		shelving.Classification, shelving.CallNumber = row.Book.Classification, row.Book.CallNumber
+	}
+	if row.Book.ShelfLocation != "" {
+		shelving.ShelfLocation = row.Book.ShelfLocation
+	}
+	if err := setCallNumber(existing, shelving); err != nil {
+		return fail(err)
+	}
+	if row.Book.SubjectIDs == nil && sameCatalogFields(before, *existing) {
+		if err := tx.Transaction(func(rowTx *gorm.DB) error {
+			return s.saveMarcRecord(ctx, rowTx, existing.ID, row.Marc)
+		}); err != nil {
+			return fail(err)
+		}
+		result.Status = ImportRowUnchanged
+		return result, nil
+	}
+
+	if err := tx.Transaction(func(rowTx *gorm.DB) error {
+		// existing was read without its credits and subjects; the revision
+		// compares the record as stored before and after the row.
+		bookRepo := s.bookRepo.WithTx(rowTx)
+		stored, err := bookRepo.FindByID(ctx, existing.ID)
+		if err != nil {
+			return err
+		}
+		if relink {
+			if err := relinkContributors(ctx, s.authorRepo.WithTx(rowTx), existing, row.Book.Contributors, row.Book.Author); err != nil {
+				return err
+			}
+		}
+		if row.Book.SubjectIDs != nil {
+			subjectRepo := s.subjectRepo.WithTx(rowTx)
+			subjects, err := resolveBookSubjects(ctx, subjectRepo, row.Book.SubjectIDs, "")
+			if err != nil {
+				return err
+			}
+			if err := setBookSubjects(ctx, subjectRepo, existing, subjects); err != nil {
+				return err
+			}
+		}
+		if existing.TotalCopies != before.TotalCopies {
+			if err := checkRegisteredCopies(ctx, s.copyRepo.WithTx(rowTx), existing); err != nil {
+				return err
+			}
+		}
+		if err := bookRepo.Update(ctx, existing); err != nil {
+			return err
+		}
+		updated, err := bookRepo.FindByID(ctx, existing.ID)
+		if err != nil {
+			return err
+		}
+		changes := bookChanges(bookDocument(stored), bookDocument(updated))
+		if err := recordRevision(ctx, s.revisionRepo.WithTx(rowTx), updated, models.BookRevision{Action: models.RevisionUpdate}, changes, opts.Actor); err != nil {
+			return err
+		}
+		return s.saveMarcRecord(ctx, rowTx, existing.ID, row.Marc)
+	}); err != nil {
+		return fail(err)
+	}
+	result.Status = ImportRowUpdated
+	return result, nil
+}
+
+// saveMarcRecord keeps the source record of a MARC import row.
+func (s *bookImportService) saveMarcRecord(ctx context.Context, tx *gorm.DB, bookID uint, rec *marc.Record) error {
+	if rec == nil {
+		return nil
+	}
+	data, err := marc.MarshalXML(rec)
+	if err != nil {
+		return err
+	}
+	return s.marcRepo.WithTx(tx).Upsert(ctx, &models.MarcRecord{BookID: bookID, Record: string(data)})
+}
+
+func sameCatalogFields(a, b models.Book) bool {
+	return a.Title == b.Title &&
+		a.Author == b.Author &&
+		a.Publisher == b.Publisher &&
+		a.PublicationYear == b.PublicationYear &&
+		a.Genre == b.Genre &&
+		a.Language == b.Language &&
+		a.ItemType == b.ItemType &&
+		a.Description == b.Description &&
+		a.Classification == b.Classification &&
+		a.CallNumber == b.CallNumber &&
+		a.ShelfLocation == b.ShelfLocation &&
+		a.Price == b.Price &&
+		a.TotalCopies == b.TotalCopies &&
+		a.AvailableCopies == b.AvailableCopies
+}
+
+func importErrorMessage(err error) string {
+	var appErr *apperror.AppError
+	if errors.As(err, &appErr) {
+		if len(appErr.Fields) == 0 {
+			return appErr.Message
+		}
+		problems := make([]string, 0, len(appErr.Fields))
+		for field, problem := range appErr.Fields {
+			problems = append(problems, field+": "+problem)
+		}
+		slices.Sort(problems)
+		return appErr.Message + ": " + strings.Join(problems, "; ")
+	}
+	return err.Error()
+}
+
+type importCounts struct {
+	total, created, updated, unchanged, skipped, failed int
+}
+
+func (c *importCounts) add(status string) {
+	c.total++
+	switch status {
+	case ImportRowCreated:
+		c.created++
+	case ImportRowUpdated:
+		c.updated++
+	case ImportRowUnchanged:
+		c.unchanged++
+	case ImportRowSkipped:
+		c.skipped++
+	case ImportRowFailed:
+		c.failed++
+	}
+}
+
+// DecodeBookImport reads an import file. Records that cannot be decoded are
+// returned with Err set so they can be reported per row; only errors that make
+// the whole file unreadable are returned as an error.
+func DecodeBookImport(r io.Reader, format string) ([]BookImportRow, error) {
+	switch importFormats[strings.ToLower(format)] {
+	case ImportFormatJSON:
+		return decodeBookImportJSON(r)
+	case ImportFormatJSONL:
+		return decodeBookImportJSONL(r)
+	case ImportFormatCSV:
+		return decodeBookImportCSV(r)
+	case FormatMARC, FormatMARCXML:
+		return decodeBookImportMARC(r, importFormats[strings.ToLower(format)])
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+func decodeBookImportJSON(r io.Reader) ([]BookImportRow, error) {
+	var records []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("decode json: %w", err)
+	}
+
+	rows := make([]BookImportRow, 0, len(records))
+	for i, record := range records {
+		row := BookImportRow{Line: i + 1}
+		if err := json.Unmarshal(record, &row.Book); err != nil {
+			row.Err = fmt.Errorf("decode record: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func decodeBookImportJSONL(r io.Reader) ([]BookImportRow, error) {
+	var rows []BookImportRow
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		row := BookImportRow{Line: line}
+		if err := json.Unmarshal([]byte(text), &row.Book); err != nil {
+			row.Err = fmt.Errorf("decode record: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read jsonl line %d: %w", line+1, err)
+	}
+	return rows, nil
+}
+
+func decodeBookImportCSV(r io.Reader) ([]BookImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["isbn"]; !ok {
+		return nil, errors.New("csv header must include an isbn column")
+	}
+
+	var rows []BookImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, BookImportRow{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := BookImportRow{
+			Line: line,
+			Book: dto.CreateBookRequest{
+				ISBN:           field("isbn"),
+				Title:          field("title"),
+				Author:         field("author"),
+				Publisher:      field("publisher"),
+				Genre:          field("genre"),
+				Language:       strings.ToLower(field("language")),
+				ItemType:       strings.ToLower(field("item_type")),
+				Description:    field("description"),
+				Classification: strings.ToLower(field("classification")),
+				CallNumber:     field("call_number"),
+				ShelfLocation:  field("shelf_location"),
+			},
+		}
+		if row.Book.PublicationYear, err = atoiOrZero(field("publication_year")); err != nil {
+			row.Err = fmt.Errorf("invalid publication_year %q", field("publication_year"))
+		} else if row.Book.TotalCopies, err = atoiOrZero(field("total_copies")); err != nil {
+			row.Err = fmt.Errorf("invalid total_copies %q", field("total_copies"))
+		} else if row.Book.Price, err = atoiOrZero(field("price")); err != nil {
+			row.Err = fmt.Errorf("invalid price %q", field("price"))
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func atoiOrZero(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
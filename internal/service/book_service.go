@@ -1,139 +1,802 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
 	"github.com/alpardfm/library-management-api/internal/dto"
 	"github.com/alpardfm/library-management-api/internal/models"
 	"github.com/alpardfm/library-management-api/internal/repository"
 	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/callnumber"
+	"github.com/alpardfm/library-management-api/pkg/database"
+	"github.com/alpardfm/library-management-api/pkg/isbn"
+	"github.com/alpardfm/library-management-api/pkg/mergepatch"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/alpardfm/library-management-api/pkg/textsearch"
+	"gorm.io/gorm"
 )
 
+// BookService manages the catalog. Changes to a book are recorded in its
+// history as made by actor.
 type BookService interface {
-	CreateBook(req dto.CreateBookRequest) (*models.Book, error)
-	GetBookByID(id uint) (*models.Book, error)
-	UpdateBook(id uint, req dto.UpdateBookRequest) (*models.Book, error)
-	DeleteBook(id uint) error
-	ListBooks(page, limit int, search, sort string) ([]models.Book, int64, error)
-	CheckAvailability(id uint) (bool, error)
+	CreateBook(ctx context.Context, actor dto.Actor, req dto.CreateBookRequest) (*models.Book, error)
+	GetBookByID(ctx context.Context, id uint) (*models.Book, error)
+	// ReplaceBook replaces the catalog fields of a book with req, clearing
+	// the optional fields it leaves out. It returns the fields that changed.
+	// The change is refused unless the book is still at version; 0 skips
+	// the check, as it does for PatchBook and DeleteBook.
+	ReplaceBook(ctx context.Context, actor dto.Actor, id, version uint, req dto.CreateBookRequest) (*models.Book, []dto.FieldChange, error)
+	// PatchBook applies a JSON merge patch (RFC 7396) to the fields of a
+	// book as a create request names them; null clears a field. It returns
+	// the fields that changed.
+	PatchBook(ctx context.Context, actor dto.Actor, id, version uint, patch []byte) (*models.Book, []dto.FieldChange, error)
+	// DeleteBook withdraws or archives a book: it is soft-deleted and can be
+	// restored until it is purged.
+	DeleteBook(ctx context.Context, actor dto.Actor, id, version uint, req dto.RemoveBookRequest) error
+	// ListRemovedBooks lists withdrawn and archived books, or those of one
+	// status, most recently removed first.
+	ListRemovedBooks(ctx context.Context, status, search string, req query.PageRequest) ([]models.Book, query.PageInfo, error)
+	RestoreBook(ctx context.Context, actor dto.Actor, id uint) (*models.Book, error)
+	// BookHistory lists the recorded revisions of a book, removed or not.
+	BookHistory(ctx context.Context, id uint, req query.PageRequest) ([]models.BookRevision, query.PageInfo, error)
+	// RevertBook brings the catalog fields of a book back to those recorded
+	// for an earlier version, as a new revision. It returns the fields that
+	// changed.
+	RevertBook(ctx context.Context, actor dto.Actor, id, version uint, req dto.RevertBookRequest) (*models.Book, []dto.FieldChange, error)
+	ListBooks(ctx context.Context, filter dto.BookListFilter, req query.PageRequest) ([]models.Book, query.PageInfo, error)
+	// SearchBooks runs a full-text search and returns the search mode used:
+	// SearchModeSubstring when it had to fall back.
+	SearchBooks(ctx context.Context, filter dto.BookListFilter, req query.PageRequest) ([]models.Book, query.PageInfo, string, error)
+	// SuggestBooks completes a partially typed search with titles and author
+	// names.
+	SuggestBooks(ctx context.Context, term string, limit int) ([]dto.BookSuggestion, error)
+	// SpellingSuggestion returns the title or author name a search that found
+	// nothing was probably meant to be, or "" when there is none.
+	SpellingSuggestion(ctx context.Context, search string) (string, error)
+	// BookFacets counts the books matching filter per value of each named
+	// facet, or of every facet when none are named.
+	BookFacets(ctx context.Context, filter dto.BookListFilter, facets []string) (map[string][]dto.FacetValue, error)
+	CheckAvailability(ctx context.Context, id uint) (bool, error)
 }
 
 type bookService struct {
-	bookRepo repository.BookRepository
+	db           *gorm.DB
+	bookRepo     repository.BookRepository
+	authorRepo   repository.AuthorRepository
+	subjectRepo  repository.SubjectRepository
+	revisionRepo repository.BookRevisionRepository
+	workRepo     repository.WorkRepository
+	copyRepo     repository.CopyRepository
+	// trigram reports whether pg_trgm is available; it is checked once, on
+	// first use.
+	trigram func() bool
 }
 
-func NewBookService(bookRepo repository.BookRepository) BookService {
-	return &bookService{bookRepo: bookRepo}
+func NewBookService(db *gorm.DB, bookRepo repository.BookRepository, authorRepo repository.AuthorRepository, subjectRepo repository.SubjectRepository, revisionRepo repository.BookRevisionRepository, workRepo repository.WorkRepository, copyRepo repository.CopyRepository) BookService {
+	return &bookService{
+		db:           db,
+		bookRepo:     bookRepo,
+		authorRepo:   authorRepo,
+		subjectRepo:  subjectRepo,
+		revisionRepo: revisionRepo,
+		workRepo:     workRepo,
+		copyRepo:     copyRepo,
+		trigram: sync.OnceValue(func() bool {
+			return database.DialectOf(db).TrigramSimilarity(db)
+		}),
+	}
 }
 
-func (s *bookService) CreateBook(req dto.CreateBookRequest) (*models.Book, error) {
-	existingBook, _ := s.bookRepo.FindByISBN(req.ISBN)
-	if existingBook != nil {
-		return nil, apperror.Conflict("book with this ISBN already exists")
+func (s *bookService) CreateBook(ctx context.Context, actor dto.Actor, req dto.CreateBookRequest) (*models.Book, error) {
+	normalizedISBN, err := normalizeISBN(req.ISBN)
+	if err != nil {
+		return nil, err
 	}
 
-	book := &models.Book{
-		ISBN:            req.ISBN,
-		Title:           req.Title,
-		Author:          req.Author,
-		Publisher:       req.Publisher,
-		PublicationYear: req.PublicationYear,
-		Genre:           req.Genre,
-		Description:     req.Description,
-		TotalCopies:     req.TotalCopies,
-		AvailableCopies: req.TotalCopies,
+	existingBook, _ := s.bookRepo.FindByISBN(ctx, normalizedISBN)
+	if existingBook != nil {
+		return nil, isbnTaken(existingBook)
 	}
 
-	if err := validateBookStock(book); err != nil {
+	book := newBook(req, normalizedISBN)
+
+	if err := validateNewBookStock(book); err != nil {
+		return nil, err
+	}
+	if err := setCallNumber(book, req); err != nil {
 		return nil, err
 	}
 
-	if err := s.bookRepo.Create(book); err != nil {
-		return nil, apperror.Internal("failed to create book", err)
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		authorRepoTx := s.authorRepo.WithTx(tx)
+
+		contributors, _, err := resolveContributors(ctx, authorRepoTx, req.Contributors, req.Author)
+		if err != nil {
+			return err
+		}
+		if len(contributors) == 0 {
+			return apperror.BadRequest("author or contributors is required")
+		}
+		if book.Author == "" {
+			book.Author = authorStatement(contributors)
+		}
+		subjectRepoTx := s.subjectRepo.WithTx(tx)
+		subjects, err := resolveBookSubjects(ctx, subjectRepoTx, req.SubjectIDs, req.Genre)
+		if err != nil {
+			return err
+		}
+		if err := checkBookWork(ctx, s.workRepo.WithTx(tx), book.WorkID); err != nil {
+			return err
+		}
+
+		if err := s.bookRepo.WithTx(tx).Create(ctx, book); err != nil {
+			return apperror.Internal("failed to create book", err)
+		}
+		if err := authorRepoTx.ReplaceContributors(ctx, book.ID, contributors); err != nil {
+			return apperror.Internal("failed to link book contributors", err)
+		}
+		book.Contributors = contributors
+		if len(subjects) > 0 {
+			if err := setBookSubjects(ctx, subjectRepoTx, book, subjects); err != nil {
+				return err
+			}
+		}
+		changes := bookChanges(bookDocument(&models.Book{}), bookDocument(book))
+		return recordRevision(ctx, s.revisionRepo.WithTx(tx), book, models.BookRevision{Action: models.RevisionCreate}, changes, actor)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return book, nil
 }
 
-func (s *bookService) GetBookByID(id uint) (*models.Book, error) {
-	book, err := s.bookRepo.FindByID(id)
+func (s *bookService) GetBookByID(ctx context.Context, id uint) (*models.Book, error) {
+	book, err := s.bookRepo.FindByID(ctx, id)
 	if err != nil {
-		return nil, apperror.NotFound("book")
+		return nil, lookupError(err, "book")
+	}
+	if err := s.fillHoldings(ctx, []*models.Book{book}); err != nil {
+		return nil, err
 	}
 	return book, nil
 }
 
-func (s *bookService) UpdateBook(id uint, req dto.UpdateBookRequest) (*models.Book, error) {
-	book, err := s.bookRepo.FindByID(id)
+func (s *bookService) ReplaceBook(ctx context.Context, actor dto.Actor, id, version uint, req dto.CreateBookRequest) (*models.Book, []dto.FieldChange, error) {
+	book, err := s.bookRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, nil, lookupError(err, "book")
+	}
+	if err := checkVersion("book", book.Version, version); err != nil {
+		return nil, nil, err
+	}
+	return s.replaceBook(ctx, actor, book, req, nil, models.BookRevision{Action: models.RevisionUpdate})
+}
+
+func (s *bookService) PatchBook(ctx context.Context, actor dto.Actor, id, version uint, patch []byte) (*models.Book, []dto.FieldChange, error) {
+	fields, err := mergepatch.Fields(patch)
+	if err != nil {
+		return nil, nil, apperror.BadRequest(err.Error())
+	}
+
+	book, err := s.bookRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, nil, lookupError(err, "book")
+	}
+	if err := checkVersion("book", book.Version, version); err != nil {
+		return nil, nil, err
+	}
+	current, err := json.Marshal(bookDocument(book))
+	if err != nil {
+		return nil, nil, apperror.Internal("failed to encode book", err)
+	}
+	merged, err := mergepatch.Apply(current, patch)
 	if err != nil {
-		return nil, apperror.NotFound("book")
+		return nil, nil, apperror.BadRequest(err.Error())
 	}
 
+	var req dto.CreateBookRequest
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return nil, nil, decodeError(err, "invalid book")
+	}
+	return s.replaceBook(ctx, actor, book, req, fields, models.BookRevision{Action: models.RevisionUpdate})
+}
+
+// replaceBook makes book match req. For a patch, patched holds the fields
+// the patch mentions: contributors and subjects are only relinked when it
+// touches them, and a new author statement alone replaces only the author
+// credits. Without patched every field is replaced. A change is recorded as
+// revision; a book left as it was is not saved.
+func (s *bookService) replaceBook(ctx context.Context, actor dto.Actor, book *models.Book, req dto.CreateBookRequest, patched map[string]json.RawMessage, revision models.BookRevision) (*models.Book, []dto.FieldChange, error) {
+	touched := func(field string) bool {
+		if patched == nil {
+			return true
+		}
+		_, ok := patched[field]
+		return ok
+	}
+
+	if err := validateRequest(&req, "invalid book"); err != nil {
+		return nil, nil, err
+	}
 	if err := validateBookStock(book); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if req.Title != "" {
-		book.Title = req.Title
+	normalizedISBN, err := normalizeISBN(req.ISBN)
+	if err != nil {
+		return nil, nil, err
 	}
-	if req.Author != "" {
-		book.Author = req.Author
+	if normalizedISBN != book.ISBN {
+		existingBook, _ := s.bookRepo.FindByISBN(ctx, normalizedISBN)
+		if existingBook != nil && existingBook.ID != book.ID {
+			return nil, nil, isbnTaken(existingBook)
+		}
 	}
-	if req.Publisher != "" {
-		book.Publisher = req.Publisher
+
+	before := bookDocument(book)
+	replaceCredits := touched("contributors")
+	relinkAuthors := !replaceCredits && req.Author != "" && req.Author != book.Author
+
+	book.ISBN = normalizedISBN
+	book.Title = req.Title
+	book.Author = req.Author
+	if book.Author == "" && !replaceCredits {
+		book.Author = authorStatement(book.Contributors)
 	}
-	if req.PublicationYear > 0 {
-		book.PublicationYear = req.PublicationYear
+	book.Publisher = req.Publisher
+	book.PublicationYear = req.PublicationYear
+	book.Genre = req.Genre
+	book.Language = strings.ToLower(req.Language)
+	book.ItemType = req.ItemType
+	if book.ItemType == "" {
+		book.ItemType = models.ItemTypeBook
 	}
-	if req.Genre != "" {
-		book.Genre = req.Genre
+	book.Description = req.Description
+	book.Price = req.Price
+	if err := setCallNumber(book, req); err != nil {
+		return nil, nil, err
 	}
-	if req.Description != "" {
-		book.Description = req.Description
+	restock := req.TotalCopies != book.TotalCopies
+	if err := setBookStock(book, req.TotalCopies); err != nil {
+		return nil, nil, err
 	}
-	if req.TotalCopies > 0 {
-		borrowedCopies := book.TotalCopies - book.AvailableCopies
-		if req.TotalCopies < borrowedCopies {
-			return nil, apperror.Conflict("total copies cannot be less than borrowed copies")
+	relinkWork := bookWorkID(book.WorkID) != req.WorkID
+	book.WorkID = workIDOf(req.WorkID)
+
+	var changes []dto.FieldChange
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		switch {
+		case replaceCredits:
+			authorRepoTx := s.authorRepo.WithTx(tx)
+			contributors, _, err := resolveContributors(ctx, authorRepoTx, req.Contributors, req.Author)
+			if err != nil {
+				return err
+			}
+			if len(contributors) == 0 {
+				return apperror.Invalid("invalid book", map[string]string{"author": "is required"})
+			}
+			if book.Author == "" {
+				book.Author = authorStatement(contributors)
+			}
+			if err := authorRepoTx.ReplaceContributors(ctx, book.ID, contributors); err != nil {
+				return apperror.Internal("failed to link book contributors", err)
+			}
+			book.Contributors = contributors
+		case relinkAuthors:
+			if err := relinkContributors(ctx, s.authorRepo.WithTx(tx), book, nil, req.Author); err != nil {
+				return err
+			}
+		}
+		if relinkWork {
+			if err := checkBookWork(ctx, s.workRepo.WithTx(tx), book.WorkID); err != nil {
+				return err
+			}
 		}
+		if restock {
+			if err := checkRegisteredCopies(ctx, s.copyRepo.WithTx(tx), book); err != nil {
+				return err
+			}
+		}
+		if touched("subject_ids") {
+			subjectRepoTx := s.subjectRepo.WithTx(tx)
+			var subjects []models.Subject
+			if len(req.SubjectIDs) > 0 {
+				var err error
+				if subjects, err = resolveBookSubjects(ctx, subjectRepoTx, req.SubjectIDs, ""); err != nil {
+					return err
+				}
+			}
+			if err := setBookSubjects(ctx, subjectRepoTx, book, subjects); err != nil {
+				return err
+			}
+		}
+		changes = bookChanges(before, bookDocument(book))
+		if len(changes) == 0 {
+			return nil
+		}
+		if err := s.bookRepo.WithTx(tx).Update(ctx, book); err != nil {
+			return updateError(err, "book", "failed to update book")
+		}
+		return recordRevision(ctx, s.revisionRepo.WithTx(tx), book, revision, changes, actor)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return book, changes, nil
+}
+
+// bookDocument is book in the form of a create request, the document that
+// PATCH requests are merged into.
+func bookDocument(book *models.Book) dto.CreateBookRequest {
+	doc := dto.CreateBookRequest{
+		ISBN:            book.ISBN,
+		Title:           book.Title,
+		Author:          book.Author,
+		Publisher:       book.Publisher,
+		PublicationYear: book.PublicationYear,
+		Genre:           book.Genre,
+		Language:        book.Language,
+		ItemType:        book.ItemType,
+		Description:     book.Description,
+		TotalCopies:     book.TotalCopies,
+		Contributors:    make([]dto.ContributorRequest, 0, len(book.Contributors)),
+		SubjectIDs:      make([]uint, 0, len(book.Subjects)),
+		WorkID:          bookWorkID(book.WorkID),
+		Classification:  book.Classification,
+		CallNumber:      book.CallNumber,
+		ShelfLocation:   book.ShelfLocation,
+		Price:           book.Price,
+	}
+	for _, c := range book.Contributors {
+		doc.Contributors = append(doc.Contributors, dto.ContributorRequest{AuthorID: c.AuthorID, Role: c.Role})
+	}
+	for _, subject := range book.Subjects {
+		doc.SubjectIDs = append(doc.SubjectIDs, subject.ID)
+	}
+	slices.Sort(doc.SubjectIDs)
+	return doc
+}
 
-		book.TotalCopies = req.TotalCopies
-		book.AvailableCopies = req.TotalCopies - borrowedCopies
+// bookChanges lists the fields that differ between two documents of the same
+// book, in declaration order and named as in requests.
+func bookChanges(before, after dto.CreateBookRequest) []dto.FieldChange {
+	changes := []dto.FieldChange{}
+	from, to := reflect.ValueOf(before), reflect.ValueOf(after)
+	for i := 0; i < from.NumField(); i++ {
+		oldValue, newValue := from.Field(i).Interface(), to.Field(i).Interface()
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		name, _, _ := strings.Cut(from.Type().Field(i).Tag.Get("json"), ",")
+		changes = append(changes, dto.FieldChange{Field: name, From: oldValue, To: newValue})
 	}
+	return changes
+}
 
-	if err := validateBookStock(book); err != nil {
-		return nil, err
+// recordRevision adds book, as just saved, to its history. revision says how
+// the change came about and is completed with the book's version, its
+// document as snapshot, the changes and actor.
+func recordRevision(ctx context.Context, revisionRepo repository.BookRevisionRepository, book *models.Book, revision models.BookRevision, changes []dto.FieldChange, actor dto.Actor) error {
+	snapshot, err := json.Marshal(bookDocument(book))
+	if err != nil {
+		return apperror.Internal("failed to encode book", err)
+	}
+	if revision.Changes, err = json.Marshal(changes); err != nil {
+		return apperror.Internal("failed to encode book changes", err)
+	}
+	revision.BookID = book.ID
+	revision.Version = book.Version
+	revision.Snapshot = snapshot
+	revision.ActorID = actor.UserID
+	revision.RequestID = actor.RequestID
+	if err := revisionRepo.Create(ctx, &revision); err != nil {
+		return apperror.Internal("failed to record book revision", err)
+	}
+	return nil
+}
+
+// saveBookChange saves a change to book made outside an edit of its record,
+// such as copies added to or written off its stock or an ISBN normalized, and
+// records it in the book's history like an edit. book is locked in the
+// transaction bookRepo and revisionRepo are bound to, and before is its
+// document as it was loaded.
+func saveBookChange(ctx context.Context, bookRepo repository.BookRepository, revisionRepo repository.BookRevisionRepository, book *models.Book, before dto.CreateBookRequest, actor dto.Actor) error {
+	changes := bookChanges(before, bookDocument(book))
+	if err := bookRepo.Update(ctx, book); err != nil {
+		return updateError(err, "book", "failed to update book")
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	// The history keeps the whole record, credits and subjects included.
+	saved, err := bookRepo.FindByID(ctx, book.ID)
+	if err != nil {
+		return apperror.Internal("failed to load book", err)
+	}
+	return recordRevision(ctx, revisionRepo, saved, models.BookRevision{Action: models.RevisionUpdate}, changes, actor)
+}
+
+// statusChange is the change of a book's catalog status from one to another.
+func statusChange(from, to string) []dto.FieldChange {
+	return []dto.FieldChange{{Field: "status", From: from, To: to}}
+}
+
+// isbnTaken is the conflict of a new ISBN with existing's.
+func isbnTaken(existing *models.Book) error {
+	if existing.Removed() {
+		return apperror.Conflict(fmt.Sprintf("book with this ISBN was %s; restore book %d instead", existing.Status, existing.ID))
+	}
+	return apperror.Conflict("book with this ISBN already exists")
+}
+
+func (s *bookService) DeleteBook(ctx context.Context, actor dto.Actor, id, version uint, req dto.RemoveBookRequest) error {
+	status := req.Status
+	if status == "" {
+		status = models.BookStatusWithdrawn
+	}
+	if status != models.BookStatusWithdrawn && status != models.BookStatusArchived {
+		return apperror.BadRequest("status must be withdrawn or archived")
 	}
 
-	if err := s.bookRepo.Update(book); err != nil {
-		return nil, apperror.Internal("failed to update book", err)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		bookRepo := s.bookRepo.WithTx(tx)
+		book, err := bookRepo.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return lookupError(err, "book")
+		}
+		if err := checkVersion("book", book.Version, version); err != nil {
+			return err
+		}
+
+		if book.AvailableCopies != book.TotalCopies {
+			return apperror.Conflict("cannot delete book with active borrows")
+		}
+
+		// The history keeps the record as it was removed, credits and
+		// subjects included.
+		removed, err := bookRepo.FindByID(ctx, id)
+		if err != nil {
+			return apperror.Internal("failed to load book", err)
+		}
+		if err := bookRepo.Remove(ctx, id, status, strings.TrimSpace(req.Reason)); err != nil {
+			return apperror.Internal("failed to delete book", err)
+		}
+		removed.Version++
+		return recordRevision(ctx, s.revisionRepo.WithTx(tx), removed, models.BookRevision{Action: models.RevisionDelete},
+			statusChange(removed.Status, status), actor)
+	})
+}
+
+func (s *bookService) ListRemovedBooks(ctx context.Context, status, search string, req query.PageRequest) ([]models.Book, query.PageInfo, error) {
+	filter := repository.BookFilter{Search: search}
+	switch status {
+	case "":
+	case models.BookStatusWithdrawn, models.BookStatusArchived:
+		filter.Statuses = []string{status}
+	default:
+		return nil, query.PageInfo{}, apperror.BadRequest("status must be withdrawn or archived")
 	}
 
+	books, info, err := s.bookRepo.ListRemoved(ctx, filter, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list removed books")
+	}
+	return books, info, nil
+}
+
+func (s *bookService) RestoreBook(ctx context.Context, actor dto.Actor, id uint) (*models.Book, error) {
+	if _, err := s.bookRepo.FindByID(ctx, id); err == nil {
+		return nil, apperror.Conflict("book is not withdrawn or archived")
+	}
+
+	var book *models.Book
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		bookRepo := s.bookRepo.WithTx(tx)
+		removed, err := bookRepo.FindWithRemoved(ctx, id)
+		if err != nil {
+			return lookupError(err, "book")
+		}
+		if err := bookRepo.Restore(ctx, id); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.NotFound("book")
+			}
+			return apperror.Internal("failed to restore book", err)
+		}
+		if book, err = bookRepo.FindByID(ctx, id); err != nil {
+			return apperror.Internal("failed to load book", err)
+		}
+		return recordRevision(ctx, s.revisionRepo.WithTx(tx), book, models.BookRevision{Action: models.RevisionRestore},
+			statusChange(removed.Status, book.Status), actor)
+	})
+	if err != nil {
+		return nil, err
+	}
 	return book, nil
 }
 
-func (s *bookService) DeleteBook(id uint) error {
-	book, err := s.bookRepo.FindByID(id)
+func (s *bookService) BookHistory(ctx context.Context, id uint, req query.PageRequest) ([]models.BookRevision, query.PageInfo, error) {
+	if _, err := s.bookRepo.FindWithRemoved(ctx, id); err != nil {
+		return nil, query.PageInfo{}, lookupError(err, "book")
+	}
+	revisions, info, err := s.revisionRepo.List(ctx, id, req)
 	if err != nil {
-		return apperror.NotFound("book")
+		return nil, info, listError(err, "failed to list book history")
+	}
+	return revisions, info, nil
+}
+
+func (s *bookService) RevertBook(ctx context.Context, actor dto.Actor, id, version uint, req dto.RevertBookRequest) (*models.Book, []dto.FieldChange, error) {
+	book, err := s.bookRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, nil, lookupError(err, "book")
+	}
+	if err := checkVersion("book", book.Version, version); err != nil {
+		return nil, nil, err
 	}
 
-	if book.AvailableCopies != book.TotalCopies {
-		return apperror.Conflict("cannot delete book with active borrows")
+	revision, err := s.revisionRepo.FindByVersion(ctx, id, req.Version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, apperror.NotFound(fmt.Sprintf("book version %d", req.Version))
+		}
+		return nil, nil, apperror.Internal("failed to load book revision", err)
+	}
+	var doc dto.CreateBookRequest
+	if err := json.Unmarshal(revision.Snapshot, &doc); err != nil {
+		return nil, nil, apperror.Internal("failed to decode book revision", err)
 	}
+	// Copies are added and written off by circulation, not by catalog
+	// edits, so the book keeps the stock it has now.
+	doc.TotalCopies = book.TotalCopies
+	return s.replaceBook(ctx, actor, book, doc, nil, models.BookRevision{Action: models.RevisionRevert, SourceVersion: req.Version})
+}
 
-	if err := s.bookRepo.Delete(id); err != nil {
-		return apperror.Internal("failed to delete book", err)
+func (s *bookService) ListBooks(ctx context.Context, filter dto.BookListFilter, req query.PageRequest) ([]models.Book, query.PageInfo, error) {
+	books, info, err := s.bookRepo.List(ctx, bookFilter(filter), req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list books")
+	}
+	if err := s.countEditions(ctx, filter, books); err != nil {
+		return nil, info, err
+	}
+	if err := s.fillHoldings(ctx, bookPointers(books)); err != nil {
+		return nil, info, err
+	}
+	return books, info, nil
+}
+
+// fillHoldings fills in the availability per branch of books.
+func (s *bookService) fillHoldings(ctx context.Context, books []*models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+	}
+	holdings, err := s.copyRepo.Holdings(ctx, ids)
+	if err != nil {
+		return apperror.Internal("failed to load book holdings", err)
+	}
+	for _, book := range books {
+		book.Holdings = holdings[book.ID]
+	}
+	return nil
+}
+
+func bookPointers(books []models.Book) []*models.Book {
+	pointers := make([]*models.Book, len(books))
+	for i := range books {
+		pointers[i] = &books[i]
+	}
+	return pointers
+}
+
+// countEditions fills in the edition counts of a listing collapsed by work.
+// A book without a work is its only edition.
+func (s *bookService) countEditions(ctx context.Context, filter dto.BookListFilter, books []models.Book) error {
+	if !filter.CollapseWorks {
+		return nil
+	}
+	var workIDs []uint
+	for i := range books {
+		if books[i].WorkID != nil {
+			workIDs = append(workIDs, *books[i].WorkID)
+		}
+	}
+	counts, err := s.bookRepo.EditionCounts(ctx, bookFilter(filter), workIDs)
+	if err != nil {
+		return apperror.Internal("failed to count editions", err)
+	}
+	for i := range books {
+		book := &books[i]
+		if book.WorkID == nil {
+			book.EditionCount = 1
+			if book.AvailableCopies > 0 {
+				book.AvailableEditions = 1
+			}
+			continue
+		}
+		count := counts[*book.WorkID]
+		book.EditionCount, book.AvailableEditions = count.Editions, count.Available
 	}
 	return nil
 }
 
-func (s *bookService) ListBooks(page, limit int, search, sort string) ([]models.Book, int64, error) {
-	return s.bookRepo.List(page, limit, search, sort)
+// Search modes of book listings.
+const (
+	SearchModeSubstring = "substring"
+	SearchModeFullText  = "fulltext"
+)
+
+// SearchBooks ranks books by full-text relevance. Without full-text support in
+// the database, for a query without words to match, or when nothing matches,
+// it runs the substring search instead, which also finds partial words and
+// misspelled stems. Ranked pages are positioned by offset, so a cursor with a
+// sort key continues a substring search.
+func (s *bookService) SearchBooks(ctx context.Context, filter dto.BookListFilter, req query.PageRequest) ([]models.Book, query.PageInfo, string, error) {
+	filter.SearchMode = SearchModeFullText
+	continuesSubstring := req.Cursor != nil && len(req.Cursor.Key) > 0
+	if filter.Search != "" && !continuesSubstring && database.DialectOf(s.db).FullTextSearch() && !textsearch.Parse(filter.Search).Empty() {
+		books, info, err := s.bookRepo.List(ctx, bookFilter(filter), req)
+		if err != nil {
+			return nil, info, "", listError(err, "failed to search books")
+		}
+		if len(books) > 0 {
+			if err := s.countEditions(ctx, filter, books); err != nil {
+				return nil, info, "", err
+			}
+			if err := s.fillHoldings(ctx, bookPointers(books)); err != nil {
+				return nil, info, "", err
+			}
+			return books, info, SearchModeFullText, nil
+		}
+	}
+
+	filter.SearchMode = SearchModeSubstring
+	books, info, err := s.bookRepo.List(ctx, bookFilter(filter), req)
+	if err != nil {
+		return nil, info, "", listError(err, "failed to search books")
+	}
+	if err := s.countEditions(ctx, filter, books); err != nil {
+		return nil, info, "", err
+	}
+	if err := s.fillHoldings(ctx, bookPointers(books)); err != nil {
+		return nil, info, "", err
+	}
+	return books, info, SearchModeSubstring, nil
+}
+
+const (
+	// suggestMinLength is the shortest term worth completing.
+	suggestMinLength = 2
+	MaxSuggestions   = 20
+)
+
+func (s *bookService) SuggestBooks(ctx context.Context, term string, limit int) ([]dto.BookSuggestion, error) {
+	term = strings.TrimSpace(term)
+	suggestions := []dto.BookSuggestion{}
+	if utf8.RuneCountInString(term) < suggestMinLength {
+		return suggestions, nil
+	}
+	if limit < 1 || limit > MaxSuggestions {
+		limit = MaxSuggestions
+	}
+
+	matches, err := s.bookRepo.Suggest(ctx, term, s.trigram(), limit)
+	if err != nil {
+		return nil, apperror.Internal("failed to suggest books", err)
+	}
+	for _, match := range matches {
+		suggestion := dto.BookSuggestion{Kind: match.Kind, Text: match.Text}
+		if match.Kind == repository.SuggestionAuthor {
+			suggestion.AuthorID = match.ID
+		} else {
+			suggestion.BookID = match.ID
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
+
+// SpellingSuggestion needs pg_trgm; without it there is never a suggestion.
+func (s *bookService) SpellingSuggestion(ctx context.Context, search string) (string, error) {
+	search = strings.TrimSpace(search)
+	if search == "" || !s.trigram() {
+		return "", nil
+	}
+
+	matches, err := s.bookRepo.Similar(ctx, search, 3)
+	if err != nil {
+		return "", apperror.Internal("failed to suggest a spelling", err)
+	}
+	for _, match := range matches {
+		if !strings.EqualFold(match.Text, search) {
+			return match.Text, nil
+		}
+	}
+	return "", nil
+}
+
+func (s *bookService) BookFacets(ctx context.Context, filter dto.BookListFilter, facets []string) (map[string][]dto.FacetValue, error) {
+	if len(facets) == 0 {
+		facets = repository.BookFacetNames
+	}
+	for _, facet := range facets {
+		if !slices.Contains(repository.BookFacetNames, facet) {
+			return nil, apperror.BadRequest(fmt.Sprintf("unknown facet %q (available: %s)", facet, strings.Join(repository.BookFacetNames, ", ")))
+		}
+	}
+
+	if filter.SearchMode == SearchModeFullText && !database.DialectOf(s.db).FullTextSearch() {
+		filter.SearchMode = SearchModeSubstring
+	}
+
+	counts, err := s.bookRepo.Facets(ctx, bookFilter(filter), facets, bookFacetSize)
+	if err != nil {
+		return nil, apperror.Internal("failed to count book facets", err)
+	}
+
+	result := make(map[string][]dto.FacetValue, len(counts))
+	for facet, values := range counts {
+		result[facet] = make([]dto.FacetValue, len(values))
+		for i, value := range values {
+			result[facet][i] = dto.FacetValue{Value: value.Value, Label: value.Label, Count: value.Count}
+		}
+	}
+	return result, nil
+}
+
+// bookFacetSize is the number of values returned per facet.
+const bookFacetSize = 20
+
+// bookFilter converts a listing filter for the repository. A full-text search
+// becomes a tsquery; otherwise a complete ISBN in any form matches the stored
+// ISBN-13. Languages compare lowercase.
+func bookFilter(filter dto.BookListFilter) repository.BookFilter {
+	search, textQuery := filter.Search, ""
+	if filter.SearchMode == SearchModeFullText {
+		search, textQuery = "", textsearch.Parse(filter.Search).TSQuery()
+	} else if normalizedISBN, err := isbn.Normalize(search); err == nil {
+		search = normalizedISBN
+	}
+	var languages []string
+	for _, language := range filter.Languages {
+		languages = append(languages, strings.ToLower(language))
+	}
+	return repository.BookFilter{
+		Search:        search,
+		TextQuery:     textQuery,
+		SubjectIDs:    filter.SubjectIDs,
+		AuthorIDs:     filter.AuthorIDs,
+		Genres:        filter.Genres,
+		Publishers:    filter.Publishers,
+		YearFrom:      filter.YearFrom,
+		YearTo:        filter.YearTo,
+		Languages:     languages,
+		ItemTypes:     filter.ItemTypes,
+		Available:     filter.Available,
+		CollapseWorks: filter.CollapseWorks,
+	}
 }
 
-func (s *bookService) CheckAvailability(id uint) (bool, error) {
-	book, err := s.bookRepo.FindByID(id)
+func (s *bookService) CheckAvailability(ctx context.Context, id uint) (bool, error) {
+	book, err := s.bookRepo.FindByID(ctx, id)
 	if err != nil {
-		return false, apperror.NotFound("book")
+		return false, lookupError(err, "book")
 	}
 
 	if err := validateBookStock(book); err != nil {
@@ -143,8 +806,167 @@ func (s *bookService) CheckAvailability(id uint) (bool, error) {
 	return book.CanBorrow(), nil
 }
 
-func validateBookStock(book *models.Book) error {
+func newBook(req dto.CreateBookRequest, normalizedISBN string) *models.Book {
+	return &models.Book{
+		ISBN:            normalizedISBN,
+		Title:           req.Title,
+		Author:          req.Author,
+		Publisher:       req.Publisher,
+		PublicationYear: req.PublicationYear,
+		Genre:           req.Genre,
+		Language:        strings.ToLower(req.Language),
+		ItemType:        req.ItemType,
+		Description:     req.Description,
+		TotalCopies:     req.TotalCopies,
+		AvailableCopies: req.TotalCopies,
+		WorkID:          workIDOf(req.WorkID),
+		Price:           req.Price,
+	}
+}
+
+// checkBookWork makes sure the work a book is linked to exists.
+func checkBookWork(ctx context.Context, workRepo repository.WorkRepository, workID *uint) error {
+	if workID == nil {
+		return nil
+	}
+	if _, err := workRepo.FindByID(ctx, *workID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Invalid("invalid book", map[string]string{"work_id": fmt.Sprintf("work %d does not exist", *workID)})
+		}
+		return apperror.Internal("failed to check book work", err)
+	}
+	return nil
+}
+
+// checkRegisteredCopies makes sure the stock of book still covers the copies
+// registered at branches, and the available ones among them.
+func checkRegisteredCopies(ctx context.Context, copyRepo repository.CopyRepository, book *models.Book) error {
+	total, available, err := copyRepo.CountByBook(ctx, book.ID)
+	if err != nil {
+		return apperror.Internal("failed to count copies", err)
+	}
+	if total > int64(book.TotalCopies) || available > int64(book.AvailableCopies) {
+		return apperror.Invalid("invalid book", map[string]string{
+			"total_copies": fmt.Sprintf("%d copies are registered at branches, %d of them on the shelf", total, available),
+		})
+	}
+	return nil
+}
+
+// setCallNumber sets the call number and shelf location of req on book. The
+// call number is stored in normal form, with its classification told from it
+// when req leaves that out.
+func setCallNumber(book *models.Book, req dto.CreateBookRequest) error {
+	book.ShelfLocation = strings.TrimSpace(req.ShelfLocation)
+	if strings.TrimSpace(req.CallNumber) == "" {
+		if req.Classification != "" {
+			return apperror.Invalid("invalid book", map[string]string{"call_number": "is required with a classification"})
+		}
+		book.Classification, book.CallNumber = "", ""
+		return nil
+	}
+
+	parsed, err := callnumber.Parse(req.Classification, req.CallNumber)
+	if err != nil {
+		field := "call_number"
+		if errors.Is(err, callnumber.ErrNoScheme) {
+			field = "classification"
+		}
+		return apperror.Invalid("invalid book", map[string]string{field: err.Error()})
+	}
+	book.Classification, book.CallNumber = parsed.Scheme, parsed.Value
+	return nil
+}
+
+// workIDOf is the work link of a request: 0 links to no work.
+func workIDOf(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// bookWorkID is the work of a book as requests name it.
+func bookWorkID(workID *uint) uint {
+	if workID == nil {
+		return 0
+	}
+	return *workID
+}
+
+// applyBookUpdate copies the non-empty catalog fields of req onto book. A new
+// total keeps the number of borrowed copies unchanged. ISBN changes are left to
+// the caller because they need a uniqueness check.
+func applyBookUpdate(book *models.Book, req dto.UpdateBookRequest) error {
+	if req.Title != "" {
+		book.Title = req.Title
+	}
+	if req.Author != "" {
+		book.Author = req.Author
+	}
+	if req.Publisher != "" {
+		book.Publisher = req.Publisher
+	}
+	if req.PublicationYear > 0 {
+		book.PublicationYear = req.PublicationYear
+	}
+	if req.Genre != "" {
+		book.Genre = req.Genre
+	}
+	if req.Language != "" {
+		book.Language = strings.ToLower(req.Language)
+	}
+	if req.ItemType != "" {
+		book.ItemType = req.ItemType
+	}
+	if req.Description != "" {
+		book.Description = req.Description
+	}
+	if req.Price > 0 {
+		book.Price = req.Price
+	}
+	if req.TotalCopies > 0 {
+		return setBookStock(book, req.TotalCopies)
+	}
+
+	return validateBookStock(book)
+}
+
+// setBookStock changes the number of copies of book, keeping the number of
+// borrowed copies unchanged.
+func setBookStock(book *models.Book, total int) error {
+	borrowedCopies := book.TotalCopies - book.AvailableCopies
+	if total < borrowedCopies {
+		return apperror.Conflict("total copies cannot be less than borrowed copies")
+	}
+
+	book.TotalCopies = total
+	book.AvailableCopies = total - borrowedCopies
+	return validateBookStock(book)
+}
+
+// normalizeISBN validates an ISBN-10 or ISBN-13 and returns its ISBN-13 storage form.
+func normalizeISBN(value string) (string, error) {
+	normalized, err := isbn.Normalize(value)
+	if err != nil {
+		return "", apperror.BadRequest(fmt.Sprintf("invalid ISBN %q: %v", value, err))
+	}
+	return normalized, nil
+}
+
+// validateNewBookStock checks the copy counts of a book being created, which
+// starts with at least one copy.
+func validateNewBookStock(book *models.Book) error {
 	if book.TotalCopies < 1 {
+		return apperror.Invalid("invalid book", map[string]string{"total_copies": "must be at least 1"})
+	}
+	return validateBookStock(book)
+}
+
+// validateBookStock checks the copy counts of book. A book is left with no
+// copies once its last one was lost or damaged.
+func validateBookStock(book *models.Book) error {
+	if book.TotalCopies < 0 {
 		return apperror.Conflict("book stock is inconsistent")
 	}
 	if book.AvailableCopies < 0 {
@@ -1,92 +1,135 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/alpardfm/library-management-api/internal/dto"
 	"github.com/alpardfm/library-management-api/internal/models"
 	"github.com/alpardfm/library-management-api/internal/repository"
 	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
 	"gorm.io/gorm"
 )
 
 type BorrowService interface {
-	BorrowBook(userID uint, req dto.BorrowBookRequest) (*models.BorrowRecord, error)
-	ReturnBook(userID uint, role string, req dto.ReturnBookRequest) (*models.BorrowRecord, int, error)
-	GetUserBorrows(userID uint, page, limit int, sort string) ([]models.BorrowRecord, int64, error)
-	GetActiveBorrows(page, limit int, sort string) ([]models.BorrowRecord, int64, error)
-	GetOverdueBorrows(page, limit int, sort string) ([]models.BorrowRecord, int64, error)
-	CalculateFine(borrowID uint) (int, error)
+	BorrowBook(ctx context.Context, userID uint, req dto.BorrowBookRequest) (*models.BorrowRecord, error)
+	ReturnBook(ctx context.Context, userID uint, role string, req dto.ReturnBookRequest) (*models.BorrowRecord, int, error)
+	// DeclareLost closes a loan whose copy was lost: the copy leaves the
+	// stock, recorded in the book's history as made by actor, and the
+	// borrower is billed its replacement.
+	DeclareLost(ctx context.Context, actor dto.Actor, req dto.DeclareLostRequest) (*models.BorrowRecord, []models.AccountEntry, error)
+	// ReturnDamaged checks in a copy damaged beyond use: the loan is closed
+	// as DeclareLost does, but the copy is back at a branch.
+	ReturnDamaged(ctx context.Context, actor dto.Actor, req dto.ReturnDamagedRequest) (*models.BorrowRecord, []models.AccountEntry, error)
+	// ReturnLost checks in a copy that was declared lost, returning it to
+	// the stock and refunding its replacement charge.
+	ReturnLost(ctx context.Context, actor dto.Actor, req dto.ReturnBookRequest) (*models.BorrowRecord, []models.AccountEntry, error)
+	GetUserBorrows(ctx context.Context, userID uint, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error)
+	GetActiveBorrows(ctx context.Context, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error)
+	GetOverdueBorrows(ctx context.Context, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error)
+	CalculateFine(ctx context.Context, borrowID uint) (int, error)
+	SweepOverdue(ctx context.Context) (int64, error)
+	CirculationReport(ctx context.Context, from, to time.Time) (*dto.CirculationReport, error)
 }
 
+const circulationReportTopBooks = 10
+
 type borrowService struct {
-	db         *gorm.DB
-	borrowRepo repository.BorrowRepository
-	bookRepo   repository.BookRepository
-	userRepo   repository.UserRepository
-	config     BorrowServiceConfig
+	db           *gorm.DB
+	borrowRepo   repository.BorrowRepository
+	bookRepo     repository.BookRepository
+	revisionRepo repository.BookRevisionRepository
+	userRepo     repository.UserRepository
+	holdRepo     repository.HoldRepository
+	copyRepo     repository.CopyRepository
+	transferRepo repository.TransferRepository
+	branchRepo   repository.BranchRepository
+	accountRepo  repository.AccountRepository
+	settings     CirculationSettings
 }
 
 type BorrowServiceConfig struct {
 	MaxBooksPerUser int
 	BorrowDays      int
 	FinePerDay      int
+	ProcessingFee   int
 }
 
 func NewBorrowService(
 	db *gorm.DB,
 	borrowRepo repository.BorrowRepository,
 	bookRepo repository.BookRepository,
+	revisionRepo repository.BookRevisionRepository,
 	userRepo repository.UserRepository,
-	config BorrowServiceConfig,
+	holdRepo repository.HoldRepository,
+	copyRepo repository.CopyRepository,
+	transferRepo repository.TransferRepository,
+	branchRepo repository.BranchRepository,
+	accountRepo repository.AccountRepository,
+	settings CirculationSettings,
 ) BorrowService {
 	return &borrowService{
-		db:         db,
-		borrowRepo: borrowRepo,
-		bookRepo:   bookRepo,
-		userRepo:   userRepo,
-		config:     config,
+		db:           db,
+		borrowRepo:   borrowRepo,
+		bookRepo:     bookRepo,
+		revisionRepo: revisionRepo,
+		userRepo:     userRepo,
+		holdRepo:     holdRepo,
+		copyRepo:     copyRepo,
+		transferRepo: transferRepo,
+		branchRepo:   branchRepo,
+		accountRepo:  accountRepo,
+		settings:     settings,
 	}
 }
 
-func (s *borrowService) BorrowBook(userID uint, req dto.BorrowBookRequest) (*models.BorrowRecord, error) {
+func (s *borrowService) BorrowBook(ctx context.Context, userID uint, req dto.BorrowBookRequest) (*models.BorrowRecord, error) {
 	var borrowRecord *models.BorrowRecord
+	config := s.settings.Circulation(ctx)
 
-	err := s.db.Transaction(func(tx *gorm.DB) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		userRepoTx := s.userRepo.WithTx(tx)
 		bookRepoTx := s.bookRepo.WithTx(tx)
 		borrowRepoTx := s.borrowRepo.WithTx(tx)
+		holdRepoTx := s.holdRepo.WithTx(tx)
 
-		user, err := userRepoTx.FindByIDForUpdate(userID)
+		user, err := userRepoTx.FindByIDForUpdate(ctx, userID)
 		if err != nil {
-			return apperror.NotFound("user")
+			return lookupError(err, "user")
 		}
 		if !user.IsActive {
 			return apperror.Forbidden("user account is deactivated")
 		}
 
-		activeCount, err := borrowRepoTx.CountActiveByUser(userID)
+		activeCount, err := borrowRepoTx.CountActiveByUser(ctx, userID)
 		if err != nil {
 			return apperror.Internal("failed to count active borrows", err)
 		}
-		if activeCount >= int64(s.config.MaxBooksPerUser) {
+		if activeCount >= int64(config.MaxBooksPerUser) {
 			return apperror.Conflict("user has reached maximum borrow limit")
 		}
 
-		book, err := bookRepoTx.FindByIDForUpdate(req.BookID)
+		book, err := bookRepoTx.FindByIDForUpdate(ctx, req.BookID)
 		if err != nil {
-			return apperror.NotFound("book")
+			return lookupError(err, "book")
 		}
 		if err := validateBookStock(book); err != nil {
 			return err
 		}
 
-		if !book.CanBorrow() {
+		// A copy set aside for the user's hold is already off the shelf.
+		hold, err := holdRepoTx.FindReady(ctx, userID, book.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Internal("failed to check ready holds", err)
+		}
+		if hold == nil && !book.CanBorrow() {
 			return apperror.Conflict("book is not available for borrowing")
 		}
 
-		existingBorrow, err := borrowRepoTx.FindActiveByUserAndBook(userID, req.BookID)
+		existingBorrow, err := borrowRepoTx.FindActiveByUserAndBook(ctx, userID, req.BookID)
 		if err == nil && existingBorrow != nil {
 			return apperror.Conflict("user has already borrowed this book")
 		}
@@ -94,26 +137,60 @@ func (s *borrowService) BorrowBook(userID uint, req dto.BorrowBookRequest) (*mod
 			return apperror.Internal("failed to check active borrow", err)
 		}
 
+		if req.BranchID != 0 {
+			if err := checkBranch(ctx, s.branchRepo.WithTx(tx), "branch_id", req.BranchID); err != nil {
+				return err
+			}
+		}
+
 		borrowRecord = &models.BorrowRecord{
 			UserID:     userID,
-			BookID:     req.BookID,
+			BookID:     &book.ID,
 			BorrowDate: time.Now(),
+			BranchID:   branchIDOf(req.BranchID),
 		}
 
 		if !req.DueDate.IsZero() {
 			borrowRecord.DueDate = req.DueDate
 		} else {
-			borrowRecord.DueDate = borrowRecord.BorrowDate.Add(time.Duration(s.config.BorrowDays) * 24 * time.Hour)
+			borrowRecord.DueDate = borrowRecord.BorrowDate.Add(time.Duration(config.BorrowDays) * 24 * time.Hour)
 		}
 
-		if err := book.Borrow(); err != nil {
-			return apperror.Conflict(err.Error())
+		copyRepoTx := s.copyRepo.WithTx(tx)
+		var item *models.Copy
+		if hold != nil {
+			if hold.ReadyCopyID != nil {
+				if item, err = copyRepoTx.FindByIDForUpdate(ctx, *hold.ReadyCopyID); err != nil {
+					return apperror.Internal("failed to load held copy", err)
+				}
+			}
+			hold.Status = models.HoldFulfilled
+			if err := holdRepoTx.Update(ctx, hold); err != nil {
+				return apperror.Internal("failed to fulfil hold", err)
+			}
+		} else {
+			if item, err = lendableCopy(ctx, copyRepoTx, book, req.BranchID); err != nil {
+				return err
+			}
+			if err := book.Borrow(); err != nil {
+				return apperror.Conflict(err.Error())
+			}
+			if err := bookRepoTx.Update(ctx, book); err != nil {
+				return apperror.Internal("failed to update book", err)
+			}
 		}
-		if err := bookRepoTx.Update(book); err != nil {
-			return apperror.Internal("failed to update book", err)
+		if item != nil {
+			item.Status = models.CopyOnLoan
+			if err := copyRepoTx.Update(ctx, item); err != nil {
+				return apperror.Internal("failed to update copy", err)
+			}
+			borrowRecord.CopyID = &item.ID
+			if borrowRecord.BranchID == nil {
+				borrowRecord.BranchID = &item.BranchID
+			}
 		}
 
-		if err := borrowRepoTx.Create(borrowRecord); err != nil {
+		if err := borrowRepoTx.Create(ctx, borrowRecord); err != nil {
 			return apperror.Internal("failed to create borrow record", err)
 		}
 
@@ -126,17 +203,18 @@ func (s *borrowService) BorrowBook(userID uint, req dto.BorrowBookRequest) (*mod
 	return borrowRecord, nil
 }
 
-func (s *borrowService) ReturnBook(userID uint, role string, req dto.ReturnBookRequest) (*models.BorrowRecord, int, error) {
+func (s *borrowService) ReturnBook(ctx context.Context, userID uint, role string, req dto.ReturnBookRequest) (*models.BorrowRecord, int, error) {
 	var borrowRecord *models.BorrowRecord
 	var fine int
 	var err error
-	err = s.db.Transaction(func(tx *gorm.DB) error {
+	config := s.settings.Circulation(ctx)
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		bookRepoTx := s.bookRepo.WithTx(tx)
 		borrowRepoTx := s.borrowRepo.WithTx(tx)
 
-		borrowRecord, err = borrowRepoTx.FindByIDForUpdate(req.BorrowRecordID)
+		borrowRecord, err = borrowRepoTx.FindByIDForUpdate(ctx, req.BorrowRecordID)
 		if err != nil {
-			return apperror.NotFound("borrow record")
+			return lookupError(err, "borrow record")
 		}
 
 		if role == "" {
@@ -147,13 +225,16 @@ func (s *borrowService) ReturnBook(userID uint, role string, req dto.ReturnBookR
 			return apperror.Forbidden("not authorized to return this book")
 		}
 
+		if borrowRecord.Status == models.StatusLost {
+			return apperror.Conflict("book was declared lost; return it as found")
+		}
 		if borrowRecord.ReturnDate != nil {
 			return apperror.Conflict("book already returned")
 		}
 
-		book, err := bookRepoTx.FindByIDForUpdate(borrowRecord.BookID)
+		book, err := loanBook(ctx, bookRepoTx, borrowRecord)
 		if err != nil {
-			return apperror.NotFound("book")
+			return err
 		}
 		if err := validateBookStock(book); err != nil {
 			return err
@@ -162,18 +243,27 @@ func (s *borrowService) ReturnBook(userID uint, role string, req dto.ReturnBookR
 			return apperror.Conflict("book stock is already full, cannot process return")
 		}
 
-		fine = borrowRecord.CalculateFine(s.config.FinePerDay)
+		fine = borrowRecord.CalculateFine(config.FinePerDay)
 
-		book.Return()
-		if err := bookRepoTx.Update(book); err != nil {
-			return apperror.Internal("failed to update book", err)
+		if req.BranchID != 0 {
+			if err := checkBranch(ctx, s.branchRepo.WithTx(tx), "branch_id", req.BranchID); err != nil {
+				return err
+			}
 		}
-
 		now := time.Now()
+		item, err := loanCopy(ctx, s.copyRepo.WithTx(tx), borrowRecord)
+		if err != nil {
+			return err
+		}
+		if err := s.checkIn(ctx, tx, bookRepoTx, book, item, req.BranchID, now); err != nil {
+			return err
+		}
+
 		borrowRecord.ReturnDate = &now
 		borrowRecord.Status = models.StatusReturned
+		borrowRecord.ReturnBranchID = returnBranchOf(req.BranchID, item)
 
-		if err := borrowRepoTx.Update(borrowRecord); err != nil {
+		if err := borrowRepoTx.Update(ctx, borrowRecord); err != nil {
 			return apperror.Internal("failed to update borrow record", err)
 		}
 
@@ -186,27 +276,359 @@ func (s *borrowService) ReturnBook(userID uint, role string, req dto.ReturnBookR
 	return borrowRecord, fine, nil
 }
 
+func (s *borrowService) DeclareLost(ctx context.Context, actor dto.Actor, req dto.DeclareLostRequest) (*models.BorrowRecord, []models.AccountEntry, error) {
+	return s.writeOff(ctx, actor, req.BorrowRecordID, 0, models.StatusLost, req.Note)
+}
+
+func (s *borrowService) ReturnDamaged(ctx context.Context, actor dto.Actor, req dto.ReturnDamagedRequest) (*models.BorrowRecord, []models.AccountEntry, error) {
+	return s.writeOff(ctx, actor, req.BorrowRecordID, req.BranchID, models.StatusDamaged, req.Note)
+}
+
+// writeOff closes an open loan with status lost or damaged. The copy leaves
+// the stock of its book, a damaged one staying where it came back, at
+// branchID or its home, and the borrower is billed the book's price and the
+// processing fee.
+func (s *borrowService) writeOff(ctx context.Context, actor dto.Actor, borrowID, branchID uint, status models.BorrowStatus, note string) (*models.BorrowRecord, []models.AccountEntry, error) {
+	var borrowRecord *models.BorrowRecord
+	var charges []models.AccountEntry
+	config := s.settings.Circulation(ctx)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		bookRepoTx := s.bookRepo.WithTx(tx)
+		borrowRepoTx := s.borrowRepo.WithTx(tx)
+		copyRepoTx := s.copyRepo.WithTx(tx)
+
+		var err error
+		borrowRecord, err = borrowRepoTx.FindByIDForUpdate(ctx, borrowID)
+		if err != nil {
+			return lookupError(err, "borrow record")
+		}
+		if borrowRecord.ReturnDate != nil {
+			return apperror.Conflict("loan is already closed")
+		}
+
+		book, err := loanBook(ctx, bookRepoTx, borrowRecord)
+		if err != nil {
+			return err
+		}
+		if err := validateBookStock(book); err != nil {
+			return err
+		}
+		if book.AvailableCopies >= book.TotalCopies {
+			return apperror.Conflict("book stock has no copy on loan to write off")
+		}
+		if branchID != 0 {
+			if err := checkBranch(ctx, s.branchRepo.WithTx(tx), "branch_id", branchID); err != nil {
+				return err
+			}
+		}
+
+		before := bookDocument(book)
+		book.TotalCopies--
+		if err := saveBookChange(ctx, bookRepoTx, s.revisionRepo.WithTx(tx), book, before, actor); err != nil {
+			return err
+		}
+		item, err := loanCopy(ctx, copyRepoTx, borrowRecord)
+		if err != nil {
+			return err
+		}
+		if item != nil {
+			item.Status = models.CopyLost
+			if status == models.StatusDamaged {
+				item.Status = models.CopyDamaged
+				item.BranchID = *returnBranchOf(branchID, item)
+			}
+			if err := copyRepoTx.Update(ctx, item); err != nil {
+				return apperror.Internal("failed to update copy", err)
+			}
+		}
+
+		now := time.Now()
+		borrowRecord.ReturnDate = &now
+		borrowRecord.Status = status
+		if status == models.StatusDamaged {
+			borrowRecord.ReturnBranchID = returnBranchOf(branchID, item)
+		}
+		if err := borrowRepoTx.Update(ctx, borrowRecord); err != nil {
+			return apperror.Internal("failed to update borrow record", err)
+		}
+
+		charges = replacementCharges(borrowRecord, book, config.ProcessingFee, actor.UserID, note)
+		if err := s.accountRepo.WithTx(tx).Create(ctx, charges); err != nil {
+			return apperror.Internal("failed to bill replacement", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return borrowRecord, charges, nil
+}
+
+func (s *borrowService) ReturnLost(ctx context.Context, actor dto.Actor, req dto.ReturnBookRequest) (*models.BorrowRecord, []models.AccountEntry, error) {
+	var borrowRecord *models.BorrowRecord
+	var refunds []models.AccountEntry
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		bookRepoTx := s.bookRepo.WithTx(tx)
+		borrowRepoTx := s.borrowRepo.WithTx(tx)
+		accountRepoTx := s.accountRepo.WithTx(tx)
+
+		var err error
+		borrowRecord, err = borrowRepoTx.FindByIDForUpdate(ctx, req.BorrowRecordID)
+		if err != nil {
+			return lookupError(err, "borrow record")
+		}
+		if borrowRecord.Status != models.StatusLost {
+			return apperror.Conflict("loan is " + string(borrowRecord.Status) + ", not lost")
+		}
+
+		book, err := loanBook(ctx, bookRepoTx, borrowRecord)
+		if err != nil {
+			return err
+		}
+		if err := validateBookStock(book); err != nil {
+			return err
+		}
+		if req.BranchID != 0 {
+			if err := checkBranch(ctx, s.branchRepo.WithTx(tx), "branch_id", req.BranchID); err != nil {
+				return err
+			}
+		}
+
+		// The copy is back in the stock, and on the shelf once checked in.
+		before := bookDocument(book)
+		book.TotalCopies++
+		if err := saveBookChange(ctx, bookRepoTx, s.revisionRepo.WithTx(tx), book, before, actor); err != nil {
+			return err
+		}
+		item, err := loanCopy(ctx, s.copyRepo.WithTx(tx), borrowRecord)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := s.checkIn(ctx, tx, bookRepoTx, book, item, req.BranchID, now); err != nil {
+			return err
+		}
+
+		borrowRecord.ReturnDate = &now
+		borrowRecord.Status = models.StatusReturned
+		borrowRecord.ReturnBranchID = returnBranchOf(req.BranchID, item)
+		if err := borrowRepoTx.Update(ctx, borrowRecord); err != nil {
+			return apperror.Internal("failed to update borrow record", err)
+		}
+
+		entries, err := accountRepoTx.ListByBorrow(ctx, borrowRecord.ID)
+		if err != nil {
+			return apperror.Internal("failed to load loan charges", err)
+		}
+		refund := 0
+		for _, entry := range entries {
+			if entry.Kind == models.ChargeReplacement || entry.Kind == models.ChargeRefund {
+				refund += entry.Amount
+			}
+		}
+		if refund > 0 {
+			refunds = []models.AccountEntry{{
+				UserID:         borrowRecord.UserID,
+				BorrowRecordID: &borrowRecord.ID,
+				BookID:         &book.ID,
+				Kind:           models.ChargeRefund,
+				Amount:         -refund,
+				Note:           "lost copy returned",
+				CreatedBy:      actor.UserID,
+			}}
+			if err := accountRepoTx.Create(ctx, refunds); err != nil {
+				return apperror.Internal("failed to refund replacement", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return borrowRecord, refunds, nil
+}
+
+// checkIn puts the copy of a loan that came back at branchID, or at its home
+// when branchID is 0, where it belongs: a copy returned away from home is
+// sent there and only back in stock when it arrives, the others are set
+// aside for a hold or shelved. item is nil for an unassigned copy.
+func (s *borrowService) checkIn(ctx context.Context, tx *gorm.DB, bookRepoTx repository.BookRepository, book *models.Book, item *models.Copy, branchID uint, now time.Time) error {
+	copyRepoTx := s.copyRepo.WithTx(tx)
+	switch {
+	case item != nil && branchID != 0 && branchID != item.HomeBranchID:
+		item.BranchID = branchID
+		item.Status = models.CopyInTransit
+		if err := copyRepoTx.Update(ctx, item); err != nil {
+			return apperror.Internal("failed to update copy", err)
+		}
+		transfer := &models.Transfer{
+			CopyID:       item.ID,
+			FromBranchID: branchID,
+			ToBranchID:   item.HomeBranchID,
+			Status:       models.TransferInTransit,
+			Reason:       models.TransferReturn,
+			ShippedAt:    &now,
+		}
+		if err := s.transferRepo.WithTx(tx).Create(ctx, transfer); err != nil {
+			return apperror.Internal("failed to create transfer", err)
+		}
+		return nil
+	case item != nil:
+		item.BranchID = item.HomeBranchID
+	}
+	return shelveCopy(ctx, s.holdRepo.WithTx(tx), bookRepoTx, copyRepoTx, book, item)
+}
+
+// loanBook locks the book of a loan. Loans of a purged book have none.
+func loanBook(ctx context.Context, bookRepo repository.BookRepository, borrowRecord *models.BorrowRecord) (*models.Book, error) {
+	if borrowRecord.BookID == nil {
+		return nil, apperror.Conflict("book " + borrowRecord.BookTitle + " was purged")
+	}
+	book, err := bookRepo.FindByIDForUpdate(ctx, *borrowRecord.BookID)
+	if err != nil {
+		return nil, lookupError(err, "book")
+	}
+	return book, nil
+}
+
+// loanCopy locks the copy lent by a loan, or returns nil when an unassigned
+// copy was.
+func loanCopy(ctx context.Context, copyRepo repository.CopyRepository, borrowRecord *models.BorrowRecord) (*models.Copy, error) {
+	if borrowRecord.CopyID == nil {
+		return nil, nil
+	}
+	item, err := copyRepo.FindByIDForUpdate(ctx, *borrowRecord.CopyID)
+	if err != nil {
+		return nil, apperror.Internal("failed to load borrowed copy", err)
+	}
+	return item, nil
+}
+
+// returnBranchOf is the branch a copy came back at: branchID, or the home of
+// item when 0. It is nil for an unassigned copy returned without a branch.
+func returnBranchOf(branchID uint, item *models.Copy) *uint {
+	if branchID == 0 && item != nil {
+		return &item.HomeBranchID
+	}
+	return branchIDOf(branchID)
+}
+
+// replacementCharges bills the borrower of a loan for the lost or damaged copy
+// of book: its price, when it has one, and the processing fee.
+func replacementCharges(borrowRecord *models.BorrowRecord, book *models.Book, fee int, actorID uint, note string) []models.AccountEntry {
+	var charges []models.AccountEntry
+	charge := func(kind string, amount int) {
+		if amount <= 0 {
+			return
+		}
+		charges = append(charges, models.AccountEntry{
+			UserID:         borrowRecord.UserID,
+			BorrowRecordID: &borrowRecord.ID,
+			BookID:         &book.ID,
+			Kind:           kind,
+			Amount:         amount,
+			Note:           strings.TrimSpace(note),
+			CreatedBy:      actorID,
+		})
+	}
+	charge(models.ChargeReplacement, book.Price)
+	charge(models.ChargeProcessing, fee)
+	return charges
+}
+
+// lendableCopy picks the copy of book to lend at a branch, or anywhere when
+// branchID is 0: an available registered copy there, or else one of the
+// unassigned copies on the shelf, for which it returns nil.
+func lendableCopy(ctx context.Context, copyRepo repository.CopyRepository, book *models.Book, branchID uint) (*models.Copy, error) {
+	item, err := copyRepo.FindAvailable(ctx, book.ID, branchID)
+	if err == nil {
+		return item, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.Internal("failed to find an available copy", err)
+	}
+	_, available, err := copyRepo.CountByBook(ctx, book.ID)
+	if err != nil {
+		return nil, apperror.Internal("failed to count copies", err)
+	}
+	if int64(book.AvailableCopies) <= available {
+		return nil, apperror.Conflict("book has no copy available at this branch")
+	}
+	return nil, nil
+}
+
 func canManageBorrowReturn(role string) bool {
 	return role == string(models.RoleAdmin) || role == string(models.RoleLibrarian)
 }
 
-func (s *borrowService) GetUserBorrows(userID uint, page, limit int, sort string) ([]models.BorrowRecord, int64, error) {
-	return s.borrowRepo.ListByUser(userID, page, limit, sort)
+func (s *borrowService) GetUserBorrows(ctx context.Context, userID uint, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error) {
+	records, info, err := s.borrowRepo.ListByUser(ctx, userID, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list borrows")
+	}
+	return records, info, nil
 }
 
-func (s *borrowService) GetActiveBorrows(page, limit int, sort string) ([]models.BorrowRecord, int64, error) {
-	return s.borrowRepo.ListActive(page, limit, sort)
+func (s *borrowService) GetActiveBorrows(ctx context.Context, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error) {
+	records, info, err := s.borrowRepo.ListActive(ctx, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list active borrows")
+	}
+	return records, info, nil
+}
+
+func (s *borrowService) GetOverdueBorrows(ctx context.Context, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error) {
+	records, info, err := s.borrowRepo.ListOverdue(ctx, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list overdue borrows")
+	}
+	return records, info, nil
+}
+
+func (s *borrowService) CalculateFine(ctx context.Context, borrowID uint) (int, error) {
+	borrowRecord, err := s.borrowRepo.FindByID(ctx, borrowID)
+	if err != nil {
+		return 0, lookupError(err, "borrow record")
+	}
+
+	return borrowRecord.CalculateFine(s.settings.Circulation(ctx).FinePerDay), nil
 }
 
-func (s *borrowService) GetOverdueBorrows(page, limit int, sort string) ([]models.BorrowRecord, int64, error) {
-	return s.borrowRepo.ListOverdue(page, limit, sort)
+// SweepOverdue persists the overdue status for every open borrow past its due date.
+func (s *borrowService) SweepOverdue(ctx context.Context) (int64, error) {
+	updated, err := s.borrowRepo.MarkOverdue(ctx, time.Now())
+	if err != nil {
+		return 0, apperror.Internal("failed to mark overdue borrows", err)
+	}
+	return updated, nil
 }
 
-func (s *borrowService) CalculateFine(borrowID uint) (int, error) {
-	borrowRecord, err := s.borrowRepo.FindByID(borrowID)
+func (s *borrowService) CirculationReport(ctx context.Context, from, to time.Time) (*dto.CirculationReport, error) {
+	if !from.Before(to) {
+		return nil, apperror.BadRequest("report start must be before report end")
+	}
+
+	stats, err := s.borrowRepo.CirculationStats(ctx, from, to, circulationReportTopBooks)
 	if err != nil {
-		return 0, apperror.NotFound("borrow record")
+		return nil, apperror.Internal("failed to build circulation report", err)
+	}
+
+	report := &dto.CirculationReport{
+		From:     from,
+		To:       to,
+		Borrowed: stats.Borrowed,
+		Returned: stats.Returned,
+		Active:   stats.Active,
+		Overdue:  stats.Overdue,
+		TopBooks: make([]dto.BookCirculationStat, 0, len(stats.TopBooks)),
+	}
+	for _, book := range stats.TopBooks {
+		report.TopBooks = append(report.TopBooks, dto.BookCirculationStat{
+			BookID:  book.BookID,
+			Title:   book.Title,
+			Borrows: book.Borrows,
+		})
 	}
 
-	return borrowRecord.CalculateFine(s.config.FinePerDay), nil
+	return report, nil
 }
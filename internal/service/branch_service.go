@@ -0,0 +1,149 @@
+// internal/service/branch_service.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"gorm.io/gorm"
+)
+
+// BranchService manages the branches of the library.
+type BranchService interface {
+	CreateBranch(ctx context.Context, req dto.BranchRequest) (*models.Branch, error)
+	GetBranch(ctx context.Context, id uint) (*models.Branch, error)
+	// UpdateBranch replaces a branch still at version; 0 skips the check.
+	UpdateBranch(ctx context.Context, id, version uint, req dto.BranchRequest) (*models.Branch, error)
+	// DeleteBranch removes a branch no copy or transfer ever involved.
+	DeleteBranch(ctx context.Context, id, version uint) error
+	ListBranches(ctx context.Context, search string, req query.PageRequest) ([]models.Branch, query.PageInfo, error)
+}
+
+type branchService struct {
+	branchRepo repository.BranchRepository
+}
+
+func NewBranchService(branchRepo repository.BranchRepository) BranchService {
+	return &branchService{branchRepo: branchRepo}
+}
+
+func (s *branchService) CreateBranch(ctx context.Context, req dto.BranchRequest) (*models.Branch, error) {
+	branch := &models.Branch{}
+	if err := s.applyBranch(ctx, branch, req); err != nil {
+		return nil, err
+	}
+	if err := s.branchRepo.Create(ctx, branch); err != nil {
+		return nil, apperror.Internal("failed to create branch", err)
+	}
+	return branch, nil
+}
+
+func (s *branchService) GetBranch(ctx context.Context, id uint) (*models.Branch, error) {
+	branch, err := s.branchRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "branch")
+	}
+	return branch, nil
+}
+
+func (s *branchService) UpdateBranch(ctx context.Context, id, version uint, req dto.BranchRequest) (*models.Branch, error) {
+	branch, err := s.GetBranch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion("branch", branch.Version, version); err != nil {
+		return nil, err
+	}
+	if err := s.applyBranch(ctx, branch, req); err != nil {
+		return nil, err
+	}
+	if err := s.branchRepo.Update(ctx, branch); err != nil {
+		return nil, updateError(err, "branch", "failed to update branch")
+	}
+	return branch, nil
+}
+
+// applyBranch copies req onto branch. Codes are upper case and unique.
+func (s *branchService) applyBranch(ctx context.Context, branch *models.Branch, req dto.BranchRequest) error {
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	name := strings.TrimSpace(req.Name)
+	fields := map[string]string{}
+	if code == "" {
+		fields["code"] = "is required"
+	}
+	if name == "" {
+		fields["name"] = "is required"
+	}
+	if len(fields) > 0 {
+		return apperror.Invalid("invalid branch", fields)
+	}
+	if code != branch.Code {
+		existing, err := s.branchRepo.FindByCode(ctx, code)
+		if err == nil && existing.ID != branch.ID {
+			return apperror.Conflict(fmt.Sprintf("branch code %s is already used by branch %d", code, existing.ID))
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Internal("failed to check branch code", err)
+		}
+	}
+	branch.Code = code
+	branch.Name = name
+	branch.Address = strings.TrimSpace(req.Address)
+	return nil
+}
+
+func (s *branchService) DeleteBranch(ctx context.Context, id, version uint) error {
+	branch, err := s.GetBranch(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion("branch", branch.Version, version); err != nil {
+		return err
+	}
+	inUse, err := s.branchRepo.InUse(ctx, id)
+	if err != nil {
+		return apperror.Internal("failed to check branch copies", err)
+	}
+	if inUse {
+		return apperror.Conflict("branch has copies, transfers or stocktakes")
+	}
+
+	if err := s.branchRepo.Delete(ctx, id); err != nil {
+		return apperror.Internal("failed to delete branch", err)
+	}
+	return nil
+}
+
+func (s *branchService) ListBranches(ctx context.Context, search string, req query.PageRequest) ([]models.Branch, query.PageInfo, error) {
+	branches, info, err := s.branchRepo.List(ctx, search, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list branches")
+	}
+	return branches, info, nil
+}
+
+// checkBranch makes sure the branch a request names in field exists.
+func checkBranch(ctx context.Context, branchRepo repository.BranchRepository, field string, id uint) error {
+	if _, err := branchRepo.FindByID(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Invalid("invalid branch", map[string]string{field: fmt.Sprintf("branch %d does not exist", id)})
+		}
+		return apperror.Internal("failed to check branch", err)
+	}
+	return nil
+}
+
+// branchIDOf is the branch of a request: 0 names no branch.
+func branchIDOf(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,321 @@
+// internal/service/copy_service.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"gorm.io/gorm"
+)
+
+// CopyService registers the copies of books at their branches and moves them
+// between branches. A book's totals stay authoritative: copies not yet
+// registered are unassigned and circulate without a branch.
+type CopyService interface {
+	// AddCopy registers a copy of a book at a branch. A new copy is added to
+	// the book's stock, which is recorded in its history as made by actor.
+	AddCopy(ctx context.Context, actor dto.Actor, bookID uint, req dto.AddCopyRequest) (*models.Copy, error)
+	ListCopies(ctx context.Context, bookID uint) ([]models.Copy, error)
+
+	// RequestTransfer asks for an available copy to be sent to a branch.
+	RequestTransfer(ctx context.Context, userID uint, req dto.TransferRequest) (*models.Transfer, error)
+	GetTransfer(ctx context.Context, id uint) (*models.Transfer, error)
+	// ShipTransfer takes the copy of a requested transfer off the shelf and
+	// puts it in transit.
+	ShipTransfer(ctx context.Context, id uint) (*models.Transfer, error)
+	// ReceiveTransfer checks a copy in at its destination, where it is set
+	// aside for a hold or goes on the shelf.
+	ReceiveTransfer(ctx context.Context, id uint) (*models.Transfer, error)
+	// CancelTransfer cancels a transfer that was not shipped yet.
+	CancelTransfer(ctx context.Context, id uint) (*models.Transfer, error)
+	// ListTransfers lists the transfers with status, or the open ones when
+	// status is empty, from or to a branch when branchID is set, oldest
+	// first.
+	ListTransfers(ctx context.Context, status string, branchID uint, req query.PageRequest) ([]models.Transfer, query.PageInfo, error)
+}
+
+type copyService struct {
+	db           *gorm.DB
+	copyRepo     repository.CopyRepository
+	transferRepo repository.TransferRepository
+	branchRepo   repository.BranchRepository
+	bookRepo     repository.BookRepository
+	revisionRepo repository.BookRevisionRepository
+	holdRepo     repository.HoldRepository
+}
+
+func NewCopyService(
+	db *gorm.DB,
+	copyRepo repository.CopyRepository,
+	transferRepo repository.TransferRepository,
+	branchRepo repository.BranchRepository,
+	bookRepo repository.BookRepository,
+	revisionRepo repository.BookRevisionRepository,
+	holdRepo repository.HoldRepository,
+) CopyService {
+	return &copyService{
+		db:           db,
+		copyRepo:     copyRepo,
+		transferRepo: transferRepo,
+		branchRepo:   branchRepo,
+		bookRepo:     bookRepo,
+		revisionRepo: revisionRepo,
+		holdRepo:     holdRepo,
+	}
+}
+
+func (s *copyService) AddCopy(ctx context.Context, actor dto.Actor, bookID uint, req dto.AddCopyRequest) (*models.Copy, error) {
+	barcode := strings.TrimSpace(req.Barcode)
+	if barcode == "" {
+		return nil, apperror.Invalid("invalid copy", map[string]string{"barcode": "is required"})
+	}
+
+	item := &models.Copy{
+		BookID:        bookID,
+		Barcode:       barcode,
+		HomeBranchID:  req.BranchID,
+		BranchID:      req.BranchID,
+		Status:        models.CopyAvailable,
+		ShelfLocation: strings.TrimSpace(req.ShelfLocation),
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		copyRepoTx := s.copyRepo.WithTx(tx)
+		bookRepoTx := s.bookRepo.WithTx(tx)
+
+		book, err := bookRepoTx.FindByIDForUpdate(ctx, bookID)
+		if err != nil {
+			return lookupError(err, "book")
+		}
+		if err := checkBranch(ctx, s.branchRepo.WithTx(tx), "branch_id", req.BranchID); err != nil {
+			return err
+		}
+		existing, err := copyRepoTx.FindByBarcode(ctx, barcode)
+		if err == nil {
+			return apperror.Conflict(fmt.Sprintf("barcode %s is already used by copy %d", barcode, existing.ID))
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Internal("failed to check barcode", err)
+		}
+
+		if req.New {
+			before := bookDocument(book)
+			book.TotalCopies++
+			book.AvailableCopies++
+			if err := saveBookChange(ctx, bookRepoTx, s.revisionRepo.WithTx(tx), book, before, actor); err != nil {
+				return err
+			}
+		} else {
+			total, available, err := copyRepoTx.CountByBook(ctx, bookID)
+			if err != nil {
+				return apperror.Internal("failed to count copies", err)
+			}
+			if total >= int64(book.TotalCopies) {
+				return apperror.Conflict("every copy of this book is registered; add a new one instead")
+			}
+			if available >= int64(book.AvailableCopies) {
+				return apperror.Conflict("no unassigned copy of this book is on the shelf")
+			}
+		}
+
+		if err := copyRepoTx.Create(ctx, item); err != nil {
+			return apperror.Internal("failed to create copy", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *copyService) ListCopies(ctx context.Context, bookID uint) ([]models.Copy, error) {
+	if _, err := s.bookRepo.FindByID(ctx, bookID); err != nil {
+		return nil, lookupError(err, "book")
+	}
+	copies, err := s.copyRepo.ListByBook(ctx, bookID)
+	if err != nil {
+		return nil, apperror.Internal("failed to list copies", err)
+	}
+	return copies, nil
+}
+
+func (s *copyService) RequestTransfer(ctx context.Context, userID uint, req dto.TransferRequest) (*models.Transfer, error) {
+	var transfer *models.Transfer
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transferRepoTx := s.transferRepo.WithTx(tx)
+
+		item, err := s.copyRepo.WithTx(tx).FindByIDForUpdate(ctx, req.CopyID)
+		if err != nil {
+			return lookupError(err, "copy")
+		}
+		if err := checkBranch(ctx, s.branchRepo.WithTx(tx), "to_branch_id", req.ToBranchID); err != nil {
+			return err
+		}
+		if item.BranchID == req.ToBranchID {
+			return apperror.Invalid("invalid transfer", map[string]string{"to_branch_id": "is the branch the copy is at"})
+		}
+		if item.Status != models.CopyAvailable {
+			return apperror.Conflict("copy is " + item.Status + ", not on the shelf")
+		}
+		if _, err := transferRepoTx.FindOpen(ctx, item.ID); err == nil {
+			return apperror.Conflict("copy already has an open transfer")
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Internal("failed to check open transfers", err)
+		}
+
+		transfer = &models.Transfer{
+			CopyID:       item.ID,
+			FromBranchID: item.BranchID,
+			ToBranchID:   req.ToBranchID,
+			Status:       models.TransferRequested,
+			Reason:       models.TransferRequest,
+			Permanent:    req.Permanent,
+			Note:         strings.TrimSpace(req.Note),
+			RequestedBy:  userID,
+		}
+		if err := transferRepoTx.Create(ctx, transfer); err != nil {
+			return apperror.Internal("failed to create transfer", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTransfer(ctx, transfer.ID)
+}
+
+func (s *copyService) GetTransfer(ctx context.Context, id uint) (*models.Transfer, error) {
+	transfer, err := s.transferRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "transfer")
+	}
+	return transfer, nil
+}
+
+func (s *copyService) ShipTransfer(ctx context.Context, id uint) (*models.Transfer, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transferRepoTx := s.transferRepo.WithTx(tx)
+		copyRepoTx := s.copyRepo.WithTx(tx)
+		bookRepoTx := s.bookRepo.WithTx(tx)
+
+		transfer, err := transferRepoTx.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return lookupError(err, "transfer")
+		}
+		if transfer.Status != models.TransferRequested {
+			return apperror.Conflict("transfer is already " + transfer.Status)
+		}
+		item, err := copyRepoTx.FindByIDForUpdate(ctx, transfer.CopyID)
+		if err != nil {
+			return apperror.Internal("failed to load transferred copy", err)
+		}
+		if item.Status != models.CopyAvailable {
+			return apperror.Conflict("copy is " + item.Status + ", not on the shelf")
+		}
+		book, err := bookRepoTx.FindByIDForUpdate(ctx, item.BookID)
+		if err != nil {
+			return lookupError(err, "book")
+		}
+		if err := book.Borrow(); err != nil {
+			return apperror.Conflict("book stock is inconsistent")
+		}
+		if err := bookRepoTx.Update(ctx, book); err != nil {
+			return apperror.Internal("failed to update book", err)
+		}
+
+		item.Status = models.CopyInTransit
+		if err := copyRepoTx.Update(ctx, item); err != nil {
+			return apperror.Internal("failed to update copy", err)
+		}
+		now := time.Now()
+		transfer.Status = models.TransferInTransit
+		transfer.ShippedAt = &now
+		if err := transferRepoTx.Update(ctx, transfer); err != nil {
+			return apperror.Internal("failed to update transfer", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTransfer(ctx, id)
+}
+
+func (s *copyService) ReceiveTransfer(ctx context.Context, id uint) (*models.Transfer, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transferRepoTx := s.transferRepo.WithTx(tx)
+		copyRepoTx := s.copyRepo.WithTx(tx)
+		bookRepoTx := s.bookRepo.WithTx(tx)
+
+		transfer, err := transferRepoTx.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return lookupError(err, "transfer")
+		}
+		if transfer.Status != models.TransferInTransit {
+			return apperror.Conflict("transfer is " + transfer.Status + ", not in transit")
+		}
+		item, err := copyRepoTx.FindByIDForUpdate(ctx, transfer.CopyID)
+		if err != nil {
+			return apperror.Internal("failed to load transferred copy", err)
+		}
+		book, err := bookRepoTx.FindByIDForUpdate(ctx, item.BookID)
+		if err != nil {
+			return lookupError(err, "book")
+		}
+
+		now := time.Now()
+		transfer.Status = models.TransferReceived
+		transfer.ReceivedAt = &now
+		if err := transferRepoTx.Update(ctx, transfer); err != nil {
+			return apperror.Internal("failed to update transfer", err)
+		}
+		item.BranchID = transfer.ToBranchID
+		if transfer.Permanent {
+			item.HomeBranchID = transfer.ToBranchID
+		}
+		return shelveCopy(ctx, s.holdRepo.WithTx(tx), bookRepoTx, copyRepoTx, book, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTransfer(ctx, id)
+}
+
+func (s *copyService) CancelTransfer(ctx context.Context, id uint) (*models.Transfer, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transferRepoTx := s.transferRepo.WithTx(tx)
+
+		transfer, err := transferRepoTx.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return lookupError(err, "transfer")
+		}
+		if transfer.Status != models.TransferRequested {
+			return apperror.Conflict("only requested transfers can be cancelled; this one is " + transfer.Status)
+		}
+		transfer.Status = models.TransferCancelled
+		if err := transferRepoTx.Update(ctx, transfer); err != nil {
+			return apperror.Internal("failed to cancel transfer", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTransfer(ctx, id)
+}
+
+func (s *copyService) ListTransfers(ctx context.Context, status string, branchID uint, req query.PageRequest) ([]models.Transfer, query.PageInfo, error) {
+	transfers, info, err := s.transferRepo.List(ctx, repository.TransferFilter{Status: status, BranchID: branchID}, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list transfers")
+	}
+	return transfers, info, nil
+}
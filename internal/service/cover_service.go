@@ -0,0 +1,226 @@
+// internal/service/cover_service.go
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/storage"
+	"github.com/alpardfm/library-management-api/pkg/thumbnail"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultMaxCoverSize bounds an uploaded cover image when no limit is set.
+const DefaultMaxCoverSize = 5 << 20
+
+// coverTypes are the sniffed media types accepted as covers.
+var coverTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// CoverImage is a stored cover rendition: Redirect is set when the storage
+// serves it to clients directly, otherwise Body holds it.
+type CoverImage struct {
+	Body        io.ReadCloser
+	ContentType string
+	Version     string
+	Redirect    string
+}
+
+type CoverService interface {
+	// SetCover stores an uploaded image as the cover of a book, along with
+	// its thumbnails, and replaces the previous cover.
+	SetCover(ctx context.Context, bookID, version uint, body io.Reader) (*models.Book, error)
+	DeleteCover(ctx context.Context, bookID, version uint) error
+	// GetCover opens the original cover, or the thumbnail of size.
+	GetCover(ctx context.Context, bookID uint, size string) (*CoverImage, error)
+	// MaxSize is the largest cover upload accepted, in bytes.
+	MaxSize() int64
+}
+
+type coverService struct {
+	bookRepo repository.BookRepository
+	store    storage.Storage
+	maxSize  int64
+}
+
+// NewCoverService keeps covers in store. maxSize bounds uploads; zero uses
+// DefaultMaxCoverSize.
+func NewCoverService(bookRepo repository.BookRepository, store storage.Storage, maxSize int64) CoverService {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxCoverSize
+	}
+	return &coverService{bookRepo: bookRepo, store: store, maxSize: maxSize}
+}
+
+func (s *coverService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// coverKey is where a rendition of a cover version is stored. Versions are
+// content hashes, so an upload never overwrites what clients may have cached.
+func coverKey(bookID uint, version, size string) string {
+	return fmt.Sprintf("covers/%d/%s/%s", bookID, version, size)
+}
+
+func (s *coverService) SetCover(ctx context.Context, bookID, version uint, body io.Reader) (*models.Book, error) {
+	data, err := io.ReadAll(io.LimitReader(body, s.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, apperror.BadRequest("cover image is empty")
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, apperror.TooLarge(fmt.Sprintf("cover image exceeds %d bytes", s.maxSize))
+	}
+
+	// Trust the bytes, not the declared content type.
+	contentType := http.DetectContentType(data)
+	if !coverTypes[contentType] {
+		return nil, apperror.Unsupported("cover must be a JPEG, PNG or GIF image")
+	}
+
+	book, err := s.bookRepo.FindByID(ctx, bookID)
+	if err != nil {
+		return nil, lookupError(err, "book")
+	}
+	if err := checkVersion("book", book.Version, version); err != nil {
+		return nil, err
+	}
+
+	img, _, err := thumbnail.Decode(data)
+	if errors.Is(err, thumbnail.ErrTooLarge) {
+		return nil, apperror.BadRequest(fmt.Sprintf("cover image must be at most %d pixels on a side and %d pixels in total",
+			thumbnail.MaxSide, thumbnail.MaxPixels))
+	}
+	if err != nil {
+		return nil, apperror.BadRequest("cover image cannot be decoded")
+	}
+
+	sum := sha256.Sum256(data)
+	coverVersion := hex.EncodeToString(sum[:8])
+	if coverVersion == book.CoverVersion {
+		return book, nil
+	}
+
+	renditions := map[string][]byte{models.CoverOriginal: data}
+	for size, width := range models.CoverWidths {
+		var buf bytes.Buffer
+		if err := thumbnail.WriteJPEG(&buf, thumbnail.Fit(img, width)); err != nil {
+			return nil, apperror.Internal("failed to create cover thumbnail", err)
+		}
+		renditions[size] = buf.Bytes()
+	}
+
+	for size, rendition := range renditions {
+		renditionType := "image/jpeg"
+		if size == models.CoverOriginal {
+			renditionType = contentType
+		}
+		err := s.store.Put(ctx, coverKey(bookID, coverVersion, size), bytes.NewReader(rendition), int64(len(rendition)), renditionType)
+		if err != nil {
+			s.removeCover(ctx, bookID, coverVersion)
+			return nil, apperror.Internal("failed to store cover", err)
+		}
+	}
+
+	if err := s.bookRepo.UpdateCover(ctx, bookID, book.Version, coverVersion, contentType); err != nil {
+		s.removeCover(ctx, bookID, coverVersion)
+		return nil, updateError(err, "book", "failed to update book cover")
+	}
+	if book.CoverVersion != "" {
+		s.removeCover(ctx, bookID, book.CoverVersion)
+	}
+
+	updated, err := s.bookRepo.FindByID(ctx, bookID)
+	if err != nil {
+		return nil, lookupError(err, "book")
+	}
+	return updated, nil
+}
+
+func (s *coverService) DeleteCover(ctx context.Context, bookID, version uint) error {
+	book, err := s.bookRepo.FindByID(ctx, bookID)
+	if err != nil {
+		return lookupError(err, "book")
+	}
+	if err := checkVersion("book", book.Version, version); err != nil {
+		return err
+	}
+	if book.CoverVersion == "" {
+		return apperror.NotFound("cover")
+	}
+
+	if err := s.bookRepo.UpdateCover(ctx, bookID, book.Version, "", ""); err != nil {
+		return updateError(err, "book", "failed to remove book cover")
+	}
+	s.removeCover(ctx, bookID, book.CoverVersion)
+	return nil
+}
+
+func (s *coverService) GetCover(ctx context.Context, bookID uint, size string) (*CoverImage, error) {
+	if size == "" {
+		size = models.CoverOriginal
+	}
+	if _, ok := models.CoverWidths[size]; !ok && size != models.CoverOriginal {
+		return nil, apperror.NotFound("cover size")
+	}
+
+	book, err := s.bookRepo.FindByID(ctx, bookID)
+	if err != nil {
+		return nil, lookupError(err, "book")
+	}
+	if book.CoverVersion == "" {
+		return nil, apperror.NotFound("cover")
+	}
+
+	image := &CoverImage{ContentType: "image/jpeg", Version: book.CoverVersion}
+	if size == models.CoverOriginal {
+		image.ContentType = book.CoverType
+	}
+
+	key := coverKey(bookID, book.CoverVersion, size)
+	if url := s.store.URL(key); url != "" {
+		image.Redirect = url
+		return image, nil
+	}
+	image.Body, err = s.store.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, apperror.NotFound("cover")
+	}
+	if err != nil {
+		return nil, apperror.Internal("failed to read cover", err)
+	}
+	return image, nil
+}
+
+// removeCover deletes the stored renditions of a cover version. Failures only
+// leave unreferenced files behind, so they are logged rather than returned.
+func (s *coverService) removeCover(ctx context.Context, bookID uint, version string) {
+	removeCoverFiles(ctx, s.store, bookID, version)
+}
+
+func removeCoverFiles(ctx context.Context, store storage.Storage, bookID uint, version string) {
+	ctx = context.WithoutCancel(ctx)
+	sizes := []string{models.CoverOriginal}
+	for size := range models.CoverWidths {
+		sizes = append(sizes, size)
+	}
+	for _, size := range sizes {
+		if err := store.Delete(ctx, coverKey(bookID, version, size)); err != nil {
+			log.Warn().Err(err).Uint("book_id", bookID).Str("cover_version", version).Msg("failed to delete cover image")
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// internal/service/errors.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+)
+
+// lookupError maps a failed repository lookup to a not-found error, unless the
+// lookup failed because the request was cancelled or ran out of time.
+func lookupError(err error, resource string) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return apperror.NotFound(resource)
+}
+
+// listError maps a failed listing: a cursor that does not fit the list is a
+// bad request, anything else an internal error described by message.
+func listError(err error, message string) error {
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return apperror.BadRequest("invalid cursor query")
+	}
+	return apperror.Internal(message, err)
+}
+
+// checkVersion rejects a change to resource, now at current, that was made
+// from an older version. Version 0 skips the check.
+func checkVersion(resource string, current, version uint) error {
+	if version != 0 && version != current {
+		return apperror.PreconditionFailed(fmt.Sprintf("%s has changed since version %d; fetch it again", resource, version))
+	}
+	return nil
+}
+
+// updateError maps a failed update: a row written since it was read is a
+// failed precondition, anything else an internal error described by message.
+func updateError(err error, resource, message string) error {
+	if errors.Is(err, repository.ErrStaleVersion) {
+		return apperror.PreconditionFailed(fmt.Sprintf("%s was changed by someone else; fetch it again", resource))
+	}
+	return apperror.Internal(message, err)
+}
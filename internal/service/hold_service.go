@@ -0,0 +1,220 @@
+// internal/service/hold_service.go
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"gorm.io/gorm"
+)
+
+// HoldService queues members for books that are out. A returned copy is set
+// aside for the oldest waiting hold it can fill, on the book or on any
+// edition of its work, and only that member can borrow it.
+type HoldService interface {
+	// PlaceHold queues a user for a book or for any edition of a work. Holds
+	// are only taken while no copy is on the shelf.
+	PlaceHold(ctx context.Context, userID uint, req dto.PlaceHoldRequest) (*models.Hold, error)
+	// CancelHold cancels a hold of userID, or any hold for admins and
+	// librarians. The copy set aside for a ready hold goes to the next one.
+	CancelHold(ctx context.Context, userID uint, role string, id uint) (*models.Hold, error)
+	ListUserHolds(ctx context.Context, userID uint, req query.PageRequest) ([]models.Hold, query.PageInfo, error)
+	// ListHolds lists the holds with status, or the waiting and ready ones
+	// when status is empty, oldest first.
+	ListHolds(ctx context.Context, status string, req query.PageRequest) ([]models.Hold, query.PageInfo, error)
+}
+
+type holdService struct {
+	db       *gorm.DB
+	holdRepo repository.HoldRepository
+	bookRepo repository.BookRepository
+	workRepo repository.WorkRepository
+	userRepo repository.UserRepository
+	copyRepo repository.CopyRepository
+}
+
+func NewHoldService(db *gorm.DB, holdRepo repository.HoldRepository, bookRepo repository.BookRepository, workRepo repository.WorkRepository, userRepo repository.UserRepository, copyRepo repository.CopyRepository) HoldService {
+	return &holdService{db: db, holdRepo: holdRepo, bookRepo: bookRepo, workRepo: workRepo, userRepo: userRepo, copyRepo: copyRepo}
+}
+
+func (s *holdService) PlaceHold(ctx context.Context, userID uint, req dto.PlaceHoldRequest) (*models.Hold, error) {
+	hold := &models.Hold{UserID: userID, Status: models.HoldWaiting}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		holdRepoTx := s.holdRepo.WithTx(tx)
+
+		user, err := s.userRepo.WithTx(tx).FindByIDForUpdate(ctx, userID)
+		if err != nil {
+			return lookupError(err, "user")
+		}
+		if !user.IsActive {
+			return apperror.Forbidden("user account is deactivated")
+		}
+
+		if req.WorkID != 0 {
+			work, err := s.workRepo.WithTx(tx).FindByID(ctx, req.WorkID)
+			if err != nil {
+				return lookupError(err, "work")
+			}
+			if len(work.Editions) == 0 {
+				return apperror.Conflict("work has no editions in the catalog")
+			}
+			for _, edition := range work.Editions {
+				if edition.CanBorrow() {
+					return apperror.Conflict("an edition of this work is available; borrow it instead")
+				}
+			}
+			hold.WorkID = &work.ID
+		} else {
+			book, err := s.bookRepo.WithTx(tx).FindByIDForUpdate(ctx, req.BookID)
+			if err != nil {
+				return lookupError(err, "book")
+			}
+			if book.CanBorrow() {
+				return apperror.Conflict("book has a copy available; borrow it instead")
+			}
+			hold.BookID = &book.ID
+		}
+
+		_, err = holdRepoTx.FindActive(ctx, userID, req.BookID, req.WorkID)
+		if err == nil {
+			return apperror.Conflict("user already has a hold on this title")
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Internal("failed to check active holds", err)
+		}
+
+		if err := holdRepoTx.Create(ctx, hold); err != nil {
+			return apperror.Internal("failed to place hold", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+func (s *holdService) CancelHold(ctx context.Context, userID uint, role string, id uint) (*models.Hold, error) {
+	var hold *models.Hold
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		holdRepoTx := s.holdRepo.WithTx(tx)
+
+		var err error
+		hold, err = holdRepoTx.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return lookupError(err, "hold")
+		}
+		if !canManageBorrowReturn(role) && hold.UserID != userID {
+			return apperror.Forbidden("not authorized to cancel this hold")
+		}
+		if !hold.Active() {
+			return apperror.Conflict("hold is already " + hold.Status)
+		}
+
+		wasReady := hold.Status == models.HoldReady
+		hold.Status = models.HoldCancelled
+		if err := holdRepoTx.Update(ctx, hold); err != nil {
+			return apperror.Internal("failed to cancel hold", err)
+		}
+		if !wasReady || hold.ReadyBookID == nil {
+			return nil
+		}
+
+		// The copy set aside goes to the next hold, or back on the shelf.
+		bookRepoTx := s.bookRepo.WithTx(tx)
+		book, err := bookRepoTx.FindByIDForUpdate(ctx, *hold.ReadyBookID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return apperror.Internal("failed to load held book", err)
+		}
+		var item *models.Copy
+		if hold.ReadyCopyID != nil {
+			if item, err = s.copyRepo.WithTx(tx).FindByIDForUpdate(ctx, *hold.ReadyCopyID); err != nil {
+				return apperror.Internal("failed to load held copy", err)
+			}
+		}
+		return shelveCopy(ctx, holdRepoTx, bookRepoTx, s.copyRepo.WithTx(tx), book, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+func (s *holdService) ListUserHolds(ctx context.Context, userID uint, req query.PageRequest) ([]models.Hold, query.PageInfo, error) {
+	holds, info, err := s.holdRepo.ListByUser(ctx, userID, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list holds")
+	}
+	return holds, info, nil
+}
+
+func (s *holdService) ListHolds(ctx context.Context, status string, req query.PageRequest) ([]models.Hold, query.PageInfo, error) {
+	holds, info, err := s.holdRepo.List(ctx, status, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list holds")
+	}
+	return holds, info, nil
+}
+
+// shelveCopy puts a copy of book that is back where it belongs, at its home
+// branch or at the end of a transfer, at the disposal of the library: it is
+// set aside for the oldest waiting hold or goes back on the shelf. item is
+// the copy, or nil for an unassigned one.
+func shelveCopy(ctx context.Context, holdRepo repository.HoldRepository, bookRepo repository.BookRepository, copyRepo repository.CopyRepository, book *models.Book, item *models.Copy) error {
+	offered, err := offerCopy(ctx, holdRepo, book, item)
+	if err != nil {
+		return err
+	}
+	if item != nil {
+		item.Status = models.CopyAvailable
+		if offered {
+			item.Status = models.CopyOnHold
+		}
+		if err := copyRepo.Update(ctx, item); err != nil {
+			return apperror.Internal("failed to update copy", err)
+		}
+	}
+	if offered {
+		return nil
+	}
+	book.Return()
+	if err := bookRepo.Update(ctx, book); err != nil {
+		return apperror.Internal("failed to update book", err)
+	}
+	return nil
+}
+
+// offerCopy sets a copy of book that came back aside for the oldest waiting
+// hold it can fill; item is the copy when it is a registered one. It reports
+// false when nobody is waiting, leaving the caller to put the copy back on
+// the shelf.
+func offerCopy(ctx context.Context, holdRepo repository.HoldRepository, book *models.Book, item *models.Copy) (bool, error) {
+	hold, err := holdRepo.NextWaiting(ctx, book)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, apperror.Internal("failed to find waiting holds", err)
+	}
+
+	now := time.Now()
+	hold.Status = models.HoldReady
+	hold.ReadyBookID = &book.ID
+	hold.ReadyAt = &now
+	if item != nil {
+		hold.ReadyCopyID = &item.ID
+	}
+	if err := holdRepo.Update(ctx, hold); err != nil {
+		return false, apperror.Internal("failed to update hold", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,191 @@
+// internal/service/maintenance_service.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/isbn"
+	"gorm.io/gorm"
+)
+
+const integrityBatchSize = 500
+
+// Outcomes reported by NormalizeISBNs.
+const (
+	ISBNNormalized     = "normalized"
+	ISBNWouldNormalize = "would_normalize"
+	ISBNInvalid        = "invalid"
+	ISBNDuplicate      = "duplicate"
+)
+
+type MaintenanceService interface {
+	CheckIntegrity(ctx context.Context) ([]dto.IntegrityIssue, error)
+	NormalizeISBNs(ctx context.Context, apply bool) ([]dto.ISBNNormalization, error)
+}
+
+type maintenanceService struct {
+	db           *gorm.DB
+	bookRepo     repository.BookRepository
+	revisionRepo repository.BookRevisionRepository
+	borrowRepo   repository.BorrowRepository
+}
+
+func NewMaintenanceService(db *gorm.DB, bookRepo repository.BookRepository, revisionRepo repository.BookRevisionRepository, borrowRepo repository.BorrowRepository) MaintenanceService {
+	return &maintenanceService{
+		db:           db,
+		bookRepo:     bookRepo,
+		revisionRepo: revisionRepo,
+		borrowRepo:   borrowRepo,
+	}
+}
+
+// CheckIntegrity compares every book's stock counters against its own
+// invariants and against the number of unreturned borrow records.
+func (s *maintenanceService) CheckIntegrity(ctx context.Context) ([]dto.IntegrityIssue, error) {
+	activeByBook, err := s.borrowRepo.CountActiveByBook(ctx)
+	if err != nil {
+		return nil, apperror.Internal("failed to count active borrows", err)
+	}
+
+	issues := []dto.IntegrityIssue{}
+	seen := make(map[uint]bool, len(activeByBook))
+
+	err = s.bookRepo.Each(ctx, integrityBatchSize, func(books []models.Book) error {
+		for i := range books {
+			book := &books[i]
+			seen[book.ID] = true
+
+			if err := validateBookStock(book); err != nil {
+				issues = append(issues, dto.IntegrityIssue{
+					Check:  "stock_bounds",
+					BookID: book.ID,
+					Message: fmt.Sprintf("total_copies=%d available_copies=%d",
+						book.TotalCopies, book.AvailableCopies),
+				})
+			}
+
+			if normalized, err := isbn.Normalize(book.ISBN); err != nil {
+				issues = append(issues, dto.IntegrityIssue{
+					Check:   "isbn_format",
+					BookID:  book.ID,
+					Message: fmt.Sprintf("isbn %q is invalid: %v", book.ISBN, err),
+				})
+			} else if normalized != book.ISBN {
+				issues = append(issues, dto.IntegrityIssue{
+					Check:   "isbn_format",
+					BookID:  book.ID,
+					Message: fmt.Sprintf("isbn %q should be stored as %s", book.ISBN, normalized),
+				})
+			}
+
+			borrowed := int64(book.TotalCopies - book.AvailableCopies)
+			if active := activeByBook[book.ID]; borrowed != active {
+				issues = append(issues, dto.IntegrityIssue{
+					Check:  "stock_vs_active_borrows",
+					BookID: book.ID,
+					Message: fmt.Sprintf("stock says %d copies out but %d borrow records are open",
+						borrowed, active),
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, apperror.Internal("failed to scan books", err)
+	}
+
+	for bookID, active := range activeByBook {
+		if !seen[bookID] {
+			issues = append(issues, dto.IntegrityIssue{
+				Check:   "orphaned_borrows",
+				BookID:  bookID,
+				Message: fmt.Sprintf("%d open borrow records reference a missing book", active),
+			})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].BookID < issues[j].BookID
+	})
+
+	return issues, nil
+}
+
+// NormalizeISBNs rewrites stored ISBNs that are valid but not in ISBN-13 form,
+// such as rows created before ISBN validation. Invalid ISBNs and rows whose
+// ISBN-13 already belongs to another book are reported and left untouched.
+// Without apply nothing is written; applied changes are recorded in each
+// book's history as made with libctl.
+func (s *maintenanceService) NormalizeISBNs(ctx context.Context, apply bool) ([]dto.ISBNNormalization, error) {
+	results := []dto.ISBNNormalization{}
+	claimed := make(map[string]uint)
+
+	err := s.bookRepo.Each(ctx, integrityBatchSize, func(books []models.Book) error {
+		for i := range books {
+			book := &books[i]
+
+			normalized, err := isbn.Normalize(book.ISBN)
+			if err != nil {
+				results = append(results, dto.ISBNNormalization{
+					BookID: book.ID, From: book.ISBN, Status: ISBNInvalid, Message: err.Error(),
+				})
+				continue
+			}
+			if normalized == book.ISBN {
+				claimed[normalized] = book.ID
+				continue
+			}
+
+			result := dto.ISBNNormalization{BookID: book.ID, From: book.ISBN, To: normalized}
+			if ownerID, ok := claimed[normalized]; ok {
+				result.Status = ISBNDuplicate
+				result.Message = fmt.Sprintf("same ISBN as book %d", ownerID)
+				results = append(results, result)
+				continue
+			}
+			if existing, err := s.bookRepo.FindByISBN(ctx, normalized); err == nil && existing.ID != book.ID {
+				result.Status = ISBNDuplicate
+				result.Message = fmt.Sprintf("same ISBN as book %d", existing.ID)
+				results = append(results, result)
+				continue
+			}
+
+			claimed[normalized] = book.ID
+			result.Status = ISBNWouldNormalize
+			if apply {
+				if err := s.normalizeISBN(ctx, book.ID, normalized); err != nil {
+					return fmt.Errorf("update book %d: %w", book.ID, err)
+				}
+				result.Status = ISBNNormalized
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, apperror.Internal("failed to normalize ISBNs", err)
+	}
+
+	return results, nil
+}
+
+// normalizeISBN stores normalized as the ISBN of book bookID.
+func (s *maintenanceService) normalizeISBN(ctx context.Context, bookID uint, normalized string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		bookRepoTx := s.bookRepo.WithTx(tx)
+
+		book, err := bookRepoTx.FindByIDForUpdate(ctx, bookID)
+		if err != nil {
+			return lookupError(err, "book")
+		}
+		before := bookDocument(book)
+		book.ISBN = normalized
+		return saveBookChange(ctx, bookRepoTx, s.revisionRepo.WithTx(tx), book, before, dto.Actor{})
+	})
+}
@@ -0,0 +1,557 @@
+// internal/service/marc_service.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/isbn"
+	"github.com/alpardfm/library-management-api/pkg/marc"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// MARC serializations accepted for import and export.
+const (
+	FormatMARC    = "marc"
+	FormatMARCXML = "marcxml"
+)
+
+const genreMaxLength = 50
+
+var yearPattern = regexp.MustCompile(`\d{4}`)
+
+// bookFromMARC maps the bibliographic fields of rec onto a create request:
+//
+//	020 $a        ISBN (first valid one; qualifiers such as "(pbk.)" are ignored)
+//	100/110/111/700 $a  author statement (main entry)
+//	1XX/7XX $a $e $4    contributors with their roles
+//	245 $a $b     title and subtitle
+//	264 (ind2 1) or 260 $b $c  publisher and year, 008/07-10 as fallback year
+//	650 $a        genre (first subject heading)
+//	008/35-37     language
+//	leader/06-07, 008/23  item type
+//	520 $a        description
+//	852/952       one copy per holdings field, at least one
+func bookFromMARC(rec *marc.Record) dto.CreateBookRequest {
+	req := dto.CreateBookRequest{
+		ISBN:         marcISBN(rec),
+		Title:        marcTitle(rec),
+		Author:       marcAuthor(rec),
+		Description:  trimISBD(subfieldOf(rec.Field("520"), 'a')),
+		TotalCopies:  len(rec.FieldsByTag("852")) + len(rec.FieldsByTag("952")),
+		Contributors: marcContributors(rec),
+		Language:     marcLanguage(rec),
+		ItemType:     marcItemType(rec),
+	}
+	if req.TotalCopies == 0 {
+		req.TotalCopies = 1
+	}
+
+	if publication := marcPublication(rec); publication != nil {
+		req.Publisher = trimISBD(publication.Subfield('b'))
+		req.PublicationYear = yearOf(publication.Subfield('c'))
+	}
+	if req.PublicationYear == 0 {
+		if f := rec.Field("008"); f != nil && len(f.Value) >= 11 {
+			req.PublicationYear = yearOf(f.Value[7:11])
+		}
+	}
+
+	if subject := rec.Field("650"); subject != nil {
+		req.Genre = truncateRunes(trimISBD(subject.Subfield('a')), genreMaxLength)
+	}
+	return req
+}
+
+// marcLanguage returns the language code of 008/35-37, or "" when it is
+// undetermined or not a code.
+func marcLanguage(rec *marc.Record) string {
+	f := rec.Field("008")
+	if f == nil || len(f.Value) < 38 {
+		return ""
+	}
+	code := strings.ToLower(f.Value[35:38])
+	if code == "und" || strings.Trim(code, "abcdefghijklmnopqrstuvwxyz") != "" {
+		return ""
+	}
+	return code
+}
+
+// marcItemType maps the type of record (leader/06), the bibliographic level
+// (leader/07) and, for text, the form of item (008/23) onto an item type.
+// Types without a counterpart count as books.
+func marcItemType(rec *marc.Record) string {
+	if len(rec.Leader) < 8 {
+		return models.ItemTypeBook
+	}
+	switch rec.Leader[6] {
+	case 'a', 't':
+		if rec.Leader[7] == 's' || rec.Leader[7] == 'b' {
+			return models.ItemTypePeriodical
+		}
+		if f := rec.Field("008"); f != nil && len(f.Value) > 23 && strings.IndexByte("oqs", f.Value[23]) >= 0 {
+			return models.ItemTypeEbook
+		}
+	case 'i':
+		return models.ItemTypeAudiobook
+	case 'c', 'd', 'j':
+		return models.ItemTypeMusic
+	case 'e', 'f':
+		return models.ItemTypeMap
+	case 'g':
+		return models.ItemTypeVideo
+	}
+	return models.ItemTypeBook
+}
+
+// setItemType writes the leader and form-of-item codes of itemType, the
+// reverse of marcItemType.
+func setItemType(rec *marc.Record, itemType string) {
+	recordType, level, form := byte('a'), byte('m'), byte(' ')
+	switch itemType {
+	case models.ItemTypeEbook:
+		form = 'o'
+	case models.ItemTypePeriodical:
+		level = 's'
+	case models.ItemTypeAudiobook:
+		recordType = 'i'
+	case models.ItemTypeMusic:
+		recordType = 'j'
+	case models.ItemTypeMap:
+		recordType = 'e'
+	case models.ItemTypeVideo:
+		recordType = 'g'
+	}
+	if len(rec.Leader) == 24 {
+		rec.Leader = rec.Leader[:6] + string([]byte{recordType, level}) + rec.Leader[8:]
+	}
+	if f := rec.Field("008"); f != nil && len(f.Value) > 23 && recordType == 'a' {
+		f.Value = f.Value[:23] + string(form) + f.Value[24:]
+	}
+}
+
+func marcISBN(rec *marc.Record) string {
+	var first string
+	for _, f := range rec.FieldsByTag("020") {
+		value := isbnCandidate(f.Subfield('a'))
+		if value == "" {
+			continue
+		}
+		if normalized, err := isbn.Normalize(value); err == nil {
+			return normalized
+		}
+		if first == "" {
+			first = value
+		}
+	}
+	return first
+}
+
+// isbnCandidate drops qualifiers: "0306406152 (pbk.) :" becomes "0306406152".
+func isbnCandidate(value string) string {
+	value = strings.TrimSpace(value)
+	if i := strings.IndexAny(value, " ("); i >= 0 {
+		value = value[:i]
+	}
+	return value
+}
+
+func marcTitle(rec *marc.Record) string {
+	f := rec.Field("245")
+	if f == nil {
+		return ""
+	}
+	title := trimISBD(f.Subfield('a'))
+	if subtitle := trimISBD(f.Subfield('b')); subtitle != "" {
+		title += ": " + subtitle
+	}
+	return title
+}
+
+func marcAuthor(rec *marc.Record) string {
+	for _, tag := range []string{"100", "110", "111", "700"} {
+		if f := rec.Field(tag); f != nil {
+			if author := trimISBD(f.Subfield('a')); author != "" {
+				return author
+			}
+		}
+	}
+	return ""
+}
+
+// marcRelators maps MARC relator terms ($e) and codes ($4) to contributor
+// roles.
+var marcRelators = map[string]string{
+	"aut": models.RoleAuthor, "author": models.RoleAuthor,
+	"edt": models.RoleEditor, "editor": models.RoleEditor,
+	"edc": models.RoleEditor, "editor of compilation": models.RoleEditor,
+	"trl": models.RoleTranslator, "translator": models.RoleTranslator,
+	"ill": models.RoleIllustrator, "illustrator": models.RoleIllustrator,
+}
+
+// marcContributors reads the name main entry and added entries. A name
+// without relator is an author; names with only other relators (narrator,
+// publisher...) and name/title entries ($t) are skipped.
+func marcContributors(rec *marc.Record) []dto.ContributorRequest {
+	var contributors []dto.ContributorRequest
+	for _, f := range rec.Fields {
+		switch f.Tag {
+		case "100", "110", "111", "700", "710", "711":
+		default:
+			continue
+		}
+		name := trimISBD(f.Subfield('a'))
+		if name == "" || f.Subfield('t') != "" {
+			continue
+		}
+
+		var roles []string
+		for _, sf := range f.Subfields {
+			if sf.Code != 'e' && sf.Code != '4' {
+				continue
+			}
+			term := strings.ToLower(trimISBD(sf.Value))
+			if role, ok := marcRelators[term]; ok && !slices.Contains(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+		if len(roles) == 0 {
+			if f.Subfield('e') != "" || f.Subfield('4') != "" {
+				continue
+			}
+			roles = []string{models.RoleAuthor}
+		}
+		for _, role := range roles {
+			contributors = append(contributors, dto.ContributorRequest{Name: name, Role: role})
+		}
+	}
+	return contributors
+}
+
+// marcPublication prefers the RDA publication statement (264 with second
+// indicator 1) over the older 260.
+func marcPublication(rec *marc.Record) *marc.Field {
+	for _, f := range rec.FieldsByTag("264") {
+		if f.Ind2 == '1' {
+			return f
+		}
+	}
+	return rec.Field("260")
+}
+
+// trimISBD removes the trailing ISBD punctuation MARC uses between elements,
+// e.g. "Clean code :" or "Martin, Robert C.,". A final period after an
+// initial ("Robert C.") is kept.
+func trimISBD(value string) string {
+	value = strings.TrimSpace(value)
+	for {
+		trimmed := strings.TrimRight(value, " /:;,=")
+		if strings.HasSuffix(trimmed, ".") && !endsWithInitial(trimmed) && !strings.HasSuffix(trimmed, "...") {
+			trimmed = strings.TrimSuffix(trimmed, ".")
+		}
+		if trimmed == value {
+			return value
+		}
+		value = trimmed
+	}
+}
+
+func endsWithInitial(value string) bool {
+	n := len(value)
+	if n < 2 || value[n-1] != '.' {
+		return false
+	}
+	c := value[n-2]
+	return c >= 'A' && c <= 'Z' && (n == 2 || value[n-3] == ' ' || value[n-3] == '.')
+}
+
+func yearOf(value string) int {
+	year, _ := strconv.Atoi(yearPattern.FindString(value))
+	return year
+}
+
+func subfieldOf(f *marc.Field, code byte) string {
+	if f == nil {
+		return ""
+	}
+	return f.Subfield(code)
+}
+
+func truncateRunes(value string, n int) string {
+	if utf8.RuneCountInString(value) <= n {
+		return value
+	}
+	return string([]rune(value)[:n])
+}
+
+// recordForBook builds the MARC record of book. Starting from the stored
+// record keeps unmapped fields; a mapped field is only rewritten when the
+// catalog value no longer matches what the stored field maps to.
+func recordForBook(book *models.Book, stored *marc.Record) *marc.Record {
+	rec := stored
+	var current dto.CreateBookRequest
+	if rec == nil {
+		rec = marc.NewRecord()
+		rec.SetControlField("008", fixedFieldFor(book))
+	} else {
+		current = bookFromMARC(rec)
+	}
+
+	rec.SetControlField("001", strconv.FormatUint(uint64(book.ID), 10))
+	rec.SetControlField("005", book.UpdatedAt.UTC().Format("20060102150405.0"))
+
+	if current.ISBN != book.ISBN {
+		if current.ISBN != "" {
+			rec.RemoveFields("020", func(f *marc.Field) bool {
+				return marcISBN(&marc.Record{Fields: []marc.Field{*f}}) == current.ISBN
+			})
+		}
+		rec.AddField(marc.Field{Tag: "020", Ind1: ' ', Ind2: ' ', Subfields: []marc.Subfield{{Code: 'a', Value: book.ISBN}}})
+	}
+
+	if current.Author != book.Author {
+		rec.RemoveFields("100", nil)
+		if book.Author != "" {
+			rec.AddField(marc.Field{Tag: "100", Ind1: '1', Ind2: ' ', Subfields: []marc.Subfield{{Code: 'a', Value: book.Author}}})
+		}
+	}
+
+	if current.Title != book.Title {
+		ind1 := byte('0')
+		if rec.Field("100") != nil || rec.Field("110") != nil || rec.Field("111") != nil {
+			ind1 = '1'
+		}
+		rec.RemoveFields("245", nil)
+		rec.AddField(marc.Field{Tag: "245", Ind1: ind1, Ind2: '0', Subfields: []marc.Subfield{{Code: 'a', Value: book.Title}}})
+	}
+
+	if current.Publisher != book.Publisher || current.PublicationYear != book.PublicationYear {
+		publication := marcPublication(rec)
+		if publication == nil {
+			rec.AddField(marc.Field{Tag: "264", Ind1: ' ', Ind2: '1'})
+			publication = marcPublication(rec)
+		}
+		setOrRemoveSubfield(publication, 'b', book.Publisher)
+		year := ""
+		if book.PublicationYear > 0 {
+			year = strconv.Itoa(book.PublicationYear)
+		}
+		setOrRemoveSubfield(publication, 'c', year)
+	}
+
+	if current.Genre != book.Genre {
+		if subject := rec.Field("650"); subject != nil && book.Genre != "" {
+			subject.Subfields = []marc.Subfield{{Code: 'a', Value: book.Genre}}
+		} else if subject != nil {
+			rec.RemoveFields("650", func(f *marc.Field) bool { return f == subject })
+		} else if book.Genre != "" {
+			rec.AddField(marc.Field{Tag: "650", Ind1: ' ', Ind2: '4', Subfields: []marc.Subfield{{Code: 'a', Value: book.Genre}}})
+		}
+	}
+
+	if current.Language != book.Language {
+		if f := rec.Field("008"); f != nil && len(f.Value) >= 38 {
+			language := book.Language
+			if language == "" {
+				language = "und"
+			}
+			f.Value = f.Value[:35] + language + f.Value[38:]
+		}
+	}
+
+	if current.ItemType != book.ItemType {
+		setItemType(rec, book.ItemType)
+	}
+
+	if current.Description != book.Description {
+		rec.RemoveFields("520", nil)
+		if book.Description != "" {
+			rec.AddField(marc.Field{Tag: "520", Ind1: ' ', Ind2: ' ', Subfields: []marc.Subfield{{Code: 'a', Value: book.Description}}})
+		}
+	}
+	return rec
+}
+
+func setOrRemoveSubfield(f *marc.Field, code byte, value string) {
+	if value != "" {
+		f.SetSubfield(code, value)
+		return
+	}
+	kept := f.Subfields[:0]
+	for _, sf := range f.Subfields {
+		if sf.Code != code {
+			kept = append(kept, sf)
+		}
+	}
+	f.Subfields = kept
+}
+
+// fixedFieldFor builds a minimal 008 for a record created from the catalog:
+// date entered, a single publication date and the language, undetermined when
+// the book has none.
+func fixedFieldFor(book *models.Book) string {
+	year := "    "
+	if book.PublicationYear > 0 {
+		year = fmt.Sprintf("%04d", book.PublicationYear)
+	}
+	language := book.Language
+	if language == "" {
+		language = "und"
+	}
+	entered := book.CreatedAt.UTC().Format("060102")
+	return entered + "s" + year + "    " + "xx " + strings.Repeat(" ", 17) + language + " d"
+}
+
+// decodeBookImportMARC reads ISO 2709 or MARCXML records. Lines count records,
+// starting at 1.
+func decodeBookImportMARC(r io.Reader, format string) ([]BookImportRow, error) {
+	var read func() (*marc.Record, error)
+	if format == FormatMARCXML {
+		read = marc.NewXMLReader(r).Read
+	} else {
+		read = marc.NewReader(r).Read
+	}
+
+	var rows []BookImportRow
+	for line := 1; ; line++ {
+		rec, err := read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if errors.Is(err, marc.ErrMalformed) || errors.Is(err, marc.ErrUnsupportedEncoding) {
+			rows = append(rows, BookImportRow{Line: line, Err: err})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s record %d: %w", format, line, err)
+		}
+		rows = append(rows, BookImportRow{Line: line, Book: bookFromMARC(rec), Marc: rec})
+	}
+}
+
+type MarcExportOptions struct {
+	Format string
+	// Filter selects the books as on ListBooks; CollapseWorks is ignored.
+	Filter    dto.BookListFilter
+	Sort      string
+	BatchSize int
+}
+
+type MarcService interface {
+	// GetBookRecord returns the MARC record of a book, built from its stored
+	// record when it was imported from MARC.
+	GetBookRecord(ctx context.Context, bookID uint) (*marc.Record, error)
+	// ExportRecords streams the records of the books matching opts to w and
+	// returns how many were written.
+	ExportRecords(ctx context.Context, w io.Writer, opts MarcExportOptions) (int, error)
+}
+
+type marcService struct {
+	db       *gorm.DB
+	bookRepo repository.BookRepository
+	marcRepo repository.MarcRecordRepository
+}
+
+func NewMarcService(db *gorm.DB, bookRepo repository.BookRepository, marcRepo repository.MarcRecordRepository) MarcService {
+	return &marcService{db: db, bookRepo: bookRepo, marcRepo: marcRepo}
+}
+
+func (s *marcService) GetBookRecord(ctx context.Context, bookID uint) (*marc.Record, error) {
+	book, err := s.bookRepo.FindByID(ctx, bookID)
+	if err != nil {
+		return nil, lookupError(err, "book")
+	}
+
+	stored, err := s.marcRepo.FindByBookID(ctx, bookID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.Internal("failed to load MARC record", err)
+	}
+
+	var raw *models.MarcRecord
+	if err == nil {
+		raw = stored
+	}
+	return recordForBook(book, parseStoredRecord(raw)), nil
+}
+
+func (s *marcService) ExportRecords(ctx context.Context, w io.Writer, opts MarcExportOptions) (int, error) {
+	if opts.BatchSize == 0 {
+		opts.BatchSize = DefaultExportBatchSize
+	}
+
+	var write func(*marc.Record) error
+	var flush, finish func() error
+	switch opts.Format {
+	case FormatMARCXML:
+		xw := marc.NewXMLWriter(w)
+		write, flush, finish = xw.Write, xw.Flush, xw.Close
+	case FormatMARC:
+		mw := marc.NewWriter(w)
+		write = mw.Write
+		flush = func() error { return nil }
+		finish = flush
+	default:
+		return 0, apperror.BadRequest(fmt.Sprintf("unsupported MARC format %q (use marc or marcxml)", opts.Format))
+	}
+
+	written := 0
+	err := s.bookRepo.Scan(ctx, exportFilter(s.db, opts.Filter), opts.Sort, opts.BatchSize, func(books []models.Book) error {
+		ids := make([]uint, len(books))
+		for i := range books {
+			ids[i] = books[i].ID
+		}
+		stored, err := s.marcRepo.FindByBookIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		byBook := make(map[uint]*models.MarcRecord, len(stored))
+		for i := range stored {
+			byBook[stored[i].BookID] = &stored[i]
+		}
+
+		for i := range books {
+			if err := write(recordForBook(&books[i], parseStoredRecord(byBook[books[i].ID]))); err != nil {
+				return fmt.Errorf("book %d: %w", books[i].ID, err)
+			}
+			written++
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+	return written, finish()
+}
+
+// parseStoredRecord decodes a stored record. A record that no longer parses is
+// logged and ignored so the book can still be exported from catalog fields.
+func parseStoredRecord(stored *models.MarcRecord) *marc.Record {
+	if stored == nil {
+		return nil
+	}
+	rec, err := marc.UnmarshalXML([]byte(stored.Record))
+	if err != nil {
+		log.Warn().Err(err).Uint("book_id", stored.BookID).Msg("ignoring unreadable stored MARC record")
+		return nil
+	}
+	return rec
+}
@@ -0,0 +1,121 @@
+// internal/service/retention_service.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/storage"
+	"gorm.io/gorm"
+)
+
+// DefaultRetentionPeriod is how long withdrawn books and deleted users are
+// kept before they are purged.
+const DefaultRetentionPeriod = 365 * 24 * time.Hour
+
+type RetentionService interface {
+	// Purge deletes the books removed longer than the retention period ago,
+	// with their revisions, and anonymizes the users deleted as long ago.
+	// Borrow records of a purged book are kept, detached from it with its
+	// title and ISBN. Without apply it only reports what it would do.
+	Purge(ctx context.Context, apply bool) (*dto.PurgeReport, error)
+}
+
+type retentionService struct {
+	db           *gorm.DB
+	bookRepo     repository.BookRepository
+	revisionRepo repository.BookRevisionRepository
+	borrowRepo   repository.BorrowRepository
+	userRepo     repository.UserRepository
+	store        storage.Storage
+	period       time.Duration
+	now          func() time.Time
+}
+
+func NewRetentionService(db *gorm.DB, bookRepo repository.BookRepository, revisionRepo repository.BookRevisionRepository, borrowRepo repository.BorrowRepository, userRepo repository.UserRepository, store storage.Storage, period time.Duration) RetentionService {
+	if period <= 0 {
+		period = DefaultRetentionPeriod
+	}
+	return &retentionService{
+		db:           db,
+		bookRepo:     bookRepo,
+		revisionRepo: revisionRepo,
+		borrowRepo:   borrowRepo,
+		userRepo:     userRepo,
+		store:        store,
+		period:       period,
+		now:          time.Now,
+	}
+}
+
+func (s *retentionService) Purge(ctx context.Context, apply bool) (*dto.PurgeReport, error) {
+	now := s.now()
+	report := &dto.PurgeReport{
+		Cutoff:          now.Add(-s.period),
+		Applied:         apply,
+		PurgedBooks:     []uint{},
+		AnonymizedUsers: []uint{},
+	}
+
+	books, err := s.bookRepo.RemovedBefore(ctx, report.Cutoff)
+	if err != nil {
+		return nil, apperror.Internal("failed to list removed books", err)
+	}
+	for i := range books {
+		book := &books[i]
+		if !apply {
+			borrows, err := s.borrowRepo.CountByBook(ctx, book.ID)
+			if err != nil {
+				return nil, apperror.Internal("failed to count borrows", err)
+			}
+			report.DetachedBorrows += borrows
+			report.PurgedBooks = append(report.PurgedBooks, book.ID)
+			continue
+		}
+
+		var detached int64
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if detached, err = s.borrowRepo.WithTx(tx).DetachBook(ctx, book); err != nil {
+				return apperror.Internal(fmt.Sprintf("failed to detach borrows of book %d", book.ID), err)
+			}
+			if err := s.revisionRepo.WithTx(tx).DeleteByBook(ctx, book.ID); err != nil {
+				return apperror.Internal(fmt.Sprintf("failed to purge history of book %d", book.ID), err)
+			}
+			if err := s.bookRepo.WithTx(tx).Purge(ctx, book.ID); err != nil {
+				return apperror.Internal(fmt.Sprintf("failed to purge book %d", book.ID), err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		report.DetachedBorrows += detached
+		if book.CoverVersion != "" && s.store != nil {
+			removeCoverFiles(ctx, s.store, book.ID, book.CoverVersion)
+		}
+		report.PurgedBooks = append(report.PurgedBooks, book.ID)
+	}
+
+	users, err := s.userRepo.DeletedBefore(ctx, report.Cutoff)
+	if err != nil {
+		return nil, apperror.Internal("failed to list deleted users", err)
+	}
+	userRepo := s.userRepo.WithDeleted()
+	for i := range users {
+		user := &users[i]
+		if apply {
+			user.Anonymize(now)
+			if err := userRepo.Update(ctx, user); err != nil {
+				return nil, apperror.Internal(fmt.Sprintf("failed to anonymize user %d", user.ID), err)
+			}
+		}
+		report.AnonymizedUsers = append(report.AnonymizedUsers, user.ID)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,420 @@
+// internal/service/settings_service.go
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Runtime setting keys.
+const (
+	SettingMaxBooksPerUser = "max_books_per_user"
+	SettingBorrowDays      = "borrow_days"
+	SettingFinePerDay      = "fine_per_day"
+	SettingProcessingFee   = "processing_fee"
+)
+
+const settingTypeInteger = "integer"
+
+// CirculationSettings supplies the circulation parameters used by BorrowService.
+// BorrowServiceConfig is a static implementation; SettingsService serves the
+// live values stored in the database.
+type CirculationSettings interface {
+	Circulation(ctx context.Context) BorrowServiceConfig
+}
+
+// Circulation returns c unchanged.
+func (c BorrowServiceConfig) Circulation(context.Context) BorrowServiceConfig {
+	return c
+}
+
+type SettingsService interface {
+	CirculationSettings
+	ListSettings(ctx context.Context) ([]dto.SettingResponse, error)
+	GetSetting(ctx context.Context, key string) (*dto.SettingResponse, error)
+	// UpdateSetting and ResetSetting change a setting still at version; 0
+	// skips the check.
+	UpdateSetting(ctx context.Context, actorID uint, key string, version uint, req dto.UpdateSettingRequest) (*dto.SettingResponse, error)
+	ResetSetting(ctx context.Context, actorID uint, key string, version uint, reason string) (*dto.SettingResponse, error)
+	ListChanges(ctx context.Context, key string, req query.PageRequest) ([]models.SettingChange, query.PageInfo, error)
+}
+
+// settingDefinition describes a typed runtime setting backed by a field of
+// BorrowServiceConfig.
+type settingDefinition struct {
+	key         string
+	description string
+	min, max    int
+	field       func(*BorrowServiceConfig) *int
+}
+
+var settingDefinitions = []settingDefinition{
+	{
+		key:         SettingMaxBooksPerUser,
+		description: "Maximum number of books a member can borrow at the same time",
+		min:         1,
+		max:         100,
+		field:       func(c *BorrowServiceConfig) *int { return &c.MaxBooksPerUser },
+	},
+	{
+		key:         SettingBorrowDays,
+		description: "Loan period in days when no due date is given",
+		min:         1,
+		max:         365,
+		field:       func(c *BorrowServiceConfig) *int { return &c.BorrowDays },
+	},
+	{
+		key:         SettingFinePerDay,
+		description: "Fine charged per overdue day",
+		min:         0,
+		max:         1000000,
+		field:       func(c *BorrowServiceConfig) *int { return &c.FinePerDay },
+	},
+	{
+		key:         SettingProcessingFee,
+		description: "Fee charged with the replacement of a lost or damaged copy",
+		min:         0,
+		max:         1000000,
+		field:       func(c *BorrowServiceConfig) *int { return &c.ProcessingFee },
+	},
+}
+
+func findSettingDefinition(key string) (settingDefinition, bool) {
+	for _, def := range settingDefinitions {
+		if def.key == key {
+			return def, true
+		}
+	}
+	return settingDefinition{}, false
+}
+
+func (d settingDefinition) parse(raw string) (int, error) {
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", d.key)
+	}
+	if value < d.min || value > d.max {
+		return 0, fmt.Errorf("%s must be between %d and %d", d.key, d.min, d.max)
+	}
+	return value, nil
+}
+
+type settingsService struct {
+	db              *gorm.DB
+	settingRepo     repository.SettingRepository
+	defaults        BorrowServiceConfig
+	refreshInterval time.Duration
+
+	mu        sync.Mutex
+	loaded    bool
+	revision  int64
+	checkedAt time.Time
+	overrides map[string]models.Setting
+}
+
+// NewSettingsService serves runtime settings on top of defaults. Cached values
+// are re-checked against the database revision at most once per
+// refreshInterval, which bounds how long other replicas serve stale values.
+func NewSettingsService(
+	db *gorm.DB,
+	settingRepo repository.SettingRepository,
+	defaults BorrowServiceConfig,
+	refreshInterval time.Duration,
+) SettingsService {
+	return &settingsService{
+		db:              db,
+		settingRepo:     settingRepo,
+		defaults:        defaults,
+		refreshInterval: refreshInterval,
+	}
+}
+
+// Circulation returns the defaults with valid database overrides applied. When
+// the database cannot be reached the last known values are kept.
+func (s *settingsService) Circulation(ctx context.Context) BorrowServiceConfig {
+	config := s.defaults
+	for key, setting := range s.cachedOverrides(ctx) {
+		def, ok := findSettingDefinition(key)
+		if !ok {
+			continue
+		}
+		value, err := def.parse(setting.Value)
+		if err != nil {
+			log.Warn().Err(err).Str("setting", key).Msg("ignoring invalid stored setting")
+			continue
+		}
+		*def.field(&config) = value
+	}
+	return config
+}
+
+func (s *settingsService) cachedOverrides(ctx context.Context) map[string]models.Setting {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.loaded && time.Since(s.checkedAt) < s.refreshInterval {
+		return s.overrides
+	}
+
+	if err := s.refreshLocked(ctx); err != nil {
+		log.Warn().Err(err).Msg("failed to refresh runtime settings, using cached values")
+		// Back off until the next interval instead of retrying on every request.
+		s.checkedAt = time.Now()
+	}
+	return s.overrides
+}
+
+func (s *settingsService) refreshLocked(ctx context.Context) error {
+	revision, err := s.settingRepo.Revision(ctx)
+	if err != nil {
+		return err
+	}
+	if s.loaded && revision == s.revision {
+		s.checkedAt = time.Now()
+		return nil
+	}
+
+	settings, err := s.settingRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	overrides := make(map[string]models.Setting, len(settings))
+	for _, setting := range settings {
+		overrides[setting.Key] = setting
+	}
+
+	s.overrides = overrides
+	s.revision = revision
+	s.loaded = true
+	s.checkedAt = time.Now()
+	return nil
+}
+
+// invalidate forces the next read to reload from the database.
+func (s *settingsService) invalidate() {
+	s.mu.Lock()
+	s.loaded = false
+	s.mu.Unlock()
+}
+
+func (s *settingsService) ListSettings(ctx context.Context) ([]dto.SettingResponse, error) {
+	settings, err := s.settingRepo.List(ctx)
+	if err != nil {
+		return nil, apperror.Internal("failed to list settings", err)
+	}
+	changes, err := s.settingRepo.ChangeCounts(ctx)
+	if err != nil {
+		return nil, apperror.Internal("failed to list settings", err)
+	}
+
+	overrides := make(map[string]*models.Setting, len(settings))
+	for i := range settings {
+		overrides[settings[i].Key] = &settings[i]
+	}
+
+	responses := make([]dto.SettingResponse, 0, len(settingDefinitions))
+	for _, def := range settingDefinitions {
+		override := overrides[def.key]
+		responses = append(responses, s.toResponse(def, override, settingVersion(override, changes[def.key])))
+	}
+	return responses, nil
+}
+
+func (s *settingsService) GetSetting(ctx context.Context, key string) (*dto.SettingResponse, error) {
+	def, ok := findSettingDefinition(key)
+	if !ok {
+		return nil, apperror.NotFound("setting")
+	}
+
+	setting, version, err := findSetting(ctx, s.settingRepo, key)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.toResponse(def, setting, version)
+	return &response, nil
+}
+
+// findSetting returns the override of key, nil when there is none, and the
+// version of the setting.
+func findSetting(ctx context.Context, settingRepo repository.SettingRepository, key string) (*models.Setting, uint, error) {
+	setting, err := settingRepo.FindByKey(ctx, key)
+	if err == nil {
+		return setting, setting.Version, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, 0, apperror.Internal("failed to load setting", err)
+	}
+	changes, err := settingRepo.ChangeCounts(ctx)
+	if err != nil {
+		return nil, 0, apperror.Internal("failed to load setting", err)
+	}
+	return nil, settingVersion(nil, changes[key]), nil
+}
+
+// settingVersion is the version of an override, or of a setting at its
+// default that has been changed the given number of times.
+func settingVersion(override *models.Setting, changes int64) uint {
+	if override != nil {
+		return override.Version
+	}
+	return uint(changes) + 1
+}
+
+func (s *settingsService) UpdateSetting(ctx context.Context, actorID uint, key string, version uint, req dto.UpdateSettingRequest) (*dto.SettingResponse, error) {
+	def, ok := findSettingDefinition(key)
+	if !ok {
+		return nil, apperror.NotFound("setting")
+	}
+
+	var value int
+	if err := json.Unmarshal(req.Value, &value); err != nil {
+		return nil, apperror.BadRequest(fmt.Sprintf("%s must be an integer", key))
+	}
+	raw := strconv.Itoa(value)
+	if _, err := def.parse(raw); err != nil {
+		return nil, apperror.BadRequest(err.Error())
+	}
+
+	var updated *models.Setting
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		settingRepoTx := s.settingRepo.WithTx(tx)
+
+		existing, current, err := findSetting(ctx, settingRepoTx, key)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("setting", current, version); err != nil {
+			return err
+		}
+		if existing != nil && existing.Value == raw {
+			updated = existing
+			return nil
+		}
+
+		change := &models.SettingChange{Key: key, NewValue: &raw, ChangedBy: actorID, Reason: req.Reason}
+		if existing != nil {
+			old := existing.Value
+			change.OldValue = &old
+			updated = existing
+			updated.Value, updated.UpdatedBy, updated.UpdatedAt = raw, actorID, time.Now()
+			err = settingRepoTx.Update(ctx, updated)
+		} else {
+			updated = &models.Setting{Key: key, Value: raw, UpdatedBy: actorID, UpdatedAt: time.Now(), Version: current + 1}
+			err = settingRepoTx.Create(ctx, updated)
+		}
+		if err != nil {
+			return updateError(err, "setting", "failed to save setting")
+		}
+		if err := settingRepoTx.CreateChange(ctx, change); err != nil {
+			return apperror.Internal("failed to record setting change", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	response := s.toResponse(def, updated, updated.Version)
+	return &response, nil
+}
+
+func (s *settingsService) ResetSetting(ctx context.Context, actorID uint, key string, version uint, reason string) (*dto.SettingResponse, error) {
+	def, ok := findSettingDefinition(key)
+	if !ok {
+		return nil, apperror.NotFound("setting")
+	}
+
+	var reset uint
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		settingRepoTx := s.settingRepo.WithTx(tx)
+
+		existing, current, err := findSetting(ctx, settingRepoTx, key)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion("setting", current, version); err != nil {
+			return err
+		}
+		reset = current
+		if existing == nil {
+			return nil
+		}
+
+		if err := settingRepoTx.Delete(ctx, existing); err != nil {
+			return updateError(err, "setting", "failed to reset setting")
+		}
+
+		change := &models.SettingChange{Key: key, OldValue: &existing.Value, ChangedBy: actorID, Reason: reason}
+		if err := settingRepoTx.CreateChange(ctx, change); err != nil {
+			return apperror.Internal("failed to record setting change", err)
+		}
+		_, reset, err = findSetting(ctx, settingRepoTx, key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	response := s.toResponse(def, nil, reset)
+	return &response, nil
+}
+
+func (s *settingsService) ListChanges(ctx context.Context, key string, req query.PageRequest) ([]models.SettingChange, query.PageInfo, error) {
+	if key != "" {
+		if _, ok := findSettingDefinition(key); !ok {
+			return nil, query.PageInfo{}, apperror.NotFound("setting")
+		}
+	}
+
+	changes, info, err := s.settingRepo.ListChanges(ctx, key, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list setting changes")
+	}
+	return changes, info, nil
+}
+
+func (s *settingsService) toResponse(def settingDefinition, override *models.Setting, version uint) dto.SettingResponse {
+	defaults := s.defaults
+	defaultValue := *def.field(&defaults)
+	lo, hi := def.min, def.max
+
+	response := dto.SettingResponse{
+		Key:         def.key,
+		Type:        settingTypeInteger,
+		Description: def.description,
+		Value:       defaultValue,
+		Default:     defaultValue,
+		Min:         &lo,
+		Max:         &hi,
+		Version:     version,
+	}
+
+	if override != nil {
+		if value, err := def.parse(override.Value); err == nil {
+			response.Value = value
+			response.Overridden = true
+		}
+		updatedAt := override.UpdatedAt
+		updatedBy := override.UpdatedBy
+		response.UpdatedAt = &updatedAt
+		response.UpdatedBy = &updatedBy
+	}
+	return response
+}
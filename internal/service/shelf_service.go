@@ -0,0 +1,347 @@
+// internal/service/shelf_service.go
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/callnumber"
+)
+
+const (
+	DefaultShelfBrowse = 5
+	MaxShelfBrowse     = 50
+
+	DefaultShelfListLimit = 1000
+	MaxShelfListLimit     = 10000
+)
+
+// Shelf list formats besides JSON. HTML is a page meant to be printed.
+const (
+	ShelfListFormatCSV  = "csv"
+	ShelfListFormatHTML = "html"
+)
+
+// ShelfService finds books where they stand on the shelves: in call number
+// order, around a book or a call number, and as shelf lists.
+type ShelfService interface {
+	// BrowseBook returns the books of the same classification shelved around
+	// a book. It fails with a conflict when the book has no call number.
+	BrowseBook(ctx context.Context, id uint, req dto.ShelfBrowseRequest) (*dto.ShelfView, error)
+	// BrowseCallNumber returns the books shelved around where callNumber
+	// would stand; the classification is told from the call number when
+	// empty.
+	BrowseCallNumber(ctx context.Context, classification, callNumber string, req dto.ShelfBrowseRequest) (*dto.ShelfView, error)
+	// ShelfList lists the books in shelf order, books without a call number
+	// last. With a branch it lists the copies at home there instead.
+	ShelfList(ctx context.Context, req dto.ShelfListRequest) (*dto.ShelfList, error)
+}
+
+type shelfService struct {
+	bookRepo   repository.BookRepository
+	copyRepo   repository.CopyRepository
+	branchRepo repository.BranchRepository
+}
+
+func NewShelfService(bookRepo repository.BookRepository, copyRepo repository.CopyRepository, branchRepo repository.BranchRepository) ShelfService {
+	return &shelfService{bookRepo: bookRepo, copyRepo: copyRepo, branchRepo: branchRepo}
+}
+
+func (s *shelfService) BrowseBook(ctx context.Context, id uint, req dto.ShelfBrowseRequest) (*dto.ShelfView, error) {
+	if err := normalizeShelfBrowse(&req); err != nil {
+		return nil, err
+	}
+	book, err := s.bookRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "book")
+	}
+	if book.CallNumber == "" {
+		return nil, apperror.Conflict("book has no call number")
+	}
+
+	view, err := s.browse(ctx, book.Classification, book.CallNumber, book.ShelfKey, book.ID, req)
+	if err != nil {
+		return nil, err
+	}
+	current := bookShelfEntry(book)
+	view.Current = &current
+	return view, nil
+}
+
+func (s *shelfService) BrowseCallNumber(ctx context.Context, classification, callNumber string, req dto.ShelfBrowseRequest) (*dto.ShelfView, error) {
+	if err := normalizeShelfBrowse(&req); err != nil {
+		return nil, err
+	}
+	parsed, err := parseCallNumberParam("call_number", classification, callNumber)
+	if err != nil {
+		return nil, err
+	}
+	return s.browse(ctx, parsed.Scheme, parsed.Value, parsed.Key(), 0, req)
+}
+
+func (s *shelfService) browse(ctx context.Context, classification, callNumber, key string, id uint, req dto.ShelfBrowseRequest) (*dto.ShelfView, error) {
+	filter := repository.ShelfFilter{BranchID: req.BranchID, Classification: classification}
+	ahead, behind, err := s.bookRepo.Shelf(ctx, key, id, filter, req.Before, req.After)
+	if err != nil {
+		return nil, apperror.Internal("failed to browse the shelf", err)
+	}
+
+	view := &dto.ShelfView{
+		Classification: classification,
+		CallNumber:     callNumber,
+		BranchID:       req.BranchID,
+		Before:         make([]dto.ShelfEntry, 0, len(ahead)),
+		After:          make([]dto.ShelfEntry, 0, len(behind)),
+	}
+	// The books ahead come nearest first.
+	for i := len(ahead) - 1; i >= 0; i-- {
+		view.Before = append(view.Before, bookShelfEntry(&ahead[i]))
+	}
+	for i := range behind {
+		view.After = append(view.After, bookShelfEntry(&behind[i]))
+	}
+	return view, nil
+}
+
+func (s *shelfService) ShelfList(ctx context.Context, req dto.ShelfListRequest) (*dto.ShelfList, error) {
+	switch {
+	case req.Limit == 0:
+		req.Limit = DefaultShelfListLimit
+	case req.Limit < 0 || req.Limit > MaxShelfListLimit:
+		return nil, apperror.Invalid("invalid shelf list", map[string]string{"limit": fmt.Sprintf("must be between 1 and %d", MaxShelfListLimit)})
+	}
+
+	list := &dto.ShelfList{
+		GeneratedAt:    time.Now().UTC().Truncate(time.Second),
+		BranchID:       req.BranchID,
+		Location:       strings.TrimSpace(req.Location),
+		Classification: req.Classification,
+		Entries:        []dto.ShelfEntry{},
+	}
+	filter := repository.ShelfFilter{BranchID: req.BranchID, Location: list.Location, Classification: req.Classification}
+	// A range runs within one classification, which its bounds tell.
+	for _, bound := range []struct {
+		field string
+		value string
+		set   func(parsed callnumber.CallNumber)
+	}{
+		{"from", req.From, func(parsed callnumber.CallNumber) { list.From, filter.FromKey = parsed.Value, parsed.Key() }},
+		{"to", req.To, func(parsed callnumber.CallNumber) { list.To, filter.ToKey = parsed.Value, parsed.Limit() }},
+	} {
+		if strings.TrimSpace(bound.value) == "" {
+			continue
+		}
+		parsed, err := parseCallNumberParam(bound.field, filter.Classification, bound.value)
+		if err != nil {
+			return nil, err
+		}
+		bound.set(parsed)
+		filter.Classification, list.Classification = parsed.Scheme, parsed.Scheme
+	}
+
+	if req.BranchID != 0 {
+		branch, err := s.branchRepo.FindByID(ctx, req.BranchID)
+		if err != nil {
+			return nil, lookupError(err, "branch")
+		}
+		list.BranchCode, list.BranchName = branch.Code, branch.Name
+
+		copies, err := s.copyRepo.ShelfList(ctx, filter, req.Limit+1)
+		if err != nil {
+			return nil, apperror.Internal("failed to list the shelf", err)
+		}
+		list.Truncated = len(copies) > req.Limit
+		for i := range copies[:min(len(copies), req.Limit)] {
+			list.Entries = append(list.Entries, copyShelfEntry(&copies[i]))
+		}
+		return list, nil
+	}
+
+	books, err := s.bookRepo.ShelfList(ctx, filter, req.Limit+1)
+	if err != nil {
+		return nil, apperror.Internal("failed to list the shelf", err)
+	}
+	list.Truncated = len(books) > req.Limit
+	for i := range books[:min(len(books), req.Limit)] {
+		list.Entries = append(list.Entries, bookShelfEntry(&books[i]))
+	}
+	return list, nil
+}
+
+func normalizeShelfBrowse(req *dto.ShelfBrowseRequest) error {
+	fields := map[string]string{}
+	for field, n := range map[string]*int{"before": &req.Before, "after": &req.After} {
+		if *n < 0 || *n > MaxShelfBrowse {
+			fields[field] = fmt.Sprintf("must be between 0 and %d", MaxShelfBrowse)
+		}
+	}
+	if len(fields) > 0 {
+		return apperror.Invalid("invalid shelf browse", fields)
+	}
+	return nil
+}
+
+// parseCallNumberParam parses a call number given as field of a request. A
+// call number whose classification cannot be told is blamed on the
+// classification.
+func parseCallNumberParam(field, classification, value string) (callnumber.CallNumber, error) {
+	parsed, err := callnumber.Parse(classification, value)
+	if err != nil {
+		if errors.Is(err, callnumber.ErrNoScheme) || errors.Is(err, callnumber.ErrUnknownScheme) {
+			field = "classification"
+		}
+		return parsed, apperror.Invalid("invalid call number", map[string]string{field: err.Error()})
+	}
+	return parsed, nil
+}
+
+func bookShelfEntry(book *models.Book) dto.ShelfEntry {
+	return dto.ShelfEntry{
+		BookID:          book.ID,
+		Classification:  book.Classification,
+		CallNumber:      book.CallNumber,
+		Title:           book.Title,
+		Author:          book.Author,
+		PublicationYear: book.PublicationYear,
+		ISBN:            book.ISBN,
+		ShelfLocation:   book.ShelfLocation,
+		Available:       book.CanBorrow(),
+		TotalCopies:     book.TotalCopies,
+	}
+}
+
+func copyShelfEntry(item *models.Copy) dto.ShelfEntry {
+	entry := dto.ShelfEntry{CopyID: item.ID, Barcode: item.Barcode, Status: item.Status}
+	if item.Book != nil {
+		entry = bookShelfEntry(item.Book)
+		entry.TotalCopies = 0
+		entry.CopyID, entry.Barcode, entry.Status = item.ID, item.Barcode, item.Status
+	}
+	entry.BookID = item.BookID
+	entry.ShelfLocation = item.Location()
+	entry.Available = item.Status == models.CopyAvailable
+	return entry
+}
+
+// WriteShelfList writes list as CSV or as an HTML page to print.
+func WriteShelfList(w io.Writer, format string, list *dto.ShelfList) error {
+	switch format {
+	case ShelfListFormatCSV:
+		out := csv.NewWriter(w)
+		columns, rows := shelfListTable(list)
+		if err := out.Write(columns); err != nil {
+			return err
+		}
+		if err := out.WriteAll(rows); err != nil {
+			return err
+		}
+		out.Flush()
+		return out.Error()
+	case ShelfListFormatHTML:
+		columns, rows := shelfListTable(list)
+		return shelfListPage.Execute(w, struct {
+			List    *dto.ShelfList
+			Scope   string
+			Columns []string
+			Rows    [][]string
+		}{list, shelfListScope(list), columns, rows})
+	default:
+		return apperror.BadRequest(fmt.Sprintf("unsupported shelf list format %q (use json, csv or html)", format))
+	}
+}
+
+// shelfListTable lays list out as columns and rows: a row per copy with its
+// barcode and status for lists of a branch, else a row per book with its
+// number of copies.
+func shelfListTable(list *dto.ShelfList) ([]string, [][]string) {
+	columns := []string{"call_number", "title", "author", "publication_year", "isbn", "shelf_location"}
+	perCopy := list.BranchID != 0
+	if perCopy {
+		columns = append(columns, "barcode", "status")
+	} else {
+		columns = append(columns, "total_copies", "available")
+	}
+
+	rows := make([][]string, 0, len(list.Entries))
+	for _, entry := range list.Entries {
+		year := ""
+		if entry.PublicationYear != 0 {
+			year = strconv.Itoa(entry.PublicationYear)
+		}
+		row := []string{entry.CallNumber, entry.Title, entry.Author, year, entry.ISBN, entry.ShelfLocation}
+		if perCopy {
+			row = append(row, entry.Barcode, entry.Status)
+		} else {
+			row = append(row, strconv.Itoa(entry.TotalCopies), strconv.FormatBool(entry.Available))
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows
+}
+
+// shelfListScope describes the part of the collection list covers, such as
+// "MAIN, Stacks, lcc QA76 to QA77".
+func shelfListScope(list *dto.ShelfList) string {
+	parts := slices.DeleteFunc([]string{list.BranchCode, list.Location}, func(part string) bool { return part == "" })
+	if list.Classification != "" {
+		span := list.Classification
+		if list.From != "" {
+			span += " from " + list.From
+		}
+		if list.To != "" {
+			span += " to " + list.To
+		}
+		parts = append(parts, span)
+	}
+	if len(parts) == 0 {
+		return "Whole collection"
+	}
+	return strings.Join(parts, ", ")
+}
+
+var shelfListPage = template.Must(template.New("shelf-list").Funcs(template.FuncMap{
+	"heading": func(column string) string {
+		return strings.ReplaceAll(strings.ToUpper(column[:1])+column[1:], "_", " ")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Shelf list</title>
+<style>
+body { font-family: sans-serif; font-size: 10pt; margin: 1.5em; }
+h1 { font-size: 14pt; margin: 0 0 0.25em; }
+p { margin: 0 0 1em; color: #444; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ccc; padding: 0.2em 0.4em; text-align: left; vertical-align: top; }
+thead { display: table-header-group; }
+tr { page-break-inside: avoid; }
+td:first-child { font-family: monospace; white-space: nowrap; }
+@page { margin: 1.5cm; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Shelf list{{with .List.BranchName}}: {{.}}{{end}}</h1>
+<p>{{.Scope}}. Generated {{.List.GeneratedAt.Format "2006-01-02 15:04 MST"}}; {{len .Rows}} entries{{if .List.Truncated}}, truncated{{end}}.</p>
+<table>
+<thead><tr>{{range .Columns}}<th>{{heading .}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+</body>
+</html>
+`))
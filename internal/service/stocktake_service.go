@@ -0,0 +1,534 @@
+// internal/service/stocktake_service.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"gorm.io/gorm"
+)
+
+// StocktakeService reconciles the catalog with the shelves. Items are scanned
+// during a stocktake of a branch and compared with the copies the catalog
+// expects there; the discrepancies found are fixed from its report.
+type StocktakeService interface {
+	// StartStocktake opens a stocktake of a branch or one of its locations;
+	// each can have one open stocktake at a time.
+	StartStocktake(ctx context.Context, userID uint, req dto.StocktakeRequest) (*models.Stocktake, error)
+	GetStocktake(ctx context.Context, id uint) (*models.Stocktake, error)
+	// ListStocktakes lists the stocktakes with status, or all of them when
+	// status is empty, of a branch when branchID is set, newest first.
+	ListStocktakes(ctx context.Context, status string, branchID uint, req query.PageRequest) ([]models.Stocktake, query.PageInfo, error)
+	// Scan records a batch of item identifiers scanned by userID.
+	Scan(ctx context.Context, id, userID uint, req dto.StocktakeScanRequest) (*dto.StocktakeScanResult, error)
+	// CompleteStocktake ends the scanning of an open stocktake.
+	CompleteStocktake(ctx context.Context, id uint) (*models.Stocktake, error)
+	// CancelStocktake drops an open stocktake.
+	CancelStocktake(ctx context.Context, id uint) (*models.Stocktake, error)
+	// Report compares the items scanned with the copies expected, as the
+	// catalog has them now.
+	Report(ctx context.Context, id uint) (*dto.StocktakeReport, error)
+	// MarkMissing takes copies the report has as missing off the shelf.
+	MarkMissing(ctx context.Context, id uint, req dto.StocktakeCopiesRequest) (*dto.StocktakeReport, error)
+	// FixLocation moves misplaced and recovered copies of the report, in the
+	// catalog, to where they were scanned: the location becomes their shelf
+	// location and recovered copies go back on the shelf. Copies of another
+	// branch are sent home instead.
+	FixLocation(ctx context.Context, id uint, req dto.StocktakeCopiesRequest) (*dto.StocktakeReport, error)
+}
+
+type stocktakeService struct {
+	db            *gorm.DB
+	stocktakeRepo repository.StocktakeRepository
+	copyRepo      repository.CopyRepository
+	bookRepo      repository.BookRepository
+	branchRepo    repository.BranchRepository
+	transferRepo  repository.TransferRepository
+	holdRepo      repository.HoldRepository
+}
+
+func NewStocktakeService(
+	db *gorm.DB,
+	stocktakeRepo repository.StocktakeRepository,
+	copyRepo repository.CopyRepository,
+	bookRepo repository.BookRepository,
+	branchRepo repository.BranchRepository,
+	transferRepo repository.TransferRepository,
+	holdRepo repository.HoldRepository,
+) StocktakeService {
+	return &stocktakeService{
+		db:            db,
+		stocktakeRepo: stocktakeRepo,
+		copyRepo:      copyRepo,
+		bookRepo:      bookRepo,
+		branchRepo:    branchRepo,
+		transferRepo:  transferRepo,
+		holdRepo:      holdRepo,
+	}
+}
+
+func (s *stocktakeService) StartStocktake(ctx context.Context, userID uint, req dto.StocktakeRequest) (*models.Stocktake, error) {
+	stocktake := &models.Stocktake{
+		BranchID:  req.BranchID,
+		Location:  strings.TrimSpace(req.Location),
+		Status:    models.StocktakeOpen,
+		Note:      strings.TrimSpace(req.Note),
+		StartedBy: userID,
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stocktakeRepoTx := s.stocktakeRepo.WithTx(tx)
+
+		if err := checkBranch(ctx, s.branchRepo.WithTx(tx), "branch_id", req.BranchID); err != nil {
+			return err
+		}
+		open, err := stocktakeRepoTx.FindOpen(ctx, stocktake.BranchID, stocktake.Location)
+		if err == nil {
+			return apperror.Conflict(fmt.Sprintf("stocktake %d of this branch and location is still open", open.ID))
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Internal("failed to check open stocktakes", err)
+		}
+
+		if err := stocktakeRepoTx.Create(ctx, stocktake); err != nil {
+			return apperror.Internal("failed to create stocktake", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetStocktake(ctx, stocktake.ID)
+}
+
+func (s *stocktakeService) GetStocktake(ctx context.Context, id uint) (*models.Stocktake, error) {
+	stocktake, err := s.stocktakeRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "stocktake")
+	}
+	return stocktake, nil
+}
+
+func (s *stocktakeService) ListStocktakes(ctx context.Context, status string, branchID uint, req query.PageRequest) ([]models.Stocktake, query.PageInfo, error) {
+	stocktakes, info, err := s.stocktakeRepo.List(ctx, repository.StocktakeFilter{Status: status, BranchID: branchID}, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list stocktakes")
+	}
+	return stocktakes, info, nil
+}
+
+func (s *stocktakeService) Scan(ctx context.Context, id, userID uint, req dto.StocktakeScanRequest) (*dto.StocktakeScanResult, error) {
+	// Scanners send an item again when it is read twice; the batch keeps
+	// the first read.
+	barcodes := make([]string, 0, len(req.Barcodes))
+	seen := make(map[string]bool, len(req.Barcodes))
+	for i, barcode := range req.Barcodes {
+		barcode = strings.TrimSpace(barcode)
+		if barcode == "" {
+			return nil, apperror.Invalid("invalid scan", map[string]string{fmt.Sprintf("barcodes[%d]", i): "is required"})
+		}
+		if !seen[barcode] {
+			seen[barcode] = true
+			barcodes = append(barcodes, barcode)
+		}
+	}
+
+	result := &dto.StocktakeScanResult{Unknown: []string{}}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stocktakeRepoTx := s.stocktakeRepo.WithTx(tx)
+
+		stocktake, err := stocktakeRepoTx.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return lookupError(err, "stocktake")
+		}
+		if !stocktake.Open() {
+			return apperror.Conflict("stocktake is " + stocktake.Status + ", not open")
+		}
+		copies, err := s.copyRepo.WithTx(tx).FindByBarcodes(ctx, barcodes)
+		if err != nil {
+			return apperror.Internal("failed to look up barcodes", err)
+		}
+		copyIDs := make(map[string]uint, len(copies))
+		for _, item := range copies {
+			copyIDs[item.Barcode] = item.ID
+		}
+
+		now := time.Now()
+		scans := make([]models.StocktakeScan, 0, len(barcodes))
+		for _, barcode := range barcodes {
+			scan := models.StocktakeScan{StocktakeID: id, Barcode: barcode, ScannedBy: userID, ScannedAt: now}
+			if copyID, ok := copyIDs[barcode]; ok {
+				scan.CopyID = &copyID
+			} else {
+				result.Unknown = append(result.Unknown, barcode)
+			}
+			scans = append(scans, scan)
+		}
+		recorded, err := stocktakeRepoTx.AddScans(ctx, scans)
+		if err != nil {
+			return apperror.Internal("failed to record scans", err)
+		}
+		result.Recorded = int(recorded)
+		result.Duplicates = len(req.Barcodes) - result.Recorded
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *stocktakeService) CompleteStocktake(ctx context.Context, id uint) (*models.Stocktake, error) {
+	return s.closeStocktake(ctx, id, models.StocktakeCompleted)
+}
+
+func (s *stocktakeService) CancelStocktake(ctx context.Context, id uint) (*models.Stocktake, error) {
+	return s.closeStocktake(ctx, id, models.StocktakeCancelled)
+}
+
+// closeStocktake ends an open stocktake with status.
+func (s *stocktakeService) closeStocktake(ctx context.Context, id uint, status string) (*models.Stocktake, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stocktakeRepoTx := s.stocktakeRepo.WithTx(tx)
+
+		stocktake, err := stocktakeRepoTx.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return lookupError(err, "stocktake")
+		}
+		if !stocktake.Open() {
+			return apperror.Conflict("stocktake is " + stocktake.Status + ", not open")
+		}
+		now := time.Now()
+		stocktake.Status = status
+		stocktake.CompletedAt = &now
+		if err := stocktakeRepoTx.Update(ctx, stocktake); err != nil {
+			return apperror.Internal("failed to update stocktake", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetStocktake(ctx, id)
+}
+
+func (s *stocktakeService) Report(ctx context.Context, id uint) (*dto.StocktakeReport, error) {
+	stocktake, err := s.stocktakeRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "stocktake")
+	}
+	found, err := reconcile(ctx, s.stocktakeRepo, s.copyRepo, stocktake)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dto.StocktakeReport{
+		StocktakeID: stocktake.ID,
+		BranchID:    stocktake.BranchID,
+		Location:    stocktake.Location,
+		Status:      stocktake.Status,
+		Expected:    found.expected,
+		Scanned:     found.scanned,
+		Found:       found.found,
+		Missing:     make([]dto.StocktakeItem, 0, len(found.missing)),
+		Misplaced:   scanItems(found.misplaced),
+		Unexpected:  scanItems(found.unexpected),
+		CheckedOut:  scanItems(found.checkedOut),
+		Recovered:   scanItems(found.recovered),
+	}
+	for i := range found.missing {
+		report.Missing = append(report.Missing, stocktakeItem(found.missing[i].Barcode, &found.missing[i], nil))
+	}
+	return report, nil
+}
+
+func (s *stocktakeService) MarkMissing(ctx context.Context, id uint, req dto.StocktakeCopiesRequest) (*dto.StocktakeReport, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		copyRepoTx := s.copyRepo.WithTx(tx)
+		bookRepoTx := s.bookRepo.WithTx(tx)
+
+		stocktake, err := s.actOn(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		found, err := reconcile(ctx, s.stocktakeRepo.WithTx(tx), copyRepoTx, stocktake)
+		if err != nil {
+			return err
+		}
+		missing := make(map[uint]bool, len(found.missing))
+		for _, item := range found.missing {
+			missing[item.ID] = true
+		}
+
+		for _, copyID := range uniqueIDs(req.CopyIDs) {
+			if !missing[copyID] {
+				return apperror.Conflict(fmt.Sprintf("copy %d is not missing in this stocktake", copyID))
+			}
+			item, err := s.lockCopy(ctx, tx, copyID)
+			if err != nil {
+				return err
+			}
+			if item.Status != models.CopyAvailable {
+				return apperror.Conflict(fmt.Sprintf("copy %d is %s, not on the shelf", copyID, item.Status))
+			}
+			book, err := bookRepoTx.FindByIDForUpdate(ctx, item.BookID)
+			if err != nil {
+				return lookupError(err, "book")
+			}
+			if err := book.Borrow(); err != nil {
+				return apperror.Conflict(fmt.Sprintf("book %d has no copy on the shelf to mark missing", book.ID))
+			}
+			if err := bookRepoTx.Update(ctx, book); err != nil {
+				return apperror.Internal("failed to update book", err)
+			}
+			item.Status = models.CopyMissing
+			if err := copyRepoTx.Update(ctx, item); err != nil {
+				return apperror.Internal("failed to update copy", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Report(ctx, id)
+}
+
+func (s *stocktakeService) FixLocation(ctx context.Context, id uint, req dto.StocktakeCopiesRequest) (*dto.StocktakeReport, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		copyRepoTx := s.copyRepo.WithTx(tx)
+		bookRepoTx := s.bookRepo.WithTx(tx)
+
+		stocktake, err := s.actOn(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		found, err := reconcile(ctx, s.stocktakeRepo.WithTx(tx), copyRepoTx, stocktake)
+		if err != nil {
+			return err
+		}
+		fixable := make(map[uint]bool, len(found.misplaced)+len(found.recovered))
+		for _, scan := range found.misplaced {
+			fixable[*scan.CopyID] = true
+		}
+		for _, scan := range found.recovered {
+			fixable[*scan.CopyID] = true
+		}
+
+		for _, copyID := range uniqueIDs(req.CopyIDs) {
+			if !fixable[copyID] {
+				return apperror.Conflict(fmt.Sprintf("copy %d is neither misplaced nor recovered in this stocktake", copyID))
+			}
+			item, err := s.lockCopy(ctx, tx, copyID)
+			if err != nil {
+				return err
+			}
+			book, err := bookRepoTx.FindByIDForUpdate(ctx, item.BookID)
+			if err != nil {
+				return lookupError(err, "book")
+			}
+
+			if item.HomeBranchID != stocktake.BranchID {
+				if err := s.sendHome(ctx, tx, bookRepoTx, book, item, stocktake.BranchID); err != nil {
+					return err
+				}
+				continue
+			}
+
+			item.BranchID = stocktake.BranchID
+			if stocktake.Location != "" {
+				item.ShelfLocation = stocktake.Location
+				if strings.EqualFold(book.ShelfLocation, stocktake.Location) {
+					item.ShelfLocation = ""
+				}
+			}
+			switch item.Status {
+			case models.CopyMissing:
+				err = shelveCopy(ctx, s.holdRepo.WithTx(tx), bookRepoTx, copyRepoTx, book, item)
+			case models.CopyAvailable, models.CopyOnHold:
+				if err = copyRepoTx.Update(ctx, item); err != nil {
+					err = apperror.Internal("failed to update copy", err)
+				}
+			default:
+				err = apperror.Conflict(fmt.Sprintf("copy %d is %s", copyID, item.Status))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Report(ctx, id)
+}
+
+// sendHome sends a copy of another branch, found at branchID, back to its
+// home branch. A copy on the shelf leaves the available copies as a shipped
+// one does, and a recovered one is only back in them when it arrives.
+func (s *stocktakeService) sendHome(ctx context.Context, tx *gorm.DB, bookRepo repository.BookRepository, book *models.Book, item *models.Copy, branchID uint) error {
+	switch item.Status {
+	case models.CopyAvailable:
+		if err := book.Borrow(); err != nil {
+			return apperror.Conflict("book stock is inconsistent")
+		}
+		if err := bookRepo.Update(ctx, book); err != nil {
+			return apperror.Internal("failed to update book", err)
+		}
+	case models.CopyMissing:
+	default:
+		return apperror.Conflict(fmt.Sprintf("copy %d is %s", item.ID, item.Status))
+	}
+
+	now := time.Now()
+	item.BranchID = branchID
+	item.Status = models.CopyInTransit
+	if err := s.copyRepo.WithTx(tx).Update(ctx, item); err != nil {
+		return apperror.Internal("failed to update copy", err)
+	}
+	transfer := &models.Transfer{
+		CopyID:       item.ID,
+		FromBranchID: branchID,
+		ToBranchID:   item.HomeBranchID,
+		Status:       models.TransferInTransit,
+		Reason:       models.TransferReturn,
+		ShippedAt:    &now,
+	}
+	if err := s.transferRepo.WithTx(tx).Create(ctx, transfer); err != nil {
+		return apperror.Internal("failed to create transfer", err)
+	}
+	return nil
+}
+
+// actOn locks a stocktake whose report is to be acted on.
+func (s *stocktakeService) actOn(ctx context.Context, tx *gorm.DB, id uint) (*models.Stocktake, error) {
+	stocktake, err := s.stocktakeRepo.WithTx(tx).FindByIDForUpdate(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "stocktake")
+	}
+	if stocktake.Status == models.StocktakeCancelled {
+		return nil, apperror.Conflict("stocktake was cancelled")
+	}
+	return stocktake, nil
+}
+
+// lockCopy locks a copy acted on from a stocktake, which must not be moving
+// between branches.
+func (s *stocktakeService) lockCopy(ctx context.Context, tx *gorm.DB, id uint) (*models.Copy, error) {
+	item, err := s.copyRepo.WithTx(tx).FindByIDForUpdate(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "copy")
+	}
+	if _, err := s.transferRepo.WithTx(tx).FindOpen(ctx, id); err == nil {
+		return nil, apperror.Conflict(fmt.Sprintf("copy %d has an open transfer", id))
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.Internal("failed to check open transfers", err)
+	}
+	return item, nil
+}
+
+// reconciliation sorts the scans of a stocktake and the copies expected on
+// its shelves into the sections of its report.
+type reconciliation struct {
+	expected   int
+	scanned    int
+	found      int
+	missing    []models.Copy
+	misplaced  []models.StocktakeScan
+	unexpected []models.StocktakeScan
+	checkedOut []models.StocktakeScan
+	recovered  []models.StocktakeScan
+}
+
+// reconcile compares the scans of a stocktake with the available copies at
+// its branch in its location. Missing copies come in shelf order, scans in
+// the order they were made.
+func reconcile(ctx context.Context, stocktakeRepo repository.StocktakeRepository, copyRepo repository.CopyRepository, stocktake *models.Stocktake) (*reconciliation, error) {
+	scans, err := stocktakeRepo.Scans(ctx, stocktake.ID)
+	if err != nil {
+		return nil, apperror.Internal("failed to load scans", err)
+	}
+	shelved, err := copyRepo.ListOnShelf(ctx, stocktake.BranchID)
+	if err != nil {
+		return nil, apperror.Internal("failed to list copies on the shelf", err)
+	}
+
+	found := &reconciliation{scanned: len(scans)}
+	scanned := make(map[uint]bool, len(scans))
+	for _, scan := range scans {
+		item := scan.Copy
+		if item == nil || item.Book == nil || item.Book.Removed() || item.Status == models.CopyDamaged {
+			found.unexpected = append(found.unexpected, scan)
+			continue
+		}
+		scanned[item.ID] = true
+		switch {
+		case item.Status == models.CopyMissing:
+			found.recovered = append(found.recovered, scan)
+		case item.Status == models.CopyOnLoan || item.Status == models.CopyInTransit || item.Status == models.CopyLost:
+			found.checkedOut = append(found.checkedOut, scan)
+		case item.BranchID != stocktake.BranchID || !stocktake.Covers(item):
+			found.misplaced = append(found.misplaced, scan)
+		default:
+			found.found++
+		}
+	}
+	for _, item := range shelved {
+		if !stocktake.Covers(&item) {
+			continue
+		}
+		found.expected++
+		if !scanned[item.ID] {
+			found.missing = append(found.missing, item)
+		}
+	}
+	return found, nil
+}
+
+func scanItems(scans []models.StocktakeScan) []dto.StocktakeItem {
+	items := make([]dto.StocktakeItem, 0, len(scans))
+	for i := range scans {
+		items = append(items, stocktakeItem(scans[i].Barcode, scans[i].Copy, &scans[i].ScannedAt))
+	}
+	return items
+}
+
+// stocktakeItem describes an item of a stocktake report; item is nil for
+// barcodes of no copy.
+func stocktakeItem(barcode string, item *models.Copy, scannedAt *time.Time) dto.StocktakeItem {
+	entry := dto.StocktakeItem{Barcode: barcode, ScannedAt: scannedAt}
+	if item == nil {
+		return entry
+	}
+	entry.CopyID = item.ID
+	entry.BookID = item.BookID
+	entry.Status = item.Status
+	entry.BranchID = item.BranchID
+	entry.ShelfLocation = item.Location()
+	if item.Book != nil {
+		entry.Title = item.Book.Title
+		entry.CallNumber = item.Book.CallNumber
+	}
+	return entry
+}
+
+// uniqueIDs drops repeated IDs, keeping the first of each.
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,483 @@
+// internal/service/subject_service.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/names"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"gorm.io/gorm"
+)
+
+var errGenreMappingDryRun = errors.New("genre mapping dry run")
+
+type SubjectService interface {
+	CreateSubject(ctx context.Context, req dto.CreateSubjectRequest) (*models.Subject, error)
+	// GetSubject returns a subject with its ancestors, root first, and its
+	// direct children.
+	GetSubject(ctx context.Context, id uint) (*models.Subject, error)
+	// UpdateSubject changes a subject still at version; 0 skips the check,
+	// as it does for DeleteSubject.
+	UpdateSubject(ctx context.Context, id, version uint, req dto.UpdateSubjectRequest) (*models.Subject, error)
+	// MoveSubject moves a subject still at version, and its subtree, below
+	// parentID, or to the root when parentID is 0.
+	MoveSubject(ctx context.Context, id, version, parentID uint) (*models.Subject, error)
+	// DeleteSubject removes a subject without children or books.
+	DeleteSubject(ctx context.Context, id, version uint) error
+	// ListSubjects returns the whole taxonomy as a tree of root subjects.
+	ListSubjects(ctx context.Context) ([]models.Subject, error)
+	// ListSubjectBooks lists the books of a subject, including those of its
+	// descendants when descendants is true.
+	ListSubjectBooks(ctx context.Context, id uint, descendants bool, req query.PageRequest) ([]models.Book, query.PageInfo, error)
+	AddAlias(ctx context.Context, id uint, name string) (*models.SubjectAlias, error)
+	RemoveAlias(ctx context.Context, id, aliasID uint) error
+	// MapGenres assigns subjects to books from their free-text genre. Each
+	// distinct genre maps to the subject with that alias, or else the only
+	// subject with that name; with create, unmatched genres become new root
+	// subjects. Nothing is written unless apply is true.
+	MapGenres(ctx context.Context, apply, create bool) (*dto.GenreMappingResult, error)
+}
+
+type subjectService struct {
+	db          *gorm.DB
+	subjectRepo repository.SubjectRepository
+}
+
+func NewSubjectService(db *gorm.DB, subjectRepo repository.SubjectRepository) SubjectService {
+	return &subjectService{db: db, subjectRepo: subjectRepo}
+}
+
+func (s *subjectService) CreateSubject(ctx context.Context, req dto.CreateSubjectRequest) (*models.Subject, error) {
+	subject, err := newSubject(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	subject.Description = req.Description
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		subjectRepo := s.subjectRepo.WithTx(tx)
+
+		var parent *models.Subject
+		if req.ParentID != 0 {
+			if parent, err = subjectRepo.FindByID(ctx, req.ParentID); err != nil {
+				return lookupError(err, "parent subject")
+			}
+			subject.ParentID = &parent.ID
+		}
+		if err := checkSiblingName(ctx, subjectRepo, subject); err != nil {
+			return err
+		}
+
+		// The path includes the subject's own ID, so it is set after insert.
+		if err := subjectRepo.Create(ctx, subject); err != nil {
+			return apperror.Internal("failed to create subject", err)
+		}
+		subject.SetPath(parent)
+		if err := subjectRepo.Update(ctx, subject); err != nil {
+			return apperror.Internal("failed to create subject", err)
+		}
+
+		for _, name := range req.Aliases {
+			if _, err := addSubjectAlias(ctx, subjectRepo, subject, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetSubject(ctx, subject.ID)
+}
+
+func (s *subjectService) GetSubject(ctx context.Context, id uint) (*models.Subject, error) {
+	subject, err := s.subjectRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "subject")
+	}
+
+	ancestors, err := s.subjectRepo.FindByIDs(ctx, subject.AncestorIDs())
+	if err != nil {
+		return nil, apperror.Internal("failed to load subject ancestors", err)
+	}
+	slices.SortFunc(ancestors, func(a, b models.Subject) int { return a.Depth - b.Depth })
+	subject.Ancestors = ancestors
+
+	if subject.Children, err = s.subjectRepo.ListChildren(ctx, id); err != nil {
+		return nil, apperror.Internal("failed to load subject children", err)
+	}
+	return subject, nil
+}
+
+func (s *subjectService) UpdateSubject(ctx context.Context, id, version uint, req dto.UpdateSubjectRequest) (*models.Subject, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		subjectRepo := s.subjectRepo.WithTx(tx)
+		subject, err := subjectRepo.FindByID(ctx, id)
+		if err != nil {
+			return lookupError(err, "subject")
+		}
+		if err := checkVersion("subject", subject.Version, version); err != nil {
+			return err
+		}
+
+		if req.Name != "" {
+			renamed, err := newSubject(req.Name)
+			if err != nil {
+				return err
+			}
+			if renamed.NameKey != subject.NameKey {
+				subject.NameKey = renamed.NameKey
+				if err := checkSiblingName(ctx, subjectRepo, subject); err != nil {
+					return err
+				}
+			}
+			subject.Name = renamed.Name
+		}
+		if req.Description != "" {
+			subject.Description = req.Description
+		}
+
+		if err := subjectRepo.Update(ctx, subject); err != nil {
+			return updateError(err, "subject", "failed to update subject")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetSubject(ctx, id)
+}
+
+func (s *subjectService) MoveSubject(ctx context.Context, id, version, parentID uint) (*models.Subject, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		subjectRepo := s.subjectRepo.WithTx(tx)
+		subject, err := subjectRepo.FindByID(ctx, id)
+		if err != nil {
+			return lookupError(err, "subject")
+		}
+		if err := checkVersion("subject", subject.Version, version); err != nil {
+			return err
+		}
+
+		var parent *models.Subject
+		if parentID != 0 {
+			if parent, err = subjectRepo.FindByID(ctx, parentID); err != nil {
+				return lookupError(err, "parent subject")
+			}
+			if strings.HasPrefix(parent.Path, subject.Path) {
+				return apperror.BadRequest("cannot move a subject below itself or one of its descendants")
+			}
+		}
+
+		oldPath, oldDepth := subject.Path, subject.Depth
+		subject.SetPath(parent)
+		if subject.Path == oldPath {
+			return nil
+		}
+		if err := checkSiblingName(ctx, subjectRepo, subject); err != nil {
+			return err
+		}
+
+		if err := subjectRepo.Update(ctx, subject); err != nil {
+			return updateError(err, "subject", "failed to move subject")
+		}
+		if err := subjectRepo.MoveSubtree(ctx, oldPath, subject.Path, subject.Depth-oldDepth); err != nil {
+			return apperror.Internal("failed to move subject descendants", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetSubject(ctx, id)
+}
+
+func (s *subjectService) DeleteSubject(ctx context.Context, id, version uint) error {
+	subject, err := s.subjectRepo.FindByID(ctx, id)
+	if err != nil {
+		return lookupError(err, "subject")
+	}
+	if err := checkVersion("subject", subject.Version, version); err != nil {
+		return err
+	}
+
+	children, err := s.subjectRepo.CountChildren(ctx, id)
+	if err != nil {
+		return apperror.Internal("failed to check subject children", err)
+	}
+	if children > 0 {
+		return apperror.Conflict("subject has child subjects; move or delete them first")
+	}
+	if subject.BookCount > 0 {
+		return apperror.Conflict("subject is assigned to books")
+	}
+
+	if err := s.subjectRepo.Delete(ctx, id); err != nil {
+		return apperror.Internal("failed to delete subject", err)
+	}
+	return nil
+}
+
+func (s *subjectService) ListSubjects(ctx context.Context) ([]models.Subject, error) {
+	subjects, err := s.subjectRepo.List(ctx)
+	if err != nil {
+		return nil, apperror.Internal("failed to list subjects", err)
+	}
+	return subjectTree(subjects), nil
+}
+
+func (s *subjectService) ListSubjectBooks(ctx context.Context, id uint, descendants bool, req query.PageRequest) ([]models.Book, query.PageInfo, error) {
+	subject, err := s.subjectRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, query.PageInfo{}, lookupError(err, "subject")
+	}
+
+	books, info, err := s.subjectRepo.ListBooks(ctx, subject, descendants, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list subject books")
+	}
+	return books, info, nil
+}
+
+func (s *subjectService) AddAlias(ctx context.Context, id uint, name string) (*models.SubjectAlias, error) {
+	var alias *models.SubjectAlias
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		subjectRepo := s.subjectRepo.WithTx(tx)
+		subject, err := subjectRepo.FindByID(ctx, id)
+		if err != nil {
+			return lookupError(err, "subject")
+		}
+		alias, err = addSubjectAlias(ctx, subjectRepo, subject, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return alias, nil
+}
+
+func (s *subjectService) RemoveAlias(ctx context.Context, id, aliasID uint) error {
+	removed, err := s.subjectRepo.DeleteAlias(ctx, id, aliasID)
+	if err != nil {
+		return apperror.Internal("failed to remove subject alias", err)
+	}
+	if removed == 0 {
+		return apperror.NotFound("subject alias")
+	}
+	return nil
+}
+
+func (s *subjectService) MapGenres(ctx context.Context, apply, create bool) (*dto.GenreMappingResult, error) {
+	result := &dto.GenreMappingResult{Applied: apply, Genres: []dto.GenreMapping{}}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		subjectRepo := s.subjectRepo.WithTx(tx)
+		genres, err := subjectRepo.GenreCounts(ctx)
+		if err != nil {
+			return apperror.Internal("failed to list genres", err)
+		}
+
+		for _, genre := range genres {
+			mapping := dto.GenreMapping{Genre: genre.Genre, Books: genre.Books}
+
+			subject, reason, err := matchSubject(ctx, subjectRepo, genre.Genre)
+			if err != nil {
+				return err
+			}
+			if subject == nil && create && names.Fold(genre.Genre) != "" {
+				if subject, err = newSubject(genre.Genre); err != nil {
+					return err
+				}
+				if err := subjectRepo.Create(ctx, subject); err != nil {
+					return apperror.Internal("failed to create subject", err)
+				}
+				subject.SetPath(nil)
+				if err := subjectRepo.Update(ctx, subject); err != nil {
+					return apperror.Internal("failed to create subject", err)
+				}
+				mapping.Created = true
+				result.SubjectsCreated++
+			}
+			if subject == nil {
+				mapping.Reason = reason
+				result.Unmapped++
+				result.Genres = append(result.Genres, mapping)
+				continue
+			}
+
+			linked, err := subjectRepo.LinkGenre(ctx, genre.Genre, subject.ID)
+			if err != nil {
+				return apperror.Internal("failed to link books to subject", err)
+			}
+			mapping.SubjectID, mapping.Subject, mapping.Linked = subject.ID, subject.Name, linked
+			result.BooksLinked += linked
+			result.Genres = append(result.Genres, mapping)
+		}
+
+		if !apply {
+			return errGenreMappingDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errGenreMappingDryRun) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// newSubject builds a subject from its display name, deriving its key.
+func newSubject(name string) (*models.Subject, error) {
+	name = strings.TrimSpace(name)
+	key := names.Fold(name)
+	if key == "" {
+		return nil, apperror.BadRequest(fmt.Sprintf("invalid subject name %q", name))
+	}
+	return &models.Subject{Name: name, NameKey: key}, nil
+}
+
+// checkSiblingName rejects a subject whose name is already used by another
+// subject with the same parent.
+func checkSiblingName(ctx context.Context, subjectRepo repository.SubjectRepository, subject *models.Subject) error {
+	sibling, err := subjectRepo.FindChildByNameKey(ctx, subject.ParentID, subject.NameKey)
+	if err == nil && sibling.ID != subject.ID {
+		return apperror.Conflict(fmt.Sprintf("subject %q already exists at this level", subject.Name))
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.Internal("failed to check subject names", err)
+	}
+	return nil
+}
+
+func addSubjectAlias(ctx context.Context, subjectRepo repository.SubjectRepository, subject *models.Subject, name string) (*models.SubjectAlias, error) {
+	name = strings.TrimSpace(name)
+	key := names.Fold(name)
+	if key == "" {
+		return nil, apperror.BadRequest(fmt.Sprintf("invalid subject alias %q", name))
+	}
+	if key == subject.NameKey {
+		return nil, apperror.BadRequest(fmt.Sprintf("%q is already the subject's name", name))
+	}
+
+	existing, err := subjectRepo.FindAliasByNameKey(ctx, key)
+	if err == nil {
+		return nil, apperror.Conflict(fmt.Sprintf("%q is already an alias of subject %d", name, existing.SubjectID))
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.Internal("failed to check subject aliases", err)
+	}
+
+	alias := &models.SubjectAlias{SubjectID: subject.ID, Name: name, NameKey: key}
+	if err := subjectRepo.CreateAlias(ctx, alias); err != nil {
+		return nil, apperror.Internal("failed to add subject alias", err)
+	}
+	return alias, nil
+}
+
+// matchSubject finds the subject a free-text term such as a genre names: the
+// subject with that alias, or else the only subject with that name. When
+// there is none it returns nil and the reason.
+func matchSubject(ctx context.Context, subjectRepo repository.SubjectRepository, term string) (*models.Subject, string, error) {
+	key := names.Fold(term)
+	if key == "" {
+		return nil, "no letters or digits", nil
+	}
+
+	alias, err := subjectRepo.FindAliasByNameKey(ctx, key)
+	if err == nil {
+		subject, err := subjectRepo.FindByID(ctx, alias.SubjectID)
+		if err != nil {
+			return nil, "", apperror.Internal("failed to load subject", err)
+		}
+		return subject, "", nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", apperror.Internal("failed to check subject aliases", err)
+	}
+
+	subjects, err := subjectRepo.FindByNameKey(ctx, key)
+	if err != nil {
+		return nil, "", apperror.Internal("failed to look up subject", err)
+	}
+	switch len(subjects) {
+	case 0:
+		return nil, "no subject has this name or alias", nil
+	case 1:
+		return &subjects[0], "", nil
+	default:
+		return nil, "several subjects have this name; add an alias to choose one", nil
+	}
+}
+
+// resolveBookSubjects returns the subjects to assign to a book: the subjects
+// with the given IDs or, when ids is nil, the subject genre names, if any.
+func resolveBookSubjects(ctx context.Context, subjectRepo repository.SubjectRepository, ids []uint, genre string) ([]models.Subject, error) {
+	if ids == nil {
+		if genre == "" {
+			return nil, nil
+		}
+		subject, _, err := matchSubject(ctx, subjectRepo, genre)
+		if err != nil || subject == nil {
+			return nil, err
+		}
+		return []models.Subject{*subject}, nil
+	}
+
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	subjects, err := subjectRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, apperror.Internal("failed to load subjects", err)
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(subjects, func(s models.Subject) bool { return s.ID == id }) {
+			return nil, apperror.BadRequest(fmt.Sprintf("subject %d not found", id))
+		}
+	}
+	return subjects, nil
+}
+
+// setBookSubjects replaces the subjects of a saved book.
+func setBookSubjects(ctx context.Context, subjectRepo repository.SubjectRepository, book *models.Book, subjects []models.Subject) error {
+	ids := make([]uint, len(subjects))
+	for i := range subjects {
+		ids[i] = subjects[i].ID
+	}
+	if err := subjectRepo.ReplaceBookSubjects(ctx, book.ID, ids); err != nil {
+		return apperror.Internal("failed to assign book subjects", err)
+	}
+	book.Subjects = subjects
+	return nil
+}
+
+// subjectTree nests subjects below their parents. Siblings keep the order of
+// subjects.
+func subjectTree(subjects []models.Subject) []models.Subject {
+	children := make(map[uint][]models.Subject)
+	roots := []models.Subject{}
+	for _, subject := range subjects {
+		if subject.ParentID == nil {
+			roots = append(roots, subject)
+		} else {
+			children[*subject.ParentID] = append(children[*subject.ParentID], subject)
+		}
+	}
+
+	var nest func(level []models.Subject) []models.Subject
+	nest = func(level []models.Subject) []models.Subject {
+		for i := range level {
+			level[i].Children = nest(children[level[i].ID])
+		}
+		return level
+	}
+	return nest(roots)
+}
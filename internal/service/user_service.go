@@ -0,0 +1,160 @@
+// internal/service/user_service.go
+package service
+
+import (
+	"context"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"gorm.io/gorm"
+)
+
+type UserService interface {
+	FindUser(ctx context.Context, identifier string) (*models.User, error)
+	// FindUserWithDeleted is FindUser including soft-deleted users.
+	FindUserWithDeleted(ctx context.Context, identifier string) (*models.User, error)
+	ChangeRole(ctx context.Context, identifier string, role models.UserRole) (*models.User, error)
+	SetActive(ctx context.Context, identifier string, active bool) (*models.User, error)
+	ListUsers(ctx context.Context, page, limit int) ([]models.User, int64, error)
+	// DeleteUser deactivates and soft-deletes a user without active borrows.
+	// Their borrow history stays until the retention period anonymizes it.
+	DeleteUser(ctx context.Context, id uint) (*models.User, error)
+	// RestoreUser undoes DeleteUser, unless the user was purged since.
+	RestoreUser(ctx context.Context, id uint) (*models.User, error)
+}
+
+type userService struct {
+	db         *gorm.DB
+	userRepo   repository.UserRepository
+	borrowRepo repository.BorrowRepository
+}
+
+func NewUserService(db *gorm.DB, userRepo repository.UserRepository, borrowRepo repository.BorrowRepository) UserService {
+	return &userService{db: db, userRepo: userRepo, borrowRepo: borrowRepo}
+}
+
+// FindUser looks a user up by username first and falls back to email.
+func (s *userService) FindUser(ctx context.Context, identifier string) (*models.User, error) {
+	return findUser(ctx, s.userRepo, identifier)
+}
+
+func (s *userService) FindUserWithDeleted(ctx context.Context, identifier string) (*models.User, error) {
+	return findUser(ctx, s.userRepo.WithDeleted(), identifier)
+}
+
+func findUser(ctx context.Context, userRepo repository.UserRepository, identifier string) (*models.User, error) {
+	user, err := userRepo.FindByUsername(ctx, identifier)
+	if err == nil {
+		return user, nil
+	}
+
+	user, err = userRepo.FindByEmail(ctx, identifier)
+	if err != nil {
+		return nil, lookupError(err, "user")
+	}
+	return user, nil
+}
+
+func (s *userService) ChangeRole(ctx context.Context, identifier string, role models.UserRole) (*models.User, error) {
+	switch role {
+	case models.RoleAdmin, models.RoleLibrarian, models.RoleMember:
+	default:
+		return nil, apperror.BadRequest("invalid role")
+	}
+
+	user, err := s.FindUser(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Role == role {
+		return user, nil
+	}
+
+	user.Role = role
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, apperror.Internal("failed to update user", err)
+	}
+
+	return user, nil
+}
+
+func (s *userService) SetActive(ctx context.Context, identifier string, active bool) (*models.User, error) {
+	user, err := s.FindUser(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsActive == active {
+		return user, nil
+	}
+
+	user.IsActive = active
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, apperror.Internal("failed to update user", err)
+	}
+
+	return user, nil
+}
+
+func (s *userService) ListUsers(ctx context.Context, page, limit int) ([]models.User, int64, error) {
+	return s.userRepo.List(ctx, page, limit)
+}
+
+// DeleteUser locks the user row, so a borrow that starts meanwhile either
+// commits first and is counted or waits and finds the user gone.
+func (s *userService) DeleteUser(ctx context.Context, id uint) (*models.User, error) {
+	var user *models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		users := s.userRepo.WithTx(tx)
+
+		var err error
+		user, err = users.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return lookupError(err, "user")
+		}
+
+		active, err := s.borrowRepo.WithTx(tx).CountActiveByUser(ctx, user.ID)
+		if err != nil {
+			return apperror.Internal("failed to count active borrows", err)
+		}
+		if active > 0 {
+			return apperror.Conflict("cannot delete user with active borrows")
+		}
+
+		user.IsActive = false
+		if err := users.Update(ctx, user); err != nil {
+			return apperror.Internal("failed to update user", err)
+		}
+		if err := users.Delete(ctx, user.ID); err != nil {
+			return apperror.Internal("failed to delete user", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *userService) RestoreUser(ctx context.Context, id uint) (*models.User, error) {
+	users := s.userRepo.WithDeleted()
+	user, err := users.FindByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "user")
+	}
+
+	switch {
+	case user.PurgedAt != nil:
+		return nil, apperror.Conflict("user was purged and cannot be restored")
+	case !user.DeletedAt.Valid:
+		return nil, apperror.Conflict("user is not deleted")
+	}
+
+	user.DeletedAt = gorm.DeletedAt{}
+	user.IsActive = true
+	if err := users.Update(ctx, user); err != nil {
+		return nil, apperror.Internal("failed to restore user", err)
+	}
+	return user, nil
+}
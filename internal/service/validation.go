@@ -0,0 +1,114 @@
+// internal/service/validation.go
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// validateRequest checks the binding rules of req, a pointer to a request
+// struct, and reports each invalid field by its JSON name.
+func validateRequest(req any, message string) error {
+	err := binding.Validator.ValidateStruct(req)
+	if err == nil {
+		return nil
+	}
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return apperror.BadRequest(err.Error())
+	}
+
+	root := reflect.TypeOf(req).Elem()
+	fields := make(map[string]string, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		fields[jsonPath(root, fieldErr.StructNamespace())] = validationMessage(fieldErr)
+	}
+	return apperror.Invalid(message, fields)
+}
+
+// decodeError reports a request body that does not fit its struct, naming
+// the field when the JSON decoder does.
+func decodeError(err error, message string) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return apperror.Invalid(message, map[string]string{typeErr.Field: "must be " + jsonKind(typeErr.Type)})
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return apperror.Invalid(message, map[string]string{strings.Trim(field, `"`): "is not a field that can be set"})
+	}
+	return apperror.BadRequest(fmt.Sprintf("%s: %v", message, err))
+}
+
+// jsonPath turns a validator namespace such as
+// "CreateBookRequest.Contributors[0].Name" into "contributors[0].name".
+func jsonPath(root reflect.Type, namespace string) string {
+	segments := strings.Split(namespace, ".")[1:]
+	typ := root
+	for i, segment := range segments {
+		name, index, _ := strings.Cut(segment, "[")
+		for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+		field, ok := typ.FieldByName(name)
+		if !ok {
+			break
+		}
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" && tag != "-" {
+			name = tag
+		}
+		if index != "" {
+			name += "[" + index
+		}
+		segments[i] = name
+		typ = field.Type
+	}
+	return strings.Join(segments, ".")
+}
+
+func validationMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required", "required_without":
+		return "is required"
+	case "gte":
+		return "must be at least " + fieldErr.Param()
+	case "lte":
+		return "must be at most " + fieldErr.Param()
+	case "gt":
+		return "must be greater than " + fieldErr.Param()
+	case "max":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fieldErr.Param())
+		}
+		return "must have at most " + fieldErr.Param() + " items"
+	case "len":
+		return fmt.Sprintf("must be %s characters long", fieldErr.Param())
+	case "alpha":
+		return "must contain only letters"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	default:
+		return fmt.Sprintf("failed the %s check", fieldErr.Tag())
+	}
+}
+
+func jsonKind(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Int32, reflect.Uint32:
+		return "an integer"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
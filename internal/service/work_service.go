@@ -0,0 +1,203 @@
+// internal/service/work_service.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"gorm.io/gorm"
+)
+
+// WorkService manages works and series. Books become editions of a work
+// through their work_id.
+type WorkService interface {
+	CreateWork(ctx context.Context, req dto.WorkRequest) (*models.Work, error)
+	GetWork(ctx context.Context, id uint) (*models.Work, error)
+	// UpdateWork replaces a work still at version; 0 skips the check, as it
+	// does for the other updates and deletes.
+	UpdateWork(ctx context.Context, id, version uint, req dto.WorkRequest) (*models.Work, error)
+	// DeleteWork removes a work that has no editions in the catalog.
+	DeleteWork(ctx context.Context, id, version uint) error
+	// ListWorks lists the works matching search, of one series when
+	// seriesID is set.
+	ListWorks(ctx context.Context, search string, seriesID uint, req query.PageRequest) ([]models.Work, query.PageInfo, error)
+
+	CreateSeries(ctx context.Context, req dto.SeriesRequest) (*models.Series, error)
+	GetSeries(ctx context.Context, id uint) (*models.Series, error)
+	UpdateSeries(ctx context.Context, id, version uint, req dto.SeriesRequest) (*models.Series, error)
+	// DeleteSeries removes a series that no work belongs to.
+	DeleteSeries(ctx context.Context, id, version uint) error
+	ListSeries(ctx context.Context, search string, req query.PageRequest) ([]models.Series, query.PageInfo, error)
+}
+
+type workService struct {
+	workRepo   repository.WorkRepository
+	seriesRepo repository.SeriesRepository
+}
+
+func NewWorkService(workRepo repository.WorkRepository, seriesRepo repository.SeriesRepository) WorkService {
+	return &workService{workRepo: workRepo, seriesRepo: seriesRepo}
+}
+
+func (s *workService) CreateWork(ctx context.Context, req dto.WorkRequest) (*models.Work, error) {
+	work := &models.Work{}
+	if err := s.applyWork(ctx, work, req); err != nil {
+		return nil, err
+	}
+	if err := s.workRepo.Create(ctx, work); err != nil {
+		return nil, apperror.Internal("failed to create work", err)
+	}
+	return s.GetWork(ctx, work.ID)
+}
+
+func (s *workService) GetWork(ctx context.Context, id uint) (*models.Work, error) {
+	work, err := s.workRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "work")
+	}
+	return work, nil
+}
+
+func (s *workService) UpdateWork(ctx context.Context, id, version uint, req dto.WorkRequest) (*models.Work, error) {
+	work, err := s.GetWork(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion("work", work.Version, version); err != nil {
+		return nil, err
+	}
+	if err := s.applyWork(ctx, work, req); err != nil {
+		return nil, err
+	}
+	if err := s.workRepo.Update(ctx, work); err != nil {
+		return nil, updateError(err, "work", "failed to update work")
+	}
+	return s.GetWork(ctx, id)
+}
+
+// applyWork copies req onto work, checking that its series exists.
+func (s *workService) applyWork(ctx context.Context, work *models.Work, req dto.WorkRequest) error {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return apperror.Invalid("invalid work", map[string]string{"title": "is required"})
+	}
+	work.Title = title
+	work.Author = strings.TrimSpace(req.Author)
+	work.Volume = req.Volume
+	work.SeriesID, work.Series = nil, nil
+	if req.SeriesID != 0 {
+		if _, err := s.seriesRepo.FindByID(ctx, req.SeriesID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.Invalid("invalid work", map[string]string{"series_id": fmt.Sprintf("series %d does not exist", req.SeriesID)})
+			}
+			return apperror.Internal("failed to check work series", err)
+		}
+		work.SeriesID = &req.SeriesID
+	}
+	return nil
+}
+
+func (s *workService) DeleteWork(ctx context.Context, id, version uint) error {
+	work, err := s.GetWork(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion("work", work.Version, version); err != nil {
+		return err
+	}
+	if work.EditionCount > 0 {
+		return apperror.Conflict("work has editions in the catalog; link them to another work first")
+	}
+
+	if err := s.workRepo.Delete(ctx, id); err != nil {
+		return apperror.Internal("failed to delete work", err)
+	}
+	return nil
+}
+
+func (s *workService) ListWorks(ctx context.Context, search string, seriesID uint, req query.PageRequest) ([]models.Work, query.PageInfo, error) {
+	works, info, err := s.workRepo.List(ctx, search, seriesID, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list works")
+	}
+	return works, info, nil
+}
+
+func (s *workService) CreateSeries(ctx context.Context, req dto.SeriesRequest) (*models.Series, error) {
+	series := &models.Series{}
+	if err := applySeries(series, req); err != nil {
+		return nil, err
+	}
+	if err := s.seriesRepo.Create(ctx, series); err != nil {
+		return nil, apperror.Internal("failed to create series", err)
+	}
+	return s.GetSeries(ctx, series.ID)
+}
+
+func (s *workService) GetSeries(ctx context.Context, id uint) (*models.Series, error) {
+	series, err := s.seriesRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "series")
+	}
+	return series, nil
+}
+
+func (s *workService) UpdateSeries(ctx context.Context, id, version uint, req dto.SeriesRequest) (*models.Series, error) {
+	series, err := s.GetSeries(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion("series", series.Version, version); err != nil {
+		return nil, err
+	}
+	if err := applySeries(series, req); err != nil {
+		return nil, err
+	}
+	if err := s.seriesRepo.Update(ctx, series); err != nil {
+		return nil, updateError(err, "series", "failed to update series")
+	}
+	return series, nil
+}
+
+func applySeries(series *models.Series, req dto.SeriesRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return apperror.Invalid("invalid series", map[string]string{"name": "is required"})
+	}
+	series.Name = name
+	series.Description = req.Description
+	return nil
+}
+
+func (s *workService) DeleteSeries(ctx context.Context, id, version uint) error {
+	series, err := s.GetSeries(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion("series", series.Version, version); err != nil {
+		return err
+	}
+	if series.WorkCount > 0 {
+		return apperror.Conflict("series has works; move them out of the series first")
+	}
+
+	if err := s.seriesRepo.Delete(ctx, id); err != nil {
+		return apperror.Internal("failed to delete series", err)
+	}
+	return nil
+}
+
+func (s *workService) ListSeries(ctx context.Context, search string, req query.PageRequest) ([]models.Series, query.PageInfo, error) {
+	series, info, err := s.seriesRepo.List(ctx, search, req)
+	if err != nil {
+		return nil, info, listError(err, "failed to list series")
+	}
+	return series, info, nil
+}
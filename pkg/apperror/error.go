@@ -8,13 +8,24 @@ const (
 	CodeForbidden    = "forbidden"
 	CodeNotFound     = "not_found"
 	CodeConflict     = "conflict"
-	CodeInternal     = "internal_error"
+	CodeTooLarge     = "payload_too_large"
+	CodeUnsupported  = "unsupported_media_type"
+	// CodePreconditionFailed rejects a change made from an outdated version
+	// of a resource; CodePreconditionRequired one that names no version.
+	CodePreconditionFailed   = "precondition_failed"
+	CodePreconditionRequired = "precondition_required"
+	CodeInternal             = "internal_error"
+	CodeTimeout              = "timeout"
+	CodeCanceled             = "request_canceled"
 )
 
 type AppError struct {
 	Code    string
 	Message string
 	Err     error
+	// Fields maps the request fields that failed validation to what is
+	// wrong with them.
+	Fields map[string]string
 }
 
 func (e *AppError) Error() string {
@@ -43,6 +54,11 @@ func BadRequest(message string) *AppError {
 	return New(CodeBadRequest, message)
 }
 
+// Invalid is a bad request naming the invalid fields.
+func Invalid(message string, fields map[string]string) *AppError {
+	return &AppError{Code: CodeBadRequest, Message: message, Fields: fields}
+}
+
 func Unauthorized(message string) *AppError {
 	return New(CodeUnauthorized, message)
 }
@@ -59,6 +75,22 @@ func Conflict(message string) *AppError {
 	return New(CodeConflict, message)
 }
 
+func TooLarge(message string) *AppError {
+	return New(CodeTooLarge, message)
+}
+
+func Unsupported(message string) *AppError {
+	return New(CodeUnsupported, message)
+}
+
+func PreconditionFailed(message string) *AppError {
+	return New(CodePreconditionFailed, message)
+}
+
+func PreconditionRequired(message string) *AppError {
+	return New(CodePreconditionRequired, message)
+}
+
 func Internal(message string, err error) *AppError {
 	return Wrap(CodeInternal, message, err)
 }
@@ -0,0 +1,191 @@
+// Package callnumber parses Dewey Decimal (DDC) and Library of Congress (LCC)
+// call numbers and builds shelf keys: strings that sort like the books are
+// shelved, with class numbers compared as numbers, cutters as decimals and
+// volume numbers as integers.
+package callnumber
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Classification schemes.
+const (
+	DDC = "ddc"
+	LCC = "lcc"
+)
+
+// NoKey is the shelf key of books without a call number; it sorts after
+// every call number.
+const NoKey = "~"
+
+var (
+	ErrEmpty         = errors.New("call number is empty")
+	ErrUnknownScheme = errors.New("classification must be ddc or lcc")
+	ErrNoScheme      = errors.New("classification cannot be told from the call number; set it")
+	ErrInvalidDDC    = errors.New("not a Dewey call number: expected a class such as 823.914, optionally after a prefix such as REF, then cutters")
+	ErrInvalidLCC    = errors.New("not a Library of Congress call number: expected a class such as QA76.73, then cutters")
+	ErrInvalidToken  = errors.New("call number may only contain letters, digits, periods, hyphens and colons")
+)
+
+var (
+	ddcClass  = regexp.MustCompile(`^\d{3}(\.\d+)?$`)
+	lccClass  = regexp.MustCompile(`^([A-Z]{1,3}) ?(\d{1,4})(\.\d+)?(.*)$`)
+	lccStart  = regexp.MustCompile(`^[A-Z]{1,3}\d`)
+	token     = regexp.MustCompile(`^[A-Z0-9.:\-]+$`)
+	prefix    = regexp.MustCompile(`^[A-Z]+$`)
+	digitRuns = regexp.MustCompile(`\d+`)
+)
+
+// CallNumber is a parsed call number.
+type CallNumber struct {
+	Scheme string
+	// Value is the call number in upper case with single spaces.
+	Value string
+	key   string
+}
+
+// String returns Value.
+func (c CallNumber) String() string {
+	return c.Value
+}
+
+// Key returns the shelf key. Keys of the same scheme compare like shelf
+// order; DDC call numbers sort before LCC ones.
+func (c CallNumber) Key() string {
+	return c.key
+}
+
+// Limit returns the least shelf key above the keys of c and of the call
+// numbers within it: QA76.73 and QA76 .C3 are within QA76, QA761 is not.
+func (c CallNumber) Limit() string {
+	return c.key + NoKey
+}
+
+// Parse validates s as a call number of scheme. An empty scheme is inferred:
+// call numbers starting with a digit are DDC, those starting with class
+// letters directly followed by a number LCC.
+func Parse(scheme, s string) (CallNumber, error) {
+	value := strings.Join(strings.Fields(strings.ToUpper(s)), " ")
+	if value == "" {
+		return CallNumber{}, ErrEmpty
+	}
+	if scheme == "" {
+		switch {
+		case unicode.IsDigit(rune(value[0])):
+			scheme = DDC
+		case lccStart.MatchString(value):
+			scheme = LCC
+		default:
+			return CallNumber{}, ErrNoScheme
+		}
+	}
+
+	switch scheme {
+	case DDC:
+		return parseDDC(value)
+	case LCC:
+		return parseLCC(value)
+	default:
+		return CallNumber{}, ErrUnknownScheme
+	}
+}
+
+// parseDDC parses prefixes such as REF or FIC, a class number and cutters.
+// Fiction shelved by author needs no class number: FIC HER.
+func parseDDC(value string) (CallNumber, error) {
+	tokens := strings.Fields(value)
+	for _, t := range tokens {
+		if !token.MatchString(t) {
+			return CallNumber{}, ErrInvalidToken
+		}
+	}
+
+	var prefixes []string
+	for len(tokens) > 0 && prefix.MatchString(tokens[0]) && !ddcClass.MatchString(tokens[0]) {
+		prefixes = append(prefixes, tokens[0])
+		tokens = tokens[1:]
+	}
+	class := ""
+	if len(tokens) > 0 && ddcClass.MatchString(tokens[0]) {
+		class, tokens = tokens[0], tokens[1:]
+	}
+	if class == "" && (len(prefixes) == 0 || len(tokens) == 0 && len(prefixes) < 2) {
+		return CallNumber{}, ErrInvalidDDC
+	}
+	if class == "" && len(tokens) == 0 {
+		// FIC HER: the last word is the cutter.
+		tokens = prefixes[len(prefixes)-1:]
+		prefixes = prefixes[:len(prefixes)-1]
+	}
+
+	parts := append([]string{"D"}, prefixes...)
+	if class != "" {
+		parts = append(parts, class)
+	}
+	for _, t := range tokens {
+		parts = append(parts, cutterKey(t))
+	}
+	return CallNumber{Scheme: DDC, Value: value, key: strings.Join(parts, " ")}, nil
+}
+
+// parseLCC parses class letters, a class number with an optional decimal
+// and cutters such as .G63.
+func parseLCC(value string) (CallNumber, error) {
+	m := lccClass.FindStringSubmatch(value)
+	if m == nil {
+		return CallNumber{}, ErrInvalidLCC
+	}
+	letters, number, decimal, rest := m[1], m[2], m[3], strings.TrimSpace(m[4])
+	if rest != "" && !strings.HasPrefix(m[4], " ") && !strings.HasPrefix(rest, ".") {
+		// QA76A1: the class number runs into something that is no cutter.
+		return CallNumber{}, ErrInvalidLCC
+	}
+
+	display := letters + number + decimal
+	parts := []string{"L", letters, strings.Repeat("0", 4-len(number)) + number + decimal}
+	for i, t := range strings.Fields(rest) {
+		if !token.MatchString(t) {
+			return CallNumber{}, ErrInvalidToken
+		}
+		if i == 0 && strings.HasPrefix(t, ".") {
+			display += t
+		} else {
+			display += " " + t
+		}
+		// A token can hold several cutters: .G63D66.
+		for _, cutter := range strings.Split(strings.TrimPrefix(t, "."), ".") {
+			if cutter != "" {
+				parts = append(parts, cutterKey(cutter))
+			}
+		}
+	}
+	return CallNumber{Scheme: LCC, Value: display, key: strings.Join(parts, " ")}, nil
+}
+
+// cutterKey makes the numbers in a cutter, year or volume token sort right.
+// Digits right after a letter are a cutter number, a decimal that sorts as
+// written; other digits, such as the 2 of V.2 or a year, are an integer and
+// are zero-padded.
+func cutterKey(t string) string {
+	var b strings.Builder
+	last := 0
+	for _, run := range digitRuns.FindAllStringIndex(t, -1) {
+		start, end := run[0], run[1]
+		b.WriteString(t[last:start])
+		if start > 0 && unicode.IsLetter(rune(t[start-1])) {
+			b.WriteString(t[start:end])
+		} else {
+			b.WriteString(strings.Repeat("0", max(0, integerWidth-(end-start))))
+			b.WriteString(t[start:end])
+		}
+		last = end
+	}
+	b.WriteString(t[last:])
+	return b.String()
+}
+
+// integerWidth is the width integers in cutter tokens are padded to.
+const integerWidth = 6
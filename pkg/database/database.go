@@ -10,29 +10,26 @@ import (
 
 	"github.com/alpardfm/library-management-api/internal/models"
 
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// Config describes a database connection. The struct tags are read by the
+// configs package, which owns loading it from files and the environment.
 type Config struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
-}
+	Driver   string `config:"driver" env:"DB_DRIVER" default:"postgres"`
+	Host     string `config:"host" env:"DB_HOST" default:"localhost"`
+	Port     string `config:"port" env:"DB_PORT" default:"5432"`
+	User     string `config:"user" env:"DB_USER" default:"postgres"`
+	Password string `config:"password" env:"DB_PASSWORD" default:"password" secret:"true"`
+	DBName   string `config:"name" env:"DB_NAME" default:"library_db"`
+	SSLMode  string `config:"sslmode" env:"DB_SSLMODE" default:"disable"`
 
-func NewConfig() *Config {
-	return &Config{
-		Host:     getEnv("DB_HOST", "localhost"),
-		Port:     getEnv("DB_PORT", "5432"),
-		User:     getEnv("DB_USER", "postgres"),
-		Password: getEnv("DB_PASSWORD", "password"),
-		DBName:   getEnv("DB_NAME", "library_db"),
-		SSLMode:  getEnv("DB_SSLMODE", "disable"),
-	}
+	// Path is the SQLite database file, or ":memory:" for a private in-memory database.
+	Path string `config:"path" env:"DB_PATH" default:"library.db"`
+
+	// StatementTimeout bounds each statement; zero disables the limit.
+	StatementTimeout time.Duration `config:"statement_timeout" env:"DB_STATEMENT_TIMEOUT" default:"15s"`
 }
 
 func (c *Config) DSN() string {
@@ -40,8 +37,12 @@ func (c *Config) DSN() string {
 		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode)
 }
 
-func Connect() (*gorm.DB, error) {
-	config := NewConfig()
+// Open connects to the database described by config using its dialect.
+func Open(config *Config) (*gorm.DB, error) {
+	dialect, err := DialectFor(config.Driver)
+	if err != nil {
+		return nil, err
+	}
 
 	// Custom logger
 	newLogger := logger.New(
@@ -54,7 +55,7 @@ func Connect() (*gorm.DB, error) {
 		},
 	)
 
-	db, err := gorm.Open(postgres.Open(config.DSN()), &gorm.Config{
+	db, err := gorm.Open(dialect.Dialector(config), &gorm.Config{
 		Logger: newLogger,
 	})
 
@@ -68,18 +69,47 @@ func Connect() (*gorm.DB, error) {
 		return nil, err
 	}
 
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	dialect.ConfigurePool(sqlDB, config)
+
+	if config.StatementTimeout > 0 {
+		if err := db.Use(StatementTimeout(config.StatementTimeout)); err != nil {
+			return nil, fmt.Errorf("failed to register statement timeout: %w", err)
+		}
+	}
 
 	return db, nil
 }
 
 func AutoMigrate(db *gorm.DB) error {
+	if err := db.SetupJoinTable(&models.Book{}, "Subjects", &models.BookSubject{}); err != nil {
+		return fmt.Errorf("failed to set up book subjects: %w", err)
+	}
+
 	models := []interface{}{
 		&models.User{},
+		&models.Subject{},
 		&models.Book{},
 		&models.BorrowRecord{},
+		&models.Setting{},
+		&models.SettingChange{},
+		&models.ImportJob{},
+		&models.ImportJobIssue{},
+		&models.MarcRecord{},
+		&models.Author{},
+		&models.AuthorVariant{},
+		&models.BookContributor{},
+		&models.SubjectAlias{},
+		&models.BookSubject{},
+		&models.BookRevision{},
+		&models.Series{},
+		&models.Work{},
+		&models.Hold{},
+		&models.Branch{},
+		&models.Copy{},
+		&models.Transfer{},
+		&models.Stocktake{},
+		&models.StocktakeScan{},
+		&models.AccountEntry{},
 	}
 
 	for _, model := range models {
@@ -88,20 +118,16 @@ func AutoMigrate(db *gorm.DB) error {
 		}
 	}
 
-	if db.Dialector.Name() != "postgres" {
-		return nil
-	}
-
-	return applyPostgresMigrations(db)
+	return DialectOf(db).Migrate(db)
 }
 
-type postgresMigration struct {
+type sqlMigration struct {
 	name      string
 	statement string
 }
 
 func applyPostgresMigrations(db *gorm.DB) error {
-	for _, migration := range basePostgresMigrations() {
+	for _, migration := range append(basePostgresMigrations(), fullTextMigrations()...) {
 		if err := db.Exec(normalizeSQL(migration.statement)).Error; err != nil {
 			return fmt.Errorf("failed to apply %s: %w", migration.name, err)
 		}
@@ -120,8 +146,8 @@ func applyPostgresMigrations(db *gorm.DB) error {
 	return nil
 }
 
-func basePostgresMigrations() []postgresMigration {
-	return []postgresMigration{
+func basePostgresMigrations() []sqlMigration {
+	return []sqlMigration{
 		{
 			name: "available copies non-negative constraint",
 			statement: `
@@ -183,8 +209,178 @@ func basePostgresMigrations() []postgresMigration {
 	}
 }
 
-func pgTrgmExtensionMigration() postgresMigration {
-	return postgresMigration{
+// fullTextMigrations maintain books.search_vector, the weighted document of
+// full-text search: title (A), author statement and credited author names (B),
+// subject names (C) and description (D). A trigger on books rebuilds it when
+// those columns change or when search_vector is set to NULL, which is how
+// changes to contributor and subject links, and author or subject renames,
+// ask for a rebuild. The books trigger is created once, together with the
+// backfill of existing rows.
+func fullTextMigrations() []sqlMigration {
+	return []sqlMigration{
+		{
+			name: "books search vector column",
+			statement: `
+				ALTER TABLE books ADD COLUMN IF NOT EXISTS search_vector tsvector
+			`,
+		},
+		{
+			name: "books search vector index",
+			statement: `
+				CREATE INDEX IF NOT EXISTS idx_books_search_vector
+				ON books
+				USING gin (search_vector)
+			`,
+		},
+		{
+			name: "books search vector function",
+			statement: `
+				CREATE OR REPLACE FUNCTION books_search_vector_refresh() RETURNS trigger AS $fn$
+				BEGIN
+					NEW.search_vector :=
+						setweight(to_tsvector('english', coalesce(NEW.title, '')), 'A') ||
+						setweight(to_tsvector('english', coalesce(NEW.author, '') || ' ' || coalesce((
+							SELECT string_agg(authors.name, ' ')
+							FROM book_contributors
+							JOIN authors ON authors.id = book_contributors.author_id
+							WHERE book_contributors.book_id = NEW.id
+						), '')), 'B') ||
+						setweight(to_tsvector('english', coalesce((
+							SELECT string_agg(subjects.name, ' ')
+							FROM book_subjects
+							JOIN subjects ON subjects.id = book_subjects.subject_id
+							WHERE book_subjects.book_id = NEW.id
+						), '')), 'C') ||
+						setweight(to_tsvector('english', coalesce(NEW.description, '')), 'D');
+					RETURN NEW;
+				END
+				$fn$ LANGUAGE plpgsql
+			`,
+		},
+		{
+			name: "book links search vector function",
+			statement: `
+				CREATE OR REPLACE FUNCTION books_search_vector_touch_link() RETURNS trigger AS $fn$
+				BEGIN
+					IF TG_OP IN ('UPDATE', 'DELETE') THEN
+						UPDATE books SET search_vector = NULL WHERE id = OLD.book_id;
+					END IF;
+					IF TG_OP IN ('INSERT', 'UPDATE') THEN
+						UPDATE books SET search_vector = NULL WHERE id = NEW.book_id;
+					END IF;
+					RETURN NULL;
+				END
+				$fn$ LANGUAGE plpgsql
+			`,
+		},
+		{
+			name: "renamed terms search vector function",
+			statement: `
+				CREATE OR REPLACE FUNCTION books_search_vector_touch_name() RETURNS trigger AS $fn$
+				BEGIN
+					IF TG_TABLE_NAME = 'authors' THEN
+						UPDATE books SET search_vector = NULL
+						WHERE id IN (SELECT book_id FROM book_contributors WHERE author_id = NEW.id);
+					ELSE
+						UPDATE books SET search_vector = NULL
+						WHERE id IN (SELECT book_id FROM book_subjects WHERE subject_id = NEW.id);
+					END IF;
+					RETURN NULL;
+				END
+				$fn$ LANGUAGE plpgsql
+			`,
+		},
+		{
+			name: "books search vector trigger",
+			statement: `
+				DO $$
+				BEGIN
+					IF NOT EXISTS (
+						SELECT 1
+						FROM pg_trigger
+						WHERE tgname = 'books_search_vector_refresh'
+					) THEN
+						CREATE TRIGGER books_search_vector_refresh
+						BEFORE INSERT OR UPDATE OF title, author, description, search_vector ON books
+						FOR EACH ROW EXECUTE FUNCTION books_search_vector_refresh();
+						UPDATE books SET search_vector = NULL;
+					END IF;
+				END $$;
+			`,
+		},
+		{
+			name: "book contributors search vector trigger",
+			statement: `
+				DO $$
+				BEGIN
+					IF NOT EXISTS (
+						SELECT 1
+						FROM pg_trigger
+						WHERE tgname = 'book_contributors_search_vector'
+					) THEN
+						CREATE TRIGGER book_contributors_search_vector
+						AFTER INSERT OR UPDATE OR DELETE ON book_contributors
+						FOR EACH ROW EXECUTE FUNCTION books_search_vector_touch_link();
+					END IF;
+				END $$;
+			`,
+		},
+		{
+			name: "book subjects search vector trigger",
+			statement: `
+				DO $$
+				BEGIN
+					IF NOT EXISTS (
+						SELECT 1
+						FROM pg_trigger
+						WHERE tgname = 'book_subjects_search_vector'
+					) THEN
+						CREATE TRIGGER book_subjects_search_vector
+						AFTER INSERT OR UPDATE OR DELETE ON book_subjects
+						FOR EACH ROW EXECUTE FUNCTION books_search_vector_touch_link();
+					END IF;
+				END $$;
+			`,
+		},
+		{
+			name: "authors search vector trigger",
+			statement: `
+				DO $$
+				BEGIN
+					IF NOT EXISTS (
+						SELECT 1
+						FROM pg_trigger
+						WHERE tgname = 'authors_search_vector'
+					) THEN
+						CREATE TRIGGER authors_search_vector
+						AFTER UPDATE OF name ON authors
+						FOR EACH ROW EXECUTE FUNCTION books_search_vector_touch_name();
+					END IF;
+				END $$;
+			`,
+		},
+		{
+			name: "subjects search vector trigger",
+			statement: `
+				DO $$
+				BEGIN
+					IF NOT EXISTS (
+						SELECT 1
+						FROM pg_trigger
+						WHERE tgname = 'subjects_search_vector'
+					) THEN
+						CREATE TRIGGER subjects_search_vector
+						AFTER UPDATE OF name ON subjects
+						FOR EACH ROW EXECUTE FUNCTION books_search_vector_touch_name();
+					END IF;
+				END $$;
+			`,
+		},
+	}
+}
+
+func pgTrgmExtensionMigration() sqlMigration {
+	return sqlMigration{
 		name: "pg_trgm extension",
 		statement: `
 			CREATE EXTENSION IF NOT EXISTS pg_trgm
@@ -192,8 +388,8 @@ func pgTrgmExtensionMigration() postgresMigration {
 	}
 }
 
-func pgTrgmIndexMigrations() []postgresMigration {
-	return []postgresMigration{
+func pgTrgmIndexMigrations() []sqlMigration {
+	return []sqlMigration{
 		{
 			name: "books title trigram index",
 			statement: `
@@ -218,11 +414,20 @@ func pgTrgmIndexMigrations() []postgresMigration {
 				USING gin (isbn gin_trgm_ops)
 			`,
 		},
+		{
+			name: "authors name trigram index",
+			statement: `
+				CREATE INDEX IF NOT EXISTS idx_authors_name_trgm
+				ON authors
+				USING gin (name gin_trgm_ops)
+			`,
+		},
 	}
 }
 
-func allPostgresMigrations() []postgresMigration {
-	migrations := append([]postgresMigration{}, basePostgresMigrations()...)
+func allPostgresMigrations() []sqlMigration {
+	migrations := append([]sqlMigration{}, basePostgresMigrations()...)
+	migrations = append(migrations, fullTextMigrations()...)
 	migrations = append(migrations, pgTrgmExtensionMigration())
 	migrations = append(migrations, pgTrgmIndexMigrations()...)
 	return migrations
@@ -240,10 +445,3 @@ func tryEnablePgTrgm(db *gorm.DB) bool {
 
 	return true
 }
-
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
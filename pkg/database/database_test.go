@@ -44,6 +44,9 @@ func TestPostgresMigrations_AreIdempotent(t *testing.T) {
 			assert.Contains(t, sql, "IF NOT EXISTS", migration.name)
 			continue
 		}
+		if strings.HasPrefix(sql, "CREATE OR REPLACE FUNCTION") {
+			continue
+		}
 
 		assert.Contains(t, sql, "IF NOT EXISTS", migration.name)
 	}
@@ -52,7 +55,7 @@ func TestPostgresMigrations_AreIdempotent(t *testing.T) {
 func TestApplyPostgresMigrations_ExecutesBaseStatements(t *testing.T) {
 	gormDB, mock := newMockPostgresDB(t)
 
-	for _, migration := range basePostgresMigrations() {
+	for _, migration := range append(basePostgresMigrations(), fullTextMigrations()...) {
 		mock.ExpectExec(regexp.QuoteMeta(normalizeSQL(migration.statement))).
 			WillReturnResult(sqlmock.NewResult(0, 0))
 	}
@@ -69,7 +72,7 @@ func TestApplyPostgresMigrations_ExecutesBaseStatements(t *testing.T) {
 func TestApplyPostgresMigrations_ExecutesTrigramStatementsWhenExtensionAvailable(t *testing.T) {
 	gormDB, mock := newMockPostgresDB(t)
 
-	for _, migration := range basePostgresMigrations() {
+	for _, migration := range append(basePostgresMigrations(), fullTextMigrations()...) {
 		mock.ExpectExec(regexp.QuoteMeta(normalizeSQL(migration.statement))).
 			WillReturnResult(sqlmock.NewResult(0, 0))
 	}
@@ -91,7 +94,7 @@ func TestApplyPostgresMigrations_ExecutesTrigramStatementsWhenExtensionAvailable
 func TestApplyPostgresMigrations_GracefullySkipsTrigramIndexesWhenExtensionFails(t *testing.T) {
 	gormDB, mock := newMockPostgresDB(t)
 
-	for _, migration := range basePostgresMigrations() {
+	for _, migration := range append(basePostgresMigrations(), fullTextMigrations()...) {
 		mock.ExpectExec(regexp.QuoteMeta(normalizeSQL(migration.statement))).
 			WillReturnResult(sqlmock.NewResult(0, 0))
 	}
@@ -0,0 +1,124 @@
+// pkg/database/dialect.go
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Dialect isolates the SQL that differs between the supported databases so
+// repositories and migrations can stay driver-agnostic.
+type Dialect interface {
+	Name() string
+	Dialector(config *Config) gorm.Dialector
+	ConfigurePool(sqlDB *sql.DB, config *Config)
+	Migrate(db *gorm.DB) error
+
+	// ILike returns a case-insensitive LIKE predicate for column with one placeholder.
+	ILike(column string) string
+	// FullTextSearch reports whether books have a search_vector column that
+	// can be matched with to_tsquery.
+	FullTextSearch() bool
+	// TrigramSimilarity reports whether db has pg_trgm, which provides
+	// similarity(), word_similarity() and the % and <% operators.
+	TrigramSimilarity(db *gorm.DB) bool
+	// EstimateCount returns about how many rows query matches, from the
+	// planner's statistics where the database keeps them.
+	EstimateCount(query *gorm.DB) (int64, error)
+}
+
+func DialectFor(driver string) (Dialect, error) {
+	switch driver {
+	case DriverPostgres, "":
+		return postgresDialect{}, nil
+	case DriverSQLite:
+		return sqliteDialect{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+}
+
+// DialectOf resolves the dialect of an open connection. Unknown dialectors
+// (e.g. test doubles) are treated as PostgreSQL, the production database.
+func DialectOf(db *gorm.DB) Dialect {
+	if db.Dialector.Name() == DriverSQLite {
+		return sqliteDialect{}
+	}
+	return postgresDialect{}
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return DriverPostgres
+}
+
+func (postgresDialect) Dialector(config *Config) gorm.Dialector {
+	return postgres.Open(config.DSN())
+}
+
+func (postgresDialect) ConfigurePool(sqlDB *sql.DB, _ *Config) {
+	sqlDB.SetMaxIdleConns(10)
+	sqlDB.SetMaxOpenConns(100)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+}
+
+func (postgresDialect) Migrate(db *gorm.DB) error {
+	if db.Dialector.Name() != DriverPostgres {
+		return nil
+	}
+	return applyPostgresMigrations(db)
+}
+
+func (postgresDialect) ILike(column string) string {
+	return column + " ILIKE ?"
+}
+
+func (postgresDialect) FullTextSearch() bool {
+	return true
+}
+
+// TrigramSimilarity checks the installed extensions; enabling pg_trgm is
+// allowed to fail at startup.
+func (postgresDialect) TrigramSimilarity(db *gorm.DB) bool {
+	var enabled bool
+	err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')").Scan(&enabled).Error
+	return err == nil && enabled
+}
+
+// EstimateCount reads the row estimate of the query plan, which costs no
+// table scan however many rows match.
+func (postgresDialect) EstimateCount(query *gorm.DB) (int64, error) {
+	stmt := query.Session(&gorm.Session{DryRun: true}).Find(query.Statement.Model).Statement
+
+	// The statement is already in PostgreSQL placeholders, so it goes to the
+	// connection as is rather than through Raw.
+	var explain string
+	err := stmt.ConnPool.QueryRowContext(stmt.Context, "EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...).Scan(&explain)
+	if err != nil {
+		return 0, err
+	}
+
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(explain), &plans); err != nil {
+		return 0, err
+	}
+	if len(plans) == 0 {
+		return 0, fmt.Errorf("empty query plan")
+	}
+	return int64(plans[0].Plan.Rows), nil
+}
@@ -0,0 +1,161 @@
+// pkg/database/sqlite.go
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/glebarez/go-sqlite"
+	gormsqlite "github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+const sqliteMemoryPath = ":memory:"
+
+var registerSQLiteFunctions sync.Once
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return DriverSQLite
+}
+
+func (sqliteDialect) Dialector(config *Config) gorm.Dialector {
+	registerSQLiteFunctions.Do(func() {
+		// SQLite's built-in LIKE and lower() only fold ASCII; casefold gives
+		// search the same Unicode behavior as PostgreSQL's ILIKE.
+		sqlite.MustRegisterDeterministicScalarFunction("casefold", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			switch value := args[0].(type) {
+			case string:
+				return strings.ToLower(value), nil
+			case []byte:
+				return strings.ToLower(string(value)), nil
+			default:
+				return value, nil
+			}
+		})
+	})
+
+	return gormsqlite.Open(sqliteDSN(config.Path))
+}
+
+// sqliteDSN enables foreign keys and opens transactions with BEGIN IMMEDIATE,
+// which takes the database write lock up front. That is coarser than the
+// row-level FOR UPDATE locks used on PostgreSQL (the GORM SQLite dialector
+// drops those clauses) but gives the same guarantee to read-check-write flows:
+// two transactions can never interleave their stock checks.
+func sqliteDSN(path string) string {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(10000)")
+	params.Set("_txlock", "immediate")
+
+	if path == "" || path == sqliteMemoryPath {
+		return "file::memory:?" + params.Encode()
+	}
+
+	params.Add("_pragma", "journal_mode(WAL)")
+	return fmt.Sprintf("file:%s?%s", path, params.Encode())
+}
+
+func (sqliteDialect) ConfigurePool(sqlDB *sql.DB, config *Config) {
+	if config.Path == "" || config.Path == sqliteMemoryPath {
+		// Every connection to :memory: opens a separate, empty database.
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		return
+	}
+
+	sqlDB.SetMaxIdleConns(4)
+	sqlDB.SetMaxOpenConns(4)
+}
+
+func (sqliteDialect) Migrate(db *gorm.DB) error {
+	for _, migration := range sqliteMigrations() {
+		if err := db.Exec(normalizeSQL(migration.statement)).Error; err != nil {
+			return fmt.Errorf("failed to apply %s: %w", migration.name, err)
+		}
+	}
+	return nil
+}
+
+func (sqliteDialect) ILike(column string) string {
+	return fmt.Sprintf("casefold(%s) LIKE casefold(?)", column)
+}
+
+// FullTextSearch is false: SQLite has no tsvector, so full-text searches fall
+// back to the substring search.
+func (sqliteDialect) FullTextSearch() bool {
+	return false
+}
+
+func (sqliteDialect) TrigramSimilarity(*gorm.DB) bool {
+	return false
+}
+
+// EstimateCount counts exactly: SQLite keeps no row estimates, and its
+// databases are small.
+func (sqliteDialect) EstimateCount(query *gorm.DB) (int64, error) {
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// sqliteMigrations mirrors the PostgreSQL invariants. SQLite cannot add CHECK
+// constraints to an existing table and GORM keeps only one check tag per
+// field, so the non-negative stock rule is enforced with triggers instead.
+func sqliteMigrations() []sqlMigration {
+	return []sqlMigration{
+		{
+			name: "available copies non-negative insert trigger",
+			statement: `
+				CREATE TRIGGER IF NOT EXISTS available_copies_non_negative_insert
+				BEFORE INSERT ON books
+				WHEN NEW.available_copies < 0
+				BEGIN
+					SELECT RAISE(ABORT, 'CHECK constraint failed: available_copies_non_negative');
+				END
+			`,
+		},
+		{
+			name: "available copies non-negative update trigger",
+			statement: `
+				CREATE TRIGGER IF NOT EXISTS available_copies_non_negative_update
+				BEFORE UPDATE OF available_copies ON books
+				WHEN NEW.available_copies < 0
+				BEGIN
+					SELECT RAISE(ABORT, 'CHECK constraint failed: available_copies_non_negative');
+				END
+			`,
+		},
+		{
+			name: "active borrow unique index",
+			statement: `
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_borrow_records_active_user_book
+				ON borrow_records (user_id, book_id)
+				WHERE return_date IS NULL
+			`,
+		},
+		{
+			name: "active borrow due date index",
+			statement: `
+				CREATE INDEX IF NOT EXISTS idx_borrow_records_active_due_date
+				ON borrow_records (due_date)
+				WHERE return_date IS NULL
+			`,
+		},
+		{
+			name: "active borrow user created index",
+			statement: `
+				CREATE INDEX IF NOT EXISTS idx_borrow_records_active_user_created_at
+				ON borrow_records (user_id, created_at DESC)
+				WHERE return_date IS NULL
+			`,
+		},
+	}
+}
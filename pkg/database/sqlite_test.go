@@ -0,0 +1,85 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newSQLiteDB(t *testing.T, path string) *gorm.DB {
+	t.Helper()
+
+	db, err := Open(&Config{Driver: DriverSQLite, Path: path})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	require.NoError(t, AutoMigrate(db))
+	return db
+}
+
+func TestDialectFor_RejectsUnknownDriver(t *testing.T) {
+	_, err := DialectFor("mysql")
+
+	assert.ErrorContains(t, err, `unsupported database driver "mysql"`)
+}
+
+func TestSQLiteMigrations_AreIdempotent(t *testing.T) {
+	db := newSQLiteDB(t, ":memory:")
+
+	assert.NoError(t, AutoMigrate(db))
+	assert.Equal(t, DriverSQLite, DialectOf(db).Name())
+}
+
+func TestSQLite_EnforcesStockConstraints(t *testing.T) {
+	db := newSQLiteDB(t, ":memory:")
+
+	book := &models.Book{ISBN: "9781234567897", Title: "Book", Author: "Author", TotalCopies: 2, AvailableCopies: 2}
+	require.NoError(t, db.Create(book).Error)
+
+	err := db.Model(book).Update("available_copies", 3).Error
+	assert.Error(t, err)
+
+	err = db.Model(book).Update("available_copies", -1).Error
+	assert.Error(t, err)
+}
+
+func TestSQLite_EnforcesSingleActiveBorrowPerUserAndBook(t *testing.T) {
+	db := newSQLiteDB(t, ":memory:")
+
+	user := &models.User{Username: "reader", Email: "reader@example.com", PasswordHash: "hash", IsActive: true}
+	book := &models.Book{ISBN: "9781234567897", Title: "Book", Author: "Author", TotalCopies: 2, AvailableCopies: 2}
+	require.NoError(t, db.Create(user).Error)
+	require.NoError(t, db.Create(book).Error)
+
+	now := time.Now()
+	first := &models.BorrowRecord{UserID: user.ID, BookID: &book.ID, BorrowDate: now, DueDate: now.Add(time.Hour)}
+	require.NoError(t, db.Create(first).Error)
+
+	duplicate := &models.BorrowRecord{UserID: user.ID, BookID: &book.ID, BorrowDate: now, DueDate: now.Add(time.Hour)}
+	assert.Error(t, db.Create(duplicate).Error)
+
+	require.NoError(t, db.Model(first).Update("return_date", now).Error)
+	again := &models.BorrowRecord{UserID: user.ID, BookID: &book.ID, BorrowDate: now, DueDate: now.Add(time.Hour)}
+	assert.NoError(t, db.Create(again).Error)
+}
+
+func TestSQLite_ILikeFoldsUnicode(t *testing.T) {
+	db := newSQLiteDB(t, filepath.Join(t.TempDir(), "library.db"))
+
+	require.NoError(t, db.Create(&models.Book{ISBN: "9781234567897", Title: "ÉTUDES Françaises", Author: "Author", TotalCopies: 1}).Error)
+
+	var count int64
+	err := db.Model(&models.Book{}).Where(DialectOf(db).ILike("title"), "%études%").Count(&count).Error
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
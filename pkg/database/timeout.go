@@ -0,0 +1,77 @@
+// pkg/database/timeout.go
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	statementTimeoutCancelKey = "statement_timeout:cancel"
+	statementTimeoutParentKey = "statement_timeout:parent"
+)
+
+// statementTimeout is a GORM plugin that bounds every statement by a deadline
+// derived from the statement's own context. Request cancellation still flows
+// through unchanged; the timeout only tightens deadlines that are longer.
+//
+// Row/Rows queries are deliberately not covered: their result is consumed
+// after the callback chain returns, so the caller owns their deadline.
+type statementTimeout struct {
+	timeout time.Duration
+}
+
+// StatementTimeout returns a plugin that applies timeout to each statement.
+func StatementTimeout(timeout time.Duration) gorm.Plugin {
+	return statementTimeout{timeout: timeout}
+}
+
+func (p statementTimeout) Name() string {
+	return "statement_timeout"
+}
+
+func (p statementTimeout) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("statement_timeout:before_create", p.before),
+		callbacks.Create().After("gorm:create").Register("statement_timeout:after_create", p.after),
+		callbacks.Query().Before("gorm:query").Register("statement_timeout:before_query", p.before),
+		callbacks.Query().After("gorm:query").Register("statement_timeout:after_query", p.after),
+		callbacks.Update().Before("gorm:update").Register("statement_timeout:before_update", p.before),
+		callbacks.Update().After("gorm:update").Register("statement_timeout:after_update", p.after),
+		callbacks.Delete().Before("gorm:delete").Register("statement_timeout:before_delete", p.before),
+		callbacks.Delete().After("gorm:delete").Register("statement_timeout:after_delete", p.after),
+		callbacks.Raw().Before("gorm:raw").Register("statement_timeout:before_raw", p.before),
+		callbacks.Raw().After("gorm:raw").Register("statement_timeout:after_raw", p.after),
+	)
+}
+
+func (p statementTimeout) before(db *gorm.DB) {
+	parent := db.Statement.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	if deadline, ok := parent.Deadline(); ok && time.Until(deadline) <= p.timeout {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(parent, p.timeout)
+	db.Statement.Context = ctx
+	db.InstanceSet(statementTimeoutParentKey, parent)
+	db.InstanceSet(statementTimeoutCancelKey, cancel)
+}
+
+// after releases the timer and restores the caller's context, because chained
+// queries (e.g. Count followed by Find) reuse the same statement.
+func (p statementTimeout) after(db *gorm.DB) {
+	if cancel, ok := db.InstanceGet(statementTimeoutCancelKey); ok {
+		cancel.(context.CancelFunc)()
+	}
+	if parent, ok := db.InstanceGet(statementTimeoutParentKey); ok {
+		db.Statement.Context = parent.(context.Context)
+	}
+}
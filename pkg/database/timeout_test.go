@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementTimeout_AbortsSlowStatements(t *testing.T) {
+	db := newSQLiteDB(t, ":memory:")
+	require.NoError(t, db.Use(StatementTimeout(time.Nanosecond)))
+
+	var count int64
+	err := db.WithContext(context.Background()).Model(&models.Book{}).Count(&count).Error
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestStatementTimeout_KeepsCallerCancellation(t *testing.T) {
+	db := newSQLiteDB(t, ":memory:")
+	require.NoError(t, db.Use(StatementTimeout(time.Minute)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var books []models.Book
+	err := db.WithContext(ctx).Find(&books).Error
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestStatementTimeout_RestoresContextBetweenChainedStatements(t *testing.T) {
+	db := newSQLiteDB(t, ":memory:")
+	require.NoError(t, db.Use(StatementTimeout(time.Minute)))
+	require.NoError(t, db.Create(&models.Book{ISBN: "9781234567897", Title: "Book", Author: "Author", TotalCopies: 1, AvailableCopies: 1}).Error)
+
+	var (
+		count int64
+		books []models.Book
+	)
+	query := db.WithContext(context.Background()).Model(&models.Book{})
+	require.NoError(t, query.Count(&count).Error)
+	require.NoError(t, query.Find(&books).Error)
+
+	assert.Equal(t, int64(1), count)
+	assert.Len(t, books, 1)
+}
@@ -0,0 +1,179 @@
+// Package isbn validates, normalizes and formats International Standard Book
+// Numbers. The canonical form used for storage and lookup is the 13-digit
+// ISBN-13 without separators.
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidLength    = errors.New("isbn must have 10 or 13 digits")
+	ErrInvalidCharacter = errors.New("isbn contains invalid characters")
+	ErrInvalidChecksum  = errors.New("isbn check digit is invalid")
+	ErrInvalidPrefix    = errors.New("isbn-13 must start with 978 or 979")
+	ErrNoISBN10         = errors.New("only 978-prefixed isbn-13 values have an isbn-10 form")
+)
+
+// Clean strips an optional "ISBN", "ISBN-10" or "ISBN-13" label, hyphens and
+// spaces, and upper-cases a trailing x. It does not validate the result.
+func Clean(s string) string {
+	s = strings.TrimSpace(s)
+	upper := strings.ToUpper(s)
+	for _, label := range []string{"ISBN-13", "ISBN-10", "ISBN13", "ISBN10", "ISBN"} {
+		if strings.HasPrefix(upper, label) {
+			s = strings.TrimLeft(s[len(label):], ": ")
+			break
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		switch {
+		case r == '-' || r == ' ' || r == '‐' || r == '‑' || r == '–':
+		case r == 'x':
+			b.WriteRune('X')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Normalize validates s as an ISBN-10 or ISBN-13 and returns its canonical
+// ISBN-13 form.
+func Normalize(s string) (string, error) {
+	digits := Clean(s)
+
+	switch len(digits) {
+	case 10:
+		if err := validate10(digits); err != nil {
+			return "", err
+		}
+		return convert10(digits), nil
+	case 13:
+		if err := validate13(digits); err != nil {
+			return "", err
+		}
+		return digits, nil
+	default:
+		return "", ErrInvalidLength
+	}
+}
+
+// Valid reports whether s is a valid ISBN-10 or ISBN-13.
+func Valid(s string) bool {
+	_, err := Normalize(s)
+	return err == nil
+}
+
+// ToISBN13 returns the ISBN-13 form of a valid ISBN-10 or ISBN-13.
+func ToISBN13(s string) (string, error) {
+	return Normalize(s)
+}
+
+// ToISBN10 returns the ISBN-10 form of s. Only 978-prefixed numbers have one.
+func ToISBN10(s string) (string, error) {
+	isbn13, err := Normalize(s)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(isbn13, "978") {
+		return "", ErrNoISBN10
+	}
+
+	body := isbn13[3:12]
+	return body + string(checkDigit10(body)), nil
+}
+
+// Format returns the hyphenated ISBN-13 form of s, e.g. 978-0-306-40615-7.
+// Registrant boundaries are known for the registration groups in ranges.go;
+// other groups are split after the group only. Invalid input is returned as is.
+func Format(s string) string {
+	isbn13, err := Normalize(s)
+	if err != nil {
+		return s
+	}
+
+	prefix, rest := isbn13[:3], isbn13[3:12]
+	check := isbn13[12:]
+
+	groupLen := groupLength(prefix, rest)
+	if groupLen == 0 {
+		return prefix + "-" + rest + "-" + check
+	}
+	group, rest := rest[:groupLen], rest[groupLen:]
+
+	registrantLen := registrantLength(prefix+"-"+group, rest)
+	if registrantLen == 0 || registrantLen >= len(rest) {
+		return prefix + "-" + group + "-" + rest + "-" + check
+	}
+	return prefix + "-" + group + "-" + rest[:registrantLen] + "-" + rest[registrantLen:] + "-" + check
+}
+
+func validate10(s string) error {
+	for i := 0; i < 9; i++ {
+		if !isDigit(s[i]) {
+			return ErrInvalidCharacter
+		}
+	}
+	if !isDigit(s[9]) && s[9] != 'X' {
+		return ErrInvalidCharacter
+	}
+	if checkDigit10(s[:9]) != s[9] {
+		return ErrInvalidChecksum
+	}
+	return nil
+}
+
+func validate13(s string) error {
+	for i := 0; i < 13; i++ {
+		if !isDigit(s[i]) {
+			return ErrInvalidCharacter
+		}
+	}
+	if !strings.HasPrefix(s, "978") && !strings.HasPrefix(s, "979") {
+		return ErrInvalidPrefix
+	}
+	if checkDigit13(s[:12]) != s[12] {
+		return ErrInvalidChecksum
+	}
+	return nil
+}
+
+func convert10(s string) string {
+	body := "978" + s[:9]
+	return body + string(checkDigit13(body))
+}
+
+// checkDigit10 computes the mod-11 check character for nine digits.
+func checkDigit10(body string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// checkDigit13 computes the mod-10 check digit for twelve digits.
+func checkDigit13(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(body[i]-'0') * weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,101 @@
+package isbn
+
+// registrantRange maps a range of the first seven digits after the
+// registration group to the length of the registrant element, as published in
+// the International ISBN Agency's RangeMessage.
+type registrantRange struct {
+	lo, hi string
+	length int
+}
+
+// registrantRanges covers the registration groups most of our catalog comes
+// from. Extend it from RangeMessage.xml when another group needs full
+// hyphenation.
+var registrantRanges = map[string][]registrantRange{
+	// English language
+	"978-0": {
+		{"0000000", "1999999", 2},
+		{"2000000", "2279999", 3},
+		{"2280000", "2289999", 4},
+		{"2290000", "6479999", 3},
+		{"6480000", "6489999", 7},
+		{"6490000", "6999999", 3},
+		{"7000000", "8499999", 4},
+		{"8500000", "8999999", 5},
+		{"9000000", "9499999", 6},
+		{"9500000", "9999999", 7},
+	},
+	"978-1": {
+		{"0000000", "0999999", 2},
+		{"1000000", "3999999", 3},
+		{"4000000", "5499999", 4},
+		{"5500000", "8697999", 5},
+		{"8698000", "9989999", 6},
+		{"9990000", "9999999", 7},
+	},
+	// German language
+	"978-3": {
+		{"0000000", "0299999", 2},
+		{"0300000", "0339999", 3},
+		{"0340000", "0369999", 4},
+		{"0370000", "0399999", 5},
+		{"0400000", "1999999", 2},
+		{"2000000", "6999999", 3},
+		{"7000000", "8499999", 4},
+		{"8500000", "8999999", 5},
+		{"9000000", "9499999", 6},
+		{"9500000", "9539999", 7},
+		{"9540000", "9699999", 5},
+		{"9700000", "9849999", 7},
+		{"9850000", "9999999", 5},
+	},
+}
+
+// groupLength returns the length of the registration group at the start of
+// rest (the nine digits after the EAN prefix), or 0 when unknown.
+func groupLength(prefix, rest string) int {
+	switch prefix {
+	case "978":
+		switch {
+		case rest[0] <= '5' || rest[0] == '7':
+			return 1
+		case rest[:2] == "65":
+			return 2
+		case rest[0] == '6':
+			return 3
+		case rest[:2] >= "80" && rest[:2] <= "94":
+			return 2
+		case rest[:3] >= "950" && rest[:3] <= "989":
+			return 3
+		case rest[:4] >= "9900" && rest[:4] <= "9989":
+			return 4
+		case rest[:3] == "999":
+			return 5
+		}
+	case "979":
+		switch {
+		case rest[0] == '8':
+			return 1
+		case rest[:2] >= "10" && rest[:2] <= "12":
+			return 2
+		}
+	}
+	return 0
+}
+
+// registrantLength returns the registrant length for the group, or 0 when the
+// group's ranges are not known.
+func registrantLength(group, rest string) int {
+	key := rest
+	for len(key) < 7 {
+		key += "0"
+	}
+	key = key[:7]
+
+	for _, r := range registrantRanges[group] {
+		if key >= r.lo && key <= r.hi {
+			return r.length
+		}
+	}
+	return 0
+}
@@ -0,0 +1,212 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+const (
+	subfieldDelimiter = 0x1F
+	fieldTerminator   = 0x1E
+	recordTerminator  = 0x1D
+
+	leaderLength    = 24
+	directoryEntry  = 12
+	maxRecordLength = 99999
+)
+
+// Reader reads ISO 2709 records from a stream.
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read returns the next record, or io.EOF when the stream is exhausted. A
+// malformed record yields an error wrapping ErrMalformed; reading can continue
+// with the record after it.
+func (r *Reader) Read() (*Record, error) {
+	for {
+		data, err := r.r.ReadBytes(recordTerminator)
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(data)) == 0 {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("%w: missing record terminator", ErrMalformed)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Files are often written one record per line.
+		data = bytes.TrimLeft(data, "\r\n\t ")
+		if len(data) == 1 {
+			continue
+		}
+		return Unmarshal(data)
+	}
+}
+
+// Unmarshal parses one ISO 2709 record, record terminator included.
+func Unmarshal(data []byte) (*Record, error) {
+	if len(data) < leaderLength+1 {
+		return nil, fmt.Errorf("%w: record shorter than its leader", ErrMalformed)
+	}
+
+	leader := string(data[:leaderLength])
+	base, ok := parseDigits(leader[12:17])
+	if !ok || base <= leaderLength || base > len(data) {
+		return nil, fmt.Errorf("%w: invalid base address %q", ErrMalformed, leader[12:17])
+	}
+	if leader[9] != 'a' && !isASCII(data) {
+		return nil, ErrUnsupportedEncoding
+	}
+
+	directory := data[leaderLength : base-1]
+	if len(directory)%directoryEntry != 0 || data[base-1] != fieldTerminator {
+		return nil, fmt.Errorf("%w: invalid directory", ErrMalformed)
+	}
+
+	rec := &Record{Leader: leader}
+	for i := 0; i < len(directory); i += directoryEntry {
+		entry := directory[i : i+directoryEntry]
+		tag := string(entry[:3])
+		length, lenOK := parseDigits(string(entry[3:7]))
+		start, startOK := parseDigits(string(entry[7:12]))
+		if !lenOK || !startOK || length < 1 || start < 0 || base+start < base || base+start+length > len(data) {
+			return nil, fmt.Errorf("%w: invalid directory entry for tag %s", ErrMalformed, tag)
+		}
+
+		raw := data[base+start : base+start+length]
+		raw = bytes.TrimSuffix(raw, []byte{fieldTerminator})
+		if !utf8.Valid(raw) {
+			return nil, fmt.Errorf("%w: tag %s is not valid UTF-8", ErrMalformed, tag)
+		}
+
+		field, err := parseField(tag, raw)
+		if err != nil {
+			return nil, err
+		}
+		rec.Fields = append(rec.Fields, field)
+	}
+	return rec, nil
+}
+
+// parseDigits reads a fixed-width number of the leader or directory. Unlike
+// strconv.Atoi it accepts ASCII digits only, so no sign slips through.
+func parseDigits(s string) (int, bool) {
+	if s == "" {
+		return 0, false
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+		n = n*10 + int(s[i]-'0')
+	}
+	return n, true
+}
+
+func parseField(tag string, raw []byte) (Field, error) {
+	if IsControlTag(tag) {
+		return Field{Tag: tag, Value: string(raw)}, nil
+	}
+	if len(raw) < 2 {
+		return Field{}, fmt.Errorf("%w: tag %s has no indicators", ErrMalformed, tag)
+	}
+
+	field := Field{Tag: tag, Ind1: raw[0], Ind2: raw[1]}
+	for _, part := range bytes.Split(raw[2:], []byte{subfieldDelimiter}) {
+		if len(part) == 0 {
+			continue
+		}
+		field.Subfields = append(field.Subfields, Subfield{Code: part[0], Value: string(part[1:])})
+	}
+	return field, nil
+}
+
+// Marshal encodes rec as ISO 2709 in UTF-8. The leader's record length, base
+// address and character coding positions are set from the content.
+func Marshal(rec *Record) ([]byte, error) {
+	if err := rec.validate(); err != nil {
+		return nil, err
+	}
+
+	var body, directory bytes.Buffer
+	for _, f := range rec.Fields {
+		start := body.Len()
+		if f.IsControl() {
+			body.WriteString(f.Value)
+		} else {
+			body.WriteByte(indicator(f.Ind1))
+			body.WriteByte(indicator(f.Ind2))
+			for _, sf := range f.Subfields {
+				body.WriteByte(subfieldDelimiter)
+				body.WriteByte(sf.Code)
+				body.WriteString(sf.Value)
+			}
+		}
+		body.WriteByte(fieldTerminator)
+
+		length := body.Len() - start
+		if length > 9999 || start > 99999 {
+			return nil, ErrRecordTooLong
+		}
+		fmt.Fprintf(&directory, "%s%04d%05d", f.Tag, length, start)
+	}
+	body.WriteByte(recordTerminator)
+
+	base := leaderLength + directory.Len() + 1
+	total := base + body.Len()
+	if total > maxRecordLength {
+		return nil, ErrRecordTooLong
+	}
+
+	leader := []byte(rec.Leader)
+	copy(leader[0:5], fmt.Sprintf("%05d", total))
+	leader[9] = 'a'
+	leader[10], leader[11] = '2', '2'
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+	copy(leader[20:24], "4500")
+
+	out := make([]byte, 0, total)
+	out = append(out, leader...)
+	out = append(out, directory.Bytes()...)
+	out = append(out, fieldTerminator)
+	out = append(out, body.Bytes()...)
+	return out, nil
+}
+
+// Writer writes ISO 2709 records to a stream.
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (w *Writer) Write(rec *Record) error {
+	data, err := Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(data)
+	return err
+}
+
+func isASCII(data []byte) bool {
+	for _, b := range data {
+		if b >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
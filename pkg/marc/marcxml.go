@@ -0,0 +1,209 @@
+package marc
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Namespace is the MARCXML (MARC 21 slim) namespace.
+const Namespace = "http://www.loc.gov/MARC21/slim"
+
+type xmlRecord struct {
+	XMLName       xml.Name          `xml:"record"`
+	Xmlns         string            `xml:"xmlns,attr,omitempty"`
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// XMLReader reads records from a MARCXML document. Both a <collection> of
+// records and a single <record> root are accepted.
+type XMLReader struct {
+	dec *xml.Decoder
+}
+
+func NewXMLReader(r io.Reader) *XMLReader {
+	return &XMLReader{dec: xml.NewDecoder(r)}
+}
+
+// Read returns the next record, or io.EOF at the end of the document. Invalid
+// content inside a well-formed record yields an error wrapping ErrMalformed
+// and reading can continue; XML syntax errors end the stream.
+func (r *XMLReader) Read() (*Record, error) {
+	for {
+		token, err := r.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+
+		var raw xmlRecord
+		if err := r.dec.DecodeElement(&raw, &start); err != nil {
+			return nil, err
+		}
+		return raw.record()
+	}
+}
+
+func (x *xmlRecord) record() (*Record, error) {
+	leader := strings.TrimSpace(x.Leader)
+	if leader == "" {
+		leader = DefaultLeader
+	}
+	// Leaders are often stored with their trailing blanks trimmed.
+	if len(leader) < 24 {
+		leader += strings.Repeat(" ", 24-len(leader))
+	}
+	rec := &Record{Leader: leader}
+
+	for _, cf := range x.ControlFields {
+		if !IsControlTag(cf.Tag) {
+			return nil, fmt.Errorf("%w: controlfield with tag %q", ErrMalformed, cf.Tag)
+		}
+		rec.Fields = append(rec.Fields, Field{Tag: cf.Tag, Value: cf.Value})
+	}
+	for _, df := range x.DataFields {
+		if len(df.Tag) != 3 || IsControlTag(df.Tag) {
+			return nil, fmt.Errorf("%w: datafield with tag %q", ErrMalformed, df.Tag)
+		}
+		field := Field{Tag: df.Tag, Ind1: xmlIndicator(df.Ind1), Ind2: xmlIndicator(df.Ind2)}
+		for _, sf := range df.Subfields {
+			if len(sf.Code) != 1 {
+				return nil, fmt.Errorf("%w: subfield code %q in tag %s", ErrMalformed, sf.Code, df.Tag)
+			}
+			field.Subfields = append(field.Subfields, Subfield{Code: sf.Code[0], Value: sf.Value})
+		}
+		rec.Fields = append(rec.Fields, field)
+	}
+
+	if err := rec.validate(); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func xmlIndicator(s string) byte {
+	if s == "" {
+		return ' '
+	}
+	return s[0]
+}
+
+func toXML(rec *Record) xmlRecord {
+	x := xmlRecord{Leader: rec.Leader}
+	for _, f := range rec.Fields {
+		if f.IsControl() {
+			x.ControlFields = append(x.ControlFields, xmlControlField{Tag: f.Tag, Value: f.Value})
+			continue
+		}
+		df := xmlDataField{Tag: f.Tag, Ind1: string(indicator(f.Ind1)), Ind2: string(indicator(f.Ind2))}
+		for _, sf := range f.Subfields {
+			df.Subfields = append(df.Subfields, xmlSubfield{Code: string(sf.Code), Value: sf.Value})
+		}
+		x.DataFields = append(x.DataFields, df)
+	}
+	return x
+}
+
+// MarshalXML encodes rec as a standalone MARCXML <record> document.
+func MarshalXML(rec *Record) ([]byte, error) {
+	if err := rec.validate(); err != nil {
+		return nil, err
+	}
+
+	x := toXML(rec)
+	x.Xmlns = Namespace
+	body, err := xml.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// UnmarshalXML parses a single MARCXML record.
+func UnmarshalXML(data []byte) (*Record, error) {
+	rec, err := NewXMLReader(strings.NewReader(string(data))).Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: no record element", ErrMalformed)
+	}
+	return rec, err
+}
+
+// XMLWriter writes records into a MARCXML <collection>. Call Close to end the
+// document.
+type XMLWriter struct {
+	w       io.Writer
+	enc     *xml.Encoder
+	started bool
+	closed  bool
+}
+
+func NewXMLWriter(w io.Writer) *XMLWriter {
+	enc := xml.NewEncoder(w)
+	enc.Indent("  ", "  ")
+	return &XMLWriter{w: w, enc: enc}
+}
+
+func (w *XMLWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	_, err := io.WriteString(w.w, xml.Header+`<collection xmlns="`+Namespace+`">`)
+	return err
+}
+
+func (w *XMLWriter) Write(rec *Record) error {
+	if err := rec.validate(); err != nil {
+		return err
+	}
+	if err := w.start(); err != nil {
+		return err
+	}
+	return w.enc.Encode(toXML(rec))
+}
+
+// Flush pushes encoded records to the underlying writer.
+func (w *XMLWriter) Flush() error {
+	return w.enc.Flush()
+}
+
+// Close ends the collection. An empty collection is still a valid document.
+func (w *XMLWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if err := w.start(); err != nil {
+		return err
+	}
+	if err := w.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w.w, "\n</collection>\n")
+	return err
+}
@@ -0,0 +1,165 @@
+// Package marc reads and writes MARC 21 bibliographic records in ISO 2709
+// (binary) and MARCXML form. It models records generically; mapping fields to
+// the catalog happens in the service layer.
+package marc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultLeader is used for records created from scratch: a new, language
+// material, monograph record in UTF-8 with ISBD punctuation. Lengths and the
+// base address are filled in when the record is written.
+const DefaultLeader = "00000nam a2200000 i 4500"
+
+var (
+	ErrMalformed           = errors.New("marc: malformed record")
+	ErrRecordTooLong       = errors.New("marc: record exceeds 99999 bytes")
+	ErrUnsupportedEncoding = errors.New("marc: MARC-8 records with non-ASCII characters are not supported; convert them to UTF-8")
+)
+
+// Record is one MARC record. Fields keep the order they were read in.
+type Record struct {
+	Leader string
+	Fields []Field
+}
+
+// Field is a control field (tags 001-009), which only has Value, or a data
+// field with two indicators and subfields.
+type Field struct {
+	Tag       string
+	Value     string
+	Ind1      byte
+	Ind2      byte
+	Subfields []Subfield
+}
+
+type Subfield struct {
+	Code  byte
+	Value string
+}
+
+// NewRecord returns an empty record with DefaultLeader.
+func NewRecord() *Record {
+	return &Record{Leader: DefaultLeader}
+}
+
+// IsControlTag reports whether tag names a control field.
+func IsControlTag(tag string) bool {
+	return len(tag) == 3 && tag < "010" && strings.HasPrefix(tag, "00")
+}
+
+// IsControl reports whether f is a control field.
+func (f *Field) IsControl() bool {
+	return IsControlTag(f.Tag)
+}
+
+// Subfield returns the first value of the subfield with code, or "".
+func (f *Field) Subfield(code byte) string {
+	for _, sf := range f.Subfields {
+		if sf.Code == code {
+			return sf.Value
+		}
+	}
+	return ""
+}
+
+// SetSubfield replaces the first subfield with code, or appends one.
+func (f *Field) SetSubfield(code byte, value string) {
+	for i := range f.Subfields {
+		if f.Subfields[i].Code == code {
+			f.Subfields[i].Value = value
+			return
+		}
+	}
+	f.Subfields = append(f.Subfields, Subfield{Code: code, Value: value})
+}
+
+// String renders the field in the usual display form, e.g.
+// "245 10 $aTitle :$bsubtitle".
+func (f *Field) String() string {
+	if f.IsControl() {
+		return f.Tag + " " + f.Value
+	}
+	var b strings.Builder
+	b.WriteString(f.Tag + " " + string(indicator(f.Ind1)) + string(indicator(f.Ind2)) + " ")
+	for _, sf := range f.Subfields {
+		b.WriteString("$" + string(sf.Code) + sf.Value)
+	}
+	return b.String()
+}
+
+// Field returns the first field with tag, or nil.
+func (r *Record) Field(tag string) *Field {
+	for i := range r.Fields {
+		if r.Fields[i].Tag == tag {
+			return &r.Fields[i]
+		}
+	}
+	return nil
+}
+
+// FieldsByTag returns every field with tag, in record order.
+func (r *Record) FieldsByTag(tag string) []*Field {
+	var fields []*Field
+	for i := range r.Fields {
+		if r.Fields[i].Tag == tag {
+			fields = append(fields, &r.Fields[i])
+		}
+	}
+	return fields
+}
+
+// AddField inserts f after the last field whose tag sorts before or equal to
+// f.Tag, keeping the record in tag order.
+func (r *Record) AddField(f Field) {
+	i := sort.Search(len(r.Fields), func(i int) bool { return r.Fields[i].Tag > f.Tag })
+	r.Fields = append(r.Fields, Field{})
+	copy(r.Fields[i+1:], r.Fields[i:])
+	r.Fields[i] = f
+}
+
+// SetControlField replaces the first control field with tag, or adds one.
+func (r *Record) SetControlField(tag, value string) {
+	if f := r.Field(tag); f != nil {
+		f.Value = value
+		return
+	}
+	r.AddField(Field{Tag: tag, Value: value})
+}
+
+// RemoveFields drops the fields with tag for which match returns true. A nil
+// match removes every field with tag.
+func (r *Record) RemoveFields(tag string, match func(*Field) bool) {
+	kept := r.Fields[:0]
+	for i := range r.Fields {
+		f := &r.Fields[i]
+		if f.Tag == tag && (match == nil || match(f)) {
+			continue
+		}
+		kept = append(kept, *f)
+	}
+	r.Fields = kept
+}
+
+func (r *Record) validate() error {
+	if len(r.Leader) != 24 {
+		return fmt.Errorf("%w: leader must be 24 characters, got %d", ErrMalformed, len(r.Leader))
+	}
+	for _, f := range r.Fields {
+		if len(f.Tag) != 3 {
+			return fmt.Errorf("%w: invalid tag %q", ErrMalformed, f.Tag)
+		}
+	}
+	return nil
+}
+
+func indicator(b byte) byte {
+	if b == 0 {
+		return ' '
+	}
+	return b
+}
@@ -0,0 +1,69 @@
+// Package mergepatch applies JSON merge patches (RFC 7396).
+package mergepatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ContentType is the media type of merge patch documents.
+const ContentType = "application/merge-patch+json"
+
+// ErrNotObject is returned for a patch that is not a JSON object. Such a
+// patch would replace the whole document, which a resource cannot accept.
+var ErrNotObject = errors.New("merge patch must be a JSON object")
+
+// Apply merges patch into the JSON document target: members of the patch
+// replace those of the target, objects are merged recursively and null
+// removes a member.
+func Apply(target, patch []byte) ([]byte, error) {
+	var patchValue any
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	patchObject, ok := patchValue.(map[string]any)
+	if !ok {
+		return nil, ErrNotObject
+	}
+
+	var targetValue any
+	if err := json.Unmarshal(target, &targetValue); err != nil {
+		return nil, fmt.Errorf("invalid merge patch target: %w", err)
+	}
+	return json.Marshal(merge(targetValue, patchObject))
+}
+
+// Fields returns the top-level members a patch sets or removes.
+func Fields(patch []byte) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, ErrNotObject
+		}
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	if fields == nil {
+		return nil, ErrNotObject
+	}
+	return fields, nil
+}
+
+func merge(target any, patch map[string]any) map[string]any {
+	result, ok := target.(map[string]any)
+	if !ok {
+		result = make(map[string]any, len(patch))
+	}
+	for name, value := range patch {
+		switch value := value.(type) {
+		case nil:
+			delete(result, name)
+		case map[string]any:
+			result[name] = merge(result[name], value)
+		default:
+			result[name] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+// Package names normalizes personal and corporate names so that different
+// renderings of the same name ("J.K. Rowling", "Rowling, J. K.") compare equal,
+// and folds other catalog terms such as subject headings.
+package names
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Key returns the comparison form of name: inverted "Surname, Given" names are
+// put in direct order, case is folded and punctuation is dropped, so initials
+// written with or without periods and spaces match. It returns "" for a name
+// without letters or digits.
+func Key(name string) string {
+	return Fold(Direct(name))
+}
+
+// Fold lowercases s and replaces every run of punctuation and spaces with a
+// single space, so "Sci-Fi" and "sci fi" compare equal. Unlike Key it keeps
+// the word order.
+func Fold(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// Direct puts an inverted name in direct order: "Rowling, J. K." becomes
+// "J. K. Rowling" and "King, Martin Luther, Jr." becomes
+// "Martin Luther King, Jr.". Names without a comma are returned trimmed.
+func Direct(name string) string {
+	parts := splitComma(name)
+	if len(parts) < 2 {
+		return strings.TrimSpace(name)
+	}
+	direct := parts[1] + " " + parts[0]
+	if len(parts) > 2 {
+		direct += ", " + strings.Join(parts[2:], ", ")
+	}
+	return direct
+}
+
+// Inverted returns the "Surname, Given" form used for sorting. A name that is
+// already inverted is returned as is; otherwise its last word is taken as the
+// surname.
+func Inverted(name string) string {
+	name = strings.TrimSpace(name)
+	if strings.Contains(name, ",") {
+		return strings.Join(splitComma(name), ", ")
+	}
+	i := strings.LastIndexByte(name, ' ')
+	if i < 0 {
+		return name
+	}
+	return name[i+1:] + ", " + strings.TrimSpace(name[:i])
+}
+
+// splitComma splits on commas, dropping the empty parts left by trailing
+// punctuation such as "Martin, Robert C.,".
+func splitComma(name string) []string {
+	var parts []string
+	for _, part := range strings.Split(name, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
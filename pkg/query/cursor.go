@@ -0,0 +1,99 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Count modes for list totals: exact counts every matching row, estimate asks
+// the query planner and none skips the total.
+const (
+	CountExact    = "exact"
+	CountEstimate = "estimate"
+	CountNone     = "none"
+)
+
+// Cursor is a position in a sorted list, handed to clients as an opaque
+// token. Key holds the sort column values and ID of the row a page starts
+// after, or before when Before is set; sorts without a usable key (such as
+// relevance) carry an Offset instead.
+type Cursor struct {
+	Sort   string   `json:"s"`
+	Key    []string `json:"k,omitempty"`
+	Offset int      `json:"o,omitempty"`
+	Before bool     `json:"b,omitempty"`
+}
+
+// Encode returns the cursor as a URL-safe token.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor reads a token made by Encode.
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.Offset < 0 {
+		return nil, errors.New("negative cursor offset")
+	}
+	return &cursor, nil
+}
+
+// PageRequest selects a page of a sorted list: the one Cursor points at, or
+// else the 1-based Page. Count is one of the count modes.
+type PageRequest struct {
+	Page   int
+	Limit  int
+	Sort   string
+	Cursor *Cursor
+	Count  string
+}
+
+// PageInfo describes a returned page. Total is only set when Count is not
+// CountNone; the cursors are empty at the ends of the list.
+type PageInfo struct {
+	Total      int64
+	Count      string
+	NextCursor string
+	PrevCursor string
+}
+
+// PageMeta returns the pagination metadata of a list response. Cursor pages
+// have no page number, and totals are left out when they were skipped.
+func PageMeta(req PageRequest, info PageInfo) gin.H {
+	meta := gin.H{
+		"limit":       req.Limit,
+		"next_cursor": nil,
+		"prev_cursor": nil,
+	}
+	if req.Sort != "" {
+		meta["sort"] = req.Sort
+	}
+	if req.Cursor == nil {
+		meta["page"] = req.Page
+	}
+	if info.NextCursor != "" {
+		meta["next_cursor"] = info.NextCursor
+	}
+	if info.PrevCursor != "" {
+		meta["prev_cursor"] = info.PrevCursor
+	}
+	if info.Count != CountNone {
+		meta["total"] = info.Total
+		meta["total_pages"] = TotalPages(info.Total, req.Limit)
+	}
+	if info.Count == CountEstimate {
+		meta["total_estimated"] = true
+	}
+	return meta
+}
@@ -12,6 +12,10 @@ type ListParams struct {
 	Limit  int
 	Search string
 	Sort   string
+	// Cursor is set when the request continues from a next_cursor or
+	// prev_cursor; Page is then ignored.
+	Cursor *Cursor
+	Count  string
 }
 
 type ListOptions struct {
@@ -38,7 +42,21 @@ func ParseListParams(c *gin.Context, opts ListOptions) (ListParams, error) {
 
 	search := strings.TrimSpace(c.Query("search"))
 
+	var cursor *Cursor
+	if token := strings.TrimSpace(c.Query("cursor")); token != "" {
+		cursor, err = DecodeCursor(token)
+		if err != nil {
+			return ListParams{}, apperror.BadRequest("invalid cursor query")
+		}
+	}
+
 	sort := strings.TrimSpace(c.Query("sort"))
+	if cursor != nil {
+		if sort != "" && sort != cursor.Sort {
+			return ListParams{}, apperror.BadRequest("cursor does not match sort query")
+		}
+		sort = cursor.Sort
+	}
 	if sort == "" {
 		sort = opts.DefaultSort
 	}
@@ -48,10 +66,32 @@ func ParseListParams(c *gin.Context, opts ListOptions) (ListParams, error) {
 		}
 	}
 
+	count := strings.TrimSpace(c.Query("count"))
+	switch count {
+	case "":
+		count = CountExact
+	case CountExact, CountEstimate, CountNone:
+	default:
+		return ListParams{}, apperror.BadRequest("count must be exact, estimate or none")
+	}
+
 	return ListParams{
 		Page:   page,
 		Limit:  limit,
 		Search: search,
 		Sort:   sort,
+		Cursor: cursor,
+		Count:  count,
 	}, nil
 }
+
+// PageRequest returns the page the parameters select.
+func (p ListParams) PageRequest() PageRequest {
+	return PageRequest{
+		Page:   p.Page,
+		Limit:  p.Limit,
+		Sort:   p.Sort,
+		Cursor: p.Cursor,
+		Count:  p.Count,
+	}
+}
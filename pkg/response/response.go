@@ -1,6 +1,7 @@
 package response
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
@@ -8,9 +9,14 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// StatusClientClosedRequest is the non-standard status logged when the client
+// disconnects before the response is written.
+const StatusClientClosedRequest = 499
+
 type ErrorBody struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
 }
 
 type Envelope struct {
@@ -31,6 +37,12 @@ func Success(c *gin.Context, status int, message string, data any, meta any) {
 }
 
 func Error(c *gin.Context, err error) {
+	// Drivers do not always wrap the context error when a query is interrupted,
+	// so a finished request context takes precedence over the returned error.
+	if ctxErr := c.Request.Context().Err(); ctxErr != nil {
+		err = ctxErr
+	}
+
 	status, body := MapError(err)
 	c.JSON(status, Envelope{
 		Success: false,
@@ -40,11 +52,25 @@ func Error(c *gin.Context, err error) {
 }
 
 func MapError(err error) (int, *ErrorBody) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, &ErrorBody{
+			Code:    apperror.CodeTimeout,
+			Message: "request timed out",
+		}
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest, &ErrorBody{
+			Code:    apperror.CodeCanceled,
+			Message: "request canceled",
+		}
+	}
+
 	var appErr *apperror.AppError
 	if errors.As(err, &appErr) {
 		return statusForCode(appErr.Code), &ErrorBody{
 			Code:    appErr.Code,
 			Message: appErr.Message,
+			Fields:  appErr.Fields,
 		}
 	}
 
@@ -66,6 +92,14 @@ func statusForCode(code string) int {
 		return http.StatusNotFound
 	case apperror.CodeConflict:
 		return http.StatusConflict
+	case apperror.CodeTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case apperror.CodeUnsupported:
+		return http.StatusUnsupportedMediaType
+	case apperror.CodePreconditionFailed:
+		return http.StatusPreconditionFailed
+	case apperror.CodePreconditionRequired:
+		return http.StatusPreconditionRequired
 	default:
 		return http.StatusInternalServerError
 	}
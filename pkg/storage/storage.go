@@ -0,0 +1,99 @@
+// Package storage keeps binary objects, such as cover images, on the local
+// filesystem or in an S3-compatible bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Drivers accepted in Config.Driver.
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// ErrNotFound is returned by Get for a key that holds no object.
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage stores objects under slash-separated keys such as
+// "covers/12/original".
+type Storage interface {
+	// Put stores size bytes read from body under key, replacing any object
+	// already there.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get opens the object under key. The caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object under key; a missing object is no error.
+	Delete(ctx context.Context, key string) error
+	// URL returns where clients can fetch key directly, or "" when objects
+	// are only reachable through Get.
+	URL(key string) string
+}
+
+// Config selects and configures a storage driver.
+type Config struct {
+	Driver string `config:"driver" env:"STORAGE_DRIVER" default:"local"`
+
+	// Dir is the root directory of the local driver.
+	Dir string `config:"dir" env:"STORAGE_DIR" default:"data/storage"`
+
+	// PublicURL is the base URL clients fetch objects from directly, e.g. a
+	// CDN or a public bucket. When empty the API serves them itself.
+	PublicURL string `config:"public_url" env:"STORAGE_PUBLIC_URL"`
+
+	// S3 driver settings. Endpoint is the server URL, e.g.
+	// "https://s3.eu-west-1.amazonaws.com" or "http://localhost:9000" for
+	// MinIO; PathStyle puts the bucket in the path instead of the host name.
+	Endpoint  string `config:"endpoint" env:"S3_ENDPOINT"`
+	Region    string `config:"region" env:"S3_REGION" default:"us-east-1"`
+	Bucket    string `config:"bucket" env:"S3_BUCKET"`
+	AccessKey string `config:"access_key" env:"S3_ACCESS_KEY"`
+	SecretKey string `config:"secret_key" env:"S3_SECRET_KEY" secret:"true"`
+	PathStyle bool   `config:"path_style" env:"S3_PATH_STYLE" default:"true"`
+}
+
+// Open returns the storage described by config.
+func Open(config *Config) (Storage, error) {
+	switch config.Driver {
+	case DriverLocal:
+		return NewLocalStorage(config.Dir, config.PublicURL)
+	case DriverS3:
+		return NewS3Storage(S3Options{
+			Endpoint:  config.Endpoint,
+			Region:    config.Region,
+			Bucket:    config.Bucket,
+			AccessKey: config.AccessKey,
+			SecretKey: config.SecretKey,
+			PathStyle: config.PathStyle,
+			PublicURL: config.PublicURL,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported storage driver %q", config.Driver)
+	}
+}
+
+// validKey rejects keys that could escape the storage root or name a
+// directory.
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		return fmt.Errorf("storage: invalid key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("storage: invalid key %q", key)
+		}
+	}
+	return nil
+}
+
+// joinURL appends key to the base URL.
+func joinURL(base, key string) string {
+	if base == "" {
+		return ""
+	}
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
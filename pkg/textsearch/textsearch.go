@@ -0,0 +1,109 @@
+// Package textsearch turns a search box query into a PostgreSQL tsquery.
+// Words must all match; "quoted words" match as a phrase, a trailing * matches
+// a prefix and a leading - excludes a word or phrase:
+//
+//	dune "desert planet" herb* -film
+//
+// becomes
+//
+//	dune & (desert <-> planet) & herb:* & !film
+//
+// Only letters and digits reach the tsquery, so the result is always valid
+// to_tsquery input.
+package textsearch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed search.
+type Query struct {
+	terms []term
+}
+
+type term struct {
+	words  []string
+	prefix bool
+	negate bool
+}
+
+// Parse reads a search. Terms without letters or digits are dropped.
+func Parse(input string) Query {
+	var q Query
+	for len(input) > 0 {
+		input = strings.TrimLeftFunc(input, unicode.IsSpace)
+		if input == "" {
+			break
+		}
+
+		var t term
+		if input[0] == '-' {
+			t.negate = true
+			input = input[1:]
+		}
+
+		var raw string
+		if strings.HasPrefix(input, `"`) {
+			end := strings.IndexByte(input[1:], '"')
+			if end < 0 {
+				raw, input = input[1:], ""
+			} else {
+				raw, input = input[1:end+1], input[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(input, unicode.IsSpace)
+			if end < 0 {
+				end = len(input)
+			}
+			raw, input = input[:end], input[end:]
+		}
+
+		t.prefix = strings.HasSuffix(raw, "*")
+		t.words = words(raw)
+		if len(t.words) > 0 {
+			q.terms = append(q.terms, t)
+		}
+	}
+	return q
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Empty reports whether the query has no term that must match; a query of
+// exclusions alone cannot be searched.
+func (q Query) Empty() bool {
+	for _, t := range q.terms {
+		if !t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// TSQuery returns the query in to_tsquery syntax, or "" for an empty query.
+func (q Query) TSQuery() string {
+	if q.Empty() {
+		return ""
+	}
+	parts := make([]string, 0, len(q.terms))
+	for _, t := range q.terms {
+		words := append([]string(nil), t.words...)
+		if t.prefix {
+			words[len(words)-1] += ":*"
+		}
+		part := strings.Join(words, " <-> ")
+		if len(words) > 1 {
+			part = "(" + part + ")"
+		}
+		if t.negate {
+			part = "!" + part
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " & ")
+}
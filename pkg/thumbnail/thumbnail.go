@@ -0,0 +1,96 @@
+// Package thumbnail decodes uploaded images and scales them down to JPEG
+// thumbnails using only the standard library.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // register decoders for Decode
+	"image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+// Limits on decoded images, so a small file cannot expand into gigabytes of
+// pixels.
+const (
+	MaxSide   = 10000
+	MaxPixels = 40_000_000
+)
+
+// Quality is the JPEG quality of generated thumbnails.
+const Quality = 85
+
+// ErrTooLarge is returned by Decode for images over MaxSide or MaxPixels.
+var ErrTooLarge = errors.New("image dimensions too large")
+
+// Decode reads a JPEG, PNG or GIF image after checking its dimensions.
+func Decode(data []byte) (image.Image, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, "", fmt.Errorf("image has no pixels")
+	}
+	if config.Width > MaxSide || config.Height > MaxSide || config.Width*config.Height > MaxPixels {
+		return nil, "", ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, format, err
+}
+
+// Fit scales img down to width pixels wide, keeping its aspect ratio.
+// Transparent areas become white. Images already narrower keep their size.
+func Fit(img image.Image, width int) *image.RGBA {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Over)
+
+	if width <= 0 || width >= bounds.Dx() {
+		return src
+	}
+	height := max(1, (bounds.Dy()*width+bounds.Dx()/2)/bounds.Dx())
+	return boxResize(src, width, height)
+}
+
+// boxResize shrinks src by averaging the block of source pixels each target
+// pixel covers.
+func boxResize(src *image.RGBA, width, height int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, max((y+1)*sh/height, y*sh/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, max((x+1)*sw/width, x*sw/width+1)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8((r + n/2) / n)
+			dst.Pix[i+1] = uint8((g + n/2) / n)
+			dst.Pix[i+2] = uint8((b + n/2) / n)
+			dst.Pix[i+3] = uint8((a + n/2) / n)
+		}
+	}
+	return dst
+}
+
+// WriteJPEG encodes img as a JPEG of Quality to w.
+func WriteJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: Quality})
+}
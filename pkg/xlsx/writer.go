@@ -0,0 +1,230 @@
+// Package xlsx writes single-sheet Office Open XML spreadsheets as a stream.
+// Rows are written straight into the zip entry of the worksheet, so memory use
+// does not grow with the number of rows. Strings are stored inline rather than
+// in a shared string table for the same reason.
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxRows is the row limit of a worksheet, header included.
+	MaxRows = 1048576
+	// MaxCellLength is the longest text a cell can hold; longer values are truncated.
+	MaxCellLength = 32767
+)
+
+var (
+	ErrTooManyRows = errors.New("xlsx: worksheet row limit reached")
+	ErrClosed      = errors.New("xlsx: writer is closed")
+)
+
+// ContentType is the media type of the files produced by Writer.
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// Writer streams one worksheet. Call Close to finish the file.
+type Writer struct {
+	zip    *zip.Writer
+	sheet  *bufio.Writer
+	rows   int
+	closed bool
+}
+
+// NewWriter writes the workbook parts and opens a worksheet named sheetName.
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	zw := zip.NewWriter(w)
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXML, escape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+		{"xl/styles.xml", stylesXML},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, xml.Header+part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	if _, err := sheet.WriteString(xml.Header + sheetStartXML); err != nil {
+		return nil, err
+	}
+
+	return &Writer{zip: zw, sheet: sheet}, nil
+}
+
+// WriteHeader writes a bold row of column titles. The first row is frozen, so
+// call it before any WriteRow.
+func (w *Writer) WriteHeader(titles []string) error {
+	values := make([]any, len(titles))
+	for i, title := range titles {
+		values[i] = title
+	}
+	return w.writeRow(values, styleBold)
+}
+
+// WriteRow writes one row. Strings, booleans, integers, floats and times are
+// supported; nil leaves the cell empty and other values are formatted with %v.
+func (w *Writer) WriteRow(values []any) error {
+	return w.writeRow(values, styleNormal)
+}
+
+// Flush pushes buffered rows to the underlying writer.
+func (w *Writer) Flush() error {
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Flush()
+}
+
+// Close finishes the worksheet and writes the zip directory. It does not close
+// the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if _, err := w.sheet.WriteString(sheetEndXML); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
+
+func (w *Writer) writeRow(values []any, style int) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if w.rows >= MaxRows {
+		return ErrTooManyRows
+	}
+	w.rows++
+
+	row := strconv.Itoa(w.rows)
+	b := w.sheet
+	b.WriteString(`<row r="` + row + `">`)
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		ref := ColumnName(i) + row
+
+		b.WriteString(`<c r="` + ref + `"`)
+		if style != styleNormal {
+			b.WriteString(` s="` + strconv.Itoa(style) + `"`)
+		}
+
+		switch v := value.(type) {
+		case bool:
+			if v {
+				b.WriteString(` t="b"><v>1</v></c>`)
+			} else {
+				b.WriteString(` t="b"><v>0</v></c>`)
+			}
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			b.WriteString(`><v>` + fmt.Sprint(v) + `</v></c>`)
+		case float32:
+			b.WriteString(`><v>` + strconv.FormatFloat(float64(v), 'g', -1, 32) + `</v></c>`)
+		case float64:
+			b.WriteString(`><v>` + strconv.FormatFloat(v, 'g', -1, 64) + `</v></c>`)
+		case time.Time:
+			writeInlineString(b, v.Format(time.RFC3339))
+		case string:
+			writeInlineString(b, v)
+		default:
+			writeInlineString(b, fmt.Sprint(v))
+		}
+	}
+	_, err := b.WriteString(`</row>`)
+	return err
+}
+
+func writeInlineString(b *bufio.Writer, s string) {
+	if utf8.RuneCountInString(s) > MaxCellLength {
+		s = string([]rune(s)[:MaxCellLength])
+	}
+	b.WriteString(` t="inlineStr"><is><t xml:space="preserve">`)
+	b.WriteString(escape(s))
+	b.WriteString(`</t></is></c>`)
+}
+
+// ColumnName returns the spreadsheet column letters for a zero-based index:
+// 0 is A, 25 is Z, 26 is AA.
+func ColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const (
+	styleNormal = 0
+	styleBold   = 1
+)
+
+const contentTypesXML = `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const rootRelsXML = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbookXML = `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const workbookRelsXML = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+const stylesXML = `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`</styleSheet>`
+
+const sheetStartXML = `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>` +
+	`<sheetData>`
+
+const sheetEndXML = `</sheetData></worksheet>`
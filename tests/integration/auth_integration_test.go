@@ -16,7 +16,7 @@ func TestAuthIntegration(t *testing.T) {
 	db, cleanup := setupIntegrationTestDB(t)
 	defer cleanup()
 
-	router := setupIntegrationRouter(db)
+	router := setupIntegrationRouter(t, db)
 
 	t.Run("Register new user", func(t *testing.T) {
 		assert.NoError(t, resetIntegrationTestDB(db))
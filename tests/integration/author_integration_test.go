@@ -0,0 +1,110 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthors_ContributorsVariantsAndMerge(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+
+	authorRepo := repository.NewAuthorRepository(db)
+	bookService := service.NewBookService(db, repository.NewBookRepository(db), authorRepo, repository.NewSubjectRepository(db), repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), repository.NewCopyRepository(db))
+	authorService := service.NewAuthorService(db, authorRepo)
+
+	rowling, err := authorService.CreateAuthor(ctx, dto.CreateAuthorRequest{Name: "J.K. Rowling", Variants: []string{"Robert Galbraith"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Rowling, J.K.", rowling.SortName)
+
+	book, err := bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{
+		ISBN:        "9780306406157",
+		Title:       "The Cuckoo's Calling",
+		TotalCopies: 1,
+		Contributors: []dto.ContributorRequest{
+			{Name: "Galbraith, Robert"},
+			{Name: "Jane Editor", Role: models.RoleEditor},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "J.K. Rowling", book.Author, "author statement is built from the credited authors")
+	require.Len(t, book.Contributors, 2)
+	assert.Equal(t, rowling.ID, book.Contributors[0].AuthorID, "a variant resolves to its author")
+
+	books, info, err := bookService.ListBooks(ctx, dto.BookListFilter{Search: "rowling"}, query.PageRequest{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), info.Total, "search matches the credited author's name")
+	require.Len(t, books, 1)
+	assert.Len(t, books[0].Contributors, 2)
+
+	// A duplicate created from a differently written name is merged back.
+	_, err = bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780132350884", Title: "Harry Potter", Author: "Joanne Rowling", TotalCopies: 1})
+	require.NoError(t, err)
+	duplicates, _, err := authorService.ListAuthors(ctx, "joanne", query.PageRequest{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, duplicates, 1)
+
+	merged, result, err := authorService.MergeAuthors(ctx, rowling.ID, rowling.Version, []uint{duplicates[0].ID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.ContributionsMoved)
+	assert.Equal(t, 1, result.VariantsAdded)
+	assert.Equal(t, int64(2), merged.BookCount)
+	assert.Len(t, merged.Variants, 2)
+	assert.Equal(t, rowling.Version+1, merged.Version)
+
+	works, info, err := authorService.ListAuthorWorks(ctx, rowling.ID, "", query.PageRequest{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), info.Total)
+	require.Len(t, works, 2)
+	assert.Equal(t, []string{models.RoleAuthor}, works[0].Roles)
+
+	err = authorService.DeleteAuthor(ctx, rowling.ID, 0)
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+}
+
+func TestAuthors_LinkBookAuthorsBackfillsLegacyBooks(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+	authorService := service.NewAuthorService(db, repository.NewAuthorRepository(db))
+
+	legacy := []models.Book{
+		{ISBN: "9780306406157", Title: "Refactoring", Author: "Martin Fowler; Kent Beck", TotalCopies: 1, AvailableCopies: 1},
+		{ISBN: "9780132350884", Title: "Patterns", Author: "Fowler, Martin", TotalCopies: 1, AvailableCopies: 1},
+	}
+	require.NoError(t, db.Create(&legacy).Error)
+
+	preview, err := authorService.LinkBookAuthors(ctx, false)
+	require.NoError(t, err)
+	assert.False(t, preview.Applied)
+	assert.Equal(t, 2, preview.BooksLinked)
+	assert.Equal(t, 2, preview.AuthorsCreated)
+
+	var count int64
+	require.NoError(t, db.Model(&models.Author{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count, "a preview must not write")
+
+	applied, err := authorService.LinkBookAuthors(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, applied.Applied)
+	assert.Equal(t, 2, applied.BooksLinked)
+
+	fowler, _, err := authorService.ListAuthors(ctx, "martin fowler", query.PageRequest{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, fowler, 1)
+	assert.Equal(t, int64(2), fowler[0].BookCount)
+
+	require.NoError(t, db.Delete(&legacy[0]).Error, "deleting a book drops its contributor links")
+}
@@ -0,0 +1,109 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/handler"
+	"github.com/alpardfm/library-management-api/internal/middleware"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookHistory_RecordsChangesAndReverts(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	bookService := service.NewBookService(db, repository.NewBookRepository(db), repository.NewAuthorRepository(db),
+		repository.NewSubjectRepository(db), repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), repository.NewCopyRepository(db))
+
+	bookHandler := handler.NewBookHandler(bookService)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(7))
+		c.Set(middleware.RequestIDKey, c.GetHeader("X-Request-ID"))
+	})
+	router.PATCH("/books/:id", bookHandler.PatchBook)
+	router.DELETE("/books/:id", bookHandler.DeleteBook)
+	router.POST("/books/:id/restore", bookHandler.RestoreBook)
+	router.GET("/books/:id/history", bookHandler.BookHistory)
+	router.POST("/books/:id/revert", bookHandler.RevertBook)
+	send := func(method, path, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		req.Header.Set("X-Request-ID", "req-"+method)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	history := func(query string) []models.BookRevision {
+		t.Helper()
+		rec := send(http.MethodGet, "/books/1/history"+query, "", "")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var body struct {
+			Data []models.BookRevision `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return body.Data
+	}
+
+	_, err := bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780306406157", Title: "Dune", Author: "Frank Herbert", TotalCopies: 2})
+	require.NoError(t, err)
+
+	rec := send(http.MethodPatch, "/books/1", `"1"`, `{"title":"Dune Messiah","total_copies":3}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	// A patch that changes nothing is not a revision.
+	rec = send(http.MethodPatch, "/books/1", `"2"`, `{"title":"Dune Messiah"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	require.Equal(t, http.StatusOK, send(http.MethodDelete, "/books/1", `"2"`, `{"status":"archived"}`).Code)
+	assert.Len(t, history(""), 3, "the history of a removed book stays readable")
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/books/1/restore", "", "").Code)
+
+	revisions := history("")
+	require.Len(t, revisions, 4)
+	assert.Equal(t, []string{models.RevisionRestore, models.RevisionDelete, models.RevisionUpdate, models.RevisionCreate},
+		[]string{revisions[0].Action, revisions[1].Action, revisions[2].Action, revisions[3].Action})
+	assert.Equal(t, []uint{4, 3, 2, 1}, []uint{revisions[0].Version, revisions[1].Version, revisions[2].Version, revisions[3].Version})
+	update := revisions[2]
+	assert.Equal(t, uint(7), update.ActorID)
+	assert.Equal(t, "req-PATCH", update.RequestID)
+	assert.Zero(t, revisions[3].ActorID, "created outside a request")
+	assert.JSONEq(t, `[{"field":"title","from":"Dune","to":"Dune Messiah"},{"field":"total_copies","from":2,"to":3}]`, string(update.Changes))
+	assert.JSONEq(t, `[{"field":"status","from":"active","to":"archived"}]`, string(revisions[1].Changes))
+	assert.Equal(t, uint(1), history("?sort=version_asc&limit=1")[0].Version)
+
+	rec = send(http.MethodPost, "/books/1/revert", `"4"`, `{"version":1}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `"5"`, rec.Header().Get("ETag"))
+	reverted, err := bookService.GetBookByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Dune", reverted.Title)
+	assert.Equal(t, 3, reverted.TotalCopies, "the stock is not reverted")
+	assert.Equal(t, 3, reverted.AvailableCopies)
+
+	revert := history("")[0]
+	assert.Equal(t, models.RevisionRevert, revert.Action)
+	assert.Equal(t, uint(1), revert.SourceVersion)
+	assert.JSONEq(t, `[{"field":"title","from":"Dune Messiah","to":"Dune"}]`, string(revert.Changes))
+
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/books/1/revert", `"5"`, `{"version":42}`).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, send(http.MethodPost, "/books/1/revert", `"4"`, `{"version":2}`).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/books/99/history", "", "").Code)
+}
@@ -0,0 +1,119 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/handler"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/mergepatch"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookUpdate_MergePatchAndReplace(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	authorRepo := repository.NewAuthorRepository(db)
+	subjectRepo := repository.NewSubjectRepository(db)
+	bookService := service.NewBookService(db, repository.NewBookRepository(db), authorRepo, subjectRepo, repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), repository.NewCopyRepository(db))
+	subjectService := service.NewSubjectService(db, subjectRepo)
+
+	bookHandler := handler.NewBookHandler(bookService)
+	router := gin.New()
+	router.PUT("/books/:id", bookHandler.UpdateBook)
+	router.PATCH("/books/:id", bookHandler.PatchBook)
+	etag := `"1"`
+	send := func(method, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/books/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("If-Match", etag)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code == http.StatusOK {
+			etag = rec.Header().Get("ETag")
+		}
+		return rec
+	}
+	type updateResponse struct {
+		Data models.Book `json:"data"`
+		Meta struct {
+			Changes []dto.FieldChange `json:"changes"`
+		} `json:"meta"`
+		Error struct {
+			Fields map[string]string `json:"fields"`
+		} `json:"error"`
+	}
+	decode := func(rec *httptest.ResponseRecorder) updateResponse {
+		var body updateResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body), rec.Body.String())
+		return body
+	}
+
+	scifi, err := subjectService.CreateSubject(ctx, dto.CreateSubjectRequest{Name: "Science Fiction"})
+	require.NoError(t, err)
+	_, err = bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{
+		ISBN: "9780306406157", Title: "Dune", Publisher: "Chilton", PublicationYear: 1965,
+		TotalCopies: 2, SubjectIDs: []uint{scifi.ID},
+		Contributors: []dto.ContributorRequest{
+			{Name: "Frank Herbert"},
+			{Name: "Brian Herbert", Role: models.RoleEditor},
+		},
+	})
+	require.NoError(t, err)
+
+	rec := send(http.MethodPatch, mergepatch.ContentType, `{"publisher":null,"subject_ids":null,"description":"Desert planet."}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	body := decode(rec)
+	assert.Empty(t, body.Data.Publisher)
+	assert.Equal(t, "Desert planet.", body.Data.Description)
+	assert.Equal(t, 1965, body.Data.PublicationYear, "fields the patch leaves out are kept")
+	assert.Len(t, body.Data.Contributors, 2)
+	require.Len(t, body.Meta.Changes, 3)
+	assert.Equal(t, "publisher", body.Meta.Changes[0].Field)
+	assert.Equal(t, "description", body.Meta.Changes[1].Field)
+	assert.Equal(t, "subject_ids", body.Meta.Changes[2].Field)
+
+	stored, err := bookService.GetBookByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, stored.Publisher)
+	assert.Empty(t, stored.Subjects)
+
+	// A new author statement replaces the author credits only.
+	rec = send(http.MethodPatch, "application/json", `{"author":"F. Herbert"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	body = decode(rec)
+	require.Len(t, body.Data.Contributors, 2)
+	assert.Equal(t, models.RoleEditor, body.Data.Contributors[1].Role)
+
+	rec = send(http.MethodPatch, mergepatch.ContentType, `{"title":"","publication_year":3000}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, map[string]string{"title": "is required", "publication_year": "must be at most 2024"}, decode(rec).Error.Fields)
+	assert.Equal(t, http.StatusUnsupportedMediaType, send(http.MethodPatch, "text/plain", `{}`).Code)
+
+	// PUT replaces the book: optional fields it leaves out are cleared and
+	// the credits are rebuilt from the author statement.
+	rec = send(http.MethodPut, "application/json", `{"isbn":"9780306406157","title":"Dune","author":"Frank Herbert","total_copies":3}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	body = decode(rec)
+	assert.Zero(t, body.Data.PublicationYear)
+	assert.Empty(t, body.Data.Description)
+	assert.Equal(t, 3, body.Data.AvailableCopies)
+	require.Len(t, body.Data.Contributors, 1)
+	assert.Equal(t, models.RoleAuthor, body.Data.Contributors[0].Role)
+
+	rec = send(http.MethodPatch, mergepatch.ContentType, `{}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, decode(rec).Meta.Changes)
+}
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"sync"
 	"testing"
 
@@ -22,7 +23,7 @@ func setupBorrowConcurrencyTest(t *testing.T, cfg service.BorrowServiceConfig) (
 	userRepo := repository.NewUserRepository(db)
 	bookRepo := repository.NewBookRepository(db)
 	borrowRepo := repository.NewBorrowRepository(db)
-	borrowService := service.NewBorrowService(db, borrowRepo, bookRepo, userRepo, cfg)
+	borrowService := service.NewBorrowService(db, borrowRepo, bookRepo, repository.NewBookRevisionRepository(db), userRepo, repository.NewHoldRepository(db), repository.NewCopyRepository(db), repository.NewTransferRepository(db), repository.NewBranchRepository(db), repository.NewAccountRepository(db), cfg)
 
 	return db, borrowService
 }
@@ -61,7 +62,7 @@ func TestBorrowBook_ConcurrentDuplicateBorrow_OnlyOneSucceeds(t *testing.T) {
 		go func() {
 			defer wg.Done()
 			<-start
-			_, err := borrowService.BorrowBook(user.ID, dto.BorrowBookRequest{BookID: book.ID})
+			_, err := borrowService.BorrowBook(context.Background(), user.ID, dto.BorrowBookRequest{BookID: book.ID})
 			results <- err
 		}()
 	}
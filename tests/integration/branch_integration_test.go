@@ -0,0 +1,138 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBranches_HoldingsCheckoutAndTransfers(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+
+	bookRepo := repository.NewBookRepository(db)
+	holdRepo := repository.NewHoldRepository(db)
+	branchRepo := repository.NewBranchRepository(db)
+	copyRepo := repository.NewCopyRepository(db)
+	transferRepo := repository.NewTransferRepository(db)
+	bookService := service.NewBookService(db, bookRepo, repository.NewAuthorRepository(db),
+		repository.NewSubjectRepository(db), repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), copyRepo)
+	borrowService := service.NewBorrowService(db, repository.NewBorrowRepository(db), bookRepo, repository.NewBookRevisionRepository(db), repository.NewUserRepository(db), holdRepo, copyRepo, transferRepo, branchRepo, repository.NewAccountRepository(db), service.BorrowServiceConfig{
+		MaxBooksPerUser: 5, BorrowDays: 14, FinePerDay: 1000,
+	})
+	branchService := service.NewBranchService(branchRepo)
+	copyService := service.NewCopyService(db, copyRepo, transferRepo, branchRepo, bookRepo, repository.NewBookRevisionRepository(db), holdRepo)
+
+	alice := &models.User{Username: "alice", Email: "alice@example.com", PasswordHash: "hash", Role: models.RoleMember, IsActive: true}
+	require.NoError(t, db.Create(alice).Error)
+	main, err := branchService.CreateBranch(ctx, dto.BranchRequest{Code: "main", Name: "Main Library"})
+	require.NoError(t, err)
+	assert.Equal(t, "MAIN", main.Code)
+	east, err := branchService.CreateBranch(ctx, dto.BranchRequest{Code: "EAST", Name: "East Branch"})
+	require.NoError(t, err)
+	var appErr *apperror.AppError
+	_, err = branchService.CreateBranch(ctx, dto.BranchRequest{Code: "East", Name: "Another East"})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+
+	book, err := bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780441172719", Title: "Dune", Author: "Frank Herbert", TotalCopies: 3})
+	require.NoError(t, err)
+
+	// The three copies in stock are registered, and a fourth is bought.
+	for barcode, branchID := range map[string]uint{"M-1": main.ID, "M-2": main.ID, "E-1": east.ID} {
+		_, err := copyService.AddCopy(ctx, dto.Actor{}, book.ID, dto.AddCopyRequest{Barcode: barcode, BranchID: branchID})
+		require.NoError(t, err)
+	}
+	_, err = copyService.AddCopy(ctx, dto.Actor{}, book.ID, dto.AddCopyRequest{Barcode: "E-2", BranchID: east.ID})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code, "every copy is registered")
+	_, err = copyService.AddCopy(ctx, dto.Actor{}, book.ID, dto.AddCopyRequest{Barcode: "E-1", BranchID: east.ID, New: true})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code, "barcodes are unique")
+	_, err = copyService.AddCopy(ctx, dto.Actor{}, book.ID, dto.AddCopyRequest{Barcode: "E-2", BranchID: east.ID, New: true})
+	require.NoError(t, err)
+
+	holdings := func() map[string][2]int64 {
+		t.Helper()
+		stored, err := bookService.GetBookByID(ctx, book.ID)
+		require.NoError(t, err)
+		byCode := map[string][2]int64{}
+		for _, holding := range stored.Holdings {
+			byCode[holding.Code] = [2]int64{holding.Copies, holding.Available}
+		}
+		return byCode
+	}
+	assert.Equal(t, map[string][2]int64{"MAIN": {2, 2}, "EAST": {2, 2}}, holdings())
+
+	// Checked out at EAST, returned at MAIN: the copy travels home first.
+	loan, err := borrowService.BorrowBook(ctx, alice.ID, dto.BorrowBookRequest{BookID: book.ID, BranchID: east.ID})
+	require.NoError(t, err)
+	require.NotNil(t, loan.CopyID)
+	assert.Equal(t, east.ID, *loan.BranchID)
+	assert.Equal(t, map[string][2]int64{"MAIN": {2, 2}, "EAST": {2, 1}}, holdings())
+
+	returned, _, err := borrowService.ReturnBook(ctx, alice.ID, "member", dto.ReturnBookRequest{BorrowRecordID: loan.ID, BranchID: main.ID})
+	require.NoError(t, err)
+	assert.Equal(t, main.ID, *returned.ReturnBranchID)
+	transfers, _, err := copyService.ListTransfers(ctx, "", east.ID, query.PageRequest{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	homecoming := transfers[0]
+	assert.Equal(t, models.TransferInTransit, homecoming.Status)
+	assert.Equal(t, models.TransferReturn, homecoming.Reason)
+	assert.Equal(t, "E-1", homecoming.Copy.Barcode)
+	stored, err := bookService.GetBookByID(ctx, book.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stored.AvailableCopies, "a copy in transit is not on a shelf")
+
+	_, err = copyService.ReceiveTransfer(ctx, homecoming.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][2]int64{"MAIN": {2, 2}, "EAST": {2, 2}}, holdings())
+
+	// A copy of MAIN moves to EAST for good.
+	copies, err := copyService.ListCopies(ctx, book.ID)
+	require.NoError(t, err)
+	require.Len(t, copies, 4)
+	moving := copies[2]
+	require.Equal(t, "M-1", moving.Barcode)
+	_, err = copyService.RequestTransfer(ctx, 1, dto.TransferRequest{CopyID: moving.ID, ToBranchID: main.ID})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeBadRequest, appErr.Code, "the copy is already there")
+	transfer, err := copyService.RequestTransfer(ctx, 1, dto.TransferRequest{CopyID: moving.ID, ToBranchID: east.ID, Permanent: true})
+	require.NoError(t, err)
+	assert.Equal(t, models.TransferRequested, transfer.Status)
+	_, err = copyService.ReceiveTransfer(ctx, transfer.ID)
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code, "not shipped yet")
+	_, err = copyService.ShipTransfer(ctx, transfer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][2]int64{"MAIN": {2, 1}, "EAST": {2, 2}}, holdings())
+	_, err = copyService.CancelTransfer(ctx, transfer.ID)
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code, "shipped transfers cannot be cancelled")
+	received, err := copyService.ReceiveTransfer(ctx, transfer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TransferReceived, received.Status)
+	assert.NotNil(t, received.ReceivedAt)
+	assert.Equal(t, map[string][2]int64{"MAIN": {1, 1}, "EAST": {3, 3}}, holdings())
+
+	// Registered copies cannot be written out of the stock, nor their branch removed.
+	_, _, err = bookService.PatchBook(ctx, dto.Actor{}, book.ID, 0, []byte(`{"total_copies":3}`))
+	require.ErrorAs(t, err, &appErr)
+	assert.Contains(t, appErr.Fields, "total_copies")
+	err = branchService.DeleteBranch(ctx, main.ID, 0)
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	unused, err := branchService.CreateBranch(ctx, dto.BranchRequest{Code: "WEST", Name: "West Branch"})
+	require.NoError(t, err)
+	require.NoError(t, branchService.DeleteBranch(ctx, unused.ID, 0))
+}
@@ -0,0 +1,142 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/handler"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// multipartCover builds a multipart body with data in the "file" field.
+func multipartCover(t *testing.T, data []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "cover.txt")
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return &body, writer.FormDataContentType()
+}
+
+func TestBookCover_UploadServeAndDelete(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	gin.SetMode(gin.TestMode)
+
+	book := &models.Book{ISBN: "9780306406157", Title: "Covered", Author: "Painter", TotalCopies: 1}
+	require.NoError(t, db.Create(book).Error)
+
+	dir := t.TempDir()
+	store, err := storage.NewLocalStorage(dir, "")
+	require.NoError(t, err)
+	bookRepo := repository.NewBookRepository(db)
+	coverHandler := handler.NewCoverHandler(service.NewCoverService(bookRepo, store, 1<<20))
+	router := gin.New()
+	router.PUT("/books/:id/cover", coverHandler.UploadCover)
+	router.DELETE("/books/:id/cover", coverHandler.DeleteCover)
+	router.GET("/books/:id/cover", coverHandler.GetCover)
+	router.GET("/books/:id/cover/:size", coverHandler.GetCover)
+
+	// A transparent PNG, sent with a misleading file name.
+	img := image.NewNRGBA(image.Rect(0, 0, 900, 1200))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 255})
+	var data bytes.Buffer
+	require.NoError(t, png.Encode(&data, img))
+	body, contentType := multipartCover(t, data.Bytes())
+
+	// Like other book edits, a cover change needs the book's ETag.
+	req := httptest.NewRequest(http.MethodPut, "/books/1/cover", bytes.NewReader(data.Bytes()))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusPreconditionRequired, rec.Code, rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodPut, "/books/1/cover", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("If-Match", `"1"`)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	var resp struct {
+		Data models.Book `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Data.Cover)
+	stored, err := bookRepo.FindByID(t.Context(), book.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", stored.CoverType)
+	assert.Equal(t, "/api/v1/books/1/cover?v="+stored.CoverVersion, resp.Data.Cover.URL)
+	assert.Equal(t, "/api/v1/books/1/cover/medium?v="+stored.CoverVersion, resp.Data.Cover.Thumbnails["medium"])
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books/1/cover/large?v="+stored.CoverVersion, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Cache-Control"), "immutable")
+	config, format, err := image.DecodeConfig(rec.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, image.Config{ColorModel: config.ColorModel, Width: 600, Height: 800}, config)
+
+	req = httptest.NewRequest(http.MethodGet, "/books/1/cover", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, "public, max-age=300", rec.Header().Get("Cache-Control"))
+
+	// A text file is refused whatever it claims to be.
+	req = httptest.NewRequest(http.MethodPut, "/books/1/cover", bytes.NewReader([]byte("plain text posing as a cover")))
+	req.Header.Set("Content-Type", "image/gif")
+	req.Header.Set("If-Match", "*")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPut, "/books/1/cover", bytes.NewReader(make([]byte, 2<<20)))
+	req.Header.Set("If-Match", "*")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	req = httptest.NewRequest(http.MethodDelete, "/books/1/cover", nil)
+	req.Header.Set("If-Match", `"1"`)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	req = httptest.NewRequest(http.MethodDelete, "/books/1/cover", nil)
+	req.Header.Set("If-Match", `"2"`)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books/1/cover/small", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	stored, err = bookRepo.FindByID(t.Context(), book.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.Cover)
+}
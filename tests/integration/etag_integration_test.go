@@ -0,0 +1,103 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/handler"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogEdits_OptimisticConcurrency(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	userRepo := repository.NewUserRepository(db)
+	bookRepo := repository.NewBookRepository(db)
+	authorRepo := repository.NewAuthorRepository(db)
+	subjectRepo := repository.NewSubjectRepository(db)
+	bookService := service.NewBookService(db, bookRepo, authorRepo, subjectRepo, repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), repository.NewCopyRepository(db))
+	authorService := service.NewAuthorService(db, authorRepo)
+	borrowService := service.NewBorrowService(db, repository.NewBorrowRepository(db), bookRepo, repository.NewBookRevisionRepository(db), userRepo, repository.NewHoldRepository(db), repository.NewCopyRepository(db), repository.NewTransferRepository(db), repository.NewBranchRepository(db), repository.NewAccountRepository(db), service.BorrowServiceConfig{
+		MaxBooksPerUser: 5, BorrowDays: 14, FinePerDay: 1000,
+	})
+
+	bookHandler := handler.NewBookHandler(bookService)
+	authorHandler := handler.NewAuthorHandler(authorService)
+	router := gin.New()
+	router.GET("/books/:id", bookHandler.GetBook)
+	router.PATCH("/books/:id", bookHandler.PatchBook)
+	router.DELETE("/books/:id", bookHandler.DeleteBook)
+	router.GET("/authors/:id", authorHandler.GetAuthor)
+	router.PUT("/authors/:id", authorHandler.UpdateAuthor)
+	send := func(method, path string, headers map[string]string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	book, err := bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780306406157", Title: "Dune", Author: "Frank Herbert", TotalCopies: 2})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), book.Version)
+
+	rec := send(http.MethodGet, "/books/1", nil, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	read := rec.Header().Get("ETag")
+	assert.Equal(t, `"1"`, read)
+	assert.Equal(t, http.StatusNotModified, send(http.MethodGet, "/books/1", map[string]string{"If-None-Match": read}, "").Code)
+
+	// Two librarians edit the version they both read: the second is refused.
+	rec = send(http.MethodPatch, "/books/1", map[string]string{"If-Match": read}, `{"title":"Dune (1965)"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	rec = send(http.MethodPatch, "/books/1", map[string]string{"If-Match": read}, `{"publisher":"Chilton"}`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Equal(t, http.StatusPreconditionRequired, send(http.MethodPatch, "/books/1", nil, `{"publisher":"Chilton"}`).Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/books/1", map[string]string{"If-None-Match": read}, "").Code)
+
+	stored, err := bookService.GetBookByID(ctx, book.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Dune (1965)", stored.Title)
+	assert.Empty(t, stored.Publisher, "the refused edit wrote nothing")
+
+	// The version also guards writes that did not go through the API.
+	stale := *stored
+	require.NoError(t, bookRepo.Update(ctx, stored))
+	assert.Equal(t, uint(3), stored.Version)
+	assert.ErrorIs(t, bookRepo.Update(ctx, &stale), repository.ErrStaleVersion)
+
+	// Circulation changes the book, so it changes the version too.
+	alice := &models.User{Username: "alice", Email: "alice@example.com", PasswordHash: "hash", Role: models.RoleMember, IsActive: true}
+	require.NoError(t, db.Create(alice).Error)
+	_, err = borrowService.BorrowBook(ctx, alice.ID, dto.BorrowBookRequest{BookID: book.ID})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusPreconditionFailed, send(http.MethodDelete, "/books/1", map[string]string{"If-Match": `"3"`}, "").Code)
+	assert.Equal(t, `"4"`, send(http.MethodGet, "/books/1", nil, "").Header().Get("ETag"))
+
+	rec = send(http.MethodGet, "/authors/1", nil, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	authorTag := rec.Header().Get("ETag")
+	assert.Equal(t, http.StatusPreconditionRequired, send(http.MethodPut, "/authors/1", nil, `{"bio":"Author of Dune."}`).Code)
+	rec = send(http.MethodPut, "/authors/1", map[string]string{"If-Match": authorTag}, `{"bio":"Author of Dune."}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NotEqual(t, authorTag, rec.Header().Get("ETag"))
+	assert.Equal(t, http.StatusPreconditionFailed, send(http.MethodPut, "/authors/1", map[string]string{"If-Match": authorTag}, `{"bio":"Overwritten."}`).Code)
+}
@@ -0,0 +1,132 @@
+package integration
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/handler"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// seedExportBooks creates books that share created_at values, so keyset
+// batches must break ties on ID.
+func seedExportBooks(t *testing.T, db *gorm.DB, n int) {
+	t.Helper()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		book := &models.Book{
+			ISBN:            fmt.Sprintf("97812345%05d", i),
+			Title:           fmt.Sprintf("Book %02d", n-i),
+			Author:          "Exporter",
+			TotalCopies:     2,
+			AvailableCopies: 2 - i%3%2,
+			CreatedAt:       base.Add(time.Duration(i/3) * time.Minute),
+		}
+		require.NoError(t, db.Create(book).Error)
+	}
+}
+
+func TestBookRepositoryScan_WalksAllRowsInSortOrder(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	seedExportBooks(t, db, 11)
+	bookRepo := repository.NewBookRepository(db)
+
+	for _, sort := range []string{"created_at_desc", "created_at_asc", "title_asc", "title_desc"} {
+		t.Run(sort, func(t *testing.T) {
+			want, _, err := bookRepo.List(context.Background(), repository.BookFilter{}, query.PageRequest{Page: 1, Limit: 100, Sort: sort})
+			require.NoError(t, err)
+
+			var got []models.Book
+			batches := 0
+			err = bookRepo.Scan(context.Background(), repository.BookFilter{}, sort, 4, func(books []models.Book) error {
+				batches++
+				got = append(got, books...)
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, 3, batches)
+			require.Len(t, got, len(want))
+
+			seen := make(map[uint]bool)
+			for i := range got {
+				assert.False(t, seen[got[i].ID], "book %d exported twice", got[i].ID)
+				seen[got[i].ID] = true
+				if sort == "title_asc" || sort == "title_desc" {
+					assert.Equal(t, want[i].ID, got[i].ID)
+				} else {
+					assert.True(t, want[i].CreatedAt.Equal(got[i].CreatedAt))
+				}
+			}
+		})
+	}
+}
+
+func TestExportBooks_StreamsCSVAndXLSXOverHTTP(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	gin.SetMode(gin.TestMode)
+	seedExportBooks(t, db, 7)
+
+	router := gin.New()
+	bookRepo := repository.NewBookRepository(db)
+	exportHandler := handler.NewExportHandler(service.NewBookExportService(db, bookRepo), service.NewMarcService(db, bookRepo, repository.NewMarcRecordRepository(db)))
+	router.GET("/books/export", exportHandler.ExportBooks)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books/export?columns=isbn,available_copies,borrowed_copies&sort=title_asc&search=9781234500003", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), ".csv")
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"isbn", "available_copies", "borrowed_copies"}, {"9781234500003", "2", "0"}}, records)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books/export?format=xlsx", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	require.NoError(t, err)
+	assert.Len(t, archive.File, 6)
+
+	// The listing filters apply; a full-text search falls back to the
+	// substring search on SQLite.
+	require.NoError(t, db.Model(&models.Book{}).Where("isbn IN ?", []string{"9781234500001", "9781234500004"}).Update("genre", "Poetry").Error)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books/export?columns=isbn&sort=title_asc&genre=poetry&available=true&search_mode=fulltext&search=book", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	records, err = csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"isbn"}, {"9781234500004"}, {"9781234500001"}}, records)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books/export?format=marcxml&genre=Poetry", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, strings.Count(rec.Body.String(), "<record"))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books/export?format=marcxml&search_mode=exact", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books/export?columns=secret", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Disposition"))
+}
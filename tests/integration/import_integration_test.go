@@ -0,0 +1,248 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/handler"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const importCSV = "isbn,title,author,total_copies,publication_year\n" +
+	"0-306-40615-2,Signals,Ada Tester,2,2001\n" +
+	"9780132350884,Clean Code,Robert C. Martin,3,2008\n" +
+	"9780132350885,Bad Checksum,Someone,1,2008\n" +
+	"978-0-306-40615-7,Signals Again,Ada Tester,1,2001\n" +
+	"9780134757599,,Martin Fowler,1,2018\n"
+
+func newImportService(db *gorm.DB) service.BookImportService {
+	return service.NewBookImportService(db, repository.NewBookRepository(db), repository.NewImportJobRepository(db), repository.NewMarcRecordRepository(db), repository.NewAuthorRepository(db), repository.NewSubjectRepository(db), repository.NewBookRevisionRepository(db), repository.NewCopyRepository(db))
+}
+
+func TestImportBooks_CreateUpsertAndDryRun(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+	importService := newImportService(db)
+
+	existing := &models.Book{ISBN: "9780132350884", Title: "Clean Code", Author: "Robert C. Martin", TotalCopies: 2, AvailableCopies: 1}
+	require.NoError(t, db.Create(existing).Error)
+
+	rows, err := service.DecodeBookImport(strings.NewReader(importCSV), "csv")
+	require.NoError(t, err)
+
+	dryRun, err := importService.ImportBooks(ctx, rows, service.BookImportOptions{Format: "csv", Mode: service.ImportModeUpsert, DryRun: true, BatchSize: 2})
+	require.NoError(t, err)
+	assert.True(t, dryRun.DryRun)
+	assert.Equal(t, 1, dryRun.Created)
+	assert.Equal(t, 1, dryRun.Updated)
+	assert.Equal(t, 3, dryRun.Failed)
+
+	var count int64
+	require.NoError(t, db.Model(&models.Book{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count, "dry run must not write")
+
+	result, err := importService.ImportBooks(ctx, rows, service.BookImportOptions{Format: "csv", Mode: service.ImportModeCreate, BatchSize: 2})
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 5)
+	assert.Equal(t, service.ImportRowCreated, result.Rows[0].Status)
+	assert.Equal(t, "9780306406157", result.Rows[0].ISBN)
+	assert.Equal(t, service.ImportRowSkipped, result.Rows[1].Status)
+	assert.Equal(t, existing.ID, result.Rows[1].BookID)
+	assert.Equal(t, service.ImportRowFailed, result.Rows[2].Status)
+	assert.Contains(t, result.Rows[2].Error, "invalid ISBN")
+	assert.Equal(t, service.ImportRowFailed, result.Rows[3].Status)
+	assert.Equal(t, "duplicate of line 2", result.Rows[3].Error)
+	assert.Equal(t, service.ImportRowFailed, result.Rows[4].Status)
+	assert.Contains(t, result.Rows[4].Error, "Title")
+
+	upsert, err := importService.ImportBooks(ctx, rows[:2], service.BookImportOptions{Format: "csv", Mode: service.ImportModeUpsert})
+	require.NoError(t, err)
+	assert.Equal(t, service.ImportRowUnchanged, upsert.Rows[0].Status)
+	assert.Equal(t, service.ImportRowUpdated, upsert.Rows[1].Status)
+
+	var updated models.Book
+	require.NoError(t, db.First(&updated, existing.ID).Error)
+	assert.Equal(t, 3, updated.TotalCopies)
+	assert.Equal(t, 2, updated.AvailableCopies, "borrowed copies are preserved")
+	assert.Equal(t, 2008, updated.PublicationYear)
+}
+
+func TestImportBooks_CallNumbers(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+	importService := newImportService(db)
+
+	rows, err := service.DecodeBookImport(strings.NewReader("isbn,title,author,total_copies,call_number,shelf_location,price\n"+
+		"9780132350884,Clean Code,Robert C. Martin,1,005.1  mar,Stacks,45000\n"+
+		"9780134757599,Refactoring,Martin Fowler,1,not a call number,,\n"), "csv")
+	require.NoError(t, err)
+	result, err := importService.ImportBooks(ctx, rows, service.BookImportOptions{Format: "csv", Mode: service.ImportModeCreate})
+	require.NoError(t, err)
+	assert.Equal(t, service.ImportRowCreated, result.Rows[0].Status)
+	assert.Equal(t, service.ImportRowFailed, result.Rows[1].Status)
+	assert.Contains(t, result.Rows[1].Error, "classification: ")
+
+	var book models.Book
+	require.NoError(t, db.First(&book, result.Rows[0].BookID).Error)
+	assert.Equal(t, "ddc", book.Classification)
+	assert.Equal(t, "005.1 MAR", book.CallNumber)
+	assert.Equal(t, "Stacks", book.ShelfLocation)
+	assert.Equal(t, 45000, book.Price)
+
+	// An upsert changes the shelf location and keeps the call number it
+	// leaves out.
+	rows, err = service.DecodeBookImport(strings.NewReader("isbn,title,author,shelf_location\n9780132350884,Clean Code,Robert C. Martin,Reference\n"), "csv")
+	require.NoError(t, err)
+	result, err = importService.ImportBooks(ctx, rows, service.BookImportOptions{Format: "csv", Mode: service.ImportModeUpsert})
+	require.NoError(t, err)
+	assert.Equal(t, service.ImportRowUpdated, result.Rows[0].Status, result.Rows[0].Error)
+	require.NoError(t, db.First(&book, book.ID).Error)
+	assert.Equal(t, "005.1 MAR", book.CallNumber)
+	assert.Equal(t, "Reference", book.ShelfLocation)
+}
+
+func TestImportBooks_UpsertKeepsRegisteredCopies(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+	importService := newImportService(db)
+
+	branchRepo := repository.NewBranchRepository(db)
+	copyService := service.NewCopyService(db, repository.NewCopyRepository(db), repository.NewTransferRepository(db), branchRepo,
+		repository.NewBookRepository(db), repository.NewBookRevisionRepository(db), repository.NewHoldRepository(db))
+	main, err := service.NewBranchService(branchRepo).CreateBranch(ctx, dto.BranchRequest{Code: "MAIN", Name: "Main Library"})
+	require.NoError(t, err)
+	book := &models.Book{ISBN: "9780132350884", Title: "Clean Code", Author: "Robert C. Martin", TotalCopies: 3, AvailableCopies: 3}
+	require.NoError(t, db.Create(book).Error)
+	for _, barcode := range []string{"M-1", "M-2"} {
+		_, err := copyService.AddCopy(ctx, dto.Actor{}, book.ID, dto.AddCopyRequest{Barcode: barcode, BranchID: main.ID})
+		require.NoError(t, err)
+	}
+
+	upsert := func(total string) dto.BookImportRowResult {
+		rows, err := service.DecodeBookImport(strings.NewReader("isbn,title,author,total_copies\n9780132350884,Clean Code,Robert C. Martin,"+total+"\n"), "csv")
+		require.NoError(t, err)
+		result, err := importService.ImportBooks(ctx, rows, service.BookImportOptions{Format: "csv", Mode: service.ImportModeUpsert})
+		require.NoError(t, err)
+		return result.Rows[0]
+	}
+
+	// An import cannot cut the stock below the copies registered at branches.
+	row := upsert("1")
+	assert.Equal(t, service.ImportRowFailed, row.Status)
+	assert.Contains(t, row.Error, "total_copies: 2 copies are registered")
+	require.NoError(t, db.First(book, book.ID).Error)
+	assert.Equal(t, 3, book.TotalCopies)
+
+	row = upsert("2")
+	assert.Equal(t, service.ImportRowUpdated, row.Status, row.Error)
+	require.NoError(t, db.First(book, book.ID).Error)
+	assert.Equal(t, 2, book.TotalCopies)
+}
+
+func TestImportBooks_AsyncJobOverHTTP(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	gin.SetMode(gin.TestMode)
+
+	importService := newImportService(db)
+	t.Cleanup(func() { _ = importService.Shutdown(context.Background()) })
+	importHandler := handler.NewImportHandler(importService)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", uint(7)) })
+	router.POST("/books/import", importHandler.StartImport)
+	router.GET("/books/import/:id", importHandler.GetImportJob)
+	router.GET("/books/import/:id/issues", importHandler.ListImportIssues)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "catalog.csv")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(importCSV))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/books/import?batch_size=2", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	var started struct {
+		Data models.ImportJob `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &started))
+	assert.Equal(t, "csv", started.Data.Format)
+	assert.Equal(t, "catalog.csv", started.Data.FileName)
+	assert.Equal(t, 5, started.Data.TotalRows)
+	location := rec.Header().Get("Location")
+	require.NotEmpty(t, location)
+
+	var job struct {
+		Data models.ImportJob `json:"data"`
+		Meta struct {
+			ProgressPercent int `json:"progress_percent"`
+		} `json:"meta"`
+	}
+	require.Eventually(t, func() bool {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books/import/"+jobIDOf(location), nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+		return job.Data.Status == models.ImportJobCompleted || job.Data.Status == models.ImportJobFailed
+	}, 5*time.Second, 20*time.Millisecond)
+
+	assert.Equal(t, models.ImportJobCompleted, job.Data.Status)
+	assert.Equal(t, 100, job.Meta.ProgressPercent)
+	assert.Equal(t, 2, job.Data.Created)
+	assert.Equal(t, 3, job.Data.Failed)
+	assert.Equal(t, uint(7), job.Data.CreatedBy)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books/import/"+jobIDOf(location)+"/issues", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var issues struct {
+		Data []models.ImportJobIssue `json:"data"`
+		Meta struct {
+			Total int64 `json:"total"`
+		} `json:"meta"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &issues))
+	assert.Equal(t, int64(3), issues.Meta.Total)
+	require.Len(t, issues.Data, 3)
+	assert.Equal(t, 4, issues.Data[0].Line)
+}
+
+func TestImportBooks_RejectsUnknownFormat(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+
+	router := gin.New()
+	router.POST("/books/import", handler.NewImportHandler(newImportService(db)).StartImport)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/books/import", strings.NewReader("isbn\n")))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func jobIDOf(location string) string {
+	return location[strings.LastIndex(location, "/")+1:]
+}
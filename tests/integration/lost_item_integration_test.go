@@ -0,0 +1,140 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLostItems_BillAndRefund(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+
+	bookRepo := repository.NewBookRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	branchRepo := repository.NewBranchRepository(db)
+	copyRepo := repository.NewCopyRepository(db)
+	transferRepo := repository.NewTransferRepository(db)
+	holdRepo := repository.NewHoldRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	bookService := service.NewBookService(db, bookRepo, repository.NewAuthorRepository(db),
+		repository.NewSubjectRepository(db), repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), copyRepo)
+	borrowService := service.NewBorrowService(db, repository.NewBorrowRepository(db), bookRepo, repository.NewBookRevisionRepository(db), userRepo, holdRepo, copyRepo, transferRepo, branchRepo, accountRepo, service.BorrowServiceConfig{
+		MaxBooksPerUser: 5, BorrowDays: 14, FinePerDay: 1000, ProcessingFee: 5000,
+	})
+	copyService := service.NewCopyService(db, copyRepo, transferRepo, branchRepo, bookRepo, repository.NewBookRevisionRepository(db), holdRepo)
+	accountService := service.NewAccountService(accountRepo, userRepo)
+
+	alice := &models.User{Username: "alice", Email: "alice@example.com", PasswordHash: "hash", Role: models.RoleMember, IsActive: true}
+	require.NoError(t, db.Create(alice).Error)
+	main, err := service.NewBranchService(branchRepo).CreateBranch(ctx, dto.BranchRequest{Code: "MAIN", Name: "Main Library"})
+	require.NoError(t, err)
+
+	dune, err := bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780441172719", Title: "Dune", Author: "Frank Herbert", TotalCopies: 2, Price: 90000})
+	require.NoError(t, err)
+	for _, barcode := range []string{"M-1", "M-2"} {
+		_, err := copyService.AddCopy(ctx, dto.Actor{}, dune.ID, dto.AddCopyRequest{Barcode: barcode, BranchID: main.ID})
+		require.NoError(t, err)
+	}
+	stock := func() (int, int) {
+		stored, err := bookService.GetBookByID(ctx, dune.ID)
+		require.NoError(t, err)
+		return stored.TotalCopies, stored.AvailableCopies
+	}
+	balance := func() int64 {
+		_, balance, _, err := accountService.GetAccount(ctx, alice.ID, query.PageRequest{Page: 1, Limit: 20})
+		require.NoError(t, err)
+		return balance
+	}
+
+	// A lost copy leaves the stock and is billed with the processing fee.
+	lost, err := borrowService.BorrowBook(ctx, alice.ID, dto.BorrowBookRequest{BookID: dune.ID, BranchID: main.ID})
+	require.NoError(t, err)
+	record, charges, err := borrowService.DeclareLost(ctx, dto.Actor{UserID: 1}, dto.DeclareLostRequest{BorrowRecordID: lost.ID, Note: "left on a train"})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusLost, record.Status)
+	assert.Len(t, charges, 2)
+	total, available := stock()
+	assert.Equal(t, 1, total)
+	assert.Equal(t, 1, available)
+	assert.Equal(t, int64(95000), balance())
+
+	var appErr *apperror.AppError
+	_, _, err = borrowService.ReturnBook(ctx, alice.ID, "member", dto.ReturnBookRequest{BorrowRecordID: lost.ID})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	_, _, err = borrowService.DeclareLost(ctx, dto.Actor{UserID: 1}, dto.DeclareLostRequest{BorrowRecordID: lost.ID})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+
+	// A damaged copy is billed the same way and stays out of the stock.
+	damaged, err := borrowService.BorrowBook(ctx, alice.ID, dto.BorrowBookRequest{BookID: dune.ID, BranchID: main.ID})
+	require.NoError(t, err)
+	record, _, err = borrowService.ReturnDamaged(ctx, dto.Actor{UserID: 1}, dto.ReturnDamagedRequest{BorrowRecordID: damaged.ID})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDamaged, record.Status)
+	assert.Equal(t, main.ID, *record.ReturnBranchID)
+	total, available = stock()
+	assert.Equal(t, 0, total)
+	assert.Equal(t, 0, available)
+	assert.Equal(t, int64(190000), balance())
+	// A book left without copies can still be edited and looked up.
+	stored, err := bookService.GetBookByID(ctx, dune.ID)
+	require.NoError(t, err)
+	patched, _, err := bookService.PatchBook(ctx, dto.Actor{UserID: 1}, dune.ID, stored.Version, []byte(`{"title": "Dune (1965)"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Dune (1965)", patched.Title)
+	assert.Equal(t, 0, patched.TotalCopies)
+	canBorrow, err := bookService.CheckAvailability(ctx, dune.ID)
+	require.NoError(t, err)
+	assert.False(t, canBorrow)
+	_, err = borrowService.BorrowBook(ctx, alice.ID, dto.BorrowBookRequest{BookID: dune.ID, BranchID: main.ID})
+	require.ErrorAs(t, err, &appErr)
+	assert.NotContains(t, appErr.Message, "inconsistent")
+	_, _, err = borrowService.ReturnLost(ctx, dto.Actor{UserID: 1}, dto.ReturnBookRequest{BorrowRecordID: damaged.ID})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+
+	// The lost copy turns up: it is back on the shelf and its replacement is
+	// refunded, the processing fee kept.
+	record, refunds, err := borrowService.ReturnLost(ctx, dto.Actor{UserID: 1}, dto.ReturnBookRequest{BorrowRecordID: lost.ID})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusReturned, record.Status)
+	require.Len(t, refunds, 1)
+	assert.Equal(t, -90000, refunds[0].Amount)
+	total, available = stock()
+	assert.Equal(t, 1, total)
+	assert.Equal(t, 1, available)
+	assert.Equal(t, int64(100000), balance())
+
+	copies, err := copyService.ListCopies(ctx, dune.ID)
+	require.NoError(t, err)
+	statuses := map[string]string{}
+	for _, item := range copies {
+		statuses[item.Barcode] = item.Status
+	}
+	assert.Equal(t, map[string]string{"M-1": models.CopyAvailable, "M-2": models.CopyDamaged}, statuses)
+
+	// Each change to the stock is in the book's history, by who made it,
+	// along with the edit of the title.
+	history, _, err := bookService.BookHistory(ctx, dune.ID, query.PageRequest{Page: 1, Limit: 20})
+	require.NoError(t, err)
+	require.Len(t, history, 5)
+	for i, total := range []int{1, 0, 0, 1} {
+		revision := history[i]
+		assert.Equal(t, models.RevisionUpdate, revision.Action)
+		assert.Equal(t, uint(1), revision.ActorID)
+		assert.Contains(t, string(revision.Snapshot), fmt.Sprintf(`"total_copies":%d`, total))
+	}
+	assert.Equal(t, models.RevisionCreate, history[4].Action)
+}
@@ -0,0 +1,92 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/handler"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/marc"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMARCImport_RoundTripsUnmappedFields(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	source := marc.NewRecord()
+	source.AddField(marc.Field{Tag: "020", Ind1: ' ', Ind2: ' ', Subfields: []marc.Subfield{{Code: 'a', Value: "0306406152 (pbk.)"}}})
+	source.AddField(marc.Field{Tag: "100", Ind1: '1', Ind2: ' ', Subfields: []marc.Subfield{{Code: 'a', Value: "Tester, Ada,"}}})
+	source.AddField(marc.Field{Tag: "245", Ind1: '1', Ind2: '0', Subfields: []marc.Subfield{{Code: 'a', Value: "Signals :"}, {Code: 'b', Value: "a study"}}})
+	source.AddField(marc.Field{Tag: "264", Ind1: ' ', Ind2: '1', Subfields: []marc.Subfield{{Code: 'b', Value: "Example Press,"}, {Code: 'c', Value: "2001."}}})
+	source.AddField(marc.Field{Tag: "500", Ind1: ' ', Ind2: ' ', Subfields: []marc.Subfield{{Code: 'a', Value: "Signed by the author."}}})
+	data, err := marc.Marshal(source)
+	require.NoError(t, err)
+
+	rows, err := service.DecodeBookImport(bytes.NewReader(data), "marc")
+	require.NoError(t, err)
+	result, err := newImportService(db).ImportBooks(ctx, rows, service.BookImportOptions{Format: "marc"})
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Created, result.Rows)
+
+	var book models.Book
+	require.NoError(t, db.Where("isbn = ?", "9780306406157").First(&book).Error)
+	assert.Equal(t, "Signals: a study", book.Title)
+	assert.Equal(t, "Tester, Ada", book.Author)
+	require.NoError(t, db.Model(&book).Update("title", "Signals, revised").Error)
+
+	bookRepo := repository.NewBookRepository(db)
+	marcService := service.NewMarcService(db, bookRepo, repository.NewMarcRecordRepository(db))
+	exportHandler := handler.NewExportHandler(service.NewBookExportService(db, bookRepo), marcService)
+	router := gin.New()
+	router.GET("/books/export", exportHandler.ExportBooks)
+	router.GET("/books/:id/marc", exportHandler.GetBookRecord)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books/"+strconv.FormatUint(uint64(book.ID), 10)+"/marc", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/marcxml+xml", rec.Header().Get("Content-Type"))
+
+	exported, err := marc.UnmarshalXML(rec.Body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, strconv.FormatUint(uint64(book.ID), 10), exported.Field("001").Value)
+	assert.Equal(t, "Signals, revised", exported.Field("245").Subfield('a'))
+	assert.Equal(t, "Signed by the author.", exported.Field("500").Subfield('a'))
+	// Unchanged mapped fields keep their original cataloguing.
+	assert.Equal(t, "0306406152 (pbk.)", exported.Field("020").Subfield('a'))
+	assert.Equal(t, "Tester, Ada,", exported.Field("100").Subfield('a'))
+
+	require.NoError(t, db.Create(&models.Book{ISBN: "9780132350884", Title: "Clean Code", Author: "Robert C. Martin", TotalCopies: 1, AvailableCopies: 1}).Error)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books/export?format=marc&sort=title_asc", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), ".mrc")
+
+	r := marc.NewReader(rec.Body)
+	var titles []string
+	for {
+		next, err := r.Read()
+		if err != nil {
+			break
+		}
+		titles = append(titles, next.Field("245").Subfield('a'))
+	}
+	assert.Equal(t, []string{"Clean Code", "Signals, revised"}, titles)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books/export?format=marcxml", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, strings.Count(rec.Body.String(), "<record>"))
+}
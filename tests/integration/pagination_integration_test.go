@@ -0,0 +1,105 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookList_CursorPagesWalkBothWays(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	seedExportBooks(t, db, 10)
+	bookRepo := repository.NewBookRepository(db)
+	ctx := context.Background()
+
+	// Two creation times for ten books: pages must break ties by ID.
+	early := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, db.Model(&models.Book{}).Where("id <= ?", 5).Update("created_at", early).Error)
+	require.NoError(t, db.Model(&models.Book{}).Where("id > ?", 5).Update("created_at", early.Add(time.Minute)).Error)
+
+	for _, sort := range []string{"created_at_desc", "created_at_asc", "title_asc"} {
+		t.Run(sort, func(t *testing.T) {
+			all, _, err := bookRepo.List(ctx, repository.BookFilter{}, query.PageRequest{Page: 1, Limit: 100, Sort: sort})
+			require.NoError(t, err)
+			require.Len(t, all, 10)
+
+			var pages [][]uint
+			req := query.PageRequest{Page: 1, Limit: 3, Sort: sort, Count: query.CountNone}
+			for {
+				books, info, err := bookRepo.List(ctx, repository.BookFilter{}, req)
+				require.NoError(t, err)
+				pages = append(pages, bookIDs(books))
+				if info.NextCursor == "" {
+					break
+				}
+				req.Cursor, err = query.DecodeCursor(info.NextCursor)
+				require.NoError(t, err)
+				require.Less(t, len(pages), 10, "cursor walk does not end")
+			}
+
+			var walked []uint
+			for _, page := range pages {
+				walked = append(walked, page...)
+			}
+			assert.Equal(t, bookIDs(all), walked)
+			assert.Len(t, pages, 4)
+
+			// Walking back from the last page returns the same pages.
+			books, info, err := bookRepo.List(ctx, repository.BookFilter{}, req)
+			require.NoError(t, err)
+			for i := len(pages) - 1; i > 0; i-- {
+				assert.Equal(t, pages[i], bookIDs(books))
+				require.NotEmpty(t, info.PrevCursor)
+				req.Cursor, err = query.DecodeCursor(info.PrevCursor)
+				require.NoError(t, err)
+				books, info, err = bookRepo.List(ctx, repository.BookFilter{}, req)
+				require.NoError(t, err)
+			}
+			assert.Equal(t, pages[0], bookIDs(books))
+			assert.Empty(t, info.PrevCursor)
+		})
+	}
+}
+
+func TestBookList_CursorIgnoresRowsAddedBehindIt(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	seedExportBooks(t, db, 6)
+	bookRepo := repository.NewBookRepository(db)
+	ctx := context.Background()
+
+	req := query.PageRequest{Page: 1, Limit: 3, Sort: "created_at_desc", Count: query.CountEstimate}
+	first, info, err := bookRepo.List(ctx, repository.BookFilter{}, req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), info.Total, "SQLite estimates by counting")
+
+	// A book added while paging lands before the cursor; an offset page would
+	// repeat the last row of the first page.
+	require.NoError(t, db.Create(&models.Book{ISBN: "9780306406157", Title: "Newest", Author: "Late", TotalCopies: 1}).Error)
+
+	req.Cursor, err = query.DecodeCursor(info.NextCursor)
+	require.NoError(t, err)
+	second, info, err := bookRepo.List(ctx, repository.BookFilter{}, req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), info.Total)
+	assert.Len(t, second, 3)
+	assert.Empty(t, info.NextCursor)
+	for _, id := range bookIDs(second) {
+		assert.NotContains(t, bookIDs(first), id)
+	}
+}
+
+func bookIDs(books []models.Book) []uint {
+	ids := make([]uint, len(books))
+	for i := range books {
+		ids[i] = books[i].ID
+	}
+	return ids
+}
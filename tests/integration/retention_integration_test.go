@@ -0,0 +1,165 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/handler"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookRemoval_WithdrawRestoreAndPurge(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	userRepo := repository.NewUserRepository(db)
+	bookRepo := repository.NewBookRepository(db)
+	borrowRepo := repository.NewBorrowRepository(db)
+	bookService := service.NewBookService(db, bookRepo, repository.NewAuthorRepository(db), repository.NewSubjectRepository(db), repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), repository.NewCopyRepository(db))
+	borrowService := service.NewBorrowService(db, borrowRepo, bookRepo, repository.NewBookRevisionRepository(db), userRepo, repository.NewHoldRepository(db), repository.NewCopyRepository(db), repository.NewTransferRepository(db), repository.NewBranchRepository(db), repository.NewAccountRepository(db), service.BorrowServiceConfig{
+		MaxBooksPerUser: 5, BorrowDays: 14, FinePerDay: 1000,
+	})
+	userService := service.NewUserService(db, userRepo, borrowRepo)
+	authService := service.NewAuthService(userRepo, "secret", time.Hour)
+
+	bookHandler := handler.NewBookHandler(bookService)
+	userHandler := handler.NewUserHandler(userService)
+	router := gin.New()
+	router.DELETE("/users/:id", userHandler.DeleteUser)
+	router.POST("/users/:id/restore", userHandler.RestoreUser)
+	router.DELETE("/books/:id", bookHandler.DeleteBook)
+	router.GET("/books/removed", bookHandler.ListRemovedBooks)
+	router.POST("/books/:id/restore", bookHandler.RestoreBook)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if method == http.MethodDelete {
+			req.Header.Set("If-Match", "*")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	alice := &models.User{Username: "alice", Email: "alice@example.com", PasswordHash: "hash", Role: models.RoleMember, IsActive: true}
+	require.NoError(t, db.Create(alice).Error)
+	borrowed := &models.Book{ISBN: "9780306406157", Title: "Borrowed Once", Author: "Reader", TotalCopies: 1, AvailableCopies: 1}
+	unread := &models.Book{ISBN: "9780140449136", Title: "Never Borrowed", Author: "Shelf", TotalCopies: 1, AvailableCopies: 1}
+	kept := &models.Book{ISBN: "9780261103573", Title: "Still Here", Author: "Shelf", TotalCopies: 1, AvailableCopies: 1}
+	require.NoError(t, db.Create([]*models.Book{borrowed, unread, kept}).Error)
+
+	record, err := borrowService.BorrowBook(ctx, alice.ID, dto.BorrowBookRequest{BookID: borrowed.ID})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, send(http.MethodDelete, "/books/1", "").Code, "borrowed books cannot be withdrawn")
+	_, _, err = borrowService.ReturnBook(ctx, alice.ID, string(models.RoleMember), dto.ReturnBookRequest{BorrowRecordID: record.ID})
+	require.NoError(t, err)
+
+	rec := send(http.MethodDelete, "/books/1", `{"status":"archived","reason":"superseded edition"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, http.StatusOK, send(http.MethodDelete, "/books/2", "").Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodDelete, "/books/3", `{"status":"lost"}`).Code)
+
+	books, _, err := bookService.ListBooks(ctx, dto.BookListFilter{}, query.PageRequest{Page: 1, Limit: 10, Sort: "title_asc"})
+	require.NoError(t, err)
+	require.Len(t, books, 1, "removed books are left out of listings")
+	assert.Equal(t, kept.ID, books[0].ID)
+	_, err = bookService.GetBookByID(ctx, unread.ID)
+	assert.Error(t, err)
+
+	rec = send(http.MethodGet, "/books/removed?status=archived", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var removed struct {
+		Data []models.Book `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &removed))
+	require.Len(t, removed.Data, 1)
+	assert.Equal(t, models.BookStatusArchived, removed.Data[0].Status)
+	assert.Equal(t, "superseded edition", removed.Data[0].StatusReason)
+	assert.NotNil(t, removed.Data[0].DeletedAt)
+
+	// Borrow history still shows the archived book.
+	history, _, err := borrowService.GetUserBorrows(ctx, alice.ID, query.PageRequest{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "Borrowed Once", history[0].Book.Title)
+
+	_, err = bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: unread.ISBN, Title: "Again", Author: "Shelf", TotalCopies: 1})
+	assert.EqualError(t, err, "book with this ISBN was withdrawn; restore book 2 instead")
+
+	rec = send(http.MethodPost, "/books/2/restore", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	restored, err := bookService.GetBookByID(ctx, unread.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.BookStatusActive, restored.Status)
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/books/2/restore", "").Code)
+	require.NoError(t, bookService.DeleteBook(ctx, dto.Actor{}, unread.ID, 0, dto.RemoveBookRequest{}))
+
+	rec = send(http.MethodDelete, "/users/1", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	_, err = userService.FindUser(ctx, "alice")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/users/1", "").Code)
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/users/1/restore", "").Code)
+	_, err = userService.FindUser(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/users/1/restore", "").Code)
+	_, err = userService.DeleteUser(ctx, alice.ID)
+	require.NoError(t, err)
+	_, err = authService.Register(ctx, dto.RegisterRequest{Username: "alice", Email: "new@example.com", Password: "secret1"})
+	assert.EqualError(t, err, "username already exists", "deleted users keep their username")
+
+	// Nothing is old enough to purge yet.
+	retention := service.NewRetentionService(db, bookRepo, repository.NewBookRevisionRepository(db), borrowRepo, userRepo, nil, 24*time.Hour)
+	report, err := retention.Purge(ctx, true)
+	require.NoError(t, err)
+	assert.Empty(t, report.PurgedBooks)
+	assert.Empty(t, report.AnonymizedUsers)
+
+	longAgo := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, db.Unscoped().Model(&models.Book{}).Where("deleted_at IS NOT NULL").Update("deleted_at", longAgo).Error)
+	require.NoError(t, db.Unscoped().Model(&models.User{}).Where("id = ?", alice.ID).Update("deleted_at", longAgo).Error)
+
+	report, err = retention.Purge(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{borrowed.ID, unread.ID}, report.PurgedBooks)
+	assert.Equal(t, int64(1), report.DetachedBorrows)
+	assert.Equal(t, []uint{alice.ID}, report.AnonymizedUsers)
+
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&models.Book{}).Where("id IN ?", []uint{borrowed.ID, unread.ID}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	var purged models.User
+	require.NoError(t, db.Unscoped().First(&purged, alice.ID).Error)
+	assert.Equal(t, "deleted-user-1", purged.Username)
+	assert.Equal(t, "deleted-user-1@invalid", purged.Email)
+	assert.NotNil(t, purged.PurgedAt)
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/users/1/restore", "").Code, "purged users cannot be restored")
+
+	record, err = borrowRepo.FindByID(ctx, record.ID)
+	require.NoError(t, err, "borrow history survives the purge")
+	assert.Equal(t, "deleted-user-1", record.User.Username)
+	assert.Nil(t, record.BookID)
+	assert.Equal(t, "Borrowed Once", record.BookTitle)
+	assert.Equal(t, borrowed.ISBN, record.BookISBN)
+
+	report, err = retention.Purge(ctx, true)
+	require.NoError(t, err)
+	assert.Empty(t, report.AnonymizedUsers, "users are anonymized once")
+}
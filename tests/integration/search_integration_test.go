@@ -0,0 +1,154 @@
+package integration
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/database"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookSearch_FiltersAndFacets(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+
+	subjectRepo := repository.NewSubjectRepository(db)
+	subjectService := service.NewSubjectService(db, subjectRepo)
+	bookService := service.NewBookService(db, repository.NewBookRepository(db), repository.NewAuthorRepository(db), subjectRepo, repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), repository.NewCopyRepository(db))
+
+	fiction, err := subjectService.CreateSubject(ctx, dto.CreateSubjectRequest{Name: "Fiction"})
+	require.NoError(t, err)
+	sciFi, err := subjectService.CreateSubject(ctx, dto.CreateSubjectRequest{Name: "Science fiction", ParentID: fiction.ID})
+	require.NoError(t, err)
+	history, err := subjectService.CreateSubject(ctx, dto.CreateSubjectRequest{Name: "History"})
+	require.NoError(t, err)
+
+	create := func(req dto.CreateBookRequest) *models.Book {
+		t.Helper()
+		req.TotalCopies = 1
+		book, err := bookService.CreateBook(ctx, dto.Actor{}, req)
+		require.NoError(t, err)
+		return book
+	}
+	dune := create(dto.CreateBookRequest{ISBN: "9780306406157", Title: "Dune", Author: "Frank Herbert", Publisher: "Chilton",
+		PublicationYear: 1965, Language: "ENG", SubjectIDs: []uint{sciFi.ID}})
+	create(dto.CreateBookRequest{ISBN: "9780132350884", Title: "Hyperion", Author: "Dan Simmons", Publisher: "Doubleday",
+		PublicationYear: 1989, Language: "eng", ItemType: models.ItemTypeEbook, SubjectIDs: []uint{sciFi.ID}})
+	create(dto.CreateBookRequest{ISBN: "9780134757599", Title: "Madame Bovary", Author: "Gustave Flaubert", Publisher: "Chilton",
+		PublicationYear: 1857, Language: "fre", SubjectIDs: []uint{fiction.ID}})
+	create(dto.CreateBookRequest{ISBN: "9780201633610", Title: "SPQR", Author: "Mary Beard", Publisher: "Profile",
+		PublicationYear: 2015, Language: "eng", SubjectIDs: []uint{history.ID}})
+	require.NoError(t, db.Model(&models.Book{}).Where("id = ?", dune.ID).Update("available_copies", 0).Error)
+
+	list := func(filter dto.BookListFilter) []string {
+		t.Helper()
+		books, info, err := bookService.ListBooks(ctx, filter, query.PageRequest{Page: 1, Limit: 10, Sort: "title_asc"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(books)), info.Total)
+		titles := make([]string, len(books))
+		for i, book := range books {
+			titles[i] = book.Title
+		}
+		return titles
+	}
+	available := true
+
+	assert.Equal(t, []string{"Dune", "Hyperion", "Madame Bovary"}, list(dto.BookListFilter{SubjectIDs: []uint{fiction.ID}}), "a subject matches its subtree")
+	assert.Equal(t, []string{"Dune", "Madame Bovary"}, list(dto.BookListFilter{Publishers: []string{"chilton"}}))
+	assert.Equal(t, []string{"Hyperion", "Madame Bovary"}, list(dto.BookListFilter{SubjectIDs: []uint{fiction.ID}, Available: &available}))
+	assert.Equal(t, []string{"Dune", "Hyperion"}, list(dto.BookListFilter{YearFrom: 1900, YearTo: 1999}))
+	assert.Equal(t, []string{"Hyperion"}, list(dto.BookListFilter{Languages: []string{"eng"}, ItemTypes: []string{models.ItemTypeEbook}}))
+	assert.Equal(t, []string{"Dune"}, list(dto.BookListFilter{AuthorIDs: []uint{dune.Contributors[0].AuthorID}}))
+
+	// Facets count within the filtered books; subjects drill down one level.
+	facets, err := bookService.BookFacets(ctx, dto.BookListFilter{Languages: []string{"eng"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []dto.FacetValue{
+		{Value: idString(fiction.ID), Label: "Fiction", Count: 2},
+		{Value: idString(history.ID), Label: "History", Count: 1},
+	}, facets["subject"])
+	assert.Equal(t, []dto.FacetValue{{Value: "Chilton", Count: 1}, {Value: "Doubleday", Count: 1}, {Value: "Profile", Count: 1}}, facets["publisher"])
+	assert.Equal(t, []dto.FacetValue{
+		{Value: "2010", Label: "2010s", Count: 1},
+		{Value: "1980", Label: "1980s", Count: 1},
+		{Value: "1960", Label: "1960s", Count: 1},
+	}, facets["decade"])
+	assert.Equal(t, []dto.FacetValue{{Value: "eng", Count: 3}}, facets["language"])
+	assert.Equal(t, []dto.FacetValue{{Value: "book", Count: 2}, {Value: "ebook", Count: 1}}, facets["item_type"])
+	assert.Equal(t, []dto.FacetValue{{Value: "true", Count: 2}, {Value: "false", Count: 1}}, facets["available"])
+	assert.Len(t, facets["author"], 3)
+
+	facets, err = bookService.BookFacets(ctx, dto.BookListFilter{SubjectIDs: []uint{fiction.ID}}, []string{"subject"})
+	require.NoError(t, err)
+	assert.Equal(t, []dto.FacetValue{{Value: idString(sciFi.ID), Label: "Science fiction", Count: 2}}, facets["subject"])
+}
+
+func idString(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func TestBookSearch_FullTextFallsBackToSubstringOnSQLite(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	if database.DialectOf(db).FullTextSearch() {
+		t.Skip("the fallback only applies without full-text support")
+	}
+	ctx := context.Background()
+
+	bookService := service.NewBookService(db, repository.NewBookRepository(db), repository.NewAuthorRepository(db), repository.NewSubjectRepository(db), repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), repository.NewCopyRepository(db))
+	_, err := bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780306406157", Title: "Dune", Author: "Frank Herbert", TotalCopies: 1})
+	require.NoError(t, err)
+
+	books, info, mode, err := bookService.SearchBooks(ctx, dto.BookListFilter{Search: "herb"}, query.PageRequest{Page: 1, Limit: 10, Sort: "relevance"})
+	require.NoError(t, err)
+	assert.Equal(t, service.SearchModeSubstring, mode)
+	assert.Equal(t, int64(1), info.Total)
+	require.Len(t, books, 1)
+	assert.Empty(t, books[0].Snippet)
+
+	facets, err := bookService.BookFacets(ctx, dto.BookListFilter{Search: "herb", SearchMode: service.SearchModeFullText}, []string{"item_type"})
+	require.NoError(t, err)
+	assert.Equal(t, []dto.FacetValue{{Value: "book", Count: 1}}, facets["item_type"])
+}
+
+func TestBookSearch_SuggestCompletesTitlesAndAuthors(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+
+	bookService := service.NewBookService(db, repository.NewBookRepository(db), repository.NewAuthorRepository(db), repository.NewSubjectRepository(db), repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), repository.NewCopyRepository(db))
+	for _, req := range []dto.CreateBookRequest{
+		{ISBN: "9780306406157", Title: "Harry Potter and the Chamber of Secrets", Author: "J.K. Rowling"},
+		{ISBN: "9780132350884", Title: "Dirty Harry", Author: "Phillip Rock"},
+		{ISBN: "9780134757599", Title: "Uncle Tom's Cabin", Author: "Harriet Beecher Stowe"},
+		{ISBN: "9780201633610", Title: "Harbour Lights", Author: "Rosamunde Pilcher"},
+	} {
+		req.TotalCopies = 1
+		_, err := bookService.CreateBook(ctx, dto.Actor{}, req)
+		require.NoError(t, err)
+	}
+
+	suggestions, err := bookService.SuggestBooks(ctx, "harr", 10)
+	require.NoError(t, err)
+	texts := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		texts[i] = suggestion.Text
+	}
+	assert.Equal(t, []string{"Harriet Beecher Stowe", "Harry Potter and the Chamber of Secrets", "Dirty Harry"}, texts,
+		"names starting with the term come first, shortest first, then later words")
+	assert.Equal(t, "author", suggestions[0].Kind)
+	assert.NotZero(t, suggestions[0].AuthorID)
+	assert.NotZero(t, suggestions[1].BookID)
+
+	suggestions, err = bookService.SuggestBooks(ctx, "harr", 1)
+	require.NoError(t, err)
+	assert.Len(t, suggestions, 1)
+}
@@ -0,0 +1,84 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettings_LiveBorrowLimitAcrossReplicas(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+
+	defaults := service.BorrowServiceConfig{MaxBooksPerUser: 5, BorrowDays: 7, FinePerDay: 1000}
+	settingRepo := repository.NewSettingRepository(db)
+	// Two services sharing a database stand in for two API replicas.
+	adminReplica := service.NewSettingsService(db, settingRepo, defaults, time.Hour)
+	borrowReplica := service.NewSettingsService(db, settingRepo, defaults, 0)
+	borrowService := service.NewBorrowService(db,
+		repository.NewBorrowRepository(db), repository.NewBookRepository(db), repository.NewBookRevisionRepository(db), repository.NewUserRepository(db), repository.NewHoldRepository(db),
+		repository.NewCopyRepository(db), repository.NewTransferRepository(db), repository.NewBranchRepository(db), repository.NewAccountRepository(db),
+		borrowReplica)
+
+	user := &models.User{Username: "settings-user", Email: "settings-user@example.com", PasswordHash: "hash", Role: models.RoleMember, IsActive: true}
+	first := &models.Book{ISBN: "9781234567001", Title: "First", Author: "Tester", TotalCopies: 1, AvailableCopies: 1}
+	second := &models.Book{ISBN: "9781234567002", Title: "Second", Author: "Tester", TotalCopies: 1, AvailableCopies: 1}
+	require.NoError(t, db.Create(user).Error)
+	require.NoError(t, db.Create(first).Error)
+	require.NoError(t, db.Create(second).Error)
+
+	require.Equal(t, 5, borrowReplica.Circulation(ctx).MaxBooksPerUser)
+
+	limit, err := adminReplica.GetSetting(ctx, service.SettingMaxBooksPerUser)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), limit.Version)
+	limit, err = adminReplica.UpdateSetting(ctx, user.ID, service.SettingMaxBooksPerUser, limit.Version, dto.UpdateSettingRequest{
+		Value:  json.RawMessage(`1`),
+		Reason: "inventory week",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), limit.Version)
+	_, err = adminReplica.UpdateSetting(ctx, user.ID, service.SettingBorrowDays, 0, dto.UpdateSettingRequest{Value: json.RawMessage(`3`)})
+	require.NoError(t, err)
+
+	record, err := borrowService.BorrowBook(ctx, user.ID, dto.BorrowBookRequest{BookID: first.ID})
+	require.NoError(t, err)
+	assert.WithinDuration(t, record.BorrowDate.Add(3*24*time.Hour), record.DueDate, time.Second)
+
+	_, err = borrowService.BorrowBook(ctx, user.ID, dto.BorrowBookRequest{BookID: second.ID})
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+
+	limit, err = adminReplica.ResetSetting(ctx, user.ID, service.SettingMaxBooksPerUser, limit.Version, "back to normal")
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), limit.Version)
+	// Versions read before the reset stay stale.
+	for _, stale := range []uint{1, 2} {
+		_, err = adminReplica.UpdateSetting(ctx, user.ID, service.SettingMaxBooksPerUser, stale, dto.UpdateSettingRequest{Value: json.RawMessage(`2`)})
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, apperror.CodePreconditionFailed, appErr.Code)
+	}
+	_, err = borrowService.BorrowBook(ctx, user.ID, dto.BorrowBookRequest{BookID: second.ID})
+	require.NoError(t, err)
+
+	changes, info, err := adminReplica.ListChanges(ctx, service.SettingMaxBooksPerUser, query.PageRequest{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), info.Total)
+	require.Len(t, changes, 2)
+	assert.Nil(t, changes[0].NewValue)
+	assert.Equal(t, "back to normal", changes[0].Reason)
+	assert.Equal(t, "1", *changes[1].NewValue)
+	assert.Nil(t, changes[1].OldValue)
+}
@@ -0,0 +1,150 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShelf_CallNumberOrderBrowseAndShelfList(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+
+	bookRepo := repository.NewBookRepository(db)
+	branchRepo := repository.NewBranchRepository(db)
+	copyRepo := repository.NewCopyRepository(db)
+	bookService := service.NewBookService(db, bookRepo, repository.NewAuthorRepository(db),
+		repository.NewSubjectRepository(db), repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), copyRepo)
+	copyService := service.NewCopyService(db, copyRepo, repository.NewTransferRepository(db), branchRepo, bookRepo, repository.NewBookRevisionRepository(db), repository.NewHoldRepository(db))
+	shelfService := service.NewShelfService(bookRepo, copyRepo, branchRepo)
+
+	main, err := service.NewBranchService(branchRepo).CreateBranch(ctx, dto.BranchRequest{Code: "MAIN", Name: "Main Library"})
+	require.NoError(t, err)
+
+	// Catalogued out of shelf order: QA76.9 sorts after QA76.73 and QA9
+	// before both, which a plain string sort gets wrong.
+	ids := map[string]uint{}
+	for _, book := range []dto.CreateBookRequest{
+		{ISBN: "9780262033848", Title: "Introduction to Algorithms", CallNumber: "qa76.6 .c662 2009"},
+		{ISBN: "9780134190440", Title: "The Go Programming Language", CallNumber: "QA76.73.G63 D66 2016", ShelfLocation: "Stacks"},
+		{ISBN: "9780201633610", Title: "Design Patterns", CallNumber: "QA76.9 .D3"},
+		{ISBN: "9780486411477", Title: "Number Theory", CallNumber: "QA9 .B7", ShelfLocation: "Stacks"},
+		{ISBN: "9780441172719", Title: "Dune", Classification: "ddc", CallNumber: "813.54 HER"},
+		{ISBN: "9780141439518", Title: "Pride and Prejudice"},
+	} {
+		book.Author, book.TotalCopies = "Someone", 1
+		created, err := bookService.CreateBook(ctx, dto.Actor{}, book)
+		require.NoError(t, err, book.Title)
+		ids[book.Title] = created.ID
+	}
+
+	var appErr *apperror.AppError
+	_, err = bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780596007126", Title: "Head First", Author: "Someone", TotalCopies: 1, Classification: "lcc", CallNumber: "005.1 FRE"})
+	require.ErrorAs(t, err, &appErr)
+	assert.Contains(t, appErr.Fields, "call_number")
+	_, err = bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780596007126", Title: "Head First", Author: "Someone", TotalCopies: 1, CallNumber: "REF FRE"})
+	require.ErrorAs(t, err, &appErr)
+	assert.Contains(t, appErr.Fields, "classification")
+
+	titles := func(books []dto.ShelfEntry) []string {
+		var out []string
+		for _, book := range books {
+			out = append(out, book.Title)
+		}
+		return out
+	}
+
+	// Sorting by call number is shelf order, Dewey before LC and books
+	// without a call number last, both through pages and backwards.
+	page, _, err := bookService.ListBooks(ctx, dto.BookListFilter{}, query.PageRequest{Page: 1, Limit: 4, Sort: "call_number_asc"})
+	require.NoError(t, err)
+	next, _, err := bookService.ListBooks(ctx, dto.BookListFilter{}, query.PageRequest{Page: 2, Limit: 4, Sort: "call_number_asc"})
+	require.NoError(t, err)
+	var listed []string
+	for _, book := range append(page, next...) {
+		listed = append(listed, book.Title)
+	}
+	assert.Equal(t, []string{"Dune", "Number Theory", "Introduction to Algorithms", "The Go Programming Language", "Design Patterns", "Pride and Prejudice"}, listed)
+	assert.Equal(t, "QA76.6.C662 2009", page[2].CallNumber, "stored in normal form")
+	assert.Equal(t, "lcc", page[2].Classification, "told from the call number")
+	page, _, err = bookService.ListBooks(ctx, dto.BookListFilter{}, query.PageRequest{Page: 1, Limit: 2, Sort: "call_number_desc"})
+	require.NoError(t, err)
+	assert.Equal(t, "Pride and Prejudice", page[0].Title)
+	assert.Equal(t, "Design Patterns", page[1].Title)
+
+	// Browsing from a book stays within its classification.
+	view, err := shelfService.BrowseBook(ctx, ids["The Go Programming Language"], dto.ShelfBrowseRequest{Before: 5, After: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Number Theory", "Introduction to Algorithms"}, titles(view.Before))
+	assert.Equal(t, "The Go Programming Language", view.Current.Title)
+	assert.Equal(t, []string{"Design Patterns"}, titles(view.After))
+	_, err = shelfService.BrowseBook(ctx, ids["Pride and Prejudice"], dto.ShelfBrowseRequest{Before: 5, After: 5})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+
+	// A call number no book has lands between its neighbours.
+	view, err = shelfService.BrowseCallNumber(ctx, "", "QA76.7", dto.ShelfBrowseRequest{Before: 1, After: 5})
+	require.NoError(t, err)
+	assert.Nil(t, view.Current)
+	assert.Equal(t, []string{"Introduction to Algorithms"}, titles(view.Before))
+	assert.Equal(t, []string{"The Go Programming Language", "Design Patterns"}, titles(view.After))
+
+	// Patching the call number moves the book on the shelf.
+	_, changes, err := bookService.PatchBook(ctx, dto.Actor{}, ids["Number Theory"], 0, []byte(`{"call_number":"QA241 .B7"}`))
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "call_number", changes[0].Field)
+
+	// A copy shelved elsewhere than its book shows its own location.
+	_, err = copyService.AddCopy(ctx, dto.Actor{}, ids["The Go Programming Language"], dto.AddCopyRequest{Barcode: "M-2", BranchID: main.ID})
+	require.NoError(t, err)
+	_, err = copyService.AddCopy(ctx, dto.Actor{}, ids["Design Patterns"], dto.AddCopyRequest{Barcode: "M-3", BranchID: main.ID, ShelfLocation: "Reference"})
+	require.NoError(t, err)
+	_, err = copyService.AddCopy(ctx, dto.Actor{}, ids["Number Theory"], dto.AddCopyRequest{Barcode: "M-1", BranchID: main.ID})
+	require.NoError(t, err)
+	// Lost and damaged copies are off the shelf for good.
+	require.NoError(t, db.Create([]*models.Copy{
+		{BookID: ids["Number Theory"], Barcode: "M-4", HomeBranchID: main.ID, BranchID: main.ID, Status: models.CopyLost},
+		{BookID: ids["Design Patterns"], Barcode: "M-5", HomeBranchID: main.ID, BranchID: main.ID, Status: models.CopyDamaged},
+	}).Error)
+
+	list, err := shelfService.ShelfList(ctx, dto.ShelfListRequest{From: "QA76", To: "QA76"})
+	require.NoError(t, err)
+	assert.Equal(t, "lcc", list.Classification)
+	assert.Equal(t, []string{"Introduction to Algorithms", "The Go Programming Language", "Design Patterns"}, titles(list.Entries))
+
+	list, err = shelfService.ShelfList(ctx, dto.ShelfListRequest{BranchID: main.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "MAIN", list.BranchCode)
+	require.Len(t, list.Entries, 3)
+	assert.Equal(t, []string{"M-2", "M-3", "M-1"}, []string{list.Entries[0].Barcode, list.Entries[1].Barcode, list.Entries[2].Barcode})
+	assert.Equal(t, "Reference", list.Entries[1].ShelfLocation)
+
+	list, err = shelfService.ShelfList(ctx, dto.ShelfListRequest{BranchID: main.ID, Location: "stacks", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"The Go Programming Language"}, titles(list.Entries))
+	assert.True(t, list.Truncated)
+
+	var out bytes.Buffer
+	require.NoError(t, service.WriteShelfList(&out, service.ShelfListFormatCSV, list))
+	records, err := csv.NewReader(&out).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, []string{"QA76.73.G63 D66 2016", "The Go Programming Language", "Someone", "", "9780134190440", "Stacks", "M-2", "available"}, records[1])
+
+	out.Reset()
+	require.NoError(t, service.WriteShelfList(&out, service.ShelfListFormatHTML, list))
+	assert.Contains(t, out.String(), "<td>QA76.73.G63 D66 2016</td>")
+	assert.Contains(t, out.String(), "MAIN, stacks")
+}
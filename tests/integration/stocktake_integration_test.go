@@ -0,0 +1,185 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStocktake_ScanReconcileAndFix(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+
+	bookRepo := repository.NewBookRepository(db)
+	holdRepo := repository.NewHoldRepository(db)
+	branchRepo := repository.NewBranchRepository(db)
+	copyRepo := repository.NewCopyRepository(db)
+	transferRepo := repository.NewTransferRepository(db)
+	bookService := service.NewBookService(db, bookRepo, repository.NewAuthorRepository(db),
+		repository.NewSubjectRepository(db), repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), copyRepo)
+	borrowService := service.NewBorrowService(db, repository.NewBorrowRepository(db), bookRepo, repository.NewBookRevisionRepository(db), repository.NewUserRepository(db), holdRepo, copyRepo, transferRepo, branchRepo, repository.NewAccountRepository(db), service.BorrowServiceConfig{
+		MaxBooksPerUser: 5, BorrowDays: 14, FinePerDay: 1000,
+	})
+	branchService := service.NewBranchService(branchRepo)
+	copyService := service.NewCopyService(db, copyRepo, transferRepo, branchRepo, bookRepo, repository.NewBookRevisionRepository(db), holdRepo)
+	stocktakeService := service.NewStocktakeService(db, repository.NewStocktakeRepository(db), copyRepo, bookRepo, branchRepo, transferRepo, holdRepo)
+
+	alice := &models.User{Username: "alice", Email: "alice@example.com", PasswordHash: "hash", Role: models.RoleMember, IsActive: true}
+	require.NoError(t, db.Create(alice).Error)
+	main, err := branchService.CreateBranch(ctx, dto.BranchRequest{Code: "MAIN", Name: "Main Library"})
+	require.NoError(t, err)
+	east, err := branchService.CreateBranch(ctx, dto.BranchRequest{Code: "EAST", Name: "East Branch"})
+	require.NoError(t, err)
+
+	dune, err := bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780441172719", Title: "Dune", Author: "Frank Herbert", TotalCopies: 3, ShelfLocation: "Stacks"})
+	require.NoError(t, err)
+	atlas, err := bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780007270491", Title: "World Atlas", Author: "Someone", TotalCopies: 1, ShelfLocation: "Reference"})
+	require.NoError(t, err)
+	emma, err := bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780141439587", Title: "Emma", Author: "Jane Austen", TotalCopies: 1, ShelfLocation: "Stacks"})
+	require.NoError(t, err)
+	for _, item := range []struct {
+		bookID   uint
+		barcode  string
+		branchID uint
+	}{
+		{dune.ID, "M-1", main.ID}, {dune.ID, "M-2", main.ID}, {dune.ID, "E-1", east.ID},
+		{atlas.ID, "M-3", main.ID}, {emma.ID, "M-4", main.ID},
+	} {
+		_, err := copyService.AddCopy(ctx, dto.Actor{}, item.bookID, dto.AddCopyRequest{Barcode: item.barcode, BranchID: item.branchID})
+		require.NoError(t, err)
+	}
+	_, err = borrowService.BorrowBook(ctx, alice.ID, dto.BorrowBookRequest{BookID: emma.ID, BranchID: main.ID})
+	require.NoError(t, err)
+
+	var appErr *apperror.AppError
+	stocktake, err := stocktakeService.StartStocktake(ctx, 1, dto.StocktakeRequest{BranchID: main.ID, Location: "stacks"})
+	require.NoError(t, err)
+	assert.Equal(t, models.StocktakeOpen, stocktake.Status)
+	_, err = stocktakeService.StartStocktake(ctx, 1, dto.StocktakeRequest{BranchID: main.ID, Location: "Stacks"})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+
+	result, err := stocktakeService.Scan(ctx, stocktake.ID, 1, dto.StocktakeScanRequest{Barcodes: []string{"M-1", "E-1", "M-4", "ZZ-9"}})
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.Recorded)
+	assert.Equal(t, []string{"ZZ-9"}, result.Unknown)
+	result, err = stocktakeService.Scan(ctx, stocktake.ID, 1, dto.StocktakeScanRequest{Barcodes: []string{"M-1", "M-3"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Recorded)
+	assert.Equal(t, 1, result.Duplicates)
+
+	barcodes := func(items []dto.StocktakeItem) []string {
+		out := []string{}
+		for _, item := range items {
+			out = append(out, item.Barcode)
+		}
+		return out
+	}
+	copyIDs := map[string]uint{}
+	for _, bookID := range []uint{dune.ID, atlas.ID} {
+		copies, err := copyService.ListCopies(ctx, bookID)
+		require.NoError(t, err)
+		for _, item := range copies {
+			copyIDs[item.Barcode] = item.ID
+		}
+	}
+
+	report, err := stocktakeService.Report(ctx, stocktake.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Expected)
+	assert.Equal(t, 5, report.Scanned)
+	assert.Equal(t, 1, report.Found)
+	assert.Equal(t, []string{"M-2"}, barcodes(report.Missing))
+	assert.Equal(t, []string{"E-1", "M-3"}, barcodes(report.Misplaced))
+	assert.Equal(t, []string{"M-4"}, barcodes(report.CheckedOut))
+	assert.Equal(t, []string{"ZZ-9"}, barcodes(report.Unexpected))
+
+	// A copy that was found cannot go missing; one that was not leaves the
+	// available copies of its book.
+	_, err = stocktakeService.MarkMissing(ctx, stocktake.ID, dto.StocktakeCopiesRequest{CopyIDs: []uint{copyIDs["M-1"]}})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	report, err = stocktakeService.MarkMissing(ctx, stocktake.ID, dto.StocktakeCopiesRequest{CopyIDs: []uint{copyIDs["M-2"]}})
+	require.NoError(t, err)
+	assert.Empty(t, report.Missing)
+	assert.Equal(t, 1, report.Expected)
+	stored, err := bookService.GetBookByID(ctx, dune.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.AvailableCopies)
+	assert.Equal(t, 3, stored.TotalCopies)
+
+	// Misplaced copies are moved where they were found, except the copy of
+	// the east branch, which is sent home.
+	report, err = stocktakeService.FixLocation(ctx, stocktake.ID, dto.StocktakeCopiesRequest{CopyIDs: []uint{copyIDs["E-1"], copyIDs["M-3"]}})
+	require.NoError(t, err)
+	assert.Empty(t, report.Misplaced)
+	assert.Equal(t, 2, report.Found)
+	assert.Equal(t, []string{"E-1", "M-4"}, barcodes(report.CheckedOut))
+	copies, err := copyService.ListCopies(ctx, dune.ID)
+	require.NoError(t, err)
+	for _, item := range copies {
+		if item.Barcode == "E-1" {
+			assert.Equal(t, east.ID, item.HomeBranchID)
+			assert.Equal(t, main.ID, item.BranchID)
+			assert.Equal(t, models.CopyInTransit, item.Status)
+		}
+	}
+	transfers, _, err := copyService.ListTransfers(ctx, models.TransferInTransit, east.ID, query.PageRequest{Page: 1, Limit: 20})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, copyIDs["E-1"], transfers[0].CopyID)
+	assert.Equal(t, main.ID, transfers[0].FromBranchID)
+	assert.Equal(t, models.TransferReturn, transfers[0].Reason)
+	stored, err = bookService.GetBookByID(ctx, dune.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.AvailableCopies)
+	copies, err = copyService.ListCopies(ctx, atlas.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "stacks", copies[0].ShelfLocation)
+
+	completed, err := stocktakeService.CompleteStocktake(ctx, stocktake.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StocktakeCompleted, completed.Status)
+	assert.Equal(t, int64(5), completed.ScanCount)
+	_, err = stocktakeService.Scan(ctx, stocktake.ID, 1, dto.StocktakeScanRequest{Barcodes: []string{"M-2"}})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+
+	// The missing copy turns up in the next stocktake and goes back on the
+	// shelf.
+	next, err := stocktakeService.StartStocktake(ctx, 1, dto.StocktakeRequest{BranchID: main.ID})
+	require.NoError(t, err)
+	_, err = stocktakeService.Scan(ctx, next.ID, 1, dto.StocktakeScanRequest{Barcodes: []string{"M-2"}})
+	require.NoError(t, err)
+	report, err = stocktakeService.Report(ctx, next.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"M-2"}, barcodes(report.Recovered))
+	report, err = stocktakeService.FixLocation(ctx, next.ID, dto.StocktakeCopiesRequest{CopyIDs: []uint{copyIDs["M-2"]}})
+	require.NoError(t, err)
+	assert.Empty(t, report.Recovered)
+	stored, err = bookService.GetBookByID(ctx, dune.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.AvailableCopies)
+
+	// The east copy is back on the shelf once it arrives.
+	_, err = copyService.ReceiveTransfer(ctx, transfers[0].ID)
+	require.NoError(t, err)
+	stored, err = bookService.GetBookByID(ctx, dune.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stored.AvailableCopies)
+
+	_, err = stocktakeService.CancelStocktake(ctx, next.ID)
+	require.NoError(t, err)
+	_, err = stocktakeService.FixLocation(ctx, next.ID, dto.StocktakeCopiesRequest{CopyIDs: []uint{copyIDs["M-2"]}})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+}
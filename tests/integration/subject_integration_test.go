@@ -0,0 +1,104 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubjects_TaxonomyBrowseAndGenreMapping(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+
+	subjectRepo := repository.NewSubjectRepository(db)
+	subjectService := service.NewSubjectService(db, subjectRepo)
+	bookService := service.NewBookService(db, repository.NewBookRepository(db), repository.NewAuthorRepository(db), subjectRepo, repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), repository.NewCopyRepository(db))
+
+	fiction, err := subjectService.CreateSubject(ctx, dto.CreateSubjectRequest{Name: "Fiction"})
+	require.NoError(t, err)
+	sciFi, err := subjectService.CreateSubject(ctx, dto.CreateSubjectRequest{Name: "Science fiction", ParentID: fiction.ID, Aliases: []string{"Sci-Fi", "SF"}})
+	require.NoError(t, err)
+	spaceOpera, err := subjectService.CreateSubject(ctx, dto.CreateSubjectRequest{Name: "Space opera", ParentID: sciFi.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 2, spaceOpera.Depth)
+	require.Len(t, spaceOpera.Ancestors, 2)
+	assert.Equal(t, "Fiction", spaceOpera.Ancestors[0].Name)
+
+	_, err = subjectService.CreateSubject(ctx, dto.CreateSubjectRequest{Name: "science-fiction", ParentID: fiction.ID})
+	require.Error(t, err, "sibling names must differ")
+
+	// A genre that is an alias assigns the subject on create.
+	dune, err := bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780306406157", Title: "Dune", Author: "Frank Herbert", Genre: "sci fi", TotalCopies: 1})
+	require.NoError(t, err)
+	require.Len(t, dune.Subjects, 1)
+	assert.Equal(t, sciFi.ID, dune.Subjects[0].ID)
+	_, err = bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780132350884", Title: "Hyperion", Author: "Dan Simmons", SubjectIDs: []uint{spaceOpera.ID}, TotalCopies: 1})
+	require.NoError(t, err)
+
+	// Legacy rows predate subjects and only carry the free-text genre.
+	legacy := []models.Book{
+		{ISBN: "9780134757599", Title: "Foundation", Author: "Isaac Asimov", Genre: "SF", TotalCopies: 1, AvailableCopies: 1},
+		{ISBN: "9780201633610", Title: "Emma", Author: "Jane Austen", Genre: "Romance", TotalCopies: 1, AvailableCopies: 1},
+	}
+	require.NoError(t, db.Create(&legacy).Error)
+
+	preview, err := subjectService.MapGenres(ctx, false, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), preview.BooksLinked)
+	assert.Equal(t, 1, preview.SubjectsCreated)
+	var count int64
+	require.NoError(t, db.Model(&models.Subject{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count, "a preview must not write")
+
+	applied, err := subjectService.MapGenres(ctx, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), applied.BooksLinked, "only the alias match is linked without create")
+	assert.Equal(t, 1, applied.Unmapped)
+
+	tree, err := subjectService.ListSubjects(ctx)
+	require.NoError(t, err)
+	require.Len(t, tree, 1)
+	assert.Equal(t, int64(3), tree[0].BookCount, "counts include descendants")
+	require.Len(t, tree[0].Children, 1)
+	assert.Equal(t, int64(3), tree[0].Children[0].BookCount)
+	require.Len(t, tree[0].Children[0].Children, 1)
+	assert.Equal(t, int64(1), tree[0].Children[0].Children[0].BookCount)
+
+	books, info, err := subjectService.ListSubjectBooks(ctx, fiction.ID, true, query.PageRequest{Page: 1, Limit: 10, Sort: "title_asc"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), info.Total)
+	assert.Equal(t, "Dune", books[0].Title)
+	_, info, err = subjectService.ListSubjectBooks(ctx, sciFi.ID, false, query.PageRequest{Page: 1, Limit: 10, Sort: "title_asc"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), info.Total)
+
+	// Moving a subtree rewrites the paths below it.
+	genres, err := subjectService.CreateSubject(ctx, dto.CreateSubjectRequest{Name: "Genres"})
+	require.NoError(t, err)
+	_, err = subjectService.MoveSubject(ctx, fiction.ID, 0, spaceOpera.ID)
+	require.Error(t, err, "a subject cannot move below its descendant")
+	_, err = subjectService.MoveSubject(ctx, fiction.ID, 0, genres.ID)
+	require.NoError(t, err)
+	moved, err := subjectService.GetSubject(ctx, spaceOpera.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, moved.Depth)
+	require.Len(t, moved.Ancestors, 3)
+	assert.Equal(t, "Genres", moved.Ancestors[0].Name)
+	_, info, err = subjectService.ListSubjectBooks(ctx, genres.ID, true, query.PageRequest{Page: 1, Limit: 10, Sort: "title_asc"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), info.Total)
+
+	require.Error(t, subjectService.DeleteSubject(ctx, spaceOpera.ID, 0), "subjects with books cannot be deleted")
+	require.NoError(t, bookService.DeleteBook(ctx, dto.Actor{}, dune.ID, 0, dto.RemoveBookRequest{}))
+	_, info, err = subjectService.ListSubjectBooks(ctx, genres.ID, true, query.PageRequest{Page: 1, Limit: 10, Sort: "title_asc"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), info.Total, "withdrawn books drop out of subject listings")
+}
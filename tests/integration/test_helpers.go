@@ -2,6 +2,7 @@ package integration
 
 import (
 	"fmt"
+	"os"
 	"testing"
 
 	"github.com/alpardfm/library-management-api/configs"
@@ -23,8 +24,12 @@ func setupIntegrationTestDB(t *testing.T) (*gorm.DB, func()) {
 
 	t.Setenv("DB_NAME", "library_test")
 	t.Setenv("DB_SSLMODE", "disable")
+	if os.Getenv("DB_DRIVER") == database.DriverSQLite && os.Getenv("DB_PATH") == "" {
+		t.Setenv("DB_PATH", ":memory:")
+	}
 
-	db, err := database.Connect()
+	cfg := loadIntegrationConfig(t)
+	db, err := database.Open(&cfg.Database)
 	if err != nil {
 		t.Skipf("skipping integration test: test database is unavailable: %v", err)
 	}
@@ -57,27 +62,50 @@ func setupIntegrationTestDB(t *testing.T) (*gorm.DB, func()) {
 }
 
 func resetIntegrationTestDB(db *gorm.DB) error {
-	if err := db.Exec("TRUNCATE TABLE borrow_records, books, users RESTART IDENTITY CASCADE").Error; err != nil {
+	if database.DialectOf(db).Name() == database.DriverSQLite {
+		// Subjects reference their parent, so detach them before deleting.
+		if err := db.Exec("UPDATE subjects SET parent_id = NULL").Error; err != nil {
+			return fmt.Errorf("detach integration subjects: %w", err)
+		}
+		for _, table := range []string{"book_subjects", "subject_aliases", "subjects", "book_contributors", "author_variants", "authors", "marc_records", "import_job_issues", "import_jobs", "setting_changes", "settings", "account_entries", "stocktake_scans", "stocktakes", "transfers", "copies", "branches", "holds", "borrow_records", "book_revisions", "books", "works", "series", "users", "sqlite_sequence"} {
+			if err := db.Exec("DELETE FROM " + table).Error; err != nil {
+				return fmt.Errorf("clear integration table %s: %w", table, err)
+			}
+		}
+		return nil
+	}
+
+	if err := db.Exec("TRUNCATE TABLE book_subjects, subject_aliases, subjects, book_contributors, author_variants, authors, marc_records, import_job_issues, import_jobs, setting_changes, settings, account_entries, stocktake_scans, stocktakes, transfers, copies, branches, holds, borrow_records, book_revisions, books, works, series, users RESTART IDENTITY CASCADE").Error; err != nil {
 		return fmt.Errorf("truncate integration tables: %w", err)
 	}
 	return nil
 }
 
-func setupIntegrationRouter(db *gorm.DB) *gin.Engine {
+func loadIntegrationConfig(t *testing.T) *configs.Config {
+	t.Helper()
+
+	cfg, err := configs.Load()
+	require.NoError(t, err)
+	return cfg
+}
+
+func setupIntegrationRouter(t *testing.T, db *gorm.DB) *gin.Engine {
+	t.Helper()
 	gin.SetMode(gin.TestMode)
 
-	cfg := configs.Load()
+	cfg := loadIntegrationConfig(t)
 
 	userRepo := repository.NewUserRepository(db)
 	bookRepo := repository.NewBookRepository(db)
 	borrowRepo := repository.NewBorrowRepository(db)
 
-	authService := service.NewAuthService(userRepo, cfg.JWTSecret, cfg.JWTExpiry)
-	bookService := service.NewBookService(bookRepo)
-	borrowService := service.NewBorrowService(db, borrowRepo, bookRepo, userRepo, service.BorrowServiceConfig{
-		MaxBooksPerUser: cfg.MaxBooksPerUser,
-		BorrowDays:      cfg.BorrowDays,
-		FinePerDay:      cfg.FinePerDay,
+	authService := service.NewAuthService(userRepo, cfg.JWT.Secret, cfg.JWT.Expiry)
+	bookService := service.NewBookService(db, bookRepo, repository.NewAuthorRepository(db), repository.NewSubjectRepository(db), repository.NewBookRevisionRepository(db), repository.NewWorkRepository(db), repository.NewCopyRepository(db))
+	borrowService := service.NewBorrowService(db, borrowRepo, bookRepo, repository.NewBookRevisionRepository(db), userRepo, repository.NewHoldRepository(db), repository.NewCopyRepository(db), repository.NewTransferRepository(db), repository.NewBranchRepository(db), repository.NewAccountRepository(db), service.BorrowServiceConfig{
+		MaxBooksPerUser: cfg.Circulation.MaxBooksPerUser,
+		BorrowDays:      cfg.Circulation.BorrowDays,
+		FinePerDay:      cfg.Circulation.FinePerDay,
+		ProcessingFee:   cfg.Circulation.ProcessingFee,
 	})
 
 	authHandler := handler.NewAuthHandler(authService)
@@ -0,0 +1,191 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/handler"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorks_GroupEditionsAndCollapseSearch(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	workRepo := repository.NewWorkRepository(db)
+	bookService := service.NewBookService(db, repository.NewBookRepository(db), repository.NewAuthorRepository(db),
+		repository.NewSubjectRepository(db), repository.NewBookRevisionRepository(db), workRepo, repository.NewCopyRepository(db))
+	workService := service.NewWorkService(workRepo, repository.NewSeriesRepository(db))
+
+	bookHandler := handler.NewBookHandler(bookService)
+	router := gin.New()
+	router.GET("/books", bookHandler.ListBooks)
+	list := func(query string) []models.Book {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books"+query, nil))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var body struct {
+			Data []models.Book `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return body.Data
+	}
+
+	dune, err := workService.CreateSeries(ctx, dto.SeriesRequest{Name: "Dune"})
+	require.NoError(t, err)
+	messiah, err := workService.CreateWork(ctx, dto.WorkRequest{Title: "Dune Messiah", Author: "Frank Herbert", SeriesID: dune.ID, Volume: 2})
+	require.NoError(t, err)
+	novel, err := workService.CreateWork(ctx, dto.WorkRequest{Title: "Dune", Author: "Frank Herbert", SeriesID: dune.ID, Volume: 1})
+	require.NoError(t, err)
+
+	editions := []dto.CreateBookRequest{
+		{ISBN: "9780441172719", Title: "Dune", Author: "Frank Herbert", Language: "eng", TotalCopies: 1, WorkID: novel.ID},
+		{ISBN: "9782266320481", Title: "Dune (édition française)", Author: "Frank Herbert", Language: "fre", TotalCopies: 1, WorkID: novel.ID},
+		{ISBN: "9780593098233", Title: "Dune Messiah", Author: "Frank Herbert", Language: "eng", TotalCopies: 1, WorkID: messiah.ID},
+		{ISBN: "9780306406157", Title: "The Dune Encyclopedia", Author: "Willis McNelly", Language: "eng", TotalCopies: 1},
+	}
+	for _, req := range editions {
+		_, err := bookService.CreateBook(ctx, dto.Actor{}, req)
+		require.NoError(t, err)
+	}
+	_, err = bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780441013593", Title: "Dune", Author: "Frank Herbert", TotalCopies: 1, WorkID: 99})
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Contains(t, appErr.Fields, "work_id")
+
+	assert.Len(t, list("?search=dune"), 4)
+	collapsed := list("?search=dune&collapse=work&sort=title_asc")
+	require.Len(t, collapsed, 3)
+	assert.Equal(t, "Dune", collapsed[0].Title, "the first catalogued edition stands for the work")
+	assert.Equal(t, int64(2), collapsed[0].EditionCount)
+	assert.Equal(t, int64(2), collapsed[0].AvailableEditions)
+	assert.Equal(t, int64(1), collapsed[2].EditionCount, "a book without a work is its only edition")
+
+	french := list("?language=fre&collapse=work")
+	require.Len(t, french, 1)
+	assert.Equal(t, int64(1), french[0].EditionCount, "only matching editions are counted")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books?collapse=author", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	series, err := workService.GetSeries(ctx, dune.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), series.WorkCount)
+	require.Len(t, series.Works, 2)
+	assert.Equal(t, []string{"Dune", "Dune Messiah"}, []string{series.Works[0].Title, series.Works[1].Title})
+	assert.Equal(t, int64(2), series.Works[0].EditionCount)
+
+	work, err := workService.GetWork(ctx, novel.ID)
+	require.NoError(t, err)
+	assert.Len(t, work.Editions, 2)
+	require.ErrorAs(t, workService.DeleteWork(ctx, novel.ID, 0), &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	require.ErrorAs(t, workService.DeleteSeries(ctx, dune.ID, 0), &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+
+	// Unlinking the last edition lets the work go.
+	_, _, err = bookService.PatchBook(ctx, dto.Actor{}, 3, 0, []byte(`{"work_id":null}`))
+	require.NoError(t, err)
+	require.NoError(t, workService.DeleteWork(ctx, messiah.ID, 0))
+}
+
+func TestHolds_AnyEditionOfAWork(t *testing.T) {
+	db, cleanup := setupIntegrationTestDB(t)
+	t.Cleanup(cleanup)
+	ctx := context.Background()
+
+	bookRepo := repository.NewBookRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	workRepo := repository.NewWorkRepository(db)
+	holdRepo := repository.NewHoldRepository(db)
+	bookService := service.NewBookService(db, bookRepo, repository.NewAuthorRepository(db),
+		repository.NewSubjectRepository(db), repository.NewBookRevisionRepository(db), workRepo, repository.NewCopyRepository(db))
+	workService := service.NewWorkService(workRepo, repository.NewSeriesRepository(db))
+	borrowService := service.NewBorrowService(db, repository.NewBorrowRepository(db), bookRepo, repository.NewBookRevisionRepository(db), userRepo, holdRepo, repository.NewCopyRepository(db), repository.NewTransferRepository(db), repository.NewBranchRepository(db), repository.NewAccountRepository(db), service.BorrowServiceConfig{
+		MaxBooksPerUser: 5, BorrowDays: 14, FinePerDay: 1000,
+	})
+	holdService := service.NewHoldService(db, holdRepo, bookRepo, workRepo, userRepo, repository.NewCopyRepository(db))
+
+	members := map[string]uint{}
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		user := &models.User{Username: name, Email: name + "@example.com", PasswordHash: "hash", Role: models.RoleMember, IsActive: true}
+		require.NoError(t, db.Create(user).Error)
+		members[name] = user.ID
+	}
+	work, err := workService.CreateWork(ctx, dto.WorkRequest{Title: "Dune", Author: "Frank Herbert"})
+	require.NoError(t, err)
+	english, err := bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9780441172719", Title: "Dune", Author: "Frank Herbert", TotalCopies: 1, WorkID: work.ID})
+	require.NoError(t, err)
+	french, err := bookService.CreateBook(ctx, dto.Actor{}, dto.CreateBookRequest{ISBN: "9782266320481", Title: "Dune", Author: "Frank Herbert", TotalCopies: 1, WorkID: work.ID})
+	require.NoError(t, err)
+
+	var appErr *apperror.AppError
+	_, err = holdService.PlaceHold(ctx, members["carol"], dto.PlaceHoldRequest{WorkID: work.ID})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code, "a copy is on the shelf")
+
+	borrowed, err := borrowService.BorrowBook(ctx, members["alice"], dto.BorrowBookRequest{BookID: english.ID})
+	require.NoError(t, err)
+	frenchLoan, err := borrowService.BorrowBook(ctx, members["bob"], dto.BorrowBookRequest{BookID: french.ID})
+	require.NoError(t, err)
+
+	carolHold, err := holdService.PlaceHold(ctx, members["carol"], dto.PlaceHoldRequest{WorkID: work.ID})
+	require.NoError(t, err)
+	_, err = holdService.PlaceHold(ctx, members["carol"], dto.PlaceHoldRequest{WorkID: work.ID})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code, "one hold per title")
+	daveHold, err := holdService.PlaceHold(ctx, members["dave"], dto.PlaceHoldRequest{BookID: english.ID})
+	require.NoError(t, err)
+
+	// The French copy comes back first and goes to carol's any-edition hold.
+	_, _, err = borrowService.ReturnBook(ctx, members["bob"], "member", dto.ReturnBookRequest{BorrowRecordID: frenchLoan.ID})
+	require.NoError(t, err)
+	held, err := holdRepo.FindReady(ctx, members["carol"], french.ID)
+	require.NoError(t, err)
+	assert.Equal(t, carolHold.ID, held.ID)
+	stored, err := bookService.GetBookByID(ctx, french.ID)
+	require.NoError(t, err)
+	assert.Zero(t, stored.AvailableCopies, "the copy waits for carol")
+
+	_, err = borrowService.BorrowBook(ctx, members["bob"], dto.BorrowBookRequest{BookID: french.ID})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	_, err = borrowService.BorrowBook(ctx, members["carol"], dto.BorrowBookRequest{BookID: french.ID})
+	require.NoError(t, err)
+	holds, _, err := holdService.ListUserHolds(ctx, members["carol"], query.PageRequest{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, holds, 1)
+	assert.Equal(t, models.HoldFulfilled, holds[0].Status)
+
+	// Dave's ready copy goes back on the shelf when he cancels.
+	_, _, err = borrowService.ReturnBook(ctx, members["alice"], "member", dto.ReturnBookRequest{BorrowRecordID: borrowed.ID})
+	require.NoError(t, err)
+	_, err = holdService.CancelHold(ctx, members["carol"], "member", daveHold.ID)
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeForbidden, appErr.Code)
+	cancelled, err := holdService.CancelHold(ctx, members["dave"], "member", daveHold.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldCancelled, cancelled.Status)
+	stored, err = bookService.GetBookByID(ctx, english.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.AvailableCopies)
+
+	queue, _, err := holdService.ListHolds(ctx, "", query.PageRequest{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, queue)
+}
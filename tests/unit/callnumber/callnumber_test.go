@@ -0,0 +1,122 @@
+package callnumber_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alpardfm/library-management-api/pkg/callnumber"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		scheme string
+		input  string
+		want   string
+		inWant string
+		err    error
+	}{
+		{name: "dewey", scheme: callnumber.DDC, input: "823.914 her", want: "823.914 HER", inWant: callnumber.DDC},
+		{name: "dewey inferred", input: " 823.914   HER ", want: "823.914 HER", inWant: callnumber.DDC},
+		{name: "dewey with prefix", scheme: callnumber.DDC, input: "REF 030 ENC", want: "REF 030 ENC", inWant: callnumber.DDC},
+		{name: "fiction by author", scheme: callnumber.DDC, input: "FIC HER", want: "FIC HER", inWant: callnumber.DDC},
+		{name: "lc", scheme: callnumber.LCC, input: "QA76.73.G63 D66 2016", want: "QA76.73.G63 D66 2016", inWant: callnumber.LCC},
+		{name: "lc respaced", scheme: callnumber.LCC, input: "qa 76.73 .g63", want: "QA76.73.G63", inWant: callnumber.LCC},
+		{name: "lc inferred", input: "qa76.73 .g63", want: "QA76.73.G63", inWant: callnumber.LCC},
+		{name: "lc class only", scheme: callnumber.LCC, input: "PS3545", want: "PS3545", inWant: callnumber.LCC},
+		{name: "empty", input: "  ", err: callnumber.ErrEmpty},
+		{name: "prefix needs a scheme", input: "FIC HER", err: callnumber.ErrNoScheme},
+		{name: "unknown scheme", scheme: "udc", input: "821.111", err: callnumber.ErrUnknownScheme},
+		{name: "dewey class too short", scheme: callnumber.DDC, input: "82 HER", err: callnumber.ErrInvalidDDC},
+		{name: "prefix alone", scheme: callnumber.DDC, input: "FIC", err: callnumber.ErrInvalidDDC},
+		{name: "lc without number", scheme: callnumber.LCC, input: "QA", err: callnumber.ErrInvalidLCC},
+		{name: "lc class runs on", scheme: callnumber.LCC, input: "QA76A1", err: callnumber.ErrInvalidLCC},
+		{name: "punctuation", scheme: callnumber.DDC, input: "823.914 HER/2", err: callnumber.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := callnumber.Parse(tt.scheme, tt.input)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Value)
+			assert.Equal(t, tt.inWant, got.Scheme)
+			assert.Less(t, got.Key(), callnumber.NoKey)
+		})
+	}
+}
+
+func TestKey_ShelfOrder(t *testing.T) {
+	shelves := map[string][]string{
+		callnumber.DDC: {
+			"005.13 STR",
+			"005.133 KER",
+			"005.2 ABE",
+			"823 AUS",
+			"823.8 DIC",
+			"823.914 HER",
+			"823.914 HER 1965",
+			"823.914 HER V.2",
+			"823.914 HER V.10",
+			"823.914 HERB",
+			"FIC ADA",
+			"FIC HER",
+			"REF 030 ENC",
+		},
+		callnumber.LCC: {
+			"P35 .C5",
+			"PR6023.A93",
+			"PS3545.I345",
+			"Q180.55 .M4",
+			"QA9 .B7",
+			"QA76 .C3",
+			"QA76.73.G63 D66 2016",
+			"QA76.73.G7",
+			"QA76.9 .D3",
+			"QA761 .A2",
+		},
+	}
+
+	for scheme, want := range shelves {
+		t.Run(scheme, func(t *testing.T) {
+			got := append([]string(nil), want...)
+			rand.New(rand.NewSource(1)).Shuffle(len(got), func(i, j int) { got[i], got[j] = got[j], got[i] })
+			keys := map[string]string{}
+			for _, value := range got {
+				parsed, err := callnumber.Parse(scheme, value)
+				require.NoError(t, err, value)
+				keys[value] = parsed.Key()
+			}
+			sort.Slice(got, func(i, j int) bool { return keys[got[i]] < keys[got[j]] })
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestLimit(t *testing.T) {
+	key := func(scheme, value string) string {
+		parsed, err := callnumber.Parse(scheme, value)
+		require.NoError(t, err)
+		return parsed.Key()
+	}
+	qa76, err := callnumber.Parse(callnumber.LCC, "QA76")
+	require.NoError(t, err)
+
+	assert.Less(t, key(callnumber.LCC, "QA76.73.G63"), qa76.Limit())
+	assert.Less(t, key(callnumber.LCC, "QA76 .C3"), qa76.Limit())
+	assert.Greater(t, key(callnumber.LCC, "QA761"), qa76.Limit())
+	assert.Greater(t, key(callnumber.LCC, "QA77"), qa76.Limit())
+	assert.Less(t, qa76.Limit(), callnumber.NoKey+callnumber.NoKey)
+
+	dewey823, err := callnumber.Parse(callnumber.DDC, "823")
+	require.NoError(t, err)
+	assert.Less(t, key(callnumber.DDC, "823.914 HER"), dewey823.Limit())
+	assert.Greater(t, key(callnumber.DDC, "824 ABC"), dewey823.Limit())
+}
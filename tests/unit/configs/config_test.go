@@ -0,0 +1,160 @@
+package configs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/configs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearConfigEnv blanks every config variable so the host environment cannot leak in.
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+	for _, s := range (&configs.Config{}).Describe() {
+		t.Setenv(s.Env, "")
+	}
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func settingsByKey(cfg *configs.Config) map[string]configs.Setting {
+	out := make(map[string]configs.Setting)
+	for _, s := range cfg.Describe() {
+		out[s.Key] = s
+	}
+	return out
+}
+
+func TestLoadFile_Defaults(t *testing.T) {
+	clearConfigEnv(t)
+
+	cfg, err := configs.LoadFile("")
+
+	require.NoError(t, err)
+	assert.Equal(t, "8080", cfg.Server.Port)
+	assert.Equal(t, 10*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, "postgres", cfg.Database.Driver)
+	assert.Equal(t, 15*time.Second, cfg.Database.StatementTimeout)
+	assert.Equal(t, 24*time.Hour, cfg.JWT.Expiry)
+	assert.Equal(t, 14, cfg.Circulation.BorrowDays)
+	assert.Equal(t, configs.SourceDefault, settingsByKey(cfg)["circulation.borrow_days"].Source)
+}
+
+func TestLoadFile_YAMLWithEnvOverride(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("BORROW_DAYS", "21")
+	path := writeConfigFile(t, "config.yaml", `
+database:
+  driver: sqlite
+  path: ":memory:"
+circulation:
+  borrow_days: 7
+  fine_per_day: 500
+`)
+
+	cfg, err := configs.LoadFile(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite", cfg.Database.Driver)
+	assert.Equal(t, ":memory:", cfg.Database.Path)
+	assert.Equal(t, 21, cfg.Circulation.BorrowDays)
+	assert.Equal(t, 500, cfg.Circulation.FinePerDay)
+
+	settings := settingsByKey(cfg)
+	assert.Equal(t, configs.SourceEnv, settings["circulation.borrow_days"].Source)
+	assert.Equal(t, configs.SourceFile, settings["circulation.fine_per_day"].Source)
+}
+
+func TestLoadFile_TOML(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeConfigFile(t, "config.toml", `
+[server]
+port = 9090
+read_timeout = "5s"
+
+[circulation]
+max_books_per_user = 3
+`)
+
+	cfg, err := configs.LoadFile(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "9090", cfg.Server.Port)
+	assert.Equal(t, 5*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 3, cfg.Circulation.MaxBooksPerUser)
+}
+
+func TestLoadFile_ReportsAllErrors(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("BORROW_DAYS", "1O")
+	t.Setenv("JWT_EXPIRY", "forever")
+	t.Setenv("FINE_PER_DAY", "-5")
+	path := writeConfigFile(t, "config.yaml", `
+server:
+  port: 70000
+cirulation:
+  borrow_days: 7
+`)
+
+	_, err := configs.LoadFile(path)
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `BORROW_DAYS: invalid integer "1O"`)
+	assert.ErrorContains(t, err, `JWT_EXPIRY: invalid duration "forever"`)
+	assert.ErrorContains(t, err, "cirulation.borrow_days")
+	assert.ErrorContains(t, err, "unknown setting")
+	assert.ErrorContains(t, err, "server.port (APP_PORT): must be a port number")
+	assert.ErrorContains(t, err, "circulation.fine_per_day (FINE_PER_DAY): must be at least 0")
+}
+
+func TestLoadFile_RejectsUnsupportedFormat(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeConfigFile(t, "config.json", `{}`)
+
+	_, err := configs.LoadFile(path)
+
+	assert.ErrorContains(t, err, "unsupported format")
+}
+
+func TestLoadFile_ProductionRefusesInsecureDefaults(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("APP_ENV", "production")
+
+	_, err := configs.LoadFile("")
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "jwt.secret (JWT_SECRET): must be changed from the default in production")
+	assert.ErrorContains(t, err, "database.password (DB_PASSWORD): must be changed from the default in production")
+
+	t.Setenv("JWT_SECRET", "short")
+	t.Setenv("DB_PASSWORD", "s3cret-db-password")
+	_, err = configs.LoadFile("")
+	assert.ErrorContains(t, err, "must be at least 32 characters in production")
+
+	t.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	_, err = configs.LoadFile("")
+	assert.NoError(t, err)
+}
+
+func TestSettings_RedactsSecrets(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("JWT_SECRET", "super-secret")
+
+	cfg, err := configs.LoadFile("")
+	require.NoError(t, err)
+
+	settings := settingsByKey(cfg)
+	assert.Equal(t, "[redacted]", settings["jwt.secret"].Value)
+	assert.Equal(t, "[redacted]", settings["database.password"].Value)
+	assert.Equal(t, "localhost", settings["database.host"].Value)
+	assert.Equal(t, "JWT_SECRET", settings["jwt.secret"].Env)
+}
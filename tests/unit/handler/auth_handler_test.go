@@ -3,6 +3,7 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -24,16 +25,16 @@ type MockAuthService struct {
 	mock.Mock
 }
 
-func (m *MockAuthService) Register(req dto.RegisterRequest) (*models.User, error) {
-	args := m.Called(req)
+func (m *MockAuthService) Register(ctx context.Context, req dto.RegisterRequest) (*models.User, error) {
+	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockAuthService) Login(req dto.LoginRequest) (*dto.LoginResponse, error) {
-	args := m.Called(req)
+func (m *MockAuthService) Login(ctx context.Context, req dto.LoginRequest) (*dto.LoginResponse, error) {
+	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -77,7 +78,7 @@ func TestAuthHandler_Register(t *testing.T) {
 
 	// Test Case 1: Success
 	t.Run("Success", func(t *testing.T) {
-		mockService.On("Register", reqBody).Return(expectedUser, nil).Once()
+		mockService.On("Register", mock.Anything, reqBody).Return(expectedUser, nil).Once()
 
 		jsonBody, _ := json.Marshal(reqBody)
 		req := httptest.NewRequest("POST", "/register", bytes.NewBuffer(jsonBody))
@@ -125,7 +126,7 @@ func TestAuthHandler_Register(t *testing.T) {
 
 	// Test Case 3: Service error
 	t.Run("Service Error", func(t *testing.T) {
-		mockService.On("Register", reqBody).
+		mockService.On("Register", mock.Anything, reqBody).
 			Return((*models.User)(nil), apperror.Conflict("username already exists")).
 			Once()
 
@@ -179,7 +180,7 @@ func TestAuthHandler_Login(t *testing.T) {
 
 	// Test Case 1: Success
 	t.Run("Success", func(t *testing.T) {
-		mockService.On("Login", reqBody).Return(expectedResponse, nil).Once()
+		mockService.On("Login", mock.Anything, reqBody).Return(expectedResponse, nil).Once()
 
 		jsonBody, _ := json.Marshal(reqBody)
 		req := httptest.NewRequest("POST", "/login", bytes.NewBuffer(jsonBody))
@@ -205,7 +206,7 @@ func TestAuthHandler_Login(t *testing.T) {
 
 	// Test Case 2: Invalid credentials
 	t.Run("Invalid Credentials", func(t *testing.T) {
-		mockService.On("Login", reqBody).
+		mockService.On("Login", mock.Anything, reqBody).
 			Return((*dto.LoginResponse)(nil), apperror.Unauthorized("invalid credentials")).
 			Once()
 
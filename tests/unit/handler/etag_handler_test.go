@@ -0,0 +1,94 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/handler"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBookHandler_GetBook_SendsETagAndHonoursIfNoneMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockBookService)
+	bookHandler := handler.NewBookHandler(mockService)
+	router := gin.New()
+	router.GET("/books/:id", bookHandler.GetBook)
+
+	mockService.On("GetBookByID", mock.Anything, uint(1)).Return(&models.Book{ID: 1, Title: "Dune", Version: 3}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books/1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+
+	for header, want := range map[string]int{
+		`"3"`:        http.StatusNotModified,
+		`"2", W/"3"`: http.StatusNotModified,
+		`*`:          http.StatusNotModified,
+		`"2"`:        http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/books/1", nil)
+		req.Header.Set("If-None-Match", header)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, header)
+		if want == http.StatusNotModified {
+			assert.Empty(t, w.Body.String())
+			assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+		}
+	}
+}
+
+func TestBookHandler_PatchBook_RequiresIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockBookService)
+	bookHandler := handler.NewBookHandler(mockService)
+	router := gin.New()
+	router.PATCH("/books/:id", bookHandler.PatchBook)
+	router.DELETE("/books/:id", bookHandler.DeleteBook)
+
+	patch := []byte(`{"title":"Dune Messiah"}`)
+	mockService.On("PatchBook", mock.Anything, mock.Anything, uint(1), uint(3), patch).
+		Return(&models.Book{ID: 1, Version: 4}, []dto.FieldChange{{Field: "title", From: "Dune", To: "Dune Messiah"}}, nil).
+		Once()
+	mockService.On("PatchBook", mock.Anything, mock.Anything, uint(1), uint(2), patch).
+		Return(nil, nil, apperror.PreconditionFailed("book has changed since version 2; fetch it again")).
+		Once()
+	mockService.On("DeleteBook", mock.Anything, mock.Anything, uint(1), uint(0), dto.RemoveBookRequest{}).Return(nil).Once()
+
+	send := func(method, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/books/1", nil)
+		if method == http.MethodPatch {
+			req = httptest.NewRequest(method, "/books/1", strings.NewReader(string(patch)))
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+		}
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPatch, `"3"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+	assert.Equal(t, http.StatusPreconditionFailed, send(http.MethodPatch, `"2"`).Code)
+	assert.Equal(t, http.StatusPreconditionRequired, send(http.MethodPatch, "").Code)
+	assert.Equal(t, http.StatusPreconditionRequired, send(http.MethodDelete, "").Code)
+	assert.Equal(t, http.StatusPreconditionFailed, send(http.MethodPatch, `W/"3"`).Code, "weak tags never match")
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPatch, `"3", "4"`).Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, "*").Code)
+
+	mockService.AssertExpectations(t)
+}
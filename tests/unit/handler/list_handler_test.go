@@ -1,15 +1,18 @@
 package handler_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/alpardfm/library-management-api/internal/dto"
 	"github.com/alpardfm/library-management-api/internal/handler"
 	"github.com/alpardfm/library-management-api/internal/models"
 	"github.com/alpardfm/library-management-api/pkg/auth"
+	"github.com/alpardfm/library-management-api/pkg/query"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -19,42 +22,102 @@ type MockBookService struct {
 	mock.Mock
 }
 
-func (m *MockBookService) CreateBook(req dto.CreateBookRequest) (*models.Book, error) {
-	args := m.Called(req)
+func (m *MockBookService) CreateBook(ctx context.Context, actor dto.Actor, req dto.CreateBookRequest) (*models.Book, error) {
+	args := m.Called(ctx, actor, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Book), args.Error(1)
 }
 
-func (m *MockBookService) GetBookByID(id uint) (*models.Book, error) {
-	args := m.Called(id)
+func (m *MockBookService) GetBookByID(ctx context.Context, id uint) (*models.Book, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Book), args.Error(1)
 }
 
-func (m *MockBookService) UpdateBook(id uint, req dto.UpdateBookRequest) (*models.Book, error) {
-	args := m.Called(id, req)
+func (m *MockBookService) ReplaceBook(ctx context.Context, actor dto.Actor, id, version uint, req dto.CreateBookRequest) (*models.Book, []dto.FieldChange, error) {
+	args := m.Called(ctx, actor, id, version, req)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.Book), args.Get(1).([]dto.FieldChange), args.Error(2)
+}
+
+func (m *MockBookService) PatchBook(ctx context.Context, actor dto.Actor, id, version uint, patch []byte) (*models.Book, []dto.FieldChange, error) {
+	args := m.Called(ctx, actor, id, version, patch)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.Book), args.Get(1).([]dto.FieldChange), args.Error(2)
+}
+
+func (m *MockBookService) DeleteBook(ctx context.Context, actor dto.Actor, id, version uint, req dto.RemoveBookRequest) error {
+	args := m.Called(ctx, actor, id, version, req)
+	return args.Error(0)
+}
+
+func (m *MockBookService) ListRemovedBooks(ctx context.Context, status, search string, req query.PageRequest) ([]models.Book, query.PageInfo, error) {
+	args := m.Called(ctx, status, search, req)
+	return args.Get(0).([]models.Book), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockBookService) RestoreBook(ctx context.Context, actor dto.Actor, id uint) (*models.Book, error) {
+	args := m.Called(ctx, actor, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Book), args.Error(1)
 }
 
-func (m *MockBookService) DeleteBook(id uint) error {
-	args := m.Called(id)
-	return args.Error(0)
+func (m *MockBookService) BookHistory(ctx context.Context, id uint, req query.PageRequest) ([]models.BookRevision, query.PageInfo, error) {
+	args := m.Called(ctx, id, req)
+	return args.Get(0).([]models.BookRevision), args.Get(1).(query.PageInfo), args.Error(2)
 }
 
-func (m *MockBookService) ListBooks(page, limit int, search, sort string) ([]models.Book, int64, error) {
-	args := m.Called(page, limit, search, sort)
-	return args.Get(0).([]models.Book), args.Get(1).(int64), args.Error(2)
+func (m *MockBookService) RevertBook(ctx context.Context, actor dto.Actor, id, version uint, req dto.RevertBookRequest) (*models.Book, []dto.FieldChange, error) {
+	args := m.Called(ctx, actor, id, version, req)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.Book), args.Get(1).([]dto.FieldChange), args.Error(2)
 }
 
-func (m *MockBookService) CheckAvailability(id uint) (bool, error) {
-	args := m.Called(id)
+func (m *MockBookService) ListBooks(ctx context.Context, filter dto.BookListFilter, req query.PageRequest) ([]models.Book, query.PageInfo, error) {
+	args := m.Called(ctx, filter, req)
+	return args.Get(0).([]models.Book), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockBookService) SearchBooks(ctx context.Context, filter dto.BookListFilter, req query.PageRequest) ([]models.Book, query.PageInfo, string, error) {
+	args := m.Called(ctx, filter, req)
+	return args.Get(0).([]models.Book), args.Get(1).(query.PageInfo), args.String(2), args.Error(3)
+}
+
+func (m *MockBookService) SuggestBooks(ctx context.Context, term string, limit int) ([]dto.BookSuggestion, error) {
+	args := m.Called(ctx, term, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dto.BookSuggestion), args.Error(1)
+}
+
+func (m *MockBookService) SpellingSuggestion(ctx context.Context, search string) (string, error) {
+	args := m.Called(ctx, search)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockBookService) BookFacets(ctx context.Context, filter dto.BookListFilter, facets []string) (map[string][]dto.FacetValue, error) {
+	args := m.Called(ctx, filter, facets)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string][]dto.FacetValue), args.Error(1)
+}
+
+func (m *MockBookService) CheckAvailability(ctx context.Context, id uint) (bool, error) {
+	args := m.Called(ctx, id)
 	return args.Bool(0), args.Error(1)
 }
 
@@ -62,42 +125,79 @@ type MockBorrowService struct {
 	mock.Mock
 }
 
-func (m *MockBorrowService) BorrowBook(userID uint, req dto.BorrowBookRequest) (*models.BorrowRecord, error) {
-	args := m.Called(userID, req)
+func (m *MockBorrowService) BorrowBook(ctx context.Context, userID uint, req dto.BorrowBookRequest) (*models.BorrowRecord, error) {
+	args := m.Called(ctx, userID, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.BorrowRecord), args.Error(1)
 }
 
-func (m *MockBorrowService) ReturnBook(userID uint, role string, req dto.ReturnBookRequest) (*models.BorrowRecord, int, error) {
-	args := m.Called(userID, role, req)
+func (m *MockBorrowService) ReturnBook(ctx context.Context, userID uint, role string, req dto.ReturnBookRequest) (*models.BorrowRecord, int, error) {
+	args := m.Called(ctx, userID, role, req)
 	if args.Get(0) == nil {
 		return nil, 0, args.Error(2)
 	}
 	return args.Get(0).(*models.BorrowRecord), args.Int(1), args.Error(2)
 }
 
-func (m *MockBorrowService) GetUserBorrows(userID uint, page, limit int, sort string) ([]models.BorrowRecord, int64, error) {
-	args := m.Called(userID, page, limit, sort)
-	return args.Get(0).([]models.BorrowRecord), args.Get(1).(int64), args.Error(2)
+func (m *MockBorrowService) DeclareLost(ctx context.Context, actor dto.Actor, req dto.DeclareLostRequest) (*models.BorrowRecord, []models.AccountEntry, error) {
+	args := m.Called(ctx, actor, req)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.BorrowRecord), args.Get(1).([]models.AccountEntry), args.Error(2)
+}
+
+func (m *MockBorrowService) ReturnDamaged(ctx context.Context, actor dto.Actor, req dto.ReturnDamagedRequest) (*models.BorrowRecord, []models.AccountEntry, error) {
+	args := m.Called(ctx, actor, req)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.BorrowRecord), args.Get(1).([]models.AccountEntry), args.Error(2)
 }
 
-func (m *MockBorrowService) GetActiveBorrows(page, limit int, sort string) ([]models.BorrowRecord, int64, error) {
-	args := m.Called(page, limit, sort)
-	return args.Get(0).([]models.BorrowRecord), args.Get(1).(int64), args.Error(2)
+func (m *MockBorrowService) ReturnLost(ctx context.Context, actor dto.Actor, req dto.ReturnBookRequest) (*models.BorrowRecord, []models.AccountEntry, error) {
+	args := m.Called(ctx, actor, req)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.BorrowRecord), args.Get(1).([]models.AccountEntry), args.Error(2)
 }
 
-func (m *MockBorrowService) GetOverdueBorrows(page, limit int, sort string) ([]models.BorrowRecord, int64, error) {
-	args := m.Called(page, limit, sort)
-	return args.Get(0).([]models.BorrowRecord), args.Get(1).(int64), args.Error(2)
+func (m *MockBorrowService) GetUserBorrows(ctx context.Context, userID uint, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error) {
+	args := m.Called(ctx, userID, req)
+	return args.Get(0).([]models.BorrowRecord), args.Get(1).(query.PageInfo), args.Error(2)
 }
 
-func (m *MockBorrowService) CalculateFine(borrowID uint) (int, error) {
-	args := m.Called(borrowID)
+func (m *MockBorrowService) GetActiveBorrows(ctx context.Context, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).([]models.BorrowRecord), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockBorrowService) GetOverdueBorrows(ctx context.Context, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).([]models.BorrowRecord), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockBorrowService) CalculateFine(ctx context.Context, borrowID uint) (int, error) {
+	args := m.Called(ctx, borrowID)
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockBorrowService) SweepOverdue(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockBorrowService) CirculationReport(ctx context.Context, from, to time.Time) (*dto.CirculationReport, error) {
+	args := m.Called(ctx, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.CirculationReport), args.Error(1)
+}
+
 func TestBookHandler_ListBooks_UsesParsedQueryAndMeta(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -112,8 +212,8 @@ func TestBookHandler_ListBooks_UsesParsedQueryAndMeta(t *testing.T) {
 		{ID: 2, Title: "Domain-Driven Design"},
 	}
 
-	mockService.On("ListBooks", 2, 100, "golang", "title_asc").
-		Return(expectedBooks, int64(201), nil).
+	mockService.On("ListBooks", mock.Anything, dto.BookListFilter{Search: "golang"}, query.PageRequest{Page: 2, Limit: 100, Sort: "title_asc", Count: query.CountExact}).
+		Return(expectedBooks, query.PageInfo{Total: 201, Count: query.CountExact, NextCursor: "next", PrevCursor: "prev"}, nil).
 		Once()
 
 	req := httptest.NewRequest("GET", "/books?page=2&limit=999&search=golang&sort=title_asc", nil)
@@ -134,10 +234,160 @@ func TestBookHandler_ListBooks_UsesParsedQueryAndMeta(t *testing.T) {
 	assert.Equal(t, float64(3), meta["total_pages"])
 	assert.Equal(t, "title_asc", meta["sort"])
 	assert.Equal(t, "golang", meta["search"])
+	assert.Equal(t, "next", meta["next_cursor"])
+	assert.Equal(t, "prev", meta["prev_cursor"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestBookHandler_ListBooks_ParsesFiltersAndFacets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockBookService)
+	bookHandler := handler.NewBookHandler(mockService)
+
+	router := gin.New()
+	router.GET("/books", bookHandler.ListBooks)
+
+	available := true
+	filter := dto.BookListFilter{
+		SubjectIDs: []uint{3, 5},
+		AuthorIDs:  []uint{7},
+		Publishers: []string{"Farrar, Straus and Giroux", "Tor"},
+		YearFrom:   1990,
+		YearTo:     1999,
+		Languages:  []string{"eng"},
+		ItemTypes:  []string{"book", "ebook"},
+		Available:  &available,
+	}
+	mockService.On("ListBooks", mock.Anything, filter, query.PageRequest{Page: 1, Limit: 10, Sort: "created_at_desc", Count: query.CountExact}).
+		Return([]models.Book{{ID: 1}}, query.PageInfo{Total: 1, Count: query.CountExact}, nil).
+		Once()
+	mockService.On("BookFacets", mock.Anything, filter, []string(nil)).
+		Return(map[string][]dto.FacetValue{"language": {{Value: "eng", Count: 1}}}, nil).
+		Once()
+
+	req := httptest.NewRequest("GET", "/books?subject=3,5&author=7&publisher=Farrar,+Straus+and+Giroux&publisher=Tor"+
+		"&year_from=1990&year_to=1999&language=eng&item_type=book,ebook&available=true&facets=all", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	facets := response["meta"].(map[string]any)["facets"].(map[string]any)
+	assert.Len(t, facets["language"], 1)
 
 	mockService.AssertExpectations(t)
 }
 
+func TestBookHandler_ListBooks_FullTextReportsSearchMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockBookService)
+	bookHandler := handler.NewBookHandler(mockService)
+
+	router := gin.New()
+	router.GET("/books", bookHandler.ListBooks)
+
+	mockService.On("SearchBooks", mock.Anything, dto.BookListFilter{Search: "dune"}, query.PageRequest{Page: 1, Limit: 10, Sort: "relevance", Count: query.CountExact}).
+		Return([]models.Book{{ID: 1}}, query.PageInfo{Total: 1, Count: query.CountExact}, "substring", nil).
+		Once()
+	mockService.On("BookFacets", mock.Anything, dto.BookListFilter{Search: "dune", SearchMode: "substring"}, []string{"language"}).
+		Return(map[string][]dto.FacetValue{}, nil).
+		Once()
+
+	req := httptest.NewRequest("GET", "/books?search=dune&search_mode=fulltext&facets=language", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	meta := response["meta"].(map[string]any)
+	assert.Equal(t, "relevance", meta["sort"], "full-text searches sort by relevance by default")
+	assert.Equal(t, "substring", meta["search_mode"], "the fallback is reported")
+
+	mockService.AssertExpectations(t)
+}
+
+func TestBookHandler_ListBooks_EmptySearchSuggestsSpelling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockBookService)
+	bookHandler := handler.NewBookHandler(mockService)
+
+	router := gin.New()
+	router.GET("/books", bookHandler.ListBooks)
+
+	mockService.On("ListBooks", mock.Anything, dto.BookListFilter{Search: "hary poter"}, query.PageRequest{Page: 1, Limit: 10, Sort: "created_at_desc", Count: query.CountExact}).
+		Return([]models.Book{}, query.PageInfo{Count: query.CountExact}, nil).
+		Once()
+	mockService.On("SpellingSuggestion", mock.Anything, "hary poter").
+		Return("Harry Potter", nil).
+		Once()
+
+	req := httptest.NewRequest("GET", "/books?search=hary+poter", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Harry Potter", response["meta"].(map[string]any)["did_you_mean"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestBookHandler_SuggestBooks_CapsLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockBookService)
+	bookHandler := handler.NewBookHandler(mockService)
+
+	router := gin.New()
+	router.GET("/books/suggest", bookHandler.SuggestBooks)
+
+	mockService.On("SuggestBooks", mock.Anything, "harr", 20).
+		Return([]dto.BookSuggestion{{Kind: "title", Text: "Harry Potter", BookID: 1}}, nil).
+		Once()
+
+	req := httptest.NewRequest("GET", "/books/suggest?q=harr&limit=500", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+
+	req = httptest.NewRequest("GET", "/books/suggest?q=harr&limit=ten", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestBookHandler_ListBooks_InvalidFiltersReturnBadRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockBookService)
+	bookHandler := handler.NewBookHandler(mockService)
+
+	router := gin.New()
+	router.GET("/books", bookHandler.ListBooks)
+
+	for _, query := range []string{"subject=fiction", "item_type=scroll", "year_from=2000&year_to=1990", "available=maybe", "search_mode=fuzzy"} {
+		req := httptest.NewRequest("GET", "/books?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+	mockService.AssertNotCalled(t, "ListBooks")
+}
+
 func TestBookHandler_ListBooks_InvalidSortReturnsBadRequest(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -162,6 +412,39 @@ func TestBookHandler_ListBooks_InvalidSortReturnsBadRequest(t *testing.T) {
 	mockService.AssertNotCalled(t, "ListBooks")
 }
 
+func TestBorrowHandler_GetActiveBorrows_CursorPageWithoutTotal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockBorrowService)
+	borrowHandler := handler.NewBorrowHandler(mockService)
+
+	router := gin.New()
+	router.GET("/borrow/active", borrowHandler.GetActiveBorrows)
+
+	// The cursor carries the sort, so the request need not repeat it.
+	cursor := &query.Cursor{Sort: "due_date_desc", Key: []string{"2026-03-01T00:00:00Z", "12"}}
+	mockService.On("GetActiveBorrows", mock.Anything, query.PageRequest{Page: 1, Limit: 10, Sort: "due_date_desc", Cursor: cursor, Count: query.CountNone}).
+		Return([]models.BorrowRecord{{ID: 11}}, query.PageInfo{Count: query.CountNone, PrevCursor: "prev"}, nil).
+		Once()
+
+	req := httptest.NewRequest("GET", "/borrow/active?count=none&cursor="+cursor.Encode(), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	meta := response["meta"].(map[string]any)
+	assert.Equal(t, "due_date_desc", meta["sort"])
+	assert.Equal(t, "prev", meta["prev_cursor"])
+	assert.Nil(t, meta["next_cursor"])
+	assert.NotContains(t, meta, "page")
+	assert.NotContains(t, meta, "total")
+
+	mockService.AssertExpectations(t)
+}
+
 func TestBorrowHandler_GetMyBorrows_InvalidPageReturnsBadRequest(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
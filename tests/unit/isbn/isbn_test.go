@@ -0,0 +1,82 @@
+package isbn_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alpardfm/library-management-api/pkg/isbn"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		err   error
+	}{
+		{name: "plain isbn-13", input: "9780306406157", want: "9780306406157"},
+		{name: "hyphenated isbn-13", input: "978-0-306-40615-7", want: "9780306406157"},
+		{name: "labelled with spaces", input: "ISBN 978 0 306 40615 7", want: "9780306406157"},
+		{name: "isbn-13 label", input: "ISBN-13: 978-1-4028-9462-6", want: "9781402894626"},
+		{name: "isbn-10 converts", input: "0-306-40615-2", want: "9780306406157"},
+		{name: "isbn-10 with lowercase x", input: "0-8044-2957-x", want: "9780804429573"},
+		{name: "979 prefix", input: "979-10-90636-07-1", want: "9791090636071"},
+		{name: "bad isbn-13 checksum", input: "9780306406158", err: isbn.ErrInvalidChecksum},
+		{name: "bad isbn-10 checksum", input: "0306406153", err: isbn.ErrInvalidChecksum},
+		{name: "wrong length", input: "97803064061", err: isbn.ErrInvalidLength},
+		{name: "letters", input: "97803064A6157", err: isbn.ErrInvalidCharacter},
+		{name: "x inside isbn-10", input: "03X6406152", err: isbn.ErrInvalidCharacter},
+		{name: "unknown prefix", input: "9770306406152", err: isbn.ErrInvalidPrefix},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := isbn.Normalize(tt.input)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.False(t, isbn.Valid(tt.input))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.True(t, isbn.Valid(tt.input))
+		})
+	}
+}
+
+func TestToISBN10(t *testing.T) {
+	got, err := isbn.ToISBN10("978-0-8044-2957-3")
+	require.NoError(t, err)
+	assert.Equal(t, "080442957X", got)
+
+	_, err = isbn.ToISBN10("9791090636071")
+	assert.ErrorIs(t, err, isbn.ErrNoISBN10)
+}
+
+func TestToISBN13_RoundTrip(t *testing.T) {
+	isbn13, err := isbn.ToISBN13("0306406152")
+	require.NoError(t, err)
+
+	isbn10, err := isbn.ToISBN10(isbn13)
+	require.NoError(t, err)
+	assert.Equal(t, "0306406152", isbn10)
+}
+
+func TestFormat(t *testing.T) {
+	tests := map[string]string{
+		"9780306406157": "978-0-306-40615-7",
+		"0306406152":    "978-0-306-40615-7",
+		"9781402894626": "978-1-4028-9462-6",
+		"9783161484100": "978-3-16-148410-0",
+		"9780804429573": "978-0-8044-2957-3",
+		// Group known, registrant ranges not bundled.
+		"9791090636071": "979-10-9063607-1",
+		"not an isbn":   "not an isbn",
+	}
+
+	for input, want := range tests {
+		assert.Equal(t, want, isbn.Format(input), input)
+	}
+}
@@ -0,0 +1,145 @@
+package marc_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/pkg/marc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleRecord() *marc.Record {
+	rec := marc.NewRecord()
+	rec.SetControlField("001", "42")
+	rec.AddField(marc.Field{Tag: "245", Ind1: '1', Ind2: '0', Subfields: []marc.Subfield{{Code: 'a', Value: "Clean code :"}, {Code: 'b', Value: "a handbook"}}})
+	rec.AddField(marc.Field{Tag: "020", Ind1: ' ', Ind2: ' ', Subfields: []marc.Subfield{{Code: 'a', Value: "9780132350884"}}})
+	rec.AddField(marc.Field{Tag: "500", Ind1: ' ', Ind2: ' ', Subfields: []marc.Subfield{{Code: 'a', Value: "Includes index — ünïcode."}}})
+	return rec
+}
+
+func TestRecord_AddFieldKeepsTagOrder(t *testing.T) {
+	rec := sampleRecord()
+
+	tags := make([]string, len(rec.Fields))
+	for i, f := range rec.Fields {
+		tags[i] = f.Tag
+	}
+	assert.Equal(t, []string{"001", "020", "245", "500"}, tags)
+	assert.Equal(t, "245 10 $aClean code :$ba handbook", rec.Field("245").String())
+}
+
+func TestMarshal_RoundTripsAndComputesLeader(t *testing.T) {
+	rec := sampleRecord()
+
+	data, err := marc.Marshal(rec)
+	require.NoError(t, err)
+	assert.Equal(t, byte(0x1D), data[len(data)-1])
+	assert.Equal(t, fmt.Sprintf("%05d", len(data)), string(data[0:5]))
+	assert.Equal(t, byte('a'), data[9])
+	assert.Equal(t, "4500", string(data[20:24]))
+
+	got, err := marc.Unmarshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, rec.Fields, got.Fields)
+	assert.Equal(t, "a handbook", got.Field("245").Subfield('b'))
+}
+
+func TestReader_SkipsMalformedRecord(t *testing.T) {
+	good, err := marc.Marshal(sampleRecord())
+	require.NoError(t, err)
+
+	var stream bytes.Buffer
+	stream.Write(good)
+	stream.WriteString("00010nam  2200000   4500\x1D")
+	stream.Write(good)
+
+	r := marc.NewReader(&stream)
+	_, err = r.Read()
+	require.NoError(t, err)
+	_, err = r.Read()
+	assert.ErrorIs(t, err, marc.ErrMalformed)
+	_, err = r.Read()
+	require.NoError(t, err)
+	_, err = r.Read()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestUnmarshal_RejectsMARC8WithNonASCII(t *testing.T) {
+	data, err := marc.Marshal(sampleRecord())
+	require.NoError(t, err)
+	data[9] = ' '
+
+	_, err = marc.Unmarshal(data)
+	assert.ErrorIs(t, err, marc.ErrUnsupportedEncoding)
+}
+
+func TestMarshalXML_RoundTrips(t *testing.T) {
+	rec := sampleRecord()
+
+	data, err := marc.MarshalXML(rec)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `xmlns="`+marc.Namespace+`"`)
+
+	got, err := marc.UnmarshalXML(data)
+	require.NoError(t, err)
+	assert.Equal(t, rec.Leader, got.Leader)
+	assert.Equal(t, rec.Fields, got.Fields)
+}
+
+func TestXMLWriter_WritesCollectionReadableByXMLReader(t *testing.T) {
+	var buf bytes.Buffer
+	w := marc.NewXMLWriter(&buf)
+	require.NoError(t, w.Write(sampleRecord()))
+	require.NoError(t, w.Write(sampleRecord()))
+	require.NoError(t, w.Close())
+	assert.True(t, strings.HasSuffix(buf.String(), "</collection>\n"))
+
+	r := marc.NewXMLReader(&buf)
+	count := 0
+	for {
+		_, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		count++
+	}
+	assert.Equal(t, 2, count)
+}
+
+func TestXMLReader_ReportsInvalidTag(t *testing.T) {
+	input := `<collection xmlns="` + marc.Namespace + `">` +
+		`<record><leader>00000nam a2200000 i 4500</leader><datafield tag="24" ind1=" " ind2=" "><subfield code="a">x</subfield></datafield></record>` +
+		`<record><leader>00000nam a2200000 i 4500</leader><controlfield tag="001">7</controlfield></record>` +
+		`</collection>`
+
+	r := marc.NewXMLReader(strings.NewReader(input))
+	_, err := r.Read()
+	assert.ErrorIs(t, err, marc.ErrMalformed)
+	rec, err := r.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "7", rec.Field("001").Value)
+}
+
+func TestUnmarshal_RejectsSignedDirectoryOffsets(t *testing.T) {
+	good, err := marc.Marshal(sampleRecord())
+	require.NoError(t, err)
+
+	for _, start := range []string{"-0099", "+0001", " 0001"} {
+		data := bytes.Clone(good)
+		// The start of the first field, in the first directory entry.
+		copy(data[24+7:24+12], start)
+
+		_, err := marc.Unmarshal(data)
+		assert.ErrorIs(t, err, marc.ErrMalformed, start)
+	}
+	data := bytes.Clone(good)
+	copy(data[24+3:24+7], "-001")
+	_, err = marc.Unmarshal(data)
+	assert.ErrorIs(t, err, marc.ErrMalformed)
+}
@@ -0,0 +1,52 @@
+package mergepatch_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alpardfm/library-management-api/pkg/mergepatch"
+)
+
+func TestApply_RFC7396Examples(t *testing.T) {
+	// Appendix A of RFC 7396, for patches that are objects.
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		got, err := mergepatch.Apply([]byte(tt.target), []byte(tt.patch))
+		require.NoError(t, err, tt.patch)
+		assert.JSONEq(t, tt.want, string(got), "%s + %s", tt.target, tt.patch)
+	}
+}
+
+func TestApply_RejectsPatchesThatAreNotObjects(t *testing.T) {
+	for _, patch := range []string{`["c"]`, `null`, `"text"`, `3`} {
+		_, err := mergepatch.Apply([]byte(`{"a":"b"}`), []byte(patch))
+		assert.ErrorIs(t, err, mergepatch.ErrNotObject, patch)
+		_, err = mergepatch.Fields([]byte(patch))
+		assert.ErrorIs(t, err, mergepatch.ErrNotObject, patch)
+	}
+	_, err := mergepatch.Apply([]byte(`{}`), []byte(`{"a":`))
+	assert.Error(t, err)
+}
+
+func TestFields(t *testing.T) {
+	fields, err := mergepatch.Fields([]byte(`{"title":"Dune","publisher":null}`))
+	require.NoError(t, err)
+	assert.Len(t, fields, 2)
+	assert.Equal(t, "null", string(fields["publisher"]))
+}
@@ -0,0 +1,45 @@
+package names_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alpardfm/library-management-api/pkg/names"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "direct with initials", input: "J.K. Rowling", want: "j k rowling"},
+		{name: "inverted with spaced initials", input: "Rowling, J. K.", want: "j k rowling"},
+		{name: "trailing comma", input: "Martin, Robert C.,", want: "robert c martin"},
+		{name: "suffix stays last", input: "King, Martin Luther, Jr.", want: "martin luther king jr"},
+		{name: "accents are letters", input: "Márquez, Gabriel García", want: "gabriel garcía márquez"},
+		{name: "punctuation only", input: " ., ", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, names.Key(tt.input))
+		})
+	}
+}
+
+func TestDirectAndInverted(t *testing.T) {
+	assert.Equal(t, "Martin Luther King, Jr.", names.Direct("King, Martin Luther, Jr."))
+	assert.Equal(t, "Plato", names.Direct(" Plato "))
+
+	assert.Equal(t, "Rowling, J.K.", names.Inverted("J.K. Rowling"))
+	assert.Equal(t, "Rowling, J. K.", names.Inverted("Rowling,  J. K."))
+	assert.Equal(t, "Plato", names.Inverted("Plato"))
+}
+
+func TestFold(t *testing.T) {
+	assert.Equal(t, "sci fi", names.Fold("Sci-Fi"))
+	assert.Equal(t, "science fiction", names.Fold("  SCIENCE   fiction. "))
+	assert.Equal(t, "fiction science", names.Fold("Fiction, Science"), "word order is kept")
+}
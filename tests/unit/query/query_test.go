@@ -30,6 +30,30 @@ func TestParseListParams_DefaultsAndClamp(t *testing.T) {
 	assert.Equal(t, 100, params.Limit)
 	assert.Equal(t, "golang", params.Search)
 	assert.Equal(t, "created_at_desc", params.Sort)
+	assert.Nil(t, params.Cursor)
+	assert.Equal(t, query.CountExact, params.Count)
+}
+
+func TestParseListParams_CursorSetsSort(t *testing.T) {
+	cursor := query.Cursor{Sort: "title_asc", Key: []string{"Dune", "12"}}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/books?count=none&cursor="+cursor.Encode(), nil)
+
+	params, err := query.ParseListParams(c, query.ListOptions{
+		DefaultPage:  1,
+		DefaultLimit: 10,
+		DefaultSort:  "created_at_desc",
+		AllowedSorts: map[string]string{
+			"created_at_desc": "created_at DESC",
+			"title_asc":       "title ASC",
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "title_asc", params.Sort)
+	assert.Equal(t, &cursor, params.Cursor)
+	assert.Equal(t, query.CountNone, params.Count)
 }
 
 func TestParseListParams_InvalidQueries(t *testing.T) {
@@ -39,6 +63,9 @@ func TestParseListParams_InvalidQueries(t *testing.T) {
 		"/books?limit=zero",
 		"/books?limit=0",
 		"/books?sort=weird",
+		"/books?cursor=not-a-cursor",
+		"/books?cursor=" + query.Cursor{Sort: "title_asc"}.Encode(),
+		"/books?count=maybe",
 	}
 
 	for _, url := range tests {
@@ -66,3 +93,35 @@ func TestTotalPages(t *testing.T) {
 	assert.Equal(t, int64(1), query.TotalPages(1, 10))
 	assert.Equal(t, int64(3), query.TotalPages(21, 10))
 }
+
+func TestCursor_RoundTrip(t *testing.T) {
+	cursor := query.Cursor{Sort: "due_date_asc", Key: []string{"2026-03-01T10:00:00Z", "7"}, Before: true}
+
+	decoded, err := query.DecodeCursor(cursor.Encode())
+
+	assert.NoError(t, err)
+	assert.Equal(t, &cursor, decoded)
+	assert.NotContains(t, cursor.Encode(), "=", "tokens are URL-safe without padding")
+}
+
+func TestPageMeta(t *testing.T) {
+	meta := query.PageMeta(
+		query.PageRequest{Page: 2, Limit: 10, Sort: "title_asc"},
+		query.PageInfo{Total: 21, Count: query.CountEstimate, NextCursor: "next"},
+	)
+	assert.Equal(t, 2, meta["page"])
+	assert.Equal(t, int64(21), meta["total"])
+	assert.Equal(t, int64(3), meta["total_pages"])
+	assert.Equal(t, true, meta["total_estimated"])
+	assert.Equal(t, "next", meta["next_cursor"])
+	assert.Nil(t, meta["prev_cursor"])
+
+	meta = query.PageMeta(
+		query.PageRequest{Limit: 10, Cursor: &query.Cursor{Offset: 10}},
+		query.PageInfo{Count: query.CountNone},
+	)
+	assert.NotContains(t, meta, "page")
+	assert.NotContains(t, meta, "sort")
+	assert.NotContains(t, meta, "total")
+	assert.NotContains(t, meta, "total_pages")
+}
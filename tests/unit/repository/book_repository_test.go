@@ -2,9 +2,11 @@
 package repository_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/pkg/query"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -13,6 +15,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/callnumber"
 )
 
 func TestBookRepository_Create(t *testing.T) {
@@ -44,16 +47,30 @@ func TestBookRepository_Create(t *testing.T) {
 			book.Publisher,
 			book.PublicationYear,
 			book.Genre,
+			book.Language,
+			models.ItemTypeBook, // defaulted on create
+			nil,                 // work_id
 			book.Description,
 			book.TotalCopies,
 			book.AvailableCopies,
 			sqlmock.AnyArg(), // created_at
 			sqlmock.AnyArg(), // updated_at
+			"",               // classification
+			"",               // call_number
+			"",               // shelf_location
+			callnumber.NoKey, // shelf_key, set before save
+			book.Price,       // price
+			1,                // version
+			models.BookStatusActive,
+			"",  // status_reason
+			nil, // deleted_at
+			"",  // cover_version
+			"",  // cover_type
 		).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
 
-	err = repo.Create(book)
+	err = repo.Create(context.Background(), book)
 
 	assert.NoError(t, err)
 	assert.Equal(t, uint(1), book.ID)
@@ -73,12 +90,12 @@ func TestBookRepository_UpdateAvailableCopies(t *testing.T) {
 	repo := repository.NewBookRepository(gormDB)
 
 	mock.ExpectBegin()
-	mock.ExpectExec(`UPDATE "books" SET "available_copies"=available_copies \+ \$1,"updated_at"=\$2 WHERE id = \$3`).
+	mock.ExpectExec(`UPDATE "books" SET "available_copies"=available_copies \+ \$1,"version"=version \+ 1,"updated_at"=\$2 WHERE id = \$3`).
 		WithArgs(-1, sqlmock.AnyArg(), 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
-	err = repo.UpdateAvailableCopies(1, -1)
+	err = repo.UpdateAvailableCopies(context.Background(), 1, -1)
 
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
@@ -96,25 +113,82 @@ func TestBookRepository_ListWithSearch(t *testing.T) {
 
 	repo := repository.NewBookRepository(gormDB)
 
+	// Search also matches the names and variants of credited authors by key.
+	// Removed books are left out.
+	searchSQL := `WHERE \(title ILIKE \$1 OR author ILIKE \$2 OR isbn ILIKE \$3 OR id IN \(SELECT book_id FROM book_contributors WHERE author_id IN \(` +
+		`SELECT id FROM authors WHERE name_key LIKE \$4 UNION SELECT author_id FROM author_variants WHERE name_key LIKE \$5\)\)\) AND "books"."deleted_at" IS NULL`
+
 	// Mock count with search
 	countRows := sqlmock.NewRows([]string{"count"}).AddRow(1)
-	mock.ExpectQuery(`SELECT count\(\*\) FROM "books" WHERE title ILIKE \$1 OR author ILIKE \$2 OR isbn ILIKE \$3`).
-		WithArgs("%test%", "%test%", "%test%").
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "books" `+searchSQL).
+		WithArgs("%test%", "%test%", "%test%", "%test%", "%test%").
 		WillReturnRows(countRows)
 
 	// Mock SELECT with search and pagination
 	rows := sqlmock.NewRows([]string{"id", "isbn", "title", "author"}).
 		AddRow(1, "9781234567897", "Test Book", "Test Author")
 
-	mock.ExpectQuery(`SELECT \* FROM "books" WHERE title ILIKE \$1 OR author ILIKE \$2 OR isbn ILIKE \$3 ORDER BY created_at DESC`).
-		WithArgs("%test%", "%test%", "%test%", 10).
+	mock.ExpectQuery(`SELECT \* FROM "books" `+searchSQL+` ORDER BY created_at DESC, id DESC LIMIT \$6`).
+		WithArgs("%test%", "%test%", "%test%", "%test%", "%test%", 11).
 		WillReturnRows(rows)
 
-	books, total, err := repo.List(1, 10, "test", "created_at_desc")
+	// Contributors and subjects are preloaded for the page.
+	mock.ExpectQuery(`SELECT \* FROM "book_contributors" WHERE "book_contributors"."book_id" = \$1 ORDER BY position ASC, role ASC`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "author_id", "role", "position"}))
+	mock.ExpectQuery(`SELECT \* FROM "book_subjects" WHERE "book_subjects"."book_id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "subject_id"}))
+
+	books, info, err := repo.List(context.Background(), repository.BookFilter{Search: "test"},
+		query.PageRequest{Page: 1, Limit: 10, Sort: "created_at_desc", Count: query.CountExact})
 
 	assert.NoError(t, err)
-	assert.Equal(t, int64(1), total)
+	assert.Equal(t, int64(1), info.Total)
+	assert.Empty(t, info.NextCursor)
+	assert.Empty(t, info.PrevCursor)
 	assert.Len(t, books, 1)
 	assert.Equal(t, "Test Book", books[0].Title)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestBookRepository_ListFullTextRanksAndHighlights(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	repo := repository.NewBookRepository(gormDB)
+	textQuery := "dune & herb:*"
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "books" WHERE search_vector @@ to_tsquery\('english', \$1\)`).
+		WithArgs(textQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	mock.ExpectQuery(`SELECT books\.\*, ts_rank_cd\(search_vector, to_tsquery\('english', \$1\)\) AS search_rank, `+
+		`ts_headline\('english', title, to_tsquery\('english', \$2\), .*\) AS title_highlight, `+
+		`ts_headline\('english', coalesce\(description, ''\), to_tsquery\('english', \$3\), .*\) AS snippet `+
+		`FROM "books" WHERE search_vector @@ to_tsquery\('english', \$4\) AND "books"."deleted_at" IS NULL ORDER BY search_rank DESC, id DESC LIMIT \$5`).
+		WithArgs(textQuery, textQuery, textQuery, textQuery, 11).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "search_rank", "title_highlight", "snippet"}).
+			AddRow(1, "Dune", 0.8, "<mark>Dune</mark>", "by Frank <mark>Herbert</mark>"))
+	mock.ExpectQuery(`SELECT \* FROM "book_contributors"`).
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "author_id", "role", "position"}))
+	mock.ExpectQuery(`SELECT \* FROM "book_subjects"`).
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "subject_id"}))
+
+	books, info, err := repo.List(context.Background(), repository.BookFilter{TextQuery: textQuery},
+		query.PageRequest{Page: 1, Limit: 10, Sort: "relevance", Count: query.CountExact})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), info.Total)
+	require.Len(t, books, 1)
+	assert.Equal(t, 0.8, books[0].SearchRank)
+	assert.Equal(t, "<mark>Dune</mark>", books[0].TitleHighlight)
+	assert.Equal(t, "by Frank <mark>Herbert</mark>", books[0].Snippet)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
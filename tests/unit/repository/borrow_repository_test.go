@@ -2,7 +2,9 @@
 package repository_test
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/alpardfm/library-management-api/internal/models"
 	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/pkg/query"
 )
 
 func TestBorrowRepository_FindActiveByUserAndBook(t *testing.T) {
@@ -33,7 +36,7 @@ func TestBorrowRepository_FindActiveByUserAndBook(t *testing.T) {
 		WithArgs(1, 1, 1).
 		WillReturnRows(rows)
 
-	record, err := repo.FindActiveByUserAndBook(1, 1)
+	record, err := repo.FindActiveByUserAndBook(context.Background(), 1, 1)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, record)
@@ -59,7 +62,7 @@ func TestBorrowRepository_CountActiveByUser(t *testing.T) {
 		WithArgs(1).
 		WillReturnRows(countRows)
 
-	count, err := repo.CountActiveByUser(1)
+	count, err := repo.CountActiveByUser(context.Background(), 1)
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(3), count)
@@ -88,7 +91,8 @@ func TestBorrowRepository_ListOverdue(t *testing.T) {
 		AddRow(1, 1, 1, "overdue").
 		AddRow(2, 2, 2, "borrowed")
 
-	mock.ExpectQuery(`SELECT \* FROM "borrow_records" WHERE status = \$1 OR \(return_date IS NULL AND due_date < \$2\) ORDER BY due_date ASC`).
+	mock.ExpectQuery(`SELECT \* FROM "borrow_records" WHERE status = \$1 OR \(return_date IS NULL AND due_date < \$2\) ORDER BY due_date ASC, id ASC LIMIT \$3`).
+		WithArgs(models.StatusOverdue, sqlmock.AnyArg(), 11).
 		WillReturnRows(rows)
 
 	// Mock book preload
@@ -105,10 +109,146 @@ func TestBorrowRepository_ListOverdue(t *testing.T) {
 	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" IN \(\$1,\$2\)`).
 		WillReturnRows(userRows)
 
-	records, total, err := repo.ListOverdue(1, 10, "due_date_asc")
+	records, info, err := repo.ListOverdue(context.Background(),
+		query.PageRequest{Page: 1, Limit: 10, Sort: "due_date_asc", Count: query.CountExact})
 
 	assert.NoError(t, err)
-	assert.Equal(t, int64(2), total)
+	assert.Equal(t, int64(2), info.Total)
 	assert.Len(t, records, 2)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestBorrowRepository_ListByUserContinuesAfterCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	repo := repository.NewBorrowRepository(gormDB)
+	last := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	cursor := &query.Cursor{Sort: "created_at_desc", Key: []string{last.Format(time.RFC3339Nano), "40"}}
+
+	// No count is run, and the page starts after the cursor row; one row
+	// beyond the limit tells there is a next page.
+	rows := sqlmock.NewRows([]string{"id", "user_id", "book_id", "created_at"}).
+		AddRow(39, 7, 1, last).
+		AddRow(38, 7, 2, last.Add(-time.Hour)).
+		AddRow(37, 7, 3, last.Add(-2*time.Hour))
+	mock.ExpectQuery(`SELECT \* FROM "borrow_records" WHERE user_id = \$1 AND \(created_at, id\) < \(\$2, \$3\) ORDER BY created_at DESC, id DESC LIMIT \$4`).
+		WithArgs(7, last, 40, 3).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT \* FROM "books" WHERE "books"."id" IN \(\$1,\$2,\$3\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(1, "Book 1").AddRow(2, "Book 2").AddRow(3, "Book 3"))
+
+	records, info, err := repo.ListByUser(context.Background(), 7,
+		query.PageRequest{Limit: 2, Sort: "created_at_desc", Cursor: cursor, Count: query.CountNone})
+
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, uint(39), records[0].ID)
+	assert.Equal(t, uint(38), records[1].ID)
+
+	next, err := query.DecodeCursor(info.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, []string{last.Add(-time.Hour).Format(time.RFC3339Nano), "38"}, next.Key)
+	assert.False(t, next.Before)
+
+	prev, err := query.DecodeCursor(info.PrevCursor)
+	require.NoError(t, err)
+	assert.Equal(t, []string{last.Format(time.RFC3339Nano), "39"}, prev.Key)
+	assert.True(t, prev.Before)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBorrowRepository_ListActiveGoesBackBeforeCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	repo := repository.NewBorrowRepository(gormDB)
+	due := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	cursor := &query.Cursor{Sort: "due_date_asc", Key: []string{due.Format(time.RFC3339Nano), "5"}, Before: true}
+
+	// The planner estimate stands in for the count.
+	mock.ExpectQuery(`EXPLAIN \(FORMAT JSON\) SELECT \* FROM "borrow_records" WHERE status = \$1`).
+		WithArgs(models.StatusBorrowed).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow(`[{"Plan": {"Node Type": "Seq Scan", "Plan Rows": 1234}}]`))
+
+	// Going back reads in reverse order and returns the page in list order.
+	rows := sqlmock.NewRows([]string{"id", "user_id", "book_id", "due_date"}).
+		AddRow(4, 1, 1, due.Add(-24*time.Hour)).
+		AddRow(3, 1, 1, due.Add(-48*time.Hour))
+	mock.ExpectQuery(`SELECT \* FROM "borrow_records" WHERE status = \$1 AND \(due_date, id\) < \(\$2, \$3\) ORDER BY due_date DESC, id DESC LIMIT \$4`).
+		WithArgs(models.StatusBorrowed, due, 5, 3).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT \* FROM "books"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(1, "Book 1"))
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "user1"))
+
+	records, info, err := repo.ListActive(context.Background(),
+		query.PageRequest{Limit: 2, Sort: "due_date_asc", Cursor: cursor, Count: query.CountEstimate})
+
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, uint(3), records[0].ID)
+	assert.Equal(t, uint(4), records[1].ID)
+	assert.Equal(t, int64(1234), info.Total)
+	assert.NotEmpty(t, info.NextCursor)
+	assert.Empty(t, info.PrevCursor, "nothing comes before the first row")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBorrowRepository_ListRejectsCursorOfAnotherSort(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	repo := repository.NewBorrowRepository(gormDB)
+	cursor := &query.Cursor{Sort: "due_date_asc", Key: []string{"Dune", "5"}}
+
+	_, _, err = repo.ListByUser(context.Background(), 1,
+		query.PageRequest{Limit: 10, Sort: "due_date_asc", Cursor: cursor, Count: query.CountNone})
+
+	assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+}
+
+func TestBorrowRepository_MarkOverdue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	repo := repository.NewBorrowRepository(gormDB)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "borrow_records" SET "status"=\$1,"updated_at"=\$2 WHERE return_date IS NULL AND due_date < \$3 AND status = \$4`).
+		WithArgs(models.StatusOverdue, now, now, models.StatusBorrowed).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	updated, err := repo.MarkOverdue(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), updated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
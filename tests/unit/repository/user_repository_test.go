@@ -2,6 +2,7 @@
 package repository_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -46,11 +47,13 @@ func TestUserRepository_Create(t *testing.T) {
 			user.IsActive,
 			sqlmock.AnyArg(), // created_at
 			sqlmock.AnyArg(), // updated_at
+			nil,              // deleted_at
+			nil,              // purged_at
 		).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
 
-	err = repo.Create(user)
+	err = repo.Create(context.Background(), user)
 
 	assert.NoError(t, err)
 	assert.Equal(t, uint(1), user.ID)
@@ -73,11 +76,11 @@ func TestUserRepository_FindByID(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "role", "is_active", "created_at", "updated_at"}).
 		AddRow(1, "testuser", "test@example.com", "hashed_password", "member", true, time.Now(), time.Now())
 
-	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1 ORDER BY "users"\."id" LIMIT \$2`).
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1 AND "users"."deleted_at" IS NULL ORDER BY "users"\."id" LIMIT \$2`).
 		WithArgs(1, 1).
 		WillReturnRows(rows)
 
-	user, err := repo.FindByID(1)
+	user, err := repo.FindByID(context.Background(), 1)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
@@ -99,11 +102,11 @@ func TestUserRepository_FindByID_NotFound(t *testing.T) {
 	repo := repository.NewUserRepository(gormDB)
 
 	// Mock empty result
-	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1 ORDER BY "users"\."id" LIMIT \$2`).
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1 AND "users"."deleted_at" IS NULL ORDER BY "users"\."id" LIMIT \$2`).
 		WithArgs(999, 1).
 		WillReturnError(gorm.ErrRecordNotFound)
 
-	user, err := repo.FindByID(999)
+	user, err := repo.FindByID(context.Background(), 999)
 
 	assert.Error(t, err)
 	assert.Nil(t, user)
@@ -126,11 +129,11 @@ func TestUserRepository_FindByUsername(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"id", "username", "email"}).
 		AddRow(1, "john_doe", "john@example.com")
 
-	mock.ExpectQuery(`SELECT \* FROM "users" WHERE username = \$1 ORDER BY "users"\."id" LIMIT \$2`).
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE username = \$1 AND "users"."deleted_at" IS NULL ORDER BY "users"\."id" LIMIT \$2`).
 		WithArgs("john_doe", 1).
 		WillReturnRows(rows)
 
-	user, err := repo.FindByUsername("john_doe")
+	user, err := repo.FindByUsername(context.Background(), "john_doe")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
@@ -160,11 +163,11 @@ func TestUserRepository_List(t *testing.T) {
 		AddRow(1, "user1", "user1@example.com").
 		AddRow(2, "user2", "user2@example.com")
 
-	mock.ExpectQuery(`SELECT \* FROM "users" LIMIT \$1`).
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."deleted_at" IS NULL LIMIT \$1`).
 		WithArgs(10).
 		WillReturnRows(rows)
 
-	users, total, err := repo.List(1, 10)
+	users, total, err := repo.List(context.Background(), 1, 10)
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(2), total)
@@ -1,7 +1,9 @@
 package response_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"testing"
 
@@ -23,6 +25,8 @@ func TestMapError(t *testing.T) {
 		{name: "not found", err: apperror.NotFound("book"), expectedCode: http.StatusNotFound, expectedType: apperror.CodeNotFound},
 		{name: "conflict", err: apperror.Conflict("already exists"), expectedCode: http.StatusConflict, expectedType: apperror.CodeConflict},
 		{name: "unknown", err: errors.New("boom"), expectedCode: http.StatusInternalServerError, expectedType: apperror.CodeInternal},
+		{name: "deadline", err: apperror.Internal("failed to list books", context.DeadlineExceeded), expectedCode: http.StatusGatewayTimeout, expectedType: apperror.CodeTimeout},
+		{name: "canceled", err: fmt.Errorf("query: %w", context.Canceled), expectedCode: response.StatusClientClosedRequest, expectedType: apperror.CodeCanceled},
 	}
 
 	for _, tt := range tests {
@@ -33,3 +37,9 @@ func TestMapError(t *testing.T) {
 		})
 	}
 }
+
+func TestMapError_KeepsFieldErrors(t *testing.T) {
+	status, body := response.MapError(apperror.Invalid("invalid book", map[string]string{"title": "is required"}))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, map[string]string{"title": "is required"}, body.Fields)
+}
@@ -0,0 +1,76 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type MockAccountRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountRepository) WithTx(tx *gorm.DB) repository.AccountRepository {
+	args := m.Called(tx)
+	return args.Get(0).(repository.AccountRepository)
+}
+
+func (m *MockAccountRepository) Create(ctx context.Context, entries []models.AccountEntry) error {
+	args := m.Called(ctx, entries)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) ListByUser(ctx context.Context, userID uint, req query.PageRequest) ([]models.AccountEntry, query.PageInfo, error) {
+	args := m.Called(ctx, userID, req)
+	return args.Get(0).([]models.AccountEntry), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockAccountRepository) Balance(ctx context.Context, userID uint) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAccountRepository) ListByBorrow(ctx context.Context, borrowRecordID uint) ([]models.AccountEntry, error) {
+	args := m.Called(ctx, borrowRecordID)
+	return args.Get(0).([]models.AccountEntry), args.Error(1)
+}
+
+func TestAccountService_GetAccount_Balance(t *testing.T) {
+	accountRepo := new(MockAccountRepository)
+	userRepo := new(MockUserRepository)
+	req := query.PageRequest{Page: 1, Limit: 20}
+	entries := []models.AccountEntry{
+		{ID: 2, UserID: 1, Kind: models.ChargeProcessing, Amount: 5000},
+		{ID: 1, UserID: 1, Kind: models.ChargeReplacement, Amount: 90000},
+	}
+	userRepo.On("FindByID", mock.Anything, uint(1)).Return(&models.User{ID: 1}, nil).Once()
+	accountRepo.On("ListByUser", mock.Anything, uint(1), req).Return(entries, query.PageInfo{Total: 2}, nil).Once()
+	accountRepo.On("Balance", mock.Anything, uint(1)).Return(int64(95000), nil).Once()
+
+	got, balance, info, err := service.NewAccountService(accountRepo, userRepo).GetAccount(context.Background(), 1, req)
+	require.NoError(t, err)
+	assert.Equal(t, entries, got)
+	assert.Equal(t, int64(95000), balance)
+	assert.Equal(t, int64(2), info.Total)
+}
+
+func TestAccountService_GetAccount_UnknownUser(t *testing.T) {
+	accountRepo := new(MockAccountRepository)
+	userRepo := new(MockUserRepository)
+	userRepo.On("FindByID", mock.Anything, uint(9)).Return(nil, gorm.ErrRecordNotFound).Once()
+
+	_, _, _, err := service.NewAccountService(accountRepo, userRepo).GetAccount(context.Background(), 9, query.PageRequest{Page: 1, Limit: 20})
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeNotFound, appErr.Code)
+	accountRepo.AssertNotCalled(t, "ListByUser", mock.Anything, mock.Anything, mock.Anything)
+}
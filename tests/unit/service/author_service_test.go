@@ -0,0 +1,219 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type MockAuthorRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuthorRepository) WithTx(tx *gorm.DB) repository.AuthorRepository {
+	args := m.Called(tx)
+	return args.Get(0).(repository.AuthorRepository)
+}
+
+func (m *MockAuthorRepository) Create(ctx context.Context, author *models.Author) error {
+	args := m.Called(ctx, author)
+	return args.Error(0)
+}
+
+func (m *MockAuthorRepository) Update(ctx context.Context, author *models.Author) error {
+	args := m.Called(ctx, author)
+	return args.Error(0)
+}
+
+func (m *MockAuthorRepository) Delete(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAuthorRepository) FindByID(ctx context.Context, id uint) (*models.Author, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Author), args.Error(1)
+}
+
+func (m *MockAuthorRepository) FindByNameKey(ctx context.Context, key string) (*models.Author, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Author), args.Error(1)
+}
+
+func (m *MockAuthorRepository) List(ctx context.Context, search string, req query.PageRequest) ([]models.Author, query.PageInfo, error) {
+	args := m.Called(ctx, search, req)
+	return args.Get(0).([]models.Author), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockAuthorRepository) FindVariantByNameKey(ctx context.Context, key string) (*models.AuthorVariant, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuthorVariant), args.Error(1)
+}
+
+func (m *MockAuthorRepository) CreateVariant(ctx context.Context, variant *models.AuthorVariant) error {
+	args := m.Called(ctx, variant)
+	return args.Error(0)
+}
+
+func (m *MockAuthorRepository) DeleteVariant(ctx context.Context, authorID, variantID uint) (int64, error) {
+	args := m.Called(ctx, authorID, variantID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAuthorRepository) MoveVariants(ctx context.Context, fromID, toID uint) error {
+	args := m.Called(ctx, fromID, toID)
+	return args.Error(0)
+}
+
+func (m *MockAuthorRepository) ListBooks(ctx context.Context, authorID uint, role string, req query.PageRequest) ([]models.Book, query.PageInfo, error) {
+	args := m.Called(ctx, authorID, role, req)
+	return args.Get(0).([]models.Book), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockAuthorRepository) ListContributions(ctx context.Context, authorID uint, bookIDs []uint) ([]models.BookContributor, error) {
+	args := m.Called(ctx, authorID, bookIDs)
+	return args.Get(0).([]models.BookContributor), args.Error(1)
+}
+
+func (m *MockAuthorRepository) ListContributors(ctx context.Context, bookID uint) ([]models.BookContributor, error) {
+	args := m.Called(ctx, bookID)
+	return args.Get(0).([]models.BookContributor), args.Error(1)
+}
+
+func (m *MockAuthorRepository) ReplaceContributors(ctx context.Context, bookID uint, contributors []models.BookContributor) error {
+	args := m.Called(ctx, bookID, contributors)
+	return args.Error(0)
+}
+
+func (m *MockAuthorRepository) MoveContributions(ctx context.Context, fromID, toID uint) (int64, error) {
+	args := m.Called(ctx, fromID, toID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAuthorRepository) BooksWithoutContributors(ctx context.Context, afterID uint, limit int) ([]models.Book, error) {
+	args := m.Called(ctx, afterID, limit)
+	return args.Get(0).([]models.Book), args.Error(1)
+}
+
+func newAuthorService(t *testing.T) (*MockAuthorRepository, sqlmock.Sqlmock, service.AuthorService) {
+	t.Helper()
+
+	mockAuthorRepo := new(MockAuthorRepository)
+	gormDB, mockDB := newMockDB(t)
+	return mockAuthorRepo, mockDB, service.NewAuthorService(gormDB, mockAuthorRepo)
+}
+
+func TestAuthorService_MergeAuthors_MovesCreditsAndKeepsNameAsVariant(t *testing.T) {
+	mockAuthorRepo, sqlMock, authorService := newAuthorService(t)
+
+	target := &models.Author{ID: 1, Name: "J. K. Rowling", NameKey: "j k rowling", Version: 3}
+	duplicate := &models.Author{ID: 2, Name: "Rowling, Joanne", NameKey: "joanne rowling"}
+
+	sqlMock.ExpectBegin()
+	mockAuthorRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockAuthorRepo).Once()
+	mockAuthorRepo.On("FindByID", mock.Anything, uint(1)).Return(target, nil).Twice()
+	mockAuthorRepo.On("FindByID", mock.Anything, uint(2)).Return(duplicate, nil).Once()
+	mockAuthorRepo.On("MoveContributions", mock.Anything, uint(2), uint(1)).Return(int64(3), nil).Once()
+	mockAuthorRepo.On("MoveVariants", mock.Anything, uint(2), uint(1)).Return(nil).Once()
+	mockAuthorRepo.On("Delete", mock.Anything, uint(2)).Return(nil).Once()
+	mockAuthorRepo.On("FindVariantByNameKey", mock.Anything, "joanne rowling").Return(nil, gorm.ErrRecordNotFound).Once()
+	mockAuthorRepo.On("CreateVariant", mock.Anything, mock.MatchedBy(func(v *models.AuthorVariant) bool {
+		return v.AuthorID == 1 && v.Name == "Rowling, Joanne" && v.NameKey == "joanne rowling"
+	})).Return(nil).Once()
+	mockAuthorRepo.On("Update", mock.Anything, target).Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	author, result, err := authorService.MergeAuthors(context.Background(), 1, 3, []uint{2, 2})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), author.ID)
+	assert.Equal(t, []uint{2}, result.MergedAuthorIDs)
+	assert.Equal(t, int64(3), result.ContributionsMoved)
+	assert.Equal(t, 1, result.VariantsAdded)
+	mockAuthorRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestAuthorService_MergeAuthors_RejectsSelfMerge(t *testing.T) {
+	mockAuthorRepo, sqlMock, authorService := newAuthorService(t)
+
+	sqlMock.ExpectBegin()
+	mockAuthorRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockAuthorRepo).Once()
+	mockAuthorRepo.On("FindByID", mock.Anything, uint(1)).Return(&models.Author{ID: 1}, nil).Once()
+	sqlMock.ExpectRollback()
+
+	_, _, err := authorService.MergeAuthors(context.Background(), 1, 0, []uint{1})
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeBadRequest, appErr.Code)
+	mockAuthorRepo.AssertNotCalled(t, "MoveContributions", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestAuthorService_MergeAuthors_RequiresTheTargetVersion(t *testing.T) {
+	mockAuthorRepo, sqlMock, authorService := newAuthorService(t)
+
+	sqlMock.ExpectBegin()
+	mockAuthorRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockAuthorRepo).Once()
+	mockAuthorRepo.On("FindByID", mock.Anything, uint(1)).Return(&models.Author{ID: 1, Version: 4}, nil).Once()
+	sqlMock.ExpectRollback()
+
+	_, _, err := authorService.MergeAuthors(context.Background(), 1, 3, []uint{2})
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodePreconditionFailed, appErr.Code)
+	mockAuthorRepo.AssertNotCalled(t, "MoveContributions", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestAuthorService_AddVariant_RejectsVariantOfAnotherAuthor(t *testing.T) {
+	mockAuthorRepo, sqlMock, authorService := newAuthorService(t)
+
+	sqlMock.ExpectBegin()
+	mockAuthorRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockAuthorRepo).Once()
+	mockAuthorRepo.On("FindByID", mock.Anything, uint(1)).Return(&models.Author{ID: 1, NameKey: "j k rowling"}, nil).Once()
+	mockAuthorRepo.On("FindVariantByNameKey", mock.Anything, "robert galbraith").
+		Return(&models.AuthorVariant{ID: 9, AuthorID: 7}, nil).Once()
+	sqlMock.ExpectRollback()
+
+	_, err := authorService.AddVariant(context.Background(), 1, "Galbraith, Robert")
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	mockAuthorRepo.AssertNotCalled(t, "CreateVariant", mock.Anything, mock.Anything)
+}
+
+func TestAuthorService_DeleteAuthor_RejectsCreditedAuthor(t *testing.T) {
+	mockAuthorRepo, _, authorService := newAuthorService(t)
+
+	mockAuthorRepo.On("FindByID", mock.Anything, uint(1)).Return(&models.Author{ID: 1, BookCount: 2}, nil).Once()
+
+	err := authorService.DeleteAuthor(context.Background(), 1, 0)
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	mockAuthorRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,141 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/textsearch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var exportBatches = [][]models.Book{
+	{
+		{ID: 1, ISBN: "9780132350884", Title: "Clean Code", Author: "Robert C. Martin", PublicationYear: 2008, TotalCopies: 3, AvailableCopies: 1},
+		{ID: 2, ISBN: "9780134757599", Title: "Refactoring, 2nd ed.", Author: "Martin Fowler", TotalCopies: 1, AvailableCopies: 0},
+	},
+	{
+		{ID: 3, ISBN: "9780306406157", Title: "Signals", Author: "Ada Tester", TotalCopies: 2, AvailableCopies: 2},
+	},
+}
+
+func TestExportBooks_CSVWithSelectedColumns(t *testing.T) {
+	mockRepo := new(MockBookRepository)
+	gormDB, _ := newMockDB(t)
+	exportService := service.NewBookExportService(gormDB, mockRepo)
+	mockRepo.On("Scan", mock.Anything, repository.BookFilter{Search: "9780132350884"}, "title_asc", 2, mock.Anything).
+		Return(exportBatches, nil)
+
+	var buf bytes.Buffer
+	rows, err := exportService.ExportBooks(context.Background(), &buf, service.BookExportOptions{
+		Format:    "csv",
+		Columns:   []string{"id", "title", "publication_year", "borrowed_copies", "is_available", "id"},
+		Filter:    dto.BookListFilter{Search: "0-13-235088-2"},
+		Sort:      "title_asc",
+		BatchSize: 2,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, rows)
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"id", "title", "publication_year", "borrowed_copies", "is_available"},
+		{"1", "Clean Code", "2008", "2", "true"},
+		{"2", "Refactoring, 2nd ed.", "", "1", "false"},
+		{"3", "Signals", "", "0", "true"},
+	}, records)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestExportBooks_JSONLKeepsColumnOrder(t *testing.T) {
+	mockRepo := new(MockBookRepository)
+	gormDB, _ := newMockDB(t)
+	exportService := service.NewBookExportService(gormDB, mockRepo)
+	mockRepo.On("Scan", mock.Anything, repository.BookFilter{}, "", service.DefaultExportBatchSize, mock.Anything).
+		Return(exportBatches[:1], nil)
+
+	var buf bytes.Buffer
+	_, err := exportService.ExportBooks(context.Background(), &buf, service.BookExportOptions{
+		Format:  "jsonl",
+		Columns: []string{"title", "id", "available_copies"},
+	})
+
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, `{"title":"Clean Code","id":1,"available_copies":1}`, lines[0])
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &decoded))
+	assert.Equal(t, "Refactoring, 2nd ed.", decoded["title"])
+}
+
+func TestExportBooks_AppliesListFilters(t *testing.T) {
+	mockRepo := new(MockBookRepository)
+	gormDB, _ := newMockDB(t)
+	exportService := service.NewBookExportService(gormDB, mockRepo)
+	available := true
+	mockRepo.On("Scan", mock.Anything, repository.BookFilter{
+		TextQuery: textsearch.Parse("clean code").TSQuery(),
+		Genres:    []string{"Software"},
+		YearFrom:  2000,
+		Languages: []string{"eng"},
+		Available: &available,
+	}, "", service.DefaultExportBatchSize, mock.Anything).Return(exportBatches[:1], nil)
+
+	var buf bytes.Buffer
+	rows, err := exportService.ExportBooks(context.Background(), &buf, service.BookExportOptions{
+		Filter: dto.BookListFilter{
+			Search:        "clean code",
+			SearchMode:    service.SearchModeFullText,
+			Genres:        []string{"Software"},
+			YearFrom:      2000,
+			Languages:     []string{"ENG"},
+			Available:     &available,
+			CollapseWorks: true,
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, rows)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestExportBooks_RejectsUnknownColumnBeforeWriting(t *testing.T) {
+	mockRepo := new(MockBookRepository)
+	gormDB, _ := newMockDB(t)
+	exportService := service.NewBookExportService(gormDB, mockRepo)
+
+	var buf bytes.Buffer
+	_, err := exportService.ExportBooks(context.Background(), &buf, service.BookExportOptions{Columns: []string{"title", "password"}})
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeBadRequest, appErr.Code)
+	assert.Contains(t, appErr.Message, `"password"`)
+	assert.Zero(t, buf.Len())
+	mockRepo.AssertNotCalled(t, "Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBookExportOptions_NormalizeDefaults(t *testing.T) {
+	opts, err := service.BookExportOptions{}.Normalize()
+
+	require.NoError(t, err)
+	assert.Equal(t, service.ExportFormatCSV, opts.Format)
+	assert.Equal(t, service.DefaultBookExportColumns, opts.Columns)
+	assert.Equal(t, service.DefaultExportBatchSize, opts.BatchSize)
+
+	_, err = service.BookExportOptions{Format: "pdf"}.Normalize()
+	assert.Error(t, err)
+}
@@ -0,0 +1,140 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeBookImport_CSVMapsColumnsByHeader(t *testing.T) {
+	input := "title,isbn,author,total_copies,publication_year\n" +
+		"Clean Code,9780132350884,Robert C. Martin,3,2008\n" +
+		"Refactoring,9780134757599,Martin Fowler,,\n"
+
+	rows, err := service.DecodeBookImport(strings.NewReader(input), "csv")
+
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "9780132350884", rows[0].Book.ISBN)
+	assert.Equal(t, "Clean Code", rows[0].Book.Title)
+	assert.Equal(t, 3, rows[0].Book.TotalCopies)
+	assert.Equal(t, 2008, rows[0].Book.PublicationYear)
+	assert.Equal(t, 3, rows[1].Line)
+	assert.Equal(t, 0, rows[1].Book.TotalCopies)
+}
+
+func TestDecodeBookImport_CSVReadsShelvingAndPrice(t *testing.T) {
+	input := "isbn,title,author,classification,call_number,shelf_location,price\n" +
+		"9780132350884,Clean Code,Robert C. Martin,DDC,005.1 MAR,Stacks,45000\n" +
+		"9780134757599,Refactoring,Martin Fowler,,,,lots\n"
+
+	rows, err := service.DecodeBookImport(strings.NewReader(input), "csv")
+
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "ddc", rows[0].Book.Classification)
+	assert.Equal(t, "005.1 MAR", rows[0].Book.CallNumber)
+	assert.Equal(t, "Stacks", rows[0].Book.ShelfLocation)
+	assert.Equal(t, 45000, rows[0].Book.Price)
+	assert.ErrorContains(t, rows[1].Err, "invalid price")
+}
+
+func TestDecodeBookImport_CSVReportsBadValuesPerRow(t *testing.T) {
+	input := "isbn,title,author,total_copies\n" +
+		"9780132350884,Clean Code,Robert C. Martin,three\n" +
+		"9780134757599,Refactoring,Martin Fowler,2\n"
+
+	rows, err := service.DecodeBookImport(strings.NewReader(input), "csv")
+
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.ErrorContains(t, rows[0].Err, "invalid total_copies")
+	assert.NoError(t, rows[1].Err)
+}
+
+func TestDecodeBookImport_CSVRequiresISBNColumn(t *testing.T) {
+	_, err := service.DecodeBookImport(strings.NewReader("title,author\nClean Code,Robert C. Martin\n"), "csv")
+
+	assert.ErrorContains(t, err, "isbn column")
+}
+
+func TestDecodeBookImport_JSONLKeepsLineNumbers(t *testing.T) {
+	input := `{"isbn":"9780132350884","title":"Clean Code","author":"Robert C. Martin","total_copies":1}` + "\n\n" +
+		`{"isbn":` + "\n" +
+		`{"isbn":"9780134757599","title":"Refactoring","author":"Martin Fowler","total_copies":2}` + "\n"
+
+	rows, err := service.DecodeBookImport(strings.NewReader(input), "jsonl")
+
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Equal(t, 3, rows[1].Line)
+	assert.Error(t, rows[1].Err)
+	assert.Equal(t, 4, rows[2].Line)
+	assert.Equal(t, 2, rows[2].Book.TotalCopies)
+}
+
+func TestDecodeBookImport_RejectsUnknownFormat(t *testing.T) {
+	_, err := service.DecodeBookImport(strings.NewReader(""), "yaml")
+
+	assert.ErrorContains(t, err, "unsupported import format")
+}
+
+const marcXMLImport = `<?xml version="1.0" encoding="UTF-8"?>
+<collection xmlns="http://www.loc.gov/MARC21/slim">
+  <record>
+    <leader>01142cam  2200301 i 4500</leader>
+    <controlfield tag="008">080505s2008    nju           001 0 eng d</controlfield>
+    <datafield tag="020" ind1=" " ind2=" "><subfield code="a">0132350882 (pbk. : alk. paper)</subfield></datafield>
+    <datafield tag="100" ind1="1" ind2=" "><subfield code="a">Martin, Robert C.,</subfield><subfield code="e">author.</subfield></datafield>
+    <datafield tag="245" ind1="1" ind2="0"><subfield code="a">Clean code :</subfield><subfield code="b">a handbook of agile software craftsmanship /</subfield><subfield code="c">Robert C. Martin.</subfield></datafield>
+    <datafield tag="264" ind1=" " ind2="1"><subfield code="a">Upper Saddle River, NJ :</subfield><subfield code="b">Prentice Hall,</subfield><subfield code="c">[2009]</subfield></datafield>
+    <datafield tag="520" ind1=" " ind2=" "><subfield code="a">Principles of writing clean code.</subfield></datafield>
+    <datafield tag="650" ind1=" " ind2="0"><subfield code="a">Agile software development.</subfield></datafield>
+    <datafield tag="700" ind1="1" ind2=" "><subfield code="a">Feathers, Michael C.,</subfield><subfield code="e">translator,</subfield><subfield code="e">editor.</subfield></datafield>
+    <datafield tag="700" ind1="1" ind2=" "><subfield code="a">Hall, Prentice,</subfield><subfield code="e">publisher.</subfield></datafield>
+    <datafield tag="852" ind1=" " ind2=" "><subfield code="b">MAIN</subfield></datafield>
+    <datafield tag="852" ind1=" " ind2=" "><subfield code="b">EAST</subfield></datafield>
+  </record>
+  <record>
+    <leader>00000nim a2200000 i 4500</leader>
+    <controlfield tag="008">990101s1999    xx            000 0 und d</controlfield>
+    <datafield tag="245" ind1="0" ind2="0"><subfield code="a">Anonymous works.</subfield></datafield>
+  </record>
+</collection>`
+
+func TestDecodeBookImport_MARCXMLMapsBibliographicFields(t *testing.T) {
+	rows, err := service.DecodeBookImport(strings.NewReader(marcXMLImport), "marcxml")
+
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	book := rows[0].Book
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Equal(t, "9780132350884", book.ISBN)
+	assert.Equal(t, "Clean code: a handbook of agile software craftsmanship", book.Title)
+	assert.Equal(t, "Martin, Robert C.", book.Author)
+	assert.Equal(t, "Prentice Hall", book.Publisher)
+	assert.Equal(t, 2009, book.PublicationYear)
+	assert.Equal(t, "Agile software development", book.Genre)
+	assert.Equal(t, "Principles of writing clean code", book.Description)
+	assert.Equal(t, 2, book.TotalCopies)
+	assert.Equal(t, "eng", book.Language)
+	assert.Equal(t, "book", book.ItemType)
+	assert.Equal(t, []dto.ContributorRequest{
+		{Name: "Martin, Robert C.", Role: "author"},
+		{Name: "Feathers, Michael C.", Role: "translator"},
+		{Name: "Feathers, Michael C.", Role: "editor"},
+	}, book.Contributors)
+	require.NotNil(t, rows[0].Marc)
+
+	assert.Equal(t, "Anonymous works", rows[1].Book.Title)
+	assert.Equal(t, 1999, rows[1].Book.PublicationYear)
+	assert.Equal(t, 1, rows[1].Book.TotalCopies)
+	assert.Equal(t, "", rows[1].Book.Language, "an undetermined language is left empty")
+	assert.Equal(t, "audiobook", rows[1].Book.ItemType)
+}
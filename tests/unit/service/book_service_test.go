@@ -1,19 +1,91 @@
 package service_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/alpardfm/library-management-api/internal/dto"
 	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
 	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
-func TestBookService_CreateBook(t *testing.T) {
+type MockBookRevisionRepository struct {
+	mock.Mock
+}
+
+func (m *MockBookRevisionRepository) WithTx(tx *gorm.DB) repository.BookRevisionRepository {
+	args := m.Called(tx)
+	return args.Get(0).(repository.BookRevisionRepository)
+}
+
+func (m *MockBookRevisionRepository) Create(ctx context.Context, revision *models.BookRevision) error {
+	args := m.Called(ctx, revision)
+	return args.Error(0)
+}
+
+func (m *MockBookRevisionRepository) List(ctx context.Context, bookID uint, req query.PageRequest) ([]models.BookRevision, query.PageInfo, error) {
+	args := m.Called(ctx, bookID, req)
+	return args.Get(0).([]models.BookRevision), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockBookRevisionRepository) FindByVersion(ctx context.Context, bookID, version uint) (*models.BookRevision, error) {
+	args := m.Called(ctx, bookID, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BookRevision), args.Error(1)
+}
+
+func (m *MockBookRevisionRepository) DeleteByBook(ctx context.Context, bookID uint) error {
+	args := m.Called(ctx, bookID)
+	return args.Error(0)
+}
+
+// newBookService records every revision without checking it; tests of the
+// history use newBookHistoryService.
+func newBookService(t *testing.T) (*MockBookRepository, *MockAuthorRepository, sqlmock.Sqlmock, service.BookService) {
+	t.Helper()
+
+	mockRepo, mockAuthorRepo, mockRevisionRepo, sqlMock, bookService := newBookHistoryService(t)
+	mockRevisionRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRevisionRepo).Maybe()
+	mockRevisionRepo.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return mockRepo, mockAuthorRepo, sqlMock, bookService
+}
+
+func newBookHistoryService(t *testing.T) (*MockBookRepository, *MockAuthorRepository, *MockBookRevisionRepository, sqlmock.Sqlmock, service.BookService) {
+	t.Helper()
+
 	mockRepo := new(MockBookRepository)
-	bookService := service.NewBookService(mockRepo)
+	mockAuthorRepo := new(MockAuthorRepository)
+	// Genres match no subject unless a test says otherwise.
+	mockSubjectRepo := new(MockSubjectRepository)
+	mockSubjectRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockSubjectRepo).Maybe()
+	mockSubjectRepo.On("FindAliasByNameKey", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Maybe()
+	mockSubjectRepo.On("FindByNameKey", mock.Anything, mock.Anything).Return([]models.Subject{}, nil).Maybe()
+	mockSubjectRepo.On("ReplaceBookSubjects", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRevisionRepo := new(MockBookRevisionRepository)
+	mockWorkRepo := new(MockWorkRepository)
+	mockWorkRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockWorkRepo).Maybe()
+	// No copies are registered at branches.
+	mockCopyRepo := new(MockCopyRepository)
+	mockCopyRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockCopyRepo).Maybe()
+	mockCopyRepo.On("CountByBook", mock.Anything, mock.Anything).Return(int64(0), int64(0), nil).Maybe()
+	mockCopyRepo.On("Holdings", mock.Anything, mock.Anything).Return(map[uint][]models.BranchHolding{}, nil).Maybe()
+	gormDB, mockDB := newMockDB(t)
+	return mockRepo, mockAuthorRepo, mockRevisionRepo, mockDB, service.NewBookService(gormDB, mockRepo, mockAuthorRepo, mockSubjectRepo, mockRevisionRepo, mockWorkRepo, mockCopyRepo)
+}
+
+func TestBookService_CreateBook(t *testing.T) {
+	mockRepo, mockAuthorRepo, sqlMock, bookService := newBookService(t)
 
 	req := dto.CreateBookRequest{
 		ISBN:        "9781234567897",
@@ -22,13 +94,28 @@ func TestBookService_CreateBook(t *testing.T) {
 		TotalCopies: 5,
 	}
 
-	mockRepo.On("FindByISBN", req.ISBN).
+	mockRepo.On("FindByISBN", mock.Anything, req.ISBN).
 		Return((*models.Book)(nil), errors.New("not found")).
 		Once()
 
-	mockRepo.On("Create", mock.AnythingOfType("*models.Book")).
+	sqlMock.ExpectBegin()
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	mockAuthorRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockAuthorRepo).Once()
+	mockAuthorRepo.On("FindByNameKey", mock.Anything, "test author").
+		Return((*models.Author)(nil), gorm.ErrRecordNotFound).
+		Once()
+	mockAuthorRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Author")).
 		Run(func(args mock.Arguments) {
-			book := args.Get(0).(*models.Book)
+			author := args.Get(1).(*models.Author)
+			author.ID = 7
+			assert.Equal(t, "Test Author", author.Name)
+			assert.Equal(t, "Author, Test", author.SortName)
+		}).
+		Return(nil).
+		Once()
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Book")).
+		Run(func(args mock.Arguments) {
+			book := args.Get(1).(*models.Book)
 			book.ID = 1
 			assert.Equal(t, req.ISBN, book.ISBN)
 			assert.Equal(t, req.Title, book.Title)
@@ -38,18 +125,24 @@ func TestBookService_CreateBook(t *testing.T) {
 		}).
 		Return(nil).
 		Once()
+	mockAuthorRepo.On("ReplaceContributors", mock.Anything, uint(1), mock.MatchedBy(func(contributors []models.BookContributor) bool {
+		return len(contributors) == 1 && contributors[0].AuthorID == 7 && contributors[0].Role == models.RoleAuthor
+	})).Return(nil).Once()
+	sqlMock.ExpectCommit()
 
-	book, err := bookService.CreateBook(req)
+	book, err := bookService.CreateBook(context.Background(), dto.Actor{}, req)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, book)
 	assert.Equal(t, uint(1), book.ID)
+	assert.Len(t, book.Contributors, 1)
 	mockRepo.AssertExpectations(t)
+	mockAuthorRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
 
 func TestBookService_CreateBook_DuplicateISBN(t *testing.T) {
-	mockRepo := new(MockBookRepository)
-	bookService := service.NewBookService(mockRepo)
+	mockRepo, _, _, bookService := newBookService(t)
 
 	req := dto.CreateBookRequest{
 		ISBN:        "9781234567897",
@@ -64,20 +157,19 @@ func TestBookService_CreateBook_DuplicateISBN(t *testing.T) {
 		Title: "Existing Book",
 	}
 
-	mockRepo.On("FindByISBN", req.ISBN).Return(existingBook, nil).Once()
+	mockRepo.On("FindByISBN", mock.Anything, req.ISBN).Return(existingBook, nil).Once()
 
-	book, err := bookService.CreateBook(req)
+	book, err := bookService.CreateBook(context.Background(), dto.Actor{}, req)
 
 	assert.Error(t, err)
 	assert.Nil(t, book)
 	assert.Equal(t, "book with this ISBN already exists", err.Error())
 	mockRepo.AssertExpectations(t)
-	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
 }
 
 func TestBookService_GetBookByID(t *testing.T) {
-	mockRepo := new(MockBookRepository)
-	bookService := service.NewBookService(mockRepo)
+	mockRepo, _, _, bookService := newBookService(t)
 
 	expectedBook := &models.Book{
 		ID:     1,
@@ -86,9 +178,9 @@ func TestBookService_GetBookByID(t *testing.T) {
 		Author: "Test Author",
 	}
 
-	mockRepo.On("FindByID", uint(1)).Return(expectedBook, nil).Once()
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(expectedBook, nil).Once()
 
-	book, err := bookService.GetBookByID(1)
+	book, err := bookService.GetBookByID(context.Background(), 1)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedBook, book)
@@ -96,14 +188,13 @@ func TestBookService_GetBookByID(t *testing.T) {
 }
 
 func TestBookService_GetBookByID_NotFound(t *testing.T) {
-	mockRepo := new(MockBookRepository)
-	bookService := service.NewBookService(mockRepo)
+	mockRepo, _, _, bookService := newBookService(t)
 
-	mockRepo.On("FindByID", uint(999)).
+	mockRepo.On("FindByID", mock.Anything, uint(999)).
 		Return((*models.Book)(nil), errors.New("record not found")).
 		Once()
 
-	book, err := bookService.GetBookByID(999)
+	book, err := bookService.GetBookByID(context.Background(), 999)
 
 	assert.Error(t, err)
 	assert.Nil(t, book)
@@ -111,29 +202,41 @@ func TestBookService_GetBookByID_NotFound(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
-func TestBookService_UpdateBook(t *testing.T) {
-	mockRepo := new(MockBookRepository)
-	bookService := service.NewBookService(mockRepo)
+func TestBookService_PatchBook(t *testing.T) {
+	mockRepo, mockAuthorRepo, sqlMock, bookService := newBookService(t)
 
 	existingBook := &models.Book{
 		ID:              1,
 		ISBN:            "9781234567897",
 		Title:           "Old Title",
 		Author:          "Old Author",
+		ItemType:        models.ItemTypeBook,
 		TotalCopies:     5,
 		AvailableCopies: 3,
 	}
 
-	req := dto.UpdateBookRequest{
-		Title:       "New Title",
-		Author:      "New Author",
-		TotalCopies: 10,
-	}
+	patch := []byte(`{"title":"New Title","author":"New Author","total_copies":10}`)
 
-	mockRepo.On("FindByID", uint(1)).Return(existingBook, nil).Once()
-	mockRepo.On("Update", mock.AnythingOfType("*models.Book")).
+	newAuthor := &models.Author{ID: 8, Name: "New Author"}
+	editor := models.BookContributor{BookID: 1, AuthorID: 3, Role: models.RoleEditor, Author: &models.Author{ID: 3, Name: "An Editor"}}
+
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(existingBook, nil).Once()
+	sqlMock.ExpectBegin()
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	mockAuthorRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockAuthorRepo).Once()
+	mockAuthorRepo.On("FindByNameKey", mock.Anything, "new author").Return(newAuthor, nil).Once()
+	mockAuthorRepo.On("ListContributors", mock.Anything, uint(1)).
+		Return([]models.BookContributor{{BookID: 1, AuthorID: 2, Role: models.RoleAuthor}, editor}, nil).
+		Once()
+	// A new author statement replaces the author credits and keeps the editor.
+	mockAuthorRepo.On("ReplaceContributors", mock.Anything, uint(1), mock.MatchedBy(func(contributors []models.BookContributor) bool {
+		return len(contributors) == 2 &&
+			contributors[0].AuthorID == 8 && contributors[0].Role == models.RoleAuthor &&
+			contributors[1].AuthorID == 3 && contributors[1].Role == models.RoleEditor && contributors[1].Position == 1
+	})).Return(nil).Once()
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Book")).
 		Run(func(args mock.Arguments) {
-			book := args.Get(0).(*models.Book)
+			book := args.Get(1).(*models.Book)
 			assert.Equal(t, "New Title", book.Title)
 			assert.Equal(t, "New Author", book.Author)
 			assert.Equal(t, 10, book.TotalCopies)
@@ -142,16 +245,25 @@ func TestBookService_UpdateBook(t *testing.T) {
 		Return(nil).
 		Once()
 
-	book, err := bookService.UpdateBook(1, req)
+	sqlMock.ExpectCommit()
+
+	book, changes, err := bookService.PatchBook(context.Background(), dto.Actor{}, 1, 0, patch)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, book)
+	fields := make([]string, len(changes))
+	for i, change := range changes {
+		fields[i] = change.Field
+	}
+	assert.Equal(t, []string{"title", "author", "total_copies", "contributors"}, fields)
+	assert.Equal(t, dto.FieldChange{Field: "title", From: "Old Title", To: "New Title"}, changes[0])
 	mockRepo.AssertExpectations(t)
+	mockAuthorRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
 
-func TestBookService_UpdateBook_RejectsTotalCopiesBelowBorrowedCopies(t *testing.T) {
-	mockRepo := new(MockBookRepository)
-	bookService := service.NewBookService(mockRepo)
+func TestBookService_PatchBook_RejectsTotalCopiesBelowBorrowedCopies(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
 
 	existingBook := &models.Book{
 		ID:              1,
@@ -162,22 +274,19 @@ func TestBookService_UpdateBook_RejectsTotalCopiesBelowBorrowedCopies(t *testing
 		AvailableCopies: 1,
 	}
 
-	req := dto.UpdateBookRequest{TotalCopies: 3}
-
-	mockRepo.On("FindByID", uint(1)).Return(existingBook, nil).Once()
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(existingBook, nil).Once()
 
-	book, err := bookService.UpdateBook(1, req)
+	book, _, err := bookService.PatchBook(context.Background(), dto.Actor{}, 1, 0, []byte(`{"total_copies":3}`))
 
 	assert.Error(t, err)
 	assert.Nil(t, book)
 	assert.Equal(t, "total copies cannot be less than borrowed copies", err.Error())
 	mockRepo.AssertExpectations(t)
-	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
 }
 
-func TestBookService_UpdateBook_RejectsInconsistentExistingStock(t *testing.T) {
-	mockRepo := new(MockBookRepository)
-	bookService := service.NewBookService(mockRepo)
+func TestBookService_PatchBook_RejectsInconsistentExistingStock(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
 
 	existingBook := &models.Book{
 		ID:              1,
@@ -188,40 +297,188 @@ func TestBookService_UpdateBook_RejectsInconsistentExistingStock(t *testing.T) {
 		AvailableCopies: 3,
 	}
 
-	mockRepo.On("FindByID", uint(1)).Return(existingBook, nil).Once()
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(existingBook, nil).Once()
 
-	book, err := bookService.UpdateBook(1, dto.UpdateBookRequest{Title: "New Title"})
+	book, _, err := bookService.PatchBook(context.Background(), dto.Actor{}, 1, 0, []byte(`{"title":"New Title"}`))
 
 	assert.Error(t, err)
 	assert.Nil(t, book)
 	assert.Equal(t, "book stock is inconsistent", err.Error())
 	mockRepo.AssertExpectations(t)
-	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestBookService_PatchBook_NullClearsField(t *testing.T) {
+	mockRepo, _, sqlMock, bookService := newBookService(t)
+
+	existingBook := &models.Book{
+		ID: 1, ISBN: "9781234567897", Title: "Title", Author: "Author",
+		Publisher: "Old House", PublicationYear: 1999, ItemType: models.ItemTypeBook, TotalCopies: 1, AvailableCopies: 1,
+	}
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(existingBook, nil).Once()
+	sqlMock.ExpectBegin()
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Book")).Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	book, changes, err := bookService.PatchBook(context.Background(), dto.Actor{}, 1, 0, []byte(`{"publisher":null,"publication_year":null}`))
+
+	assert.NoError(t, err)
+	assert.Empty(t, book.Publisher)
+	assert.Zero(t, book.PublicationYear)
+	assert.Equal(t, []dto.FieldChange{
+		{Field: "publisher", From: "Old House", To: ""},
+		{Field: "publication_year", From: 1999, To: 0},
+	}, changes)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBookService_PatchBook_ReportsInvalidFields(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+
+	existingBook := &models.Book{ID: 1, ISBN: "9781234567897", Title: "Title", Author: "Author", TotalCopies: 1, AvailableCopies: 1}
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(existingBook, nil)
+
+	_, _, err := bookService.PatchBook(context.Background(), dto.Actor{}, 1, 0, []byte(`{"title":null,"publication_year":999,"contributors":[{"role":"narrator"}]}`))
+
+	var appErr *apperror.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, apperror.CodeBadRequest, appErr.Code)
+		assert.Equal(t, map[string]string{
+			"title":                "is required",
+			"publication_year":     "must be at least 1000",
+			"contributors[0].name": "is required",
+			"contributors[0].role": "must be one of author, editor, translator, illustrator",
+		}, appErr.Fields)
+	}
+
+	_, _, err = bookService.PatchBook(context.Background(), dto.Actor{}, 1, 0, []byte(`{"available_copies":3}`))
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, map[string]string{"available_copies": "is not a field that can be set"}, appErr.Fields)
+	}
+
+	_, _, err = bookService.PatchBook(context.Background(), dto.Actor{}, 1, 0, []byte(`{"total_copies":"3"}`))
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, map[string]string{"total_copies": "must be an integer"}, appErr.Fields)
+	}
+
+	_, _, err = bookService.PatchBook(context.Background(), dto.Actor{}, 1, 0, []byte(`[{"op":"replace"}]`))
+	assert.EqualError(t, err, "merge patch must be a JSON object")
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestBookService_PatchBook_RejectsStaleVersion(t *testing.T) {
+	mockRepo, _, sqlMock, bookService := newBookService(t)
+
+	existingBook := func() *models.Book {
+		return &models.Book{ID: 1, ISBN: "9781234567897", Title: "Title", Author: "Author", ItemType: models.ItemTypeBook, TotalCopies: 1, AvailableCopies: 1, Version: 4}
+	}
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(existingBook(), nil).Once()
+
+	_, _, err := bookService.PatchBook(context.Background(), dto.Actor{}, 1, 3, []byte(`{"title":"New"}`))
+
+	var appErr *apperror.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, apperror.CodePreconditionFailed, appErr.Code)
+		assert.Equal(t, "book has changed since version 3; fetch it again", appErr.Message)
+	}
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+
+	// Another edit lands between the read and the write.
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(existingBook(), nil).Once()
+	sqlMock.ExpectBegin()
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Book")).Return(repository.ErrStaleVersion).Once()
+	sqlMock.ExpectRollback()
+
+	_, _, err = bookService.PatchBook(context.Background(), dto.Actor{}, 1, 4, []byte(`{"title":"New"}`))
+
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, apperror.CodePreconditionFailed, appErr.Code)
+	}
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBookService_ReplaceBook_ClearsOmittedFields(t *testing.T) {
+	mockRepo, mockAuthorRepo, sqlMock, bookService := newBookService(t)
+
+	existingBook := &models.Book{
+		ID: 1, ISBN: "9781234567897", Title: "Title", Author: "Author", Publisher: "Old House",
+		ItemType: models.ItemTypeEbook, TotalCopies: 2, AvailableCopies: 2,
+		Contributors: []models.BookContributor{
+			{BookID: 1, AuthorID: 2, Role: models.RoleAuthor, Author: &models.Author{ID: 2, Name: "Author"}},
+			{BookID: 1, AuthorID: 3, Role: models.RoleEditor, Position: 1, Author: &models.Author{ID: 3, Name: "An Editor"}},
+		},
+	}
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(existingBook, nil).Once()
+	sqlMock.ExpectBegin()
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	mockAuthorRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockAuthorRepo).Once()
+	mockAuthorRepo.On("FindByNameKey", mock.Anything, "author").Return(&models.Author{ID: 2, Name: "Author"}, nil).Once()
+	// Without contributors the credits are rebuilt from the author statement.
+	mockAuthorRepo.On("ReplaceContributors", mock.Anything, uint(1), mock.MatchedBy(func(contributors []models.BookContributor) bool {
+		return len(contributors) == 1 && contributors[0].AuthorID == 2
+	})).Return(nil).Once()
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Book")).Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	book, changes, err := bookService.ReplaceBook(context.Background(), dto.Actor{}, 1, 0, dto.CreateBookRequest{
+		ISBN: "9781234567897", Title: "Title", Author: "Author", TotalCopies: 2,
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, book.Publisher)
+	assert.Equal(t, models.ItemTypeBook, book.ItemType)
+	fields := make([]string, len(changes))
+	for i, change := range changes {
+		fields[i] = change.Field
+	}
+	assert.Equal(t, []string{"publisher", "item_type", "contributors"}, fields)
+	mockRepo.AssertExpectations(t)
+	mockAuthorRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
 
 func TestBookService_DeleteBook(t *testing.T) {
-	mockRepo := new(MockBookRepository)
-	bookService := service.NewBookService(mockRepo)
+	mockRepo, _, mockRevisionRepo, sqlMock, bookService := newBookHistoryService(t)
 
 	existingBook := &models.Book{
 		ID:              1,
 		Title:           "Test Book",
 		TotalCopies:     5,
 		AvailableCopies: 5,
+		Status:          models.BookStatusActive,
+		Version:         2,
 	}
 
-	mockRepo.On("FindByID", uint(1)).Return(existingBook, nil).Once()
-	mockRepo.On("Delete", uint(1)).Return(nil).Once()
-
-	err := bookService.DeleteBook(1)
+	sqlMock.ExpectBegin()
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	mockRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(existingBook, nil).Once()
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(existingBook, nil).Once()
+	mockRepo.On("Remove", mock.Anything, uint(1), models.BookStatusArchived, "superseded edition").Return(nil).Once()
+	mockRevisionRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRevisionRepo).Once()
+	mockRevisionRepo.On("Create", mock.Anything, mock.MatchedBy(func(revision *models.BookRevision) bool {
+		return revision.BookID == 1 && revision.Version == 3 && revision.Action == models.RevisionDelete &&
+			revision.ActorID == 7 && revision.RequestID == "req-1" &&
+			string(revision.Changes) == `[{"field":"status","from":"active","to":"archived"}]`
+	})).Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	err := bookService.DeleteBook(context.Background(), dto.Actor{UserID: 7, RequestID: "req-1"}, 1, 0, dto.RemoveBookRequest{
+		Status: models.BookStatusArchived,
+		Reason: " superseded edition ",
+	})
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
+	mockRevisionRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
 
 func TestBookService_DeleteBook_WithActiveBorrows(t *testing.T) {
-	mockRepo := new(MockBookRepository)
-	bookService := service.NewBookService(mockRepo)
+	mockRepo, _, sqlMock, bookService := newBookService(t)
 
 	existingBook := &models.Book{
 		ID:              1,
@@ -230,40 +487,177 @@ func TestBookService_DeleteBook_WithActiveBorrows(t *testing.T) {
 		AvailableCopies: 3,
 	}
 
-	mockRepo.On("FindByID", uint(1)).Return(existingBook, nil).Once()
+	sqlMock.ExpectBegin()
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	mockRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(existingBook, nil).Once()
+	sqlMock.ExpectRollback()
 
-	err := bookService.DeleteBook(1)
+	err := bookService.DeleteBook(context.Background(), dto.Actor{}, 1, 0, dto.RemoveBookRequest{})
 
 	assert.Error(t, err)
 	assert.Equal(t, "cannot delete book with active borrows", err.Error())
 	mockRepo.AssertExpectations(t)
-	mockRepo.AssertNotCalled(t, "Delete", mock.Anything)
+	mockRepo.AssertNotCalled(t, "Remove", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBookService_RestoreBook(t *testing.T) {
+	mockRepo, _, sqlMock, bookService := newBookService(t)
+
+	mockRepo.On("FindByID", mock.Anything, uint(4)).Return((*models.Book)(nil), gorm.ErrRecordNotFound).Once()
+	sqlMock.ExpectBegin()
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	mockRepo.On("FindWithRemoved", mock.Anything, uint(4)).Return(&models.Book{ID: 4, Status: models.BookStatusWithdrawn}, nil).Once()
+	mockRepo.On("Restore", mock.Anything, uint(4)).Return(nil).Once()
+	mockRepo.On("FindByID", mock.Anything, uint(4)).Return(&models.Book{ID: 4, Status: models.BookStatusActive}, nil).Once()
+	sqlMock.ExpectCommit()
+
+	book, err := bookService.RestoreBook(context.Background(), dto.Actor{}, 4)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.BookStatusActive, book.Status)
+	mockRepo.AssertExpectations(t)
+
+	mockRepo.On("FindByID", mock.Anything, uint(4)).Return(&models.Book{ID: 4}, nil).Once()
+	_, err = bookService.RestoreBook(context.Background(), dto.Actor{}, 4)
+	assert.EqualError(t, err, "book is not withdrawn or archived")
+}
+
+func TestBookService_PatchBook_UnchangedBookIsNotSaved(t *testing.T) {
+	mockRepo, _, mockRevisionRepo, sqlMock, bookService := newBookHistoryService(t)
+
+	existingBook := &models.Book{
+		ID:              1,
+		ISBN:            "9781234567897",
+		Title:           "Test Book",
+		Author:          "Test Author",
+		ItemType:        models.ItemTypeBook,
+		TotalCopies:     5,
+		AvailableCopies: 5,
+		Version:         3,
+	}
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(existingBook, nil).Once()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+
+	book, changes, err := bookService.PatchBook(context.Background(), dto.Actor{}, 1, 3, []byte(`{"title":"Test Book"}`))
+
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Equal(t, uint(3), book.Version)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	mockRevisionRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestBookService_RevertBook(t *testing.T) {
+	mockRepo, mockAuthorRepo, mockRevisionRepo, sqlMock, bookService := newBookHistoryService(t)
+
+	existingBook := &models.Book{
+		ID:              1,
+		ISBN:            "9781234567897",
+		Title:           "Wrong Title",
+		Author:          "Test Author",
+		ItemType:        models.ItemTypeBook,
+		TotalCopies:     5,
+		AvailableCopies: 5,
+		Contributors:    []models.BookContributor{{BookID: 1, AuthorID: 9, Role: models.RoleAuthor}},
+		Version:         4,
+	}
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(existingBook, nil).Once()
+	mockRevisionRepo.On("FindByVersion", mock.Anything, uint(1), uint(2)).Return(&models.BookRevision{
+		BookID:   1,
+		Version:  2,
+		Snapshot: []byte(`{"isbn":"9781234567897","title":"Test Book","author":"Test Author","item_type":"book","total_copies":7,"contributors":[{"author_id":9,"role":"author"}],"subject_ids":[]}`),
+	}, nil).Once()
+
+	sqlMock.ExpectBegin()
+	mockAuthorRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockAuthorRepo).Once()
+	mockAuthorRepo.On("FindByID", mock.Anything, uint(9)).Return(&models.Author{ID: 9, Name: "Test Author"}, nil).Once()
+	mockAuthorRepo.On("ReplaceContributors", mock.Anything, uint(1), mock.Anything).Return(nil).Once()
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Book")).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Book).Version++
+	}).Once()
+	mockRevisionRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRevisionRepo).Once()
+	mockRevisionRepo.On("Create", mock.Anything, mock.MatchedBy(func(revision *models.BookRevision) bool {
+		return revision.Version == 5 && revision.Action == models.RevisionRevert && revision.SourceVersion == 2 &&
+			revision.ActorID == 0 && string(revision.Changes) == `[{"field":"title","from":"Wrong Title","to":"Test Book"}]`
+	})).Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	book, changes, err := bookService.RevertBook(context.Background(), dto.Actor{}, 1, 4, dto.RevertBookRequest{Version: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Test Book", book.Title)
+	assert.Equal(t, 5, book.TotalCopies, "the stock is not reverted")
+	assert.Equal(t, []dto.FieldChange{{Field: "title", From: "Wrong Title", To: "Test Book"}}, changes)
+	mockRepo.AssertExpectations(t)
+	mockAuthorRepo.AssertExpectations(t)
+	mockRevisionRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBookService_RevertBook_UnknownVersion(t *testing.T) {
+	mockRepo, _, mockRevisionRepo, _, bookService := newBookHistoryService(t)
+
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(&models.Book{ID: 1, Version: 4}, nil).Once()
+	mockRevisionRepo.On("FindByVersion", mock.Anything, uint(1), uint(9)).Return(nil, gorm.ErrRecordNotFound).Once()
+
+	_, _, err := bookService.RevertBook(context.Background(), dto.Actor{}, 1, 4, dto.RevertBookRequest{Version: 9})
+
+	assert.EqualError(t, err, "book version 9 not found")
+}
+
+func TestBookService_BookHistory_UnknownBook(t *testing.T) {
+	mockRepo, _, mockRevisionRepo, _, bookService := newBookHistoryService(t)
+
+	mockRepo.On("FindWithRemoved", mock.Anything, uint(8)).Return(nil, gorm.ErrRecordNotFound).Once()
+
+	_, _, err := bookService.BookHistory(context.Background(), 8, query.PageRequest{Page: 1, Limit: 20})
+
+	assert.EqualError(t, err, "book not found")
+	mockRevisionRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBookService_CreateBookRejectsISBNOfRemovedBook(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+
+	removed := &models.Book{ID: 9, ISBN: "9781234567897", Status: models.BookStatusWithdrawn}
+	removed.DeletedAt = gorm.DeletedAt{Valid: true}
+	mockRepo.On("FindByISBN", mock.Anything, "9781234567897").Return(removed, nil).Once()
+
+	_, err := bookService.CreateBook(context.Background(), dto.Actor{}, dto.CreateBookRequest{
+		ISBN: "9781234567897", Title: "Again", Author: "Someone", TotalCopies: 1,
+	})
+
+	var appErr *apperror.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	}
+	assert.EqualError(t, err, "book with this ISBN was withdrawn; restore book 9 instead")
 }
 
 func TestBookService_ListBooks(t *testing.T) {
-	mockRepo := new(MockBookRepository)
-	bookService := service.NewBookService(mockRepo)
+	mockRepo, _, _, bookService := newBookService(t)
 
 	expectedBooks := []models.Book{
 		{ID: 1, Title: "Book 1"},
 		{ID: 2, Title: "Book 2"},
 	}
 
-	mockRepo.On("List", 1, 10, "test", "created_at_desc").
-		Return(expectedBooks, int64(2), nil).
+	mockRepo.On("List", mock.Anything, repository.BookFilter{Search: "test"}, query.PageRequest{Page: 1, Limit: 10, Sort: "created_at_desc"}).
+		Return(expectedBooks, query.PageInfo{Total: 2}, nil).
 		Once()
 
-	books, total, err := bookService.ListBooks(1, 10, "test", "created_at_desc")
+	books, info, err := bookService.ListBooks(context.Background(), dto.BookListFilter{Search: "test"}, query.PageRequest{Page: 1, Limit: 10, Sort: "created_at_desc"})
 
 	assert.NoError(t, err)
-	assert.Equal(t, int64(2), total)
+	assert.Equal(t, int64(2), info.Total)
 	assert.Len(t, books, 2)
 	mockRepo.AssertExpectations(t)
 }
 
 func TestBookService_CheckAvailability(t *testing.T) {
-	mockRepo := new(MockBookRepository)
-	bookService := service.NewBookService(mockRepo)
+	mockRepo, _, _, bookService := newBookService(t)
 
 	book := &models.Book{
 		ID:              1,
@@ -272,9 +666,9 @@ func TestBookService_CheckAvailability(t *testing.T) {
 		AvailableCopies: 3,
 	}
 
-	mockRepo.On("FindByID", uint(1)).Return(book, nil).Once()
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(book, nil).Once()
 
-	available, err := bookService.CheckAvailability(1)
+	available, err := bookService.CheckAvailability(context.Background(), 1)
 
 	assert.NoError(t, err)
 	assert.True(t, available)
@@ -282,8 +676,7 @@ func TestBookService_CheckAvailability(t *testing.T) {
 }
 
 func TestBookService_CheckAvailability_NotAvailable(t *testing.T) {
-	mockRepo := new(MockBookRepository)
-	bookService := service.NewBookService(mockRepo)
+	mockRepo, _, _, bookService := newBookService(t)
 
 	book := &models.Book{
 		ID:              1,
@@ -292,9 +685,9 @@ func TestBookService_CheckAvailability_NotAvailable(t *testing.T) {
 		AvailableCopies: 0,
 	}
 
-	mockRepo.On("FindByID", uint(1)).Return(book, nil).Once()
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(book, nil).Once()
 
-	available, err := bookService.CheckAvailability(1)
+	available, err := bookService.CheckAvailability(context.Background(), 1)
 
 	assert.NoError(t, err)
 	assert.False(t, available)
@@ -302,8 +695,7 @@ func TestBookService_CheckAvailability_NotAvailable(t *testing.T) {
 }
 
 func TestBookService_CheckAvailability_RejectsInconsistentStock(t *testing.T) {
-	mockRepo := new(MockBookRepository)
-	bookService := service.NewBookService(mockRepo)
+	mockRepo, _, _, bookService := newBookService(t)
 
 	book := &models.Book{
 		ID:              1,
@@ -312,12 +704,252 @@ func TestBookService_CheckAvailability_RejectsInconsistentStock(t *testing.T) {
 		AvailableCopies: 3,
 	}
 
-	mockRepo.On("FindByID", uint(1)).Return(book, nil).Once()
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(book, nil).Once()
 
-	available, err := bookService.CheckAvailability(1)
+	available, err := bookService.CheckAvailability(context.Background(), 1)
 
 	assert.Error(t, err)
 	assert.False(t, available)
 	assert.Equal(t, "book stock is inconsistent", err.Error())
 	mockRepo.AssertExpectations(t)
 }
+
+func TestBookService_CreateBook_NormalizesISBN10(t *testing.T) {
+	mockRepo, mockAuthorRepo, sqlMock, bookService := newBookService(t)
+
+	req := dto.CreateBookRequest{
+		ISBN:        "0-306-40615-2",
+		Title:       "Test Book",
+		Author:      "Test Author",
+		TotalCopies: 1,
+	}
+
+	mockRepo.On("FindByISBN", mock.Anything, "9780306406157").
+		Return((*models.Book)(nil), errors.New("not found")).
+		Once()
+	sqlMock.ExpectBegin()
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	mockAuthorRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockAuthorRepo).Once()
+	mockAuthorRepo.On("FindByNameKey", mock.Anything, "test author").Return(&models.Author{ID: 7, Name: "Test Author"}, nil).Once()
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Book")).Return(nil).Once()
+	mockAuthorRepo.On("ReplaceContributors", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	book, err := bookService.CreateBook(context.Background(), dto.Actor{}, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "9780306406157", book.ISBN)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBookService_CreateBook_ISBN10MatchesExistingISBN13(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+
+	mockRepo.On("FindByISBN", mock.Anything, "9780306406157").
+		Return(&models.Book{ID: 1, ISBN: "9780306406157"}, nil).
+		Once()
+
+	_, err := bookService.CreateBook(context.Background(), dto.Actor{}, dto.CreateBookRequest{
+		ISBN: "0306406152", Title: "Test Book", Author: "Test Author", TotalCopies: 1,
+	})
+
+	assert.EqualError(t, err, "book with this ISBN already exists")
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestBookService_CreateBook_RejectsInvalidISBN(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+
+	_, err := bookService.CreateBook(context.Background(), dto.Actor{}, dto.CreateBookRequest{
+		ISBN: "978-0-306-40615-8", Title: "Test Book", Author: "Test Author", TotalCopies: 1,
+	})
+
+	var appErr *apperror.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, apperror.CodeBadRequest, appErr.Code)
+		assert.Contains(t, appErr.Message, "check digit")
+	}
+	mockRepo.AssertNotCalled(t, "FindByISBN", mock.Anything, mock.Anything)
+}
+
+func TestBookService_CreateBook_RequiresACopy(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+	mockRepo.On("FindByISBN", mock.Anything, "9781234567897").Return(nil, gorm.ErrRecordNotFound).Once()
+
+	_, err := bookService.CreateBook(context.Background(), dto.Actor{}, dto.CreateBookRequest{
+		ISBN: "9781234567897", Title: "Test Book", Author: "Test Author",
+	})
+
+	var appErr *apperror.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, apperror.CodeBadRequest, appErr.Code)
+		assert.Equal(t, "must be at least 1", appErr.Fields["total_copies"])
+	}
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestBookService_PatchBook_ChangesISBN(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+
+	existingBook := &models.Book{ID: 1, ISBN: "9781234567897", Title: "Title", Author: "Author", TotalCopies: 1, AvailableCopies: 1}
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(existingBook, nil).Once()
+	mockRepo.On("FindByISBN", mock.Anything, "9780306406157").
+		Return(&models.Book{ID: 2, ISBN: "9780306406157"}, nil).
+		Once()
+
+	_, _, err := bookService.PatchBook(context.Background(), dto.Actor{}, 1, 0, []byte(`{"isbn":"0-306-40615-2"}`))
+
+	assert.EqualError(t, err, "book with this ISBN already exists")
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestBookService_ListBooks_NormalizesISBNSearch(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+
+	mockRepo.On("List", mock.Anything, repository.BookFilter{Search: "9780306406157"}, query.PageRequest{Page: 1, Limit: 10, Sort: ""}).
+		Return([]models.Book{{ID: 1}}, query.PageInfo{Total: 1}, nil).
+		Once()
+
+	_, _, err := bookService.ListBooks(context.Background(), dto.BookListFilter{Search: "0-306-40615-2"}, query.PageRequest{Page: 1, Limit: 10, Sort: ""})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBookService_BookFacets_DefaultsToEveryFacet(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+
+	mockRepo.On("Facets", mock.Anything, repository.BookFilter{Languages: []string{"eng"}}, repository.BookFacetNames, 20).
+		Return(map[string][]repository.FacetCount{
+			"subject": {{Value: "4", Label: "Fiction", Count: 12}},
+		}, nil).
+		Once()
+
+	facets, err := bookService.BookFacets(context.Background(), dto.BookListFilter{Languages: []string{"ENG"}}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.FacetValue{{Value: "4", Label: "Fiction", Count: 12}}, facets["subject"])
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBookService_BookFacets_RejectsUnknownFacet(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+
+	_, err := bookService.BookFacets(context.Background(), dto.BookListFilter{}, []string{"colour"})
+
+	var appErr *apperror.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, apperror.CodeBadRequest, appErr.Code)
+	}
+	mockRepo.AssertNotCalled(t, "Facets", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBookService_SearchBooks_RanksFullTextMatches(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+
+	mockRepo.On("List", mock.Anything, repository.BookFilter{TextQuery: "(desert <-> planet) & herb:*"}, query.PageRequest{Page: 1, Limit: 10, Sort: "relevance"}).
+		Return([]models.Book{{ID: 1, Snippet: "a <mark>desert</mark> <mark>planet</mark>"}}, query.PageInfo{Total: 1}, nil).
+		Once()
+
+	books, info, mode, err := bookService.SearchBooks(context.Background(), dto.BookListFilter{Search: `"desert planet" herb*`}, query.PageRequest{Page: 1, Limit: 10, Sort: "relevance"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, service.SearchModeFullText, mode)
+	assert.Equal(t, int64(1), info.Total)
+	assert.Len(t, books, 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBookService_SearchBooks_FallsBackToSubstringWithoutMatches(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+
+	mockRepo.On("List", mock.Anything, repository.BookFilter{TextQuery: "herber"}, query.PageRequest{Page: 1, Limit: 10, Sort: "relevance"}).
+		Return([]models.Book{}, query.PageInfo{}, nil).
+		Once()
+	mockRepo.On("List", mock.Anything, repository.BookFilter{Search: "herber"}, query.PageRequest{Page: 1, Limit: 10, Sort: "relevance"}).
+		Return([]models.Book{{ID: 1, Author: "Frank Herbert"}}, query.PageInfo{Total: 1}, nil).
+		Once()
+
+	books, _, mode, err := bookService.SearchBooks(context.Background(), dto.BookListFilter{Search: "herber"}, query.PageRequest{Page: 1, Limit: 10, Sort: "relevance"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, service.SearchModeSubstring, mode)
+	assert.Len(t, books, 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBookService_SearchBooks_KeysetCursorContinuesSubstringSearch(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+
+	// Ranked pages carry offsets; a cursor with a key came from a substring
+	// page, so the full-text search is not retried.
+	req := query.PageRequest{Limit: 10, Sort: "relevance", Cursor: &query.Cursor{Sort: "relevance", Key: []string{"Dune", "3"}}}
+	mockRepo.On("List", mock.Anything, repository.BookFilter{Search: "herber"}, req).
+		Return([]models.Book{{ID: 4, Author: "Frank Herbert"}}, query.PageInfo{}, nil).
+		Once()
+
+	_, _, mode, err := bookService.SearchBooks(context.Background(), dto.BookListFilter{Search: "herber"}, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, service.SearchModeSubstring, mode)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBookService_ListBooks_InvalidCursorIsBadRequest(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+
+	req := query.PageRequest{Limit: 10, Sort: "title_asc", Cursor: &query.Cursor{Sort: "title_asc", Key: []string{"x"}}}
+	mockRepo.On("List", mock.Anything, repository.BookFilter{}, req).
+		Return([]models.Book(nil), query.PageInfo{}, fmt.Errorf("%w: cursor has 1 values, want 2", repository.ErrInvalidCursor)).
+		Once()
+
+	_, _, err := bookService.ListBooks(context.Background(), dto.BookListFilter{}, req)
+
+	var appErr *apperror.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, apperror.CodeBadRequest, appErr.Code)
+	}
+}
+
+func TestBookService_SuggestBooks_IgnoresShortTerms(t *testing.T) {
+	mockRepo, _, _, bookService := newBookService(t)
+
+	suggestions, err := bookService.SuggestBooks(context.Background(), " h ", 10)
+
+	assert.NoError(t, err)
+	assert.Empty(t, suggestions)
+	assert.NotNil(t, suggestions, "an empty list, not null")
+	mockRepo.AssertNotCalled(t, "Suggest", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBookService_SpellingSuggestion_SkipsTheSearchItself(t *testing.T) {
+	mockRepo, _, sqlMock, bookService := newBookService(t)
+
+	sqlMock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm'\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mockRepo.On("Similar", mock.Anything, "harry poter", 3).
+		Return([]repository.Suggestion{
+			{Kind: repository.SuggestionTitle, Text: "Harry Poter", Score: 1},
+			{Kind: repository.SuggestionTitle, Text: "Harry Potter", Score: 0.8},
+		}, nil).
+		Once()
+
+	suggestion, err := bookService.SpellingSuggestion(context.Background(), "harry poter")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Harry Potter", suggestion)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBookService_SpellingSuggestion_NeedsTrigram(t *testing.T) {
+	mockRepo, _, sqlMock, bookService := newBookService(t)
+
+	sqlMock.ExpectQuery(`SELECT EXISTS`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	suggestion, err := bookService.SpellingSuggestion(context.Background(), "harry poter")
+
+	assert.NoError(t, err)
+	assert.Empty(t, suggestion)
+	mockRepo.AssertNotCalled(t, "Similar", mock.Anything, mock.Anything, mock.Anything)
+}
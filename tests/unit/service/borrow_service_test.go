@@ -1,6 +1,7 @@
 package service_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -10,6 +11,8 @@ import (
 	"github.com/alpardfm/library-management-api/internal/models"
 	"github.com/alpardfm/library-management-api/internal/repository"
 	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -26,55 +29,154 @@ func (m *MockBookRepository) WithTx(tx *gorm.DB) repository.BookRepository {
 	return args.Get(0).(repository.BookRepository)
 }
 
-func (m *MockBookRepository) Create(book *models.Book) error {
-	args := m.Called(book)
+func (m *MockBookRepository) Create(ctx context.Context, book *models.Book) error {
+	args := m.Called(ctx, book)
 	return args.Error(0)
 }
 
-func (m *MockBookRepository) FindByID(id uint) (*models.Book, error) {
-	args := m.Called(id)
+func (m *MockBookRepository) FindByID(ctx context.Context, id uint) (*models.Book, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Book), args.Error(1)
 }
 
-func (m *MockBookRepository) FindByIDForUpdate(id uint) (*models.Book, error) {
-	args := m.Called(id)
+func (m *MockBookRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.Book, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Book), args.Error(1)
 }
 
-func (m *MockBookRepository) FindByISBN(isbn string) (*models.Book, error) {
-	args := m.Called(isbn)
+func (m *MockBookRepository) FindWithRemoved(ctx context.Context, id uint) (*models.Book, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Book), args.Error(1)
 }
 
-func (m *MockBookRepository) Update(book *models.Book) error {
-	args := m.Called(book)
+func (m *MockBookRepository) FindByISBN(ctx context.Context, isbn string) (*models.Book, error) {
+	args := m.Called(ctx, isbn)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Book), args.Error(1)
+}
+
+func (m *MockBookRepository) Update(ctx context.Context, book *models.Book) error {
+	args := m.Called(ctx, book)
 	return args.Error(0)
 }
 
-func (m *MockBookRepository) Delete(id uint) error {
-	args := m.Called(id)
+func (m *MockBookRepository) Remove(ctx context.Context, id uint, status, reason string) error {
+	args := m.Called(ctx, id, status, reason)
 	return args.Error(0)
 }
 
-func (m *MockBookRepository) List(page, limit int, search, sort string) ([]models.Book, int64, error) {
-	args := m.Called(page, limit, search, sort)
-	return args.Get(0).([]models.Book), args.Get(1).(int64), args.Error(2)
+func (m *MockBookRepository) Restore(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
 }
 
-func (m *MockBookRepository) UpdateAvailableCopies(id uint, change int) error {
-	args := m.Called(id, change)
+func (m *MockBookRepository) Purge(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockBookRepository) ListRemoved(ctx context.Context, filter repository.BookFilter, req query.PageRequest) ([]models.Book, query.PageInfo, error) {
+	args := m.Called(ctx, filter, req)
+	return args.Get(0).([]models.Book), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockBookRepository) RemovedBefore(ctx context.Context, cutoff time.Time) ([]models.Book, error) {
+	args := m.Called(ctx, cutoff)
+	return args.Get(0).([]models.Book), args.Error(1)
+}
+
+func (m *MockBookRepository) List(ctx context.Context, filter repository.BookFilter, req query.PageRequest) ([]models.Book, query.PageInfo, error) {
+	args := m.Called(ctx, filter, req)
+	return args.Get(0).([]models.Book), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockBookRepository) EditionCounts(ctx context.Context, filter repository.BookFilter, workIDs []uint) (map[uint]repository.EditionCount, error) {
+	args := m.Called(ctx, filter, workIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uint]repository.EditionCount), args.Error(1)
+}
+
+func (m *MockBookRepository) Suggest(ctx context.Context, term string, fuzzy bool, limit int) ([]repository.Suggestion, error) {
+	args := m.Called(ctx, term, fuzzy, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repository.Suggestion), args.Error(1)
+}
+
+func (m *MockBookRepository) Similar(ctx context.Context, term string, limit int) ([]repository.Suggestion, error) {
+	args := m.Called(ctx, term, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repository.Suggestion), args.Error(1)
+}
+
+func (m *MockBookRepository) Facets(ctx context.Context, filter repository.BookFilter, facets []string, size int) (map[string][]repository.FacetCount, error) {
+	args := m.Called(ctx, filter, facets, size)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string][]repository.FacetCount), args.Error(1)
+}
+
+func (m *MockBookRepository) UpdateAvailableCopies(ctx context.Context, id uint, change int) error {
+	args := m.Called(ctx, id, change)
+	return args.Error(0)
+}
+
+func (m *MockBookRepository) UpdateCover(ctx context.Context, id, version uint, coverVersion, contentType string) error {
+	args := m.Called(ctx, id, version, coverVersion, contentType)
+	return args.Error(0)
+}
+
+func (m *MockBookRepository) Each(ctx context.Context, batchSize int, fn func(books []models.Book) error) error {
+	args := m.Called(ctx, batchSize, fn)
+	if batches, ok := args.Get(0).([][]models.Book); ok {
+		for _, batch := range batches {
+			if err := fn(batch); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockBookRepository) Scan(ctx context.Context, filter repository.BookFilter, sort string, batchSize int, fn func(books []models.Book) error) error {
+	args := m.Called(ctx, filter, sort, batchSize, fn)
+	if batches, ok := args.Get(0).([][]models.Book); ok {
+		for _, batch := range batches {
+			if err := fn(batch); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockBookRepository) Shelf(ctx context.Context, key string, id uint, filter repository.ShelfFilter, before, after int) ([]models.Book, []models.Book, error) {
+	args := m.Called(ctx, key, id, filter, before, after)
+	return args.Get(0).([]models.Book), args.Get(1).([]models.Book), args.Error(2)
+}
+
+func (m *MockBookRepository) ShelfList(ctx context.Context, filter repository.ShelfFilter, limit int) ([]models.Book, error) {
+	args := m.Called(ctx, filter, limit)
+	return args.Get(0).([]models.Book), args.Error(1)
+}
+
 type MockBorrowRepository struct {
 	mock.Mock
 }
@@ -84,60 +186,91 @@ func (m *MockBorrowRepository) WithTx(tx *gorm.DB) repository.BorrowRepository {
 	return args.Get(0).(repository.BorrowRepository)
 }
 
-func (m *MockBorrowRepository) Create(record *models.BorrowRecord) error {
-	args := m.Called(record)
+func (m *MockBorrowRepository) Create(ctx context.Context, record *models.BorrowRecord) error {
+	args := m.Called(ctx, record)
 	return args.Error(0)
 }
 
-func (m *MockBorrowRepository) FindByID(id uint) (*models.BorrowRecord, error) {
-	args := m.Called(id)
+func (m *MockBorrowRepository) FindByID(ctx context.Context, id uint) (*models.BorrowRecord, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.BorrowRecord), args.Error(1)
 }
 
-func (m *MockBorrowRepository) FindByIDForUpdate(id uint) (*models.BorrowRecord, error) {
-	args := m.Called(id)
+func (m *MockBorrowRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.BorrowRecord, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.BorrowRecord), args.Error(1)
 }
 
-func (m *MockBorrowRepository) FindActiveByUserAndBook(userID, bookID uint) (*models.BorrowRecord, error) {
-	args := m.Called(userID, bookID)
+func (m *MockBorrowRepository) FindActiveByUserAndBook(ctx context.Context, userID, bookID uint) (*models.BorrowRecord, error) {
+	args := m.Called(ctx, userID, bookID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.BorrowRecord), args.Error(1)
 }
 
-func (m *MockBorrowRepository) Update(record *models.BorrowRecord) error {
-	args := m.Called(record)
+func (m *MockBorrowRepository) Update(ctx context.Context, record *models.BorrowRecord) error {
+	args := m.Called(ctx, record)
 	return args.Error(0)
 }
 
-func (m *MockBorrowRepository) ListByUser(userID uint, page, limit int, sort string) ([]models.BorrowRecord, int64, error) {
-	args := m.Called(userID, page, limit, sort)
-	return args.Get(0).([]models.BorrowRecord), args.Get(1).(int64), args.Error(2)
+func (m *MockBorrowRepository) ListByUser(ctx context.Context, userID uint, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error) {
+	args := m.Called(ctx, userID, req)
+	return args.Get(0).([]models.BorrowRecord), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockBorrowRepository) ListActive(ctx context.Context, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).([]models.BorrowRecord), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockBorrowRepository) ListOverdue(ctx context.Context, req query.PageRequest) ([]models.BorrowRecord, query.PageInfo, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).([]models.BorrowRecord), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockBorrowRepository) CountActiveByUser(ctx context.Context, userID uint) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockBorrowRepository) ListActive(page, limit int, sort string) ([]models.BorrowRecord, int64, error) {
-	args := m.Called(page, limit, sort)
-	return args.Get(0).([]models.BorrowRecord), args.Get(1).(int64), args.Error(2)
+func (m *MockBorrowRepository) CountActiveByBook(ctx context.Context) (map[uint]int64, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uint]int64), args.Error(1)
+}
+
+func (m *MockBorrowRepository) CountByBook(ctx context.Context, bookID uint) (int64, error) {
+	args := m.Called(ctx, bookID)
+	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockBorrowRepository) ListOverdue(page, limit int, sort string) ([]models.BorrowRecord, int64, error) {
-	args := m.Called(page, limit, sort)
-	return args.Get(0).([]models.BorrowRecord), args.Get(1).(int64), args.Error(2)
+func (m *MockBorrowRepository) DetachBook(ctx context.Context, book *models.Book) (int64, error) {
+	args := m.Called(ctx, book)
+	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockBorrowRepository) CountActiveByUser(userID uint) (int64, error) {
-	args := m.Called(userID)
+func (m *MockBorrowRepository) MarkOverdue(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(ctx, now)
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockBorrowRepository) CirculationStats(ctx context.Context, from, to time.Time, top int) (*repository.CirculationStats, error) {
+	args := m.Called(ctx, from, to, top)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repository.CirculationStats), args.Error(1)
+}
+
 type MockUserRepository struct {
 	mock.Mock
 }
@@ -147,58 +280,72 @@ func (m *MockUserRepository) WithTx(tx *gorm.DB) repository.UserRepository {
 	return args.Get(0).(repository.UserRepository)
 }
 
-func (m *MockUserRepository) Create(user *models.User) error {
-	args := m.Called(user)
+// WithDeleted returns the mock itself: expectations cover deleted users too.
+func (m *MockUserRepository) WithDeleted() repository.UserRepository {
+	return m
+}
+
+func (m *MockUserRepository) Create(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) FindByID(id uint) (*models.User, error) {
-	args := m.Called(id)
+func (m *MockUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindByIDForUpdate(id uint) (*models.User, error) {
-	args := m.Called(id)
+func (m *MockUserRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.User, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindByUsername(username string) (*models.User, error) {
-	args := m.Called(username)
+func (m *MockUserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	args := m.Called(ctx, username)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindByEmail(email string) (*models.User, error) {
-	args := m.Called(email)
+func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(ctx, email)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) Update(user *models.User) error {
-	args := m.Called(user)
+func (m *MockUserRepository) Update(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) Delete(id uint) error {
-	args := m.Called(id)
+func (m *MockUserRepository) Delete(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) List(page, limit int) ([]models.User, int64, error) {
-	args := m.Called(page, limit)
+func (m *MockUserRepository) List(ctx context.Context, page, limit int) ([]models.User, int64, error) {
+	args := m.Called(ctx, page, limit)
 	return args.Get(0).([]models.User), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockUserRepository) DeletedBefore(ctx context.Context, cutoff time.Time) ([]models.User, error) {
+	args := m.Called(ctx, cutoff)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
 func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
 	t.Helper()
 
@@ -216,21 +363,71 @@ func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
 	return gormDB, mockDB
 }
 
+// newBorrowService serves borrows for users without holds and returns
+// nobody is waiting for; tests of holds use newBorrowHoldService.
 func newBorrowService(t *testing.T) (*MockBorrowRepository, *MockBookRepository, *MockUserRepository, sqlmock.Sqlmock, service.BorrowService) {
 	t.Helper()
 
-	mockBorrowRepo := new(MockBorrowRepository)
-	mockBookRepo := new(MockBookRepository)
-	mockUserRepo := new(MockUserRepository)
+	mockBorrowRepo, mockBookRepo, mockUserRepo, mockHoldRepo, mockDB, svc := newBorrowHoldService(t)
+	mockHoldRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockHoldRepo).Maybe()
+	mockHoldRepo.On("FindReady", mock.Anything, mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Maybe()
+	mockHoldRepo.On("NextWaiting", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Maybe()
+	return mockBorrowRepo, mockBookRepo, mockUserRepo, mockDB, svc
+}
+
+func newBorrowHoldService(t *testing.T) (*MockBorrowRepository, *MockBookRepository, *MockUserRepository, *MockHoldRepository, sqlmock.Sqlmock, service.BorrowService) {
+	t.Helper()
+
+	m, mockDB, svc := newBorrowBranchService(t)
+	m.copyRepo.On("FindAvailable", mock.Anything, mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Maybe()
+	m.copyRepo.On("CountByBook", mock.Anything, mock.Anything).Return(int64(0), int64(0), nil).Maybe()
+	return m.borrowRepo, m.bookRepo, m.userRepo, m.holdRepo, mockDB, svc
+}
+
+type borrowServiceMocks struct {
+	borrowRepo   *MockBorrowRepository
+	bookRepo     *MockBookRepository
+	userRepo     *MockUserRepository
+	holdRepo     *MockHoldRepository
+	copyRepo     *MockCopyRepository
+	transferRepo *MockTransferRepository
+	branchRepo   *MockBranchRepository
+	accountRepo  *MockAccountRepository
+	revisionRepo *MockBookRevisionRepository
+}
+
+// newBorrowBranchService serves borrows of copies at branches. Only the new
+// repositories join transactions by default; tests expect the WithTx calls
+// of the others.
+func newBorrowBranchService(t *testing.T) (borrowServiceMocks, sqlmock.Sqlmock, service.BorrowService) {
+	t.Helper()
+
+	m := borrowServiceMocks{
+		borrowRepo:   new(MockBorrowRepository),
+		bookRepo:     new(MockBookRepository),
+		userRepo:     new(MockUserRepository),
+		holdRepo:     new(MockHoldRepository),
+		copyRepo:     new(MockCopyRepository),
+		transferRepo: new(MockTransferRepository),
+		branchRepo:   new(MockBranchRepository),
+		accountRepo:  new(MockAccountRepository),
+		revisionRepo: new(MockBookRevisionRepository),
+	}
+	m.copyRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.copyRepo).Maybe()
+	m.transferRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.transferRepo).Maybe()
+	m.branchRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.branchRepo).Maybe()
+	m.accountRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.accountRepo).Maybe()
+	m.revisionRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.revisionRepo).Maybe()
 	gormDB, mockDB := newMockDB(t)
 
-	svc := service.NewBorrowService(gormDB, mockBorrowRepo, mockBookRepo, mockUserRepo, service.BorrowServiceConfig{
+	svc := service.NewBorrowService(gormDB, m.borrowRepo, m.bookRepo, m.revisionRepo, m.userRepo, m.holdRepo, m.copyRepo, m.transferRepo, m.branchRepo, m.accountRepo, service.BorrowServiceConfig{
 		MaxBooksPerUser: 5,
 		BorrowDays:      7,
 		FinePerDay:      1000,
+		ProcessingFee:   5000,
 	})
 
-	return mockBorrowRepo, mockBookRepo, mockUserRepo, mockDB, svc
+	return m, mockDB, svc
 }
 
 func TestBorrowService_BorrowBook_Success(t *testing.T) {
@@ -246,33 +443,33 @@ func TestBorrowService_BorrowBook_Success(t *testing.T) {
 	mockUserRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockUserRepo).Once()
 	mockBookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBookRepo).Once()
 	mockBorrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBorrowRepo).Once()
-	mockUserRepo.On("FindByIDForUpdate", userID).Return(user, nil).Once()
-	mockBorrowRepo.On("CountActiveByUser", userID).Return(int64(1), nil).Once()
-	mockBookRepo.On("FindByIDForUpdate", uint(1)).Return(book, nil).Once()
-	mockBorrowRepo.On("FindActiveByUserAndBook", userID, uint(1)).Return((*models.BorrowRecord)(nil), gorm.ErrRecordNotFound).Once()
-	mockBookRepo.On("Update", mock.AnythingOfType("*models.Book")).
+	mockUserRepo.On("FindByIDForUpdate", mock.Anything, userID).Return(user, nil).Once()
+	mockBorrowRepo.On("CountActiveByUser", mock.Anything, userID).Return(int64(1), nil).Once()
+	mockBookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+	mockBorrowRepo.On("FindActiveByUserAndBook", mock.Anything, userID, uint(1)).Return((*models.BorrowRecord)(nil), gorm.ErrRecordNotFound).Once()
+	mockBookRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Book")).
 		Run(func(args mock.Arguments) {
-			updatedBook := args.Get(0).(*models.Book)
+			updatedBook := args.Get(1).(*models.Book)
 			assert.Equal(t, 2, updatedBook.AvailableCopies)
 		}).
 		Return(nil).
 		Once()
-	mockBorrowRepo.On("Create", mock.AnythingOfType("*models.BorrowRecord")).
+	mockBorrowRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.BorrowRecord")).
 		Run(func(args mock.Arguments) {
-			record := args.Get(0).(*models.BorrowRecord)
+			record := args.Get(1).(*models.BorrowRecord)
 			assert.Equal(t, userID, record.UserID)
-			assert.Equal(t, uint(1), record.BookID)
+			assert.Equal(t, uint(1), *record.BookID)
 			assert.False(t, record.DueDate.IsZero())
 		}).
 		Return(nil).
 		Once()
 	sqlMock.ExpectCommit()
 
-	borrowRecord, err := borrowService.BorrowBook(userID, req)
+	borrowRecord, err := borrowService.BorrowBook(context.Background(), userID, req)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, borrowRecord)
-	assert.Equal(t, uint(1), borrowRecord.BookID)
+	assert.Equal(t, uint(1), *borrowRecord.BookID)
 	mockUserRepo.AssertExpectations(t)
 	mockBookRepo.AssertExpectations(t)
 	mockBorrowRepo.AssertExpectations(t)
@@ -288,7 +485,7 @@ func TestBorrowService_ReturnBook_Success(t *testing.T) {
 	borrowRecord := &models.BorrowRecord{
 		ID:         1,
 		UserID:     userID,
-		BookID:     1,
+		BookID:     uintPtr(1),
 		BorrowDate: now.Add(-10 * 24 * time.Hour),
 		DueDate:    now.Add(-(72*time.Hour + time.Minute)),
 		Status:     models.StatusBorrowed,
@@ -302,18 +499,18 @@ func TestBorrowService_ReturnBook_Success(t *testing.T) {
 	sqlMock.ExpectBegin()
 	mockBookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBookRepo).Once()
 	mockBorrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBorrowRepo).Once()
-	mockBorrowRepo.On("FindByIDForUpdate", uint(1)).Return(borrowRecord, nil).Once()
-	mockBookRepo.On("FindByIDForUpdate", uint(1)).Return(book, nil).Once()
-	mockBookRepo.On("Update", mock.AnythingOfType("*models.Book")).
+	mockBorrowRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(borrowRecord, nil).Once()
+	mockBookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+	mockBookRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Book")).
 		Run(func(args mock.Arguments) {
-			updatedBook := args.Get(0).(*models.Book)
+			updatedBook := args.Get(1).(*models.Book)
 			assert.Equal(t, 3, updatedBook.AvailableCopies)
 		}).
 		Return(nil).
 		Once()
-	mockBorrowRepo.On("Update", mock.AnythingOfType("*models.BorrowRecord")).
+	mockBorrowRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.BorrowRecord")).
 		Run(func(args mock.Arguments) {
-			updatedRecord := args.Get(0).(*models.BorrowRecord)
+			updatedRecord := args.Get(1).(*models.BorrowRecord)
 			assert.NotNil(t, updatedRecord.ReturnDate)
 			assert.Equal(t, models.StatusReturned, updatedRecord.Status)
 		}).
@@ -321,7 +518,7 @@ func TestBorrowService_ReturnBook_Success(t *testing.T) {
 		Once()
 	sqlMock.ExpectCommit()
 
-	returnedRecord, fine, err := borrowService.ReturnBook(userID, "member", req)
+	returnedRecord, fine, err := borrowService.ReturnBook(context.Background(), userID, "member", req)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, returnedRecord)
@@ -338,17 +535,17 @@ func TestBorrowService_ReturnBook_NotOwner_RollsBack(t *testing.T) {
 	borrowRecord := &models.BorrowRecord{
 		ID:     1,
 		UserID: 99,
-		BookID: 1,
+		BookID: uintPtr(1),
 		Status: models.StatusBorrowed,
 	}
 
 	sqlMock.ExpectBegin()
 	mockBookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBookRepo).Once()
 	mockBorrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBorrowRepo).Once()
-	mockBorrowRepo.On("FindByIDForUpdate", uint(1)).Return(borrowRecord, nil).Once()
+	mockBorrowRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(borrowRecord, nil).Once()
 	sqlMock.ExpectRollback()
 
-	returnedRecord, fine, err := borrowService.ReturnBook(1, "member", req)
+	returnedRecord, fine, err := borrowService.ReturnBook(context.Background(), 1, "member", req)
 
 	assert.Error(t, err)
 	assert.Nil(t, returnedRecord)
@@ -367,7 +564,7 @@ func TestBorrowService_ReturnBook_AdminCanReturnOtherUserBook(t *testing.T) {
 	borrowRecord := &models.BorrowRecord{
 		ID:         1,
 		UserID:     99,
-		BookID:     1,
+		BookID:     uintPtr(1),
 		BorrowDate: now.Add(-2 * 24 * time.Hour),
 		DueDate:    now.Add(5 * 24 * time.Hour),
 		Status:     models.StatusBorrowed,
@@ -377,13 +574,13 @@ func TestBorrowService_ReturnBook_AdminCanReturnOtherUserBook(t *testing.T) {
 	sqlMock.ExpectBegin()
 	mockBookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBookRepo).Once()
 	mockBorrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBorrowRepo).Once()
-	mockBorrowRepo.On("FindByIDForUpdate", uint(1)).Return(borrowRecord, nil).Once()
-	mockBookRepo.On("FindByIDForUpdate", uint(1)).Return(book, nil).Once()
-	mockBookRepo.On("Update", mock.AnythingOfType("*models.Book")).Return(nil).Once()
-	mockBorrowRepo.On("Update", mock.AnythingOfType("*models.BorrowRecord")).Return(nil).Once()
+	mockBorrowRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(borrowRecord, nil).Once()
+	mockBookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+	mockBookRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Book")).Return(nil).Once()
+	mockBorrowRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.BorrowRecord")).Return(nil).Once()
 	sqlMock.ExpectCommit()
 
-	returnedRecord, fine, err := borrowService.ReturnBook(1, "admin", req)
+	returnedRecord, fine, err := borrowService.ReturnBook(context.Background(), 1, "admin", req)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, returnedRecord)
@@ -400,7 +597,7 @@ func TestBorrowService_ReturnBook_StockAlreadyFull_RollsBack(t *testing.T) {
 	borrowRecord := &models.BorrowRecord{
 		ID:     1,
 		UserID: 1,
-		BookID: 1,
+		BookID: uintPtr(1),
 		Status: models.StatusBorrowed,
 	}
 	book := &models.Book{
@@ -412,11 +609,11 @@ func TestBorrowService_ReturnBook_StockAlreadyFull_RollsBack(t *testing.T) {
 	sqlMock.ExpectBegin()
 	mockBookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBookRepo).Once()
 	mockBorrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBorrowRepo).Once()
-	mockBorrowRepo.On("FindByIDForUpdate", uint(1)).Return(borrowRecord, nil).Once()
-	mockBookRepo.On("FindByIDForUpdate", uint(1)).Return(book, nil).Once()
+	mockBorrowRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(borrowRecord, nil).Once()
+	mockBookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
 	sqlMock.ExpectRollback()
 
-	returnedRecord, fine, err := borrowService.ReturnBook(1, "member", req)
+	returnedRecord, fine, err := borrowService.ReturnBook(context.Background(), 1, "member", req)
 
 	assert.Error(t, err)
 	assert.Nil(t, returnedRecord)
@@ -424,8 +621,8 @@ func TestBorrowService_ReturnBook_StockAlreadyFull_RollsBack(t *testing.T) {
 	assert.Equal(t, "book stock is already full, cannot process return", err.Error())
 	mockBookRepo.AssertExpectations(t)
 	mockBorrowRepo.AssertExpectations(t)
-	mockBookRepo.AssertNotCalled(t, "Update", mock.Anything)
-	mockBorrowRepo.AssertNotCalled(t, "Update", mock.Anything)
+	mockBookRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	mockBorrowRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
 	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
 
@@ -441,15 +638,15 @@ func TestBorrowService_BorrowBook_TransactionError_RollsBack(t *testing.T) {
 	mockUserRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockUserRepo).Once()
 	mockBookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBookRepo).Once()
 	mockBorrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBorrowRepo).Once()
-	mockUserRepo.On("FindByIDForUpdate", userID).Return(user, nil).Once()
-	mockBorrowRepo.On("CountActiveByUser", userID).Return(int64(0), nil).Once()
-	mockBookRepo.On("FindByIDForUpdate", uint(1)).Return(book, nil).Once()
-	mockBorrowRepo.On("FindActiveByUserAndBook", userID, uint(1)).Return((*models.BorrowRecord)(nil), gorm.ErrRecordNotFound).Once()
-	mockBookRepo.On("Update", mock.AnythingOfType("*models.Book")).Return(nil).Once()
-	mockBorrowRepo.On("Create", mock.AnythingOfType("*models.BorrowRecord")).Return(errors.New("insert failed")).Once()
+	mockUserRepo.On("FindByIDForUpdate", mock.Anything, userID).Return(user, nil).Once()
+	mockBorrowRepo.On("CountActiveByUser", mock.Anything, userID).Return(int64(0), nil).Once()
+	mockBookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+	mockBorrowRepo.On("FindActiveByUserAndBook", mock.Anything, userID, uint(1)).Return((*models.BorrowRecord)(nil), gorm.ErrRecordNotFound).Once()
+	mockBookRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Book")).Return(nil).Once()
+	mockBorrowRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.BorrowRecord")).Return(errors.New("insert failed")).Once()
 	sqlMock.ExpectRollback()
 
-	borrowRecord, err := borrowService.BorrowBook(userID, req)
+	borrowRecord, err := borrowService.BorrowBook(context.Background(), userID, req)
 
 	assert.Error(t, err)
 	assert.Nil(t, borrowRecord)
@@ -467,19 +664,19 @@ func TestBorrowService_BorrowBook_DuplicateActiveBorrow_RollsBack(t *testing.T)
 	req := dto.BorrowBookRequest{BookID: 1}
 	user := &models.User{ID: userID, IsActive: true}
 	book := &models.Book{ID: 1, TotalCopies: 5, AvailableCopies: 2}
-	existingBorrow := &models.BorrowRecord{ID: 99, UserID: userID, BookID: req.BookID, Status: models.StatusBorrowed}
+	existingBorrow := &models.BorrowRecord{ID: 99, UserID: userID, BookID: &req.BookID, Status: models.StatusBorrowed}
 
 	sqlMock.ExpectBegin()
 	mockUserRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockUserRepo).Once()
 	mockBookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBookRepo).Once()
 	mockBorrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBorrowRepo).Once()
-	mockUserRepo.On("FindByIDForUpdate", userID).Return(user, nil).Once()
-	mockBorrowRepo.On("CountActiveByUser", userID).Return(int64(1), nil).Once()
-	mockBookRepo.On("FindByIDForUpdate", req.BookID).Return(book, nil).Once()
-	mockBorrowRepo.On("FindActiveByUserAndBook", userID, req.BookID).Return(existingBorrow, nil).Once()
+	mockUserRepo.On("FindByIDForUpdate", mock.Anything, userID).Return(user, nil).Once()
+	mockBorrowRepo.On("CountActiveByUser", mock.Anything, userID).Return(int64(1), nil).Once()
+	mockBookRepo.On("FindByIDForUpdate", mock.Anything, req.BookID).Return(book, nil).Once()
+	mockBorrowRepo.On("FindActiveByUserAndBook", mock.Anything, userID, req.BookID).Return(existingBorrow, nil).Once()
 	sqlMock.ExpectRollback()
 
-	borrowRecord, err := borrowService.BorrowBook(userID, req)
+	borrowRecord, err := borrowService.BorrowBook(context.Background(), userID, req)
 
 	assert.Error(t, err)
 	assert.Nil(t, borrowRecord)
@@ -501,11 +698,11 @@ func TestBorrowService_BorrowBook_MaxLimitReached_RollsBack(t *testing.T) {
 	mockUserRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockUserRepo).Once()
 	mockBookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBookRepo).Once()
 	mockBorrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBorrowRepo).Once()
-	mockUserRepo.On("FindByIDForUpdate", userID).Return(user, nil).Once()
-	mockBorrowRepo.On("CountActiveByUser", userID).Return(int64(5), nil).Once()
+	mockUserRepo.On("FindByIDForUpdate", mock.Anything, userID).Return(user, nil).Once()
+	mockBorrowRepo.On("CountActiveByUser", mock.Anything, userID).Return(int64(5), nil).Once()
 	sqlMock.ExpectRollback()
 
-	borrowRecord, err := borrowService.BorrowBook(userID, req)
+	borrowRecord, err := borrowService.BorrowBook(context.Background(), userID, req)
 
 	assert.Error(t, err)
 	assert.Nil(t, borrowRecord)
@@ -528,12 +725,12 @@ func TestBorrowService_BorrowBook_BookUnavailable_RollsBack(t *testing.T) {
 	mockUserRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockUserRepo).Once()
 	mockBookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBookRepo).Once()
 	mockBorrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBorrowRepo).Once()
-	mockUserRepo.On("FindByIDForUpdate", userID).Return(user, nil).Once()
-	mockBorrowRepo.On("CountActiveByUser", userID).Return(int64(0), nil).Once()
-	mockBookRepo.On("FindByIDForUpdate", req.BookID).Return(book, nil).Once()
+	mockUserRepo.On("FindByIDForUpdate", mock.Anything, userID).Return(user, nil).Once()
+	mockBorrowRepo.On("CountActiveByUser", mock.Anything, userID).Return(int64(0), nil).Once()
+	mockBookRepo.On("FindByIDForUpdate", mock.Anything, req.BookID).Return(book, nil).Once()
 	sqlMock.ExpectRollback()
 
-	borrowRecord, err := borrowService.BorrowBook(userID, req)
+	borrowRecord, err := borrowService.BorrowBook(context.Background(), userID, req)
 
 	assert.Error(t, err)
 	assert.Nil(t, borrowRecord)
@@ -543,3 +740,104 @@ func TestBorrowService_BorrowBook_BookUnavailable_RollsBack(t *testing.T) {
 	mockBorrowRepo.AssertExpectations(t)
 	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
+
+func TestBorrowService_DeclareLost_BillsReplacement(t *testing.T) {
+	m, sqlMock, borrowService := newBorrowBranchService(t)
+	m.mockTransactions()
+	book := &models.Book{ID: 1, TotalCopies: 2, AvailableCopies: 1, Price: 90000}
+	copyID := uint(7)
+	record := &models.BorrowRecord{ID: 3, UserID: 4, BookID: uintPtr(1), CopyID: &copyID, BorrowDate: time.Now(), DueDate: time.Now().Add(time.Hour), Status: models.StatusBorrowed}
+	item := &models.Copy{ID: 7, BookID: 1, HomeBranchID: 1, BranchID: 1, Status: models.CopyOnLoan}
+
+	sqlMock.ExpectBegin()
+	m.borrowRepo.On("FindByIDForUpdate", mock.Anything, uint(3)).Return(record, nil).Once()
+	m.bookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+	m.bookRepo.On("Update", mock.Anything, book).Return(nil).Once()
+	m.bookRepo.On("FindByID", mock.Anything, uint(1)).Return(book, nil).Once()
+	m.revisionRepo.On("Create", mock.Anything, mock.MatchedBy(func(revision *models.BookRevision) bool {
+		return revision.Action == models.RevisionUpdate && revision.ActorID == 9 &&
+			string(revision.Changes) == `[{"field":"total_copies","from":2,"to":1}]`
+	})).Return(nil).Once()
+	m.copyRepo.On("FindByIDForUpdate", mock.Anything, uint(7)).Return(item, nil).Once()
+	m.copyRepo.On("Update", mock.Anything, item).Return(nil).Once()
+	m.borrowRepo.On("Update", mock.Anything, record).Return(nil).Once()
+	m.accountRepo.On("Create", mock.Anything, mock.MatchedBy(func(entries []models.AccountEntry) bool {
+		return len(entries) == 2 &&
+			entries[0].Kind == models.ChargeReplacement && entries[0].Amount == 90000 &&
+			entries[1].Kind == models.ChargeProcessing && entries[1].Amount == 5000 &&
+			entries[0].UserID == 4 && entries[1].CreatedBy == 9
+	})).Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	closed, charges, err := borrowService.DeclareLost(context.Background(), dto.Actor{UserID: 9}, dto.DeclareLostRequest{BorrowRecordID: 3})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusLost, closed.Status)
+	assert.NotNil(t, closed.ReturnDate)
+	assert.Len(t, charges, 2)
+	assert.Equal(t, models.CopyLost, item.Status)
+	assert.Equal(t, 1, book.TotalCopies)
+	assert.Equal(t, 1, book.AvailableCopies)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBorrowService_DeclareLost_ClosedLoan(t *testing.T) {
+	m, sqlMock, borrowService := newBorrowBranchService(t)
+	m.mockTransactions()
+	returned := time.Now()
+	record := &models.BorrowRecord{ID: 3, UserID: 4, BookID: uintPtr(1), ReturnDate: &returned, Status: models.StatusReturned}
+
+	sqlMock.ExpectBegin()
+	m.borrowRepo.On("FindByIDForUpdate", mock.Anything, uint(3)).Return(record, nil).Once()
+	sqlMock.ExpectRollback()
+
+	_, _, err := borrowService.DeclareLost(context.Background(), dto.Actor{UserID: 9}, dto.DeclareLostRequest{BorrowRecordID: 3})
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	m.accountRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBorrowService_ReturnLost_RefundsReplacement(t *testing.T) {
+	m, sqlMock, borrowService := newBorrowBranchService(t)
+	m.mockTransactions()
+	book := &models.Book{ID: 1, TotalCopies: 1, AvailableCopies: 1, Price: 90000}
+	copyID := uint(7)
+	lostAt := time.Now().Add(-time.Hour)
+	record := &models.BorrowRecord{ID: 3, UserID: 4, BookID: uintPtr(1), CopyID: &copyID, ReturnDate: &lostAt, Status: models.StatusLost}
+	item := &models.Copy{ID: 7, BookID: 1, HomeBranchID: 1, BranchID: 1, Status: models.CopyLost}
+
+	sqlMock.ExpectBegin()
+	m.borrowRepo.On("FindByIDForUpdate", mock.Anything, uint(3)).Return(record, nil).Once()
+	m.bookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+	m.bookRepo.On("Update", mock.Anything, book).Return(nil).Twice()
+	m.bookRepo.On("FindByID", mock.Anything, uint(1)).Return(book, nil).Once()
+	m.revisionRepo.On("Create", mock.Anything, mock.MatchedBy(func(revision *models.BookRevision) bool {
+		return revision.ActorID == 9 && string(revision.Changes) == `[{"field":"total_copies","from":1,"to":2}]`
+	})).Return(nil).Once()
+	m.copyRepo.On("FindByIDForUpdate", mock.Anything, uint(7)).Return(item, nil).Once()
+	m.holdRepo.On("NextWaiting", mock.Anything, book).Return(nil, gorm.ErrRecordNotFound).Once()
+	m.copyRepo.On("Update", mock.Anything, item).Return(nil).Once()
+	m.borrowRepo.On("Update", mock.Anything, record).Return(nil).Once()
+	// The price was changed since the loan was billed; what was billed is
+	// refunded, less an earlier partial refund.
+	m.accountRepo.On("ListByBorrow", mock.Anything, uint(3)).Return([]models.AccountEntry{
+		{Kind: models.ChargeReplacement, Amount: 80000},
+		{Kind: models.ChargeProcessing, Amount: 5000},
+		{Kind: models.ChargeRefund, Amount: -20000},
+	}, nil).Once()
+	m.accountRepo.On("Create", mock.Anything, mock.MatchedBy(func(entries []models.AccountEntry) bool {
+		return len(entries) == 1 && entries[0].Kind == models.ChargeRefund && entries[0].Amount == -60000
+	})).Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	returned, refunds, err := borrowService.ReturnLost(context.Background(), dto.Actor{UserID: 9}, dto.ReturnBookRequest{BorrowRecordID: 3})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusReturned, returned.Status)
+	assert.Equal(t, uint(1), *returned.ReturnBranchID)
+	assert.Len(t, refunds, 1)
+	assert.Equal(t, models.CopyAvailable, item.Status)
+	assert.Equal(t, 2, book.TotalCopies)
+	assert.Equal(t, 2, book.AvailableCopies)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
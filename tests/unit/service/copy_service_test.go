@@ -0,0 +1,381 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type MockBranchRepository struct {
+	mock.Mock
+}
+
+func (m *MockBranchRepository) WithTx(tx *gorm.DB) repository.BranchRepository {
+	args := m.Called(tx)
+	return args.Get(0).(repository.BranchRepository)
+}
+
+func (m *MockBranchRepository) Create(ctx context.Context, branch *models.Branch) error {
+	args := m.Called(ctx, branch)
+	return args.Error(0)
+}
+
+func (m *MockBranchRepository) Update(ctx context.Context, branch *models.Branch) error {
+	args := m.Called(ctx, branch)
+	return args.Error(0)
+}
+
+func (m *MockBranchRepository) Delete(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockBranchRepository) FindByID(ctx context.Context, id uint) (*models.Branch, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Branch), args.Error(1)
+}
+
+func (m *MockBranchRepository) FindByCode(ctx context.Context, code string) (*models.Branch, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Branch), args.Error(1)
+}
+
+func (m *MockBranchRepository) InUse(ctx context.Context, id uint) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockBranchRepository) List(ctx context.Context, search string, req query.PageRequest) ([]models.Branch, query.PageInfo, error) {
+	args := m.Called(ctx, search, req)
+	return args.Get(0).([]models.Branch), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+type MockCopyRepository struct {
+	mock.Mock
+}
+
+func (m *MockCopyRepository) WithTx(tx *gorm.DB) repository.CopyRepository {
+	args := m.Called(tx)
+	return args.Get(0).(repository.CopyRepository)
+}
+
+func (m *MockCopyRepository) Create(ctx context.Context, item *models.Copy) error {
+	args := m.Called(ctx, item)
+	return args.Error(0)
+}
+
+func (m *MockCopyRepository) Update(ctx context.Context, item *models.Copy) error {
+	args := m.Called(ctx, item)
+	return args.Error(0)
+}
+
+func (m *MockCopyRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.Copy, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Copy), args.Error(1)
+}
+
+func (m *MockCopyRepository) FindByBarcode(ctx context.Context, barcode string) (*models.Copy, error) {
+	args := m.Called(ctx, barcode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Copy), args.Error(1)
+}
+
+func (m *MockCopyRepository) FindByBarcodes(ctx context.Context, barcodes []string) ([]models.Copy, error) {
+	args := m.Called(ctx, barcodes)
+	return args.Get(0).([]models.Copy), args.Error(1)
+}
+
+func (m *MockCopyRepository) FindAvailable(ctx context.Context, bookID, branchID uint) (*models.Copy, error) {
+	args := m.Called(ctx, bookID, branchID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Copy), args.Error(1)
+}
+
+func (m *MockCopyRepository) ListByBook(ctx context.Context, bookID uint) ([]models.Copy, error) {
+	args := m.Called(ctx, bookID)
+	return args.Get(0).([]models.Copy), args.Error(1)
+}
+
+func (m *MockCopyRepository) CountByBook(ctx context.Context, bookID uint) (int64, int64, error) {
+	args := m.Called(ctx, bookID)
+	return args.Get(0).(int64), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockCopyRepository) Holdings(ctx context.Context, bookIDs []uint) (map[uint][]models.BranchHolding, error) {
+	args := m.Called(ctx, bookIDs)
+	return args.Get(0).(map[uint][]models.BranchHolding), args.Error(1)
+}
+
+func (m *MockCopyRepository) ShelfList(ctx context.Context, filter repository.ShelfFilter, limit int) ([]models.Copy, error) {
+	args := m.Called(ctx, filter, limit)
+	return args.Get(0).([]models.Copy), args.Error(1)
+}
+
+func (m *MockCopyRepository) ListOnShelf(ctx context.Context, branchID uint) ([]models.Copy, error) {
+	args := m.Called(ctx, branchID)
+	return args.Get(0).([]models.Copy), args.Error(1)
+}
+
+type MockTransferRepository struct {
+	mock.Mock
+}
+
+func (m *MockTransferRepository) WithTx(tx *gorm.DB) repository.TransferRepository {
+	args := m.Called(tx)
+	return args.Get(0).(repository.TransferRepository)
+}
+
+func (m *MockTransferRepository) Create(ctx context.Context, transfer *models.Transfer) error {
+	args := m.Called(ctx, transfer)
+	return args.Error(0)
+}
+
+func (m *MockTransferRepository) Update(ctx context.Context, transfer *models.Transfer) error {
+	args := m.Called(ctx, transfer)
+	return args.Error(0)
+}
+
+func (m *MockTransferRepository) FindByID(ctx context.Context, id uint) (*models.Transfer, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Transfer), args.Error(1)
+}
+
+func (m *MockTransferRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.Transfer, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Transfer), args.Error(1)
+}
+
+func (m *MockTransferRepository) FindOpen(ctx context.Context, copyID uint) (*models.Transfer, error) {
+	args := m.Called(ctx, copyID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Transfer), args.Error(1)
+}
+
+func (m *MockTransferRepository) List(ctx context.Context, filter repository.TransferFilter, req query.PageRequest) ([]models.Transfer, query.PageInfo, error) {
+	args := m.Called(ctx, filter, req)
+	return args.Get(0).([]models.Transfer), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+type copyServiceMocks struct {
+	copyRepo     *MockCopyRepository
+	transferRepo *MockTransferRepository
+	branchRepo   *MockBranchRepository
+	bookRepo     *MockBookRepository
+	revisionRepo *MockBookRevisionRepository
+	holdRepo     *MockHoldRepository
+}
+
+func newCopyService(t *testing.T) (copyServiceMocks, service.CopyService) {
+	t.Helper()
+
+	m := copyServiceMocks{
+		copyRepo:     new(MockCopyRepository),
+		transferRepo: new(MockTransferRepository),
+		branchRepo:   new(MockBranchRepository),
+		bookRepo:     new(MockBookRepository),
+		revisionRepo: new(MockBookRevisionRepository),
+		holdRepo:     new(MockHoldRepository),
+	}
+	m.copyRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.copyRepo).Maybe()
+	m.transferRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.transferRepo).Maybe()
+	m.branchRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.branchRepo).Maybe()
+	m.bookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.bookRepo).Maybe()
+	m.holdRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.holdRepo).Maybe()
+	m.revisionRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.revisionRepo).Maybe()
+	gormDB, sqlMock := newMockDB(t)
+	sqlMock.MatchExpectationsInOrder(false)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+	sqlMock.ExpectRollback()
+	return m, service.NewCopyService(gormDB, m.copyRepo, m.transferRepo, m.branchRepo, m.bookRepo, m.revisionRepo, m.holdRepo)
+}
+
+func TestCopyService_AddCopy_NoUnassignedCopyOnShelf(t *testing.T) {
+	m, copyService := newCopyService(t)
+	book := &models.Book{ID: 1, TotalCopies: 3, AvailableCopies: 1}
+	m.bookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+	m.branchRepo.On("FindByID", mock.Anything, uint(2)).Return(&models.Branch{ID: 2}, nil).Once()
+	m.copyRepo.On("FindByBarcode", mock.Anything, "B-0001").Return(nil, gorm.ErrRecordNotFound).Once()
+	m.copyRepo.On("CountByBook", mock.Anything, uint(1)).Return(int64(1), int64(1), nil).Once()
+
+	_, err := copyService.AddCopy(context.Background(), dto.Actor{UserID: 5}, 1, dto.AddCopyRequest{Barcode: "B-0001", BranchID: 2})
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	m.copyRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCopyService_AddCopy_New(t *testing.T) {
+	m, copyService := newCopyService(t)
+	book := &models.Book{ID: 1, TotalCopies: 2, AvailableCopies: 0}
+	m.bookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+	m.branchRepo.On("FindByID", mock.Anything, uint(2)).Return(&models.Branch{ID: 2}, nil).Once()
+	m.copyRepo.On("FindByBarcode", mock.Anything, "B-0002").Return(nil, gorm.ErrRecordNotFound).Once()
+	m.bookRepo.On("Update", mock.Anything, book).Return(nil).Once()
+	m.bookRepo.On("FindByID", mock.Anything, uint(1)).Return(book, nil).Once()
+	m.revisionRepo.On("Create", mock.Anything, mock.MatchedBy(func(revision *models.BookRevision) bool {
+		return revision.Action == models.RevisionUpdate && revision.ActorID == 5 && revision.RequestID == "req-1" &&
+			string(revision.Changes) == `[{"field":"total_copies","from":2,"to":3}]`
+	})).Return(nil).Once()
+	m.copyRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Copy")).Return(nil).Once()
+
+	item, err := copyService.AddCopy(context.Background(), dto.Actor{UserID: 5, RequestID: "req-1"}, 1, dto.AddCopyRequest{Barcode: "B-0002", BranchID: 2, New: true})
+	require.NoError(t, err)
+	assert.Equal(t, 3, book.TotalCopies)
+	assert.Equal(t, 1, book.AvailableCopies)
+	assert.Equal(t, uint(2), item.HomeBranchID)
+	assert.Equal(t, models.CopyAvailable, item.Status)
+}
+
+func TestCopyService_ReceiveTransfer_Permanent(t *testing.T) {
+	m, copyService := newCopyService(t)
+	transfer := &models.Transfer{ID: 4, CopyID: 7, FromBranchID: 1, ToBranchID: 2, Status: models.TransferInTransit, Permanent: true}
+	item := &models.Copy{ID: 7, BookID: 1, HomeBranchID: 1, BranchID: 1, Status: models.CopyInTransit}
+	book := &models.Book{ID: 1, TotalCopies: 2, AvailableCopies: 1}
+	m.transferRepo.On("FindByIDForUpdate", mock.Anything, uint(4)).Return(transfer, nil).Once()
+	m.copyRepo.On("FindByIDForUpdate", mock.Anything, uint(7)).Return(item, nil).Once()
+	m.bookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+	m.transferRepo.On("Update", mock.Anything, transfer).Return(nil).Once()
+	m.holdRepo.On("NextWaiting", mock.Anything, book).Return(nil, gorm.ErrRecordNotFound).Once()
+	m.copyRepo.On("Update", mock.Anything, item).Return(nil).Once()
+	m.bookRepo.On("Update", mock.Anything, book).Return(nil).Once()
+	m.transferRepo.On("FindByID", mock.Anything, uint(4)).Return(transfer, nil).Once()
+
+	_, err := copyService.ReceiveTransfer(context.Background(), 4)
+	require.NoError(t, err)
+	assert.Equal(t, models.TransferReceived, transfer.Status)
+	assert.NotNil(t, transfer.ReceivedAt)
+	assert.Equal(t, uint(2), item.HomeBranchID)
+	assert.Equal(t, uint(2), item.BranchID)
+	assert.Equal(t, models.CopyAvailable, item.Status)
+	assert.Equal(t, 2, book.AvailableCopies)
+}
+
+func TestCopyService_ShipTransfer_NotRequested(t *testing.T) {
+	m, copyService := newCopyService(t)
+	m.transferRepo.On("FindByIDForUpdate", mock.Anything, uint(4)).
+		Return(&models.Transfer{ID: 4, Status: models.TransferCancelled}, nil).Once()
+
+	_, err := copyService.ShipTransfer(context.Background(), 4)
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+}
+
+func TestBranchService_DeleteBranch_InUse(t *testing.T) {
+	branchRepo := new(MockBranchRepository)
+	branchRepo.On("FindByID", mock.Anything, uint(1)).Return(&models.Branch{ID: 1, Code: "MAIN", Version: 1}, nil).Once()
+	branchRepo.On("InUse", mock.Anything, uint(1)).Return(true, nil).Once()
+
+	err := service.NewBranchService(branchRepo).DeleteBranch(context.Background(), 1, 0)
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	branchRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+// mockTransactions lets the repositories of m join any transaction and finds
+// no ready holds.
+func (m borrowServiceMocks) mockTransactions() {
+	m.userRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.userRepo).Maybe()
+	m.bookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.bookRepo).Maybe()
+	m.borrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.borrowRepo).Maybe()
+	m.holdRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.holdRepo).Maybe()
+	m.holdRepo.On("FindReady", mock.Anything, mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Maybe()
+}
+
+func TestBorrowService_BorrowBook_AtBranch(t *testing.T) {
+	m, sqlMock, borrowService := newBorrowBranchService(t)
+	m.mockTransactions()
+	book := &models.Book{ID: 1, TotalCopies: 2, AvailableCopies: 2}
+	item := &models.Copy{ID: 7, BookID: 1, HomeBranchID: 2, BranchID: 2, Status: models.CopyAvailable}
+
+	sqlMock.ExpectBegin()
+	m.userRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(&models.User{ID: 1, IsActive: true}, nil).Once()
+	m.borrowRepo.On("CountActiveByUser", mock.Anything, uint(1)).Return(int64(0), nil).Once()
+	m.bookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+	m.borrowRepo.On("FindActiveByUserAndBook", mock.Anything, uint(1), uint(1)).Return(nil, gorm.ErrRecordNotFound).Once()
+	m.branchRepo.On("FindByID", mock.Anything, uint(2)).Return(&models.Branch{ID: 2}, nil).Once()
+	m.copyRepo.On("FindAvailable", mock.Anything, uint(1), uint(2)).Return(item, nil).Once()
+	m.bookRepo.On("Update", mock.Anything, book).Return(nil).Once()
+	m.copyRepo.On("Update", mock.Anything, item).Return(nil).Once()
+	m.borrowRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.BorrowRecord")).Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	record, err := borrowService.BorrowBook(context.Background(), 1, dto.BorrowBookRequest{BookID: 1, BranchID: 2})
+	require.NoError(t, err)
+	assert.Equal(t, models.CopyOnLoan, item.Status)
+	assert.Equal(t, uint(7), *record.CopyID)
+	assert.Equal(t, uint(2), *record.BranchID)
+	assert.Equal(t, 1, book.AvailableCopies)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBorrowService_ReturnBook_AwayFromHome(t *testing.T) {
+	m, sqlMock, borrowService := newBorrowBranchService(t)
+	m.mockTransactions()
+	book := &models.Book{ID: 1, TotalCopies: 2, AvailableCopies: 1}
+	copyID := uint(7)
+	record := &models.BorrowRecord{ID: 3, UserID: 1, BookID: uintPtr(1), CopyID: &copyID, BorrowDate: time.Now(), DueDate: time.Now().Add(time.Hour), Status: models.StatusBorrowed}
+	item := &models.Copy{ID: 7, BookID: 1, HomeBranchID: 1, BranchID: 1, Status: models.CopyOnLoan}
+
+	sqlMock.ExpectBegin()
+	m.borrowRepo.On("FindByIDForUpdate", mock.Anything, uint(3)).Return(record, nil).Once()
+	m.bookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+	m.branchRepo.On("FindByID", mock.Anything, uint(2)).Return(&models.Branch{ID: 2}, nil).Once()
+	m.copyRepo.On("FindByIDForUpdate", mock.Anything, uint(7)).Return(item, nil).Once()
+	m.copyRepo.On("Update", mock.Anything, item).Return(nil).Once()
+	m.transferRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Transfer")).
+		Run(func(args mock.Arguments) {
+			transfer := args.Get(1).(*models.Transfer)
+			assert.Equal(t, uint(2), transfer.FromBranchID)
+			assert.Equal(t, uint(1), transfer.ToBranchID)
+			assert.Equal(t, models.TransferInTransit, transfer.Status)
+			assert.Equal(t, models.TransferReturn, transfer.Reason)
+		}).
+		Return(nil).Once()
+	m.borrowRepo.On("Update", mock.Anything, record).Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	_, _, err := borrowService.ReturnBook(context.Background(), 1, "member", dto.ReturnBookRequest{BorrowRecordID: 3, BranchID: 2})
+	require.NoError(t, err)
+	assert.Equal(t, models.CopyInTransit, item.Status)
+	assert.Equal(t, uint(2), item.BranchID)
+	assert.Equal(t, uint(2), *record.ReturnBranchID)
+	assert.Equal(t, 1, book.AvailableCopies, "the copy is back in stock when it arrives home")
+	m.bookRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	m.holdRepo.AssertNotCalled(t, "NextWaiting", mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
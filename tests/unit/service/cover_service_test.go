@@ -0,0 +1,193 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newCoverService(t *testing.T, maxSize int64) (*MockBookRepository, string, service.CoverService) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := storage.NewLocalStorage(dir, "")
+	require.NoError(t, err)
+	bookRepo := new(MockBookRepository)
+	return bookRepo, dir, service.NewCoverService(bookRepo, store, maxSize)
+}
+
+func coverPNG(t *testing.T, width, height int, fill color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, fill)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func storedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	require.NoError(t, err)
+	return files
+}
+
+func TestCoverService_SetCoverStoresOriginalAndThumbnails(t *testing.T) {
+	bookRepo, dir, coverService := newCoverService(t, 0)
+	data := coverPNG(t, 400, 600, color.RGBA{R: 180, A: 255})
+
+	var version string
+	bookRepo.On("FindByID", mock.Anything, uint(3)).Return(&models.Book{ID: 3, Version: 2}, nil).Once()
+	bookRepo.On("UpdateCover", mock.Anything, uint(3), uint(2), mock.AnythingOfType("string"), "image/png").
+		Run(func(args mock.Arguments) { version = args.String(3) }).Return(nil).Once()
+	bookRepo.On("FindByID", mock.Anything, uint(3)).Return(&models.Book{ID: 3, CoverVersion: "stored"}, nil).Once()
+
+	book, err := coverService.SetCover(context.Background(), 3, 2, bytes.NewReader(data))
+
+	require.NoError(t, err)
+	assert.Equal(t, "stored", book.CoverVersion)
+	require.Len(t, version, 16)
+	assert.ElementsMatch(t, []string{
+		"covers/3/" + version + "/original",
+		"covers/3/" + version + "/small",
+		"covers/3/" + version + "/medium",
+		"covers/3/" + version + "/large",
+	}, storedFiles(t, dir))
+	bookRepo.AssertExpectations(t)
+
+	bookRepo.On("FindByID", mock.Anything, uint(3)).Return(&models.Book{ID: 3, CoverVersion: version, CoverType: "image/png"}, nil).Once()
+	cover, err := coverService.GetCover(context.Background(), 3, "small")
+	require.NoError(t, err)
+	defer cover.Body.Close()
+	assert.Equal(t, "image/jpeg", cover.ContentType)
+	config, _, err := image.DecodeConfig(cover.Body)
+	require.NoError(t, err)
+	assert.Equal(t, models.CoverWidths[models.CoverSmall], config.Width)
+}
+
+func TestCoverService_SetCoverReplacesPreviousCover(t *testing.T) {
+	bookRepo, dir, coverService := newCoverService(t, 0)
+	ctx := context.Background()
+	first := coverPNG(t, 60, 90, color.RGBA{G: 100, A: 255})
+	second := coverPNG(t, 60, 90, color.RGBA{B: 100, A: 255})
+
+	var versions []string
+	bookRepo.On("UpdateCover", mock.Anything, uint(5), uint(0), mock.AnythingOfType("string"), "image/png").
+		Run(func(args mock.Arguments) { versions = append(versions, args.String(3)) }).Return(nil)
+	bookRepo.On("FindByID", mock.Anything, uint(5)).Return(&models.Book{ID: 5}, nil).Twice()
+
+	_, err := coverService.SetCover(ctx, 5, 0, bytes.NewReader(first))
+	require.NoError(t, err)
+
+	bookRepo.On("FindByID", mock.Anything, uint(5)).Return(&models.Book{ID: 5, CoverVersion: versions[0], CoverType: "image/png"}, nil)
+	_, err = coverService.SetCover(ctx, 5, 0, bytes.NewReader(second))
+	require.NoError(t, err)
+
+	require.Len(t, versions, 2)
+	assert.NotEqual(t, versions[0], versions[1])
+	for _, file := range storedFiles(t, dir) {
+		assert.Contains(t, file, versions[1], "files of the replaced cover are deleted")
+	}
+}
+
+func TestCoverService_SetCoverRejectsInvalidUploads(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+		code string
+	}{
+		{"empty", nil, apperror.CodeBadRequest},
+		{"text", []byte("<html><body>not a cover</body></html>"), apperror.CodeUnsupported},
+		{"webp", append([]byte("RIFF\x00\x00\x00\x00WEBPVP8 "), make([]byte, 32)...), apperror.CodeUnsupported},
+		{"too large", bytes.Repeat([]byte{0xff}, 2048), apperror.CodeTooLarge},
+		{"truncated jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), apperror.CodeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookRepo, dir, coverService := newCoverService(t, 1024)
+			bookRepo.On("FindByID", mock.Anything, uint(1)).Return(&models.Book{ID: 1}, nil).Maybe()
+
+			_, err := coverService.SetCover(context.Background(), 1, 0, bytes.NewReader(tt.body))
+
+			var appErr *apperror.AppError
+			if assert.ErrorAs(t, err, &appErr) {
+				assert.Equal(t, tt.code, appErr.Code)
+			}
+			assert.Empty(t, storedFiles(t, dir))
+			bookRepo.AssertNotCalled(t, "UpdateCover", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCoverService_SetCoverChecksTheBookVersion(t *testing.T) {
+	bookRepo, dir, coverService := newCoverService(t, 0)
+	ctx := context.Background()
+	data := coverPNG(t, 60, 90, color.RGBA{R: 100, A: 255})
+	bookRepo.On("FindByID", mock.Anything, uint(4)).Return(&models.Book{ID: 4, Version: 3}, nil)
+
+	_, err := coverService.SetCover(ctx, 4, 2, bytes.NewReader(data))
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodePreconditionFailed, appErr.Code)
+
+	// A book changed between the read and the write keeps its cover.
+	bookRepo.On("UpdateCover", mock.Anything, uint(4), uint(3), mock.AnythingOfType("string"), "image/png").
+		Return(repository.ErrStaleVersion).Once()
+	_, err = coverService.SetCover(ctx, 4, 3, bytes.NewReader(data))
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodePreconditionFailed, appErr.Code)
+	assert.Empty(t, storedFiles(t, dir))
+
+	err = coverService.DeleteCover(ctx, 4, 2)
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodePreconditionFailed, appErr.Code)
+}
+
+func TestCoverService_GetCover(t *testing.T) {
+	bookRepo, _, coverService := newCoverService(t, 0)
+	bookRepo.On("FindByID", mock.Anything, uint(1)).Return(&models.Book{ID: 1}, nil)
+
+	_, err := coverService.GetCover(context.Background(), 1, "huge")
+	assert.ErrorContains(t, err, "cover size not found")
+
+	_, err = coverService.GetCover(context.Background(), 1, "")
+	assert.ErrorContains(t, err, "cover not found")
+}
+
+func TestCoverService_GetCoverRedirectsToPublicURL(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir(), "https://cdn.example.com")
+	require.NoError(t, err)
+	bookRepo := new(MockBookRepository)
+	bookRepo.On("FindByID", mock.Anything, uint(2)).Return(&models.Book{ID: 2, CoverVersion: "abc", CoverType: "image/gif"}, nil)
+
+	cover, err := service.NewCoverService(bookRepo, store, 0).GetCover(context.Background(), 2, "")
+
+	require.NoError(t, err)
+	assert.Nil(t, cover.Body)
+	assert.Equal(t, "https://cdn.example.com/covers/2/abc/original", cover.Redirect)
+	assert.Equal(t, "image/gif", cover.ContentType)
+}
@@ -0,0 +1,230 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type MockHoldRepository struct {
+	mock.Mock
+}
+
+func (m *MockHoldRepository) WithTx(tx *gorm.DB) repository.HoldRepository {
+	args := m.Called(tx)
+	return args.Get(0).(repository.HoldRepository)
+}
+
+func (m *MockHoldRepository) Create(ctx context.Context, hold *models.Hold) error {
+	args := m.Called(ctx, hold)
+	return args.Error(0)
+}
+
+func (m *MockHoldRepository) Update(ctx context.Context, hold *models.Hold) error {
+	args := m.Called(ctx, hold)
+	return args.Error(0)
+}
+
+func (m *MockHoldRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.Hold, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Hold), args.Error(1)
+}
+
+func (m *MockHoldRepository) FindActive(ctx context.Context, userID, bookID, workID uint) (*models.Hold, error) {
+	args := m.Called(ctx, userID, bookID, workID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Hold), args.Error(1)
+}
+
+func (m *MockHoldRepository) FindReady(ctx context.Context, userID, bookID uint) (*models.Hold, error) {
+	args := m.Called(ctx, userID, bookID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Hold), args.Error(1)
+}
+
+func (m *MockHoldRepository) NextWaiting(ctx context.Context, book *models.Book) (*models.Hold, error) {
+	args := m.Called(ctx, book)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Hold), args.Error(1)
+}
+
+func (m *MockHoldRepository) ListByUser(ctx context.Context, userID uint, req query.PageRequest) ([]models.Hold, query.PageInfo, error) {
+	args := m.Called(ctx, userID, req)
+	return args.Get(0).([]models.Hold), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockHoldRepository) List(ctx context.Context, status string, req query.PageRequest) ([]models.Hold, query.PageInfo, error) {
+	args := m.Called(ctx, status, req)
+	return args.Get(0).([]models.Hold), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func newHoldService(t *testing.T) (*MockHoldRepository, *MockBookRepository, *MockWorkRepository, *MockUserRepository, service.HoldService) {
+	t.Helper()
+
+	holdRepo := new(MockHoldRepository)
+	bookRepo := new(MockBookRepository)
+	workRepo := new(MockWorkRepository)
+	userRepo := new(MockUserRepository)
+	holdRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(holdRepo).Maybe()
+	bookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(bookRepo).Maybe()
+	workRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(workRepo).Maybe()
+	userRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(userRepo).Maybe()
+	copyRepo := new(MockCopyRepository)
+	copyRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(copyRepo).Maybe()
+	gormDB, sqlMock := newMockDB(t)
+	sqlMock.MatchExpectationsInOrder(false)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+	sqlMock.ExpectRollback()
+	return holdRepo, bookRepo, workRepo, userRepo, service.NewHoldService(gormDB, holdRepo, bookRepo, workRepo, userRepo, copyRepo)
+}
+
+func TestHoldService_PlaceHold_AnyEdition(t *testing.T) {
+	holdRepo, _, workRepo, userRepo, holdService := newHoldService(t)
+
+	userRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(&models.User{ID: 1, IsActive: true}, nil)
+	workRepo.On("FindByID", mock.Anything, uint(3)).Return(&models.Work{ID: 3, Editions: []models.Book{
+		{ID: 10, TotalCopies: 1, AvailableCopies: 0},
+		{ID: 11, TotalCopies: 2, AvailableCopies: 0},
+	}}, nil).Once()
+	holdRepo.On("FindActive", mock.Anything, uint(1), uint(0), uint(3)).Return(nil, gorm.ErrRecordNotFound).Once()
+	holdRepo.On("Create", mock.Anything, mock.MatchedBy(func(hold *models.Hold) bool {
+		return hold.UserID == 1 && hold.WorkID != nil && *hold.WorkID == 3 && hold.BookID == nil && hold.Status == models.HoldWaiting
+	})).Return(nil).Once()
+
+	hold, err := holdService.PlaceHold(context.Background(), 1, dto.PlaceHoldRequest{WorkID: 3})
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldWaiting, hold.Status)
+	holdRepo.AssertExpectations(t)
+}
+
+func TestHoldService_PlaceHold_EditionAvailable(t *testing.T) {
+	holdRepo, _, workRepo, userRepo, holdService := newHoldService(t)
+
+	userRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(&models.User{ID: 1, IsActive: true}, nil)
+	workRepo.On("FindByID", mock.Anything, uint(3)).Return(&models.Work{ID: 3, Editions: []models.Book{
+		{ID: 10, TotalCopies: 1, AvailableCopies: 0},
+		{ID: 11, TotalCopies: 2, AvailableCopies: 1},
+	}}, nil).Once()
+
+	_, err := holdService.PlaceHold(context.Background(), 1, dto.PlaceHoldRequest{WorkID: 3})
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	holdRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestHoldService_CancelHold_PassesCopyOn(t *testing.T) {
+	holdRepo, bookRepo, _, _, holdService := newHoldService(t)
+	bookID := uint(10)
+	book := &models.Book{ID: bookID, TotalCopies: 1, AvailableCopies: 0}
+	next := &models.Hold{ID: 6, UserID: 2, Status: models.HoldWaiting}
+
+	holdRepo.On("FindByIDForUpdate", mock.Anything, uint(5)).
+		Return(&models.Hold{ID: 5, UserID: 1, BookID: &bookID, Status: models.HoldReady, ReadyBookID: &bookID}, nil).Once()
+	holdRepo.On("Update", mock.Anything, mock.MatchedBy(func(hold *models.Hold) bool {
+		return hold.ID == 5 && hold.Status == models.HoldCancelled
+	})).Return(nil).Once()
+	bookRepo.On("FindByIDForUpdate", mock.Anything, bookID).Return(book, nil).Once()
+	holdRepo.On("NextWaiting", mock.Anything, book).Return(next, nil).Once()
+	holdRepo.On("Update", mock.Anything, next).Return(nil).Once()
+
+	hold, err := holdService.CancelHold(context.Background(), 1, "member", 5)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldCancelled, hold.Status)
+	assert.Equal(t, models.HoldReady, next.Status)
+	assert.Equal(t, &bookID, next.ReadyBookID)
+	assert.Zero(t, book.AvailableCopies, "the copy stays off the shelf")
+	bookRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	holdRepo.AssertExpectations(t)
+}
+
+func TestHoldService_CancelHold_NotOwner(t *testing.T) {
+	holdRepo, _, _, _, holdService := newHoldService(t)
+
+	holdRepo.On("FindByIDForUpdate", mock.Anything, uint(5)).
+		Return(&models.Hold{ID: 5, UserID: 1, Status: models.HoldWaiting}, nil).Once()
+
+	_, err := holdService.CancelHold(context.Background(), 2, "member", 5)
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeForbidden, appErr.Code)
+	holdRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestBorrowService_BorrowBook_FulfilsReadyHold(t *testing.T) {
+	borrowRepo, bookRepo, userRepo, holdRepo, sqlMock, borrowService := newBorrowHoldService(t)
+	book := &models.Book{ID: 1, TotalCopies: 1, AvailableCopies: 0}
+	hold := &models.Hold{ID: 4, UserID: 1, Status: models.HoldReady}
+
+	sqlMock.ExpectBegin()
+	userRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(userRepo).Once()
+	bookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(bookRepo).Once()
+	borrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(borrowRepo).Once()
+	holdRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(holdRepo).Once()
+	userRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(&models.User{ID: 1, IsActive: true}, nil).Once()
+	borrowRepo.On("CountActiveByUser", mock.Anything, uint(1)).Return(int64(0), nil).Once()
+	bookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+	holdRepo.On("FindReady", mock.Anything, uint(1), uint(1)).Return(hold, nil).Once()
+	borrowRepo.On("FindActiveByUserAndBook", mock.Anything, uint(1), uint(1)).Return((*models.BorrowRecord)(nil), gorm.ErrRecordNotFound).Once()
+	holdRepo.On("Update", mock.Anything, hold).Return(nil).Once()
+	borrowRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.BorrowRecord")).Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	_, err := borrowService.BorrowBook(context.Background(), 1, dto.BorrowBookRequest{BookID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldFulfilled, hold.Status)
+	assert.Zero(t, book.AvailableCopies)
+	bookRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	holdRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBorrowService_ReturnBook_SetsCopyAsideForHold(t *testing.T) {
+	borrowRepo, bookRepo, _, holdRepo, sqlMock, borrowService := newBorrowHoldService(t)
+	book := &models.Book{ID: 1, TotalCopies: 2, AvailableCopies: 0}
+	record := &models.BorrowRecord{ID: 3, UserID: 1, BookID: uintPtr(1), BorrowDate: time.Now(), DueDate: time.Now().Add(time.Hour), Status: models.StatusBorrowed}
+	waiting := &models.Hold{ID: 8, UserID: 2, Status: models.HoldWaiting}
+
+	sqlMock.ExpectBegin()
+	bookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(bookRepo).Once()
+	borrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(borrowRepo).Once()
+	holdRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(holdRepo).Once()
+	borrowRepo.On("FindByIDForUpdate", mock.Anything, uint(3)).Return(record, nil).Once()
+	bookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+	holdRepo.On("NextWaiting", mock.Anything, book).Return(waiting, nil).Once()
+	holdRepo.On("Update", mock.Anything, waiting).Return(nil).Once()
+	borrowRepo.On("Update", mock.Anything, record).Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	_, _, err := borrowService.ReturnBook(context.Background(), 1, "member", dto.ReturnBookRequest{BorrowRecordID: 3})
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldReady, waiting.Status)
+	assert.Equal(t, uint(1), *waiting.ReadyBookID)
+	assert.NotNil(t, waiting.ReadyAt)
+	assert.Zero(t, book.AvailableCopies, "the returned copy is held, not shelved")
+	bookRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -0,0 +1,168 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func newMaintenanceService(t *testing.T) (*MockBookRepository, *MockBorrowRepository, *MockBookRevisionRepository, sqlmock.Sqlmock, service.MaintenanceService) {
+	t.Helper()
+
+	mockBookRepo := new(MockBookRepository)
+	mockBookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBookRepo).Maybe()
+	mockRevisionRepo := new(MockBookRevisionRepository)
+	mockRevisionRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRevisionRepo).Maybe()
+	mockBorrowRepo := new(MockBorrowRepository)
+	gormDB, sqlMock := newMockDB(t)
+	return mockBookRepo, mockBorrowRepo, mockRevisionRepo, sqlMock, service.NewMaintenanceService(gormDB, mockBookRepo, mockRevisionRepo, mockBorrowRepo)
+}
+
+func TestMaintenanceService_CheckIntegrity_NoIssues(t *testing.T) {
+	mockBookRepo, mockBorrowRepo, _, _, maintenanceService := newMaintenanceService(t)
+
+	mockBorrowRepo.On("CountActiveByBook", mock.Anything).Return(map[uint]int64{1: 2}, nil).Once()
+	mockBookRepo.On("Each", mock.Anything, mock.Anything, mock.Anything).
+		Return([][]models.Book{{
+			{ID: 1, ISBN: "9781234567897", TotalCopies: 5, AvailableCopies: 3},
+			{ID: 2, ISBN: "9780306406157", TotalCopies: 1, AvailableCopies: 1},
+			// Every copy was lost or damaged.
+			{ID: 3, ISBN: "9780441172719", TotalCopies: 0, AvailableCopies: 0},
+		}}, nil).
+		Once()
+
+	issues, err := maintenanceService.CheckIntegrity(context.Background())
+
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+	mockBookRepo.AssertExpectations(t)
+	mockBorrowRepo.AssertExpectations(t)
+}
+
+func TestMaintenanceService_CheckIntegrity_ReportsMismatches(t *testing.T) {
+	mockBookRepo, mockBorrowRepo, _, _, maintenanceService := newMaintenanceService(t)
+
+	mockBorrowRepo.On("CountActiveByBook", mock.Anything).Return(map[uint]int64{1: 1, 9: 1}, nil).Once()
+	mockBookRepo.On("Each", mock.Anything, mock.Anything, mock.Anything).
+		Return([][]models.Book{{
+			{ID: 1, ISBN: "9781234567897", TotalCopies: 5, AvailableCopies: 2},
+			{ID: 2, ISBN: "9780306406157", TotalCopies: 2, AvailableCopies: 3},
+		}}, nil).
+		Once()
+
+	issues, err := maintenanceService.CheckIntegrity(context.Background())
+
+	assert.NoError(t, err)
+	if assert.Len(t, issues, 4) {
+		assert.Equal(t, "stock_vs_active_borrows", issues[0].Check)
+		assert.Equal(t, uint(1), issues[0].BookID)
+		assert.Equal(t, "stock_bounds", issues[1].Check)
+		assert.Equal(t, uint(2), issues[1].BookID)
+		assert.Equal(t, "stock_vs_active_borrows", issues[2].Check)
+		assert.Equal(t, "orphaned_borrows", issues[3].Check)
+		assert.Equal(t, uint(9), issues[3].BookID)
+	}
+}
+
+func TestMaintenanceService_CheckIntegrity_ReportsNonCanonicalISBNs(t *testing.T) {
+	mockBookRepo, mockBorrowRepo, _, _, maintenanceService := newMaintenanceService(t)
+
+	mockBorrowRepo.On("CountActiveByBook", mock.Anything).Return(map[uint]int64{}, nil).Once()
+	mockBookRepo.On("Each", mock.Anything, mock.Anything, mock.Anything).
+		Return([][]models.Book{{
+			{ID: 1, ISBN: "0306406152", TotalCopies: 1, AvailableCopies: 1},
+			{ID: 2, ISBN: "9780306406158", TotalCopies: 1, AvailableCopies: 1},
+		}}, nil).
+		Once()
+
+	issues, err := maintenanceService.CheckIntegrity(context.Background())
+
+	assert.NoError(t, err)
+	if assert.Len(t, issues, 2) {
+		assert.Equal(t, "isbn_format", issues[0].Check)
+		assert.Contains(t, issues[0].Message, "should be stored as 9780306406157")
+		assert.Equal(t, "isbn_format", issues[1].Check)
+		assert.Contains(t, issues[1].Message, "invalid")
+	}
+}
+
+func TestMaintenanceService_NormalizeISBNs(t *testing.T) {
+	mockBookRepo, _, mockRevisionRepo, sqlMock, maintenanceService := newMaintenanceService(t)
+
+	mockBookRepo.On("Each", mock.Anything, mock.Anything, mock.Anything).
+		Return([][]models.Book{{
+			{ID: 1, ISBN: "9781234567897"},
+			{ID: 2, ISBN: "0306406152"},
+			{ID: 3, ISBN: "978-0-306-40615-7"},
+			{ID: 4, ISBN: "12345"},
+			{ID: 5, ISBN: "080442957X"},
+		}}, nil).
+		Once()
+	mockBookRepo.On("FindByISBN", mock.Anything, "9780306406157").
+		Return((*models.Book)(nil), gorm.ErrRecordNotFound).Once()
+	mockBookRepo.On("FindByISBN", mock.Anything, "9780804429573").
+		Return(&models.Book{ID: 8, ISBN: "9780804429573"}, nil).Once()
+	sqlMock.ExpectBegin()
+	mockBookRepo.On("FindByIDForUpdate", mock.Anything, uint(2)).
+		Return(&models.Book{ID: 2, ISBN: "0306406152", Version: 3}, nil).Once()
+	mockBookRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Book")).
+		Run(func(args mock.Arguments) {
+			book := args.Get(1).(*models.Book)
+			assert.Equal(t, uint(2), book.ID)
+			assert.Equal(t, "9780306406157", book.ISBN)
+			book.Version++
+		}).
+		Return(nil).Once()
+	mockBookRepo.On("FindByID", mock.Anything, uint(2)).
+		Return(&models.Book{ID: 2, ISBN: "9780306406157", Version: 4}, nil).Once()
+	mockRevisionRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.BookRevision")).
+		Run(func(args mock.Arguments) {
+			revision := args.Get(1).(*models.BookRevision)
+			assert.Equal(t, models.RevisionUpdate, revision.Action)
+			assert.Equal(t, uint(4), revision.Version)
+			assert.Equal(t, uint(0), revision.ActorID)
+			assert.Contains(t, string(revision.Changes), `"field":"isbn"`)
+		}).
+		Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	results, err := maintenanceService.NormalizeISBNs(context.Background(), true)
+
+	assert.NoError(t, err)
+	if assert.Len(t, results, 4) {
+		assert.Equal(t, service.ISBNNormalized, results[0].Status)
+		assert.Equal(t, uint(2), results[0].BookID)
+		assert.Equal(t, service.ISBNDuplicate, results[1].Status)
+		assert.Equal(t, uint(3), results[1].BookID)
+		assert.Equal(t, service.ISBNInvalid, results[2].Status)
+		assert.Equal(t, service.ISBNDuplicate, results[3].Status)
+		assert.Equal(t, "same ISBN as book 8", results[3].Message)
+	}
+	mockBookRepo.AssertExpectations(t)
+	mockRevisionRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestMaintenanceService_NormalizeISBNs_DryRunDoesNotWrite(t *testing.T) {
+	mockBookRepo, _, _, _, maintenanceService := newMaintenanceService(t)
+
+	mockBookRepo.On("Each", mock.Anything, mock.Anything, mock.Anything).
+		Return([][]models.Book{{{ID: 2, ISBN: "0306406152"}}}, nil).Once()
+	mockBookRepo.On("FindByISBN", mock.Anything, "9780306406157").
+		Return((*models.Book)(nil), gorm.ErrRecordNotFound).Once()
+
+	results, err := maintenanceService.NormalizeISBNs(context.Background(), false)
+
+	assert.NoError(t, err)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, service.ISBNWouldNormalize, results[0].Status)
+		assert.Equal(t, "9780306406157", results[0].To)
+	}
+	mockBookRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,88 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionService_PurgeDetachesBorrowHistory(t *testing.T) {
+	bookRepo := new(MockBookRepository)
+	borrowRepo := new(MockBorrowRepository)
+	userRepo := new(MockUserRepository)
+	revisionRepo := new(MockBookRevisionRepository)
+	bookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(bookRepo).Maybe()
+	borrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(borrowRepo).Maybe()
+	revisionRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(revisionRepo).Maybe()
+	gormDB, sqlMock := newMockDB(t)
+	retention := service.NewRetentionService(gormDB, bookRepo, revisionRepo, borrowRepo, userRepo, nil, 24*time.Hour)
+
+	bookRepo.On("RemovedBefore", mock.Anything, mock.AnythingOfType("time.Time")).
+		Return([]models.Book{{ID: 1}, {ID: 2, Title: "Read Once", ISBN: "9780306406157"}}, nil)
+	borrowRepo.On("CountByBook", mock.Anything, uint(1)).Return(int64(0), nil)
+	borrowRepo.On("CountByBook", mock.Anything, uint(2)).Return(int64(3), nil)
+	userRepo.On("DeletedBefore", mock.Anything, mock.AnythingOfType("time.Time")).
+		Return([]models.User{{ID: 5, Username: "alice", Email: "alice@example.com"}}, nil)
+
+	report, err := retention.Purge(context.Background(), false)
+	require.NoError(t, err)
+	assert.False(t, report.Applied)
+	assert.Equal(t, []uint{1, 2}, report.PurgedBooks)
+	assert.Equal(t, int64(3), report.DetachedBorrows)
+	assert.Equal(t, []uint{5}, report.AnonymizedUsers)
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), report.Cutoff, time.Minute)
+	bookRepo.AssertNotCalled(t, "Purge", mock.Anything, mock.Anything)
+	borrowRepo.AssertNotCalled(t, "DetachBook", mock.Anything, mock.Anything)
+	userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+
+	for _, id := range []uint{1, 2} {
+		sqlMock.ExpectBegin()
+		borrowRepo.On("DetachBook", mock.Anything, mock.MatchedBy(func(book *models.Book) bool { return book.ID == id })).
+			Return(int64(id-1)*3, nil).Once()
+		revisionRepo.On("DeleteByBook", mock.Anything, id).Return(nil).Once()
+		bookRepo.On("Purge", mock.Anything, id).Return(nil).Once()
+		sqlMock.ExpectCommit()
+	}
+	userRepo.On("Update", mock.Anything, mock.MatchedBy(func(user *models.User) bool {
+		return user.Username == "deleted-user-5" && user.Email == "deleted-user-5@invalid" && user.PurgedAt != nil
+	})).Return(nil).Once()
+
+	report, err = retention.Purge(context.Background(), true)
+	require.NoError(t, err)
+	assert.True(t, report.Applied)
+	assert.Equal(t, int64(3), report.DetachedBorrows)
+	bookRepo.AssertExpectations(t)
+	borrowRepo.AssertExpectations(t)
+	revisionRepo.AssertExpectations(t)
+	userRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestRetentionService_PurgeRollsBackFailedBook(t *testing.T) {
+	bookRepo := new(MockBookRepository)
+	borrowRepo := new(MockBorrowRepository)
+	revisionRepo := new(MockBookRevisionRepository)
+	bookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(bookRepo).Maybe()
+	borrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(borrowRepo).Maybe()
+	revisionRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(revisionRepo).Maybe()
+	gormDB, sqlMock := newMockDB(t)
+	retention := service.NewRetentionService(gormDB, bookRepo, revisionRepo, borrowRepo, new(MockUserRepository), nil, 24*time.Hour)
+
+	bookRepo.On("RemovedBefore", mock.Anything, mock.AnythingOfType("time.Time")).Return([]models.Book{{ID: 1}}, nil)
+	sqlMock.ExpectBegin()
+	borrowRepo.On("DetachBook", mock.Anything, mock.AnythingOfType("*models.Book")).Return(int64(2), nil).Once()
+	revisionRepo.On("DeleteByBook", mock.Anything, uint(1)).Return(nil).Once()
+	bookRepo.On("Purge", mock.Anything, uint(1)).Return(errors.New("constraint violation")).Once()
+	sqlMock.ExpectRollback()
+
+	_, err := retention.Purge(context.Background(), true)
+	assert.EqualError(t, err, "failed to purge book 1")
+	assert.NoError(t, sqlMock.ExpectationsWereMet(), "history and borrows are restored with the book")
+}
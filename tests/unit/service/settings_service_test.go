@@ -0,0 +1,285 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+)
+
+type MockSettingRepository struct {
+	mock.Mock
+}
+
+func (m *MockSettingRepository) WithTx(tx *gorm.DB) repository.SettingRepository {
+	args := m.Called(tx)
+	return args.Get(0).(repository.SettingRepository)
+}
+
+func (m *MockSettingRepository) List(ctx context.Context) ([]models.Setting, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.Setting), args.Error(1)
+}
+
+func (m *MockSettingRepository) FindByKey(ctx context.Context, key string) (*models.Setting, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Setting), args.Error(1)
+}
+
+func (m *MockSettingRepository) Create(ctx context.Context, setting *models.Setting) error {
+	args := m.Called(ctx, setting)
+	return args.Error(0)
+}
+
+func (m *MockSettingRepository) Update(ctx context.Context, setting *models.Setting) error {
+	args := m.Called(ctx, setting)
+	return args.Error(0)
+}
+
+func (m *MockSettingRepository) Delete(ctx context.Context, setting *models.Setting) error {
+	args := m.Called(ctx, setting)
+	return args.Error(0)
+}
+
+func (m *MockSettingRepository) ChangeCounts(ctx context.Context) (map[string]int64, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
+func (m *MockSettingRepository) CreateChange(ctx context.Context, change *models.SettingChange) error {
+	args := m.Called(ctx, change)
+	return args.Error(0)
+}
+
+func (m *MockSettingRepository) ListChanges(ctx context.Context, key string, req query.PageRequest) ([]models.SettingChange, query.PageInfo, error) {
+	args := m.Called(ctx, key, req)
+	return args.Get(0).([]models.SettingChange), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockSettingRepository) Revision(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+var defaultCirculation = service.BorrowServiceConfig{
+	MaxBooksPerUser: 5,
+	BorrowDays:      14,
+	FinePerDay:      1000,
+}
+
+func newSettingsService(t *testing.T, refresh time.Duration) (*MockSettingRepository, sqlmock.Sqlmock, service.SettingsService) {
+	t.Helper()
+
+	mockRepo := new(MockSettingRepository)
+	gormDB, sqlMock := newMockDB(t)
+	svc := service.NewSettingsService(gormDB, mockRepo, defaultCirculation, refresh)
+
+	return mockRepo, sqlMock, svc
+}
+
+func TestSettingsService_Circulation_AppliesOverridesAndCaches(t *testing.T) {
+	mockRepo, _, svc := newSettingsService(t, time.Hour)
+
+	mockRepo.On("Revision", mock.Anything).Return(int64(3), nil).Once()
+	mockRepo.On("List", mock.Anything).Return([]models.Setting{
+		{Key: service.SettingBorrowDays, Value: "7"},
+		{Key: service.SettingFinePerDay, Value: "not-a-number"},
+		{Key: "retired_setting", Value: "1"},
+	}, nil).Once()
+
+	config := svc.Circulation(context.Background())
+	again := svc.Circulation(context.Background())
+
+	assert.Equal(t, 7, config.BorrowDays)
+	assert.Equal(t, 5, config.MaxBooksPerUser)
+	assert.Equal(t, 1000, config.FinePerDay, "invalid stored values fall back to the default")
+	assert.Equal(t, config, again)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSettingsService_Circulation_ReloadsWhenRevisionChanges(t *testing.T) {
+	mockRepo, _, svc := newSettingsService(t, 0)
+
+	mockRepo.On("Revision", mock.Anything).Return(int64(1), nil).Twice()
+	mockRepo.On("List", mock.Anything).Return([]models.Setting{{Key: service.SettingMaxBooksPerUser, Value: "2"}}, nil).Once()
+	assert.Equal(t, 2, svc.Circulation(context.Background()).MaxBooksPerUser)
+	assert.Equal(t, 2, svc.Circulation(context.Background()).MaxBooksPerUser)
+
+	mockRepo.On("Revision", mock.Anything).Return(int64(2), nil).Once()
+	mockRepo.On("List", mock.Anything).Return([]models.Setting{{Key: service.SettingMaxBooksPerUser, Value: "9"}}, nil).Once()
+	assert.Equal(t, 9, svc.Circulation(context.Background()).MaxBooksPerUser)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSettingsService_Circulation_KeepsLastValuesWhenDatabaseFails(t *testing.T) {
+	mockRepo, _, svc := newSettingsService(t, 0)
+
+	mockRepo.On("Revision", mock.Anything).Return(int64(1), nil).Once()
+	mockRepo.On("List", mock.Anything).Return([]models.Setting{{Key: service.SettingBorrowDays, Value: "21"}}, nil).Once()
+	require.Equal(t, 21, svc.Circulation(context.Background()).BorrowDays)
+
+	mockRepo.On("Revision", mock.Anything).Return(int64(0), errors.New("connection refused")).Once()
+	assert.Equal(t, 21, svc.Circulation(context.Background()).BorrowDays)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSettingsService_UpdateSetting_RecordsChange(t *testing.T) {
+	mockRepo, sqlMock, svc := newSettingsService(t, time.Hour)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	mockRepo.On("FindByKey", mock.Anything, service.SettingBorrowDays).
+		Return(&models.Setting{Key: service.SettingBorrowDays, Value: "14", Version: 2}, nil).Once()
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Setting")).
+		Run(func(args mock.Arguments) {
+			setting := args.Get(1).(*models.Setting)
+			assert.Equal(t, "21", setting.Value)
+			assert.Equal(t, uint(7), setting.UpdatedBy)
+			setting.Version++
+		}).
+		Return(nil).Once()
+	mockRepo.On("CreateChange", mock.Anything, mock.AnythingOfType("*models.SettingChange")).
+		Run(func(args mock.Arguments) {
+			change := args.Get(1).(*models.SettingChange)
+			require.NotNil(t, change.OldValue)
+			require.NotNil(t, change.NewValue)
+			assert.Equal(t, "14", *change.OldValue)
+			assert.Equal(t, "21", *change.NewValue)
+			assert.Equal(t, "summer holidays", change.Reason)
+		}).
+		Return(nil).Once()
+
+	setting, err := svc.UpdateSetting(context.Background(), 7, service.SettingBorrowDays, 2, dto.UpdateSettingRequest{
+		Value:  json.RawMessage(`21`),
+		Reason: "summer holidays",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 21, setting.Value)
+	assert.Equal(t, 14, setting.Default)
+	assert.True(t, setting.Overridden)
+	assert.Equal(t, uint(3), setting.Version)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSettingsService_UpdateSetting_RequiresTheCurrentVersion(t *testing.T) {
+	mockRepo, sqlMock, svc := newSettingsService(t, time.Hour)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+
+	// A setting at its default is at one more than its number of changes.
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	mockRepo.On("FindByKey", mock.Anything, service.SettingBorrowDays).Return(nil, gorm.ErrRecordNotFound).Once()
+	mockRepo.On("ChangeCounts", mock.Anything).Return(map[string]int64{service.SettingBorrowDays: 2}, nil).Once()
+
+	_, err := svc.UpdateSetting(context.Background(), 7, service.SettingBorrowDays, 2, dto.UpdateSettingRequest{Value: json.RawMessage(`21`)})
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodePreconditionFailed, appErr.Code)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSettingsService_UpdateSetting_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+		code  string
+	}{
+		{name: "unknown key", key: "loan_color", value: `1`, code: apperror.CodeNotFound},
+		{name: "not an integer", key: service.SettingBorrowDays, value: `"fourteen"`, code: apperror.CodeBadRequest},
+		{name: "fraction", key: service.SettingBorrowDays, value: `1.5`, code: apperror.CodeBadRequest},
+		{name: "below minimum", key: service.SettingMaxBooksPerUser, value: `0`, code: apperror.CodeBadRequest},
+		{name: "above maximum", key: service.SettingBorrowDays, value: `366`, code: apperror.CodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo, _, svc := newSettingsService(t, time.Hour)
+
+			_, err := svc.UpdateSetting(context.Background(), 1, tt.key, 0, dto.UpdateSettingRequest{Value: json.RawMessage(tt.value)})
+
+			var appErr *apperror.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, tt.code, appErr.Code)
+			mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestSettingsService_UpdateSetting_InvalidatesCache(t *testing.T) {
+	mockRepo, sqlMock, svc := newSettingsService(t, time.Hour)
+
+	mockRepo.On("Revision", mock.Anything).Return(int64(0), nil).Once()
+	mockRepo.On("List", mock.Anything).Return([]models.Setting{}, nil).Once()
+	require.Equal(t, 1000, svc.Circulation(context.Background()).FinePerDay)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	mockRepo.On("FindByKey", mock.Anything, service.SettingFinePerDay).Return(nil, gorm.ErrRecordNotFound).Once()
+	mockRepo.On("ChangeCounts", mock.Anything).Return(map[string]int64{}, nil).Once()
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(s *models.Setting) bool { return s.Value == "500" && s.Version == 2 })).Return(nil).Once()
+	mockRepo.On("CreateChange", mock.Anything, mock.AnythingOfType("*models.SettingChange")).Return(nil).Once()
+	setting, err := svc.UpdateSetting(context.Background(), 1, service.SettingFinePerDay, 1, dto.UpdateSettingRequest{Value: json.RawMessage(`500`)})
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), setting.Version)
+
+	mockRepo.On("Revision", mock.Anything).Return(int64(1), nil).Once()
+	mockRepo.On("List", mock.Anything).Return([]models.Setting{{Key: service.SettingFinePerDay, Value: "500"}}, nil).Once()
+	assert.Equal(t, 500, svc.Circulation(context.Background()).FinePerDay)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSettingsService_ResetSetting_RemovesOverride(t *testing.T) {
+	mockRepo, sqlMock, svc := newSettingsService(t, time.Hour)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+
+	mockRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockRepo).Once()
+	override := &models.Setting{Key: service.SettingBorrowDays, Value: "21", Version: 4}
+	mockRepo.On("FindByKey", mock.Anything, service.SettingBorrowDays).Return(override, nil).Once()
+	mockRepo.On("Delete", mock.Anything, override).Return(nil).Once()
+	mockRepo.On("CreateChange", mock.Anything, mock.AnythingOfType("*models.SettingChange")).
+		Run(func(args mock.Arguments) {
+			change := args.Get(1).(*models.SettingChange)
+			assert.Equal(t, "21", *change.OldValue)
+			assert.Nil(t, change.NewValue)
+		}).
+		Return(nil).Once()
+	mockRepo.On("FindByKey", mock.Anything, service.SettingBorrowDays).Return(nil, gorm.ErrRecordNotFound).Once()
+	mockRepo.On("ChangeCounts", mock.Anything).Return(map[string]int64{service.SettingBorrowDays: 4}, nil).Once()
+
+	setting, err := svc.ResetSetting(context.Background(), 1, service.SettingBorrowDays, 4, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, 14, setting.Value)
+	assert.False(t, setting.Overridden)
+	assert.Equal(t, uint(5), setting.Version)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,65 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/callnumber"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShelfService_BrowseBook_NearestAheadComesLast(t *testing.T) {
+	bookRepo, copyRepo, branchRepo := new(MockBookRepository), new(MockCopyRepository), new(MockBranchRepository)
+	shelfService := service.NewShelfService(bookRepo, copyRepo, branchRepo)
+	ctx := context.Background()
+
+	book := &models.Book{ID: 2, Title: "B", Classification: callnumber.LCC, CallNumber: "QA76.73", ShelfKey: "L QA 0076.73"}
+	bookRepo.On("FindByID", ctx, uint(2)).Return(book, nil)
+	bookRepo.On("Shelf", ctx, book.ShelfKey, uint(2), repository.ShelfFilter{BranchID: 4, Classification: callnumber.LCC}, 2, 1).
+		Return([]models.Book{{ID: 1, Title: "A2"}, {ID: 5, Title: "A1"}}, []models.Book{{ID: 3, Title: "C"}}, nil)
+
+	view, err := shelfService.BrowseBook(ctx, 2, dto.ShelfBrowseRequest{BranchID: 4, Before: 2, After: 1})
+	require.NoError(t, err)
+	require.Len(t, view.Before, 2)
+	assert.Equal(t, "A1", view.Before[0].Title)
+	assert.Equal(t, "A2", view.Before[1].Title)
+	assert.Equal(t, "B", view.Current.Title)
+	assert.Equal(t, "C", view.After[0].Title)
+}
+
+func TestShelfService_ShelfList_RangeSetsClassification(t *testing.T) {
+	bookRepo, copyRepo, branchRepo := new(MockBookRepository), new(MockCopyRepository), new(MockBranchRepository)
+	shelfService := service.NewShelfService(bookRepo, copyRepo, branchRepo)
+	ctx := context.Background()
+
+	from, err := callnumber.Parse("", "823")
+	require.NoError(t, err)
+	to, err := callnumber.Parse("", "824")
+	require.NoError(t, err)
+	bookRepo.On("ShelfList", ctx, repository.ShelfFilter{Classification: callnumber.DDC, FromKey: from.Key(), ToKey: to.Limit()}, 3).
+		Return([]models.Book{{ID: 1}, {ID: 2}, {ID: 3}}, nil)
+
+	list, err := shelfService.ShelfList(ctx, dto.ShelfListRequest{From: "823", To: "824", Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, callnumber.DDC, list.Classification)
+	assert.Len(t, list.Entries, 2)
+	assert.True(t, list.Truncated)
+	copyRepo.AssertNotCalled(t, "ShelfList", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestShelfService_ShelfList_RangeAcrossClassifications(t *testing.T) {
+	shelfService := service.NewShelfService(new(MockBookRepository), new(MockCopyRepository), new(MockBranchRepository))
+
+	_, err := shelfService.ShelfList(context.Background(), dto.ShelfListRequest{From: "823", To: "QA76"})
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeBadRequest, appErr.Code)
+	assert.Contains(t, appErr.Fields, "to")
+}
@@ -0,0 +1,210 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type MockStocktakeRepository struct {
+	mock.Mock
+}
+
+func (m *MockStocktakeRepository) WithTx(tx *gorm.DB) repository.StocktakeRepository {
+	args := m.Called(tx)
+	return args.Get(0).(repository.StocktakeRepository)
+}
+
+func (m *MockStocktakeRepository) Create(ctx context.Context, stocktake *models.Stocktake) error {
+	args := m.Called(ctx, stocktake)
+	return args.Error(0)
+}
+
+func (m *MockStocktakeRepository) Update(ctx context.Context, stocktake *models.Stocktake) error {
+	args := m.Called(ctx, stocktake)
+	return args.Error(0)
+}
+
+func (m *MockStocktakeRepository) FindByID(ctx context.Context, id uint) (*models.Stocktake, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Stocktake), args.Error(1)
+}
+
+func (m *MockStocktakeRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.Stocktake, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Stocktake), args.Error(1)
+}
+
+func (m *MockStocktakeRepository) FindOpen(ctx context.Context, branchID uint, location string) (*models.Stocktake, error) {
+	args := m.Called(ctx, branchID, location)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Stocktake), args.Error(1)
+}
+
+func (m *MockStocktakeRepository) List(ctx context.Context, filter repository.StocktakeFilter, req query.PageRequest) ([]models.Stocktake, query.PageInfo, error) {
+	args := m.Called(ctx, filter, req)
+	return args.Get(0).([]models.Stocktake), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockStocktakeRepository) AddScans(ctx context.Context, scans []models.StocktakeScan) (int64, error) {
+	args := m.Called(ctx, scans)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStocktakeRepository) Scans(ctx context.Context, stocktakeID uint) ([]models.StocktakeScan, error) {
+	args := m.Called(ctx, stocktakeID)
+	return args.Get(0).([]models.StocktakeScan), args.Error(1)
+}
+
+type stocktakeServiceMocks struct {
+	stocktakeRepo *MockStocktakeRepository
+	copyRepo      *MockCopyRepository
+	bookRepo      *MockBookRepository
+	transferRepo  *MockTransferRepository
+}
+
+func newStocktakeService(t *testing.T) (stocktakeServiceMocks, service.StocktakeService) {
+	t.Helper()
+
+	m := stocktakeServiceMocks{
+		stocktakeRepo: new(MockStocktakeRepository),
+		copyRepo:      new(MockCopyRepository),
+		bookRepo:      new(MockBookRepository),
+		transferRepo:  new(MockTransferRepository),
+	}
+	m.stocktakeRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.stocktakeRepo).Maybe()
+	m.copyRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.copyRepo).Maybe()
+	m.bookRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.bookRepo).Maybe()
+	m.transferRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(m.transferRepo).Maybe()
+	gormDB, sqlMock := newMockDB(t)
+	sqlMock.MatchExpectationsInOrder(false)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+	sqlMock.ExpectRollback()
+	return m, service.NewStocktakeService(gormDB, m.stocktakeRepo, m.copyRepo, m.bookRepo, new(MockBranchRepository), m.transferRepo, new(MockHoldRepository))
+}
+
+func TestStocktakeService_Scan_SkipsRepeatsAndReportsUnknown(t *testing.T) {
+	m, stocktakeService := newStocktakeService(t)
+	m.stocktakeRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(&models.Stocktake{ID: 1, Status: models.StocktakeOpen}, nil).Once()
+	m.copyRepo.On("FindByBarcodes", mock.Anything, []string{"M-1", "X-9", "M-2"}).
+		Return([]models.Copy{{ID: 11, Barcode: "M-1"}, {ID: 12, Barcode: "M-2"}}, nil).Once()
+	// M-2 was scanned in an earlier batch.
+	m.stocktakeRepo.On("AddScans", mock.Anything, mock.MatchedBy(func(scans []models.StocktakeScan) bool {
+		return len(scans) == 3 && *scans[0].CopyID == 11 && scans[1].CopyID == nil && scans[2].ScannedBy == 5
+	})).Return(int64(2), nil).Once()
+
+	result, err := stocktakeService.Scan(context.Background(), 1, 5, dto.StocktakeScanRequest{Barcodes: []string{"M-1", " X-9", "M-1", "M-2"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Recorded)
+	assert.Equal(t, 2, result.Duplicates)
+	assert.Equal(t, []string{"X-9"}, result.Unknown)
+}
+
+func TestStocktakeService_Report_SortsScans(t *testing.T) {
+	m, stocktakeService := newStocktakeService(t)
+	stocktake := &models.Stocktake{ID: 1, BranchID: 2, Location: "stacks", Status: models.StocktakeOpen}
+	stacks := &models.Book{ID: 1, Title: "Stacks", ShelfLocation: "Stacks"}
+	reference := &models.Book{ID: 2, Title: "Reference", ShelfLocation: "Reference"}
+	removed := &models.Book{ID: 3, Title: "Withdrawn", DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}
+	scan := func(barcode string, item *models.Copy) models.StocktakeScan {
+		s := models.StocktakeScan{StocktakeID: 1, Barcode: barcode, Copy: item}
+		if item != nil {
+			s.CopyID = &item.ID
+		}
+		return s
+	}
+	m.stocktakeRepo.On("FindByID", mock.Anything, uint(1)).Return(stocktake, nil).Once()
+	m.stocktakeRepo.On("Scans", mock.Anything, uint(1)).Return([]models.StocktakeScan{
+		scan("M-1", &models.Copy{ID: 1, BranchID: 2, Status: models.CopyAvailable, Book: stacks}),
+		scan("M-2", &models.Copy{ID: 2, BranchID: 3, Status: models.CopyAvailable, Book: stacks}),
+		scan("M-3", &models.Copy{ID: 3, BranchID: 2, Status: models.CopyAvailable, Book: reference}),
+		scan("M-4", &models.Copy{ID: 4, BranchID: 2, Status: models.CopyOnLoan, Book: stacks}),
+		scan("M-5", &models.Copy{ID: 5, BranchID: 2, Status: models.CopyMissing, Book: stacks}),
+		scan("M-6", &models.Copy{ID: 6, BranchID: 2, Status: models.CopyAvailable, Book: removed}),
+		scan("X-1", nil),
+	}, nil).Once()
+	m.copyRepo.On("ListOnShelf", mock.Anything, uint(2)).Return([]models.Copy{
+		{ID: 1, Barcode: "M-1", BranchID: 2, Book: stacks},
+		{ID: 3, Barcode: "M-3", BranchID: 2, Book: reference},
+		{ID: 7, Barcode: "M-7", BranchID: 2, Book: reference, ShelfLocation: "Stacks"},
+		{ID: 8, Barcode: "M-8", BranchID: 2, Book: stacks},
+	}, nil).Once()
+
+	report, err := stocktakeService.Report(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Expected)
+	assert.Equal(t, 7, report.Scanned)
+	assert.Equal(t, 1, report.Found)
+	barcodes := func(items []dto.StocktakeItem) []string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.Barcode)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"M-7", "M-8"}, barcodes(report.Missing))
+	assert.Equal(t, []string{"M-2", "M-3"}, barcodes(report.Misplaced))
+	assert.Equal(t, []string{"M-4"}, barcodes(report.CheckedOut))
+	assert.Equal(t, []string{"M-5"}, barcodes(report.Recovered))
+	assert.Equal(t, []string{"M-6", "X-1"}, barcodes(report.Unexpected))
+	assert.Equal(t, "Reference", report.Misplaced[1].ShelfLocation)
+}
+
+func TestStocktakeService_MarkMissing_OnlyMissingCopies(t *testing.T) {
+	m, stocktakeService := newStocktakeService(t)
+	book := &models.Book{ID: 1, Title: "Stacks"}
+	m.stocktakeRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(&models.Stocktake{ID: 1, BranchID: 2, Status: models.StocktakeCompleted}, nil).Once()
+	m.stocktakeRepo.On("Scans", mock.Anything, uint(1)).Return([]models.StocktakeScan{
+		{Barcode: "M-1", CopyID: new(uint), Copy: &models.Copy{ID: 1, BranchID: 2, Status: models.CopyAvailable, Book: book}},
+	}, nil).Once()
+	m.copyRepo.On("ListOnShelf", mock.Anything, uint(2)).Return([]models.Copy{{ID: 1, Barcode: "M-1", BranchID: 2, Book: book}}, nil).Once()
+
+	_, err := stocktakeService.MarkMissing(context.Background(), 1, dto.StocktakeCopiesRequest{CopyIDs: []uint{1}})
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	m.copyRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	m.bookRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestStocktakeService_FixLocation_KeepsOtherBranchCopyHeld(t *testing.T) {
+	m, stocktakeService := newStocktakeService(t)
+	book := &models.Book{ID: 1, Title: "Stacks", ShelfLocation: "Stacks"}
+	copyID := uint(1)
+	// The copy of branch 3 waits at branch 2 for a hold, shelved out of place.
+	item := &models.Copy{ID: 1, BookID: 1, HomeBranchID: 3, BranchID: 2, Status: models.CopyOnHold, ShelfLocation: "Desk", Book: book}
+	m.stocktakeRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(&models.Stocktake{ID: 1, BranchID: 2, Location: "stacks", Status: models.StocktakeCompleted}, nil).Once()
+	m.stocktakeRepo.On("Scans", mock.Anything, uint(1)).Return([]models.StocktakeScan{{Barcode: "E-1", CopyID: &copyID, Copy: item}}, nil).Once()
+	m.copyRepo.On("ListOnShelf", mock.Anything, uint(2)).Return([]models.Copy{}, nil).Once()
+	m.copyRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(item, nil).Once()
+	m.transferRepo.On("FindOpen", mock.Anything, uint(1)).Return(nil, gorm.ErrRecordNotFound).Once()
+	m.bookRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(book, nil).Once()
+
+	_, err := stocktakeService.FixLocation(context.Background(), 1, dto.StocktakeCopiesRequest{CopyIDs: []uint{1}})
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	assert.Equal(t, uint(3), item.HomeBranchID)
+	m.copyRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	m.transferRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,248 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type MockSubjectRepository struct {
+	mock.Mock
+}
+
+func (m *MockSubjectRepository) WithTx(tx *gorm.DB) repository.SubjectRepository {
+	args := m.Called(tx)
+	return args.Get(0).(repository.SubjectRepository)
+}
+
+func (m *MockSubjectRepository) Create(ctx context.Context, subject *models.Subject) error {
+	args := m.Called(ctx, subject)
+	return args.Error(0)
+}
+
+func (m *MockSubjectRepository) Update(ctx context.Context, subject *models.Subject) error {
+	args := m.Called(ctx, subject)
+	return args.Error(0)
+}
+
+func (m *MockSubjectRepository) Delete(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockSubjectRepository) FindByID(ctx context.Context, id uint) (*models.Subject, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Subject), args.Error(1)
+}
+
+func (m *MockSubjectRepository) FindByIDs(ctx context.Context, ids []uint) ([]models.Subject, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]models.Subject), args.Error(1)
+}
+
+func (m *MockSubjectRepository) FindByNameKey(ctx context.Context, key string) ([]models.Subject, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).([]models.Subject), args.Error(1)
+}
+
+func (m *MockSubjectRepository) FindChildByNameKey(ctx context.Context, parentID *uint, key string) (*models.Subject, error) {
+	args := m.Called(ctx, parentID, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Subject), args.Error(1)
+}
+
+func (m *MockSubjectRepository) List(ctx context.Context) ([]models.Subject, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.Subject), args.Error(1)
+}
+
+func (m *MockSubjectRepository) ListChildren(ctx context.Context, parentID uint) ([]models.Subject, error) {
+	args := m.Called(ctx, parentID)
+	return args.Get(0).([]models.Subject), args.Error(1)
+}
+
+func (m *MockSubjectRepository) CountChildren(ctx context.Context, id uint) (int64, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSubjectRepository) MoveSubtree(ctx context.Context, oldPath, newPath string, depthChange int) error {
+	args := m.Called(ctx, oldPath, newPath, depthChange)
+	return args.Error(0)
+}
+
+func (m *MockSubjectRepository) FindAliasByNameKey(ctx context.Context, key string) (*models.SubjectAlias, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SubjectAlias), args.Error(1)
+}
+
+func (m *MockSubjectRepository) CreateAlias(ctx context.Context, alias *models.SubjectAlias) error {
+	args := m.Called(ctx, alias)
+	return args.Error(0)
+}
+
+func (m *MockSubjectRepository) DeleteAlias(ctx context.Context, subjectID, aliasID uint) (int64, error) {
+	args := m.Called(ctx, subjectID, aliasID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSubjectRepository) ListBooks(ctx context.Context, subject *models.Subject, descendants bool, req query.PageRequest) ([]models.Book, query.PageInfo, error) {
+	args := m.Called(ctx, subject, descendants, req)
+	return args.Get(0).([]models.Book), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func (m *MockSubjectRepository) ReplaceBookSubjects(ctx context.Context, bookID uint, subjectIDs []uint) error {
+	args := m.Called(ctx, bookID, subjectIDs)
+	return args.Error(0)
+}
+
+func (m *MockSubjectRepository) GenreCounts(ctx context.Context) ([]repository.GenreCount, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]repository.GenreCount), args.Error(1)
+}
+
+func (m *MockSubjectRepository) LinkGenre(ctx context.Context, genre string, subjectID uint) (int64, error) {
+	args := m.Called(ctx, genre, subjectID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func newSubjectService(t *testing.T) (*MockSubjectRepository, sqlmock.Sqlmock, service.SubjectService) {
+	t.Helper()
+
+	mockSubjectRepo := new(MockSubjectRepository)
+	gormDB, mockDB := newMockDB(t)
+	return mockSubjectRepo, mockDB, service.NewSubjectService(gormDB, mockSubjectRepo)
+}
+
+func TestSubjectService_MoveSubject_RejectsMoveBelowDescendant(t *testing.T) {
+	mockSubjectRepo, sqlMock, subjectService := newSubjectService(t)
+
+	sqlMock.ExpectBegin()
+	mockSubjectRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockSubjectRepo).Once()
+	mockSubjectRepo.On("FindByID", mock.Anything, uint(1)).Return(&models.Subject{ID: 1, Path: "/1/"}, nil).Once()
+	mockSubjectRepo.On("FindByID", mock.Anything, uint(4)).Return(&models.Subject{ID: 4, Path: "/1/4/", Depth: 1}, nil).Once()
+	sqlMock.ExpectRollback()
+
+	_, err := subjectService.MoveSubject(context.Background(), 1, 0, 4)
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeBadRequest, appErr.Code)
+	mockSubjectRepo.AssertNotCalled(t, "MoveSubtree", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSubjectService_MoveSubject_RewritesSubtreePaths(t *testing.T) {
+	mockSubjectRepo, sqlMock, subjectService := newSubjectService(t)
+
+	parentID := uint(1)
+	subject := &models.Subject{ID: 4, Name: "Fantasy", NameKey: "fantasy", ParentID: &parentID, Path: "/1/4/", Depth: 1, Version: 2}
+	newParent := &models.Subject{ID: 7, Path: "/2/7/", Depth: 1}
+
+	sqlMock.ExpectBegin()
+	mockSubjectRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockSubjectRepo).Once()
+	mockSubjectRepo.On("FindByID", mock.Anything, uint(4)).Return(subject, nil).Once()
+	mockSubjectRepo.On("FindByID", mock.Anything, uint(7)).Return(newParent, nil).Once()
+	mockSubjectRepo.On("FindChildByNameKey", mock.Anything, mock.Anything, "fantasy").Return(nil, gorm.ErrRecordNotFound).Once()
+	mockSubjectRepo.On("Update", mock.Anything, mock.MatchedBy(func(s *models.Subject) bool {
+		return *s.ParentID == 7 && s.Path == "/2/7/4/" && s.Depth == 2
+	})).Return(nil).Once()
+	mockSubjectRepo.On("MoveSubtree", mock.Anything, "/1/4/", "/2/7/4/", 1).Return(nil).Once()
+	sqlMock.ExpectCommit()
+	mockSubjectRepo.On("FindByID", mock.Anything, uint(4)).Return(subject, nil).Once()
+	mockSubjectRepo.On("FindByIDs", mock.Anything, []uint{2, 7}).Return([]models.Subject{*newParent, {ID: 2, Path: "/2/"}}, nil).Once()
+	mockSubjectRepo.On("ListChildren", mock.Anything, uint(4)).Return([]models.Subject{}, nil).Once()
+
+	moved, err := subjectService.MoveSubject(context.Background(), 4, 2, 7)
+
+	require.NoError(t, err)
+	require.Len(t, moved.Ancestors, 2)
+	assert.Equal(t, uint(2), moved.Ancestors[0].ID, "ancestors are listed root first")
+	mockSubjectRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSubjectService_MoveSubject_RequiresTheCurrentVersion(t *testing.T) {
+	mockSubjectRepo, sqlMock, subjectService := newSubjectService(t)
+
+	sqlMock.ExpectBegin()
+	mockSubjectRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockSubjectRepo).Once()
+	mockSubjectRepo.On("FindByID", mock.Anything, uint(4)).Return(&models.Subject{ID: 4, Path: "/4/", Version: 3}, nil).Once()
+	sqlMock.ExpectRollback()
+
+	_, err := subjectService.MoveSubject(context.Background(), 4, 2, 7)
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodePreconditionFailed, appErr.Code)
+	mockSubjectRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSubjectService_MapGenres_DryRunReportsAndRollsBack(t *testing.T) {
+	mockSubjectRepo, sqlMock, subjectService := newSubjectService(t)
+
+	sciFi := models.Subject{ID: 3, Name: "Science fiction", NameKey: "science fiction"}
+
+	sqlMock.ExpectBegin()
+	mockSubjectRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockSubjectRepo).Once()
+	mockSubjectRepo.On("GenreCounts", mock.Anything).Return([]repository.GenreCount{
+		{Genre: "History", Books: 2},
+		{Genre: "Sci-Fi", Books: 4},
+		{Genre: "science fiction", Books: 1},
+	}, nil).Once()
+	mockSubjectRepo.On("FindAliasByNameKey", mock.Anything, "history").Return(nil, gorm.ErrRecordNotFound).Once()
+	mockSubjectRepo.On("FindByNameKey", mock.Anything, "history").
+		Return([]models.Subject{{ID: 5, Name: "History"}, {ID: 9, Name: "History"}}, nil).Once()
+	mockSubjectRepo.On("FindAliasByNameKey", mock.Anything, "sci fi").Return(&models.SubjectAlias{SubjectID: 3}, nil).Once()
+	mockSubjectRepo.On("FindByID", mock.Anything, uint(3)).Return(&sciFi, nil).Once()
+	mockSubjectRepo.On("LinkGenre", mock.Anything, "Sci-Fi", uint(3)).Return(int64(4), nil).Once()
+	mockSubjectRepo.On("FindAliasByNameKey", mock.Anything, "science fiction").Return(nil, gorm.ErrRecordNotFound).Once()
+	mockSubjectRepo.On("FindByNameKey", mock.Anything, "science fiction").Return([]models.Subject{sciFi}, nil).Once()
+	mockSubjectRepo.On("LinkGenre", mock.Anything, "science fiction", uint(3)).Return(int64(1), nil).Once()
+	sqlMock.ExpectRollback()
+
+	result, err := subjectService.MapGenres(context.Background(), false, false)
+
+	require.NoError(t, err)
+	assert.False(t, result.Applied)
+	assert.Equal(t, int64(5), result.BooksLinked)
+	assert.Equal(t, 1, result.Unmapped)
+	require.Len(t, result.Genres, 3)
+	assert.Contains(t, result.Genres[0].Reason, "several subjects")
+	assert.Equal(t, uint(3), result.Genres[1].SubjectID)
+	mockSubjectRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSubjectService_DeleteSubject_RejectsSubjectWithChildren(t *testing.T) {
+	mockSubjectRepo, _, subjectService := newSubjectService(t)
+
+	mockSubjectRepo.On("FindByID", mock.Anything, uint(1)).Return(&models.Subject{ID: 1}, nil).Once()
+	mockSubjectRepo.On("CountChildren", mock.Anything, uint(1)).Return(int64(2), nil).Once()
+
+	err := subjectService.DeleteSubject(context.Background(), 1, 0)
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	mockSubjectRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,105 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestUserService_ChangeRole_FallsBackToEmail(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	userService := service.NewUserService(nil, mockUserRepo, new(MockBorrowRepository))
+
+	user := &models.User{ID: 1, Username: "alice", Email: "alice@example.com", Role: models.RoleMember}
+
+	mockUserRepo.On("FindByUsername", mock.Anything, "alice@example.com").Return((*models.User)(nil), errors.New("not found")).Once()
+	mockUserRepo.On("FindByEmail", mock.Anything, "alice@example.com").Return(user, nil).Once()
+	mockUserRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.User")).
+		Run(func(args mock.Arguments) {
+			assert.Equal(t, models.RoleAdmin, args.Get(1).(*models.User).Role)
+		}).
+		Return(nil).
+		Once()
+
+	updated, err := userService.ChangeRole(context.Background(), "alice@example.com", models.RoleAdmin)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, updated.Role)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestUserService_ChangeRole_RejectsUnknownRole(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	userService := service.NewUserService(nil, mockUserRepo, new(MockBorrowRepository))
+
+	user, err := userService.ChangeRole(context.Background(), "alice", models.UserRole("superuser"))
+
+	assert.Error(t, err)
+	assert.Nil(t, user)
+	assert.Equal(t, "invalid role", err.Error())
+	mockUserRepo.AssertNotCalled(t, "FindByUsername", mock.Anything, mock.Anything)
+}
+
+func TestUserService_DeleteUser_RefusesActiveBorrows(t *testing.T) {
+	db, sqlMock := newMockDB(t)
+	mockUserRepo := new(MockUserRepository)
+	mockBorrowRepo := new(MockBorrowRepository)
+	userService := service.NewUserService(db, mockUserRepo, mockBorrowRepo)
+
+	sqlMock.ExpectBegin()
+	mockUserRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockUserRepo).Once()
+	mockBorrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBorrowRepo).Once()
+	mockUserRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(&models.User{ID: 1, Username: "alice", IsActive: true}, nil).Once()
+	mockBorrowRepo.On("CountActiveByUser", mock.Anything, uint(1)).Return(int64(2), nil).Once()
+	sqlMock.ExpectRollback()
+
+	_, err := userService.DeleteUser(context.Background(), 1)
+
+	assert.EqualError(t, err, "cannot delete user with active borrows")
+	mockUserRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserService_DeleteUser(t *testing.T) {
+	db, sqlMock := newMockDB(t)
+	mockUserRepo := new(MockUserRepository)
+	mockBorrowRepo := new(MockBorrowRepository)
+	userService := service.NewUserService(db, mockUserRepo, mockBorrowRepo)
+
+	sqlMock.ExpectBegin()
+	mockUserRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockUserRepo).Once()
+	mockBorrowRepo.On("WithTx", mock.AnythingOfType("*gorm.DB")).Return(mockBorrowRepo).Once()
+	mockUserRepo.On("FindByIDForUpdate", mock.Anything, uint(1)).Return(&models.User{ID: 1, Username: "alice", IsActive: true}, nil).Once()
+	mockBorrowRepo.On("CountActiveByUser", mock.Anything, uint(1)).Return(int64(0), nil).Once()
+	mockUserRepo.On("Update", mock.Anything, mock.MatchedBy(func(user *models.User) bool { return !user.IsActive })).Return(nil).Once()
+	mockUserRepo.On("Delete", mock.Anything, uint(1)).Return(nil).Once()
+	sqlMock.ExpectCommit()
+
+	user, err := userService.DeleteUser(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.False(t, user.IsActive)
+	mockUserRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserService_RestoreUser_RefusesPurgedUsers(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	userService := service.NewUserService(nil, mockUserRepo, new(MockBorrowRepository))
+
+	purged := &models.User{ID: 1, Username: "deleted-user-1", DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}
+	purged.Anonymize(time.Now())
+	mockUserRepo.On("FindByID", mock.Anything, uint(1)).Return(purged, nil).Once()
+
+	_, err := userService.RestoreUser(context.Background(), 1)
+
+	assert.EqualError(t, err, "user was purged and cannot be restored")
+	mockUserRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,151 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/internal/dto"
+	"github.com/alpardfm/library-management-api/internal/models"
+	"github.com/alpardfm/library-management-api/internal/repository"
+	"github.com/alpardfm/library-management-api/internal/service"
+	"github.com/alpardfm/library-management-api/pkg/apperror"
+	"github.com/alpardfm/library-management-api/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type MockWorkRepository struct {
+	mock.Mock
+}
+
+func (m *MockWorkRepository) WithTx(tx *gorm.DB) repository.WorkRepository {
+	args := m.Called(tx)
+	return args.Get(0).(repository.WorkRepository)
+}
+
+func (m *MockWorkRepository) Create(ctx context.Context, work *models.Work) error {
+	args := m.Called(ctx, work)
+	return args.Error(0)
+}
+
+func (m *MockWorkRepository) Update(ctx context.Context, work *models.Work) error {
+	args := m.Called(ctx, work)
+	return args.Error(0)
+}
+
+func (m *MockWorkRepository) Delete(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockWorkRepository) FindByID(ctx context.Context, id uint) (*models.Work, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Work), args.Error(1)
+}
+
+func (m *MockWorkRepository) List(ctx context.Context, search string, seriesID uint, req query.PageRequest) ([]models.Work, query.PageInfo, error) {
+	args := m.Called(ctx, search, seriesID, req)
+	return args.Get(0).([]models.Work), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+type MockSeriesRepository struct {
+	mock.Mock
+}
+
+func (m *MockSeriesRepository) Create(ctx context.Context, series *models.Series) error {
+	args := m.Called(ctx, series)
+	return args.Error(0)
+}
+
+func (m *MockSeriesRepository) Update(ctx context.Context, series *models.Series) error {
+	args := m.Called(ctx, series)
+	return args.Error(0)
+}
+
+func (m *MockSeriesRepository) Delete(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockSeriesRepository) FindByID(ctx context.Context, id uint) (*models.Series, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Series), args.Error(1)
+}
+
+func (m *MockSeriesRepository) List(ctx context.Context, search string, req query.PageRequest) ([]models.Series, query.PageInfo, error) {
+	args := m.Called(ctx, search, req)
+	return args.Get(0).([]models.Series), args.Get(1).(query.PageInfo), args.Error(2)
+}
+
+func TestWorkService_CreateWork_UnknownSeries(t *testing.T) {
+	workRepo := new(MockWorkRepository)
+	seriesRepo := new(MockSeriesRepository)
+	workService := service.NewWorkService(workRepo, seriesRepo)
+
+	seriesRepo.On("FindByID", mock.Anything, uint(9)).Return(nil, gorm.ErrRecordNotFound).Once()
+
+	_, err := workService.CreateWork(context.Background(), dto.WorkRequest{Title: "Dune", SeriesID: 9, Volume: 1})
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeBadRequest, appErr.Code)
+	assert.Contains(t, appErr.Fields, "series_id")
+	workRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestWorkService_UpdateWork_MovesOutOfSeries(t *testing.T) {
+	workRepo := new(MockWorkRepository)
+	workService := service.NewWorkService(workRepo, new(MockSeriesRepository))
+	seriesID := uint(2)
+
+	workRepo.On("FindByID", mock.Anything, uint(1)).
+		Return(&models.Work{ID: 1, Title: "Dune", SeriesID: &seriesID, Series: &models.Series{ID: 2}, Volume: 1, Version: 3}, nil)
+	workRepo.On("Update", mock.Anything, mock.MatchedBy(func(work *models.Work) bool {
+		return work.Title == "Dune Messiah" && work.SeriesID == nil && work.Series == nil && work.Volume == 0
+	})).Return(nil).Once()
+
+	_, err := workService.UpdateWork(context.Background(), 1, 3, dto.WorkRequest{Title: " Dune Messiah "})
+	require.NoError(t, err)
+	workRepo.AssertExpectations(t)
+
+	_, err = workService.UpdateWork(context.Background(), 1, 2, dto.WorkRequest{Title: "Dune"})
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodePreconditionFailed, appErr.Code)
+}
+
+func TestWorkService_DeleteWork_WithEditions(t *testing.T) {
+	workRepo := new(MockWorkRepository)
+	workService := service.NewWorkService(workRepo, new(MockSeriesRepository))
+
+	workRepo.On("FindByID", mock.Anything, uint(1)).Return(&models.Work{ID: 1, Version: 1, EditionCount: 2}, nil).Once()
+
+	err := workService.DeleteWork(context.Background(), 1, 0)
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	workRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestWorkService_DeleteSeries_WithWorks(t *testing.T) {
+	seriesRepo := new(MockSeriesRepository)
+	workService := service.NewWorkService(new(MockWorkRepository), seriesRepo)
+
+	seriesRepo.On("FindByID", mock.Anything, uint(4)).Return(&models.Series{ID: 4, Version: 1, WorkCount: 3}, nil).Once()
+
+	err := workService.DeleteSeries(context.Background(), 4, 1)
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeConflict, appErr.Code)
+	seriesRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
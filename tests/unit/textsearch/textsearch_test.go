@@ -0,0 +1,37 @@
+package textsearch_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alpardfm/library-management-api/pkg/textsearch"
+)
+
+func TestParse_TSQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "words must all match", input: "Dune  Herbert", want: "dune & herbert"},
+		{name: "phrase", input: `"desert planet" arrakis`, want: "(desert <-> planet) & arrakis"},
+		{name: "prefix", input: "herb*", want: "herb:*"},
+		{name: "prefix ends a phrase", input: `"frank herb*"`, want: "(frank <-> herb:*)"},
+		{name: "exclusion", input: "dune -film", want: "dune & !film"},
+		{name: "excluded phrase", input: `dune -"director's cut"`, want: "dune & !(director <-> s <-> cut)"},
+		{name: "operators are dropped", input: "c++ & (go | rust)!", want: "c & go & rust"},
+		{name: "unclosed quote", input: `"desert planet`, want: "(desert <-> planet)"},
+		{name: "accents are letters", input: "García Márquez", want: "garcía & márquez"},
+		{name: "punctuation only", input: `"" - * &`, want: ""},
+		{name: "exclusions only", input: "-film", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := textsearch.Parse(tt.input)
+			assert.Equal(t, tt.want, q.TSQuery())
+			assert.Equal(t, tt.want == "", q.Empty())
+		})
+	}
+}
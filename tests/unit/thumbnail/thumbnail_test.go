@@ -0,0 +1,78 @@
+package thumbnail_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/alpardfm/library-management-api/pkg/thumbnail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestFit_ScalesDownKeepingAspectRatio(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 600))
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 400; x++ {
+			c := color.RGBA{R: 200, A: 255}
+			if x >= 200 {
+				c = color.RGBA{B: 200, A: 255}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+	img, format, err := thumbnail.Decode(encodePNG(t, src))
+	require.NoError(t, err)
+	assert.Equal(t, "png", format)
+
+	thumb := thumbnail.Fit(img, 100)
+	assert.Equal(t, image.Rect(0, 0, 100, 150), thumb.Bounds())
+	assert.Equal(t, color.RGBA{R: 200, A: 255}, thumb.RGBAAt(10, 10))
+	assert.Equal(t, color.RGBA{B: 200, A: 255}, thumb.RGBAAt(90, 140))
+
+	var buf bytes.Buffer
+	require.NoError(t, thumbnail.WriteJPEG(&buf, thumb))
+	decoded, err := jpeg.DecodeConfig(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 100, decoded.Width)
+}
+
+func TestFit_KeepsSmallImagesAndFillsTransparencyWhite(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 50, 80))
+	thumb := thumbnail.Fit(src, 300)
+	assert.Equal(t, image.Rect(0, 0, 50, 80), thumb.Bounds())
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, thumb.RGBAAt(25, 40))
+}
+
+func TestDecode_RejectsHugeDimensionsBeforeDecoding(t *testing.T) {
+	// A PNG header claiming 20000x20000 pixels, with no image data.
+	data := []byte("\x89PNG\r\n\x1a\n")
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], 20000)
+	binary.BigEndian.PutUint32(ihdr[4:], 20000)
+	ihdr[8], ihdr[9] = 8, 6
+	chunk := append([]byte("IHDR"), ihdr...)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(ihdr)))
+	data = append(data, chunk...)
+	data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(chunk))
+
+	_, _, err := thumbnail.Decode(data)
+	assert.ErrorIs(t, err, thumbnail.ErrTooLarge)
+}
+
+func TestDecode_RejectsNonImages(t *testing.T) {
+	_, _, err := thumbnail.Decode([]byte("not an image"))
+	assert.Error(t, err)
+}
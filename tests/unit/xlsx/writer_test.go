@@ -0,0 +1,88 @@
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpardfm/library-management-api/pkg/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readPart(t *testing.T, data []byte, name string) string {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	for _, f := range reader.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err)
+		defer rc.Close()
+		body, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		return string(body)
+	}
+	t.Fatalf("part %s not found", name)
+	return ""
+}
+
+func TestWriter_WritesWorkbookParts(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := xlsx.NewWriter(&buf, "Books & More")
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteHeader([]string{"id", "title"}))
+	require.NoError(t, w.WriteRow([]any{uint(1), "Clean <Code>"}))
+	require.NoError(t, w.WriteRow([]any{2, nil, true, 1.5, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}))
+	require.NoError(t, w.Close())
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		assert.NotEmpty(t, readPart(t, buf.Bytes(), name))
+	}
+	assert.Contains(t, readPart(t, buf.Bytes(), "xl/workbook.xml"), `name="Books &amp; More"`)
+
+	sheet := readPart(t, buf.Bytes(), "xl/worksheets/sheet1.xml")
+	assert.Contains(t, sheet, `<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="A2"><v>1</v></c>`)
+	assert.Contains(t, sheet, `Clean &lt;Code&gt;`)
+	assert.NotContains(t, sheet, `r="B3"`, "nil values leave the cell empty")
+	assert.Contains(t, sheet, `<c r="C3" t="b"><v>1</v></c>`)
+	assert.Contains(t, sheet, `<c r="D3"><v>1.5</v></c>`)
+	assert.Contains(t, sheet, `2026-01-02T03:04:05Z`)
+	assert.True(t, strings.HasSuffix(sheet, "</sheetData></worksheet>"))
+}
+
+func TestWriter_TruncatesLongCells(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := xlsx.NewWriter(&buf, "Sheet1")
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteRow([]any{strings.Repeat("é", xlsx.MaxCellLength+10)}))
+	require.NoError(t, w.Close())
+
+	sheet := readPart(t, buf.Bytes(), "xl/worksheets/sheet1.xml")
+	assert.Equal(t, xlsx.MaxCellLength, strings.Count(sheet, "é"))
+}
+
+func TestWriter_RejectsRowsAfterClose(t *testing.T) {
+	w, err := xlsx.NewWriter(io.Discard, "Sheet1")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.ErrorIs(t, w.WriteRow([]any{"late"}), xlsx.ErrClosed)
+}
+
+func TestColumnName(t *testing.T) {
+	assert.Equal(t, "A", xlsx.ColumnName(0))
+	assert.Equal(t, "Z", xlsx.ColumnName(25))
+	assert.Equal(t, "AA", xlsx.ColumnName(26))
+	assert.Equal(t, "AZ", xlsx.ColumnName(51))
+	assert.Equal(t, "BA", xlsx.ColumnName(52))
+}